@@ -1,7 +1,25 @@
 package main
 
-import "github.com/margar-melkonyan/remote-desktop.git/internal/app"
+import (
+	"os"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/app"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/config"
+)
 
 func main() {
+	config.Load()
+
+	// Служебные команды:
+	//   remote-desktop-server reconcile [-repair]
+	//   remote-desktop-server set-role <email> <role>
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "reconcile":
+			os.Exit(app.RunReconcile(os.Args[2:]))
+		case "set-role":
+			os.Exit(app.RunSetRole(os.Args[2:]))
+		}
+	}
 	app.RunHttpServer()
 }
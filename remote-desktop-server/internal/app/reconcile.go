@@ -0,0 +1,61 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common/dependency"
+)
+
+// RunReconcile выполняет сверку пользователей приложения и Guacamole из командной строки
+// и выводит отчет в формате JSON.
+//
+// Использование:
+//
+//	remote-desktop-server reconcile [-repair]
+//
+// Без флага -repair расхождения только выводятся в отчет.
+//
+// Возвращает:
+//   - int: код завершения (0 — расхождений нет или все исправлены, 1 — остались расхождения или ошибки)
+func RunReconcile(args []string) int {
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	repair := flags.Bool("repair", false, "repair detected drift instead of only reporting it")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(
+		context.Background(),
+		syscall.SIGINT,
+		syscall.SIGTERM,
+	)
+	defer stop()
+	deps := dependency.NewAppDependencies()
+
+	report, err := deps.ReconciliationService.Reconcile(ctx, common.ReconciliationOptions{
+		DisableDeleted: *repair,
+		RepairOrphans:  *repair,
+	})
+	if err != nil {
+		slog.Error(fmt.Sprintf("Reconciliation failed: %s", err.Error()))
+		return 1
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		slog.Error(err.Error())
+		return 1
+	}
+	if report.HasDrift() {
+		return 1
+	}
+	return 0
+}
@@ -20,11 +20,17 @@ func RunHttpServer() {
 		syscall.SIGTERM,
 	)
 	defer stop()
+	deps := dependency.NewAppDependencies()
+	// Фоновые задачи завершаются вместе с сервером по сигналу остановки
+	go deps.BalancingService.Run(ctx)
+	go deps.RecordingService.Run(ctx)
+	go deps.CommandIndexService.Run(ctx)
+	go deps.ReconciliationService.Run(ctx)
 	go func() {
 		addr := fmt.Sprintf(":%s", config.ServerConfig.Port)
 		server := &http.Server{
 			Addr:    addr,
-			Handler: router.NewRouter(dependency.NewAppDependencies()),
+			Handler: router.NewRouter(deps),
 		}
 		slog.Info(
 			fmt.Sprintf("Http Server start on port %s",
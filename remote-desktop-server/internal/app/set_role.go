@@ -0,0 +1,41 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common/dependency"
+)
+
+// RunSetRole назначает роль пользователю из командной строки.
+// Используется, в частности, чтобы назначить первого администратора.
+//
+// Использование:
+//
+//	remote-desktop-server set-role <email> <role>
+//
+// Возвращает:
+//   - int: код завершения (0 — роль назначена, 1 — ошибка назначения, 2 — неверные аргументы)
+func RunSetRole(args []string) int {
+	if len(args) != 2 {
+		fmt.Fprintf(os.Stderr, "usage: remote-desktop-server set-role <email> <%s>\n", strings.Join(common.Roles, "|"))
+		return 2
+	}
+	email, role := args[0], args[1]
+	if !common.IsValidRole(role) {
+		fmt.Fprintf(os.Stderr, "unknown role %q, expected one of: %s\n", role, strings.Join(common.Roles, ", "))
+		return 2
+	}
+
+	deps := dependency.NewAppDependencies()
+	if err := deps.RoleService.AssignByEmail(context.Background(), email, role); err != nil {
+		slog.Error(fmt.Sprintf("Setting role failed: %s", err.Error()))
+		return 1
+	}
+	fmt.Printf("%s is now %s\n", email, role)
+	return 0
+}
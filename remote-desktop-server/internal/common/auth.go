@@ -9,8 +9,9 @@ import (
 )
 
 // Константы для ключей контекста:
-const USER_MAIL = "user_mail" // Ключ для хранения email пользователя в контексте
-const USER = "user"           // Ключ для хранения данных пользователя в контексте
+const USER_MAIL = "user_mail"       // Ключ для хранения email пользователя в контексте
+const USER = "user"                 // Ключ для хранения данных пользователя в контексте
+const AUTH_SESSION = "auth_session" // Ключ для хранения сессии входа пользователя в контексте
 
 // AuthSignInRequest представляет структуру запроса для входа пользователя.
 // Поля:
@@ -34,23 +35,89 @@ type AuthSignUpRequest struct {
 	PasswordConfirmation string `json:"password_confirmation" validate:"required,min=8,max=255"`
 }
 
+// AuthRefreshRequest представляет запрос на обновление токенов или выход.
+// Поля:
+//   - RefreshToken: refresh токен, полученный при входе или предыдущем обновлении (обязательное)
+type AuthRefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required,max=255"`
+}
+
+// AuthTokens представляет токены, выдаваемые при входе и обновлении.
+// Токен Guacamole клиенту не передается: он хранится в сессии входа на сервере.
+// Поля:
+//   - Token: короткоживущий JWT токен доступа
+//   - RefreshToken: одноразовый токен для получения новой пары токенов
+type AuthTokens struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// AuthSession представляет сессию входа пользователя.
+// Все refresh токены, полученные из одного входа, принадлежат одной сессии (семейству):
+// при выходе или повторном использовании токена отзывается вся сессия.
+// Поля:
+//   - ID: идентификатор сессии (передается в JWT в поле sid)
+//   - UserID: пользователь
+//   - TokenVersion: версия токенов пользователя на момент входа (после смены пароля сессия недействительна)
+//   - GuacamoleToken: токен Guacamole сессии (хранится только на сервере)
+//   - GuacamoleCredentials: зашифрованный пароль Guacamole для повторной аутентификации,
+//     когда токен Guacamole истекает
+//   - RevokedAt: время отзыва сессии
+//   - CreatedAt: время входа
+type AuthSession struct {
+	ID                   uuid.UUID
+	UserID               uuid.UUID
+	TokenVersion         int
+	GuacamoleToken       string
+	GuacamoleCredentials []byte
+	RevokedAt            *time.Time
+	CreatedAt            time.Time
+}
+
+// RefreshToken представляет refresh токен (в базе хранится только хэш токена).
+// Поля:
+//   - ID: идентификатор токена
+//   - SessionID: сессия входа, к которой относится токен
+//   - Used: токен уже обменян на новую пару токенов
+//   - Expired: срок действия токена истек
+type RefreshToken struct {
+	ID        uuid.UUID
+	SessionID uuid.UUID
+	Used      bool
+	Expired   bool
+}
+
 // User представляет модель пользователя в системе.
 // Поля:
 //   - ID: уникальный идентификатор (не возвращается в JSON)
 //   - Name: имя пользователя
 //   - Email: электронная почта
 //   - Password: хэш пароля (не возвращается в JSON)
+//   - Role: роль пользователя (admin, operator или viewer)
+//   - Locale: предпочитаемый язык (ru или en, пустой — язык по умолчанию)
+//   - Timezone: часовой пояс IANA (например, Europe/Moscow, пустой — не задан)
+//   - TokenVersion: версия токенов доступа, увеличивается при смене пароля (не возвращается в JSON)
+//   - GuacamoleCredentials: зашифрованный пароль Guacamole, управляемый сервером, у пользователей,
+//     входящих через внешний провайдер (nil — пароль Guacamole совпадает с паролем пользователя)
 //   - CreatedAt: дата создания
 //   - UpdatedAt: дата обновления (не возвращается в JSON)
 //   - DeletedAt: дата удаления (soft delete, не возвращается в JSON)
+//   - EmailVerifiedAt: дата подтверждения электронной почты (nil — почта не подтверждена и вход запрещен)
 type User struct {
-	ID        uuid.UUID  `json:"-"`
-	Name      string     `json:"name"`
-	Email     string     `json:"email"`
-	Password  string     `json:"-"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"-"`
-	DeletedAt *time.Time `json:"-"`
+	ID           uuid.UUID  `json:"-"`
+	Name         string     `json:"name"`
+	Email        string     `json:"email"`
+	Password     string     `json:"-"`
+	Role         string     `json:"role"`
+	Locale       string     `json:"locale"`
+	Timezone     string     `json:"timezone"`
+	TokenVersion int        `json:"-"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"-"`
+	DeletedAt    *time.Time `json:"-"`
+
+	EmailVerifiedAt      *time.Time `json:"-"`
+	GuacamoleCredentials []byte     `json:"-"`
 }
 
 // UserResponse представляет структуру ответа с данными пользователя.
@@ -59,10 +126,19 @@ type User struct {
 //   - ID: уникальный идентификатор
 //   - Name: имя пользователя
 //   - Email: электронная почта (может быть опущена)
+//   - Role: роль пользователя (может быть опущена)
+//   - Permissions: разрешения приложения, полученные от роли (могут быть опущены)
+//   - Locale, Timezone: предпочитаемые язык и часовой пояс (могут быть опущены)
+//   - PendingEmail: новая электронная почта, ожидающая подтверждения по ссылке из письма (может быть опущена)
 //   - CreatedAt: дата создания аккаунта (может быть опущена)
 type UserResponse struct {
-	ID        uuid.UUID  `json:"id"`
-	Name      string     `json:"name"`
-	Email     string     `json:"email,omitempty"`
-	CreatedAt *time.Time `json:"created_at,omitempty"`
+	ID           uuid.UUID  `json:"id"`
+	Name         string     `json:"name"`
+	Email        string     `json:"email,omitempty"`
+	Role         string     `json:"role,omitempty"`
+	Permissions  []string   `json:"permissions,omitempty"`
+	Locale       string     `json:"locale,omitempty"`
+	Timezone     string     `json:"timezone,omitempty"`
+	PendingEmail string     `json:"pending_email,omitempty"`
+	CreatedAt    *time.Time `json:"created_at,omitempty"`
 }
@@ -2,6 +2,8 @@
 // Включает DTO (Data Transfer Objects) для запросов/ответов API и базовые модели.
 package common
 
+import "time"
+
 // DBConfig содержит параметры подключения к базе данных
 // Поля:
 //   - Username: имя пользователя БД
@@ -23,9 +25,194 @@ type DBConfig struct {
 // Поля:
 //   - AccessTokenSecret: секретный ключ для подписи access токенов
 //   - AccessTokenTTL: время жизни access токена (например "15m" - 15 минут)
+//   - RefreshTokenTTL: время жизни refresh токена
 type JWTConfig struct {
 	AccessTokenSecret string
 	AccessTokenTTL    string
+	RefreshTokenTTL   time.Duration
+}
+
+// GuacamoleAccountConfig содержит учетные данные служебной учетной записи Guacamole,
+// от имени которой выполняются фоновые задачи (без пользовательского токена)
+// Поля:
+//   - Username: имя пользователя Guacamole (например, guacadmin)
+//   - Password: пароль пользователя Guacamole
+type GuacamoleAccountConfig struct {
+	Username string
+	Password string
+}
+
+// BalancingConfig содержит параметры проверки доступности участников групп балансировки
+// Поля:
+//   - ProbeInterval: интервал между проверками
+//   - ProbeTimeout: таймаут TCP подключения к участнику
+//   - FailureThreshold: количество неудачных проверок подряд, после которого участник отключается
+type BalancingConfig struct {
+	ProbeInterval    time.Duration
+	ProbeTimeout     time.Duration
+	FailureThreshold int
+}
+
+// RecordingConfig содержит параметры хранения записей сессий
+// Поля:
+//   - Path: каталог записей на стороне guacd (используется в параметре recording-path)
+//   - StoragePath: тот же каталог, смонтированный в контейнер сервера
+//   - Retention: срок хранения записей по умолчанию (0 — хранить бессрочно)
+//   - SweepInterval: интервал удаления устаревших записей
+//   - IndexInterval: интервал индексации команд в записях терминала
+type RecordingConfig struct {
+	Path          string
+	StoragePath   string
+	Retention     time.Duration
+	SweepInterval time.Duration
+	IndexInterval time.Duration
+}
+
+// SharingConfig содержит параметры совместного доступа к активным сессиям
+// Поля:
+//   - LinkTTL: время жизни ссылки совместного доступа по умолчанию
+//   - ViewerURL: страница просмотра общей сессии, получающая токен ссылки во фрагменте адреса
+type SharingConfig struct {
+	LinkTTL   time.Duration
+	ViewerURL string
+}
+
+// ReconciliationConfig содержит параметры фоновой сверки пользователей приложения и Guacamole
+// Поля:
+//   - Interval: интервал между сверками (0 — фоновая сверка отключена)
+//   - RepairOrphans: удалять ли учетные записи Guacamole без пользователя приложения
+//     и помечать удаленными пользователей без учетной записи Guacamole
+type ReconciliationConfig struct {
+	Interval      time.Duration
+	RepairOrphans bool
+}
+
+// MailConfig содержит параметры отправки писем через SMTP
+// Поля:
+//   - Host: адрес SMTP сервера (пустой — письма только пишутся в журнал)
+//   - Port: порт SMTP сервера
+//   - Username: имя пользователя для аутентификации (пустое — без аутентификации)
+//   - Password: пароль для аутентификации
+//   - From: адрес отправителя
+//   - StartTLS: требовать шифрование соединения командой STARTTLS
+type MailConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+	StartTLS bool
+}
+
+// PasswordResetConfig содержит параметры сброса пароля
+// Поля:
+//   - TokenTTL: время жизни токена сброса пароля
+//   - URL: адрес страницы сброса пароля, к которому добавляется параметр token
+type PasswordResetConfig struct {
+	TokenTTL time.Duration
+	URL      string
+}
+
+// EmailVerificationConfig содержит параметры подтверждения электронной почты
+// Поля:
+//   - TokenTTL: время жизни токена подтверждения
+//   - URL: адрес страницы подтверждения, к которому добавляется параметр token
+type EmailVerificationConfig struct {
+	TokenTTL time.Duration
+	URL      string
+}
+
+// TwoFactorConfig содержит параметры двухфакторной аутентификации
+// Поля:
+//   - Issuer: название сервиса в приложении-аутентификаторе
+//   - ChallengeTTL: время жизни токена второго шага входа
+//   - MaxAttempts: количество неверных кодов, после которого вход нужно начать заново
+//   - RequiredPermissions: системные разрешения Guacamole, владельцам которых TOTP обязателен
+type TwoFactorConfig struct {
+	Issuer              string
+	ChallengeTTL        time.Duration
+	MaxAttempts         int
+	RequiredPermissions []string
+}
+
+// OIDCConfig содержит параметры входа через провайдера OpenID Connect
+// Поля:
+//   - IssuerURL: адрес провайдера (без него вход через OIDC отключен)
+//   - ClientID, ClientSecret: учетные данные клиента у провайдера
+//   - RedirectURL: адрес обработчика /auth/oidc/callback, зарегистрированный у провайдера
+//   - Scopes: запрашиваемые scope
+//   - GroupsClaim: утверждение ID токена со списком групп пользователя
+//   - GroupRoles: роли для групп провайдера
+//   - FrontendURL: страница фронтенда, на которую передаются выданные токены
+//   - StateTTL: время, за которое нужно завершить вход у провайдера
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	GroupsClaim  string
+	GroupRoles   map[string]string
+	FrontendURL  string
+	StateTTL     time.Duration
+}
+
+// AuthConfig содержит параметры проверки учетных данных при входе по паролю
+// Поля:
+//   - Backends: способы проверки в порядке опроса (local — пароль в базе приложения, ldap — каталог LDAP)
+//   - DefaultRole: роль пользователей, зарегистрированных самостоятельно или через внешний провайдер
+//     без подходящей группы
+type AuthConfig struct {
+	Backends    []string
+	DefaultRole string
+}
+
+// LoginProtectionConfig содержит параметры защиты входа по паролю от подбора
+// Поля:
+//   - AccountMaxFailures: неудачных попыток входа в учетную запись до ее временной блокировки (0 — не блокировать)
+//   - IPMaxFailures: неудачных попыток входа с одного адреса до его временной блокировки (0 — не блокировать)
+//   - FailureWindow: период, после которого без новых неудач счетчик попыток начинается заново
+//   - LockoutDuration: длительность временной блокировки
+//   - DelayBase: задержка после первой неудачной попытки; после каждой следующей удваивается
+//   - DelayMax: наибольшая задержка между попытками
+//   - TrustForwardedFor: определять адрес клиента по заголовку X-Forwarded-For (сервер за обратным прокси)
+//   - Store: хранилище попыток (postgres или memory — для тестов и запуска в одном экземпляре)
+type LoginProtectionConfig struct {
+	AccountMaxFailures int
+	IPMaxFailures      int
+	FailureWindow      time.Duration
+	LockoutDuration    time.Duration
+	DelayBase          time.Duration
+	DelayMax           time.Duration
+	TrustForwardedFor  bool
+	Store              string
+}
+
+// LDAPConfig содержит параметры входа через каталог LDAP (Active Directory)
+// Поля:
+//   - URL: адрес сервера (ldap:// или ldaps://)
+//   - StartTLS: включать TLS командой StartTLS после подключения по ldap://
+//   - InsecureSkipVerify: не проверять сертификат сервера (только для тестовых стендов)
+//   - BindDN, BindPassword: служебная учетная запись для поиска пользователей (пустые — анонимный поиск)
+//   - BaseDN: каталог, в котором ищутся пользователи
+//   - UserFilter: фильтр поиска пользователя, %s заменяется экранированным email
+//   - EmailAttribute, NameAttribute: атрибуты электронной почты и имени пользователя
+//   - GroupAttribute: атрибут со списком групп пользователя (DN групп)
+//   - GroupRoles: роли для групп каталога
+//   - Timeout: время ожидания ответа сервера
+type LDAPConfig struct {
+	URL                string
+	StartTLS           bool
+	InsecureSkipVerify bool
+	BindDN             string
+	BindPassword       string
+	BaseDN             string
+	UserFilter         string
+	EmailAttribute     string
+	NameAttribute      string
+	GroupAttribute     string
+	GroupRoles         map[string]string
+	Timeout            time.Duration
 }
 
 // ServerConfig содержит основную конфигурацию сервера
@@ -35,11 +222,42 @@ type JWTConfig struct {
 //   - BcryptPower: сложность хеширования паролей (4-31)
 //   - DbConfig: конфигурация базы данных
 //   - JWTConfig: конфигурация JWT аутентификации
+//   - GuacamoleAPIURL: базовый URL REST API Guacamole
+//   - GuacamoleServiceAccount: служебная учетная запись Guacamole для фоновых задач
+//   - GuacamoleCredentialsKey: ключ шифрования паролей Guacamole в сессиях входа
+//   - GuacamoleDriveRoot: каталог guacd, внутри которого создаются виртуальные диски RDP
+//   - BalancingConfig: параметры проверки доступности групп балансировки
+//   - RecordingConfig: параметры хранения записей сессий
+//   - SharingConfig: параметры ссылок совместного доступа
+//   - ReconciliationConfig: параметры фоновой сверки пользователей с Guacamole
+//   - MailConfig: параметры отправки писем
+//   - PasswordResetConfig: параметры сброса пароля
+//   - EmailVerificationConfig: параметры подтверждения электронной почты
+//   - TwoFactorConfig: параметры двухфакторной аутентификации
+//   - OIDCConfig: параметры входа через OpenID Connect
+//   - AuthConfig: способы проверки учетных данных при входе по паролю
+//   - LDAPConfig: параметры входа через LDAP
+//   - LoginProtectionConfig: параметры защиты входа от подбора пароля
 type ServerConfig struct {
-	Port            string
-	LogLevel        int8
-	BcryptPower     int
-	DbConfig        []*DBConfig
-	JWTConfig       JWTConfig
-	GuacamoleAPIURL string
+	Port                    string
+	LogLevel                int8
+	BcryptPower             int
+	DbConfig                []*DBConfig
+	JWTConfig               JWTConfig
+	GuacamoleAPIURL         string
+	GuacamoleServiceAccount GuacamoleAccountConfig
+	GuacamoleCredentialsKey string
+	GuacamoleDriveRoot      string
+	BalancingConfig         BalancingConfig
+	RecordingConfig         RecordingConfig
+	SharingConfig           SharingConfig
+	ReconciliationConfig    ReconciliationConfig
+	MailConfig              MailConfig
+	PasswordResetConfig     PasswordResetConfig
+	EmailVerificationConfig EmailVerificationConfig
+	TwoFactorConfig         TwoFactorConfig
+	OIDCConfig              OIDCConfig
+	AuthConfig              AuthConfig
+	LDAPConfig              LDAPConfig
+	LoginProtectionConfig   LoginProtectionConfig
 }
@@ -7,6 +7,7 @@ import (
 
 	"github.com/margar-melkonyan/remote-desktop.git/internal/config"
 	http_handler "github.com/margar-melkonyan/remote-desktop.git/internal/handler/http"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/mailer"
 	"github.com/margar-melkonyan/remote-desktop.git/internal/repository"
 	"github.com/margar-melkonyan/remote-desktop.git/internal/service"
 	"github.com/margar-melkonyan/remote-desktop.git/internal/storage/postgres"
@@ -15,7 +16,16 @@ import (
 // GlobalRepositories содержит все интерфейсы репозиториев, используемые в приложении.
 // Служит контейнером для зависимостей слоя доступа к данным.
 type GlobalRepositories struct {
-	UserRepository repository.UserRepository // Репозиторий для операций с пользователями
+	UserRepository        repository.UserRepository        // Репозиторий для операций с пользователями
+	AuthSessionRepository repository.AuthSessionRepository // Репозиторий сессий входа
+}
+
+// BackgroundServices содержит сервисы, выполняющие фоновые задачи на протяжении работы сервера.
+type BackgroundServices struct {
+	BalancingService      *service.BalancingService      // Проверка доступности участников групп балансировки
+	RecordingService      *service.RecordingService      // Удаление записей сессий с истекшим сроком хранения
+	CommandIndexService   *service.CommandIndexService   // Индексация команд в записях терминала
+	ReconciliationService *service.ReconciliationService // Сверка пользователей приложения и Guacamole
 }
 
 // AppDependencies содержит все зависимости приложения:
@@ -28,10 +38,28 @@ type GlobalRepositories struct {
 //   - Внедрения зависимостей между слоями
 //   - Предоставления единой точки доступа к сервисам
 type AppDependencies struct {
-	UserHandler    http_handler.UserHandler
-	AuthHandler    http_handler.AuthHandler
-	SessionHandler http_handler.SessionHandler
+	UserHandler                 http_handler.UserHandler
+	UserAdminHandler            http_handler.UserAdminHandler
+	AuthHandler                 http_handler.AuthHandler
+	PasswordHandler             http_handler.PasswordHandler
+	ProfileHandler              http_handler.ProfileHandler
+	EmailVerificationHandler    http_handler.EmailVerificationHandler
+	TwoFactorHandler            http_handler.TwoFactorHandler
+	OIDCHandler                 http_handler.OIDCHandler
+	SessionHandler              http_handler.SessionHandler
+	ConnectionGroupHandler      http_handler.ConnectionGroupHandler
+	ActiveConnectionHandler     http_handler.ActiveConnectionHandler
+	HistoryHandler              http_handler.HistoryHandler
+	RecordingHandler            http_handler.RecordingHandler
+	CommandHandler              http_handler.CommandHandler
+	SharingHandler              http_handler.SharingHandler
+	ConnectionPermissionHandler http_handler.ConnectionPermissionHandler
+	UserGroupHandler            http_handler.UserGroupHandler
+	ReconciliationHandler       http_handler.ReconciliationHandler
+	LoginProtectionHandler      http_handler.LoginProtectionHandler
+	RoleService                 *service.RoleService // Назначение ролей (команда set-role)
 	GlobalRepositories
+	BackgroundServices
 }
 
 // NewAppDependencies создает и инициализирует все зависимости приложения.
@@ -82,22 +110,131 @@ func NewAppDependencies() *AppDependencies {
 
 	// Инициализация репозиториев
 	userRepo := repository.NewUserRepository(db)
+	recordingPolicyRepo := repository.NewRecordingPolicyRepository(db)
+	recordingIndexRepo := repository.NewRecordingIndexRepository(db)
+	shareLinkRepo := repository.NewShareLinkRepository(db)
+	userGroupRepo := repository.NewUserGroupRepository(db)
+	passwordResetRepo := repository.NewPasswordResetRepository(db)
+	authSessionRepo := repository.NewAuthSessionRepository(db)
+	twoFactorRepo := repository.NewTwoFactorRepository(db)
+	oidcStateRepo := repository.NewOIDCStateRepository(db)
+	oidcIdentityRepo := repository.NewOIDCIdentityRepository(db)
+	emailVerificationRepo := repository.NewEmailVerificationRepository(db)
+	// Неудачные попытки входа (LOGIN_ATTEMPTS_STORE=memory — в памяти процесса)
+	loginAttemptRepo := repository.NewLoginAttemptRepository(db)
+	if config.ServerConfig.LoginProtectionConfig.Store == "memory" {
+		loginAttemptRepo = repository.NewMemoryLoginAttemptRepository()
+	}
 	guacRepo := repository.NewGuacamoleRepository(dbGuac)
+	historyRepo := repository.NewHistoryRepository(dbGuac)
+	// Отправка писем (без настроенного SMTP письма пишутся в журнал)
+	mail := mailer.New(config.ServerConfig.MailConfig)
 	// Инициализация сервисов
 	userService := service.NewUserService(userRepo)
-	authService := service.NewAuthService(userRepo, guacRepo)
-	sessionService := service.NewSessionService()
+	tokenService := service.NewTokenService(authSessionRepo, userRepo)
+	loginProtectionService := service.NewLoginProtectionService(
+		loginAttemptRepo,
+		userRepo,
+		config.ServerConfig.LoginProtectionConfig,
+	)
+	twoFactorService := service.NewTwoFactorService(
+		twoFactorRepo,
+		userRepo,
+		guacRepo,
+		tokenService,
+		loginProtectionService,
+	)
+	authenticators := service.NewAuthenticators(userRepo, guacRepo, tokenService)
+	emailVerificationService := service.NewEmailVerificationService(
+		userRepo,
+		emailVerificationRepo,
+		guacRepo,
+		tokenService,
+		mail,
+	)
+	authService := service.NewAuthService(
+		userRepo,
+		guacRepo,
+		tokenService,
+		twoFactorService,
+		emailVerificationService,
+		loginProtectionService,
+		authenticators,
+	)
+	oidcService := service.NewOIDCService(
+		oidcStateRepo,
+		oidcIdentityRepo,
+		userRepo,
+		guacRepo,
+		tokenService,
+		twoFactorService,
+	)
+	passwordService := service.NewPasswordService(userRepo, passwordResetRepo, guacRepo, tokenService, mail)
+	profileService := service.NewProfileService(userRepo, guacRepo, emailVerificationService)
+	sessionService := service.NewSessionService(recordingPolicyRepo, tokenService)
+	connectionGroupService := service.NewConnectionGroupService(sessionService)
+	balancingService := service.NewBalancingService(sessionService, connectionGroupService)
+	activeConnectionService := service.NewActiveConnectionService(sessionService)
+	historyService := service.NewHistoryService(historyRepo, sessionService)
+	recordingService := service.NewRecordingService(sessionService, recordingPolicyRepo, recordingIndexRepo)
+	commandIndexService := service.NewCommandIndexService(recordingService, sessionService, recordingIndexRepo, historyRepo)
+	sharingService := service.NewSharingService(sessionService, shareLinkRepo)
+	connectionPermissionService := service.NewConnectionPermissionService(guacRepo, sessionService)
+	userGroupService := service.NewUserGroupService(userGroupRepo, userRepo, guacRepo, sessionService)
+	reconciliationService := service.NewReconciliationService(userRepo, guacRepo, sessionService)
+	roleService := service.NewRoleService(userRepo, guacRepo)
+	userAdminService := service.NewUserAdminService(userRepo, guacRepo, tokenService, roleService)
 	// Создание обработчиков
 	userHandler := http_handler.NewUserHandler(*userService)
+	userAdminHandler := http_handler.NewUserAdminHandler(userAdminService)
 	authHandler := http_handler.NewAuthHandler(*authService)
+	passwordHandler := http_handler.NewPasswordHandler(passwordService)
+	profileHandler := http_handler.NewProfileHandler(profileService)
+	emailVerificationHandler := http_handler.NewEmailVerificationHandler(emailVerificationService)
+	twoFactorHandler := http_handler.NewTwoFactorHandler(twoFactorService)
+	oidcHandler := http_handler.NewOIDCHandler(oidcService)
 	sessionHandler := http_handler.NewSessionHandler(sessionService)
+	connectionGroupHandler := http_handler.NewConnectionGroupHandler(connectionGroupService, balancingService)
+	activeConnectionHandler := http_handler.NewActiveConnectionHandler(activeConnectionService)
+	historyHandler := http_handler.NewHistoryHandler(historyService)
+	recordingHandler := http_handler.NewRecordingHandler(recordingService)
+	commandHandler := http_handler.NewCommandHandler(commandIndexService)
+	sharingHandler := http_handler.NewSharingHandler(sharingService)
+	connectionPermissionHandler := http_handler.NewConnectionPermissionHandler(connectionPermissionService)
+	userGroupHandler := http_handler.NewUserGroupHandler(userGroupService)
+	reconciliationHandler := http_handler.NewReconciliationHandler(reconciliationService)
+	loginProtectionHandler := http_handler.NewLoginProtectionHandler(loginProtectionService)
 
 	return &AppDependencies{
-		UserHandler:    *userHandler,
-		AuthHandler:    *authHandler,
-		SessionHandler: *sessionHandler,
+		UserHandler:                 *userHandler,
+		UserAdminHandler:            *userAdminHandler,
+		AuthHandler:                 *authHandler,
+		PasswordHandler:             *passwordHandler,
+		ProfileHandler:              *profileHandler,
+		EmailVerificationHandler:    *emailVerificationHandler,
+		TwoFactorHandler:            *twoFactorHandler,
+		OIDCHandler:                 *oidcHandler,
+		SessionHandler:              *sessionHandler,
+		ConnectionGroupHandler:      *connectionGroupHandler,
+		ActiveConnectionHandler:     *activeConnectionHandler,
+		HistoryHandler:              *historyHandler,
+		RecordingHandler:            *recordingHandler,
+		CommandHandler:              *commandHandler,
+		SharingHandler:              *sharingHandler,
+		ConnectionPermissionHandler: *connectionPermissionHandler,
+		UserGroupHandler:            *userGroupHandler,
+		ReconciliationHandler:       *reconciliationHandler,
+		LoginProtectionHandler:      *loginProtectionHandler,
+		RoleService:                 roleService,
 		GlobalRepositories: GlobalRepositories{
-			UserRepository: userRepo,
+			UserRepository:        userRepo,
+			AuthSessionRepository: authSessionRepo,
+		},
+		BackgroundServices: BackgroundServices{
+			BalancingService:      balancingService,
+			RecordingService:      recordingService,
+			CommandIndexService:   commandIndexService,
+			ReconciliationService: reconciliationService,
 		},
 	}
 }
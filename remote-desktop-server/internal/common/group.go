@@ -0,0 +1,51 @@
+// Package common содержит общие структуры данных и константы для всего приложения.
+// Включает DTO (Data Transfer Objects) для запросов/ответов API и базовые модели.
+package common
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserGroupRequest представляет запрос на создание или изменение группы пользователей
+type UserGroupRequest struct {
+	Name        string `json:"name" validate:"required,min=2,max=128"`   // Название группы (имя группы в Guacamole)
+	Description string `json:"description" validate:"omitempty,max=255"` // Описание группы
+}
+
+// UserGroupMemberRequest представляет запрос на добавление пользователя в группу
+type UserGroupMemberRequest struct {
+	Email string `json:"email" validate:"required,email,max=255"` // Email пользователя
+}
+
+// UserSubgroupRequest представляет запрос на вложение группы в другую группу
+type UserSubgroupRequest struct {
+	GroupID string `json:"group_id" validate:"required,uuid"` // Идентификатор вкладываемой группы
+}
+
+// UserGroup представляет группу пользователей.
+// Группа зеркалируется в Guacamole как сущность USER_GROUP с тем же названием.
+type UserGroup struct {
+	ID           uuid.UUID `json:"id"`            // Идентификатор группы
+	Name         string    `json:"name"`          // Название группы
+	Description  string    `json:"description"`   // Описание группы
+	MembersCount int       `json:"members_count"` // Количество пользователей в группе (без вложенных групп)
+	CreatedAt    time.Time `json:"created_at"`    // Время создания
+	UpdatedAt    time.Time `json:"updated_at"`    // Время изменения
+}
+
+// UserGroupMember представляет пользователя, входящего в группу
+type UserGroupMember struct {
+	ID      uuid.UUID `json:"id"`       // Идентификатор пользователя
+	Name    string    `json:"name"`     // Имя пользователя
+	Email   string    `json:"email"`    // Email пользователя (имя пользователя в Guacamole)
+	AddedAt time.Time `json:"added_at"` // Время добавления в группу
+}
+
+// UserGroupDetails представляет группу пользователей вместе с участниками и вложенными группами
+type UserGroupDetails struct {
+	UserGroup
+	Members   []*UserGroupMember `json:"members"`   // Пользователи группы
+	Subgroups []*UserGroup       `json:"subgroups"` // Вложенные группы (их участники наследуют доступ группы)
+}
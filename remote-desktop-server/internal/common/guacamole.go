@@ -2,46 +2,279 @@
 // Включает DTO (Data Transfer Objects) для запросов/ответов API и базовые модели.
 package common
 
+import "time"
+
+// Протоколы подключений, поддерживаемые Guacamole
+const (
+	ProtocolSSH        = "ssh"        // SSH протокол подключения
+	ProtocolRDP        = "rdp"        // RDP протокол подключения
+	ProtocolVNC        = "vnc"        // VNC протокол подключения
+	ProtocolTelnet     = "telnet"     // Telnet протокол подключения
+	ProtocolKubernetes = "kubernetes" // Подключение к контейнеру пода Kubernetes
+)
+
+// Protocols содержит список всех поддерживаемых протоколов подключений
+var Protocols = []string{
+	ProtocolSSH,
+	ProtocolRDP,
+	ProtocolVNC,
+	ProtocolTelnet,
+	ProtocolKubernetes,
+}
+
+// IsSupportedProtocol проверяет, поддерживается ли указанный протокол
+func IsSupportedProtocol(protocol string) bool {
+	for _, p := range Protocols {
+		if p == protocol {
+			return true
+		}
+	}
+	return false
+}
+
 type GuacamoleUser struct {
 	ID          uint64   `json:"id"`          // Уникальный идентификатор пользователя
 	Username    string   `json:"username"`    // Логин пользователя
 	PasswordHex string   `json:"-"`           // Хеш пароля (не сериализуется в JSON)
 	SaultHex    string   `json:"-"`           // Соль для пароля (не сериализуется в JSON)
 	Permissions []string `json:"permissions"` // Список разрешений пользователя
+	Disabled    bool     `json:"disabled"`    // Учетная запись отключена (до подтверждения электронной почты)
+}
+
+// GuacamolePassword представляет пароль пользователя Guacamole в виде, хранимом в guacamole_user
+type GuacamolePassword struct {
+	HashHex string // SHA-256 от пароля с солью в шестнадцатеричном виде
+	SaltHex string // Соль в шестнадцатеричном виде (пустая строка — без соли)
+}
+
+// GuacamoleAccount представляет пользователя Guacamole для сверки с пользователями приложения
+type GuacamoleAccount struct {
+	EntityID      uint64 `json:"entity_id"`     // Идентификатор сущности
+	Username      string `json:"username"`      // Имя пользователя (email)
+	HasUser       bool   `json:"has_user"`      // Есть ли учетная запись guacamole_user у сущности
+	Disabled      bool   `json:"disabled"`      // Отключена ли учетная запись
+	Expired       bool   `json:"expired"`       // Истек ли пароль (при входе Guacamole требует сменить пароль)
+	Administrator bool   `json:"administrator"` // Есть ли у пользователя системное разрешение ADMINISTER
 }
 
 type GuacamoleConnectionRequest struct {
-	Id         string `json:"identifier,omitempty"`                        // Идентификатор подключения (опциональный)
-	Name       string `json:"name" validate:"required,min=4,max=255"`      // Название подключения
-	HostName   string `json:"host_name" validate:"required,min=4,max=255"` // IP-адрес или URL хоста
-	Username   string `json:"username" validate:"required,min=4,max=255"`  // Имя пользователя
-	Password   string `json:"password" validate:"required,min=4,max=255"`  // Пароль
-	IgnoreCert string `json:"-"`                                           // Игнорировать сертификат (не сериализуется)
-	Port       string `json:"port" validate:"required,min=2,max=255"`      // Номер порта
-	Protocol   string `json:"protocol" validate:"required,min=2,max=255"`  // Протокол подключения
+	Id               string                `json:"identifier,omitempty"`                                                          // Идентификатор подключения (опциональный)
+	Name             string                `json:"name" validate:"required,min=4,max=255"`                                        // Название подключения
+	HostName         string                `json:"host_name" validate:"required,min=4,max=255"`                                   // IP-адрес или URL хоста
+	Username         string                `json:"username" validate:"required_if=Protocol ssh,required_if=Protocol rdp,max=255"` // Имя пользователя (обязательно для SSH и RDP)
+	Password         string                `json:"password" validate:"required_if=Protocol rdp,max=255"`                          // Пароль (обязателен для RDP, для SSH может использоваться ключ)
+	IgnoreCert       string                `json:"-"`                                                                             // Игнорировать сертификат (не сериализуется)
+	Port             string                `json:"port" validate:"required,min=2,max=255"`                                        // Номер порта
+	Protocol         string                `json:"protocol" validate:"required,oneof=ssh rdp vnc telnet kubernetes"`              // Протокол подключения
+	ParentIdentifier string                `json:"parent_identifier,omitempty" validate:"omitempty,max=128"`                      // Идентификатор группы подключений (по умолчанию "ROOT")
+	RDP              *RDPParameters        `json:"rdp,omitempty"`                                                                 // Параметры RDP подключения
+	SSH              *SSHParameters        `json:"ssh,omitempty"`                                                                 // Параметры SSH подключения
+	VNC              *VNCParameters        `json:"vnc,omitempty"`                                                                 // Параметры VNC подключения
+	Telnet           *TelnetParameters     `json:"telnet,omitempty"`                                                              // Параметры Telnet подключения
+	Kubernetes       *KubernetesParameters `json:"kubernetes,omitempty" validate:"required_if=Protocol kubernetes"`               // Параметры подключения к Kubernetes
+	Attributes       *ConnectionAttributes `json:"attributes,omitempty"`                                                          // Атрибуты подключения (ограничения и балансировка)
+	Recording        *RecordingSettings    `json:"recording,omitempty"`                                                           // Настройки записи сессий
+	Parameters       map[string]string     `json:"parameters,omitempty" validate:"guacamole_parameters,dive,max=16384"`           // Дополнительные параметры Guacamole (только из списка разрешенных для протокола)
 }
 
-type Parameters struct {
-	HostName   string `json:"hostname"`    // Хост для подключения
-	Username   string `json:"username"`    // Имя пользователя
-	Password   string `json:"password"`    // Пароль
-	IgnoreCert string `json:"ignore-cert"` // Флаг игнорирования сертификата
-	Port       string `json:"port"`        // Номер порта
+// ConnectionAttributes содержит атрибуты подключения Guacamole, не зависящие от протокола.
+// Вес и режим резерва используются, когда подключение входит в группу балансировки.
+type ConnectionAttributes struct {
+	MaxConnections        int  `json:"max_connections,omitempty" guac:"max-connections" validate:"omitempty,gte=0"`                   // Максимальное число одновременных подключений
+	MaxConnectionsPerUser int  `json:"max_connections_per_user,omitempty" guac:"max-connections-per-user" validate:"omitempty,gte=0"` // Максимальное число подключений одного пользователя
+	Weight                int  `json:"weight,omitempty" guac:"weight" validate:"omitempty,gte=1,lte=1000"`                            // Вес подключения в группе балансировки
+	FailoverOnly          bool `json:"failover_only,omitempty" guac:"failover-only"`                                                  // Использовать только при недоступности остальных подключений группы
 }
 
-type Attributes struct{}
+// RDPParameters содержит параметры, специфичные для RDP подключений.
+// Тег guac задает имя параметра в API Guacamole.
+type RDPParameters struct {
+	Domain           string `json:"domain,omitempty" guac:"domain" validate:"omitempty,max=255"`                                      // Домен Windows
+	Security         string `json:"security,omitempty" guac:"security" validate:"omitempty,oneof=any nla nla-ext tls vmconnect rdp"`  // Режим безопасности
+	ResizeMethod     string `json:"resize_method,omitempty" guac:"resize-method" validate:"omitempty,oneof=display-update reconnect"` // Способ изменения размера экрана
+	ServerLayout     string `json:"server_layout,omitempty" guac:"server-layout" validate:"omitempty,max=64"`                         // Раскладка клавиатуры сервера (например, en-us-qwerty)
+	ColorDepth       int    `json:"color_depth,omitempty" guac:"color-depth" validate:"omitempty,oneof=8 16 24 32"`                   // Глубина цвета в битах
+	Width            int    `json:"width,omitempty" guac:"width" validate:"omitempty,gte=0,lte=8192"`                                 // Ширина экрана
+	Height           int    `json:"height,omitempty" guac:"height" validate:"omitempty,gte=0,lte=8192"`                               // Высота экрана
+	DPI              int    `json:"dpi,omitempty" guac:"dpi" validate:"omitempty,gte=0,lte=1000"`                                     // Разрешение экрана в DPI
+	Console          bool   `json:"console,omitempty" guac:"console"`                                                                 // Подключение к консоли сервера
+	InitialProgram   string `json:"initial_program,omitempty" guac:"initial-program" validate:"omitempty,max=255"`                    // Программа, запускаемая при входе
+	EnableDrive      bool   `json:"enable_drive,omitempty" guac:"enable-drive"`                                                       // Проброс виртуального диска
+	DriveName        string `json:"drive_name,omitempty" guac:"drive-name" validate:"omitempty,max=255"`                              // Имя виртуального диска
+	DrivePath        string `json:"drive_path,omitempty" guac:"drive-path" validate:"required_with=EnableDrive,omitempty,max=255"`    // Путь к каталогу диска на сервере guacd (внутри GUAC_DRIVE_ROOT)
+	CreateDrivePath  bool   `json:"create_drive_path,omitempty" guac:"create-drive-path"`                                             // Создать каталог диска автоматически
+	EnablePrinting   bool   `json:"enable_printing,omitempty" guac:"enable-printing"`                                                 // Проброс виртуального принтера
+	PrinterName      string `json:"printer_name,omitempty" guac:"printer-name" validate:"omitempty,max=255"`                          // Имя виртуального принтера
+	DisableAudio     bool   `json:"disable_audio,omitempty" guac:"disable-audio"`                                                     // Отключить воспроизведение звука
+	EnableAudioInput bool   `json:"enable_audio_input,omitempty" guac:"enable-audio-input"`                                           // Включить микрофон
+}
+
+// SSHParameters содержит параметры, специфичные для SSH подключений.
+type SSHParameters struct {
+	PrivateKey          string `json:"private_key,omitempty" guac:"private-key" validate:"omitempty,max=16384"`                 // Закрытый ключ в формате OpenSSH (PEM)
+	Passphrase          string `json:"passphrase,omitempty" guac:"passphrase" validate:"omitempty,max=255"`                     // Пароль закрытого ключа
+	HostKey             string `json:"host_key,omitempty" guac:"host-key" validate:"omitempty,max=4096"`                        // Ожидаемый публичный ключ сервера
+	Command             string `json:"command,omitempty" guac:"command" validate:"omitempty,max=255"`                           // Команда, выполняемая вместо оболочки
+	ColorScheme         string `json:"color_scheme,omitempty" guac:"color-scheme" validate:"omitempty,max=1024"`                // Цветовая схема терминала
+	FontName            string `json:"font_name,omitempty" guac:"font-name" validate:"omitempty,max=255"`                       // Имя шрифта терминала
+	FontSize            int    `json:"font_size,omitempty" guac:"font-size" validate:"omitempty,gte=4,lte=96"`                  // Размер шрифта терминала
+	Scrollback          int    `json:"scrollback,omitempty" guac:"scrollback" validate:"omitempty,gte=0,lte=100000"`            // Количество строк прокрутки
+	TerminalType        string `json:"terminal_type,omitempty" guac:"terminal-type" validate:"omitempty,max=64"`                // Тип терминала (xterm, vt100 и т.д.)
+	ServerAliveInterval int    `json:"server_alive_interval,omitempty" guac:"server-alive-interval" validate:"omitempty,gte=0"` // Интервал keepalive сообщений в секундах
+	EnableSFTP          bool   `json:"enable_sftp,omitempty" guac:"enable-sftp"`                                                // Включить передачу файлов через SFTP
+	SFTPRootDirectory   string `json:"sftp_root_directory,omitempty" guac:"sftp-root-directory" validate:"omitempty,max=255"`   // Корневой каталог SFTP
+}
+
+// VNCParameters содержит параметры, специфичные для VNC подключений.
+// Тег guac задает имя параметра в API Guacamole.
+type VNCParameters struct {
+	ColorDepth  int    `json:"color_depth,omitempty" guac:"color-depth" validate:"omitempty,oneof=8 16 24 32"` // Глубина цвета в битах
+	Cursor      string `json:"cursor,omitempty" guac:"cursor" validate:"omitempty,oneof=local remote"`         // Режим отрисовки курсора
+	ReadOnly    bool   `json:"read_only,omitempty" guac:"read-only"`                                           // Только просмотр без управления
+	SwapRedBlue bool   `json:"swap_red_blue,omitempty" guac:"swap-red-blue"`                                   // Поменять местами красный и синий каналы
+	Encodings   string `json:"encodings,omitempty" guac:"encodings" validate:"omitempty,max=255"`              // Список кодировок через пробел
+	AutoRetry   int    `json:"autoretry,omitempty" guac:"autoretry" validate:"omitempty,gte=0,lte=100"`        // Количество повторных попыток подключения
+}
+
+// TelnetParameters содержит параметры, специфичные для Telnet подключений.
+// Регулярные выражения используются guacd для автоматического входа.
+type TelnetParameters struct {
+	UsernameRegex     string `json:"username_regex,omitempty" guac:"username-regex" validate:"omitempty,max=255"`           // Приглашение ввода имени пользователя
+	PasswordRegex     string `json:"password_regex,omitempty" guac:"password-regex" validate:"omitempty,max=255"`           // Приглашение ввода пароля
+	LoginSuccessRegex string `json:"login_success_regex,omitempty" guac:"login-success-regex" validate:"omitempty,max=255"` // Признак успешного входа
+	LoginFailureRegex string `json:"login_failure_regex,omitempty" guac:"login-failure-regex" validate:"omitempty,max=255"` // Признак неудачного входа
+	TerminalType      string `json:"terminal_type,omitempty" guac:"terminal-type" validate:"omitempty,max=64"`              // Тип терминала (xterm, vt100 и т.д.)
+}
+
+// KubernetesParameters содержит параметры подключения к контейнеру пода Kubernetes.
+// HostName и Port запроса указывают на API сервер кластера.
+type KubernetesParameters struct {
+	Namespace  string `json:"namespace,omitempty" guac:"namespace" validate:"omitempty,max=253"`       // Пространство имен (по умолчанию "default")
+	Pod        string `json:"pod" guac:"pod" validate:"required,max=253"`                              // Имя пода
+	Container  string `json:"container,omitempty" guac:"container" validate:"omitempty,max=253"`       // Имя контейнера (по умолчанию первый контейнер пода)
+	UseSSL     bool   `json:"use_ssl,omitempty" guac:"use-ssl"`                                        // Использовать SSL/TLS при подключении к API
+	IgnoreCert bool   `json:"ignore_cert,omitempty" guac:"ignore-cert"`                                // Игнорировать сертификат API сервера
+	CACert     string `json:"ca_cert,omitempty" guac:"ca-cert"`                                        // Сертификат удостоверяющего центра (PEM)
+	ClientCert string `json:"client_cert,omitempty" guac:"client-cert"`                                // Клиентский сертификат (PEM)
+	ClientKey  string `json:"client_key,omitempty" guac:"client-key"`                                  // Закрытый ключ клиентского сертификата (PEM)
+	ExecCmd    string `json:"exec_command,omitempty" guac:"exec-command" validate:"omitempty,max=255"` // Команда, выполняемая вместо подключения к консоли
+}
+
+// Parameters содержит общие для всех протоколов параметры подключения Guacamole
+type Parameters struct {
+	HostName   string `guac:"hostname"`    // Хост для подключения
+	Username   string `guac:"username"`    // Имя пользователя
+	Password   string `guac:"password"`    // Пароль
+	IgnoreCert string `guac:"ignore-cert"` // Флаг игнорирования сертификата
+	Port       string `guac:"port"`        // Номер порта
+}
 
 type GuacamoleRDConnectionRequest struct {
-	Id               string              `json:"identifier,omitempty"` // Идентификатор подключения
-	Name             string              `json:"name"`                 // Название подключения
-	Protocol         string              `json:"protocol"`             // Протокол (RDP, SSH и т.д.)
-	ParentIdentifier string              `json:"parentIdentifier"`     // Идентификатор родительской группы (по умолчанию "ROOT")
-	Parameters       `json:"parameters"` // Вложенная структура параметров
-	Attributes       `json:"attributes"` // Вложенная структура атрибутов
+	Id               string            `json:"identifier,omitempty"` // Идентификатор подключения
+	Name             string            `json:"name"`                 // Название подключения
+	Protocol         string            `json:"protocol"`             // Протокол (RDP, SSH и т.д.)
+	ParentIdentifier string            `json:"parentIdentifier"`     // Идентификатор родительской группы (по умолчанию "ROOT")
+	Parameters       map[string]string `json:"parameters"`           // Параметры подключения в формате Guacamole
+	Attributes       map[string]string `json:"attributes"`           // Атрибуты подключения
 }
 
 type GuacamoleRDConnectionResponse struct {
-	ID       string `json:"identifier"` // Идентификатор подключения
-	Name     string `json:"name"`       // Название подключения
-	Protocol string `json:"protocol"`   // Тип протокола
+	ID               string `json:"identifier"`                 // Идентификатор подключения
+	Name             string `json:"name"`                       // Название подключения
+	Protocol         string `json:"protocol"`                   // Тип протокола
+	ParentIdentifier string `json:"parentIdentifier,omitempty"` // Идентификатор родительской группы
+}
+
+// MoveConnectionRequest представляет запрос на перемещение подключения в другую группу
+type MoveConnectionRequest struct {
+	ParentIdentifier string `json:"parent_identifier" validate:"required,max=128"` // Идентификатор новой родительской группы ("ROOT" для корня)
+}
+
+// Типы групп подключений Guacamole
+const (
+	ConnectionGroupOrganizational = "ORGANIZATIONAL" // Папка для упорядочивания подключений
+	ConnectionGroupBalancing      = "BALANCING"      // Группа балансировки между равнозначными подключениями
+)
+
+// RootConnectionGroup идентификатор корневой группы подключений Guacamole
+const RootConnectionGroup = "ROOT"
+
+// GuacamoleConnectionGroupRequest представляет запрос на создание или обновление группы подключений.
+// Поля с тегом guac передаются в Guacamole как атрибуты группы.
+type GuacamoleConnectionGroupRequest struct {
+	Id                    string `json:"identifier,omitempty"`                                                                          // Идентификатор группы (опциональный)
+	Name                  string `json:"name" validate:"required,min=1,max=128"`                                                        // Название группы
+	ParentIdentifier      string `json:"parent_identifier,omitempty" validate:"omitempty,max=128"`                                      // Идентификатор родительской группы (по умолчанию "ROOT")
+	Type                  string `json:"type" validate:"required,oneof=ORGANIZATIONAL BALANCING"`                                       // Тип группы
+	MaxConnections        int    `json:"max_connections,omitempty" guac:"max-connections" validate:"omitempty,gte=0"`                   // Максимальное число одновременных подключений
+	MaxConnectionsPerUser int    `json:"max_connections_per_user,omitempty" guac:"max-connections-per-user" validate:"omitempty,gte=0"` // Максимальное число подключений одного пользователя
+	EnableSessionAffinity bool   `json:"enable_session_affinity,omitempty" guac:"enable-session-affinity"`                              // Закреплять пользователя за выбранным подключением группы
+}
+
+// GuacamoleConnectionGroup представляет группу подключений в формате API Guacamole.
+// Используется как для запросов к Guacamole, так и для возврата дерева подключений.
+type GuacamoleConnectionGroup struct {
+	Id                    string                           `json:"identifier,omitempty"`            // Идентификатор группы
+	Name                  string                           `json:"name"`                            // Название группы
+	ParentIdentifier      string                           `json:"parentIdentifier,omitempty"`      // Идентификатор родительской группы
+	Type                  string                           `json:"type"`                            // Тип группы (ORGANIZATIONAL, BALANCING)
+	ActiveConnections     int                              `json:"activeConnections"`               // Количество активных подключений
+	Attributes            map[string]string                `json:"attributes"`                      // Атрибуты группы
+	ChildConnections      []*GuacamoleRDConnectionResponse `json:"childConnections,omitempty"`      // Подключения группы
+	ChildConnectionGroups []*GuacamoleConnectionGroup      `json:"childConnectionGroups,omitempty"` // Вложенные группы
+}
+
+// BalancingGroupRequest представляет запрос на создание группы балансировки
+// из набора равнозначных подключений (например, jump-хостов).
+type BalancingGroupRequest struct {
+	Name                  string                        `json:"name" validate:"required,min=1,max=128"`                   // Название группы
+	ParentIdentifier      string                        `json:"parent_identifier,omitempty" validate:"omitempty,max=128"` // Идентификатор родительской группы (по умолчанию "ROOT")
+	EnableSessionAffinity bool                          `json:"enable_session_affinity,omitempty"`                        // Закреплять пользователя за выбранным подключением
+	Members               []*GuacamoleConnectionRequest `json:"members" validate:"required,min=1,dive,required"`          // Подключения, входящие в группу
+}
+
+// BalancingMemberHealth описывает результат проверки доступности участника группы балансировки
+type BalancingMemberHealth struct {
+	ConnectionID        string    `json:"identifier"`           // Идентификатор подключения
+	Name                string    `json:"name"`                 // Название подключения
+	Address             string    `json:"address"`              // Адрес host:port, по которому выполнялась проверка
+	Reachable           bool      `json:"reachable"`            // Доступен ли порт участника по TCP
+	LatencyMs           int64     `json:"latency_ms"`           // Время установки TCP соединения в миллисекундах
+	ConsecutiveFailures int       `json:"consecutive_failures"` // Количество неудачных проверок подряд
+	Disabled            bool      `json:"disabled"`             // Отключен ли участник (вес 0)
+	Error               string    `json:"error,omitempty"`      // Текст ошибки последней проверки
+	CheckedAt           time.Time `json:"checked_at"`           // Время последней проверки
+}
+
+// BalancingGroupHealth описывает состояние всех участников группы балансировки
+type BalancingGroupHealth struct {
+	Id      string                   `json:"identifier"` // Идентификатор группы
+	Name    string                   `json:"name"`       // Название группы
+	Members []*BalancingMemberHealth `json:"members"`    // Состояние участников
+}
+
+// GuacamoleActiveConnection представляет активное подключение (туннель) в формате API Guacamole
+type GuacamoleActiveConnection struct {
+	Identifier           string `json:"identifier"`           // Идентификатор активного подключения
+	ConnectionIdentifier string `json:"connectionIdentifier"` // Идентификатор подключения
+	StartDate            int64  `json:"startDate"`            // Время начала в миллисекундах Unix
+	RemoteHost           string `json:"remoteHost"`           // Адрес клиента, открывшего подключение
+	Username             string `json:"username"`             // Имя пользователя Guacamole
+	Connectable          bool   `json:"connectable"`          // Можно ли присоединиться к подключению
+}
+
+// ActiveConnectionResponse представляет активное подключение в ответе API
+type ActiveConnectionResponse struct {
+	ID             string    `json:"identifier"`      // Идентификатор активного подключения
+	ConnectionID   string    `json:"connection_id"`   // Идентификатор подключения
+	ConnectionName string    `json:"connection_name"` // Название подключения
+	Username       string    `json:"username"`        // Пользователь, открывший подключение
+	RemoteHost     string    `json:"remote_host"`     // Адрес клиента
+	StartedAt      time.Time `json:"started_at"`      // Время начала подключения
+}
+
+// GuacamolePermissions представляет набор разрешений пользователя в формате API Guacamole
+type GuacamolePermissions struct {
+	SystemPermissions     []string            `json:"systemPermissions"`     // Системные разрешения (ADMINISTER, CREATE_CONNECTION и т.д.)
+	ConnectionPermissions map[string][]string `json:"connectionPermissions"` // Разрешения на подключения по идентификатору
 }
@@ -0,0 +1,154 @@
+// Package common содержит общие структуры данных и константы для всего приложения.
+// Включает DTO (Data Transfer Objects) для запросов/ответов API и базовые модели.
+package common
+
+// Группы параметров Guacamole, общие для нескольких протоколов.
+// Имена соответствуют документации Apache Guacamole (раздел "Configuring connections").
+var (
+	// Параметры записи сессий. Задаются только настройками записи (RecordingSettings):
+	// каталог записей определяет сервер, поэтому в дополнительных параметрах они не принимаются
+	recordingParameters = []string{
+		"recording-path",
+		"recording-name",
+		"recording-exclude-output",
+		"recording-exclude-mouse",
+		"recording-exclude-touch",
+		"recording-include-keys",
+		"create-recording-path",
+	}
+	// Параметры записи терминала в формате typescript (задаются только настройками записи)
+	typescriptParameters = []string{
+		"typescript-path",
+		"typescript-name",
+		"create-typescript-path",
+	}
+	// Параметры передачи файлов через SFTP
+	sftpParameters = []string{
+		"enable-sftp",
+		"sftp-hostname",
+		"sftp-host-key",
+		"sftp-port",
+		"sftp-username",
+		"sftp-password",
+		"sftp-private-key",
+		"sftp-passphrase",
+		"sftp-directory",
+		"sftp-root-directory",
+		"sftp-server-alive-interval",
+		"sftp-disable-download",
+		"sftp-disable-upload",
+	}
+	// Параметры отображения терминала
+	terminalParameters = []string{
+		"color-scheme",
+		"font-name",
+		"font-size",
+		"scrollback",
+		"backspace",
+		"terminal-type",
+	}
+	// Параметры буфера обмена и режима только для чтения
+	clipboardParameters = []string{
+		"read-only",
+		"disable-copy",
+		"disable-paste",
+	}
+	// Параметры Wake-on-LAN
+	wolParameters = []string{
+		"wol-send-packet",
+		"wol-mac-addr",
+		"wol-broadcast-addr",
+		"wol-udp-port",
+		"wol-wait-time",
+	}
+)
+
+// guacamoleParameters содержит список дополнительных параметров Guacamole, разрешенных для каждого протокола.
+// Общие параметры (hostname, port, username, password) задаются полями формы и в список не входят.
+// Параметры с путями на сервере guacd (recording-path, typescript-path, drive-path и create-*-path)
+// в список не входят: каталог записей формирует сервер, а каталог диска задается типизированным
+// полем и ограничивается GUAC_DRIVE_ROOT.
+var guacamoleParameters = map[string][]string{
+	ProtocolRDP: join(
+		[]string{
+			"domain", "security", "ignore-cert", "cert-tofu", "cert-fingerprints", "disable-auth",
+			"gateway-hostname", "gateway-port", "gateway-username", "gateway-password", "gateway-domain",
+			"initial-program", "client-name", "server-layout", "timezone", "enable-touch", "console",
+			"width", "height", "dpi", "color-depth", "resize-method", "force-lossless", "normalize-clipboard",
+			"console-audio", "disable-audio", "enable-audio-input", "enable-printing", "printer-name",
+			"enable-drive", "drive-name", "create-drive-path", "disable-download", "disable-upload",
+			"static-channels", "enable-wallpaper", "enable-theming", "enable-font-smoothing",
+			"enable-full-window-drag", "enable-desktop-composition", "enable-menu-animations",
+			"disable-bitmap-caching", "disable-offscreen-caching", "disable-glyph-caching",
+			"preconnection-id", "preconnection-blob", "load-balance-info",
+			"remote-app", "remote-app-dir", "remote-app-args",
+		},
+		sftpParameters, clipboardParameters, wolParameters,
+	),
+	ProtocolSSH: join(
+		[]string{
+			"host-key", "server-alive-interval", "private-key", "passphrase", "command",
+			"locale", "timezone", "clipboard-encoding",
+		},
+		terminalParameters, sftpParameters, clipboardParameters, wolParameters,
+	),
+	ProtocolVNC: join(
+		[]string{
+			"autoretry", "color-depth", "swap-red-blue", "cursor", "encodings", "force-lossless",
+			"compress-level", "quality-level", "dest-host", "dest-port", "reverse-connect", "listen-timeout",
+			"enable-audio", "audio-servername", "clipboard-encoding", "disable-display-resize",
+		},
+		sftpParameters, clipboardParameters, wolParameters,
+	),
+	ProtocolTelnet: join(
+		[]string{
+			"username-regex", "password-regex", "login-success-regex", "login-failure-regex",
+		},
+		terminalParameters, clipboardParameters, wolParameters,
+	),
+	ProtocolKubernetes: join(
+		[]string{
+			"namespace", "pod", "container", "exec-command", "use-ssl",
+			"client-cert", "client-key", "ca-cert", "ignore-cert",
+		},
+		terminalParameters, clipboardParameters,
+	),
+}
+
+// IsAllowedParameter проверяет, может ли параметр Guacamole быть задан для указанного протокола
+func IsAllowedParameter(protocol string, name string) bool {
+	for _, parameter := range guacamoleParameters[protocol] {
+		if parameter == name {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRecordingParameter проверяет, относится ли параметр Guacamole к записи сессий или терминала
+func IsRecordingParameter(name string) bool {
+	for _, parameter := range join(recordingParameters, typescriptParameters) {
+		if parameter == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SupportsTypescript проверяет, поддерживает ли протокол запись терминала в формате typescript
+func SupportsTypescript(protocol string) bool {
+	switch protocol {
+	case ProtocolSSH, ProtocolTelnet, ProtocolKubernetes:
+		return true
+	}
+	return false
+}
+
+// join объединяет несколько списков параметров в один
+func join(groups ...[]string) []string {
+	result := make([]string, 0)
+	for _, group := range groups {
+		result = append(result, group...)
+	}
+	return result
+}
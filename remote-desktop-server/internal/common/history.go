@@ -0,0 +1,61 @@
+// Package common содержит общие структуры данных и константы для всего приложения.
+// Включает DTO (Data Transfer Objects) для запросов/ответов API и базовые модели.
+package common
+
+import "time"
+
+// HistoryFilter содержит параметры выборки истории подключений
+// Поля:
+//   - ConnectionID: идентификатор подключения (пустой — все подключения)
+//   - Username: имя пользователя Guacamole (пустой — все пользователи)
+//   - From: начало периода (включительно)
+//   - To: конец периода (не включительно)
+//   - Page: номер страницы, начиная с 1
+//   - PerPage: количество записей на странице
+//   - Limit: ограничение количества строк отчета
+type HistoryFilter struct {
+	ConnectionID string     `json:"connection_id" validate:"omitempty,numeric"`
+	Username     string     `json:"username" validate:"omitempty,max=128"`
+	From         *time.Time `json:"from"`
+	To           *time.Time `json:"to"`
+	Page         int        `json:"page" validate:"gte=1"`
+	PerPage      int        `json:"per_page" validate:"gte=1,lte=500"`
+	Limit        int        `json:"limit" validate:"gte=1,lte=1000"`
+}
+
+// ConnectionHistoryRecord представляет запись истории подключений Guacamole
+type ConnectionHistoryRecord struct {
+	ID              int64      `json:"id"`               // Идентификатор записи
+	ConnectionID    string     `json:"connection_id"`    // Идентификатор подключения (пустой, если подключение удалено)
+	ConnectionName  string     `json:"connection_name"`  // Название подключения на момент использования
+	Username        string     `json:"username"`         // Пользователь, открывший подключение
+	RemoteHost      string     `json:"remote_host"`      // Адрес клиента
+	StartDate       time.Time  `json:"start_date"`       // Время начала
+	EndDate         *time.Time `json:"end_date"`         // Время окончания (nil, если подключение активно)
+	DurationSeconds int64      `json:"duration_seconds"` // Длительность в секундах
+	Active          bool       `json:"active"`           // Подключение еще не завершено
+}
+
+// HistoryPage представляет страницу истории подключений
+type HistoryPage struct {
+	Items   []*ConnectionHistoryRecord `json:"items"`    // Записи страницы
+	Total   int                        `json:"total"`    // Общее количество записей
+	Page    int                        `json:"page"`     // Номер страницы
+	PerPage int                        `json:"per_page"` // Размер страницы
+}
+
+// UserWeeklyUsage представляет суммарное время подключений пользователя за неделю
+type UserWeeklyUsage struct {
+	Username  string    `json:"username"`   // Пользователь
+	WeekStart time.Time `json:"week_start"` // Понедельник недели
+	Sessions  int       `json:"sessions"`   // Количество подключений
+	Hours     float64   `json:"hours"`      // Суммарное время в часах
+}
+
+// HostUsage представляет статистику использования хоста
+type HostUsage struct {
+	HostName string  `json:"host_name"` // Хост (или название подключения, если хост неизвестен)
+	Sessions int     `json:"sessions"`  // Количество подключений
+	Users    int     `json:"users"`     // Количество различных пользователей
+	Hours    float64 `json:"hours"`     // Суммарное время в часах
+}
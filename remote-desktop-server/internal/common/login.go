@@ -0,0 +1,127 @@
+// Package common содержит общие структуры данных и константы для всего приложения.
+// Включает DTO (Data Transfer Objects) для запросов/ответов API и базовые модели.
+package common
+
+import "time"
+
+// Объекты, для которых учитываются неудачные попытки входа
+const (
+	LoginScopeAccount = "account" // Учетная запись (email в нижнем регистре)
+	LoginScopeIP      = "ip"      // Адрес клиента
+)
+
+// События журнала входа по паролю
+const (
+	LoginEventSucceeded = "succeeded" // Учетные данные верны
+	LoginEventFailed    = "failed"    // Неверный пароль, неизвестный пользователь или неверный код второго шага
+	LoginEventThrottled = "throttled" // Попытка отклонена без проверки пароля: задержка или блокировка
+	LoginEventLocked    = "locked"    // Учетная запись или адрес временно заблокированы
+	LoginEventUnlocked  = "unlocked"  // Блокировка снята администратором
+)
+
+// LoginFailurePolicy содержит правила подсчета неудачных попыток входа
+type LoginFailurePolicy struct {
+	Window      time.Duration // Период, после которого без новых неудач счетчик начинается заново
+	MaxFailures int           // Неудач до временной блокировки (0 — не блокировать)
+	Lockout     time.Duration // Длительность блокировки
+	DelayBase   time.Duration // Задержка после первой неудачи, удваиваемая с каждой следующей
+	DelayMax    time.Duration // Наибольшая задержка между попытками (0 — без ограничения)
+}
+
+// Delay возвращает задержку после failures неудачных попыток подряд: DelayBase,
+// удваиваемая с каждой следующей неудачей, но не больше DelayMax
+func (policy LoginFailurePolicy) Delay(failures int) time.Duration {
+	delay := policy.DelayBase
+	if failures <= 0 || delay <= 0 {
+		return 0
+	}
+	for i := 1; i < failures && (policy.DelayMax <= 0 || delay < policy.DelayMax); i++ {
+		delay *= 2
+	}
+	if policy.DelayMax > 0 && delay > policy.DelayMax {
+		return policy.DelayMax
+	}
+	return delay
+}
+
+// Wait возвращает, сколько осталось ждать до следующей попытки после неудач throttle (nil — неудач не было),
+// и действует ли блокировка
+func (policy LoginFailurePolicy) Wait(throttle *LoginThrottle, now time.Time) (time.Duration, bool) {
+	if throttle == nil || throttle.Failures <= 0 {
+		return 0, false
+	}
+	if throttle.LockedUntil != nil {
+		if throttle.LockedUntil.After(now) {
+			return throttle.LockedUntil.Sub(now), true
+		}
+		// Блокировка закончилась: следующая неудача начнет счетчик заново
+		return 0, false
+	}
+	if throttle.LastFailedAt.Before(now.Add(-policy.Window)) {
+		return 0, false
+	}
+	next := throttle.LastFailedAt.Add(policy.Delay(throttle.Failures))
+	if !next.After(now) {
+		return 0, false
+	}
+	return next.Sub(now), false
+}
+
+// LoginThrottle представляет неудачные попытки входа в учетную запись или с адреса
+type LoginThrottle struct {
+	Scope        string     `json:"scope"`                  // account или ip
+	Key          string     `json:"key"`                    // Email или адрес клиента
+	Failures     int        `json:"failures"`               // Неудачных попыток подряд
+	LastFailedAt time.Time  `json:"last_failed_at"`         // Время последней неудачной попытки
+	LockedUntil  *time.Time `json:"locked_until,omitempty"` // Время окончания блокировки
+}
+
+// LoginReservation представляет попытку входа, учтенную как неудачная до проверки учетных данных.
+// Пока учетные данные проверяются, одновременные попытки видят увеличенный счетчик и ждут задержку;
+// если попытка оказалась удачной, учет снимается (LoginAttemptRepository.Release).
+type LoginReservation struct {
+	Throttle   *LoginThrottle // Неудачные попытки с учетом этой попытки (если попытка отклонена — без изменений)
+	Previous   *LoginThrottle // Неудачные попытки до этой попытки (nil — их не было)
+	RetryAfter time.Duration  // Сколько ждать до следующей попытки, если эта отклонена (0 — попытка учтена)
+	Locked     bool           // Попытка отклонена из-за блокировки (иначе — из-за задержки между попытками)
+}
+
+// LoginUnlockRequest представляет запрос администратора на снятие блокировки входа
+type LoginUnlockRequest struct {
+	Scope string `json:"scope" validate:"required,oneof=account ip"` // account или ip
+	Key   string `json:"key" validate:"required,max=255"`            // Email или адрес клиента
+}
+
+// LoginAuditEntry представляет запись журнала входа по паролю
+type LoginAuditEntry struct {
+	ID         int64     `json:"id"`               // Идентификатор записи
+	Event      string    `json:"event"`            // Событие (LoginEvent*)
+	Email      string    `json:"email"`            // Email, указанный при входе (для блокировки адреса — пустой)
+	RemoteAddr string    `json:"remote_addr"`      // Адрес клиента
+	Actor      string    `json:"actor,omitempty"`  // Администратор, снявший блокировку
+	Detail     string    `json:"detail,omitempty"` // Подробности (причина отказа, время окончания блокировки)
+	CreatedAt  time.Time `json:"created_at"`       // Время события
+}
+
+// LoginAuditFilter содержит параметры выборки журнала входа
+// Поля:
+//   - Email: email (точное совпадение без учета регистра)
+//   - RemoteAddr: адрес клиента
+//   - Event: событие (пустое — все события)
+//   - Page: номер страницы, начиная с 1
+//   - PerPage: количество записей на странице
+type LoginAuditFilter struct {
+	Email      string `json:"email" validate:"omitempty,max=255"`
+	RemoteAddr string `json:"remote_addr" validate:"omitempty,max=255"`
+	Event      string `json:"event" validate:"omitempty,oneof=succeeded failed throttled locked unlocked"`
+	Page       int    `json:"page" validate:"gte=1"`
+	PerPage    int    `json:"per_page" validate:"gte=1,lte=500"`
+}
+
+// LoginAuditPage представляет страницу журнала входа
+type LoginAuditPage struct {
+	Items   []*LoginAuditEntry `json:"items"`    // Записи страницы, новые первыми
+	Total   int                `json:"total"`    // Общее количество записей, удовлетворяющих фильтру
+	Page    int                `json:"page"`     // Номер страницы
+	PerPage int                `json:"per_page"` // Размер страницы
+}
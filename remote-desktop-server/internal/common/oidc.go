@@ -0,0 +1,38 @@
+// Package common содержит общие структуры данных и константы для всего приложения.
+// Включает DTO (Data Transfer Objects) для запросов/ответов API и базовые модели.
+package common
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OIDCLoginState представляет незавершенный вход через провайдера OpenID Connect
+// (в базе хранится хэш параметра state).
+// Поля:
+//   - ID: идентификатор
+//   - CodeVerifier: code_verifier PKCE
+//   - Nonce: значение, которое провайдер должен вернуть в ID токене
+//   - ExpiresAt: время истечения
+type OIDCLoginState struct {
+	ID           uuid.UUID
+	CodeVerifier string
+	Nonce        string
+	ExpiresAt    time.Time
+}
+
+// OIDCIdentity представляет связь учетной записи провайдера OpenID Connect с пользователем.
+// Учетная запись провайдера определяется парой issuer и sub, а не электронной почтой,
+// которую пользователь может изменить у провайдера.
+// Поля:
+//   - Issuer: провайдер (утверждение iss)
+//   - Subject: идентификатор пользователя у провайдера (утверждение sub)
+//   - UserID: связанный пользователь
+//   - CreatedAt: время связывания
+type OIDCIdentity struct {
+	Issuer    string
+	Subject   string
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
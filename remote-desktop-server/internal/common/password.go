@@ -0,0 +1,45 @@
+// Package common содержит общие структуры данных и константы для всего приложения.
+// Включает DTO (Data Transfer Objects) для запросов/ответов API и базовые модели.
+package common
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PasswordChangeRequest представляет запрос на смену пароля текущего пользователя.
+// Поля:
+//   - CurrentPassword: действующий пароль (обязательное)
+//   - Password: новый пароль (обязательное, 8-255 символов)
+//   - PasswordConfirmation: подтверждение нового пароля (должно совпадать с Password)
+type PasswordChangeRequest struct {
+	CurrentPassword      string `json:"current_password" validate:"required,max=255"`
+	Password             string `json:"password" validate:"required,min=8,max=255,eqfield=PasswordConfirmation"`
+	PasswordConfirmation string `json:"password_confirmation" validate:"required,min=8,max=255"`
+}
+
+// PasswordForgotRequest представляет запрос на отправку письма для сброса пароля.
+// Поля:
+//   - Email: электронная почта пользователя (обязательное, валидный email)
+type PasswordForgotRequest struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+// PasswordResetRequest представляет запрос на установку нового пароля по токену из письма.
+// Поля:
+//   - Token: токен сброса пароля (обязательное)
+//   - Password: новый пароль (обязательное, 8-255 символов)
+//   - PasswordConfirmation: подтверждение нового пароля (должно совпадать с Password)
+type PasswordResetRequest struct {
+	Token                string `json:"token" validate:"required,max=255"`
+	Password             string `json:"password" validate:"required,min=8,max=255,eqfield=PasswordConfirmation"`
+	PasswordConfirmation string `json:"password_confirmation" validate:"required,min=8,max=255"`
+}
+
+// PasswordResetToken представляет токен сброса пароля (в базе хранится только хэш токена)
+type PasswordResetToken struct {
+	ID        uuid.UUID `json:"id"`         // Идентификатор токена
+	UserID    uuid.UUID `json:"user_id"`    // Пользователь, запросивший сброс
+	ExpiresAt time.Time `json:"expires_at"` // Время истечения токена
+}
@@ -0,0 +1,37 @@
+// Package common содержит общие структуры данных и константы для всего приложения.
+// Включает DTO (Data Transfer Objects) для запросов/ответов API и базовые модели.
+package common
+
+// Типы сущностей Guacamole, которым выдаются разрешения
+const (
+	EntityTypeUser      = "USER"       // Пользователь
+	EntityTypeUserGroup = "USER_GROUP" // Группа пользователей
+)
+
+// Разрешения на объекты Guacamole (тип guacamole_object_permission_type)
+const (
+	PermissionRead       = "READ"       // Просмотр и запуск подключения
+	PermissionUpdate     = "UPDATE"     // Изменение подключения
+	PermissionDelete     = "DELETE"     // Удаление подключения
+	PermissionAdminister = "ADMINISTER" // Управление доступом к подключению
+)
+
+// GuacamoleEntity представляет пользователя или группу Guacamole (таблица guacamole_entity)
+type GuacamoleEntity struct {
+	ID   uint64 `json:"entity_id"` // Идентификатор сущности
+	Name string `json:"name"`      // Имя пользователя (email) или название группы
+	Type string `json:"type"`      // Тип сущности (USER или USER_GROUP)
+}
+
+// ConnectionPermissionRequest представляет запрос на выдачу доступа к подключению
+type ConnectionPermissionRequest struct {
+	Type        string   `json:"type" validate:"required,oneof=USER USER_GROUP"`                                 // Тип получателя
+	Name        string   `json:"name" validate:"required,max=128"`                                               // Email пользователя или название группы
+	Permissions []string `json:"permissions" validate:"required,min=1,dive,oneof=READ UPDATE DELETE ADMINISTER"` // Выдаваемые разрешения
+}
+
+// ConnectionGrant представляет разрешения пользователя или группы на подключение
+type ConnectionGrant struct {
+	GuacamoleEntity
+	Permissions []string `json:"permissions"` // Разрешения на подключение
+}
@@ -0,0 +1,45 @@
+// Package common содержит общие структуры данных и константы для всего приложения.
+// Включает DTO (Data Transfer Objects) для запросов/ответов API и базовые модели.
+package common
+
+import "time"
+
+// ReconciliationOptions определяет, какие расхождения исправляются при сверке
+type ReconciliationOptions struct {
+	DisableDeleted bool `json:"disable_deleted"` // Отключать учетные записи Guacamole удаленных пользователей
+	RepairOrphans  bool `json:"repair_orphans"`  // Удалять учетные записи без пары и помечать удаленными пользователей без учетной записи Guacamole
+}
+
+// ReconciliationReport представляет результат сверки пользователей приложения и Guacamole
+type ReconciliationReport struct {
+	ReconciliationOptions
+	StartedAt           time.Time `json:"started_at"`            // Время начала сверки
+	FinishedAt          time.Time `json:"finished_at"`           // Время окончания сверки
+	IncompleteEntities  []string  `json:"incomplete_entities"`   // Сущности Guacamole без учетной записи guacamole_user
+	OrphanedAccounts    []string  `json:"orphaned_accounts"`     // Учетные записи Guacamole без пользователя приложения
+	MissingAccounts     []string  `json:"missing_accounts"`      // Пользователи приложения без учетной записи Guacamole
+	DeletedUsersEnabled []string  `json:"deleted_users_enabled"` // Удаленные пользователи с включенной учетной записью Guacamole
+	DisabledAccounts    []string  `json:"disabled_accounts"`     // Активные пользователи с отключенной вручную учетной записью Guacamole
+	Repaired            []string  `json:"repaired"`              // Исправленные расхождения
+	Errors              []string  `json:"errors"`                // Ошибки исправления
+}
+
+// HasDrift сообщает, остались ли неисправленные расхождения или ошибки
+func (report *ReconciliationReport) HasDrift() bool {
+	found := len(report.IncompleteEntities) +
+		len(report.OrphanedAccounts) +
+		len(report.MissingAccounts) +
+		len(report.DeletedUsersEnabled) +
+		len(report.DisabledAccounts)
+	return len(report.Errors) > 0 || found > len(report.Repaired)
+}
+
+// ReconciliationStatus представляет состояние фоновой сверки пользователей
+type ReconciliationStatus struct {
+	Enabled   bool                  `json:"enabled"`     // Включена ли фоновая сверка
+	Interval  string                `json:"interval"`    // Интервал между сверками
+	Running   bool                  `json:"running"`     // Выполняется ли сверка сейчас
+	LastRun   *ReconciliationReport `json:"last_run"`    // Результат последней сверки
+	LastError string                `json:"last_error"`  // Ошибка последней сверки
+	NextRunAt *time.Time            `json:"next_run_at"` // Время следующей сверки
+}
@@ -0,0 +1,83 @@
+// Package common содержит общие структуры данных и константы для всего приложения.
+// Включает DTO (Data Transfer Objects) для запросов/ответов API и базовые модели.
+package common
+
+import "time"
+
+// Типы файлов записей сессий
+const (
+	RecordingTypeGuacamole  = "guacamole"         // Запись протокола Guacamole (графическая сессия)
+	RecordingTypeTypescript = "typescript"        // Запись терминала в формате typescript
+	RecordingTypeTiming     = "typescript-timing" // Временные метки записи typescript
+)
+
+// Суффиксы имен файлов typescript, которые guacd добавляет к typescript-name
+const (
+	TypescriptSuffix       = ".typescript"
+	TypescriptTimingSuffix = ".typescript.timing"
+)
+
+// DefaultRecordingName шаблон имени записи по умолчанию.
+// Переменные ${GUAC_*} подставляются Guacamole при начале подключения.
+const DefaultRecordingName = "${GUAC_DATE}-${GUAC_TIME}-${GUAC_USERNAME}"
+
+// RecordingSettings содержит настройки записи сессий подключения.
+// Каталог записи назначается сервером: все записи подключения хранятся в отдельном каталоге.
+type RecordingSettings struct {
+	Enabled       bool   `json:"enabled"`                                                              // Записывать сессии подключения
+	NameTemplate  string `json:"name_template,omitempty" validate:"omitempty,max=255,excludesall=/\\"` // Шаблон имени файла записи
+	IncludeKeys   bool   `json:"include_keys,omitempty"`                                               // Записывать нажатия клавиш
+	Typescript    bool   `json:"typescript,omitempty"`                                                 // Дополнительно записывать терминал в typescript (SSH, Telnet, Kubernetes)
+	RetentionDays int    `json:"retention_days,omitempty" validate:"omitempty,gte=0,lte=3650"`         // Срок хранения записей в днях (0 — срок по умолчанию)
+}
+
+// RecordingPolicy описывает срок хранения записей отдельного подключения
+type RecordingPolicy struct {
+	ConnectionID  string    `json:"connection_id"`  // Идентификатор подключения
+	RetentionDays int       `json:"retention_days"` // Срок хранения записей в днях
+	UpdatedAt     time.Time `json:"updated_at"`     // Время последнего изменения
+}
+
+// Recording описывает файл записи сессии
+type Recording struct {
+	Name       string     `json:"name"`        // Имя файла
+	Type       string     `json:"type"`        // Тип записи (guacamole, typescript, typescript-timing)
+	Size       int64      `json:"size"`        // Размер в байтах
+	ModifiedAt time.Time  `json:"modified_at"` // Время последнего изменения
+	ExpiresAt  *time.Time `json:"expires_at"`  // Время удаления по сроку хранения (nil — бессрочно)
+}
+
+// IndexedRecording описывает запись typescript, команды которой сохранены в индексе поиска
+type IndexedRecording struct {
+	ID            string    `json:"id"`             // Идентификатор записи индекса
+	ConnectionID  string    `json:"connection_id"`  // Идентификатор подключения
+	RecordingName string    `json:"recording_name"` // Имя файла typescript
+	Username      string    `json:"username"`       // Пользователь сессии (по истории подключений)
+	StartedAt     time.Time `json:"started_at"`     // Время начала записи
+	Size          int64     `json:"size"`           // Размер файла на момент индексации
+}
+
+// RecordedCommand представляет команду, найденную в записи терминала
+type RecordedCommand struct {
+	ConnectionID  string    `json:"connection_id"`  // Идентификатор подключения
+	RecordingName string    `json:"recording_name"` // Имя файла typescript
+	Username      string    `json:"username"`       // Пользователь сессии
+	Command       string    `json:"command"`        // Текст команды
+	OffsetMs      int64     `json:"offset_ms"`      // Время от начала записи в миллисекундах
+	ExecutedAt    time.Time `json:"executed_at"`    // Время выполнения
+}
+
+// CommandSearchFilter содержит параметры поиска команд в записях терминала.
+// Период, подключение, пользователь и пагинация задаются так же, как для истории подключений.
+type CommandSearchFilter struct {
+	Query string `json:"q" validate:"required,min=2,max=255"` // Подстрока команды
+	HistoryFilter
+}
+
+// CommandSearchPage представляет страницу результатов поиска команд
+type CommandSearchPage struct {
+	Items   []*RecordedCommand `json:"items"`    // Найденные команды
+	Total   int                `json:"total"`    // Общее количество найденных команд
+	Page    int                `json:"page"`     // Номер страницы
+	PerPage int                `json:"per_page"` // Размер страницы
+}
@@ -0,0 +1,92 @@
+// Package common содержит общие структуры данных и константы для всего приложения.
+// Включает DTO (Data Transfer Objects) для запросов/ответов API и базовые модели.
+package common
+
+// Роли пользователей приложения (users.role)
+const (
+	RoleAdmin    = "admin"    // Администратор: управление пользователями, группами и сервером
+	RoleOperator = "operator" // Оператор: управление подключениями и доступом к ним
+	RoleViewer   = "viewer"   // Наблюдатель: только запуск доступных подключений
+)
+
+// Roles перечисляет роли по возрастанию полномочий
+var Roles = []string{RoleViewer, RoleOperator, RoleAdmin}
+
+// Разрешения приложения, которые проверяет middleware.RequirePermission
+const (
+	PermissionUseConnections    = "connections:use"    // Просмотр и запуск подключений, своя история
+	PermissionManageConnections = "connections:manage" // Создание и изменение подключений, записи, доступ и совместный доступ
+	PermissionManageUsers       = "users:manage"       // Пользователи и группы пользователей
+	PermissionAdministerSystem  = "system:administer"  // Сверка с Guacamole и другие служебные операции
+)
+
+// rolePermissions задает разрешения приложения для каждой роли
+var rolePermissions = map[string][]string{
+	RoleViewer: {
+		PermissionUseConnections,
+	},
+	RoleOperator: {
+		PermissionUseConnections,
+		PermissionManageConnections,
+	},
+	RoleAdmin: {
+		PermissionUseConnections,
+		PermissionManageConnections,
+		PermissionManageUsers,
+		PermissionAdministerSystem,
+	},
+}
+
+// roleGuacamolePermissions задает системные разрешения Guacamole для каждой роли
+var roleGuacamolePermissions = map[string][]string{
+	RoleViewer: {},
+	RoleOperator: {
+		"CREATE_CONNECTION",
+		"CREATE_CONNECTION_GROUP",
+		"CREATE_SHARING_PROFILE",
+	},
+	RoleAdmin: {
+		"ADMINISTER",
+		"CREATE_CONNECTION",
+		"CREATE_CONNECTION_GROUP",
+		"CREATE_SHARING_PROFILE",
+		"CREATE_USER",
+		"CREATE_USER_GROUP",
+	},
+}
+
+// IsValidRole проверяет, что роль существует
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// RoleRank возвращает место роли в Roles (чем больше, тем шире полномочия) или -1 для неизвестной роли
+func RoleRank(role string) int {
+	for i, known := range Roles {
+		if known == role {
+			return i
+		}
+	}
+	return -1
+}
+
+// RoleHasPermission проверяет, есть ли у роли разрешение приложения
+func RoleHasPermission(role string, permission string) bool {
+	for _, granted := range rolePermissions[role] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RolePermissions возвращает разрешения приложения роли
+func RolePermissions(role string) []string {
+	return append([]string(nil), rolePermissions[role]...)
+}
+
+// RoleGuacamolePermissions возвращает системные разрешения Guacamole роли
+func RoleGuacamolePermissions(role string) []string {
+	return append([]string{}, roleGuacamolePermissions[role]...)
+}
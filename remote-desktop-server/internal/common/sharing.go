@@ -0,0 +1,68 @@
+// Package common содержит общие структуры данных и константы для всего приложения.
+// Включает DTO (Data Transfer Objects) для запросов/ответов API и базовые модели.
+package common
+
+import "time"
+
+// SharingProfileRequest представляет запрос на создание профиля совместного доступа к подключению
+type SharingProfileRequest struct {
+	Name     string `json:"name" validate:"required,min=2,max=128"` // Название профиля
+	ReadOnly bool   `json:"read_only"`                              // Только просмотр (иначе — полное управление)
+}
+
+// SharingProfile представляет профиль совместного доступа Guacamole
+type SharingProfile struct {
+	ID           string `json:"id"`            // Идентификатор профиля
+	Name         string `json:"name"`          // Название профиля
+	ConnectionID string `json:"connection_id"` // Идентификатор подключения
+	ReadOnly     bool   `json:"read_only"`     // Только просмотр
+}
+
+// GuacamoleSharingProfile представляет профиль совместного доступа в формате API Guacamole
+type GuacamoleSharingProfile struct {
+	Identifier                  string            `json:"identifier,omitempty"`        // Идентификатор профиля
+	Name                        string            `json:"name"`                        // Название профиля
+	PrimaryConnectionIdentifier string            `json:"primaryConnectionIdentifier"` // Идентификатор подключения
+	Parameters                  map[string]string `json:"parameters,omitempty"`        // Параметры (read-only)
+	Attributes                  map[string]string `json:"attributes"`                  // Атрибуты профиля
+}
+
+// GuacamoleSharingCredentials представляет учетные данные для подключения к активной сессии
+type GuacamoleSharingCredentials struct {
+	Values map[string]string `json:"values"` // Значения учетных данных (ключ совместного доступа в "key")
+}
+
+// ShareLinkRequest представляет запрос на создание ссылки совместного доступа к активной сессии
+type ShareLinkRequest struct {
+	SharingProfileID string `json:"sharing_profile_id" validate:"required,numeric"`          // Профиль совместного доступа
+	ExpiresInMinutes int    `json:"expires_in_minutes" validate:"omitempty,gte=1,lte=10080"` // Время жизни ссылки в минутах (по умолчанию SHARE_LINK_TTL)
+	MaxUses          int    `json:"max_uses" validate:"omitempty,gte=1,lte=1000"`            // Максимальное число переходов (0 — без ограничения)
+	Note             string `json:"note,omitempty" validate:"omitempty,max=255"`             // Комментарий для аудита
+}
+
+// ShareLink представляет ссылку совместного доступа.
+// Сам токен ссылки не хранится: в базе сохраняется только его хэш.
+type ShareLink struct {
+	ID               string     `json:"id"`                 // Идентификатор ссылки
+	ConnectionID     string     `json:"connection_id"`      // Идентификатор подключения
+	SharingProfileID string     `json:"sharing_profile_id"` // Профиль совместного доступа
+	CreatedBy        string     `json:"created_by"`         // Email создателя
+	Note             string     `json:"note,omitempty"`     // Комментарий
+	ExpiresAt        time.Time  `json:"expires_at"`         // Время истечения
+	MaxUses          *int       `json:"max_uses"`           // Максимальное число переходов (nil — без ограничения)
+	Uses             int        `json:"uses"`               // Количество переходов
+	RevokedAt        *time.Time `json:"revoked_at"`         // Время отзыва
+	CreatedAt        time.Time  `json:"created_at"`         // Время создания
+	URL              string     `json:"url,omitempty"`      // Ссылка (возвращается только при создании)
+	TokenHash        string     `json:"-"`                  // SHA-256 хэш токена ссылки
+	ShareCredentials []byte     `json:"-"`                  // Ключ совместного доступа Guacamole, зашифрованный GUAC_CREDENTIALS_KEY
+}
+
+// ShareLinkUse представляет переход по ссылке совместного доступа
+type ShareLinkUse struct {
+	ID         int64     `json:"id"`          // Идентификатор перехода
+	LinkID     string    `json:"link_id"`     // Идентификатор ссылки
+	RemoteAddr string    `json:"remote_addr"` // Адрес клиента
+	UserAgent  string    `json:"user_agent"`  // User-Agent клиента
+	UsedAt     time.Time `json:"used_at"`     // Время перехода
+}
@@ -0,0 +1,123 @@
+// Package common содержит общие структуры данных и константы для всего приложения.
+// Включает DTO (Data Transfer Objects) для запросов/ответов API и базовые модели.
+package common
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TwoFactorCodeRequest представляет запрос с кодом приложения-аутентификатора.
+// Поля:
+//   - Code: шестизначный код TOTP (обязательное)
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required,min=6,max=8"`
+}
+
+// TwoFactorDisableRequest представляет запрос на отключение двухфакторной аутентификации.
+// Поля:
+//   - CurrentPassword: действующий пароль (обязательное)
+//   - Code: код TOTP или код восстановления (обязательное)
+type TwoFactorDisableRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required,max=255"`
+	Code            string `json:"code" validate:"required,min=6,max=32"`
+}
+
+// TwoFactorChallengeRequest представляет второй шаг входа.
+// Поля:
+//   - ChallengeToken: токен, выданный POST /auth/sign-in (обязательное)
+//   - Code: код TOTP (обязательное, если не указан RecoveryCode)
+//   - RecoveryCode: код восстановления (обязательное, если не указан Code)
+type TwoFactorChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required,max=255"`
+	Code           string `json:"code" validate:"required_without=RecoveryCode,omitempty,min=6,max=8"`
+	RecoveryCode   string `json:"recovery_code" validate:"required_without=Code,omitempty,max=32"`
+}
+
+// TwoFactorEnrollRequest представляет запрос на подключение TOTP во время входа,
+// когда двухфакторная аутентификация обязательна, но еще не настроена.
+// Поля:
+//   - ChallengeToken: токен, выданный POST /auth/sign-in (обязательное)
+type TwoFactorEnrollRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required,max=255"`
+}
+
+// TwoFactorEnrollment представляет данные для настройки приложения-аутентификатора.
+// Поля:
+//   - Secret: секрет в base32 для ручного ввода
+//   - ProvisioningURI: URI otpauth:// для QR кода
+type TwoFactorEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// TwoFactorStatus представляет состояние двухфакторной аутентификации пользователя.
+// Поля:
+//   - Enabled: TOTP подключен и подтвержден
+//   - Pending: секрет выдан, но еще не подтвержден кодом
+//   - Required: двухфакторная аутентификация обязательна для пользователя
+//   - RecoveryCodesLeft: количество неиспользованных кодов восстановления
+type TwoFactorStatus struct {
+	Enabled           bool `json:"enabled"`
+	Pending           bool `json:"pending"`
+	Required          bool `json:"required"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// TwoFactorRecoveryCodes представляет новые коды восстановления.
+// Коды показываются один раз, в базе хранятся только их хэши.
+type TwoFactorRecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// AuthChallenge представляет незавершенный вход, ожидающий второй фактор.
+// Поля:
+//   - ChallengeToken: короткоживущий токен для POST /auth/two-factor
+//   - EnrollmentRequired: TOTP обязателен, но не настроен — сначала POST /auth/two-factor/enroll
+//   - ExpiresAt: время истечения токена
+type AuthChallenge struct {
+	ChallengeToken     string    `json:"challenge_token"`
+	EnrollmentRequired bool      `json:"enrollment_required"`
+	ExpiresAt          time.Time `json:"expires_at"`
+}
+
+// AuthSignInResponse представляет результат входа.
+// Если требуется второй фактор, заполнен только TwoFactor, иначе — токены.
+// Поля:
+//   - AuthTokens: токен доступа и refresh токен
+//   - TwoFactor: незавершенный вход, ожидающий код TOTP
+//   - RecoveryCodes: коды восстановления, выданные при подключении TOTP во время входа
+type AuthSignInResponse struct {
+	*AuthTokens
+	TwoFactor     *AuthChallenge `json:"two_factor,omitempty"`
+	RecoveryCodes []string       `json:"recovery_codes,omitempty"`
+}
+
+// UserTOTP представляет секрет TOTP пользователя (в базе хранится зашифрованным).
+// Поля:
+//   - UserID: пользователь
+//   - Secret: зашифрованный секрет
+//   - LastUsedStep: шаг времени последнего принятого кода (защита от повторного использования)
+//   - EnabledAt: время подтверждения (nil — подключение не завершено)
+type UserTOTP struct {
+	UserID       uuid.UUID
+	Secret       []byte
+	LastUsedStep int64
+	EnabledAt    *time.Time
+}
+
+// AuthChallengeRecord представляет сохраненный незавершенный вход (в базе хранится хэш токена).
+// Поля:
+//   - ID: идентификатор
+//   - UserID: пользователь, прошедший проверку пароля
+//   - Credentials: зашифрованный пароль для получения токена Guacamole после второго шага
+//   - Attempts: количество неверных кодов
+//   - ExpiresAt: время истечения
+type AuthChallengeRecord struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Credentials []byte
+	Attempts    int
+	ExpiresAt   time.Time
+}
@@ -0,0 +1,116 @@
+// Package common содержит общие структуры данных и константы для всего приложения.
+// Включает DTO (Data Transfer Objects) для запросов/ответов API и базовые модели.
+package common
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Состояния учетной записи пользователя для фильтра списка пользователей
+const (
+	UserStatusActive   = "active"   // Учетная запись действует
+	UserStatusDisabled = "disabled" // Учетная запись отключена администратором (users.deleted_at)
+)
+
+// UserFilter содержит параметры выборки списка пользователей
+// Поля:
+//   - Search: подстрока имени или email (без учета регистра)
+//   - Role: роль (пустая — все роли)
+//   - Status: active или disabled (пустой — все пользователи)
+//   - Page: номер страницы, начиная с 1
+//   - PerPage: количество пользователей на странице
+type UserFilter struct {
+	Search  string `json:"search" validate:"omitempty,max=255"`
+	Role    string `json:"role" validate:"omitempty,oneof=admin operator viewer"`
+	Status  string `json:"status" validate:"omitempty,oneof=active disabled"`
+	Page    int    `json:"page" validate:"gte=1"`
+	PerPage int    `json:"per_page" validate:"gte=1,lte=500"`
+}
+
+// UserAccount представляет пользователя в списке пользователей администратора
+type UserAccount struct {
+	ID         uuid.UUID  `json:"id"`                    // Идентификатор пользователя
+	Name       string     `json:"name"`                  // Имя
+	Email      string     `json:"email"`                 // Электронная почта
+	Role       string     `json:"role"`                  // Роль
+	External   bool       `json:"external"`              // Пароль Guacamole управляется сервером (вход через OpenID Connect или LDAP)
+	Disabled   bool       `json:"disabled"`              // Учетная запись отключена
+	Verified   bool       `json:"email_verified"`        // Электронная почта подтверждена
+	DisabledAt *time.Time `json:"disabled_at,omitempty"` // Время отключения
+	CreatedAt  time.Time  `json:"created_at"`            // Время регистрации
+}
+
+// UserPage представляет страницу списка пользователей
+type UserPage struct {
+	Items   []*UserAccount `json:"items"`    // Пользователи страницы
+	Total   int            `json:"total"`    // Общее количество пользователей, удовлетворяющих фильтру
+	Page    int            `json:"page"`     // Номер страницы
+	PerPage int            `json:"per_page"` // Размер страницы
+}
+
+// UserDetails представляет пользователя вместе с состоянием его учетной записи Guacamole
+type UserDetails struct {
+	UserAccount
+	Guacamole *GuacamoleAccount `json:"guacamole"` // Учетная запись Guacamole (nil, если не найдена)
+}
+
+// UserConnectionPermission представляет разрешения пользователя на подключение
+type UserConnectionPermission struct {
+	ConnectionID   uint64   `json:"connection_id"`   // Идентификатор подключения
+	ConnectionName string   `json:"connection_name"` // Название подключения
+	Group          string   `json:"group,omitempty"` // Группа, через которую выданы разрешения (пустая — выданы пользователю)
+	Permissions    []string `json:"permissions"`     // Разрешения на подключение
+}
+
+// UserPermissions представляет разрешения пользователя в приложении и в Guacamole
+type UserPermissions struct {
+	Role                  string                      `json:"role"`                   // Роль
+	Permissions           []string                    `json:"permissions"`            // Разрешения приложения, которые дает роль
+	SystemPermissions     []string                    `json:"system_permissions"`     // Действующие системные разрешения Guacamole, включая полученные через группы
+	ConnectionPermissions []*UserConnectionPermission `json:"connection_permissions"` // Разрешения на подключения, включая полученные через группы
+}
+
+// UserRoleRequest представляет запрос на назначение роли пользователю
+type UserRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=admin operator viewer"`
+}
+
+// UserProfileRequest представляет запрос на изменение профиля текущего пользователя.
+// Незаданные поля не изменяются.
+// Поля:
+//   - Name: имя (4-255 символов)
+//   - Email: новая электронная почта; меняется только после перехода по ссылке из письма на новый адрес
+//   - CurrentPassword: действующий пароль (обязателен при смене электронной почты)
+//   - Locale: предпочитаемый язык (ru или en, пустая строка сбрасывает значение)
+//   - Timezone: часовой пояс IANA (пустая строка сбрасывает значение)
+type UserProfileRequest struct {
+	Name            *string `json:"name" validate:"omitnil,min=4,max=255"`
+	Email           *string `json:"email" validate:"omitnil,email,min=8,max=255"`
+	CurrentPassword string  `json:"current_password" validate:"required_with=Email,max=255"`
+	Locale          *string `json:"locale" validate:"omitempty,oneof=ru en"`
+	Timezone        *string `json:"timezone" validate:"omitempty,timezone"`
+}
+
+// EmailVerificationRequest представляет запрос на подтверждение электронной почты по токену из письма.
+// Поля:
+//   - Token: токен подтверждения (обязательное)
+type EmailVerificationRequest struct {
+	Token string `json:"token" validate:"required,max=255"`
+}
+
+// EmailVerificationResendRequest представляет запрос на повторную отправку письма для подтверждения почты.
+// Поля:
+//   - Email: электронная почта, указанная при регистрации (обязательное, валидный email)
+type EmailVerificationResendRequest struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+// EmailVerificationToken представляет токен подтверждения электронной почты (в базе хранится только хэш токена)
+type EmailVerificationToken struct {
+	ID        uuid.UUID `json:"id"`         // Идентификатор токена
+	UserID    uuid.UUID `json:"user_id"`    // Пользователь
+	Email     string    `json:"email"`      // Подтверждаемый адрес
+	ExpiresAt time.Time `json:"expires_at"` // Время истечения токена
+}
@@ -4,23 +4,28 @@ import (
 	"log/slog"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
 )
 
 var ServerConfig *common.ServerConfig
 
-// init инициализирует конфигурацию сервера при старте приложения.
+// Load загружает конфигурацию сервера при старте приложения.
 // Выполняет:
 //   - Загрузку переменных окружения
 //   - Инициализацию конфигурации, если она не была загружена ранее
 //   - Настройку логгера с указанным уровнем логирования
-func init() {
-	mustLoadEnv()
-	if ServerConfig == nil {
-		NewConfig()
-		setNewDefaultLogger(slog.Level(ServerConfig.LogLevel))
+//
+// Вызывается из main до создания зависимостей; тесты задают ServerConfig сами.
+func Load() {
+	if ServerConfig != nil {
+		return
 	}
+	mustLoadEnv()
+	NewConfig()
+	setNewDefaultLogger(slog.Level(ServerConfig.LogLevel))
 }
 
 // NewConfig создает и инициализирует новую конфигурацию сервера из переменных окружения.
@@ -32,6 +37,20 @@ func init() {
 //   - SERVER_PORT: порт сервера
 //   - DB_*: параметры подключения к БД
 //   - JWT_*: параметры JWT токенов
+//   - GUAC_SERVICE_*: служебная учетная запись Guacamole
+//   - GUAC_CREDENTIALS_KEY: ключ шифрования паролей Guacamole в сессиях входа и секретов TOTP
+//     (обязательный, должен отличаться от JWT_ACCESS_TOKEN_SECRET)
+//   - GUAC_DRIVE_ROOT: каталог guacd для виртуальных дисков RDP (по умолчанию /drive)
+//   - BALANCING_*: параметры проверки доступности групп балансировки (необязательные)
+//   - RECORDING_*: параметры хранения записей сессий (необязательные)
+//   - SMTP_*: параметры отправки писем (необязательные)
+//   - PASSWORD_RESET_*: параметры сброса пароля (необязательные)
+//   - EMAIL_VERIFICATION_*: параметры подтверждения электронной почты (необязательные)
+//   - TWO_FACTOR_*: параметры двухфакторной аутентификации (необязательные)
+//   - OIDC_*: параметры входа через OpenID Connect (необязательные)
+//   - AUTH_BACKENDS: способы проверки учетных данных при входе (по умолчанию local)
+//   - AUTH_DEFAULT_ROLE: роль новых пользователей (по умолчанию viewer)
+//   - LDAP_*: параметры входа через LDAP (необязательные)
 //
 // Возвращает:
 //   - Инициализирует глобальную переменную ServerConfig
@@ -58,8 +77,96 @@ func NewConfig() {
 		JWTConfig: common.JWTConfig{
 			AccessTokenSecret: os.Getenv("JWT_ACCESS_TOKEN_SECRET"),
 			AccessTokenTTL:    os.Getenv("JWT_ACCESS_TOKEN_TTL"),
+			RefreshTokenTTL:   mustParseDuration("JWT_REFRESH_TOKEN_TTL", 30*24*time.Hour),
 		},
 		GuacamoleAPIURL: os.Getenv("GUAC_API_URL"),
+		GuacamoleServiceAccount: common.GuacamoleAccountConfig{
+			Username: os.Getenv("GUAC_SERVICE_USERNAME"),
+			Password: os.Getenv("GUAC_SERVICE_PASSWORD"),
+		},
+		GuacamoleCredentialsKey: mustParseCredentialsKey("GUAC_CREDENTIALS_KEY", "JWT_ACCESS_TOKEN_SECRET"),
+		GuacamoleDriveRoot:      getEnv("GUAC_DRIVE_ROOT", "/drive"),
+		BalancingConfig: common.BalancingConfig{
+			ProbeInterval:    mustParseDuration("BALANCING_PROBE_INTERVAL", 30*time.Second),
+			ProbeTimeout:     mustParseDuration("BALANCING_PROBE_TIMEOUT", 3*time.Second),
+			FailureThreshold: mustParseInt("BALANCING_FAILURE_THRESHOLD", 3),
+		},
+		RecordingConfig: common.RecordingConfig{
+			Path:          getEnv("RECORDING_PATH", "/record"),
+			StoragePath:   getEnv("RECORDING_STORAGE_PATH", "/record"),
+			Retention:     mustParseDuration("RECORDING_RETENTION", 30*24*time.Hour),
+			SweepInterval: mustParseDuration("RECORDING_SWEEP_INTERVAL", time.Hour),
+			IndexInterval: mustParseDuration("RECORDING_INDEX_INTERVAL", 5*time.Minute),
+		},
+		ReconciliationConfig: common.ReconciliationConfig{
+			Interval:      mustParseDuration("RECONCILE_INTERVAL", time.Hour),
+			RepairOrphans: mustParseBool("RECONCILE_REPAIR_ORPHANS", false),
+		},
+		SharingConfig: common.SharingConfig{
+			LinkTTL:   mustParseDuration("SHARE_LINK_TTL", time.Hour),
+			ViewerURL: getEnv("SHARE_VIEWER_URL", "http://localhost:4000/shared"),
+		},
+		MailConfig: common.MailConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     getEnv("SMTP_PORT", "587"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     getEnv("SMTP_FROM", "no-reply@localhost"),
+			StartTLS: mustParseBool("SMTP_STARTTLS", true),
+		},
+		PasswordResetConfig: common.PasswordResetConfig{
+			TokenTTL: mustParseDuration("PASSWORD_RESET_TTL", time.Hour),
+			URL:      getEnv("PASSWORD_RESET_URL", "http://localhost:4000/reset-password"),
+		},
+		EmailVerificationConfig: common.EmailVerificationConfig{
+			TokenTTL: mustParseDuration("EMAIL_VERIFICATION_TTL", 24*time.Hour),
+			URL:      getEnv("EMAIL_VERIFICATION_URL", "http://localhost:4000/verify-email"),
+		},
+		TwoFactorConfig: common.TwoFactorConfig{
+			Issuer:              getEnv("TWO_FACTOR_ISSUER", "Remote Desktop"),
+			ChallengeTTL:        mustParseDuration("TWO_FACTOR_CHALLENGE_TTL", 5*time.Minute),
+			MaxAttempts:         mustParseInt("TWO_FACTOR_MAX_ATTEMPTS", 5),
+			RequiredPermissions: getList("TWO_FACTOR_REQUIRED_PERMISSIONS", []string{"ADMINISTER"}),
+		},
+		OIDCConfig: common.OIDCConfig{
+			IssuerURL:    os.Getenv("OIDC_ISSUER_URL"),
+			ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+			ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:  getEnv("OIDC_REDIRECT_URL", "http://localhost:8000/auth/oidc/callback"),
+			Scopes:       getList("OIDC_SCOPES", []string{"openid", "email", "profile"}),
+			GroupsClaim:  getEnv("OIDC_GROUPS_CLAIM", "groups"),
+			GroupRoles:   mustParseGroupRoles("OIDC_GROUP_ROLES"),
+			FrontendURL:  getEnv("OIDC_FRONTEND_URL", "http://localhost:4000/oidc-callback"),
+			StateTTL:     mustParseDuration("OIDC_STATE_TTL", 10*time.Minute),
+		},
+		AuthConfig: common.AuthConfig{
+			Backends:    mustParseAuthBackends("AUTH_BACKENDS"),
+			DefaultRole: mustParseRole("AUTH_DEFAULT_ROLE", common.RoleViewer),
+		},
+		LDAPConfig: common.LDAPConfig{
+			URL:                os.Getenv("LDAP_URL"),
+			StartTLS:           mustParseBool("LDAP_STARTTLS", false),
+			InsecureSkipVerify: mustParseBool("LDAP_INSECURE_SKIP_VERIFY", false),
+			BindDN:             os.Getenv("LDAP_BIND_DN"),
+			BindPassword:       os.Getenv("LDAP_BIND_PASSWORD"),
+			BaseDN:             os.Getenv("LDAP_BASE_DN"),
+			UserFilter:         getEnv("LDAP_USER_FILTER", "(&(objectClass=person)(mail=%s))"),
+			EmailAttribute:     getEnv("LDAP_EMAIL_ATTRIBUTE", "mail"),
+			NameAttribute:      getEnv("LDAP_NAME_ATTRIBUTE", "cn"),
+			GroupAttribute:     getEnv("LDAP_GROUP_ATTRIBUTE", "memberOf"),
+			GroupRoles:         mustParseGroupRoles("LDAP_GROUP_ROLES"),
+			Timeout:            mustParseDuration("LDAP_TIMEOUT", 10*time.Second),
+		},
+		LoginProtectionConfig: common.LoginProtectionConfig{
+			AccountMaxFailures: mustParseInt("LOGIN_ACCOUNT_MAX_FAILURES", 5),
+			IPMaxFailures:      mustParseInt("LOGIN_IP_MAX_FAILURES", 20),
+			FailureWindow:      mustParseDuration("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+			LockoutDuration:    mustParseDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+			DelayBase:          mustParseDuration("LOGIN_DELAY_BASE", time.Second),
+			DelayMax:           mustParseDuration("LOGIN_DELAY_MAX", 30*time.Second),
+			TrustForwardedFor:  mustParseBool("LOGIN_TRUST_FORWARDED_FOR", false),
+			Store:              mustParseLoginStore("LOGIN_ATTEMPTS_STORE"),
+		},
 	}
 	ServerConfig.DbConfig = append(ServerConfig.DbConfig, &common.DBConfig{
 		Username: os.Getenv("DB_USERNAME"),
@@ -78,3 +185,167 @@ func NewConfig() {
 		SSLMode:  os.Getenv("DB_GUAC_SSLMODE"),
 	})
 }
+
+// mustParseDuration читает длительность из переменной окружения.
+// Если переменная не задана, возвращает значение по умолчанию.
+// При некорректном значении завершает работу приложения с panic.
+func mustParseDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		slog.Error(err.Error())
+		panic(err.Error())
+	}
+	return duration
+}
+
+// mustParseInt читает целое число из переменной окружения.
+// Если переменная не задана, возвращает значение по умолчанию.
+// При некорректном значении завершает работу приложения с panic.
+func mustParseInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		slog.Error(err.Error())
+		panic(err.Error())
+	}
+	return number
+}
+
+// mustParseBool читает логическое значение из переменной окружения.
+// Если переменная не задана, возвращает значение по умолчанию.
+// При некорректном значении завершает работу приложения с panic.
+func mustParseBool(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	flag, err := strconv.ParseBool(value)
+	if err != nil {
+		slog.Error(err.Error())
+		panic(err.Error())
+	}
+	return flag
+}
+
+// getEnv читает строку из переменной окружения.
+// Если переменная не задана, возвращает значение по умолчанию.
+func getEnv(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// getList читает список значений, разделенных запятыми, из переменной окружения.
+// Если переменная не задана, возвращает значение по умолчанию;
+// пустое значение задает пустой список.
+func getList(key string, fallback []string) []string {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	list := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// mustParseGroupRoles читает соответствие групп ролям из переменной окружения
+// в формате "группа:роль,группа:роль".
+// Если переменная не задана, возвращает пустое соответствие.
+// При некорректном значении или неизвестной роли завершает работу приложения с panic.
+func mustParseGroupRoles(key string) map[string]string {
+	mapping := make(map[string]string)
+	for _, item := range getList(key, nil) {
+		separator := strings.LastIndex(item, ":")
+		if separator <= 0 {
+			message := "invalid " + key + " entry: " + item
+			slog.Error(message)
+			panic(message)
+		}
+		group := strings.TrimSpace(item[:separator])
+		role := strings.TrimSpace(item[separator+1:])
+		if !common.IsValidRole(role) {
+			message := "unknown role in " + key + " entry: " + item
+			slog.Error(message)
+			panic(message)
+		}
+		mapping[group] = role
+	}
+	return mapping
+}
+
+// mustParseRole читает роль из переменной окружения.
+// Если переменная не задана, возвращает значение по умолчанию.
+// При неизвестной роли завершает работу приложения с panic.
+func mustParseRole(key string, fallback string) string {
+	role := getEnv(key, fallback)
+	if !common.IsValidRole(role) {
+		message := "unknown role in " + key + ": " + role
+		slog.Error(message)
+		panic(message)
+	}
+	return role
+}
+
+// mustParseAuthBackends читает способы проверки учетных данных из переменной окружения.
+// Если переменная не задана, используется только пароль в базе приложения (local).
+// При неизвестном способе или пустом списке завершает работу приложения с panic.
+func mustParseAuthBackends(key string) []string {
+	backends := getList(key, []string{"local"})
+	if len(backends) == 0 {
+		message := key + " must not be empty"
+		slog.Error(message)
+		panic(message)
+	}
+	for _, backend := range backends {
+		if backend != "local" && backend != "ldap" {
+			message := "unknown " + key + " entry: " + backend
+			slog.Error(message)
+			panic(message)
+		}
+	}
+	return backends
+}
+
+// mustParseLoginStore читает хранилище попыток входа из переменной окружения.
+// Если переменная не задана, попытки хранятся в базе приложения (postgres).
+// При неизвестном хранилище завершает работу приложения с panic.
+func mustParseLoginStore(key string) string {
+	store := getEnv(key, "postgres")
+	if store != "postgres" && store != "memory" {
+		message := "unknown " + key + ": " + store
+		slog.Error(message)
+		panic(message)
+	}
+	return store
+}
+
+// mustParseCredentialsKey читает ключ шифрования из переменной окружения.
+// Ключ обязателен и не должен совпадать с секретом подписи JWT: утечка одного секрета
+// не должна раскрывать зашифрованные пароли Guacamole и секреты TOTP.
+// Если ключ не задан или совпадает с секретом, завершает работу приложения с panic.
+func mustParseCredentialsKey(key string, jwtSecretKey string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		message := key + " is required"
+		slog.Error(message)
+		panic(message)
+	}
+	if value == os.Getenv(jwtSecretKey) {
+		message := key + " must differ from " + jwtSecretKey
+		slog.Error(message)
+		panic(message)
+	}
+	return value
+}
@@ -0,0 +1,91 @@
+// Package http_handler предоставляет HTTP обработчики для API RemoteDesktop.
+package http_handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/helper"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/service"
+)
+
+// ActiveConnectionHandler обрабатывает HTTP запросы для мониторинга активных подключений.
+type ActiveConnectionHandler struct {
+	service *service.ActiveConnectionService
+}
+
+// NewActiveConnectionHandler создает новый экземпляр ActiveConnectionHandler.
+//
+// Параметры:
+//   - service: сервис активных подключений
+//
+// Возвращает:
+//   - *ActiveConnectionHandler: указатель на созданный обработчик
+func NewActiveConnectionHandler(service *service.ActiveConnectionService) *ActiveConnectionHandler {
+	return &ActiveConnectionHandler{service: service}
+}
+
+// Get возвращает список активных подключений.
+// Администратор видит все подключения, остальные пользователи — только свои.
+func (h *ActiveConnectionHandler) Get(w http.ResponseWriter, r *http.Request) {
+	resp := helper.Response{}
+	guacToken, ok := guacamoleToken(w, r)
+	if !ok {
+		return
+	}
+	email, _ := r.Context().Value(common.USER_MAIL).(string)
+
+	data, err := h.service.GetActiveConnections(email, guacToken)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Error fetching active connections: %s", err.Error()))
+		resp.ResponseWrite(w, r, http.StatusNotFound)
+		return
+	}
+
+	resp.Data = data
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// Kill принудительно завершает активное подключение.
+//
+// Возможные коды ответа:
+//   - 200: подключение завершено
+//   - 403: подключение принадлежит другому пользователю
+//   - 404: подключение не найдено
+//   - 500: внутренняя ошибка сервера
+func (h *ActiveConnectionHandler) Kill(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		resp.Message = "Active connection ID is required"
+		resp.ResponseWrite(w, r, http.StatusBadRequest)
+		return
+	}
+	guacToken, ok := guacamoleToken(w, r)
+	if !ok {
+		return
+	}
+	email, _ := r.Context().Value(common.USER_MAIL).(string)
+
+	err := h.service.KillActiveConnection(id, email, guacToken)
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		resp.Message = "Active connection not found"
+		resp.ResponseWrite(w, r, http.StatusNotFound)
+		return
+	case errors.Is(err, service.ErrForbidden):
+		resp.Message = "You can not terminate connections of other users"
+		resp.ResponseWrite(w, r, http.StatusForbidden)
+		return
+	case err != nil:
+		slog.Error(fmt.Sprintf("Error killing active connection: %s", err.Error()))
+		resp.ResponseWrite(w, r, http.StatusInternalServerError)
+		return
+	}
+
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
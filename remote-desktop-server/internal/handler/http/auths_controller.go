@@ -3,8 +3,12 @@ package http_handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
@@ -42,13 +46,16 @@ func NewAuthHandler(service service.AuthService) *AuthHandler {
 //  3. Валидирует входные данные
 //  4. При ошибках валидации возвращает локализованные сообщения
 //  5. Вызывает сервис аутентификации
-//  6. Возвращает JWT токен при успешной аутентификации
+//  6. Возвращает JWT токен при успешной аутентификации или токен второго шага,
+//     если у пользователя подключен (или обязателен) TOTP
 //
 // Возможные коды ответа:
-//   - 200: успешный вход, возвращает токен
+//   - 200: успешный вход, возвращает токены или two_factor с токеном второго шага
 //   - 400: ошибка парсинга JSON
+//   - 403: электронная почта пользователя не подтверждена
 //   - 422: ошибки валидации
 //   - 409: конфликт (неверные учетные данные)
+//   - 429: слишком много неудачных попыток входа; в заголовке Retry-After — секунды до следующей попытки
 //   - 500: внутренняя ошибка сервера
 func (h *AuthHandler) SingIn(w http.ResponseWriter, r *http.Request) {
 	resp := helper.Response{}
@@ -80,7 +87,19 @@ func (h *AuthHandler) SingIn(w http.ResponseWriter, r *http.Request) {
 		resp.ResponseWrite(w, r, http.StatusUnprocessableEntity)
 		return
 	}
-	token, err := h.service.SignIn(r.Context(), form)
+	token, err := h.service.SignIn(r.Context(), form, r.RemoteAddr, r.Header.Get("X-Forwarded-For"))
+	var throttled *service.LoginThrottledError
+	if errors.As(err, &throttled) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(throttled.RetryAfter/time.Second)))
+		resp.Message = err.Error()
+		resp.ResponseWrite(w, r, http.StatusTooManyRequests)
+		return
+	}
+	if errors.Is(err, service.ErrEmailNotVerified) {
+		resp.Message = err.Error()
+		resp.ResponseWrite(w, r, http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		resp.Message = err.Error()
 		resp.ResponseWrite(w, r, http.StatusConflict)
@@ -104,8 +123,10 @@ func (h *AuthHandler) SingIn(w http.ResponseWriter, r *http.Request) {
 //  5. Вызывает сервис регистрации
 //  6. Возвращает статус создания
 //
+// Войти можно только после подтверждения почты по ссылке из отправленного письма.
+//
 // Возможные коды ответа:
-//   - 200: успешная регистрация
+//   - 200: успешная регистрация, письмо для подтверждения почты отправлено
 //   - 400: ошибка парсинга JSON
 //   - 422: ошибки валидации
 //   - 409: конфликт (пользователь уже существует)
@@ -145,6 +166,69 @@ func (h *AuthHandler) SignUp(w http.ResponseWriter, r *http.Request) {
 		resp.ResponseWrite(w, r, http.StatusConflict)
 		return
 	}
-	resp.Message = "Created!"
+	resp.Message = "Created! Follow the link sent to your email to activate the account"
 	resp.ResponseWrite(w, r, http.StatusOK)
 }
+
+// Refresh обменивает refresh токен на новую пару токенов.
+//
+// Возможные коды ответа:
+//   - 200: успешное обновление, возвращает новые токены
+//   - 400: ошибка парсинга JSON
+//   - 401: токен недействителен (при повторном использовании сессия входа отзывается)
+//   - 422: ошибки валидации
+//   - 500: внутренняя ошибка сервера
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	resp := helper.Response{}
+	if resp.IsValidMediaType(w, r) {
+		return
+	}
+	var form common.AuthRefreshRequest
+	if !decodeForm(w, r, &form) {
+		return
+	}
+	tokens, err := h.service.Refresh(r.Context(), form)
+	if err != nil {
+		h.writeTokenError(w, r, err)
+		return
+	}
+	resp.Data = tokens
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// SignOut завершает сессию входа: отзывает refresh токены сессии и токен Guacamole.
+//
+// Возможные коды ответа:
+//   - 200: успешный выход (в том числе повторный)
+//   - 400: ошибка парсинга JSON
+//   - 401: токен не найден
+//   - 422: ошибки валидации
+//   - 500: внутренняя ошибка сервера
+func (h *AuthHandler) SignOut(w http.ResponseWriter, r *http.Request) {
+	resp := helper.Response{}
+	if resp.IsValidMediaType(w, r) {
+		return
+	}
+	var form common.AuthRefreshRequest
+	if !decodeForm(w, r, &form) {
+		return
+	}
+	if err := h.service.SignOut(r.Context(), form); err != nil {
+		h.writeTokenError(w, r, err)
+		return
+	}
+	resp.Message = "Signed out!"
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// writeTokenError записывает ответ, соответствующий ошибке обновления или отзыва токенов.
+func (h *AuthHandler) writeTokenError(w http.ResponseWriter, r *http.Request, err error) {
+	resp := helper.Response{}
+	if errors.Is(err, service.ErrInvalidToken) {
+		resp.Message = "Refresh token is invalid or has expired"
+		resp.ResponseWrite(w, r, http.StatusUnauthorized)
+		return
+	}
+	slog.Error(fmt.Sprintf("Error refreshing tokens: %s", err.Error()))
+	resp.ResponseWrite(w, r, http.StatusInternalServerError)
+}
@@ -0,0 +1,274 @@
+// Package http_handler предоставляет HTTP обработчики для API RemoteDesktop.
+package http_handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/helper"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/service"
+)
+
+// ConnectionGroupHandler обрабатывает HTTP запросы для работы с группами подключений.
+type ConnectionGroupHandler struct {
+	service   *service.ConnectionGroupService
+	balancing *service.BalancingService
+}
+
+// NewConnectionGroupHandler создает новый экземпляр ConnectionGroupHandler.
+//
+// Параметры:
+//   - service: сервис для работы с группами подключений
+//   - balancing: сервис групп балансировки
+//
+// Возвращает:
+//   - *ConnectionGroupHandler: указатель на созданный обработчик
+func NewConnectionGroupHandler(
+	service *service.ConnectionGroupService,
+	balancing *service.BalancingService,
+) *ConnectionGroupHandler {
+	return &ConnectionGroupHandler{
+		service:   service,
+		balancing: balancing,
+	}
+}
+
+// Get возвращает плоский список групп подключений.
+func (h *ConnectionGroupHandler) Get(w http.ResponseWriter, r *http.Request) {
+	resp := helper.Response{}
+	guacToken, ok := guacamoleToken(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := h.service.GetGroups(guacToken)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Error fetching connection groups: %s", err.Error()))
+		resp.ResponseWrite(w, r, http.StatusNotFound)
+		return
+	}
+
+	resp.Data = data
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// Tree возвращает дерево группы со всеми вложенными группами и подключениями.
+func (h *ConnectionGroupHandler) Tree(w http.ResponseWriter, r *http.Request) {
+	resp := helper.Response{}
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		resp.Message = "Group ID is required"
+		resp.ResponseWrite(w, r, http.StatusBadRequest)
+		return
+	}
+	guacToken, ok := guacamoleToken(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := h.service.GetGroupTree(id, guacToken)
+	if err != nil {
+		resp.ResponseWrite(w, r, http.StatusNotFound)
+		return
+	}
+
+	resp.Data = data
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// Edit возвращает информацию о конкретной группе подключений.
+func (h *ConnectionGroupHandler) Edit(w http.ResponseWriter, r *http.Request) {
+	resp := helper.Response{}
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		resp.Message = "Group ID is required"
+		resp.ResponseWrite(w, r, http.StatusBadRequest)
+		return
+	}
+	guacToken, ok := guacamoleToken(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := h.service.EditGroup(id, guacToken)
+	if err != nil {
+		resp.ResponseWrite(w, r, http.StatusNotFound)
+		return
+	}
+
+	resp.Data = data
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// StoreGroup создает новую группу подключений.
+func (h *ConnectionGroupHandler) StoreGroup(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	guacToken, ok := guacamoleToken(w, r)
+	if !ok {
+		return
+	}
+	var form common.GuacamoleConnectionGroupRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 10<<20) // Ограничение тела запроса 10MB
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		resp.Message = "Invalid JSON"
+		resp.ResponseWrite(w, r, http.StatusBadRequest)
+		return
+	}
+	validate := helper.NewValidator()
+	err := validate.Struct(form)
+	if err != nil {
+		errs := err.(validator.ValidationErrors)
+		humanReadableErrors, err := helper.LocalizedValidationMessages(r.Context(), errs)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Error localizing validation messages: %s", err.Error()))
+			resp.ResponseWrite(w, r, http.StatusInternalServerError)
+			return
+		}
+		resp.Errors = humanReadableErrors
+		resp.ResponseWrite(w, r, http.StatusUnprocessableEntity)
+		return
+	}
+	group, err := h.service.CreateGroup(&form, guacToken)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Error creating connection group: %s", err.Error()))
+		resp.ResponseWrite(w, r, http.StatusInternalServerError)
+		return
+	}
+	resp.Data = group
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// UpdateGroup обновляет группу подключений.
+func (h *ConnectionGroupHandler) UpdateGroup(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		resp.Message = "Group ID is required"
+		resp.ResponseWrite(w, r, http.StatusBadRequest)
+		return
+	}
+	guacToken, ok := guacamoleToken(w, r)
+	if !ok {
+		return
+	}
+	var form common.GuacamoleConnectionGroupRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 10<<20) // Ограничение тела запроса 10MB
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		resp.Message = "Invalid JSON"
+		resp.ResponseWrite(w, r, http.StatusBadRequest)
+		return
+	}
+	validate := helper.NewValidator()
+	err := validate.Struct(form)
+	if err != nil {
+		errs := err.(validator.ValidationErrors)
+		humanReadableErrors, err := helper.LocalizedValidationMessages(r.Context(), errs)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Error localizing validation messages: %s", err.Error()))
+			resp.ResponseWrite(w, r, http.StatusInternalServerError)
+			return
+		}
+		resp.Errors = humanReadableErrors
+		resp.ResponseWrite(w, r, http.StatusUnprocessableEntity)
+		return
+	}
+
+	if err := h.service.UpdateGroup(id, &form, guacToken); err != nil {
+		slog.Error(fmt.Sprintf("Error updating connection group: %s", err.Error()))
+		resp.ResponseWrite(w, r, http.StatusInternalServerError)
+		return
+	}
+
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// RemoveGroup удаляет группу подключений вместе с ее содержимым.
+func (h *ConnectionGroupHandler) RemoveGroup(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		resp.Message = "Group ID is required"
+		resp.ResponseWrite(w, r, http.StatusBadRequest)
+		return
+	}
+	guacToken, ok := guacamoleToken(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DestroyGroup(id, guacToken); err != nil {
+		slog.Error(fmt.Sprintf("Error removing connection group: %s", err.Error()))
+		resp.ResponseWrite(w, r, http.StatusInternalServerError)
+		return
+	}
+
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// StoreBalancingGroup создает группу балансировки вместе с подключениями-участниками.
+func (h *ConnectionGroupHandler) StoreBalancingGroup(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	guacToken, ok := guacamoleToken(w, r)
+	if !ok {
+		return
+	}
+	var form common.BalancingGroupRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 10<<20) // Ограничение тела запроса 10MB
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		resp.Message = "Invalid JSON"
+		resp.ResponseWrite(w, r, http.StatusBadRequest)
+		return
+	}
+	validate := helper.NewValidator()
+	err := validate.Struct(form)
+	if err != nil {
+		errs := err.(validator.ValidationErrors)
+		humanReadableErrors, err := helper.LocalizedValidationMessages(r.Context(), errs)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Error localizing validation messages: %s", err.Error()))
+			resp.ResponseWrite(w, r, http.StatusInternalServerError)
+			return
+		}
+		resp.Errors = humanReadableErrors
+		resp.ResponseWrite(w, r, http.StatusUnprocessableEntity)
+		return
+	}
+	group, err := h.balancing.CreatePool(&form, guacToken)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Error creating balancing group: %s", err.Error()))
+		resp.ResponseWrite(w, r, http.StatusInternalServerError)
+		return
+	}
+	resp.Data = group
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// Health возвращает результат проверки доступности участников группы балансировки.
+func (h *ConnectionGroupHandler) Health(w http.ResponseWriter, r *http.Request) {
+	resp := helper.Response{}
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		resp.Message = "Group ID is required"
+		resp.ResponseWrite(w, r, http.StatusBadRequest)
+		return
+	}
+	guacToken, ok := guacamoleToken(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := h.balancing.Health(r.Context(), id, guacToken)
+	if err != nil {
+		resp.Message = err.Error()
+		resp.ResponseWrite(w, r, http.StatusNotFound)
+		return
+	}
+
+	resp.Data = data
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
@@ -0,0 +1,160 @@
+// Package http_handler предоставляет HTTP обработчики для API RemoteDesktop.
+package http_handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/helper"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/service"
+)
+
+// ConnectionPermissionHandler обрабатывает HTTP запросы управления доступом к подключениям.
+type ConnectionPermissionHandler struct {
+	service *service.ConnectionPermissionService
+}
+
+// NewConnectionPermissionHandler создает новый экземпляр ConnectionPermissionHandler.
+//
+// Параметры:
+//   - service: сервис доступа к подключениям
+//
+// Возвращает:
+//   - *ConnectionPermissionHandler: указатель на созданный обработчик
+func NewConnectionPermissionHandler(service *service.ConnectionPermissionService) *ConnectionPermissionHandler {
+	return &ConnectionPermissionHandler{service: service}
+}
+
+// Get возвращает пользователей и группы, имеющие доступ к подключению.
+func (h *ConnectionPermissionHandler) Get(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	id, guacToken, ok := h.prepare(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := h.service.GetPermissions(r.Context(), id, guacToken)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.Data = data
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// Grant выдает пользователю или группе разрешения на подключение.
+//
+// Возможные коды ответа:
+//   - 200: разрешения выданы, в ответе — список доступа к подключению
+//   - 403: у вызывающего нет права ADMINISTER на подключение
+//   - 404: подключение, пользователь или группа не найдены
+//   - 422: ошибка валидации
+func (h *ConnectionPermissionHandler) Grant(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	id, guacToken, ok := h.prepare(w, r)
+	if !ok {
+		return
+	}
+	var form common.ConnectionPermissionRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 10<<20) // Ограничение тела запроса 10MB
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		resp.Message = "Invalid JSON"
+		resp.ResponseWrite(w, r, http.StatusBadRequest)
+		return
+	}
+	validate := helper.NewValidator()
+	if err := validate.Struct(form); err != nil {
+		errs := err.(validator.ValidationErrors)
+		humanReadableErrors, err := helper.LocalizedValidationMessages(r.Context(), errs)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Error localizing validation messages: %s", err.Error()))
+			resp.ResponseWrite(w, r, http.StatusInternalServerError)
+			return
+		}
+		resp.Errors = humanReadableErrors
+		resp.ResponseWrite(w, r, http.StatusUnprocessableEntity)
+		return
+	}
+
+	data, err := h.service.GrantPermissions(r.Context(), id, &form, guacToken)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.Data = data
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// Revoke отзывает разрешения пользователя или группы на подключение.
+//
+// Параметры запроса:
+//   - permission: отзываемое разрешение, можно указать несколько раз (по умолчанию — все разрешения)
+func (h *ConnectionPermissionHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	id, guacToken, ok := h.prepare(w, r)
+	if !ok {
+		return
+	}
+	email, _ := r.Context().Value(common.USER_MAIL).(string)
+
+	err := h.service.RevokePermissions(
+		r.Context(),
+		id,
+		chi.URLParam(r, "entityId"),
+		r.URL.Query()["permission"],
+		email,
+		guacToken,
+	)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// prepare читает идентификатор подключения и токен Guacamole.
+// При ошибке записывает ответ и возвращает ok=false.
+func (h *ConnectionPermissionHandler) prepare(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	var resp helper.Response
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		resp.Message = "Connection ID is required"
+		resp.ResponseWrite(w, r, http.StatusBadRequest)
+		return "", "", false
+	}
+	guacToken, ok := guacamoleToken(w, r)
+	if !ok {
+		return "", "", false
+	}
+	return id, guacToken, true
+}
+
+// writeError записывает ответ, соответствующий ошибке сервиса доступа к подключениям.
+func (h *ConnectionPermissionHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var resp helper.Response
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		resp.Message = "Connection, user or group not found"
+		resp.ResponseWrite(w, r, http.StatusNotFound)
+		return
+	case errors.Is(err, service.ErrForbidden):
+		resp.Message = "You can not manage access to this connection"
+		resp.ResponseWrite(w, r, http.StatusForbidden)
+		return
+	case errors.Is(err, service.ErrUnsupported):
+		resp.Message = "Unknown connection permission"
+		resp.ResponseWrite(w, r, http.StatusUnprocessableEntity)
+		return
+	}
+	slog.Error(fmt.Sprintf("Error managing connection permissions: %s", err.Error()))
+	resp.ResponseWrite(w, r, http.StatusInternalServerError)
+}
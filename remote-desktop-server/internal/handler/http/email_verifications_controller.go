@@ -0,0 +1,100 @@
+// Package http_handler предоставляет HTTP обработчики для API RemoteDesktop.
+package http_handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/helper"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/service"
+)
+
+// EmailVerificationHandler обрабатывает HTTP запросы подтверждения электронной почты.
+type EmailVerificationHandler struct {
+	service *service.EmailVerificationService
+}
+
+// NewEmailVerificationHandler создает новый экземпляр EmailVerificationHandler.
+//
+// Параметры:
+//   - service: сервис подтверждения электронной почты
+//
+// Возвращает:
+//   - *EmailVerificationHandler: указатель на созданный обработчик
+func NewEmailVerificationHandler(service *service.EmailVerificationService) *EmailVerificationHandler {
+	return &EmailVerificationHandler{service: service}
+}
+
+// Verify подтверждает электронную почту по токену из письма.
+//
+// Возможные коды ответа:
+//   - 200: почта подтверждена (после смены почты сессии входа пользователя отозваны)
+//   - 400: ошибка парсинга JSON
+//   - 409: новый адрес занят после отправки письма
+//   - 422: ошибки валидации или недействительный токен
+//   - 500: внутренняя ошибка сервера
+func (h *EmailVerificationHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	if resp.IsValidMediaType(w, r) {
+		return
+	}
+	var form common.EmailVerificationRequest
+	if !decodeForm(w, r, &form) {
+		return
+	}
+
+	if err := h.service.Verify(r.Context(), form); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.Message = "Email has been verified"
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// Resend повторно отправляет письмо для подтверждения почты, указанной при регистрации.
+// Ответ не зависит от того, зарегистрирован ли email.
+//
+// Возможные коды ответа:
+//   - 200: запрос принят
+//   - 400: ошибка парсинга JSON
+//   - 422: ошибки валидации
+//   - 500: внутренняя ошибка сервера (в том числе ошибка отправки письма)
+func (h *EmailVerificationHandler) Resend(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	if resp.IsValidMediaType(w, r) {
+		return
+	}
+	var form common.EmailVerificationResendRequest
+	if !decodeForm(w, r, &form) {
+		return
+	}
+
+	if err := h.service.Resend(r.Context(), form); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.Message = "If this email is registered and not verified yet, a verification link has been sent to it"
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// writeError записывает ответ, соответствующий ошибке сервиса подтверждения электронной почты.
+func (h *EmailVerificationHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var resp helper.Response
+	switch {
+	case errors.Is(err, service.ErrInvalidToken):
+		resp.Message = "Email verification link is invalid or has expired"
+		resp.ResponseWrite(w, r, http.StatusUnprocessableEntity)
+		return
+	case errors.Is(err, service.ErrConflict):
+		resp.Message = "User with this email already exists"
+		resp.ResponseWrite(w, r, http.StatusConflict)
+		return
+	}
+	slog.Error(fmt.Sprintf("Error verifying email: %s", err.Error()))
+	resp.ResponseWrite(w, r, http.StatusInternalServerError)
+}
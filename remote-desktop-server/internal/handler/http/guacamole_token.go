@@ -0,0 +1,24 @@
+// Package http_handler предоставляет HTTP обработчики для API RemoteDesktop.
+package http_handler
+
+import (
+	"net/http"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/helper"
+)
+
+// guacamoleToken возвращает токен Guacamole сессии входа текущего пользователя.
+// Токен хранится на сервере (сессию в контекст запроса добавляет AuthMiddleware)
+// и продлевается SessionService, поэтому клиент его не передает.
+// При отсутствии сессии записывает ответ 401 и возвращает ok=false.
+func guacamoleToken(w http.ResponseWriter, r *http.Request) (string, bool) {
+	session, ok := r.Context().Value(common.AUTH_SESSION).(*common.AuthSession)
+	if !ok || session == nil {
+		var resp helper.Response
+		resp.Message = "You should be authorized!"
+		resp.ResponseWrite(w, r, http.StatusUnauthorized)
+		return "", false
+	}
+	return session.GuacamoleToken, true
+}
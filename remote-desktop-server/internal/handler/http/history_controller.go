@@ -0,0 +1,255 @@
+// Package http_handler предоставляет HTTP обработчики для API RemoteDesktop.
+package http_handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/helper"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/service"
+)
+
+const (
+	defaultHistoryPerPage = 50 // Размер страницы истории по умолчанию
+	defaultReportLimit    = 10 // Количество строк отчета о хостах по умолчанию
+	csvFormat             = "csv"
+)
+
+// HistoryHandler обрабатывает HTTP запросы истории подключений и отчетов по ней.
+type HistoryHandler struct {
+	service *service.HistoryService
+}
+
+// NewHistoryHandler создает новый экземпляр HistoryHandler.
+//
+// Параметры:
+//   - service: сервис истории подключений
+//
+// Возвращает:
+//   - *HistoryHandler: указатель на созданный обработчик
+func NewHistoryHandler(service *service.HistoryService) *HistoryHandler {
+	return &HistoryHandler{service: service}
+}
+
+// Get возвращает страницу истории подключений.
+// При вызове через /sessions/{id}/history история ограничивается указанным подключением.
+//
+// Параметры запроса:
+//   - connection_id, username: фильтры по подключению и пользователю
+//   - from, to: период в формате RFC3339 или YYYY-MM-DD
+//   - page, per_page: пагинация
+//   - format: json (по умолчанию) или csv
+func (h *HistoryHandler) Get(w http.ResponseWriter, r *http.Request) {
+	filter, guacToken, ok := h.prepare(w, r)
+	if !ok {
+		return
+	}
+	email, _ := r.Context().Value(common.USER_MAIL).(string)
+
+	page, err := h.service.GetHistory(r.Context(), filter, email, guacToken)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	if r.URL.Query().Get("format") == csvFormat {
+		rows := make([][]string, 0, len(page.Items))
+		for _, record := range page.Items {
+			endDate := ""
+			if record.EndDate != nil {
+				endDate = record.EndDate.Format(time.RFC3339)
+			}
+			rows = append(rows, []string{
+				strconv.FormatInt(record.ID, 10),
+				record.ConnectionID,
+				record.ConnectionName,
+				record.Username,
+				record.RemoteHost,
+				record.StartDate.Format(time.RFC3339),
+				endDate,
+				strconv.FormatInt(record.DurationSeconds, 10),
+			})
+		}
+		helper.WriteCSV(w, "connection-history.csv", []string{
+			"id", "connection_id", "connection_name", "username",
+			"remote_host", "start_date", "end_date", "duration_seconds",
+		}, rows)
+		return
+	}
+
+	resp := helper.Response{Data: page}
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// WeeklyUsage возвращает отчет о суммарном времени подключений пользователей по неделям.
+func (h *HistoryHandler) WeeklyUsage(w http.ResponseWriter, r *http.Request) {
+	filter, guacToken, ok := h.prepare(w, r)
+	if !ok {
+		return
+	}
+	email, _ := r.Context().Value(common.USER_MAIL).(string)
+
+	report, err := h.service.WeeklyUsage(r.Context(), filter, email, guacToken)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	if r.URL.Query().Get("format") == csvFormat {
+		rows := make([][]string, 0, len(report))
+		for _, usage := range report {
+			rows = append(rows, []string{
+				usage.Username,
+				usage.WeekStart.Format(time.DateOnly),
+				strconv.Itoa(usage.Sessions),
+				strconv.FormatFloat(usage.Hours, 'f', 2, 64),
+			})
+		}
+		helper.WriteCSV(w, "weekly-usage.csv", []string{"username", "week_start", "sessions", "hours"}, rows)
+		return
+	}
+
+	resp := helper.Response{Data: report}
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// TopHosts возвращает отчет о наиболее используемых хостах.
+func (h *HistoryHandler) TopHosts(w http.ResponseWriter, r *http.Request) {
+	filter, guacToken, ok := h.prepare(w, r)
+	if !ok {
+		return
+	}
+	email, _ := r.Context().Value(common.USER_MAIL).(string)
+
+	report, err := h.service.TopHosts(r.Context(), filter, email, guacToken)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	if r.URL.Query().Get("format") == csvFormat {
+		rows := make([][]string, 0, len(report))
+		for _, usage := range report {
+			rows = append(rows, []string{
+				usage.HostName,
+				strconv.Itoa(usage.Sessions),
+				strconv.Itoa(usage.Users),
+				strconv.FormatFloat(usage.Hours, 'f', 2, 64),
+			})
+		}
+		helper.WriteCSV(w, "top-hosts.csv", []string{"host_name", "sessions", "users", "hours"}, rows)
+		return
+	}
+
+	resp := helper.Response{Data: report}
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// prepare проверяет токен Guacamole и разбирает параметры фильтра из строки запроса.
+// При ошибке записывает ответ и возвращает ok = false.
+func (h *HistoryHandler) prepare(w http.ResponseWriter, r *http.Request) (*common.HistoryFilter, string, bool) {
+	var resp helper.Response
+	guacToken, ok := guacamoleToken(w, r)
+	if !ok {
+		return nil, "", false
+	}
+
+	filter, err := parseHistoryFilter(r.URL.Query())
+	if err != nil {
+		resp.Message = err.Error()
+		resp.ResponseWrite(w, r, http.StatusBadRequest)
+		return nil, "", false
+	}
+	if id := chi.URLParam(r, "id"); id != "" {
+		filter.ConnectionID = id
+	}
+
+	validate := helper.NewValidator()
+	if err := validate.Struct(filter); err != nil {
+		errs := err.(validator.ValidationErrors)
+		humanReadableErrors, err := helper.LocalizedValidationMessages(r.Context(), errs)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Error localizing validation messages: %s", err.Error()))
+			resp.ResponseWrite(w, r, http.StatusInternalServerError)
+			return nil, "", false
+		}
+		resp.Errors = humanReadableErrors
+		resp.ResponseWrite(w, r, http.StatusUnprocessableEntity)
+		return nil, "", false
+	}
+	return filter, guacToken, true
+}
+
+// writeError записывает ответ об ошибке сервиса истории.
+func (h *HistoryHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var resp helper.Response
+	if errors.Is(err, service.ErrForbidden) {
+		resp.Message = "You can not view history of other users"
+		resp.ResponseWrite(w, r, http.StatusForbidden)
+		return
+	}
+	slog.Error(fmt.Sprintf("Error fetching connection history: %s", err.Error()))
+	resp.ResponseWrite(w, r, http.StatusInternalServerError)
+}
+
+// parseHistoryFilter разбирает параметры фильтра истории из строки запроса.
+//
+// Параметры:
+//   - query: параметры строки запроса
+//
+// Возвращает:
+//   - *common.HistoryFilter: фильтр со значениями по умолчанию для незаданных параметров
+//   - error: ошибка, если параметр имеет неверный формат
+func parseHistoryFilter(query url.Values) (*common.HistoryFilter, error) {
+	filter := &common.HistoryFilter{
+		ConnectionID: query.Get("connection_id"),
+		Username:     query.Get("username"),
+		Page:         1,
+		PerPage:      defaultHistoryPerPage,
+		Limit:        defaultReportLimit,
+	}
+
+	for name, dst := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		date, err := parseHistoryDate(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s date: %q", name, value)
+		}
+		*dst = &date
+	}
+	if filter.From != nil && filter.To != nil && !filter.To.After(*filter.From) {
+		return nil, errors.New("to date must be after from date")
+	}
+
+	for name, dst := range map[string]*int{"page": &filter.Page, "per_page": &filter.PerPage, "limit": &filter.Limit} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %q", name, value)
+		}
+		*dst = number
+	}
+	return filter, nil
+}
+
+// parseHistoryDate разбирает дату в формате RFC3339 или YYYY-MM-DD (полночь UTC).
+func parseHistoryDate(value string) (time.Time, error) {
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
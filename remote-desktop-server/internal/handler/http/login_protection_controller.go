@@ -0,0 +1,168 @@
+// Package http_handler предоставляет HTTP обработчики для API RemoteDesktop.
+package http_handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/helper"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/service"
+)
+
+const defaultLoginAuditPerPage = 50 // Размер страницы журнала входа по умолчанию
+
+// LoginProtectionHandler обрабатывает HTTP запросы администратора к блокировкам и журналу входа.
+type LoginProtectionHandler struct {
+	service *service.LoginProtectionService
+}
+
+// NewLoginProtectionHandler создает новый экземпляр LoginProtectionHandler.
+//
+// Параметры:
+//   - service: сервис защиты входа от подбора пароля
+//
+// Возвращает:
+//   - *LoginProtectionHandler: указатель на созданный обработчик
+func NewLoginProtectionHandler(service *service.LoginProtectionService) *LoginProtectionHandler {
+	return &LoginProtectionHandler{service: service}
+}
+
+// Locked возвращает действующие блокировки входа учетных записей и адресов.
+func (h *LoginProtectionHandler) Locked(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	data, err := h.service.GetLocked(r.Context())
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	resp.Data = data
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// Unlock снимает блокировку входа учетной записи или адреса.
+//
+// Возможные коды ответа:
+//   - 200: блокировка снята, неудачные попытки сброшены
+//   - 404: неудачных попыток для учетной записи или адреса нет
+//   - 422: ошибки валидации
+func (h *LoginProtectionHandler) Unlock(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	if resp.IsValidMediaType(w, r) {
+		return
+	}
+	var form common.LoginUnlockRequest
+	if !decodeForm(w, r, &form) {
+		return
+	}
+	actor, _ := r.Context().Value(common.USER).(*common.User)
+
+	if err := h.service.Unlock(r.Context(), actor, form); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// UnlockUser снимает блокировку входа в учетную запись пользователя.
+//
+// Возможные коды ответа:
+//   - 200: блокировка снята (или ее не было)
+//   - 404: пользователь не найден
+func (h *LoginProtectionHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	actor, _ := r.Context().Value(common.USER).(*common.User)
+
+	if err := h.service.UnlockUser(r.Context(), actor, chi.URLParam(r, "id")); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// Audit возвращает страницу журнала входа по паролю.
+//
+// Параметры запроса:
+//   - email: email, указанный при входе
+//   - remote_addr: адрес клиента
+//   - event: succeeded, failed, throttled, locked или unlocked
+//   - page, per_page: пагинация
+func (h *LoginProtectionHandler) Audit(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	filter, err := parseLoginAuditFilter(r.URL.Query())
+	if err != nil {
+		resp.Message = err.Error()
+		resp.ResponseWrite(w, r, http.StatusBadRequest)
+		return
+	}
+	validate := helper.NewValidator()
+	if err := validate.Struct(filter); err != nil {
+		errs := err.(validator.ValidationErrors)
+		humanReadableErrors, err := helper.LocalizedValidationMessages(r.Context(), errs)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Error localizing validation messages: %s", err.Error()))
+			resp.ResponseWrite(w, r, http.StatusInternalServerError)
+			return
+		}
+		resp.Errors = humanReadableErrors
+		resp.ResponseWrite(w, r, http.StatusUnprocessableEntity)
+		return
+	}
+
+	page, err := h.service.GetAudit(r.Context(), filter)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	resp.Data = page
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// writeError записывает ответ, соответствующий ошибке сервиса защиты входа.
+func (h *LoginProtectionHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var resp helper.Response
+	if errors.Is(err, service.ErrNotFound) {
+		resp.Message = "No failed sign-ins found"
+		resp.ResponseWrite(w, r, http.StatusNotFound)
+		return
+	}
+	slog.Error(fmt.Sprintf("Error managing sign-in protection: %s", err.Error()))
+	resp.ResponseWrite(w, r, http.StatusInternalServerError)
+}
+
+// parseLoginAuditFilter разбирает параметры журнала входа из строки запроса.
+//
+// Параметры:
+//   - query: параметры строки запроса
+//
+// Возвращает:
+//   - *common.LoginAuditFilter: фильтр со значениями по умолчанию для незаданных параметров
+//   - error: ошибка, если параметр пагинации имеет неверный формат
+func parseLoginAuditFilter(query url.Values) (*common.LoginAuditFilter, error) {
+	filter := &common.LoginAuditFilter{
+		Email:      strings.TrimSpace(query.Get("email")),
+		RemoteAddr: strings.TrimSpace(query.Get("remote_addr")),
+		Event:      query.Get("event"),
+		Page:       1,
+		PerPage:    defaultLoginAuditPerPage,
+	}
+	for name, dst := range map[string]*int{"page": &filter.Page, "per_page": &filter.PerPage} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %q", name, value)
+		}
+		*dst = number
+	}
+	return filter, nil
+}
@@ -0,0 +1,137 @@
+// Package http_handler предоставляет HTTP обработчики для API RemoteDesktop.
+package http_handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/helper"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/service"
+)
+
+// oidcStateCookie cookie со значением state, связывающая вход с браузером, который его начал
+const oidcStateCookie = "oidc_state"
+
+// OIDCHandler обрабатывает HTTP запросы входа через провайдера OpenID Connect.
+type OIDCHandler struct {
+	service *service.OIDCService
+}
+
+// NewOIDCHandler создает новый экземпляр OIDCHandler.
+//
+// Параметры:
+//   - service: сервис входа через OpenID Connect
+//
+// Возвращает:
+//   - *OIDCHandler: указатель на созданный обработчик
+func NewOIDCHandler(service *service.OIDCService) *OIDCHandler {
+	return &OIDCHandler{service: service}
+}
+
+// Login перенаправляет браузер на страницу авторизации провайдера.
+// Значение state сохраняется в cookie oidc_state (HttpOnly, SameSite=Lax), которую
+// браузер вернет при переходе по адресу возврата.
+//
+// Возможные коды ответа:
+//   - 302: перенаправление к провайдеру
+//   - 404: вход через OpenID Connect не настроен
+//   - 502: провайдер недоступен
+func (h *OIDCHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	target, state, err := h.service.Login(r.Context())
+	switch {
+	case errors.Is(err, service.ErrUnsupported):
+		resp.Message = "OpenID Connect login is not configured"
+		resp.ResponseWrite(w, r, http.StatusNotFound)
+		return
+	case err != nil:
+		slog.Error(fmt.Sprintf("Error starting OpenID Connect login: %s", err.Error()))
+		resp.ResponseWrite(w, r, http.StatusBadGateway)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/",
+		MaxAge:   int(h.service.StateTTL().Seconds()),
+		HttpOnly: true,
+		Secure:   requestScheme(r) == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// Callback завершает вход по коду авторизации и перенаправляет браузер на страницу фронтенда.
+// Токены передаются во фрагменте адреса (#token=...&refresh_token=...), который браузер
+// не отправляет на сервер и не записывает в заголовок Referer; при ошибке — #error=код.
+// Если нужен код TOTP, во фрагменте передается токен второго шага
+// (#challenge_token=...&enrollment_required=...), и вход завершается запросом POST /auth/two-factor.
+//
+// Коды ошибок:
+//   - access_denied: провайдер отказал во входе или не сообщил подтвержденную электронную почту,
+//     пользователь отключен или связан с другой учетной записью провайдера
+//   - invalid_state: вход не начинался в этом браузере, истек или уже завершен
+//   - server_error: ошибка провайдера или сервера
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var browserState string
+	if cookie, err := r.Cookie(oidcStateCookie); err == nil {
+		browserState = cookie.Value
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   requestScheme(r) == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+	if providerError := query.Get("error"); providerError != "" {
+		slog.Warn(fmt.Sprintf("OpenID Connect provider returned error: %s %s", providerError, query.Get("error_description")))
+		h.redirect(w, r, url.Values{"error": {"access_denied"}})
+		return
+	}
+
+	result, err := h.service.Callback(r.Context(), query.Get("code"), query.Get("state"), browserState)
+	switch {
+	case errors.Is(err, service.ErrUnsupported):
+		var resp helper.Response
+		resp.Message = "OpenID Connect login is not configured"
+		resp.ResponseWrite(w, r, http.StatusNotFound)
+		return
+	case errors.Is(err, service.ErrInvalidToken):
+		h.redirect(w, r, url.Values{"error": {"invalid_state"}})
+		return
+	case errors.Is(err, service.ErrForbidden):
+		slog.Warn(err.Error())
+		h.redirect(w, r, url.Values{"error": {"access_denied"}})
+		return
+	case err != nil:
+		slog.Error(fmt.Sprintf("Error completing OpenID Connect login: %s", err.Error()))
+		h.redirect(w, r, url.Values{"error": {"server_error"}})
+		return
+	}
+
+	if result.TwoFactor != nil {
+		h.redirect(w, r, url.Values{
+			"challenge_token":     {result.TwoFactor.ChallengeToken},
+			"enrollment_required": {strconv.FormatBool(result.TwoFactor.EnrollmentRequired)},
+		})
+		return
+	}
+	h.redirect(w, r, url.Values{
+		"token":         {result.Token},
+		"refresh_token": {result.RefreshToken},
+	})
+}
+
+// redirect перенаправляет браузер на страницу фронтенда с параметрами во фрагменте адреса
+func (h *OIDCHandler) redirect(w http.ResponseWriter, r *http.Request, fragment url.Values) {
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, h.service.FrontendURL(fragment), http.StatusFound)
+}
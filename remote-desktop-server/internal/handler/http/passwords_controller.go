@@ -0,0 +1,127 @@
+// Package http_handler предоставляет HTTP обработчики для API RemoteDesktop.
+package http_handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/helper"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/service"
+)
+
+// PasswordHandler обрабатывает HTTP запросы смены и сброса пароля.
+type PasswordHandler struct {
+	service *service.PasswordService
+}
+
+// NewPasswordHandler создает новый экземпляр PasswordHandler.
+//
+// Параметры:
+//   - service: сервис смены и сброса пароля
+//
+// Возвращает:
+//   - *PasswordHandler: указатель на созданный обработчик
+func NewPasswordHandler(service *service.PasswordService) *PasswordHandler {
+	return &PasswordHandler{service: service}
+}
+
+// Change меняет пароль текущего пользователя.
+//
+// Возможные коды ответа:
+//   - 200: пароль изменен, возвращает новые токены (ранее выданные токены отозваны)
+//   - 400: ошибка парсинга JSON
+//   - 422: ошибки валидации или неверный текущий пароль
+//   - 500: внутренняя ошибка сервера
+func (h *PasswordHandler) Change(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	if resp.IsValidMediaType(w, r) {
+		return
+	}
+	var form common.PasswordChangeRequest
+	if !decodeForm(w, r, &form) {
+		return
+	}
+
+	email, _ := r.Context().Value(common.USER_MAIL).(string)
+	tokens, err := h.service.ChangePassword(r.Context(), email, form)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.Data = tokens
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// Forgot отправляет письмо со ссылкой для сброса пароля.
+// Ответ не зависит от того, зарегистрирован ли email.
+//
+// Возможные коды ответа:
+//   - 200: запрос принят
+//   - 400: ошибка парсинга JSON
+//   - 422: ошибки валидации
+//   - 500: внутренняя ошибка сервера (в том числе ошибка отправки письма)
+func (h *PasswordHandler) Forgot(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	if resp.IsValidMediaType(w, r) {
+		return
+	}
+	var form common.PasswordForgotRequest
+	if !decodeForm(w, r, &form) {
+		return
+	}
+
+	if err := h.service.ForgotPassword(r.Context(), form); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.Message = "If this email is registered, a password reset link has been sent to it"
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// Reset устанавливает новый пароль по токену из письма.
+//
+// Возможные коды ответа:
+//   - 200: пароль изменен
+//   - 400: ошибка парсинга JSON
+//   - 422: ошибки валидации или недействительный токен
+//   - 500: внутренняя ошибка сервера
+func (h *PasswordHandler) Reset(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	if resp.IsValidMediaType(w, r) {
+		return
+	}
+	var form common.PasswordResetRequest
+	if !decodeForm(w, r, &form) {
+		return
+	}
+
+	if err := h.service.ResetPassword(r.Context(), form); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.Message = "Password has been changed"
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// writeError записывает ответ, соответствующий ошибке сервиса смены пароля.
+func (h *PasswordHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var resp helper.Response
+	switch {
+	case errors.Is(err, service.ErrInvalidPassword):
+		resp.Message = "Current password is not valid"
+		resp.ResponseWrite(w, r, http.StatusUnprocessableEntity)
+		return
+	case errors.Is(err, service.ErrInvalidToken):
+		resp.Message = "Password reset link is invalid or has expired"
+		resp.ResponseWrite(w, r, http.StatusUnprocessableEntity)
+		return
+	}
+	slog.Error(fmt.Sprintf("Error changing password: %s", err.Error()))
+	resp.ResponseWrite(w, r, http.StatusInternalServerError)
+}
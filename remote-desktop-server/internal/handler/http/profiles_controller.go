@@ -0,0 +1,79 @@
+// Package http_handler предоставляет HTTP обработчики для API RemoteDesktop.
+package http_handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/helper"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/service"
+)
+
+// ProfileHandler обрабатывает HTTP запросы изменения профиля текущего пользователя.
+type ProfileHandler struct {
+	service *service.ProfileService
+}
+
+// NewProfileHandler создает новый экземпляр ProfileHandler.
+//
+// Параметры:
+//   - service: сервис профиля пользователя
+//
+// Возвращает:
+//   - *ProfileHandler: указатель на созданный обработчик
+func NewProfileHandler(service *service.ProfileService) *ProfileHandler {
+	return &ProfileHandler{service: service}
+}
+
+// Update изменяет профиль текущего пользователя.
+//
+// Возможные коды ответа:
+//   - 200: профиль изменен; при смене почты на новый адрес отправлено письмо (pending_email)
+//   - 400: ошибка парсинга JSON
+//   - 409: новый адрес уже занят
+//   - 422: ошибки валидации, неверный пароль или почта задается внешним провайдером
+//   - 500: внутренняя ошибка сервера
+func (h *ProfileHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	if resp.IsValidMediaType(w, r) {
+		return
+	}
+	var form common.UserProfileRequest
+	if !decodeForm(w, r, &form) {
+		return
+	}
+
+	user, _ := r.Context().Value(common.USER).(*common.User)
+	data, err := h.service.UpdateProfile(r.Context(), user, form)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.Data = data
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// writeError записывает ответ, соответствующий ошибке сервиса профиля.
+func (h *ProfileHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var resp helper.Response
+	switch {
+	case errors.Is(err, service.ErrInvalidPassword):
+		resp.Message = "Current password is not valid"
+		resp.ResponseWrite(w, r, http.StatusUnprocessableEntity)
+		return
+	case errors.Is(err, service.ErrUnsupported):
+		resp.Message = "Email is managed by your identity provider"
+		resp.ResponseWrite(w, r, http.StatusUnprocessableEntity)
+		return
+	case errors.Is(err, service.ErrConflict):
+		resp.Message = "User with this email already exists"
+		resp.ResponseWrite(w, r, http.StatusConflict)
+		return
+	}
+	slog.Error(fmt.Sprintf("Error updating profile: %s", err.Error()))
+	resp.ResponseWrite(w, r, http.StatusInternalServerError)
+}
@@ -0,0 +1,86 @@
+// Package http_handler предоставляет HTTP обработчики для API RemoteDesktop.
+package http_handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/helper"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/service"
+)
+
+// ReconciliationHandler обрабатывает HTTP запросы сверки пользователей приложения и Guacamole.
+type ReconciliationHandler struct {
+	service *service.ReconciliationService
+}
+
+// NewReconciliationHandler создает новый экземпляр ReconciliationHandler.
+//
+// Параметры:
+//   - service: сервис сверки пользователей
+//
+// Возвращает:
+//   - *ReconciliationHandler: указатель на созданный обработчик
+func NewReconciliationHandler(service *service.ReconciliationService) *ReconciliationHandler {
+	return &ReconciliationHandler{service: service}
+}
+
+// Status возвращает состояние фоновой сверки и результат последнего запуска.
+func (h *ReconciliationHandler) Status(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	guacToken, ok := guacamoleToken(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := h.service.Status(guacToken)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.Data = data
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// Run немедленно запускает сверку и возвращает ее результат.
+//
+// Возможные коды ответа:
+//   - 200: сверка выполнена
+//   - 403: вызывающий не администратор
+//   - 409: сверка уже выполняется
+func (h *ReconciliationHandler) Run(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	guacToken, ok := guacamoleToken(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := h.service.RunNow(r.Context(), guacToken)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.Data = data
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// writeError записывает ответ, соответствующий ошибке сервиса сверки.
+func (h *ReconciliationHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var resp helper.Response
+	switch {
+	case errors.Is(err, service.ErrForbidden):
+		resp.Message = "Only administrators can view reconciliation status"
+		resp.ResponseWrite(w, r, http.StatusForbidden)
+		return
+	case errors.Is(err, service.ErrConflict):
+		resp.Message = "Reconciliation is already running"
+		resp.ResponseWrite(w, r, http.StatusConflict)
+		return
+	}
+	slog.Error(fmt.Sprintf("Error reconciling users: %s", err.Error()))
+	resp.ResponseWrite(w, r, http.StatusInternalServerError)
+}
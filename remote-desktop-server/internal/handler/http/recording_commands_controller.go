@@ -0,0 +1,118 @@
+// Package http_handler предоставляет HTTP обработчики для API RemoteDesktop.
+package http_handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/helper"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/service"
+)
+
+// CommandHandler обрабатывает HTTP запросы индекса команд из записей терминала.
+type CommandHandler struct {
+	service *service.CommandIndexService
+}
+
+// NewCommandHandler создает новый экземпляр CommandHandler.
+//
+// Параметры:
+//   - service: сервис индекса команд
+//
+// Возвращает:
+//   - *CommandHandler: указатель на созданный обработчик
+func NewCommandHandler(service *service.CommandIndexService) *CommandHandler {
+	return &CommandHandler{service: service}
+}
+
+// Search ищет команды во всех проиндексированных записях терминала.
+//
+// Параметры запроса:
+//   - q: подстрока команды (обязательный)
+//   - connection_id, username, from, to, page, per_page: как для истории подключений
+func (h *CommandHandler) Search(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	guacToken, ok := guacamoleToken(w, r)
+	if !ok {
+		return
+	}
+	historyFilter, err := parseHistoryFilter(r.URL.Query())
+	if err != nil {
+		resp.Message = err.Error()
+		resp.ResponseWrite(w, r, http.StatusBadRequest)
+		return
+	}
+	filter := &common.CommandSearchFilter{
+		Query:         r.URL.Query().Get("q"),
+		HistoryFilter: *historyFilter,
+	}
+	validate := helper.NewValidator()
+	if err := validate.Struct(filter); err != nil {
+		errs := err.(validator.ValidationErrors)
+		humanReadableErrors, err := helper.LocalizedValidationMessages(r.Context(), errs)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Error localizing validation messages: %s", err.Error()))
+			resp.ResponseWrite(w, r, http.StatusInternalServerError)
+			return
+		}
+		resp.Errors = humanReadableErrors
+		resp.ResponseWrite(w, r, http.StatusUnprocessableEntity)
+		return
+	}
+	email, _ := r.Context().Value(common.USER_MAIL).(string)
+
+	data, err := h.service.SearchCommands(r.Context(), filter, email, guacToken)
+	switch {
+	case errors.Is(err, service.ErrForbidden):
+		resp.Message = "You can not search sessions of other users"
+		resp.ResponseWrite(w, r, http.StatusForbidden)
+		return
+	case err != nil:
+		slog.Error(fmt.Sprintf("Error searching commands: %s", err.Error()))
+		resp.ResponseWrite(w, r, http.StatusInternalServerError)
+		return
+	}
+
+	resp.Data = data
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// Index немедленно индексирует запись typescript и возвращает найденные команды.
+func (h *CommandHandler) Index(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	id := chi.URLParam(r, "id")
+	name := chi.URLParam(r, "name")
+	if id == "" || name == "" {
+		resp.Message = "Connection ID and recording name are required"
+		resp.ResponseWrite(w, r, http.StatusBadRequest)
+		return
+	}
+	guacToken, ok := guacamoleToken(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := h.service.IndexRecording(r.Context(), id, name, guacToken)
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		resp.Message = "Recording not found"
+		resp.ResponseWrite(w, r, http.StatusNotFound)
+		return
+	case errors.Is(err, service.ErrUnsupported):
+		resp.Message = "Only typescript recordings can be indexed"
+		resp.ResponseWrite(w, r, http.StatusUnprocessableEntity)
+		return
+	case err != nil:
+		slog.Error(fmt.Sprintf("Error indexing recording: %s", err.Error()))
+		resp.ResponseWrite(w, r, http.StatusInternalServerError)
+		return
+	}
+
+	resp.Data = data
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
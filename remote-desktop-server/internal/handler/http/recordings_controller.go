@@ -0,0 +1,254 @@
+// Package http_handler предоставляет HTTP обработчики для API RemoteDesktop.
+package http_handler
+
+import (
+	"errors"
+	"fmt"
+	"image/png"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/helper"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/service"
+)
+
+const (
+	defaultThumbnailWidth = 320  // Ширина миниатюры по умолчанию
+	maxThumbnailWidth     = 1920 // Максимальная ширина миниатюры
+)
+
+// RecordingHandler обрабатывает HTTP запросы для работы с записями сессий подключений.
+type RecordingHandler struct {
+	service *service.RecordingService
+}
+
+// NewRecordingHandler создает новый экземпляр RecordingHandler.
+//
+// Параметры:
+//   - service: сервис записей сессий
+//
+// Возвращает:
+//   - *RecordingHandler: указатель на созданный обработчик
+func NewRecordingHandler(service *service.RecordingService) *RecordingHandler {
+	return &RecordingHandler{service: service}
+}
+
+// Get возвращает список записей подключения.
+func (h *RecordingHandler) Get(w http.ResponseWriter, r *http.Request) {
+	resp := helper.Response{}
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		resp.Message = "Connection ID is required"
+		resp.ResponseWrite(w, r, http.StatusBadRequest)
+		return
+	}
+	guacToken, ok := guacamoleToken(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := h.service.GetRecordings(r.Context(), id, guacToken)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.Data = data
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// Download отдает файл записи подключения.
+// Поддерживает запросы диапазонов (Range) для перемотки в плеере.
+func (h *RecordingHandler) Download(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	id := chi.URLParam(r, "id")
+	name := chi.URLParam(r, "name")
+	if id == "" || name == "" {
+		resp.Message = "Connection ID and recording name are required"
+		resp.ResponseWrite(w, r, http.StatusBadRequest)
+		return
+	}
+	guacToken, ok := guacamoleToken(w, r)
+	if !ok {
+		return
+	}
+
+	file, err := h.service.OpenRecording(id, name, guacToken)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	http.ServeContent(w, r, name, info.ModTime(), file)
+}
+
+// Index возвращает длительность, события клавиатуры и индекс перемотки записи.
+func (h *RecordingHandler) Index(w http.ResponseWriter, r *http.Request) {
+	resp := helper.Response{}
+	id := chi.URLParam(r, "id")
+	name := chi.URLParam(r, "name")
+	if id == "" || name == "" {
+		resp.Message = "Connection ID and recording name are required"
+		resp.ResponseWrite(w, r, http.StatusBadRequest)
+		return
+	}
+	guacToken, ok := guacamoleToken(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := h.service.GetRecordingIndex(id, name, guacToken)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.Data = data
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// Thumbnail возвращает PNG снимок экрана записи.
+//
+// Параметры запроса:
+//   - at: момент времени от начала записи в миллисекундах (по умолчанию 0)
+//   - width: максимальная ширина снимка в пикселях (по умолчанию 320)
+func (h *RecordingHandler) Thumbnail(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	id := chi.URLParam(r, "id")
+	name := chi.URLParam(r, "name")
+	if id == "" || name == "" {
+		resp.Message = "Connection ID and recording name are required"
+		resp.ResponseWrite(w, r, http.StatusBadRequest)
+		return
+	}
+	guacToken, ok := guacamoleToken(w, r)
+	if !ok {
+		return
+	}
+	at, err := queryInt(r, "at", 0)
+	if err != nil || at < 0 {
+		resp.Message = "Invalid at parameter"
+		resp.ResponseWrite(w, r, http.StatusBadRequest)
+		return
+	}
+	width, err := queryInt(r, "width", defaultThumbnailWidth)
+	if err != nil || width < 1 || width > maxThumbnailWidth {
+		resp.Message = "Invalid width parameter"
+		resp.ResponseWrite(w, r, http.StatusBadRequest)
+		return
+	}
+
+	thumbnail, err := h.service.GetRecordingThumbnail(id, name, time.Duration(at)*time.Millisecond, width, guacToken)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.WriteHeader(http.StatusOK)
+	if err := png.Encode(w, thumbnail); err != nil {
+		slog.Error(fmt.Sprintf("Error encoding thumbnail: %s", err.Error()))
+	}
+}
+
+// Asciicast преобразует запись typescript в формат asciicast v2 для воспроизведения
+// в терминальном плеере (например, asciinema).
+//
+// Параметры запроса:
+//   - width, height: размер терминала в колонках и строках (по умолчанию 80x24)
+func (h *RecordingHandler) Asciicast(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	id := chi.URLParam(r, "id")
+	name := chi.URLParam(r, "name")
+	if id == "" || name == "" {
+		resp.Message = "Connection ID and recording name are required"
+		resp.ResponseWrite(w, r, http.StatusBadRequest)
+		return
+	}
+	guacToken, ok := guacamoleToken(w, r)
+	if !ok {
+		return
+	}
+	width, widthErr := queryInt(r, "width", 0)
+	height, heightErr := queryInt(r, "height", 0)
+	if widthErr != nil || heightErr != nil || width < 0 || width > 1000 || height < 0 || height > 1000 {
+		resp.Message = "Invalid terminal size"
+		resp.ResponseWrite(w, r, http.StatusBadRequest)
+		return
+	}
+
+	stream, err := h.service.OpenAsciicast(id, name, width, height, guacToken)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	defer stream.Close()
+
+	w.Header().Set("Content-Type", "application/x-asciicast")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", stream.Name()))
+	w.WriteHeader(http.StatusOK)
+	if err := stream.Convert(w); err != nil {
+		slog.Error(fmt.Sprintf("Error converting recording %s: %s", name, err.Error()))
+	}
+}
+
+// Remove удаляет файл записи подключения.
+func (h *RecordingHandler) Remove(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	id := chi.URLParam(r, "id")
+	name := chi.URLParam(r, "name")
+	if id == "" || name == "" {
+		resp.Message = "Connection ID and recording name are required"
+		resp.ResponseWrite(w, r, http.StatusBadRequest)
+		return
+	}
+	guacToken, ok := guacamoleToken(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DestroyRecording(id, name, guacToken); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// writeError записывает ответ об ошибке сервиса записей.
+func (h *RecordingHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var resp helper.Response
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		resp.Message = "Recording not found"
+		resp.ResponseWrite(w, r, http.StatusNotFound)
+		return
+	case errors.Is(err, service.ErrUnsupported):
+		resp.Message = "Operation is not supported for this recording type"
+		resp.ResponseWrite(w, r, http.StatusUnprocessableEntity)
+		return
+	}
+	slog.Error(fmt.Sprintf("Error accessing recordings: %s", err.Error()))
+	resp.ResponseWrite(w, r, http.StatusInternalServerError)
+}
+
+// queryInt читает целое число из параметра строки запроса.
+// Если параметр не задан, возвращает значение по умолчанию.
+func queryInt(r *http.Request, name string, fallback int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(value)
+}
@@ -3,9 +3,13 @@ package http_handler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net"
 	"net/http"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
@@ -31,7 +35,8 @@ func NewSessionHandler(service *service.SessionService) *SessionHandler {
 }
 
 // Get возвращает список подключений.
-// Поддерживает фильтрацию по протоколу через query параметр.
+// Поддерживает фильтрацию по протоколу через query параметр
+// (all, ssh, rdp, vnc, telnet, kubernetes).
 func (h *SessionHandler) Get(w http.ResponseWriter, r *http.Request) {
 	resp := helper.Response{}
 	var protocol string
@@ -41,14 +46,17 @@ func (h *SessionHandler) Get(w http.ResponseWriter, r *http.Request) {
 	} else {
 		protocol = "all"
 	}
-
-	guacToken := r.Header.Get("Guacamole-Token")
-	if guacToken == "" {
-		resp.Message = "Guacamole-Token is required"
+	if protocol != "all" && !common.IsSupportedProtocol(protocol) {
+		resp.Message = "Unsupported protocol"
 		resp.ResponseWrite(w, r, http.StatusBadRequest)
 		return
 	}
 
+	guacToken, ok := guacamoleToken(w, r)
+	if !ok {
+		return
+	}
+
 	data, err := h.service.GetSession(protocol, guacToken)
 	if err != nil {
 		resp.ResponseWrite(w, r, http.StatusNotFound)
@@ -69,10 +77,8 @@ func (h *SessionHandler) Edit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	guacToken := r.Header.Get("Guacamole-Token")
-	if guacToken == "" {
-		resp.Message = "Guacamole-Token is required"
-		resp.ResponseWrite(w, r, http.StatusBadRequest)
+	guacToken, ok := guacamoleToken(w, r)
+	if !ok {
 		return
 	}
 
@@ -89,10 +95,8 @@ func (h *SessionHandler) Edit(w http.ResponseWriter, r *http.Request) {
 // StoreConnection создает новое подключение.
 func (h *SessionHandler) StoreConnection(w http.ResponseWriter, r *http.Request) {
 	var resp helper.Response
-	guacToken := r.Header.Get("Guacamole-Token")
-	if guacToken == "" {
-		resp.Message = "Guacamole-Token is required"
-		resp.ResponseWrite(w, r, http.StatusBadRequest)
+	guacToken, ok := guacamoleToken(w, r)
+	if !ok {
 		return
 	}
 	var form common.GuacamoleConnectionRequest
@@ -102,7 +106,7 @@ func (h *SessionHandler) StoreConnection(w http.ResponseWriter, r *http.Request)
 		resp.ResponseWrite(w, r, http.StatusBadRequest)
 		return
 	}
-	validate := validator.New()
+	validate := helper.NewValidator()
 	err := validate.Struct(form)
 	if err != nil {
 		errs := err.(validator.ValidationErrors)
@@ -133,10 +137,8 @@ func (h *SessionHandler) UpdateConnection(w http.ResponseWriter, r *http.Request
 		resp.ResponseWrite(w, r, http.StatusBadRequest)
 		return
 	}
-	guacToken := r.Header.Get("Guacamole-Token")
-	if guacToken == "" {
-		resp.Message = "Guacamole-Token is required"
-		resp.ResponseWrite(w, r, http.StatusBadRequest)
+	guacToken, ok := guacamoleToken(w, r)
+	if !ok {
 		return
 	}
 	var form common.GuacamoleConnectionRequest
@@ -146,7 +148,7 @@ func (h *SessionHandler) UpdateConnection(w http.ResponseWriter, r *http.Request
 		resp.ResponseWrite(w, r, http.StatusBadRequest)
 		return
 	}
-	validate := validator.New()
+	validate := helper.NewValidator()
 	err := validate.Struct(form)
 	if err != nil {
 		errs := err.(validator.ValidationErrors)
@@ -181,10 +183,8 @@ func (h *SessionHandler) RemoveConnection(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	guacToken := r.Header.Get("Guacamole-Token")
-	if guacToken == "" {
-		resp.Message = "Guacamole-Token is required"
-		resp.ResponseWrite(w, r, http.StatusBadRequest)
+	guacToken, ok := guacamoleToken(w, r)
+	if !ok {
 		return
 	}
 
@@ -196,3 +196,128 @@ func (h *SessionHandler) RemoveConnection(w http.ResponseWriter, r *http.Request
 
 	resp.ResponseWrite(w, r, http.StatusOK)
 }
+
+// MoveConnection перемещает подключение в другую группу подключений.
+func (h *SessionHandler) MoveConnection(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		resp.Message = "Connection ID is required"
+		resp.ResponseWrite(w, r, http.StatusBadRequest)
+		return
+	}
+	guacToken, ok := guacamoleToken(w, r)
+	if !ok {
+		return
+	}
+	var form common.MoveConnectionRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 10<<20) // Ограничение тела запроса 10MB
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		resp.Message = "Invalid JSON"
+		resp.ResponseWrite(w, r, http.StatusBadRequest)
+		return
+	}
+	validate := helper.NewValidator()
+	err := validate.Struct(form)
+	if err != nil {
+		errs := err.(validator.ValidationErrors)
+		humanReadableErrors, err := helper.LocalizedValidationMessages(r.Context(), errs)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Error localizing validation messages: %s", err.Error()))
+			resp.ResponseWrite(w, r, http.StatusInternalServerError)
+			return
+		}
+		resp.Errors = humanReadableErrors
+		resp.ResponseWrite(w, r, http.StatusUnprocessableEntity)
+		return
+	}
+
+	if err := h.service.MoveConnection(id, form.ParentIdentifier, guacToken); err != nil {
+		slog.Error(fmt.Sprintf("Error moving connection: %s", err.Error()))
+		resp.ResponseWrite(w, r, http.StatusInternalServerError)
+		return
+	}
+
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// Tunnel проксирует WebSocket туннель Guacamole к подключению.
+// Браузер аутентифицируется JWT токеном (заголовок Authorization или параметр token),
+// а токен Guacamole подставляет сервер. Параметры GUAC_* строки запроса
+// (размер экрана, аудио, форматы изображений) передаются в Guacamole.
+func (h *SessionHandler) Tunnel(w http.ResponseWriter, r *http.Request) {
+	resp := helper.Response{}
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		resp.Message = "Connection ID is required"
+		resp.ResponseWrite(w, r, http.StatusBadRequest)
+		return
+	}
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		resp.Message = "WebSocket upgrade is required"
+		resp.ResponseWrite(w, r, http.StatusBadRequest)
+		return
+	}
+	guacToken, ok := guacamoleToken(w, r)
+	if !ok {
+		return
+	}
+	if _, ok := w.(http.Hijacker); !ok {
+		slog.Error("Error opening tunnel: the ResponseWriter doesn't support hijacking")
+		resp.ResponseWrite(w, r, http.StatusInternalServerError)
+		return
+	}
+
+	upstream, header, err := h.service.OpenTunnel(id, guacToken, r.URL.Query(), r.Header)
+	if err != nil {
+		var apiErr *service.GuacamoleAPIError
+		switch {
+		case errors.Is(err, service.ErrInvalidToken):
+			resp.Message = "You should be authorized!"
+			resp.ResponseWrite(w, r, http.StatusUnauthorized)
+		case errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusForbidden || apiErr.StatusCode == http.StatusNotFound):
+			resp.Message = "Connection not found"
+			resp.ResponseWrite(w, r, http.StatusNotFound)
+		default:
+			slog.Error(fmt.Sprintf("Error opening tunnel: %s", err.Error()))
+			resp.ResponseWrite(w, r, http.StatusBadGateway)
+		}
+		return
+	}
+	proxyTunnel(w, r, upstream, header)
+}
+
+// proxyTunnel завершает рукопожатие WebSocket с браузером ответом Guacamole и передает данные
+// между браузером и туннелем Guacamole, пока одна из сторон не закроет соединение.
+// ResponseWriter должен поддерживать http.Hijacker; соединение upstream закрывается.
+func proxyTunnel(w http.ResponseWriter, r *http.Request, upstream net.Conn, header http.Header) {
+	defer upstream.Close()
+	resp := helper.Response{}
+
+	client, buffered, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		slog.Error(fmt.Sprintf("Error hijacking tunnel connection: %s", err.Error()))
+		resp.ResponseWrite(w, r, http.StatusInternalServerError)
+		return
+	}
+	defer client.Close()
+
+	buffered.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	header.Write(buffered)
+	buffered.WriteString("\r\n")
+	if err := buffered.Flush(); err != nil {
+		return
+	}
+
+	// Соединения закрываются, как только одна из сторон завершает туннель
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(upstream, buffered)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(client, upstream)
+		done <- struct{}{}
+	}()
+	<-done
+}
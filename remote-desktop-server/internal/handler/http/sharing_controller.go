@@ -0,0 +1,310 @@
+// Package http_handler предоставляет HTTP обработчики для API RemoteDesktop.
+package http_handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/helper"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/service"
+)
+
+// SharingHandler обрабатывает HTTP запросы профилей и ссылок совместного доступа к сессиям.
+type SharingHandler struct {
+	service *service.SharingService
+}
+
+// NewSharingHandler создает новый экземпляр SharingHandler.
+//
+// Параметры:
+//   - service: сервис совместного доступа
+//
+// Возвращает:
+//   - *SharingHandler: указатель на созданный обработчик
+func NewSharingHandler(service *service.SharingService) *SharingHandler {
+	return &SharingHandler{service: service}
+}
+
+// GetProfiles возвращает профили совместного доступа подключения.
+func (h *SharingHandler) GetProfiles(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	id, guacToken, ok := h.prepare(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := h.service.GetSharingProfiles(id, guacToken)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.Data = data
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// StoreProfile создает профиль совместного доступа (только просмотр или полное управление).
+func (h *SharingHandler) StoreProfile(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	id, guacToken, ok := h.prepare(w, r)
+	if !ok {
+		return
+	}
+	var form common.SharingProfileRequest
+	if !decodeForm(w, r, &form) {
+		return
+	}
+
+	data, err := h.service.CreateSharingProfile(id, &form, guacToken)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.Data = data
+	resp.ResponseWrite(w, r, http.StatusCreated)
+}
+
+// RemoveProfile удаляет профиль совместного доступа.
+func (h *SharingHandler) RemoveProfile(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	id, guacToken, ok := h.prepare(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DestroySharingProfile(id, chi.URLParam(r, "profileId"), guacToken); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// GetLinks возвращает ссылки совместного доступа подключения.
+// Администратор видит все ссылки, остальные пользователи — только свои.
+func (h *SharingHandler) GetLinks(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	id, guacToken, ok := h.prepare(w, r)
+	if !ok {
+		return
+	}
+	email, _ := r.Context().Value(common.USER_MAIL).(string)
+
+	data, err := h.service.GetShareLinks(r.Context(), id, email, guacToken)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.Data = data
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// StoreLink создает ссылку совместного доступа к активной сессии вызывающего.
+// Ссылка возвращается только в ответе на этот запрос.
+//
+// Возможные коды ответа:
+//   - 201: ссылка создана
+//   - 404: подключение или профиль не найдены
+//   - 409: у вызывающего нет активной сессии к подключению
+//   - 422: ошибка валидации
+func (h *SharingHandler) StoreLink(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	id, guacToken, ok := h.prepare(w, r)
+	if !ok {
+		return
+	}
+	var form common.ShareLinkRequest
+	if !decodeForm(w, r, &form) {
+		return
+	}
+	email, _ := r.Context().Value(common.USER_MAIL).(string)
+
+	link, token, err := h.service.CreateShareLink(r.Context(), id, &form, email, guacToken)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	link.URL = fmt.Sprintf("%s://%s/share/%s", requestScheme(r), r.Host, token)
+
+	resp.Data = link
+	resp.ResponseWrite(w, r, http.StatusCreated)
+}
+
+// RevokeLink отзывает ссылку совместного доступа.
+func (h *SharingHandler) RevokeLink(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	id, guacToken, ok := h.prepare(w, r)
+	if !ok {
+		return
+	}
+	email, _ := r.Context().Value(common.USER_MAIL).(string)
+
+	if err := h.service.RevokeShareLink(r.Context(), id, chi.URLParam(r, "linkId"), email, guacToken); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// GetLinkUses возвращает журнал переходов по ссылке совместного доступа.
+func (h *SharingHandler) GetLinkUses(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	id, guacToken, ok := h.prepare(w, r)
+	if !ok {
+		return
+	}
+	email, _ := r.Context().Value(common.USER_MAIL).(string)
+
+	data, err := h.service.GetShareLinkUses(r.Context(), id, chi.URLParam(r, "linkId"), email, guacToken)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.Data = data
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// Redeem открывает ссылку совместного доступа.
+// Маршрут публичный: доступ определяется только токеном ссылки.
+// Браузер перенаправляется на страницу просмотра (SHARE_VIEWER_URL) с токеном во фрагменте адреса,
+// а страница открывает по тому же адресу WebSocket туннель к сессии: ключ совместного доступа
+// Guacamole браузеру не передается, а туннель закрывается, когда ссылка истекает или отзывается.
+// Параметры GUAC_* строки запроса туннеля (размер экрана, аудио, форматы изображений)
+// передаются в Guacamole.
+//
+// Возможные коды ответа:
+//   - 101: туннель открыт (запрос с Upgrade: websocket)
+//   - 302: перенаправление на страницу просмотра
+//   - 410: ссылка не существует, отозвана, истекла или исчерпана либо сессия владельца завершена
+//   - 502: Guacamole недоступен
+func (h *SharingHandler) Redeem(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	token := chi.URLParam(r, "token")
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		h.join(w, r, token)
+		return
+	}
+
+	target, err := h.service.Redeem(r.Context(), token)
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		resp.Message = "Share link is expired or revoked"
+		resp.ResponseWrite(w, r, http.StatusGone)
+		return
+	case err != nil:
+		slog.Error(fmt.Sprintf("Error redeeming share link: %s", err.Error()))
+		resp.ResponseWrite(w, r, http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// join открывает туннель зрителя к сессии по ссылке совместного доступа
+func (h *SharingHandler) join(w http.ResponseWriter, r *http.Request, token string) {
+	var resp helper.Response
+	if _, ok := w.(http.Hijacker); !ok {
+		slog.Error("Error opening shared tunnel: the ResponseWriter doesn't support hijacking")
+		resp.ResponseWrite(w, r, http.StatusInternalServerError)
+		return
+	}
+
+	upstream, header, err := h.service.Join(r.Context(), token, r.RemoteAddr, r.UserAgent(), r.URL.Query(), r.Header)
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		resp.Message = "Share link is expired or revoked"
+		resp.ResponseWrite(w, r, http.StatusGone)
+		return
+	case err != nil:
+		slog.Error(fmt.Sprintf("Error opening shared tunnel: %s", err.Error()))
+		resp.ResponseWrite(w, r, http.StatusBadGateway)
+		return
+	}
+	proxyTunnel(w, r, upstream, header)
+}
+
+// prepare читает идентификатор подключения и токен Guacamole.
+// При ошибке записывает ответ и возвращает ok=false.
+func (h *SharingHandler) prepare(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	var resp helper.Response
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		resp.Message = "Connection ID is required"
+		resp.ResponseWrite(w, r, http.StatusBadRequest)
+		return "", "", false
+	}
+	guacToken, ok := guacamoleToken(w, r)
+	if !ok {
+		return "", "", false
+	}
+	return id, guacToken, true
+}
+
+// decodeForm читает JSON тело запроса в форму и валидирует ее.
+// При ошибке записывает ответ и возвращает false.
+func decodeForm(w http.ResponseWriter, r *http.Request, form interface{}) bool {
+	var resp helper.Response
+	r.Body = http.MaxBytesReader(w, r.Body, 10<<20) // Ограничение тела запроса 10MB
+	if err := json.NewDecoder(r.Body).Decode(form); err != nil {
+		resp.Message = "Invalid JSON"
+		resp.ResponseWrite(w, r, http.StatusBadRequest)
+		return false
+	}
+	validate := helper.NewValidator()
+	if err := validate.Struct(form); err != nil {
+		errs := err.(validator.ValidationErrors)
+		humanReadableErrors, err := helper.LocalizedValidationMessages(r.Context(), errs)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Error localizing validation messages: %s", err.Error()))
+			resp.ResponseWrite(w, r, http.StatusInternalServerError)
+			return false
+		}
+		resp.Errors = humanReadableErrors
+		resp.ResponseWrite(w, r, http.StatusUnprocessableEntity)
+		return false
+	}
+	return true
+}
+
+// writeError записывает ответ, соответствующий ошибке сервиса совместного доступа.
+func (h *SharingHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var resp helper.Response
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		resp.Message = "Connection, sharing profile or share link not found"
+		resp.ResponseWrite(w, r, http.StatusNotFound)
+		return
+	case errors.Is(err, service.ErrForbidden):
+		resp.Message = "You can not manage share links of other users"
+		resp.ResponseWrite(w, r, http.StatusForbidden)
+		return
+	case errors.Is(err, service.ErrUnsupported):
+		resp.Message = "Open the connection before sharing it"
+		resp.ResponseWrite(w, r, http.StatusConflict)
+		return
+	}
+	slog.Error(fmt.Sprintf("Error managing session sharing: %s", err.Error()))
+	resp.ResponseWrite(w, r, http.StatusInternalServerError)
+}
+
+// requestScheme определяет схему запроса с учетом обратного прокси
+func requestScheme(r *http.Request) string {
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		return proto
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
@@ -0,0 +1,250 @@
+// Package http_handler предоставляет HTTP обработчики для API RemoteDesktop.
+package http_handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/helper"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/service"
+)
+
+// TwoFactorHandler обрабатывает HTTP запросы двухфакторной аутентификации.
+type TwoFactorHandler struct {
+	service *service.TwoFactorService
+}
+
+// NewTwoFactorHandler создает новый экземпляр TwoFactorHandler.
+//
+// Параметры:
+//   - service: сервис двухфакторной аутентификации
+//
+// Возвращает:
+//   - *TwoFactorHandler: указатель на созданный обработчик
+func NewTwoFactorHandler(service *service.TwoFactorService) *TwoFactorHandler {
+	return &TwoFactorHandler{service: service}
+}
+
+// Status возвращает состояние двухфакторной аутентификации текущего пользователя.
+func (h *TwoFactorHandler) Status(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	email, _ := r.Context().Value(common.USER_MAIL).(string)
+	status, err := h.service.Status(r.Context(), email)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.Data = status
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// Enroll выдает новый секрет TOTP текущему пользователю.
+//
+// Возможные коды ответа:
+//   - 200: возвращает секрет и URI otpauth:// для QR кода
+//   - 409: TOTP уже подключен
+//   - 500: внутренняя ошибка сервера
+func (h *TwoFactorHandler) Enroll(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	email, _ := r.Context().Value(common.USER_MAIL).(string)
+	enrollment, err := h.service.Enroll(r.Context(), email)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.Data = enrollment
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// Enable подтверждает секрет TOTP кодом из приложения-аутентификатора.
+//
+// Возможные коды ответа:
+//   - 200: TOTP подключен, возвращает коды восстановления
+//   - 400: ошибка парсинга JSON
+//   - 404: секрет не выдавался
+//   - 409: TOTP уже подключен
+//   - 422: ошибки валидации или неверный код
+//   - 500: внутренняя ошибка сервера
+func (h *TwoFactorHandler) Enable(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	if resp.IsValidMediaType(w, r) {
+		return
+	}
+	var form common.TwoFactorCodeRequest
+	if !decodeForm(w, r, &form) {
+		return
+	}
+
+	email, _ := r.Context().Value(common.USER_MAIL).(string)
+	codes, err := h.service.Enable(r.Context(), email, form)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.Data = codes
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// Disable отключает TOTP текущего пользователя.
+//
+// Возможные коды ответа:
+//   - 200: TOTP отключен
+//   - 400: ошибка парсинга JSON
+//   - 403: TOTP обязателен для пользователя
+//   - 404: TOTP не подключен
+//   - 422: ошибки валидации, неверный пароль или код
+//   - 500: внутренняя ошибка сервера
+func (h *TwoFactorHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	if resp.IsValidMediaType(w, r) {
+		return
+	}
+	var form common.TwoFactorDisableRequest
+	if !decodeForm(w, r, &form) {
+		return
+	}
+
+	email, _ := r.Context().Value(common.USER_MAIL).(string)
+	if err := h.service.Disable(r.Context(), email, form); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.Message = "Two-factor authentication has been disabled"
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// RegenerateRecoveryCodes заменяет коды восстановления текущего пользователя.
+//
+// Возможные коды ответа:
+//   - 200: возвращает новые коды восстановления
+//   - 400: ошибка парсинга JSON
+//   - 404: TOTP не подключен
+//   - 422: ошибки валидации или неверный код
+//   - 500: внутренняя ошибка сервера
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	if resp.IsValidMediaType(w, r) {
+		return
+	}
+	var form common.TwoFactorCodeRequest
+	if !decodeForm(w, r, &form) {
+		return
+	}
+
+	email, _ := r.Context().Value(common.USER_MAIL).(string)
+	codes, err := h.service.RegenerateRecoveryCodes(r.Context(), email, form)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.Data = codes
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// Verify завершает вход кодом TOTP или кодом восстановления (второй шаг POST /auth/sign-in).
+//
+// Возможные коды ответа:
+//   - 200: вход выполнен, возвращает токены (и коды восстановления, если TOTP подключен при входе)
+//   - 400: ошибка парсинга JSON
+//   - 401: токен второго шага недействителен, истек или исчерпаны попытки
+//   - 422: ошибки валидации или неверный код
+//   - 429: слишком много неудачных попыток входа; в заголовке Retry-After — секунды до следующей попытки
+//   - 500: внутренняя ошибка сервера
+func (h *TwoFactorHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	if resp.IsValidMediaType(w, r) {
+		return
+	}
+	var form common.TwoFactorChallengeRequest
+	if !decodeForm(w, r, &form) {
+		return
+	}
+
+	tokens, err := h.service.Complete(r.Context(), form, r.RemoteAddr, r.Header.Get("X-Forwarded-For"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.Data = tokens
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// EnrollChallenge выдает секрет TOTP во время входа, если TOTP обязателен, но не подключен.
+// Секрет подтверждается первым кодом в POST /auth/two-factor.
+//
+// Возможные коды ответа:
+//   - 200: возвращает секрет и URI otpauth:// для QR кода
+//   - 400: ошибка парсинга JSON
+//   - 401: токен второго шага недействителен
+//   - 409: TOTP уже подключен
+//   - 422: ошибки валидации
+//   - 500: внутренняя ошибка сервера
+func (h *TwoFactorHandler) EnrollChallenge(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	if resp.IsValidMediaType(w, r) {
+		return
+	}
+	var form common.TwoFactorEnrollRequest
+	if !decodeForm(w, r, &form) {
+		return
+	}
+
+	enrollment, err := h.service.EnrollChallenge(r.Context(), form)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.Data = enrollment
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// writeError записывает ответ, соответствующий ошибке сервиса двухфакторной аутентификации.
+func (h *TwoFactorHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var resp helper.Response
+	var throttled *service.LoginThrottledError
+	switch {
+	case errors.As(err, &throttled):
+		w.Header().Set("Retry-After", strconv.Itoa(int(throttled.RetryAfter/time.Second)))
+		resp.Message = err.Error()
+		resp.ResponseWrite(w, r, http.StatusTooManyRequests)
+		return
+	case errors.Is(err, service.ErrInvalidToken):
+		resp.Message = "Two-factor challenge is invalid or has expired, sign in again"
+		resp.ResponseWrite(w, r, http.StatusUnauthorized)
+		return
+	case errors.Is(err, service.ErrInvalidCode):
+		resp.Message = "Two-factor code is not valid"
+		resp.ResponseWrite(w, r, http.StatusUnprocessableEntity)
+		return
+	case errors.Is(err, service.ErrInvalidPassword):
+		resp.Message = "Current password is not valid"
+		resp.ResponseWrite(w, r, http.StatusUnprocessableEntity)
+		return
+	case errors.Is(err, service.ErrForbidden):
+		resp.Message = "Two-factor authentication is required for your account"
+		resp.ResponseWrite(w, r, http.StatusForbidden)
+		return
+	case errors.Is(err, service.ErrNotFound):
+		resp.Message = "Two-factor authentication is not set up"
+		resp.ResponseWrite(w, r, http.StatusNotFound)
+		return
+	case errors.Is(err, service.ErrConflict):
+		resp.Message = "Two-factor authentication is already enabled"
+		resp.ResponseWrite(w, r, http.StatusConflict)
+		return
+	}
+	slog.Error(fmt.Sprintf("Error processing two-factor authentication: %s", err.Error()))
+	resp.ResponseWrite(w, r, http.StatusInternalServerError)
+}
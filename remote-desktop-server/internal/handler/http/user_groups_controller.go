@@ -0,0 +1,231 @@
+// Package http_handler предоставляет HTTP обработчики для API RemoteDesktop.
+package http_handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/helper"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/service"
+)
+
+// UserGroupHandler обрабатывает HTTP запросы для работы с группами пользователей.
+type UserGroupHandler struct {
+	service *service.UserGroupService
+}
+
+// NewUserGroupHandler создает новый экземпляр UserGroupHandler.
+//
+// Параметры:
+//   - service: сервис групп пользователей
+//
+// Возвращает:
+//   - *UserGroupHandler: указатель на созданный обработчик
+func NewUserGroupHandler(service *service.UserGroupService) *UserGroupHandler {
+	return &UserGroupHandler{service: service}
+}
+
+// Get возвращает список групп пользователей.
+func (h *UserGroupHandler) Get(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	data, err := h.service.GetGroups(r.Context())
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.Data = data
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// Show возвращает группу вместе с участниками и вложенными группами.
+func (h *UserGroupHandler) Show(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	data, err := h.service.GetGroup(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.Data = data
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// StoreGroup создает группу пользователей.
+//
+// Возможные коды ответа:
+//   - 201: группа создана
+//   - 403: вызывающий не администратор
+//   - 409: группа с таким названием уже существует
+//   - 422: ошибка валидации
+func (h *UserGroupHandler) StoreGroup(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	guacToken, ok := h.prepare(w, r)
+	if !ok {
+		return
+	}
+	var form common.UserGroupRequest
+	if !decodeForm(w, r, &form) {
+		return
+	}
+
+	data, err := h.service.CreateGroup(r.Context(), &form, guacToken)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.Data = data
+	resp.ResponseWrite(w, r, http.StatusCreated)
+}
+
+// UpdateGroup изменяет название и описание группы пользователей.
+func (h *UserGroupHandler) UpdateGroup(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	guacToken, ok := h.prepare(w, r)
+	if !ok {
+		return
+	}
+	var form common.UserGroupRequest
+	if !decodeForm(w, r, &form) {
+		return
+	}
+
+	data, err := h.service.UpdateGroup(r.Context(), chi.URLParam(r, "id"), &form, guacToken)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.Data = data
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// RemoveGroup удаляет группу пользователей.
+func (h *UserGroupHandler) RemoveGroup(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	guacToken, ok := h.prepare(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DestroyGroup(r.Context(), chi.URLParam(r, "id"), guacToken); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// StoreMember добавляет пользователя в группу.
+func (h *UserGroupHandler) StoreMember(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	guacToken, ok := h.prepare(w, r)
+	if !ok {
+		return
+	}
+	var form common.UserGroupMemberRequest
+	if !decodeForm(w, r, &form) {
+		return
+	}
+
+	if err := h.service.AddMember(r.Context(), chi.URLParam(r, "id"), &form, guacToken); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// RemoveMember удаляет пользователя из группы.
+func (h *UserGroupHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	guacToken, ok := h.prepare(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.service.RemoveMember(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "userId"), guacToken)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// StoreSubgroup вкладывает группу в другую группу.
+func (h *UserGroupHandler) StoreSubgroup(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	guacToken, ok := h.prepare(w, r)
+	if !ok {
+		return
+	}
+	var form common.UserSubgroupRequest
+	if !decodeForm(w, r, &form) {
+		return
+	}
+
+	if err := h.service.AddSubgroup(r.Context(), chi.URLParam(r, "id"), &form, guacToken); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// RemoveSubgroup убирает вложенную группу из родительской группы.
+func (h *UserGroupHandler) RemoveSubgroup(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	guacToken, ok := h.prepare(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.service.RemoveSubgroup(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "childId"), guacToken)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// prepare читает токен Guacamole, необходимый для проверки прав администратора.
+// При ошибке записывает ответ и возвращает ok=false.
+func (h *UserGroupHandler) prepare(w http.ResponseWriter, r *http.Request) (string, bool) {
+	guacToken, ok := guacamoleToken(w, r)
+	if !ok {
+		return "", false
+	}
+	return guacToken, true
+}
+
+// writeError записывает ответ, соответствующий ошибке сервиса групп пользователей.
+func (h *UserGroupHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var resp helper.Response
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		resp.Message = "User group or user not found"
+		resp.ResponseWrite(w, r, http.StatusNotFound)
+		return
+	case errors.Is(err, service.ErrForbidden):
+		resp.Message = "Only administrators can manage user groups"
+		resp.ResponseWrite(w, r, http.StatusForbidden)
+		return
+	case errors.Is(err, service.ErrConflict):
+		resp.Message = "User group with this name already exists"
+		resp.ResponseWrite(w, r, http.StatusConflict)
+		return
+	case errors.Is(err, service.ErrUnsupported):
+		resp.Message = "A group can not be nested into itself"
+		resp.ResponseWrite(w, r, http.StatusUnprocessableEntity)
+		return
+	}
+	slog.Error(fmt.Sprintf("Error managing user groups: %s", err.Error()))
+	resp.ResponseWrite(w, r, http.StatusInternalServerError)
+}
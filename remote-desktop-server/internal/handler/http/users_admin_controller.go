@@ -0,0 +1,228 @@
+// Package http_handler предоставляет HTTP обработчики для API RemoteDesktop.
+package http_handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/helper"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/service"
+)
+
+const defaultUsersPerPage = 50 // Размер страницы списка пользователей по умолчанию
+
+// UserAdminHandler обрабатывает HTTP запросы администратора для управления пользователями.
+type UserAdminHandler struct {
+	service *service.UserAdminService
+}
+
+// NewUserAdminHandler создает новый экземпляр UserAdminHandler.
+//
+// Параметры:
+//   - service: сервис управления пользователями
+//
+// Возвращает:
+//   - *UserAdminHandler: указатель на созданный обработчик
+func NewUserAdminHandler(service *service.UserAdminService) *UserAdminHandler {
+	return &UserAdminHandler{service: service}
+}
+
+// Get возвращает страницу списка пользователей.
+//
+// Параметры запроса:
+//   - search: подстрока имени или email
+//   - role: admin, operator или viewer
+//   - status: active или disabled
+//   - page, per_page: пагинация
+func (h *UserAdminHandler) Get(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	filter, err := parseUserFilter(r.URL.Query())
+	if err != nil {
+		resp.Message = err.Error()
+		resp.ResponseWrite(w, r, http.StatusBadRequest)
+		return
+	}
+	validate := helper.NewValidator()
+	if err := validate.Struct(filter); err != nil {
+		errs := err.(validator.ValidationErrors)
+		humanReadableErrors, err := helper.LocalizedValidationMessages(r.Context(), errs)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Error localizing validation messages: %s", err.Error()))
+			resp.ResponseWrite(w, r, http.StatusInternalServerError)
+			return
+		}
+		resp.Errors = humanReadableErrors
+		resp.ResponseWrite(w, r, http.StatusUnprocessableEntity)
+		return
+	}
+
+	page, err := h.service.GetUsers(r.Context(), filter)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	resp.Data = page
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// Show возвращает пользователя вместе с состоянием его учетной записи Guacamole.
+func (h *UserAdminHandler) Show(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	data, err := h.service.GetUser(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	resp.Data = data
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// Permissions возвращает разрешения пользователя в приложении и в Guacamole.
+func (h *UserAdminHandler) Permissions(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	data, err := h.service.GetPermissions(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	resp.Data = data
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// UpdateRole назначает пользователю роль.
+//
+// Возможные коды ответа:
+//   - 200: роль назначена
+//   - 404: пользователь не найден или отключен
+//   - 422: ошибка валидации или попытка изменить собственную роль
+func (h *UserAdminHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	var form common.UserRoleRequest
+	if !decodeForm(w, r, &form) {
+		return
+	}
+	actor, _ := r.Context().Value(common.USER).(*common.User)
+
+	if err := h.service.SetRole(r.Context(), actor, chi.URLParam(r, "id"), form.Role); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// Disable отключает пользователя и отзывает его сессии входа.
+func (h *UserAdminHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	actor, _ := r.Context().Value(common.USER).(*common.User)
+
+	if err := h.service.Disable(r.Context(), actor, chi.URLParam(r, "id")); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// Enable снова включает отключенного пользователя.
+//
+// Возможные коды ответа:
+//   - 200: пользователь включен
+//   - 404: пользователь не найден
+//   - 409: с той же почтой зарегистрирован другой действующий пользователь
+func (h *UserAdminHandler) Enable(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	actor, _ := r.Context().Value(common.USER).(*common.User)
+
+	if err := h.service.Enable(r.Context(), actor, chi.URLParam(r, "id")); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// ExpirePassword помечает пароль пользователя истекшим.
+//
+// Возможные коды ответа:
+//   - 200: пароль помечен истекшим, сессии пользователя отозваны
+//   - 404: пользователь не найден или отключен
+//   - 422: пароль пользователя управляется внешним провайдером
+func (h *UserAdminHandler) ExpirePassword(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	actor, _ := r.Context().Value(common.USER).(*common.User)
+
+	if err := h.service.ExpirePassword(r.Context(), actor, chi.URLParam(r, "id")); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// Remove удаляет пользователя вместе с его учетной записью Guacamole.
+func (h *UserAdminHandler) Remove(w http.ResponseWriter, r *http.Request) {
+	var resp helper.Response
+	actor, _ := r.Context().Value(common.USER).(*common.User)
+
+	if err := h.service.Delete(r.Context(), actor, chi.URLParam(r, "id")); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	resp.ResponseWrite(w, r, http.StatusOK)
+}
+
+// writeError записывает ответ, соответствующий ошибке сервиса управления пользователями.
+func (h *UserAdminHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var resp helper.Response
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		resp.Message = "User not found"
+		resp.ResponseWrite(w, r, http.StatusNotFound)
+		return
+	case errors.Is(err, service.ErrConflict):
+		resp.Message = "Another active user with this email already exists"
+		resp.ResponseWrite(w, r, http.StatusConflict)
+		return
+	case errors.Is(err, service.ErrUnsupported):
+		resp.Message = strings.TrimPrefix(err.Error(), service.ErrUnsupported.Error()+": ")
+		resp.ResponseWrite(w, r, http.StatusUnprocessableEntity)
+		return
+	}
+	slog.Error(fmt.Sprintf("Error managing users: %s", err.Error()))
+	resp.ResponseWrite(w, r, http.StatusInternalServerError)
+}
+
+// parseUserFilter разбирает параметры списка пользователей из строки запроса.
+//
+// Параметры:
+//   - query: параметры строки запроса
+//
+// Возвращает:
+//   - *common.UserFilter: фильтр со значениями по умолчанию для незаданных параметров
+//   - error: ошибка, если параметр пагинации имеет неверный формат
+func parseUserFilter(query url.Values) (*common.UserFilter, error) {
+	filter := &common.UserFilter{
+		Search:  strings.TrimSpace(query.Get("search")),
+		Role:    query.Get("role"),
+		Status:  query.Get("status"),
+		Page:    1,
+		PerPage: defaultUsersPerPage,
+	}
+	for name, dst := range map[string]*int{"page": &filter.Page, "per_page": &filter.PerPage} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %q", name, value)
+		}
+		*dst = number
+	}
+	return filter, nil
+}
@@ -8,6 +8,7 @@ import (
 	"net"
 	"net/http"
 
+	"github.com/google/uuid"
 	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
 	"github.com/margar-melkonyan/remote-desktop.git/internal/common/dependency"
 	"github.com/margar-melkonyan/remote-desktop.git/internal/helper"
@@ -38,8 +39,10 @@ func (w *responseWriterWrapper) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 //	   1. Проверяет наличие токена в заголовке Authorization или query параметре token
 //	   2. Валидирует токен с помощью service.CheckTokenIsNotExpired
 //	   3. Ищет пользователя в репозитории по email из токена
-//	   4. При успешной аутентификации добавляет email и данные пользователя в контекст
-//	   5. При ошибках возвращает HTTP 401 с соответствующим сообщением
+//	   4. Проверяет, что токен не отозван сменой пароля (версия токена совпадает с версией пользователя)
+//	      и что его сессия входа не завершена
+//	   5. При успешной аутентификации добавляет email, данные пользователя и сессию входа в контекст
+//	   6. При ошибках возвращает HTTP 401 с соответствующим сообщением
 func AuthMiddleware(dependency *dependency.AppDependencies) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -67,8 +70,25 @@ func AuthMiddleware(dependency *dependency.AppDependencies) func(next http.Handl
 				resp.ResponseWrite(w, r, http.StatusUnauthorized)
 				return
 			}
+			session, err := findAuthSession(r.Context(), dependency, claims)
+			if err != nil {
+				resp := helper.Response{}
+				resp.Message = err.Error()
+				resp.ResponseWrite(w, r, http.StatusInternalServerError)
+				return
+			}
+			if claims.Version != user.TokenVersion || session == nil || session.RevokedAt != nil || session.UserID != user.ID {
+				resp := helper.Response{}
+				resp.Message = "your token has been revoked"
+				resp.ResponseWrite(w, r, http.StatusUnauthorized)
+				return
+			}
 			ctx := context.WithValue(r.Context(), common.USER_MAIL, claims.Sub.Email)
 			ctx = context.WithValue(ctx, common.USER, user)
+			ctx = context.WithValue(ctx, common.AUTH_SESSION, session)
+			if user.Locale != "" {
+				ctx = context.WithValue(ctx, "locale", user.Locale)
+			}
 			r = r.WithContext(ctx)
 
 			wrappedWriter := &responseWriterWrapper{w}
@@ -76,3 +96,17 @@ func AuthMiddleware(dependency *dependency.AppDependencies) func(next http.Handl
 		})
 	}
 }
+
+// findAuthSession возвращает сессию входа, указанную в токене, или nil,
+// если токен выдан без сессии или сессия не найдена
+func findAuthSession(
+	ctx context.Context,
+	dependency *dependency.AppDependencies,
+	claims *service.Claims,
+) (*common.AuthSession, error) {
+	sessionID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		return nil, nil
+	}
+	return dependency.GlobalRepositories.AuthSessionRepository.FindByID(ctx, sessionID)
+}
@@ -7,9 +7,14 @@ import (
 	"log/slog"
 	"net"
 	"net/http"
+	"net/url"
 	"time"
 )
 
+// redactedQueryParameters перечисляет параметры запроса, значения которых не попадают в журнал:
+// токен доступа WebSocket туннеля и код авторизации OIDC
+var redactedQueryParameters = []string{"token", "code"}
+
 // statusRecorder - обертка для http.ResponseWriter, которая запоминает статус код ответа
 // и поддерживает интерфейс http.Hijacker для работы с WebSocket и другими соединениями
 type statusRecorder struct {
@@ -37,7 +42,7 @@ func (sr *statusRecorder) WriteHeader(code int) {
 // Логирует:
 //   - HTTP метод (GET, POST и т.д.)
 //   - Статус код ответа
-//   - URI запроса
+//   - URI запроса (значения секретных параметров запроса заменяются на REDACTED)
 //   - Время выполнения запроса
 //
 // Параметры:
@@ -57,8 +62,35 @@ func Logger(next http.Handler) http.Handler {
 			"Request info",
 			slog.String("method", r.Method),
 			slog.Int("status", sr.statusCode),
-			slog.String("uri", r.URL.String()),
+			slog.String("uri", redactURI(r.URL)),
 			slog.String("duration", time.Since(start).String()),
 		)
 	})
 }
+
+// redactURI возвращает URI запроса, в котором значения секретных параметров заменены на REDACTED.
+//
+// Параметры:
+//   - uri: URI запроса
+//
+// Возвращает:
+//   - string: URI для записи в журнал
+func redactURI(uri *url.URL) string {
+	if uri.RawQuery == "" {
+		return uri.String()
+	}
+	query, err := url.ParseQuery(uri.RawQuery)
+	redacted := *uri
+	if err != nil {
+		// Запрос, который не удалось разобрать, в журнал не попадает целиком
+		redacted.RawQuery = "REDACTED"
+		return redacted.String()
+	}
+	for _, name := range redactedQueryParameters {
+		if query.Has(name) {
+			query.Set(name, "REDACTED")
+		}
+	}
+	redacted.RawQuery = query.Encode()
+	return redacted.String()
+}
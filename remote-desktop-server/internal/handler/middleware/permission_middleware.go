@@ -0,0 +1,34 @@
+// Package middleware содержит промежуточные обработчики HTTP запросов
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/helper"
+)
+
+// RequirePermission создает middleware, пропускающий запрос, только если роль пользователя
+// дает указанное разрешение приложения. Должен выполняться после AuthMiddleware.
+//
+// Параметры:
+//   - permission string: разрешение приложения (common.Permission*)
+//
+// Возвращает:
+//
+//	func(next http.Handler) http.Handler: middleware функцию, которая возвращает HTTP 403,
+//	если у пользователя нет разрешения
+func RequirePermission(permission string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := r.Context().Value(common.USER).(*common.User)
+			if !ok || !common.RoleHasPermission(user.Role, permission) {
+				resp := helper.Response{}
+				resp.Message = "You don't have permission to perform this action"
+				resp.ResponseWrite(w, r, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
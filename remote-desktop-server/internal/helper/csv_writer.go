@@ -0,0 +1,29 @@
+// Package helper предоставляет вспомогательные функции и структуры для работы с HTTP-ответами.
+package helper
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// WriteCSV записывает CSV-файл в ответ для скачивания.
+//
+// Параметры:
+//   - w: HTTP ResponseWriter для записи ответа
+//   - filename: имя файла в заголовке Content-Disposition
+//   - header: заголовок таблицы
+//   - rows: строки таблицы
+func WriteCSV(w http.ResponseWriter, filename string, header []string, rows [][]string) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+
+	writer := csv.NewWriter(w)
+	writer.Write(header)
+	writer.WriteAll(rows)
+	if err := writer.Error(); err != nil {
+		slog.Error(fmt.Sprintf("Error writing CSV: %s", err.Error()))
+	}
+}
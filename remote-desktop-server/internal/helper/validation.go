@@ -8,14 +8,42 @@ package helper
 import (
 	"context"
 	"errors"
+	"reflect"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/iancoleman/strcase"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
 	"github.com/margar-melkonyan/remote-desktop.git/internal/lang/eng"
 	"github.com/margar-melkonyan/remote-desktop.git/internal/lang/ru"
 )
 
+// NewValidator создает валидатор с зарегистрированными правилами приложения.
+//
+// Дополнительные правила:
+//   - guacamole_parameters: ключи карты параметров должны входить в список
+//     разрешенных для протокола из поля Protocol той же структуры
+func NewValidator() *validator.Validate {
+	validate := validator.New()
+	validate.RegisterValidation("guacamole_parameters", validateGuacamoleParameters)
+	return validate
+}
+
+// validateGuacamoleParameters проверяет, что все ключи карты параметров разрешены для протокола подключения
+func validateGuacamoleParameters(fl validator.FieldLevel) bool {
+	parent := reflect.Indirect(fl.Parent())
+	protocol := parent.FieldByName("Protocol")
+	if !protocol.IsValid() || protocol.Kind() != reflect.String {
+		return false
+	}
+	for _, key := range fl.Field().MapKeys() {
+		if !common.IsAllowedParameter(protocol.String(), key.String()) {
+			return false
+		}
+	}
+	return true
+}
+
 func getValidationMessages(locale string) map[string]string {
 	switch locale {
 	case "ru":
@@ -78,10 +106,11 @@ func LocalizedValidationMessages(
 			"{field}", getAttribute(locale, strcase.ToSnake(err.Field())),
 		)
 		if err.Param() != "" {
-			res = strings.ReplaceAll(
-				res,
-				"{param}", getAttribute(locale, strcase.ToSnake(err.Param())),
-			)
+			param := getAttribute(locale, strcase.ToSnake(err.Param()))
+			if param == "" {
+				param = err.Param()
+			}
+			res = strings.ReplaceAll(res, "{param}", param)
 		}
 		validatedMessages[strcase.ToSnake(err.Field())] = res
 	}
@@ -1,19 +1,88 @@
 package eng
 
 var attribute = map[string]string{
-	"user_id":               "User",
-	"category_id":           "Category",
-	"platform_id":           "Platform",
-	"password":              "Password",
-	"password_confirmation": "Password confirmation",
-	"email":                 "E-mail",
-	"name":                  "Name",
-	"firstname":             "Firstname",
-	"lastname":              "Lastname",
-	"patronymic":            "Patronymic",
-	"text":                  "Text",
-	"is_private":            "Private",
-	"creator_id":            "Creator",
+	"user_id":                  "User",
+	"category_id":              "Category",
+	"platform_id":              "Platform",
+	"password":                 "Password",
+	"password_confirmation":    "Password confirmation",
+	"email":                    "E-mail",
+	"name":                     "Name",
+	"firstname":                "Firstname",
+	"lastname":                 "Lastname",
+	"patronymic":               "Patronymic",
+	"text":                     "Text",
+	"is_private":               "Private",
+	"creator_id":               "Creator",
+	"host_name":                "Host",
+	"port":                     "Port",
+	"protocol":                 "Protocol",
+	"username":                 "Username",
+	"color_depth":              "Color depth",
+	"cursor":                   "Cursor",
+	"encodings":                "Encodings",
+	"autoretry":                "Reconnect attempts",
+	"username_regex":           "Username prompt",
+	"password_regex":           "Password prompt",
+	"login_success_regex":      "Login success pattern",
+	"login_failure_regex":      "Login failure pattern",
+	"terminal_type":            "Terminal type",
+	"kubernetes":               "Kubernetes parameters",
+	"namespace":                "Namespace",
+	"pod":                      "Pod",
+	"container":                "Container",
+	"exec_cmd":                 "Command",
+	"rdp":                      "RDP parameters",
+	"ssh":                      "SSH parameters",
+	"parameters":               "Parameters",
+	"domain":                   "Domain",
+	"security":                 "Security mode",
+	"resize_method":            "Resize method",
+	"server_layout":            "Keyboard layout",
+	"width":                    "Width",
+	"height":                   "Height",
+	"dpi":                      "DPI",
+	"initial_program":          "Initial program",
+	"drive_name":               "Drive name",
+	"drive_path":               "Drive path",
+	"printer_name":             "Printer name",
+	"private_key":              "Private key",
+	"passphrase":               "Passphrase",
+	"host_key":                 "Host key",
+	"command":                  "Command",
+	"color_scheme":             "Color scheme",
+	"font_name":                "Font name",
+	"font_size":                "Font size",
+	"scrollback":               "Scrollback",
+	"server_alive_interval":    "Keepalive interval",
+	"sftp_root_directory":      "SFTP root directory",
+	"enable_drive":             "Drive redirection",
+	"parent_identifier":        "Parent group",
+	"type":                     "Type",
+	"max_connections":          "Maximum connections",
+	"max_connections_per_user": "Maximum connections per user",
+	"members":                  "Members",
+	"weight":                   "Weight",
+	"connection_id":            "Connection",
+	"page":                     "Page",
+	"per_page":                 "Page size",
+	"limit":                    "Limit",
+	"query":                    "Search query",
+	"sharing_profile_id":       "Sharing profile",
+	"expires_in_minutes":       "Expiration time",
+	"max_uses":                 "Maximum uses",
+	"note":                     "Note",
+	"permissions":              "Permissions",
+	"description":              "Description",
+	"group_id":                 "Group",
+	"current_password":         "Current password",
+	"token":                    "Token",
+	"refresh_token":            "Refresh token",
+	"code":                     "Code",
+	"recovery_code":            "Recovery code",
+	"challenge_token":          "Challenge token",
+	"locale":                   "Language",
+	"timezone":                 "Timezone",
 }
 
 func GetAttribute(field string) string {
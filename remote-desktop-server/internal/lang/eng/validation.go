@@ -1,13 +1,21 @@
 package eng
 
 var messages = map[string]string{
-	"required": "The {field} field is required.",
-	"email":    "The {field} must be a valid email address.",
-	"min":      "The {field} must be at least {param} characters long.",
-	"max":      "The {field} must be at most {param} characters long.",
-	"gte":      "The {field} must be greater than or equal to {param}.",
-	"lte":      "The {field} must be less than or equal to {param}.",
-	"eqfield":  "The field {field} must be equal to the field {param}.",
+	"required":             "The {field} field is required.",
+	"email":                "The {field} must be a valid email address.",
+	"min":                  "The {field} must be at least {param} characters long.",
+	"max":                  "The {field} must be at most {param} characters long.",
+	"gte":                  "The {field} must be greater than or equal to {param}.",
+	"lte":                  "The {field} must be less than or equal to {param}.",
+	"eqfield":              "The field {field} must be equal to the field {param}.",
+	"required_if":          "The {field} field is required for the selected protocol.",
+	"oneof":                "The {field} must be one of: {param}.",
+	"guacamole_parameters": "The {field} contain parameters not supported by the selected protocol.",
+	"required_with":        "The {field} field is required when {param} is set.",
+	"required_without":     "The {field} field is required when {param} is not set.",
+	"numeric":              "The {field} must be a number.",
+	"uuid":                 "The {field} must be a valid UUID.",
+	"timezone":             "The {field} must be a valid IANA time zone.",
 }
 
 func GetMessages() map[string]string {
@@ -1,16 +1,87 @@
 package ru
 
 var attribute = map[string]string{
-	"user_id":     "Пользователь",
-	"category_id": "Категория",
-	"platform_id": "Платформа",
-	"passowrd":    "Пароль",
-	"mail":        "Почта",
-	"name":        "Название",
-	"firstname":   "Имя",
-	"lastname":    "Фамилия",
-	"patronymic":  "Отчество",
-	"text":        "Текст",
+	"user_id":                  "Пользователь",
+	"category_id":              "Категория",
+	"platform_id":              "Платформа",
+	"passowrd":                 "Пароль",
+	"mail":                     "Почта",
+	"name":                     "Название",
+	"firstname":                "Имя",
+	"lastname":                 "Фамилия",
+	"patronymic":               "Отчество",
+	"text":                     "Текст",
+	"host_name":                "Хост",
+	"port":                     "Порт",
+	"protocol":                 "Протокол",
+	"username":                 "Имя пользователя",
+	"password":                 "Пароль",
+	"color_depth":              "Глубина цвета",
+	"cursor":                   "Курсор",
+	"encodings":                "Кодировки",
+	"autoretry":                "Попытки переподключения",
+	"username_regex":           "Приглашение имени пользователя",
+	"password_regex":           "Приглашение пароля",
+	"login_success_regex":      "Признак успешного входа",
+	"login_failure_regex":      "Признак неудачного входа",
+	"terminal_type":            "Тип терминала",
+	"kubernetes":               "Параметры Kubernetes",
+	"namespace":                "Пространство имен",
+	"pod":                      "Под",
+	"container":                "Контейнер",
+	"exec_cmd":                 "Команда",
+	"rdp":                      "Параметры RDP",
+	"ssh":                      "Параметры SSH",
+	"parameters":               "Параметры",
+	"domain":                   "Домен",
+	"security":                 "Режим безопасности",
+	"resize_method":            "Способ изменения размера",
+	"server_layout":            "Раскладка клавиатуры",
+	"width":                    "Ширина",
+	"height":                   "Высота",
+	"dpi":                      "DPI",
+	"initial_program":          "Начальная программа",
+	"drive_name":               "Имя диска",
+	"drive_path":               "Путь к диску",
+	"printer_name":             "Имя принтера",
+	"private_key":              "Закрытый ключ",
+	"passphrase":               "Пароль ключа",
+	"host_key":                 "Ключ сервера",
+	"command":                  "Команда",
+	"color_scheme":             "Цветовая схема",
+	"font_name":                "Шрифт",
+	"font_size":                "Размер шрифта",
+	"scrollback":               "Строки прокрутки",
+	"server_alive_interval":    "Интервал keepalive",
+	"sftp_root_directory":      "Корневой каталог SFTP",
+	"enable_drive":             "Проброс диска",
+	"parent_identifier":        "Родительская группа",
+	"type":                     "Тип",
+	"max_connections":          "Максимум подключений",
+	"max_connections_per_user": "Максимум подключений на пользователя",
+	"members":                  "Участники",
+	"weight":                   "Вес",
+	"connection_id":            "Подключение",
+	"page":                     "Страница",
+	"per_page":                 "Размер страницы",
+	"limit":                    "Ограничение",
+	"query":                    "Поисковый запрос",
+	"sharing_profile_id":       "Профиль совместного доступа",
+	"expires_in_minutes":       "Время действия",
+	"max_uses":                 "Максимум переходов",
+	"note":                     "Комментарий",
+	"permissions":              "Разрешения",
+	"description":              "Описание",
+	"group_id":                 "Группа",
+	"current_password":         "Текущий пароль",
+	"token":                    "Токен",
+	"password_confirmation":    "Подтверждение пароля",
+	"refresh_token":            "Refresh токен",
+	"code":                     "Код",
+	"recovery_code":            "Код восстановления",
+	"challenge_token":          "Токен второго шага входа",
+	"locale":                   "Язык",
+	"timezone":                 "Часовой пояс",
 }
 
 func GetAttribute(field string) string {
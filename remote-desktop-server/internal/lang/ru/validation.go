@@ -1,13 +1,21 @@
 package ru
 
 var messages = map[string]string{
-	"required": "Поле {field} обязательно для заполнения.",
-	"email":    "Поле {field} должно быть корректным адресом электронной почты.",
-	"min":      "Поле {field} должно содержать не менее {param} символов.",
-	"max":      "Поле {field} должно содержать не более {param} символов.",
-	"gte":      "Поле {field} должно быть больше или равно {param}.",
-	"lte":      "Поле {field} должно быть меньше или равно {param}.",
-	"eqfield":  "Поле {field} должно быть равно полью {param}.",
+	"required":             "Поле {field} обязательно для заполнения.",
+	"email":                "Поле {field} должно быть корректным адресом электронной почты.",
+	"min":                  "Поле {field} должно содержать не менее {param} символов.",
+	"max":                  "Поле {field} должно содержать не более {param} символов.",
+	"gte":                  "Поле {field} должно быть больше или равно {param}.",
+	"lte":                  "Поле {field} должно быть меньше или равно {param}.",
+	"eqfield":              "Поле {field} должно быть равно полью {param}.",
+	"required_if":          "Поле {field} обязательно для выбранного протокола.",
+	"oneof":                "Поле {field} должно иметь одно из значений: {param}.",
+	"guacamole_parameters": "Поле {field} содержит параметры, не поддерживаемые выбранным протоколом.",
+	"required_with":        "Поле {field} обязательно, если указано поле {param}.",
+	"required_without":     "Поле {field} обязательно, если не указано поле {param}.",
+	"numeric":              "Поле {field} должно быть числом.",
+	"timezone":             "Поле {field} должно быть часовым поясом IANA.",
+	"uuid":                 "Поле {field} должно быть корректным UUID.",
 }
 
 func GetMessages() map[string]string {
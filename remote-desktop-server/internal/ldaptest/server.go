@@ -0,0 +1,331 @@
+// Package ldaptest предоставляет сервер LDAP для тестов входа через каталог.
+//
+// Server принимает соединения на локальном адресе и отвечает на простую привязку (bind)
+// и поиск по записям, заданным тестом, поэтому тесты проверяют настоящий обмен
+// с каталогом через go-ldap без внешнего сервера.
+package ldaptest
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+)
+
+// Коды результата LDAP (RFC 4511, раздел 4.1.9)
+const (
+	resultSuccess            = 0
+	resultOperationsError    = 1
+	resultProtocolError      = 2
+	resultSizeLimitExceeded  = 4
+	resultInvalidCredentials = 49
+	resultInsufficientAccess = 50
+	resultUnwillingToPerform = 53
+)
+
+// Теги операций LDAP
+const (
+	applicationBindRequest   = 0
+	applicationBindResponse  = 1
+	applicationUnbindRequest = 2
+	applicationSearchRequest = 3
+	applicationSearchEntry   = 4
+	applicationSearchDone    = 5
+)
+
+// Теги фильтров поиска и способа привязки
+const (
+	filterAnd            = 0
+	filterOr             = 1
+	filterNot            = 2
+	filterEqualityMatch  = 3
+	filterPresent        = 7
+	authenticationSimple = 0
+)
+
+// Entry запись каталога
+type Entry struct {
+	DN         string              // Имя записи
+	Password   string              // Пароль для привязки от имени записи (пустой — привязка запрещена)
+	Attributes map[string][]string // Атрибуты записи
+}
+
+// Server сервер LDAP, хранящий записи в памяти.
+// Поддерживает простую привязку, поиск с фильтрами and, or, not, равенства и присутствия атрибута
+// и завершение сеанса (unbind).
+type Server struct {
+	URL string // Адрес сервера вида ldap://127.0.0.1:port
+
+	listener  net.Listener
+	entries   []Entry
+	anonymous bool
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+	binds     []string
+}
+
+// NewServer запускает сервер на локальном адресе.
+// Поиск разрешен только после успешной привязки, если не вызван AllowAnonymousSearch.
+//
+// Параметры:
+//   - entries: записи каталога
+//
+// Возвращает:
+//   - *Server: запущенный сервер (остановить — Close)
+func NewServer(entries ...Entry) *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("ldaptest: failed to listen: %s", err.Error()))
+	}
+	server := &Server{
+		URL:      "ldap://" + listener.Addr().String(),
+		listener: listener,
+		entries:  entries,
+	}
+	server.wg.Add(1)
+	go server.serve()
+	return server
+}
+
+// AllowAnonymousSearch разрешает поиск без привязки
+func (s *Server) AllowAnonymousSearch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.anonymous = true
+}
+
+// Binds возвращает имена записей, привязка от имени которых выполнена успешно, в порядке привязки
+func (s *Server) Binds() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.binds...)
+}
+
+// Close останавливает сервер и ожидает завершения открытых соединений
+func (s *Server) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+// serve принимает соединения до остановки сервера
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			s.handle(conn)
+		}()
+	}
+}
+
+// handle обрабатывает запросы клиента до завершения сеанса
+func (s *Server) handle(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+	bound := false
+	for {
+		packet, err := ber.ReadPacket(reader)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		messageID, _ := packet.Children[0].Value.(int64)
+		request := packet.Children[1]
+		if request.ClassType != ber.ClassApplication {
+			return
+		}
+
+		switch request.Tag {
+		case applicationBindRequest:
+			code := s.bind(request)
+			bound = code == resultSuccess
+			conn.Write(response(messageID, applicationBindResponse, code, "").Bytes())
+		case applicationSearchRequest:
+			s.mu.Lock()
+			allowed := bound || s.anonymous
+			s.mu.Unlock()
+			if !allowed {
+				conn.Write(response(messageID, applicationSearchDone, resultInsufficientAccess, "anonymous search is not allowed").Bytes())
+				continue
+			}
+			for _, message := range s.search(messageID, request) {
+				conn.Write(message.Bytes())
+			}
+		case applicationUnbindRequest:
+			return
+		default:
+			conn.Write(response(messageID, applicationSearchDone, resultUnwillingToPerform, "operation is not supported").Bytes())
+		}
+	}
+}
+
+// bind проверяет простую привязку и возвращает код результата.
+// Привязка с пустыми именем и паролем анонимна и завершается успешно.
+func (s *Server) bind(request *ber.Packet) int64 {
+	if len(request.Children) < 3 {
+		return resultProtocolError
+	}
+	version, _ := request.Children[0].Value.(int64)
+	name, _ := request.Children[1].Value.(string)
+	authentication := request.Children[2]
+	if version != 3 || authentication.ClassType != ber.ClassContext ||
+		authentication.Tag != authenticationSimple {
+		return resultProtocolError
+	}
+	password := authentication.Data.String()
+	if name == "" && password == "" {
+		return resultSuccess
+	}
+
+	for _, entry := range s.entries {
+		if strings.EqualFold(entry.DN, name) && entry.Password != "" && entry.Password == password {
+			s.mu.Lock()
+			s.binds = append(s.binds, entry.DN)
+			s.mu.Unlock()
+			return resultSuccess
+		}
+	}
+	return resultInvalidCredentials
+}
+
+// search возвращает найденные записи и завершающий ответ поиска
+func (s *Server) search(messageID int64, request *ber.Packet) []*ber.Packet {
+	if len(request.Children) < 8 {
+		return []*ber.Packet{response(messageID, applicationSearchDone, resultProtocolError, "")}
+	}
+	baseDN, _ := request.Children[0].Value.(string)
+	sizeLimit, _ := request.Children[3].Value.(int64)
+	filter := request.Children[6]
+	var attributes []string
+	for _, attribute := range request.Children[7].Children {
+		if name, ok := attribute.Value.(string); ok {
+			attributes = append(attributes, name)
+		}
+	}
+
+	var messages []*ber.Packet
+	for _, entry := range s.entries {
+		if !inSubtree(entry.DN, baseDN) {
+			continue
+		}
+		matched, err := match(filter, entry)
+		if err != nil {
+			return []*ber.Packet{response(messageID, applicationSearchDone, resultOperationsError, err.Error())}
+		}
+		if !matched {
+			continue
+		}
+		if sizeLimit > 0 && int64(len(messages)) == sizeLimit {
+			return append(messages, response(messageID, applicationSearchDone, resultSizeLimitExceeded, ""))
+		}
+		messages = append(messages, searchEntry(messageID, entry, attributes))
+	}
+	return append(messages, response(messageID, applicationSearchDone, resultSuccess, ""))
+}
+
+// match проверяет, подходит ли запись под фильтр поиска
+func match(filter *ber.Packet, entry Entry) (bool, error) {
+	if filter.ClassType != ber.ClassContext {
+		return false, fmt.Errorf("invalid filter")
+	}
+	switch filter.Tag {
+	case filterAnd, filterOr:
+		for _, child := range filter.Children {
+			matched, err := match(child, entry)
+			if err != nil {
+				return false, err
+			}
+			if matched == (filter.Tag == filterOr) {
+				return matched, nil
+			}
+		}
+		return filter.Tag == filterAnd, nil
+	case filterNot:
+		if len(filter.Children) != 1 {
+			return false, fmt.Errorf("invalid not filter")
+		}
+		matched, err := match(filter.Children[0], entry)
+		return !matched, err
+	case filterEqualityMatch:
+		if len(filter.Children) != 2 {
+			return false, fmt.Errorf("invalid equality filter")
+		}
+		attribute, _ := filter.Children[0].Value.(string)
+		value, _ := filter.Children[1].Value.(string)
+		for _, candidate := range attributeValues(entry, attribute) {
+			if strings.EqualFold(candidate, value) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case filterPresent:
+		return len(attributeValues(entry, filter.Data.String())) > 0, nil
+	}
+	return false, fmt.Errorf("filter type %d is not supported", filter.Tag)
+}
+
+// attributeValues возвращает значения атрибута записи (имена атрибутов не зависят от регистра)
+func attributeValues(entry Entry, attribute string) []string {
+	if strings.EqualFold(attribute, "objectClass") && len(entry.Attributes["objectClass"]) == 0 {
+		return []string{"top"}
+	}
+	for name, values := range entry.Attributes {
+		if strings.EqualFold(name, attribute) {
+			return values
+		}
+	}
+	return nil
+}
+
+// inSubtree проверяет, находится ли запись в поддереве baseDN
+func inSubtree(dn string, baseDN string) bool {
+	dn, baseDN = strings.ToLower(dn), strings.ToLower(baseDN)
+	return baseDN == "" || dn == baseDN || strings.HasSuffix(dn, ","+baseDN)
+}
+
+// searchEntry формирует ответ с найденной записью и запрошенными атрибутами
+func searchEntry(messageID int64, entry Entry, attributes []string) *ber.Packet {
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, applicationSearchEntry, nil, "Search Result Entry")
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.DN, "Object Name"))
+	list := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for _, attribute := range attributes {
+		values := attributeValues(entry, attribute)
+		if attribute == "" || len(values) == 0 {
+			continue
+		}
+		item := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		item.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, attribute, "Type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+		}
+		item.AppendChild(set)
+		list.AppendChild(item)
+	}
+	result.AppendChild(list)
+	return envelope(messageID, result)
+}
+
+// response формирует ответ LDAPResult с кодом результата
+func response(messageID int64, application ber.Tag, code int64, message string) *ber.Packet {
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, application, nil, "Response")
+	result.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "Result Code"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, message, "Diagnostic Message"))
+	return envelope(messageID, result)
+}
+
+// envelope помещает операцию в сообщение LDAP с идентификатором запроса
+func envelope(messageID int64, operation *ber.Packet) *ber.Packet {
+	message := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	message.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "Message ID"))
+	message.AppendChild(operation)
+	return message
+}
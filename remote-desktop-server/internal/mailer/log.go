@@ -0,0 +1,34 @@
+package mailer
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+)
+
+// LogMailer пишет письма в журнал вместо отправки.
+// Предназначен для разработки: текст письма (включая ссылки с токенами) попадает в журнал.
+type LogMailer struct{}
+
+// NewLogMailer создает новый экземпляр LogMailer
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+// Send пишет письмо в журнал.
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//   - message: письмо
+//
+// Возвращает:
+//   - error: всегда nil
+func (m *LogMailer) Send(ctx context.Context, message Message) error {
+	slog.Warn(
+		"SMTP is not configured, mail is written to the log",
+		slog.String("to", strings.Join(message.To, ", ")),
+		slog.String("subject", message.Subject),
+		slog.String("body", message.Body),
+	)
+	return nil
+}
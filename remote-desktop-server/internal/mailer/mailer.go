@@ -0,0 +1,39 @@
+// Package mailer отправляет письма пользователям (например, ссылки для сброса пароля).
+//
+// Сервисы зависят только от интерфейса Mailer, поэтому способ доставки можно заменить:
+// SMTPMailer отправляет письма через SMTP сервер, LogMailer пишет их в журнал
+// и используется, пока SMTP сервер не настроен.
+package mailer
+
+import (
+	"context"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+)
+
+// Message представляет текстовое письмо
+type Message struct {
+	To      []string // Адреса получателей
+	Subject string   // Тема письма
+	Body    string   // Текст письма (text/plain, UTF-8)
+}
+
+// Mailer определяет контракт для отправки писем
+type Mailer interface {
+	// Send отправляет письмо
+	Send(ctx context.Context, message Message) error
+}
+
+// New создает Mailer по конфигурации.
+//
+// Параметры:
+//   - config: параметры SMTP сервера
+//
+// Возвращает:
+//   - Mailer: SMTPMailer, если задан адрес SMTP сервера, иначе LogMailer
+func New(config common.MailConfig) Mailer {
+	if config.Host == "" {
+		return NewLogMailer()
+	}
+	return NewSMTPMailer(config)
+}
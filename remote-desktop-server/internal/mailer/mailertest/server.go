@@ -0,0 +1,243 @@
+// Package mailertest предоставляет SMTP сервер для тестов отправки писем.
+//
+// Server принимает письма на локальном адресе и сохраняет их в памяти,
+// поэтому тесты проверяют настоящую отправку через mailer.SMTPMailer
+// без внешнего почтового сервера.
+package mailertest
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+)
+
+// Mail письмо, принятое сервером
+type Mail struct {
+	From string   // Адрес отправителя из команды MAIL FROM
+	To   []string // Адреса получателей из команд RCPT TO
+	Data string   // Письмо в формате RFC 5322
+}
+
+// Subject возвращает декодированную тему письма
+func (m Mail) Subject() (string, error) {
+	message, err := mail.ReadMessage(strings.NewReader(m.Data))
+	if err != nil {
+		return "", err
+	}
+	return new(mime.WordDecoder).DecodeHeader(message.Header.Get("Subject"))
+}
+
+// Text возвращает текст письма, декодированный из quoted-printable.
+// Окончания строк CRLF, которых требует SMTP, приводятся к LF.
+func (m Mail) Text() (string, error) {
+	message, err := mail.ReadMessage(strings.NewReader(m.Data))
+	if err != nil {
+		return "", err
+	}
+	body := io.Reader(message.Body)
+	if strings.EqualFold(message.Header.Get("Content-Transfer-Encoding"), "quoted-printable") {
+		body = quotedprintable.NewReader(body)
+	}
+	text, err := io.ReadAll(body)
+	return strings.ReplaceAll(string(text), "\r\n", "\n"), err
+}
+
+// Server SMTP сервер, сохраняющий принятые письма.
+// Поддерживает EHLO/HELO, AUTH PLAIN, MAIL, RCPT, DATA, RSET, NOOP и QUIT.
+type Server struct {
+	Host string // Адрес, на котором сервер принимает соединения
+	Port string // Порт сервера
+
+	listener net.Listener
+	username string
+	password string
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	mails    []Mail
+}
+
+// NewServer запускает сервер на локальном адресе.
+// Если задано имя пользователя, сервер объявляет AUTH PLAIN и принимает письма
+// только после успешной аутентификации с указанными учетными данными.
+//
+// Параметры:
+//   - username: имя пользователя (пустое — без аутентификации)
+//   - password: пароль
+//
+// Возвращает:
+//   - *Server: запущенный сервер (остановить — Close)
+func NewServer(username string, password string) *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("mailertest: failed to listen: %s", err.Error()))
+	}
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	server := &Server{
+		Host:     host,
+		Port:     port,
+		listener: listener,
+		username: username,
+		password: password,
+	}
+	server.wg.Add(1)
+	go server.serve()
+	return server
+}
+
+// Config возвращает параметры отправки писем через сервер
+func (s *Server) Config(from string) common.MailConfig {
+	return common.MailConfig{
+		Host:     s.Host,
+		Port:     s.Port,
+		Username: s.username,
+		Password: s.password,
+		From:     from,
+	}
+}
+
+// Mails возвращает принятые письма в порядке получения
+func (s *Server) Mails() []Mail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Mail(nil), s.mails...)
+}
+
+// Close останавливает сервер и ожидает завершения открытых соединений
+func (s *Server) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+// serve принимает соединения до остановки сервера
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			s.handle(conn)
+		}()
+	}
+}
+
+// handle ведет SMTP диалог с клиентом
+func (s *Server) handle(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+	reply := func(format string, args ...interface{}) {
+		fmt.Fprintf(conn, format+"\r\n", args...)
+	}
+	authenticated := s.username == ""
+	var current *Mail
+
+	reply("220 mailertest ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			if s.username != "" {
+				reply("250-mailertest")
+				reply("250 AUTH PLAIN")
+			} else {
+				reply("250 mailertest")
+			}
+		case "HELO":
+			reply("250 mailertest")
+		case "AUTH":
+			mechanism, initial, _ := strings.Cut(arg, " ")
+			if !strings.EqualFold(mechanism, "PLAIN") {
+				reply("504 unrecognized authentication type")
+				continue
+			}
+			credentials, err := base64.StdEncoding.DecodeString(initial)
+			if err != nil || string(credentials) != "\x00"+s.username+"\x00"+s.password {
+				reply("535 authentication failed")
+				continue
+			}
+			authenticated = true
+			reply("235 authentication succeeded")
+		case "MAIL":
+			if !authenticated {
+				reply("530 authentication required")
+				continue
+			}
+			current = &Mail{From: address(arg, "FROM:")}
+			reply("250 OK")
+		case "RCPT":
+			if current == nil {
+				reply("503 need MAIL command")
+				continue
+			}
+			current.To = append(current.To, address(arg, "TO:"))
+			reply("250 OK")
+		case "DATA":
+			if current == nil || len(current.To) == 0 {
+				reply("503 need RCPT command")
+				continue
+			}
+			reply("354 end data with <CR><LF>.<CR><LF>")
+			data, err := readData(reader)
+			if err != nil {
+				return
+			}
+			current.Data = data
+			s.mu.Lock()
+			s.mails = append(s.mails, *current)
+			s.mu.Unlock()
+			current = nil
+			reply("250 OK")
+		case "RSET":
+			current = nil
+			reply("250 OK")
+		case "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 command not implemented")
+		}
+	}
+}
+
+// address извлекает адрес из аргумента команды MAIL FROM:<...> или RCPT TO:<...>
+func address(arg string, prefix string) string {
+	if len(arg) >= len(prefix) && strings.EqualFold(arg[:len(prefix)], prefix) {
+		arg = arg[len(prefix):]
+	}
+	arg, _, _ = strings.Cut(strings.TrimSpace(arg), " ")
+	return strings.Trim(arg, "<>")
+}
+
+// readData читает письмо до строки из одной точки и снимает экранирование точек
+func readData(reader *bufio.Reader) (string, error) {
+	var data strings.Builder
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		if line == ".\r\n" || line == ".\n" {
+			return data.String(), nil
+		}
+		data.WriteString(strings.TrimPrefix(line, "."))
+	}
+}
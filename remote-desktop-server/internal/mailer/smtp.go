@@ -0,0 +1,165 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+)
+
+// defaultTimeout ограничивает время отправки письма, если у контекста нет дедлайна
+const defaultTimeout = 30 * time.Second
+
+// SMTPMailer отправляет письма через SMTP сервер
+type SMTPMailer struct {
+	config common.MailConfig
+}
+
+// NewSMTPMailer создает новый экземпляр SMTPMailer.
+//
+// Параметры:
+//   - config: параметры SMTP сервера
+//
+// Возвращает:
+//   - *SMTPMailer: указатель на созданный экземпляр
+func NewSMTPMailer(config common.MailConfig) *SMTPMailer {
+	return &SMTPMailer{
+		config: config,
+	}
+}
+
+// Send отправляет письмо через SMTP сервер.
+//
+// Параметры:
+//   - ctx: контекст выполнения (дедлайн контекста ограничивает время отправки)
+//   - message: письмо
+//
+// Возвращает:
+//   - error: ошибка подключения, шифрования, аутентификации или отправки
+//
+// Особенности:
+//   - При включенном StartTLS письмо не отправляется, если сервер не поддерживает STARTTLS
+//   - Аутентификация PLAIN выполняется, только если задано имя пользователя
+func (m *SMTPMailer) Send(ctx context.Context, message Message) error {
+	from, err := mail.ParseAddress(m.config.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender address: %w", err)
+	}
+	if len(message.To) == 0 {
+		return errors.New("mail has no recipients")
+	}
+	recipients := make([]*mail.Address, 0, len(message.To))
+	for _, to := range message.To {
+		recipient, err := mail.ParseAddress(to)
+		if err != nil {
+			return fmt.Errorf("invalid recipient address: %w", err)
+		}
+		recipients = append(recipients, recipient)
+	}
+	body, err := buildMessage(from, recipients, message)
+	if err != nil {
+		return err
+	}
+
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(m.config.Host, m.config.Port))
+	if err != nil {
+		return err
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultTimeout)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, m.config.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if m.config.StartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server does not support STARTTLS")
+		}
+		if err := client.StartTLS(&tls.Config{ServerName: m.config.Host}); err != nil {
+			return err
+		}
+	}
+	if m.config.Username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("smtp server does not support authentication")
+		}
+		auth := smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, recipient := range recipients {
+		if err := client.Rcpt(recipient.Address); err != nil {
+			return err
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(body); err != nil {
+		writer.Close()
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// buildMessage формирует письмо в формате RFC 5322 с телом в кодировке quoted-printable
+func buildMessage(from *mail.Address, recipients []*mail.Address, message Message) ([]byte, error) {
+	to := make([]string, 0, len(recipients))
+	for _, recipient := range recipients {
+		to = append(to, recipient.String())
+	}
+
+	var buf bytes.Buffer
+	headers := [][2]string{
+		{"From", from.String()},
+		{"To", strings.Join(to, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", message.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "text/plain; charset=UTF-8"},
+		{"Content-Transfer-Encoding", "quoted-printable"},
+	}
+	for _, header := range headers {
+		fmt.Fprintf(&buf, "%s: %s\r\n", header[0], header[1])
+	}
+	buf.WriteString("\r\n")
+
+	writer := quotedprintable.NewWriter(&buf)
+	if _, err := writer.Write([]byte(message.Body)); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
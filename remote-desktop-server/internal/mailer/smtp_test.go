@@ -0,0 +1,127 @@
+package mailer_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/mailer"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/mailer/mailertest"
+)
+
+func TestSMTPMailerSend(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		password string
+	}{
+		{name: "without authentication"},
+		{name: "with authentication", username: "mailer", password: "secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := mailertest.NewServer(tt.username, tt.password)
+			defer server.Close()
+
+			message := mailer.Message{
+				To:      []string{"Иван <ivan@example.com>", "ops@example.com"},
+				Subject: "Сброс пароля",
+				Body:    "Здравствуйте!\n.\nСсылка: https://example.com/reset?token=" + strings.Repeat("a", 80) + "\n",
+			}
+			err := mailer.NewSMTPMailer(server.Config("RemoteDesktop <noreply@example.com>")).Send(context.Background(), message)
+			if err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			mails := server.Mails()
+			if len(mails) != 1 {
+				t.Fatalf("server received %d mails, want 1", len(mails))
+			}
+			mail := mails[0]
+			if mail.From != "noreply@example.com" {
+				t.Errorf("MAIL FROM = %q, want noreply@example.com", mail.From)
+			}
+			if want := []string{"ivan@example.com", "ops@example.com"}; !reflect.DeepEqual(mail.To, want) {
+				t.Errorf("RCPT TO = %q, want %q", mail.To, want)
+			}
+			subject, err := mail.Subject()
+			if err != nil || subject != message.Subject {
+				t.Errorf("Subject() = %q, %v, want %q", subject, err, message.Subject)
+			}
+			text, err := mail.Text()
+			if err != nil || text != message.Body {
+				t.Errorf("Text() = %q, %v, want %q", text, err, message.Body)
+			}
+		})
+	}
+}
+
+func TestSMTPMailerSendErrors(t *testing.T) {
+	server := mailertest.NewServer("mailer", "secret")
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		modify  func(message *mailer.Message, config *common.MailConfig)
+		wantErr string
+	}{
+		{
+			name:    "no recipients",
+			modify:  func(message *mailer.Message, config *common.MailConfig) { message.To = nil },
+			wantErr: "mail has no recipients",
+		},
+		{
+			name: "invalid recipient",
+			modify: func(message *mailer.Message, config *common.MailConfig) {
+				message.To = []string{"not an address"}
+			},
+			wantErr: "invalid recipient address",
+		},
+		{
+			name:    "invalid sender",
+			modify:  func(message *mailer.Message, config *common.MailConfig) { config.From = "noreply" },
+			wantErr: "invalid sender address",
+		},
+		{
+			name:    "wrong password",
+			modify:  func(message *mailer.Message, config *common.MailConfig) { config.Password = "wrong" },
+			wantErr: "535",
+		},
+		{
+			name:    "starttls is not supported",
+			modify:  func(message *mailer.Message, config *common.MailConfig) { config.StartTLS = true },
+			wantErr: "does not support STARTTLS",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := mailer.Message{To: []string{"ivan@example.com"}, Subject: "test", Body: "test"}
+			config := server.Config("noreply@example.com")
+			tt.modify(&message, &config)
+
+			err := mailer.NewSMTPMailer(config).Send(context.Background(), message)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Send() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+	if mails := server.Mails(); len(mails) != 0 {
+		t.Errorf("server received %d mails, want 0", len(mails))
+	}
+}
+
+func TestNew(t *testing.T) {
+	server := mailertest.NewServer("", "")
+	defer server.Close()
+
+	if _, ok := mailer.New(server.Config("noreply@example.com")).(*mailer.SMTPMailer); !ok {
+		t.Error("New() with SMTP host does not return SMTPMailer")
+	}
+	config := server.Config("noreply@example.com")
+	config.Host = ""
+	if _, ok := mailer.New(config).(*mailer.LogMailer); !ok {
+		t.Error("New() without SMTP host does not return LogMailer")
+	}
+}
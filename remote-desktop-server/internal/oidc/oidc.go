@@ -0,0 +1,275 @@
+// Package oidc реализует вход через провайдера OpenID Connect по схеме
+// authorization code + PKCE (RFC 7636).
+//
+// Пакет получает настройки провайдера из документа обнаружения
+// (/.well-known/openid-configuration), формирует адрес авторизации, обменивает
+// код авторизации на токены и проверяет подпись и утверждения ID токена
+// ключами провайдера (JWKS).
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Config содержит параметры клиента OpenID Connect
+type Config struct {
+	IssuerURL    string   // Адрес провайдера (значение iss в ID токене)
+	ClientID     string   // Идентификатор клиента
+	ClientSecret string   // Секрет клиента (пустой для публичного клиента)
+	RedirectURL  string   // Адрес возврата после авторизации
+	Scopes       []string // Запрашиваемые scope (openid добавляется всегда)
+	GroupsClaim  string   // Утверждение ID токена со списком групп пользователя
+}
+
+// Provider содержит адреса провайдера из документа обнаружения
+type Provider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Identity содержит сведения о пользователе из проверенного ID токена.
+// Пользователь у провайдера определяется парой Issuer и Subject; электронная почта
+// может меняться и учитывается, только если провайдер ее подтвердил.
+type Identity struct {
+	Issuer        string   // Провайдер, выдавший ID токен (iss)
+	Subject       string   // Идентификатор пользователя у провайдера (sub)
+	Email         string   // Электронная почта
+	EmailVerified bool     // Провайдер подтвердил почту (утверждение email_verified равно true)
+	Name          string   // Отображаемое имя
+	Groups        []string // Группы пользователя из Config.GroupsClaim
+}
+
+// Client выполняет вход через провайдера OpenID Connect
+type Client struct {
+	config   Config
+	provider *Provider
+	keys     *keySet
+	http     *http.Client
+}
+
+// NewClient получает документ обнаружения провайдера и создает клиента.
+//
+// Параметры:
+//   - ctx: контекст
+//   - config: параметры клиента
+//
+// Возвращает:
+//   - *Client: клиент
+//   - error: ошибка получения документа обнаружения или несовпадение issuer
+func NewClient(ctx context.Context, config Config) (*Client, error) {
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	issuer := strings.TrimSuffix(config.IssuerURL, "/")
+
+	var provider Provider
+	if err := getJSON(ctx, httpClient, issuer+"/.well-known/openid-configuration", &provider); err != nil {
+		return nil, fmt.Errorf("failed to discover oidc provider: %w", err)
+	}
+	if strings.TrimSuffix(provider.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("oidc issuer mismatch: expected %s, got %s", issuer, provider.Issuer)
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.JWKSURI == "" {
+		return nil, errors.New("oidc discovery document is incomplete")
+	}
+
+	return &Client{
+		config:   config,
+		provider: &provider,
+		keys:     newKeySet(provider.JWKSURI, httpClient),
+		http:     httpClient,
+	}, nil
+}
+
+// AuthCodeURL возвращает адрес страницы авторизации провайдера.
+//
+// Параметры:
+//   - state: значение для защиты от CSRF, возвращается провайдером в адрес возврата
+//   - nonce: значение, которое провайдер запишет в ID токен
+//   - verifier: code_verifier PKCE (в адрес передается только его хэш)
+//
+// Возвращает:
+//   - string: адрес для перенаправления браузера
+func (c *Client) AuthCodeURL(state string, nonce string, verifier string) string {
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", c.config.ClientID)
+	query.Set("redirect_uri", c.config.RedirectURL)
+	query.Set("scope", strings.Join(c.scopes(), " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", CodeChallenge(verifier))
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(c.provider.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return c.provider.AuthorizationEndpoint + separator + query.Encode()
+}
+
+// Exchange обменивает код авторизации на ID токен и проверяет его.
+//
+// Параметры:
+//   - ctx: контекст
+//   - code: код авторизации из адреса возврата
+//   - verifier: code_verifier PKCE, использованный в AuthCodeURL
+//   - nonce: nonce, использованный в AuthCodeURL
+//
+// Возвращает:
+//   - *Identity: сведения о пользователе
+//   - error: ошибка обмена кода или проверки ID токена
+func (c *Client) Exchange(ctx context.Context, code string, verifier string, nonce string) (*Identity, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.config.RedirectURL)
+	form.Set("code_verifier", verifier)
+	form.Set("client_id", c.config.ClientID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(c.config.ClientID), url.QueryEscape(c.config.ClientSecret))
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc token request failed with status %d: %s", resp.StatusCode, body)
+	}
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to parse oidc token response: %w", err)
+	}
+	if tokens.IDToken == "" {
+		return nil, errors.New("oidc token response has no id_token")
+	}
+	return c.Verify(ctx, tokens.IDToken, nonce)
+}
+
+// Verify проверяет подпись и утверждения ID токена.
+//
+// Параметры:
+//   - ctx: контекст
+//   - rawIDToken: ID токен
+//   - nonce: ожидаемое значение nonce
+//
+// Возвращает:
+//   - *Identity: сведения о пользователе
+//   - error: ошибка, если подпись неверна, токен истек, выдан другим провайдером
+//     или другому клиенту либо nonce не совпадает
+func (c *Client) Verify(ctx context.Context, rawIDToken string, nonce string) (*Identity, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(
+		rawIDToken,
+		claims,
+		func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			return c.keys.key(ctx, kid)
+		},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(c.provider.Issuer),
+		jwt.WithAudience(c.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token: %w", err)
+	}
+	if claimNonce, _ := claims["nonce"].(string); claimNonce != nonce {
+		return nil, errors.New("invalid id token: nonce mismatch")
+	}
+
+	identity := &Identity{Issuer: c.provider.Issuer}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Email, _ = claims["email"].(string)
+	identity.Name, _ = claims["name"].(string)
+	// Отсутствующее или нестандартное (например, строковое) утверждение не подтверждает почту
+	identity.EmailVerified, _ = claims["email_verified"].(bool)
+	if c.config.GroupsClaim != "" {
+		identity.Groups = stringList(claims[c.config.GroupsClaim])
+	}
+	if identity.Subject == "" {
+		return nil, errors.New("invalid id token: sub is missing")
+	}
+	return identity, nil
+}
+
+// scopes возвращает запрашиваемые scope, всегда включая openid
+func (c *Client) scopes() []string {
+	scopes := []string{"openid"}
+	for _, scope := range c.config.Scopes {
+		if scope != "openid" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// CodeChallenge возвращает code_challenge PKCE метода S256 для code_verifier
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// stringList приводит утверждение (строку или массив строк) к списку строк
+func stringList(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+// getJSON выполняет GET запрос и разбирает JSON ответ
+func getJSON(ctx context.Context, client *http.Client, url string, target interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(target)
+}
@@ -0,0 +1,285 @@
+package oidc_test
+
+import (
+	"context"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/oidc"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/oidc/oidctest"
+)
+
+// newClient запускает провайдера и создает клиента для него
+func newClient(t *testing.T) (*oidc.Client, *oidctest.Provider) {
+	t.Helper()
+	provider := oidctest.NewProvider("remote-desktop")
+	t.Cleanup(provider.Close)
+	client, err := oidc.NewClient(context.Background(), oidc.Config{
+		IssuerURL:   provider.Issuer,
+		ClientID:    provider.ClientID,
+		RedirectURL: "https://rd.example.com/api/v1/auth/oidc/callback",
+		Scopes:      []string{"email", "openid", "profile"},
+		GroupsClaim: "groups",
+	})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	return client, provider
+}
+
+func TestCodeChallenge(t *testing.T) {
+	// Пример из RFC 7636, приложение B
+	got := oidc.CodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	if want := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"; got != want {
+		t.Errorf("CodeChallenge() = %s, want %s", got, want)
+	}
+}
+
+func TestNewClientIssuerMismatch(t *testing.T) {
+	provider := oidctest.NewProvider("remote-desktop")
+	defer provider.Close()
+	issuerURL := provider.Issuer
+	// Документ обнаружения сообщает другой issuer
+	provider.Issuer = "https://other.example.com"
+
+	_, err := oidc.NewClient(context.Background(), oidc.Config{IssuerURL: issuerURL, ClientID: "remote-desktop"})
+	if err == nil || !strings.Contains(err.Error(), "issuer mismatch") {
+		t.Fatalf("NewClient() error = %v, want issuer mismatch", err)
+	}
+}
+
+func TestNewClientUnavailable(t *testing.T) {
+	provider := oidctest.NewProvider("remote-desktop")
+	provider.Close()
+
+	_, err := oidc.NewClient(context.Background(), oidc.Config{IssuerURL: provider.Issuer, ClientID: "remote-desktop"})
+	if err == nil {
+		t.Fatal("NewClient() succeeded without a provider")
+	}
+}
+
+func TestClientAuthCodeURL(t *testing.T) {
+	client, provider := newClient(t)
+
+	authURL, err := url.Parse(client.AuthCodeURL("state-value", "nonce-value", "verifier-value"))
+	if err != nil {
+		t.Fatalf("AuthCodeURL() is not a valid URL: %v", err)
+	}
+	if got := authURL.Scheme + "://" + authURL.Host + authURL.Path; got != provider.Issuer+"/authorize" {
+		t.Errorf("AuthCodeURL() endpoint = %s, want %s/authorize", got, provider.Issuer)
+	}
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             "remote-desktop",
+		"redirect_uri":          "https://rd.example.com/api/v1/auth/oidc/callback",
+		"scope":                 "openid email profile",
+		"state":                 "state-value",
+		"nonce":                 "nonce-value",
+		"code_challenge":        oidc.CodeChallenge("verifier-value"),
+		"code_challenge_method": "S256",
+	}
+	query := authURL.Query()
+	for key, value := range want {
+		if got := query.Get(key); got != value {
+			t.Errorf("AuthCodeURL() %s = %q, want %q", key, got, value)
+		}
+	}
+	if strings.Contains(authURL.RawQuery, "verifier-value") {
+		t.Error("AuthCodeURL() exposes the code verifier")
+	}
+}
+
+func TestClientExchange(t *testing.T) {
+	tests := []struct {
+		name     string
+		verifier string
+		nonce    string
+		wantErr  bool
+	}{
+		{name: "valid code", verifier: "verifier-value", nonce: "nonce-value"},
+		{name: "wrong code verifier", verifier: "other-verifier", nonce: "nonce-value", wantErr: true},
+		{name: "wrong nonce", verifier: "verifier-value", nonce: "other-nonce", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, provider := newClient(t)
+			ctx := context.Background()
+			code, _, err := provider.Authorize(
+				client.AuthCodeURL("state-value", "nonce-value", "verifier-value"),
+				map[string]interface{}{"sub": "user-1", "email": "ivan@example.com", "email_verified": true},
+			)
+			if err != nil {
+				t.Fatalf("Authorize() error = %v", err)
+			}
+
+			identity, err := client.Exchange(ctx, code, tt.verifier, tt.nonce)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Exchange() = %+v, want error", identity)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exchange() error = %v", err)
+			}
+			if identity.Subject != "user-1" || identity.Email != "ivan@example.com" || !identity.EmailVerified {
+				t.Errorf("Exchange() identity = %+v", identity)
+			}
+			if _, err := client.Exchange(ctx, code, tt.verifier, tt.nonce); err == nil {
+				t.Error("Exchange() accepted the same code twice")
+			}
+		})
+	}
+}
+
+func TestClientVerify(t *testing.T) {
+	client, provider := newClient(t)
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		token   func() string
+		nonce   string
+		want    *oidc.Identity
+		wantErr bool
+	}{
+		{
+			name: "verified email",
+			token: func() string {
+				return provider.IDToken(map[string]interface{}{
+					"sub":            "user-1",
+					"email":          "ivan@example.com",
+					"email_verified": true,
+					"name":           "Иван",
+					"nonce":          "nonce-value",
+					"groups":         []string{"rd-admins", "rd-operators"},
+				})
+			},
+			nonce: "nonce-value",
+			want: &oidc.Identity{
+				Issuer:        provider.Issuer,
+				Subject:       "user-1",
+				Email:         "ivan@example.com",
+				EmailVerified: true,
+				Name:          "Иван",
+				Groups:        []string{"rd-admins", "rd-operators"},
+			},
+		},
+		{
+			name: "email_verified is missing",
+			token: func() string {
+				return provider.IDToken(map[string]interface{}{"sub": "user-1", "email": "ivan@example.com"})
+			},
+			want: &oidc.Identity{Issuer: provider.Issuer, Subject: "user-1", Email: "ivan@example.com"},
+		},
+		{
+			name: "email_verified is a string",
+			token: func() string {
+				return provider.IDToken(map[string]interface{}{
+					"sub":            "user-1",
+					"email":          "ivan@example.com",
+					"email_verified": "true",
+				})
+			},
+			want: &oidc.Identity{Issuer: provider.Issuer, Subject: "user-1", Email: "ivan@example.com"},
+		},
+		{
+			name: "email_verified is false",
+			token: func() string {
+				return provider.IDToken(map[string]interface{}{
+					"sub":            "user-1",
+					"email":          "ivan@example.com",
+					"email_verified": false,
+				})
+			},
+			want: &oidc.Identity{Issuer: provider.Issuer, Subject: "user-1", Email: "ivan@example.com"},
+		},
+		{
+			name: "single group as a string",
+			token: func() string {
+				return provider.IDToken(map[string]interface{}{"sub": "user-1", "groups": "rd-admins"})
+			},
+			want: &oidc.Identity{Issuer: provider.Issuer, Subject: "user-1", Groups: []string{"rd-admins"}},
+		},
+		{
+			name: "another audience",
+			token: func() string {
+				return provider.IDToken(map[string]interface{}{"aud": "other-client"})
+			},
+			wantErr: true,
+		},
+		{
+			name: "another issuer",
+			token: func() string {
+				return provider.IDToken(map[string]interface{}{"iss": "https://other.example.com"})
+			},
+			wantErr: true,
+		},
+		{
+			name: "expired",
+			token: func() string {
+				return provider.IDToken(map[string]interface{}{"exp": now.Add(-time.Hour).Unix()})
+			},
+			wantErr: true,
+		},
+		{
+			name: "without expiration",
+			token: func() string {
+				return provider.IDToken(map[string]interface{}{"exp": nil})
+			},
+			wantErr: true,
+		},
+		{
+			name: "nonce mismatch",
+			token: func() string {
+				return provider.IDToken(map[string]interface{}{"nonce": "other-nonce"})
+			},
+			nonce:   "nonce-value",
+			wantErr: true,
+		},
+		{
+			name: "sub is missing",
+			token: func() string {
+				return provider.IDToken(map[string]interface{}{"sub": nil})
+			},
+			wantErr: true,
+		},
+		{
+			name: "signed with a shared secret",
+			token: func() string {
+				token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+					"iss": provider.Issuer,
+					"aud": provider.ClientID,
+					"sub": "user-1",
+					"iat": now.Unix(),
+					"exp": now.Add(time.Hour).Unix(),
+				})
+				token.Header["kid"] = oidctest.KeyID
+				raw, _ := token.SignedString([]byte("shared-secret"))
+				return raw
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := client.Verify(context.Background(), tt.token(), tt.nonce)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Verify() = %+v, want error", identity)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if !reflect.DeepEqual(identity, tt.want) {
+				t.Errorf("Verify() = %+v, want %+v", identity, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+// Package oidctest предоставляет провайдера OpenID Connect для тестов входа.
+//
+// Provider обслуживает документ обнаружения, JWKS и адрес обмена кода на локальном
+// адресе и подписывает ID токены ключом RSA, поэтому тесты проверяют настоящий обмен
+// кода с проверкой PKCE без внешнего провайдера.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeyID идентификатор ключа, которым провайдер подписывает ID токены
+const KeyID = "oidctest"
+
+// Provider провайдер OpenID Connect, выдающий ID токены с заданными утверждениями
+type Provider struct {
+	Issuer   string // Адрес провайдера (значение iss в ID токенах)
+	ClientID string // Идентификатор клиента (значение aud в ID токенах)
+
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	mu     sync.Mutex
+	codes  map[string]authorization
+}
+
+// authorization запрос авторизации, для которого выдан код
+type authorization struct {
+	redirectURI string
+	challenge   string
+	nonce       string
+	claims      map[string]interface{}
+}
+
+// NewProvider запускает провайдера на локальном адресе.
+//
+// Параметры:
+//   - clientID: идентификатор клиента, которому провайдер выдает ID токены
+//
+// Возвращает:
+//   - *Provider: запущенный провайдер (остановить — Close)
+func NewProvider(clientID string) *Provider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(fmt.Sprintf("oidctest: failed to generate key: %s", err.Error()))
+	}
+	provider := &Provider{
+		ClientID: clientID,
+		key:      key,
+		codes:    make(map[string]authorization),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", provider.discovery)
+	mux.HandleFunc("GET /jwks", provider.jwks)
+	mux.HandleFunc("POST /token", provider.token)
+	provider.server = httptest.NewServer(mux)
+	provider.Issuer = provider.server.URL
+	return provider
+}
+
+// Close останавливает провайдера
+func (p *Provider) Close() {
+	p.server.Close()
+}
+
+// Authorize выполняет вход пользователя по адресу авторизации, как это сделал бы браузер.
+// Утверждения claims дополняют стандартные утверждения ID токена, который будет выдан
+// за возвращенный код; значение nil удаляет стандартное утверждение.
+//
+// Параметры:
+//   - authURL: адрес авторизации, сформированный клиентом
+//   - claims: утверждения ID токена
+//
+// Возвращает:
+//   - string: код авторизации
+//   - string: значение state из адреса авторизации
+//   - error: ошибка, если адрес авторизации не соответствует authorization code + PKCE
+func (p *Provider) Authorize(authURL string, claims map[string]interface{}) (string, string, error) {
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		return "", "", err
+	}
+	query := parsed.Query()
+	switch {
+	case query.Get("response_type") != "code":
+		return "", "", fmt.Errorf("unsupported response_type %q", query.Get("response_type"))
+	case query.Get("client_id") != p.ClientID:
+		return "", "", fmt.Errorf("unknown client_id %q", query.Get("client_id"))
+	case query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "":
+		return "", "", fmt.Errorf("pkce S256 challenge is missing")
+	case query.Get("state") == "":
+		return "", "", fmt.Errorf("state is missing")
+	}
+
+	code := randomString()
+	p.mu.Lock()
+	p.codes[code] = authorization{
+		redirectURI: query.Get("redirect_uri"),
+		challenge:   query.Get("code_challenge"),
+		nonce:       query.Get("nonce"),
+		claims:      claims,
+	}
+	p.mu.Unlock()
+	return code, query.Get("state"), nil
+}
+
+// IDToken возвращает ID токен, подписанный ключом провайдера.
+// Утверждения claims дополняют стандартные iss, aud, sub, iat и exp;
+// значение nil удаляет стандартное утверждение.
+func (p *Provider) IDToken(claims map[string]interface{}) string {
+	now := time.Now()
+	token := jwt.MapClaims{
+		"iss": p.Issuer,
+		"aud": p.ClientID,
+		"sub": "oidctest-subject",
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+	for name, value := range claims {
+		if value == nil {
+			delete(token, name)
+			continue
+		}
+		token[name] = value
+	}
+	signed := jwt.NewWithClaims(jwt.SigningMethodRS256, token)
+	signed.Header["kid"] = KeyID
+	raw, err := signed.SignedString(p.key)
+	if err != nil {
+		panic(fmt.Sprintf("oidctest: failed to sign id token: %s", err.Error()))
+	}
+	return raw
+}
+
+// discovery отдает документ обнаружения
+func (p *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 p.Issuer,
+		"authorization_endpoint": p.Issuer + "/authorize",
+		"token_endpoint":         p.Issuer + "/token",
+		"jwks_uri":               p.Issuer + "/jwks",
+	})
+}
+
+// jwks отдает открытый ключ провайдера
+func (p *Provider) jwks(w http.ResponseWriter, r *http.Request) {
+	encode := func(value *big.Int) string {
+		return base64.RawURLEncoding.EncodeToString(value.Bytes())
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": KeyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   encode(p.key.N),
+			"e":   encode(big.NewInt(int64(p.key.E))),
+		}},
+	})
+}
+
+// token обменивает код авторизации на ID токен, проверяя code_verifier
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	code := r.PostForm.Get("code")
+	p.mu.Lock()
+	login, ok := p.codes[code]
+	delete(p.codes, code)
+	p.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	switch {
+	case r.PostForm.Get("grant_type") != "authorization_code" || !ok:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	case base64.RawURLEncoding.EncodeToString(sum[:]) != login.challenge:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "pkce"})
+		return
+	case r.PostForm.Get("redirect_uri") != login.redirectURI:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "redirect_uri"})
+		return
+	}
+
+	claims := map[string]interface{}{"nonce": login.nonce}
+	for name, value := range login.claims {
+		claims[name] = value
+	}
+	writeJSON(w, http.StatusOK, map[string]string{
+		"token_type": "Bearer",
+		"id_token":   p.IDToken(claims),
+	})
+}
+
+// writeJSON отправляет JSON ответ
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// randomString возвращает случайную строку для кодов авторизации
+func randomString() string {
+	data := make([]byte, 16)
+	rand.Read(data)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
@@ -0,0 +1,227 @@
+package recording
+
+import (
+	"errors"
+	"image"
+	"io"
+	"log/slog"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// DefaultIndexInterval интервал между точками индекса перемотки по умолчанию
+const DefaultIndexInterval = 5 * time.Second
+
+// KeyEvent описывает нажатие или отпускание клавиши в записи
+type KeyEvent struct {
+	TimestampMs int64  `json:"timestamp_ms"` // Время от начала записи в миллисекундах
+	Keysym      int    `json:"keysym"`       // Код клавиши X11 keysym
+	Key         string `json:"key"`          // Читаемое название клавиши
+	Pressed     bool   `json:"pressed"`      // true — нажатие, false — отпускание
+}
+
+// IndexEntry точка индекса перемотки: кадр записи и его положение в файле.
+// Воспроизведение с точки требует состояния экрана, поэтому индекс используется
+// для быстрого перехода в плеере и выбора кадров для миниатюр.
+type IndexEntry struct {
+	TimestampMs int64 `json:"timestamp_ms"` // Время кадра от начала записи в миллисекундах
+	Offset      int64 `json:"offset"`       // Смещение инструкции sync в байтах
+}
+
+// Summary содержит сведения о записи, полученные за один проход без отрисовки
+type Summary struct {
+	DurationMs int64         `json:"duration_ms"` // Длительность записи в миллисекундах
+	Width      int           `json:"width"`       // Ширина экрана
+	Height     int           `json:"height"`      // Высота экрана
+	Frames     int           `json:"frames"`      // Количество кадров (инструкций sync)
+	Size       int64         `json:"size"`        // Размер записи в байтах
+	KeyEvents  []*KeyEvent   `json:"key_events"`  // Нажатия клавиш (если запись включала клавиши)
+	Index      []*IndexEntry `json:"index"`       // Точки перемотки
+}
+
+// Analyze читает запись и вычисляет длительность, размер экрана, события клавиатуры
+// и индекс перемотки с заданным интервалом.
+//
+// Параметры:
+//   - r: поток записи
+//   - interval: интервал между точками индекса (0 — DefaultIndexInterval)
+//
+// Возвращает:
+//   - *Summary: сведения о записи
+//   - error: ошибка чтения или формата записи
+func Analyze(r io.Reader, interval time.Duration) (*Summary, error) {
+	if interval <= 0 {
+		interval = DefaultIndexInterval
+	}
+	reader := NewReader(r)
+	summary := &Summary{
+		KeyEvents: make([]*KeyEvent, 0),
+		Index:     make([]*IndexEntry, 0),
+	}
+	var (
+		start, current int64
+		started        bool
+		nextIndex      int64
+	)
+	for {
+		instruction, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch instruction.Opcode {
+		case "sync":
+			timestamp, err := timestampOf(instruction)
+			if err != nil {
+				return nil, err
+			}
+			if !started {
+				start, started = timestamp, true
+			}
+			current = timestamp - start
+			summary.Frames++
+			if current >= nextIndex {
+				summary.Index = append(summary.Index, &IndexEntry{
+					TimestampMs: current,
+					Offset:      instruction.Offset,
+				})
+				nextIndex = current + interval.Milliseconds()
+			}
+		case "size":
+			values, err := ints(instruction.Args, 3)
+			if err == nil && values[0] == defaultLayer {
+				summary.Width, summary.Height = values[1], values[2]
+			}
+		case "key":
+			values, err := ints(instruction.Args, 2)
+			if err != nil {
+				return nil, err
+			}
+			summary.KeyEvents = append(summary.KeyEvents, &KeyEvent{
+				TimestampMs: current,
+				Keysym:      values[0],
+				Key:         KeysymName(values[0]),
+				Pressed:     values[1] == 1,
+			})
+		}
+	}
+	summary.DurationMs = current
+	summary.Size = reader.Offset()
+	return summary, nil
+}
+
+// Thumbnails воспроизводит запись и возвращает снимки экрана в указанные моменты времени.
+// Снимок соответствует первому кадру не раньше запрошенного момента; моменты после
+// окончания записи получают последний кадр.
+//
+// Параметры:
+//   - r: поток записи
+//   - positions: моменты времени от начала записи
+//   - maxWidth: максимальная ширина снимка (0 — без уменьшения)
+//
+// Возвращает:
+//   - []image.Image: снимки в порядке positions
+//   - error: ошибка чтения или формата записи (ErrMalformed, в том числе если запись
+//     превышает ограничения экрана на количество слоев, потоков или объем данных)
+func Thumbnails(r io.Reader, positions []time.Duration, maxWidth int) ([]image.Image, error) {
+	order := make([]int, len(positions))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return positions[order[i]] < positions[order[j]]
+	})
+
+	reader := NewReader(r)
+	screen := newDisplay()
+	result := make([]image.Image, len(positions))
+	next := 0
+	var (
+		start   int64
+		started bool
+		skipped int
+	)
+	for next < len(order) {
+		instruction, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if instruction.Opcode != "sync" {
+			if err := screen.apply(instruction); errors.Is(err, errDisplayLimit) {
+				return nil, err
+			} else if err != nil {
+				skipped++
+			}
+			continue
+		}
+		timestamp, err := timestampOf(instruction)
+		if err != nil {
+			return nil, err
+		}
+		if !started {
+			start, started = timestamp, true
+		}
+		current := time.Duration(timestamp-start) * time.Millisecond
+		for next < len(order) && positions[order[next]] <= current {
+			result[order[next]] = scale(screen.snapshot(), maxWidth)
+			next++
+		}
+	}
+	for ; next < len(order); next++ {
+		result[order[next]] = scale(screen.snapshot(), maxWidth)
+	}
+	if skipped > 0 {
+		slog.Debug("Recording instructions skipped while rendering", slog.Int("count", skipped))
+	}
+	return result, nil
+}
+
+// Thumbnail возвращает снимок экрана записи в указанный момент времени.
+func Thumbnail(r io.Reader, position time.Duration, maxWidth int) (image.Image, error) {
+	thumbnails, err := Thumbnails(r, []time.Duration{position}, maxWidth)
+	if err != nil {
+		return nil, err
+	}
+	return thumbnails[0], nil
+}
+
+// timestampOf возвращает метку времени инструкции sync в миллисекундах
+func timestampOf(instruction *Instruction) (int64, error) {
+	if len(instruction.Args) < 1 {
+		return 0, ErrMalformed
+	}
+	timestamp, err := strconv.ParseInt(instruction.Args[0], 10, 64)
+	if err != nil {
+		return 0, ErrMalformed
+	}
+	return timestamp, nil
+}
+
+// scale уменьшает изображение до maxWidth с сохранением пропорций
+// (выборкой ближайшего пикселя).
+func scale(src *image.RGBA, maxWidth int) image.Image {
+	bounds := src.Bounds()
+	if maxWidth <= 0 || bounds.Dx() <= maxWidth {
+		return src
+	}
+	width := maxWidth
+	height := max(bounds.Dy()*maxWidth/bounds.Dx(), 1)
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		sy := bounds.Min.Y + y*bounds.Dy()/height
+		for x := 0; x < width; x++ {
+			sx := bounds.Min.X + x*bounds.Dx()/width
+			offset := src.PixOffset(sx, sy)
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], src.Pix[offset:offset+4])
+		}
+	}
+	return dst
+}
@@ -0,0 +1,212 @@
+package recording
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		interval time.Duration
+		want     Summary
+	}{
+		{
+			name:  "empty recording",
+			input: "",
+			want:  Summary{KeyEvents: []*KeyEvent{}, Index: []*IndexEntry{}},
+		},
+		{
+			name: "duration, size and frames",
+			input: "4.size,1.0,3.800,3.600;" +
+				"4.size,1.1,2.10,2.10;" +
+				"4.sync,4.1000;" +
+				"4.sync,4.4500;",
+			want: Summary{
+				DurationMs: 3500,
+				Width:      800,
+				Height:     600,
+				Frames:     2,
+				Size:       72,
+				KeyEvents:  []*KeyEvent{},
+				Index:      []*IndexEntry{{TimestampMs: 0, Offset: 44}},
+			},
+		},
+		{
+			name: "index interval",
+			input: "4.sync,1.0;" +
+				"4.sync,4.1000;" +
+				"4.sync,4.2000;" +
+				"4.sync,4.2500;",
+			interval: 2 * time.Second,
+			want: Summary{
+				DurationMs: 2500,
+				Frames:     4,
+				Size:       53,
+				KeyEvents:  []*KeyEvent{},
+				Index: []*IndexEntry{
+					{TimestampMs: 0, Offset: 0},
+					{TimestampMs: 2000, Offset: 25},
+				},
+			},
+		},
+		{
+			name: "key events",
+			input: "4.sync,3.100;" +
+				"3.key,2.97,1.1;" +
+				"4.sync,3.350;" +
+				"3.key,5.65293,1.0;",
+			want: Summary{
+				DurationMs: 250,
+				Frames:     2,
+				Size:       59,
+				KeyEvents: []*KeyEvent{
+					{TimestampMs: 0, Keysym: 97, Key: "a", Pressed: true},
+					{TimestampMs: 250, Keysym: 0xff0d, Key: "Return", Pressed: false},
+				},
+				Index: []*IndexEntry{{TimestampMs: 0, Offset: 0}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Analyze(strings.NewReader(tt.input), tt.interval)
+			if err != nil {
+				t.Fatalf("Analyze() error = %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Analyze() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyzeMalformed(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "sync without timestamp", input: "4.sync;"},
+		{name: "sync with invalid timestamp", input: "4.sync,3.abc;"},
+		{name: "key with missing state", input: "3.key,2.97;"},
+		{name: "broken stream", input: "4.sync,2.10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Analyze(strings.NewReader(tt.input), 0); !errors.Is(err, ErrMalformed) {
+				t.Errorf("Analyze() error = %v, want ErrMalformed", err)
+			}
+		})
+	}
+}
+
+func TestThumbnails(t *testing.T) {
+	input := "4.size,1.0,2.40,2.20;" +
+		"4.sync,4.1000;" +
+		"4.rect,1.0,1.0,1.0,2.10,2.10;" +
+		"5.cfill,2.12,1.0,3.255,1.0,1.0,3.255;" +
+		"4.sync,4.3000;" +
+		"4.rect,1.0,1.0,1.0,2.10,2.10;" +
+		"5.cfill,2.12,1.0,1.0,1.0,3.255,3.255;" +
+		"4.sync,4.5000;"
+	positions := []time.Duration{5 * time.Second, 0, 2 * time.Second, time.Minute}
+
+	images, err := Thumbnails(strings.NewReader(input), positions, 0)
+	if err != nil {
+		t.Fatalf("Thumbnails() error = %v", err)
+	}
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+	want := []color.Color{blue, color.RGBA{}, red, blue}
+	for i, img := range images {
+		if img.Bounds().Dx() != 40 || img.Bounds().Dy() != 20 {
+			t.Errorf("thumbnail %d bounds = %v, want 40x20", i, img.Bounds())
+		}
+		if got := img.At(5, 5); got != want[i] {
+			t.Errorf("thumbnail %d at %s = %v, want %v", i, positions[i], got, want[i])
+		}
+	}
+}
+
+// encodeInstruction кодирует инструкцию протокола Guacamole
+func encodeInstruction(opcode string, args ...string) string {
+	elements := append([]string{opcode}, args...)
+	for i, element := range elements {
+		elements[i] = fmt.Sprintf("%d.%s", len(element), element)
+	}
+	return strings.Join(elements, ",") + ";"
+}
+
+func TestThumbnailsLimits(t *testing.T) {
+	var layers, streams strings.Builder
+	for i := 1; i <= maxLayers; i++ {
+		layers.WriteString(encodeInstruction("rect", strconv.Itoa(-i), "0", "0", "1", "1"))
+	}
+	for i := 0; i <= maxStreams; i++ {
+		streams.WriteString(encodeInstruction("img", strconv.Itoa(i), "12", "0", "image/png", "0", "0"))
+	}
+	blob := strings.Repeat("A", 12<<20)
+	var wide bytes.Buffer
+	if err := png.Encode(&wide, image.NewGray(image.Rect(0, 0, maxLayerSize+1, 1))); err != nil {
+		t.Fatalf("png.Encode() error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "too many layers", input: layers.String()},
+		{name: "too many open streams", input: streams.String()},
+		{
+			name: "stream too large",
+			input: encodeInstruction("img", "1", "12", "0", "image/png", "0", "0") +
+				encodeInstruction("blob", "1", blob) +
+				encodeInstruction("blob", "1", blob),
+		},
+		{
+			name:  "image larger than a layer",
+			input: encodeInstruction("png", "12", "0", "0", "0", base64.StdEncoding.EncodeToString(wide.Bytes())),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := encodeInstruction("size", "0", "40", "20") + tt.input + encodeInstruction("sync", "1000")
+			_, err := Thumbnails(strings.NewReader(input), []time.Duration{0}, 0)
+			if !errors.Is(err, ErrMalformed) {
+				t.Errorf("Thumbnails() error = %v, want ErrMalformed", err)
+			}
+		})
+	}
+}
+
+func TestKeysymName(t *testing.T) {
+	tests := []struct {
+		keysym int
+		want   string
+	}{
+		{keysym: 0x61, want: "a"},
+		{keysym: 0xe9, want: "é"},
+		{keysym: 0x01000436, want: "ж"},
+		{keysym: 0xffbe, want: "F1"},
+		{keysym: 0xffc9, want: "F12"},
+		{keysym: 0xff1b, want: "Escape"},
+		{keysym: 0xffe3, want: "Control_L"},
+		{keysym: 0x1234, want: "0x1234"},
+	}
+	for _, tt := range tests {
+		if got := KeysymName(tt.keysym); got != tt.want {
+			t.Errorf("KeysymName(%#x) = %q, want %q", tt.keysym, got, tt.want)
+		}
+	}
+}
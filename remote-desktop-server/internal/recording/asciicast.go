@@ -0,0 +1,74 @@
+package recording
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+)
+
+// Размер терминала по умолчанию: typescript не содержит размеров терминала
+const (
+	DefaultTerminalWidth  = 80
+	DefaultTerminalHeight = 24
+)
+
+// AsciicastHeader заголовок файла asciicast v2
+type AsciicastHeader struct {
+	Version   int               `json:"version"`             // Версия формата (всегда 2)
+	Width     int               `json:"width"`               // Количество колонок терминала
+	Height    int               `json:"height"`              // Количество строк терминала
+	Timestamp int64             `json:"timestamp,omitempty"` // Время начала записи (Unix)
+	Duration  float64           `json:"duration,omitempty"`  // Длительность записи в секундах
+	Title     string            `json:"title,omitempty"`     // Заголовок записи
+	Env       map[string]string `json:"env,omitempty"`       // Переменные окружения терминала
+}
+
+// ConvertTypescript преобразует запись typescript в формат asciicast v2:
+// строку заголовка JSON и события вывода вида [время, "o", данные].
+//
+// Параметры:
+//   - data: файл вывода typescript
+//   - timing: файл временных меток
+//   - w: получатель файла asciicast
+//   - header: заголовок (Version выставляется автоматически, нулевой размер заменяется значением по умолчанию)
+//
+// Возвращает:
+//   - error: ошибка чтения записи или записи результата
+func ConvertTypescript(data io.Reader, timing io.Reader, w io.Writer, header AsciicastHeader) error {
+	header.Version = 2
+	if header.Width <= 0 {
+		header.Width = DefaultTerminalWidth
+	}
+	if header.Height <= 0 {
+		header.Height = DefaultTerminalHeight
+	}
+
+	out := bufio.NewWriter(w)
+	encoder := json.NewEncoder(out)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(header); err != nil {
+		return err
+	}
+
+	reader := NewTypescriptReader(data, timing)
+	for {
+		event, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := encoder.Encode([]interface{}{Seconds(event.Time), "o", event.Data}); err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
+
+// Seconds приводит длительность к секундам с точностью до микросекунды, как в файлах asciinema
+func Seconds(duration time.Duration) float64 {
+	return float64(duration.Microseconds()) / 1e6
+}
@@ -0,0 +1,93 @@
+package recording
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConvertTypescript(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		timing string
+		header AsciicastHeader
+		want   string
+	}{
+		{
+			name:   "default terminal size",
+			data:   "[BEGIN TYPESCRIPT]\n$ ls\r\n",
+			timing: "0.25 6\n",
+			want: `{"version":2,"width":80,"height":24}` + "\n" +
+				`[0.25,"o","$ ls\r\n"]` + "\n",
+		},
+		{
+			name:   "header fields are kept",
+			data:   "a<b>",
+			timing: "0.000001 1\n1.5 3\n",
+			header: AsciicastHeader{
+				Version:   1,
+				Width:     120,
+				Height:    40,
+				Timestamp: 1700000000,
+				Duration:  1.500001,
+				Title:     "ssh",
+				Env:       map[string]string{"TERM": "xterm"},
+			},
+			want: `{"version":2,"width":120,"height":40,"timestamp":1700000000,"duration":1.500001,"title":"ssh","env":{"TERM":"xterm"}}` + "\n" +
+				`[0.000001,"o","a"]` + "\n" +
+				`[1.500001,"o","<b>"]` + "\n",
+		},
+		{
+			name:   "escape sequences and unicode",
+			data:   "\x1b[1mжир\x1b[0m",
+			timing: "0 14\n",
+			want: `{"version":2,"width":80,"height":24}` + "\n" +
+				`[0,"o","\u001b[1mжир\u001b[0m"]` + "\n",
+		},
+		{
+			name:   "empty recording",
+			want:   `{"version":2,"width":80,"height":24}` + "\n",
+			header: AsciicastHeader{Width: -1, Height: 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := ConvertTypescript(strings.NewReader(tt.data), strings.NewReader(tt.timing), &out, tt.header)
+			if err != nil {
+				t.Fatalf("ConvertTypescript() error = %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("ConvertTypescript() =\n%s\nwant\n%s", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertTypescriptMalformed(t *testing.T) {
+	var out bytes.Buffer
+	err := ConvertTypescript(strings.NewReader("abc"), strings.NewReader("0.1 1\nbroken\n"), &out, AsciicastHeader{})
+	if !errors.Is(err, ErrMalformed) {
+		t.Errorf("ConvertTypescript() error = %v, want ErrMalformed", err)
+	}
+}
+
+func TestSeconds(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		want     float64
+	}{
+		{duration: 0, want: 0},
+		{duration: 1500 * time.Millisecond, want: 1.5},
+		{duration: time.Microsecond, want: 0.000001},
+		{duration: 1234567891 * time.Nanosecond, want: 1.234567},
+	}
+	for _, tt := range tests {
+		if got := Seconds(tt.duration); got != tt.want {
+			t.Errorf("Seconds(%s) = %v, want %v", tt.duration, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package recording
+
+import (
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxLineLength ограничивает длину восстанавливаемой строки терминала
+const maxLineLength = 4096
+
+// promptPattern распознает строку с приглашением командной оболочки и введенной командой:
+// "user@host:~$ ls", "[root@host dir]# ls", "(venv) user@host:~/app$ ls".
+var promptPattern = regexp.MustCompile(
+	`^(?:\([^)]*\)\s+)?(?:[\w.-]+@[\w.-]+(?::[^\s$#]*)?|\[[^\]]+\])\s?[$#%>]\s+(\S.*)$`,
+)
+
+// Command команда, введенная в терминале во время записи
+type Command struct {
+	TimestampMs int64  `json:"timestamp_ms"` // Время выполнения от начала записи в миллисекундах
+	Text        string `json:"text"`         // Текст команды
+}
+
+// ExtractCommands восстанавливает строки терминала из вывода typescript и возвращает
+// команды, введенные после приглашения командной оболочки.
+//
+// typescript содержит только вывод терминала, поэтому команды определяются по эху ввода.
+// Управляющие последовательности ANSI удаляются, возврат каретки, backspace и стирание
+// строки применяются к восстанавливаемой строке.
+//
+// Параметры:
+//   - data: файл вывода typescript
+//   - timing: файл временных меток
+//
+// Возвращает:
+//   - []*Command: команды в порядке выполнения (при ошибке — найденные до нее)
+//   - error: ошибка чтения записи
+func ExtractCommands(data io.Reader, timing io.Reader) ([]*Command, error) {
+	reader := NewTypescriptReader(data, timing)
+	terminal := &lineBuffer{}
+	commands := make([]*Command, 0)
+	for {
+		event, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return commands, err
+		}
+		for _, line := range terminal.write(event.Data) {
+			if match := promptPattern.FindStringSubmatch(line); match != nil {
+				commands = append(commands, &Command{
+					TimestampMs: event.Time.Milliseconds(),
+					Text:        strings.TrimSpace(match[1]),
+				})
+			}
+		}
+	}
+	return commands, nil
+}
+
+// Состояния разбора управляющих последовательностей
+const (
+	stateText   = iota // Обычный текст
+	stateEscape        // Получен ESC
+	stateCSI           // Последовательность ESC [
+	stateOSC           // Последовательность ESC ]
+	stateOSCEnd        // ESC внутри OSC (возможное окончание ESC \)
+)
+
+// lineBuffer восстанавливает текущую строку терминала с учетом перемещений курсора
+type lineBuffer struct {
+	line   []rune
+	cursor int
+	state  int
+	params strings.Builder
+}
+
+// write обрабатывает вывод и возвращает завершенные строки
+func (b *lineBuffer) write(data string) []string {
+	lines := make([]string, 0)
+	for _, char := range data {
+		switch b.state {
+		case stateEscape:
+			switch char {
+			case '[':
+				b.state = stateCSI
+				b.params.Reset()
+			case ']':
+				b.state = stateOSC
+			default:
+				b.state = stateText
+			}
+			continue
+		case stateCSI:
+			if char >= 0x40 && char <= 0x7e {
+				b.control(char, b.params.String())
+				b.state = stateText
+			} else {
+				b.params.WriteRune(char)
+			}
+			continue
+		case stateOSC:
+			if char == 0x07 {
+				b.state = stateText
+			} else if char == 0x1b {
+				b.state = stateOSCEnd
+			}
+			continue
+		case stateOSCEnd:
+			b.state = stateText
+			continue
+		}
+
+		switch {
+		case char == 0x1b:
+			b.state = stateEscape
+		case char == '\n':
+			lines = append(lines, strings.TrimRight(string(b.line), " "))
+			b.line = b.line[:0]
+			b.cursor = 0
+		case char == '\r':
+			b.cursor = 0
+		case char == '\b':
+			if b.cursor > 0 {
+				b.cursor--
+			}
+		case char == '\t':
+			b.put(' ')
+		case char < 0x20 || char == 0x7f:
+			// Прочие управляющие символы (звонок и т.д.) не отображаются
+		default:
+			b.put(char)
+		}
+	}
+	return lines
+}
+
+// put записывает символ в позицию курсора в режиме замены
+func (b *lineBuffer) put(char rune) {
+	if b.cursor >= maxLineLength {
+		return
+	}
+	for len(b.line) < b.cursor {
+		b.line = append(b.line, ' ')
+	}
+	if b.cursor < len(b.line) {
+		b.line[b.cursor] = char
+	} else {
+		b.line = append(b.line, char)
+	}
+	b.cursor++
+}
+
+// control применяет последовательность CSI, влияющую на текущую строку
+func (b *lineBuffer) control(final rune, params string) {
+	count := 1
+	if value, err := strconv.Atoi(strings.TrimPrefix(params, "?")); err == nil && value > 0 {
+		// Больше maxLineLength символов строка не вмещает; ограничение также исключает
+		// переполнение при сложении с позицией курсора
+		count = min(value, maxLineLength)
+	}
+	switch final {
+	case 'C': // Курсор вправо
+		b.cursor = min(b.cursor+count, maxLineLength)
+	case 'D': // Курсор влево
+		b.cursor = max(b.cursor-count, 0)
+	case 'G': // Курсор в колонку
+		b.cursor = min(count-1, maxLineLength)
+	case 'K': // Стирание строки
+		switch params {
+		case "", "0":
+			if b.cursor < len(b.line) {
+				b.line = b.line[:b.cursor]
+			}
+		case "1":
+			for i := 0; i < b.cursor && i < len(b.line); i++ {
+				b.line[i] = ' '
+			}
+		case "2":
+			b.line = b.line[:0]
+		}
+	case 'P': // Удаление символов
+		if b.cursor < len(b.line) {
+			end := min(b.cursor+count, len(b.line))
+			b.line = append(b.line[:b.cursor], b.line[end:]...)
+		}
+	case '@': // Вставка пробелов
+		count = min(count, maxLineLength-len(b.line))
+		if b.cursor < len(b.line) && count > 0 {
+			spaces := []rune(strings.Repeat(" ", count))
+			b.line = append(b.line[:b.cursor], append(spaces, b.line[b.cursor:]...)...)
+		}
+		if len(b.line) > maxLineLength {
+			b.line = b.line[:maxLineLength]
+		}
+	}
+}
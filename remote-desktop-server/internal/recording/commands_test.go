@@ -0,0 +1,131 @@
+package recording
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractCommands(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		timing string
+		want   []*Command
+	}{
+		{
+			name:   "debian prompt",
+			data:   "user@host:~$ ls -la\r\ntotal 0\r\n",
+			timing: "1.5 21\n0.1 9\n",
+			want:   []*Command{{TimestampMs: 1500, Text: "ls -la"}},
+		},
+		{
+			name:   "root prompt in brackets",
+			data:   "[root@host tmp]# whoami\nroot\n",
+			timing: "0 29\n",
+			want:   []*Command{{TimestampMs: 0, Text: "whoami"}},
+		},
+		{
+			name:   "virtualenv prompt",
+			data:   "(venv) dev@box:~/app$ python manage.py\n",
+			timing: "2 39\n",
+			want:   []*Command{{TimestampMs: 2000, Text: "python manage.py"}},
+		},
+		{
+			name:   "prompt without command",
+			data:   "user@host:~$ \nuser@host:~$",
+			timing: "0 26\n",
+			want:   []*Command{},
+		},
+		{
+			name:   "colored prompt and title",
+			data:   "\x1b]0;user@host: ~\x07\x1b[01;32muser@host\x1b[00m:\x1b[01;34m~\x1b[00m$ uptime\n",
+			timing: "0 71\n",
+			want:   []*Command{{TimestampMs: 0, Text: "uptime"}},
+		},
+		{
+			name:   "backspace and line erase",
+			data:   "user@host:~$ lx\b\x1b[Ks -l\n",
+			timing: "0 26\n",
+			want:   []*Command{{TimestampMs: 0, Text: "ls -l"}},
+		},
+		{
+			name:   "carriage return redraws the line",
+			data:   "user@host:~$ rm -rf\ruser@host:~$ echo hi\x1b[K\n",
+			timing: "0 44\n",
+			want:   []*Command{{TimestampMs: 0, Text: "echo hi"}},
+		},
+		{
+			name:   "output is not a command",
+			data:   "Welcome to host\nlast login: today\n",
+			timing: "0 34\n",
+			want:   []*Command{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractCommands(strings.NewReader(tt.data), strings.NewReader(tt.timing))
+			if err != nil {
+				t.Fatalf("ExtractCommands() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExtractCommands() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineBufferControl(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "cursor left and overwrite", input: "abcd\x1b[2DX\n", want: "abXd"},
+		{name: "cursor right pads with spaces", input: "ab\x1b[3Cc\n", want: "ab   c"},
+		{name: "cursor to column", input: "abcd\x1b[2GX\n", want: "aXcd"},
+		{name: "erase to start of line", input: "abcd\x1b[2D\x1b[1K\n", want: "  cd"},
+		{name: "erase whole line", input: "abcd\x1b[2Kef\n", want: "    ef"},
+		{name: "delete characters", input: "abcdef\x1b[4D\x1b[2P\n", want: "abef"},
+		{name: "insert spaces", input: "abcd\x1b[2D\x1b[2@\n", want: "ab  cd"},
+		{name: "tab and bell", input: "a\tb\x07\n", want: "a b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buffer := &lineBuffer{}
+			lines := buffer.write(tt.input)
+			if len(lines) != 1 || lines[0] != tt.want {
+				t.Errorf("write(%q) = %q, want [%q]", tt.input, lines, tt.want)
+			}
+		})
+	}
+}
+
+// TestLineBufferControlBounds проверяет, что счетчики последовательностей CSI не выводят
+// строку и курсор за пределы maxLineLength
+func TestLineBufferControlBounds(t *testing.T) {
+	long := strings.Repeat("a", maxLineLength)
+	tests := []struct {
+		name    string
+		input   string
+		wantLen int
+	}{
+		{name: "huge insert", input: "abcd\x1b[2D\x1b[999999999999@\n", wantLen: maxLineLength},
+		{name: "insert into full line", input: long + "\x1b[10D\x1b[5@\n", wantLen: maxLineLength},
+		{name: "repeated inserts", input: "ab\x1b[D" + strings.Repeat("\x1b[4000@", 100) + "\n", wantLen: maxLineLength},
+		{name: "huge delete", input: "abcd\x1b[2D\x1b[999999999999P\n", wantLen: 2},
+		{name: "cursor right overflow", input: "ab\x1b[9223372036854775807Cc\n", wantLen: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buffer := &lineBuffer{}
+			lines := buffer.write(tt.input)
+			if len(lines) != 1 || len([]rune(lines[0])) > maxLineLength {
+				t.Fatalf("write() returned %d lines, line length over %d", len(lines), maxLineLength)
+			}
+			if got := len(strings.TrimRight(lines[0], " ")); got != tt.wantLen {
+				t.Errorf("line length = %d, want %d", got, tt.wantLen)
+			}
+		})
+	}
+}
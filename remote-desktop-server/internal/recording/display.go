@@ -0,0 +1,338 @@
+package recording
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strconv"
+)
+
+// Ограничения экрана, чтобы поврежденная запись не исчерпала память
+const (
+	maxLayerSize     = 8192                            // Ширина и высота слоя
+	maxLayers        = 512                             // Количество слоев и буферов
+	maxStreams       = 64                              // Количество одновременно открытых потоков изображений
+	maxStreamSize    = 16 << 20                        // Размер данных одного потока изображения
+	maxDisplayPixels = 2 * maxLayerSize * maxLayerSize // Суммарная площадь всех слоев
+)
+
+// errDisplayLimit возвращается, если запись превышает ограничения экрана.
+// Такую запись воспроизведение не продолжает.
+var errDisplayLimit = fmt.Errorf("%w: display limit exceeded", ErrMalformed)
+
+// Маски каналов Guacamole, определяющие способ наложения изображения
+const (
+	maskSrc = 0xC // Замена содержимого (SRC)
+)
+
+// defaultLayer индекс основного слоя экрана
+const defaultLayer = 0
+
+// layer хранит содержимое слоя или буфера и текущий контур для заливки
+type layer struct {
+	img  *image.RGBA
+	path []image.Rectangle
+}
+
+// imageStream накапливает данные изображения, передаваемые инструкциями blob
+type imageStream struct {
+	layer    int
+	mask     int
+	mimetype string
+	x, y     int
+	data     bytes.Buffer
+}
+
+// display воспроизводит графические инструкции записи.
+// Поддерживаются size, png, img/blob/end, rect/cfill, copy и dispose; остальные
+// инструкции (курсор, звук, вложенные слои) на итоговое изображение экрана не влияют
+// и пропускаются.
+type display struct {
+	layers  map[int]*layer
+	streams map[int]*imageStream
+}
+
+// newDisplay создает пустой экран
+func newDisplay() *display {
+	return &display{
+		layers:  map[int]*layer{defaultLayer: {img: image.NewRGBA(image.Rect(0, 0, 0, 0))}},
+		streams: make(map[int]*imageStream),
+	}
+}
+
+// apply применяет графическую инструкцию к экрану.
+// Ошибки отдельных инструкций (например, неподдерживаемый формат изображения)
+// не прерывают воспроизведение; превышение ограничений экрана возвращает errDisplayLimit.
+func (d *display) apply(instruction *Instruction) error {
+	args := instruction.Args
+	switch instruction.Opcode {
+	case "size":
+		values, err := ints(args, 3)
+		if err != nil {
+			return err
+		}
+		return d.resize(values[0], values[1], values[2])
+	case "png":
+		if len(args) < 5 {
+			return fmt.Errorf("png: %w", ErrMalformed)
+		}
+		values, err := ints(args[:4], 4)
+		if err != nil {
+			return err
+		}
+		data, err := base64.StdEncoding.DecodeString(args[4])
+		if err != nil {
+			return fmt.Errorf("png: %w", err)
+		}
+		return d.drawEncoded(values[1], values[0], "image/png", values[2], values[3], data)
+	case "img":
+		if len(args) < 6 {
+			return fmt.Errorf("img: %w", ErrMalformed)
+		}
+		values, err := ints([]string{args[0], args[1], args[2], args[4], args[5]}, 5)
+		if err != nil {
+			return err
+		}
+		if _, ok := d.streams[values[0]]; !ok && len(d.streams) >= maxStreams {
+			return fmt.Errorf("img: %w: more than %d open streams", errDisplayLimit, maxStreams)
+		}
+		d.streams[values[0]] = &imageStream{
+			mask:     values[1],
+			layer:    values[2],
+			mimetype: args[3],
+			x:        values[3],
+			y:        values[4],
+		}
+	case "blob":
+		if len(args) < 2 {
+			return fmt.Errorf("blob: %w", ErrMalformed)
+		}
+		index, err := strconv.Atoi(args[0])
+		if err != nil {
+			return err
+		}
+		stream, ok := d.streams[index]
+		if !ok {
+			return nil
+		}
+		if stream.data.Len()+base64.StdEncoding.DecodedLen(len(args[1])) > maxStreamSize {
+			delete(d.streams, index)
+			return fmt.Errorf("blob: %w: stream exceeds %d bytes", errDisplayLimit, maxStreamSize)
+		}
+		data, err := base64.StdEncoding.DecodeString(args[1])
+		if err != nil {
+			return fmt.Errorf("blob: %w", err)
+		}
+		stream.data.Write(data)
+	case "end":
+		if len(args) < 1 {
+			return fmt.Errorf("end: %w", ErrMalformed)
+		}
+		index, err := strconv.Atoi(args[0])
+		if err != nil {
+			return err
+		}
+		stream, ok := d.streams[index]
+		if !ok {
+			return nil
+		}
+		delete(d.streams, index)
+		return d.drawEncoded(stream.layer, stream.mask, stream.mimetype, stream.x, stream.y, stream.data.Bytes())
+	case "rect":
+		values, err := ints(args, 5)
+		if err != nil {
+			return err
+		}
+		rect := image.Rect(values[1], values[2], values[1]+values[3], values[2]+values[4])
+		if err := d.fit(values[0], rect); err != nil {
+			return err
+		}
+		target, err := d.layer(values[0])
+		if err != nil {
+			return err
+		}
+		target.path = append(target.path, rect)
+	case "cfill":
+		values, err := ints(args, 6)
+		if err != nil {
+			return err
+		}
+		target, err := d.layer(values[1])
+		if err != nil {
+			return err
+		}
+		fill := image.NewUniform(color.NRGBA{
+			R: uint8(values[2]),
+			G: uint8(values[3]),
+			B: uint8(values[4]),
+			A: uint8(values[5]),
+		})
+		for _, rect := range target.path {
+			draw.Draw(target.img, rect, fill, image.Point{}, operation(values[0]))
+		}
+		target.path = nil
+	case "copy":
+		values, err := ints(args, 9)
+		if err != nil {
+			return err
+		}
+		src, err := d.layer(values[0])
+		if err != nil {
+			return err
+		}
+		srcRect := image.Rect(values[1], values[2], values[1]+values[3], values[2]+values[4])
+		dstRect := image.Rect(values[7], values[8], values[7]+values[3], values[8]+values[4])
+		if err := d.fit(values[6], dstRect); err != nil {
+			return err
+		}
+		dst, err := d.layer(values[6])
+		if err != nil {
+			return err
+		}
+		draw.Draw(dst.img, dstRect, src.img, srcRect.Min, operation(values[5]))
+	case "dispose":
+		values, err := ints(args, 1)
+		if err != nil {
+			return err
+		}
+		if values[0] != defaultLayer {
+			delete(d.layers, values[0])
+		}
+	}
+	return nil
+}
+
+// snapshot возвращает копию основного слоя экрана
+func (d *display) snapshot() *image.RGBA {
+	src := d.layers[defaultLayer].img
+	dst := image.NewRGBA(src.Bounds())
+	draw.Draw(dst, dst.Bounds(), src, src.Bounds().Min, draw.Src)
+	return dst
+}
+
+// layer возвращает слой по индексу, создавая пустой слой при первом обращении
+func (d *display) layer(index int) (*layer, error) {
+	target, ok := d.layers[index]
+	if !ok {
+		if len(d.layers) >= maxLayers {
+			return nil, fmt.Errorf("%w: more than %d layers", errDisplayLimit, maxLayers)
+		}
+		target = &layer{img: image.NewRGBA(image.Rect(0, 0, 0, 0))}
+		d.layers[index] = target
+	}
+	return target, nil
+}
+
+// resize изменяет размер слоя с сохранением содержимого
+func (d *display) resize(index int, width int, height int) error {
+	width = clamp(width, 0, maxLayerSize)
+	height = clamp(height, 0, maxLayerSize)
+	target, err := d.layer(index)
+	if err != nil {
+		return err
+	}
+	pixels := width * height
+	for _, other := range d.layers {
+		if other != target {
+			pixels += other.img.Bounds().Dx() * other.img.Bounds().Dy()
+		}
+	}
+	if pixels > maxDisplayPixels {
+		return fmt.Errorf("%w: layers exceed %d pixels", errDisplayLimit, maxDisplayPixels)
+	}
+	resized := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(resized, resized.Bounds(), target.img, image.Point{}, draw.Src)
+	target.img = resized
+	return nil
+}
+
+// fit увеличивает буфер (слой с отрицательным индексом), чтобы в него поместилась область.
+// Буферы Guacamole не имеют фиксированного размера и растут при рисовании.
+func (d *display) fit(index int, rect image.Rectangle) error {
+	if index >= 0 {
+		return nil
+	}
+	target, err := d.layer(index)
+	if err != nil {
+		return err
+	}
+	bounds := target.img.Bounds()
+	if rect.Max.X <= bounds.Max.X && rect.Max.Y <= bounds.Max.Y {
+		return nil
+	}
+	return d.resize(index, max(bounds.Max.X, rect.Max.X), max(bounds.Max.Y, rect.Max.Y))
+}
+
+// drawEncoded декодирует изображение и рисует его на слое
+func (d *display) drawEncoded(index int, mask int, mimetype string, x int, y int, data []byte) error {
+	var (
+		decode       func(io.Reader) (image.Image, error)
+		decodeConfig func(io.Reader) (image.Config, error)
+	)
+	switch mimetype {
+	case "image/png":
+		decode, decodeConfig = png.Decode, png.DecodeConfig
+	case "image/jpeg":
+		decode, decodeConfig = jpeg.Decode, jpeg.DecodeConfig
+	default:
+		return fmt.Errorf("unsupported image type %q", mimetype)
+	}
+	// Размер проверяется до декодирования: небольшое сжатое изображение может занимать гигабайты
+	imgConfig, err := decodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to decode %s: %w", mimetype, err)
+	}
+	if imgConfig.Width > maxLayerSize || imgConfig.Height > maxLayerSize {
+		return fmt.Errorf("%w: %s image %dx%d", errDisplayLimit, mimetype, imgConfig.Width, imgConfig.Height)
+	}
+	img, err := decode(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to decode %s: %w", mimetype, err)
+	}
+	rect := img.Bounds().Sub(img.Bounds().Min).Add(image.Pt(x, y))
+	if err := d.fit(index, rect); err != nil {
+		return err
+	}
+	target, err := d.layer(index)
+	if err != nil {
+		return err
+	}
+	draw.Draw(target.img, rect, img, img.Bounds().Min, operation(mask))
+	return nil
+}
+
+// operation возвращает операцию наложения для маски каналов Guacamole.
+// Маски, отличные от SRC, приводятся к наложению поверх (OVER).
+func operation(mask int) draw.Op {
+	if mask == maskSrc {
+		return draw.Src
+	}
+	return draw.Over
+}
+
+// ints разбирает первые count аргументов инструкции как целые числа
+func ints(args []string, count int) ([]int, error) {
+	if len(args) < count {
+		return nil, fmt.Errorf("%w: expected %d arguments, got %d", ErrMalformed, count, len(args))
+	}
+	values := make([]int, count)
+	for i := 0; i < count; i++ {
+		value, err := strconv.Atoi(args[i])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrMalformed, err.Error())
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// clamp ограничивает значение диапазоном [low, high]
+func clamp(value int, low int, high int) int {
+	return min(max(value, low), high)
+}
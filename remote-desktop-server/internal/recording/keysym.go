@@ -0,0 +1,57 @@
+package recording
+
+import "fmt"
+
+// keysymNames содержит названия специальных клавиш X11 keysym, используемых Guacamole
+var keysymNames = map[int]string{
+	0xff08: "BackSpace",
+	0xff09: "Tab",
+	0xff0d: "Return",
+	0xff13: "Pause",
+	0xff14: "Scroll_Lock",
+	0xff1b: "Escape",
+	0xff50: "Home",
+	0xff51: "Left",
+	0xff52: "Up",
+	0xff53: "Right",
+	0xff54: "Down",
+	0xff55: "Page_Up",
+	0xff56: "Page_Down",
+	0xff57: "End",
+	0xff61: "Print",
+	0xff63: "Insert",
+	0xff67: "Menu",
+	0xff7f: "Num_Lock",
+	0xff8d: "KP_Enter",
+	0xffe1: "Shift_L",
+	0xffe2: "Shift_R",
+	0xffe3: "Control_L",
+	0xffe4: "Control_R",
+	0xffe5: "Caps_Lock",
+	0xffe7: "Meta_L",
+	0xffe8: "Meta_R",
+	0xffe9: "Alt_L",
+	0xffea: "Alt_R",
+	0xffeb: "Super_L",
+	0xffec: "Super_R",
+	0xfe03: "AltGr",
+	0xffff: "Delete",
+}
+
+// KeysymName возвращает читаемое название клавиши по ее keysym.
+// Печатные символы возвращаются как есть, функциональные клавиши — как F1..F24.
+func KeysymName(keysym int) string {
+	switch {
+	case keysym >= 0x20 && keysym <= 0x7e, keysym >= 0xa0 && keysym <= 0xff:
+		return string(rune(keysym))
+	case keysym >= 0x01000100 && keysym <= 0x0110ffff:
+		// Символы Unicode кодируются как 0x01000000 + код символа
+		return string(rune(keysym - 0x01000000))
+	case keysym >= 0xffbe && keysym <= 0xffd5:
+		return fmt.Sprintf("F%d", keysym-0xffbe+1)
+	}
+	if name, ok := keysymNames[keysym]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", keysym)
+}
@@ -0,0 +1,137 @@
+// Package recording разбирает записи сессий Apache Guacamole.
+//
+// Запись guacd — это поток инструкций протокола Guacamole, отправленных клиенту.
+// Каждая инструкция состоит из элементов вида LENGTH.VALUE, разделенных запятыми
+// и завершенных точкой с запятой, например "4.size,1.0,4.1024,3.768;".
+// Длина элемента указывается в символах Unicode, а не в байтах.
+package recording
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxElementLength ограничивает длину элемента инструкции, чтобы поврежденная запись
+// не приводила к чтению всего файла в память
+const maxElementLength = 16 << 20
+
+// ErrMalformed возвращается, если поток не соответствует формату протокола Guacamole
+var ErrMalformed = errors.New("malformed guacamole instruction")
+
+// Instruction представляет одну инструкцию протокола Guacamole
+type Instruction struct {
+	Opcode string   // Код операции (первый элемент)
+	Args   []string // Аргументы инструкции
+	Offset int64    // Смещение начала инструкции в байтах от начала потока
+}
+
+// Reader последовательно читает инструкции из потока записи
+type Reader struct {
+	src    *bufio.Reader
+	offset int64
+}
+
+// NewReader создает Reader для чтения инструкций из r
+func NewReader(r io.Reader) *Reader {
+	return &Reader{src: bufio.NewReaderSize(r, 64<<10)}
+}
+
+// Offset возвращает количество прочитанных байт
+func (r *Reader) Offset() int64 {
+	return r.offset
+}
+
+// Next читает следующую инструкцию.
+//
+// Возвращает:
+//   - *Instruction: прочитанная инструкция
+//   - error: io.EOF в конце потока, ErrMalformed при нарушении формата
+func (r *Reader) Next() (*Instruction, error) {
+	instruction := &Instruction{Offset: r.offset}
+	elements := make([]string, 0, 8)
+	for {
+		element, terminator, err := r.element()
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(elements) == 0 {
+				return nil, io.EOF
+			}
+			if errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("%w: unexpected end of stream", ErrMalformed)
+			}
+			return nil, err
+		}
+		elements = append(elements, element)
+		if terminator == ';' {
+			break
+		}
+	}
+	instruction.Opcode = elements[0]
+	instruction.Args = elements[1:]
+	return instruction, nil
+}
+
+// element читает один элемент инструкции и следующий за ним разделитель.
+func (r *Reader) element() (string, byte, error) {
+	length := 0
+	digits := 0
+	for {
+		b, err := r.readByte()
+		if err != nil {
+			if errors.Is(err, io.EOF) && digits > 0 {
+				return "", 0, fmt.Errorf("%w: unexpected end of stream", ErrMalformed)
+			}
+			return "", 0, err
+		}
+		// Переводы строк между инструкциями допускаются и пропускаются
+		if digits == 0 && (b == '\n' || b == '\r') {
+			continue
+		}
+		if b == '.' && digits > 0 {
+			break
+		}
+		if b < '0' || b > '9' {
+			return "", 0, fmt.Errorf("%w: unexpected byte %q in element length", ErrMalformed, b)
+		}
+		length = length*10 + int(b-'0')
+		digits++
+		if length > maxElementLength {
+			return "", 0, fmt.Errorf("%w: element is too long", ErrMalformed)
+		}
+	}
+
+	var value strings.Builder
+	value.Grow(length)
+	for i := 0; i < length; i++ {
+		char, size, err := r.src.ReadRune()
+		if err != nil {
+			return "", 0, fmt.Errorf("%w: unexpected end of stream", ErrMalformed)
+		}
+		if char == utf8.RuneError && size == 1 {
+			return "", 0, fmt.Errorf("%w: invalid UTF-8", ErrMalformed)
+		}
+		r.offset += int64(size)
+		value.WriteRune(char)
+	}
+
+	terminator, err := r.readByte()
+	if err != nil {
+		return "", 0, fmt.Errorf("%w: unexpected end of stream", ErrMalformed)
+	}
+	if terminator != ',' && terminator != ';' {
+		return "", 0, fmt.Errorf("%w: unexpected terminator %q", ErrMalformed, terminator)
+	}
+	return value.String(), terminator, nil
+}
+
+// readByte читает один байт и учитывает его в смещении.
+func (r *Reader) readByte() (byte, error) {
+	b, err := r.src.ReadByte()
+	if err == nil {
+		r.offset++
+	}
+	return b, err
+}
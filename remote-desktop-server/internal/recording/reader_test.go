@@ -0,0 +1,108 @@
+package recording
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReaderNext(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		want   []Instruction
+		offset int64
+	}{
+		{
+			name:   "single instruction",
+			input:  "4.size,1.0,4.1024,3.768;",
+			want:   []Instruction{{Opcode: "size", Args: []string{"0", "1024", "768"}}},
+			offset: 24,
+		},
+		{
+			name:  "instruction without arguments",
+			input: "3.nop;",
+			want:  []Instruction{{Opcode: "nop", Args: []string{}}},
+		},
+		{
+			name:  "empty element",
+			input: "4.name,0.;",
+			want:  []Instruction{{Opcode: "name", Args: []string{""}}},
+		},
+		{
+			name:  "newlines between instructions",
+			input: "4.sync,2.10;\n\r\n4.sync,2.20;\n",
+			want: []Instruction{
+				{Opcode: "sync", Args: []string{"10"}},
+				{Opcode: "sync", Args: []string{"20"}, Offset: 12},
+			},
+		},
+		{
+			name:  "length counts unicode characters",
+			input: "4.name,6.привет;3.nop;",
+			want: []Instruction{
+				{Opcode: "name", Args: []string{"привет"}},
+				{Opcode: "nop", Args: []string{}, Offset: 22},
+			},
+		},
+		{
+			name:  "separators inside value",
+			input: "3.arg,3.a;b,1.,;",
+			want:  []Instruction{{Opcode: "arg", Args: []string{"a;b", ","}}},
+		},
+		{
+			name:  "empty stream",
+			input: "",
+			want:  []Instruction{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := NewReader(strings.NewReader(tt.input))
+			got := make([]Instruction, 0)
+			for {
+				instruction, err := reader.Next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Next() error = %v", err)
+				}
+				got = append(got, *instruction)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Next() = %+v, want %+v", got, tt.want)
+			}
+			if tt.offset > 0 && reader.Offset() != tt.offset {
+				t.Errorf("Offset() = %d, want %d", reader.Offset(), tt.offset)
+			}
+		})
+	}
+}
+
+func TestReaderNextMalformed(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "missing length", input: ".size;"},
+		{name: "letter in length", input: "4x.size;"},
+		{name: "missing terminator", input: "4.size"},
+		{name: "wrong terminator", input: "4.size:"},
+		{name: "value shorter than length", input: "10.size;"},
+		{name: "truncated after separator", input: "4.size,1.0,"},
+		{name: "truncated length", input: "4.sync,12"},
+		{name: "invalid utf-8", input: "2.a\xff;"},
+		{name: "element too long", input: "99999999999.x;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewReader(strings.NewReader(tt.input)).Next()
+			if !errors.Is(err, ErrMalformed) {
+				t.Errorf("Next() error = %v, want ErrMalformed", err)
+			}
+		})
+	}
+}
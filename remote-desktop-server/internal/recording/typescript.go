@@ -0,0 +1,152 @@
+package recording
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// typescriptHeader заголовок, который guacd записывает в начало файла typescript.
+// Заголовок и окончание записи не учитываются в файле временных меток, поэтому
+// окончание не попадает ни в один фрагмент вывода.
+const typescriptHeader = "[BEGIN TYPESCRIPT]\n"
+
+// maxTypescriptChunk ограничивает размер одного фрагмента вывода из файла временных меток
+const maxTypescriptChunk = 1 << 20
+
+// TypescriptEvent фрагмент вывода терминала
+type TypescriptEvent struct {
+	Time time.Duration // Время от начала записи
+	Data string        // Вывод терминала (только целые символы UTF-8)
+}
+
+// TypescriptReader последовательно читает вывод терминала из пары файлов typescript и timing.
+// Каждая строка файла временных меток имеет вид "<задержка в секундах> <количество байт>".
+// Символы UTF-8, разорванные между фрагментами, переносятся в следующий фрагмент.
+type TypescriptReader struct {
+	data    *bufio.Reader
+	timing  *bufio.Scanner
+	elapsed time.Duration
+	pending []byte
+	started bool
+}
+
+// NewTypescriptReader создает TypescriptReader для файла вывода data и файла временных меток timing
+func NewTypescriptReader(data io.Reader, timing io.Reader) *TypescriptReader {
+	return &TypescriptReader{
+		data:   bufio.NewReader(data),
+		timing: bufio.NewScanner(timing),
+	}
+}
+
+// Next читает следующий фрагмент вывода.
+//
+// Возвращает:
+//   - *TypescriptEvent: фрагмент вывода
+//   - error: io.EOF после последнего фрагмента, ErrMalformed при нарушении формата
+func (r *TypescriptReader) Next() (*TypescriptEvent, error) {
+	if !r.started {
+		r.started = true
+		if header, err := r.data.Peek(len(typescriptHeader)); err == nil && string(header) == typescriptHeader {
+			r.data.Discard(len(typescriptHeader))
+		}
+	}
+
+	for {
+		delay, count, err := r.nextTiming()
+		if errors.Is(err, io.EOF) {
+			if len(r.pending) > 0 {
+				event := &TypescriptEvent{Time: r.elapsed, Data: strings.ToValidUTF8(string(r.pending), "�")}
+				r.pending = nil
+				return event, nil
+			}
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		r.elapsed += delay
+
+		chunk := make([]byte, count)
+		read, err := io.ReadFull(r.data, chunk)
+		chunk = append(r.pending, chunk[:read]...)
+		if err != nil {
+			// Файл вывода короче, чем указано во временных метках: запись оборвана
+			r.pending = nil
+			if len(chunk) == 0 {
+				return nil, io.EOF
+			}
+			return &TypescriptEvent{Time: r.elapsed, Data: strings.ToValidUTF8(string(chunk), "�")}, nil
+		}
+
+		complete := completeRunes(chunk)
+		r.pending = append([]byte(nil), chunk[complete:]...)
+		if complete == 0 {
+			continue
+		}
+		return &TypescriptEvent{Time: r.elapsed, Data: strings.ToValidUTF8(string(chunk[:complete]), "�")}, nil
+	}
+}
+
+// nextTiming читает следующую строку файла временных меток
+func (r *TypescriptReader) nextTiming() (time.Duration, int, error) {
+	for r.timing.Scan() {
+		line := strings.TrimSpace(r.timing.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return 0, 0, fmt.Errorf("%w: invalid timing line %q", ErrMalformed, line)
+		}
+		seconds, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil || seconds < 0 {
+			return 0, 0, fmt.Errorf("%w: invalid timing delay %q", ErrMalformed, fields[0])
+		}
+		count, err := strconv.Atoi(fields[1])
+		if err != nil || count < 0 || count > maxTypescriptChunk {
+			return 0, 0, fmt.Errorf("%w: invalid timing length %q", ErrMalformed, fields[1])
+		}
+		return time.Duration(seconds * float64(time.Second)), count, nil
+	}
+	if err := r.timing.Err(); err != nil {
+		return 0, 0, err
+	}
+	return 0, 0, io.EOF
+}
+
+// TypescriptDuration возвращает длительность записи по файлу временных меток
+func TypescriptDuration(timing io.Reader) (time.Duration, error) {
+	reader := &TypescriptReader{timing: bufio.NewScanner(timing)}
+	var total time.Duration
+	for {
+		delay, _, err := reader.nextTiming()
+		if errors.Is(err, io.EOF) {
+			return total, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		total += delay
+	}
+}
+
+// completeRunes возвращает длину префикса, не заканчивающегося разорванным символом UTF-8
+func completeRunes(data []byte) int {
+	// Символ UTF-8 занимает не более 4 байт, поэтому достаточно проверить хвост
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if !utf8.RuneStart(data[i]) {
+			continue
+		}
+		if utf8.FullRune(data[i:]) {
+			return len(data)
+		}
+		return i
+	}
+	return len(data)
+}
@@ -0,0 +1,173 @@
+package recording
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTypescriptReaderNext(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		timing string
+		want   []TypescriptEvent
+	}{
+		{
+			name:   "header is skipped",
+			data:   "[BEGIN TYPESCRIPT]\nhello world\n[END TYPESCRIPT]\n",
+			timing: "0.5 6\n1.25 6\n",
+			want: []TypescriptEvent{
+				{Time: 500 * time.Millisecond, Data: "hello "},
+				{Time: 1750 * time.Millisecond, Data: "world\n"},
+			},
+		},
+		{
+			name:   "without header",
+			data:   "ls\n",
+			timing: "0 3\n",
+			want:   []TypescriptEvent{{Time: 0, Data: "ls\n"}},
+		},
+		{
+			name:   "blank timing lines",
+			data:   "ab",
+			timing: "\n0.1 1\n\n  \n0.1 1\n",
+			want: []TypescriptEvent{
+				{Time: 100 * time.Millisecond, Data: "a"},
+				{Time: 200 * time.Millisecond, Data: "b"},
+			},
+		},
+		{
+			name: "rune split between chunks",
+			// "при" занимает 6 байт: первый фрагмент обрывает второй символ
+			data:   "при",
+			timing: "0.1 3\n0.2 3\n",
+			want: []TypescriptEvent{
+				{Time: 100 * time.Millisecond, Data: "п"},
+				{Time: 300 * time.Millisecond, Data: "ри"},
+			},
+		},
+		{
+			name:   "chunk with incomplete rune only",
+			data:   "ж!",
+			timing: "0.1 1\n0.1 2\n",
+			want:   []TypescriptEvent{{Time: 200 * time.Millisecond, Data: "ж!"}},
+		},
+		{
+			name:   "incomplete rune at the end",
+			data:   "a\xd0",
+			timing: "0.1 2\n",
+			want: []TypescriptEvent{
+				{Time: 100 * time.Millisecond, Data: "a"},
+				{Time: 100 * time.Millisecond, Data: "�"},
+			},
+		},
+		{
+			name:   "output shorter than timing",
+			data:   "abc",
+			timing: "0.1 2\n0.1 5\n0.1 5\n",
+			want: []TypescriptEvent{
+				{Time: 100 * time.Millisecond, Data: "ab"},
+				{Time: 200 * time.Millisecond, Data: "c"},
+			},
+		},
+		{
+			name:   "empty recording",
+			data:   "",
+			timing: "",
+			want:   []TypescriptEvent{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := NewTypescriptReader(strings.NewReader(tt.data), strings.NewReader(tt.timing))
+			got := make([]TypescriptEvent, 0)
+			for {
+				event, err := reader.Next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Next() error = %v", err)
+				}
+				got = append(got, *event)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Next() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTypescriptReaderNextMalformed(t *testing.T) {
+	tests := []struct {
+		name   string
+		timing string
+	}{
+		{name: "single field", timing: "0.1\n"},
+		{name: "extra field", timing: "0.1 1 1\n"},
+		{name: "invalid delay", timing: "abc 1\n"},
+		{name: "negative delay", timing: "-1 1\n"},
+		{name: "invalid length", timing: "0.1 x\n"},
+		{name: "negative length", timing: "0.1 -1\n"},
+		{name: "chunk too long", timing: "0.1 2097152\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := NewTypescriptReader(strings.NewReader("data"), strings.NewReader(tt.timing))
+			if _, err := reader.Next(); !errors.Is(err, ErrMalformed) {
+				t.Errorf("Next() error = %v, want ErrMalformed", err)
+			}
+		})
+	}
+}
+
+func TestTypescriptDuration(t *testing.T) {
+	tests := []struct {
+		name    string
+		timing  string
+		want    time.Duration
+		wantErr error
+	}{
+		{name: "empty", timing: "", want: 0},
+		{name: "sum of delays", timing: "0.5 10\n1.5 3\n\n2 1\n", want: 4 * time.Second},
+		{name: "malformed", timing: "0.5\n", wantErr: ErrMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TypescriptDuration(strings.NewReader(tt.timing))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("TypescriptDuration() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("TypescriptDuration() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompleteRunes(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want int
+	}{
+		{name: "empty", data: "", want: 0},
+		{name: "ascii", data: "abc", want: 3},
+		{name: "complete multibyte", data: "aж", want: 3},
+		{name: "split two-byte rune", data: "a\xd0", want: 1},
+		{name: "split four-byte rune", data: "a\xf0\x9f\x98", want: 1},
+		{name: "complete four-byte rune", data: "a😀", want: 5},
+		{name: "continuation bytes only", data: "\x80\x80", want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := completeRunes([]byte(tt.data)); got != tt.want {
+				t.Errorf("completeRunes(%q) = %d, want %d", tt.data, got, tt.want)
+			}
+		})
+	}
+}
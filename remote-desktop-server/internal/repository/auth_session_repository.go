@@ -0,0 +1,341 @@
+// Package repository предоставляет реализации репозиториев для работы с данными приложения.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+)
+
+// authSessionColumns список колонок сессии входа в порядке scanAuthSession
+const authSessionColumns = `
+	id, user_id, token_version, guacamole_token, guacamole_credentials, revoked_at, created_at
+`
+
+// authSessionRepo реализует AuthSessionRepository для работы с PostgreSQL
+type authSessionRepo struct {
+	db *sql.DB
+}
+
+// AuthSessionRepository определяет контракт для хранения сессий входа и их refresh токенов
+type AuthSessionRepository interface {
+	// Create сохраняет новую сессию входа и заполняет ее идентификатор и время создания
+	Create(ctx context.Context, session *common.AuthSession) error
+
+	// FindByID возвращает сессию входа по идентификатору или nil, если она не найдена
+	FindByID(ctx context.Context, id uuid.UUID) (*common.AuthSession, error)
+
+	// Revoke отзывает сессию входа и возвращает true, если она была действующей
+	Revoke(ctx context.Context, id uuid.UUID) (bool, error)
+
+	// RevokeByUser отзывает все действующие сессии пользователя и возвращает их
+	RevokeByUser(ctx context.Context, userID uuid.UUID) ([]*common.AuthSession, error)
+
+	// FindByGuacamoleToken возвращает действующую сессию с текущим или предыдущим токеном Guacamole или nil
+	FindByGuacamoleToken(ctx context.Context, guacToken string) (*common.AuthSession, error)
+
+	// ReplaceGuacamoleToken заменяет токен Guacamole сессии, если он не был заменен ранее
+	ReplaceGuacamoleToken(ctx context.Context, id uuid.UUID, oldToken string, newToken string) (bool, error)
+
+	// CreateRefreshToken сохраняет хэш нового refresh токена сессии
+	CreateRefreshToken(ctx context.Context, sessionID uuid.UUID, tokenHash string, ttl time.Duration) error
+
+	// FindRefreshToken возвращает refresh токен по хэшу или nil, если он не найден
+	FindRefreshToken(ctx context.Context, tokenHash string) (*common.RefreshToken, error)
+
+	// RotateRefreshToken помечает токен использованным и сохраняет следующий токен сессии
+	RotateRefreshToken(ctx context.Context, token *common.RefreshToken, nextTokenHash string, ttl time.Duration) (bool, error)
+}
+
+// NewAuthSessionRepository создает новый экземпляр AuthSessionRepository
+func NewAuthSessionRepository(db *sql.DB) AuthSessionRepository {
+	return &authSessionRepo{
+		db: db,
+	}
+}
+
+// Create сохраняет новую сессию входа
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - session: данные сессии (ID и CreatedAt заполняются после сохранения)
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса
+func (repo *authSessionRepo) Create(ctx context.Context, session *common.AuthSession) error {
+	query := `
+		INSERT INTO auth_sessions (user_id, token_version, guacamole_token, guacamole_credentials)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+	return repo.db.QueryRowContext(
+		ctx,
+		query,
+		session.UserID,
+		session.TokenVersion,
+		session.GuacamoleToken,
+		session.GuacamoleCredentials,
+	).Scan(&session.ID, &session.CreatedAt)
+}
+
+// FindByID ищет сессию входа по идентификатору
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор сессии
+//
+// Возвращает:
+//   - *common.AuthSession: найденная сессия (в том числе отозванная) или nil
+//   - error: ошибка выполнения запроса
+func (repo *authSessionRepo) FindByID(ctx context.Context, id uuid.UUID) (*common.AuthSession, error) {
+	query := "SELECT" + authSessionColumns + "FROM auth_sessions WHERE id = $1"
+	session, err := scanAuthSession(repo.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return session, err
+}
+
+// Revoke отзывает сессию входа. Повторный отзыв не меняет время отзыва.
+// Зашифрованные учетные данные Guacamole отозванной сессии удаляются.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор сессии
+//
+// Возвращает:
+//   - bool: true, если сессия была действующей и отозвана этим вызовом
+//   - error: ошибка выполнения запроса
+func (repo *authSessionRepo) Revoke(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `
+		UPDATE auth_sessions SET revoked_at = CURRENT_TIMESTAMP, guacamole_credentials = NULL
+		WHERE id = $1 AND revoked_at IS NULL
+	`
+	result, err := repo.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// RevokeByUser отзывает все действующие сессии входа пользователя
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - userID: идентификатор пользователя
+//
+// Возвращает:
+//   - []*common.AuthSession: сессии, отозванные этим вызовом
+//   - error: ошибка выполнения запроса
+func (repo *authSessionRepo) RevokeByUser(ctx context.Context, userID uuid.UUID) ([]*common.AuthSession, error) {
+	query := `
+		UPDATE auth_sessions SET revoked_at = CURRENT_TIMESTAMP, guacamole_credentials = NULL
+		WHERE user_id = $1 AND revoked_at IS NULL
+		RETURNING` + authSessionColumns
+	rows, err := repo.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]*common.AuthSession, 0)
+	for rows.Next() {
+		session, err := scanAuthSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// FindByGuacamoleToken ищет действующую сессию входа по токену Guacamole
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - guacToken: текущий или предыдущий (уже замененный) токен Guacamole сессии
+//
+// Возвращает:
+//   - *common.AuthSession: найденная сессия или nil
+//   - error: ошибка выполнения запроса
+//
+// Особенности:
+//   - Поиск по предыдущему токену позволяет запросам, начатым до замены токена,
+//     получить уже выданный новый токен
+func (repo *authSessionRepo) FindByGuacamoleToken(ctx context.Context, guacToken string) (*common.AuthSession, error) {
+	if guacToken == "" {
+		return nil, nil
+	}
+	query := "SELECT" + authSessionColumns + `
+		FROM auth_sessions
+		WHERE revoked_at IS NULL AND (guacamole_token = $1 OR previous_guacamole_token = $1)
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+	session, err := scanAuthSession(repo.db.QueryRowContext(ctx, query, guacToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return session, err
+}
+
+// ReplaceGuacamoleToken заменяет токен Guacamole сессии входа
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор сессии
+//   - oldToken: токен, который заменяется
+//   - newToken: новый токен
+//
+// Возвращает:
+//   - bool: false, если токен сессии уже отличается от oldToken (заменен параллельным запросом)
+//   - error: ошибка выполнения запроса
+func (repo *authSessionRepo) ReplaceGuacamoleToken(
+	ctx context.Context,
+	id uuid.UUID,
+	oldToken string,
+	newToken string,
+) (bool, error) {
+	query := `
+		UPDATE auth_sessions SET previous_guacamole_token = guacamole_token, guacamole_token = $3
+		WHERE id = $1 AND guacamole_token = $2 AND revoked_at IS NULL
+	`
+	result, err := repo.db.ExecContext(ctx, query, id, oldToken, newToken)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// CreateRefreshToken сохраняет refresh токен сессии входа
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - sessionID: идентификатор сессии
+//   - tokenHash: SHA-256 хэш токена (сам токен передается клиенту и не хранится)
+//   - ttl: время жизни токена
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса
+func (repo *authSessionRepo) CreateRefreshToken(
+	ctx context.Context,
+	sessionID uuid.UUID,
+	tokenHash string,
+	ttl time.Duration,
+) error {
+	query := `
+		INSERT INTO refresh_tokens (session_id, token_hash, expires_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP + make_interval(secs => $3))
+	`
+	_, err := repo.db.ExecContext(ctx, query, sessionID, tokenHash, ttl.Seconds())
+	return err
+}
+
+// FindRefreshToken ищет refresh токен по хэшу
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - tokenHash: SHA-256 хэш токена
+//
+// Возвращает:
+//   - *common.RefreshToken: найденный токен (в том числе использованный или истекший) или nil
+//   - error: ошибка выполнения запроса
+func (repo *authSessionRepo) FindRefreshToken(ctx context.Context, tokenHash string) (*common.RefreshToken, error) {
+	query := `
+		SELECT id, session_id, used_at IS NOT NULL, expires_at <= CURRENT_TIMESTAMP
+		FROM refresh_tokens WHERE token_hash = $1
+	`
+	var token common.RefreshToken
+	err := repo.db.QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.ID,
+		&token.SessionID,
+		&token.Used,
+		&token.Expired,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// RotateRefreshToken обменивает refresh токен на следующий токен той же сессии
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - token: обмениваемый токен
+//   - nextTokenHash: SHA-256 хэш следующего токена
+//   - ttl: время жизни следующего токена
+//
+// Возвращает:
+//   - bool: false, если токен уже был использован (например, параллельным запросом)
+//   - error: ошибка выполнения запроса
+//
+// Особенности:
+//   - Отметка об использовании и создание следующего токена выполняются в одной транзакции
+func (repo *authSessionRepo) RotateRefreshToken(
+	ctx context.Context,
+	token *common.RefreshToken,
+	nextTokenHash string,
+	ttl time.Duration,
+) (bool, error) {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query := "UPDATE refresh_tokens SET used_at = CURRENT_TIMESTAMP WHERE id = $1 AND used_at IS NULL"
+	result, err := tx.ExecContext(ctx, query, token.ID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+
+	query = `
+		INSERT INTO refresh_tokens (session_id, token_hash, expires_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP + make_interval(secs => $3))
+	`
+	if _, err := tx.ExecContext(ctx, query, token.SessionID, nextTokenHash, ttl.Seconds()); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// scanAuthSession читает сессию входа из строки результата
+func scanAuthSession(row interface{ Scan(dest ...any) error }) (*common.AuthSession, error) {
+	var session common.AuthSession
+	err := row.Scan(
+		&session.ID,
+		&session.UserID,
+		&session.TokenVersion,
+		&session.GuacamoleToken,
+		&session.GuacamoleCredentials,
+		&session.RevokedAt,
+		&session.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
@@ -0,0 +1,111 @@
+// Package repository предоставляет реализации репозиториев для работы с данными приложения.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+)
+
+// emailVerificationRepo реализует EmailVerificationRepository для работы с PostgreSQL
+type emailVerificationRepo struct {
+	db *sql.DB
+}
+
+// EmailVerificationRepository определяет контракт для хранения токенов подтверждения электронной почты
+type EmailVerificationRepository interface {
+	// Create сохраняет хэш нового токена подтверждения адреса пользователя
+	Create(ctx context.Context, userID uuid.UUID, email string, tokenHash string, ttl time.Duration) error
+
+	// Consume помечает действующий токен использованным и возвращает его, иначе nil
+	Consume(ctx context.Context, tokenHash string) (*common.EmailVerificationToken, error)
+
+	// Release снимает отметку об использовании токена (компенсация неудачного подтверждения)
+	Release(ctx context.Context, id uuid.UUID) error
+}
+
+// NewEmailVerificationRepository создает новый экземпляр EmailVerificationRepository
+func NewEmailVerificationRepository(db *sql.DB) EmailVerificationRepository {
+	return &emailVerificationRepo{
+		db: db,
+	}
+}
+
+// Create сохраняет токен подтверждения электронной почты
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - userID: идентификатор пользователя
+//   - email: подтверждаемый адрес
+//   - tokenHash: SHA-256 хэш токена (сам токен отправляется на подтверждаемый адрес и не хранится)
+//   - ttl: время жизни токена
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса
+func (repo *emailVerificationRepo) Create(
+	ctx context.Context,
+	userID uuid.UUID,
+	email string,
+	tokenHash string,
+	ttl time.Duration,
+) error {
+	query := `
+		INSERT INTO email_verification_tokens (user_id, email, token_hash, expires_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP + make_interval(secs => $4))
+	`
+	_, err := repo.db.ExecContext(ctx, query, userID, email, tokenHash, ttl.Seconds())
+	return err
+}
+
+// Consume помечает токен подтверждения использованным
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - tokenHash: SHA-256 хэш токена
+//
+// Возвращает:
+//   - *common.EmailVerificationToken: использованный токен или nil, если токен не найден,
+//     истек, уже использован или пользователь удален
+//   - error: ошибка выполнения запроса
+//
+// Особенности:
+//   - Проверка и отметка выполняются одним запросом, поэтому токен нельзя использовать дважды
+func (repo *emailVerificationRepo) Consume(ctx context.Context, tokenHash string) (*common.EmailVerificationToken, error) {
+	query := `
+		UPDATE email_verification_tokens t SET used_at = CURRENT_TIMESTAMP
+		FROM users u
+		WHERE t.token_hash = $1
+			AND t.used_at IS NULL
+			AND t.expires_at > CURRENT_TIMESTAMP
+			AND u.id = t.user_id
+			AND u.deleted_at IS NULL
+		RETURNING t.id, t.user_id, t.email, t.expires_at
+	`
+	var token common.EmailVerificationToken
+	err := repo.db.QueryRowContext(ctx, query, tokenHash).Scan(&token.ID, &token.UserID, &token.Email, &token.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Release снимает отметку об использовании токена подтверждения
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор токена
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса
+func (repo *emailVerificationRepo) Release(ctx context.Context, id uuid.UUID) error {
+	query := "UPDATE email_verification_tokens SET used_at = NULL WHERE id = $1"
+	_, err := repo.db.ExecContext(ctx, query, id)
+	return err
+}
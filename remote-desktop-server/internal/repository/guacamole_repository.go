@@ -6,6 +6,7 @@ import (
 	"database/sql"
 	"errors"
 
+	"github.com/lib/pq"
 	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
 )
 
@@ -16,9 +17,64 @@ type guacamoleRepo struct {
 
 // GuacamoleRepository определяет контракт для работы с хранилищем Apache
 type GuacamoleRepository interface {
-	CreateEntity(ctx context.Context, username string) (uint64, error)
-	CreateUserAndPermissions(ctx context.Context, form common.GuacamoleUser) error
+	// CreateUser создает пользователя Guacamole (сущность, учетную запись и системные разрешения) в одной транзакции
+	CreateUser(ctx context.Context, form common.GuacamoleUser) (uint64, error)
+
+	// FindUserAccounts возвращает всех пользователей Guacamole для сверки с пользователями приложения
+	FindUserAccounts(ctx context.Context) ([]*common.GuacamoleAccount, error)
+
+	// FindUserAccount возвращает пользователя Guacamole по имени или nil, если пользователь не найден
+	FindUserAccount(ctx context.Context, username string) (*common.GuacamoleAccount, error)
+
+	// SetUserDisabled отключает или включает учетную запись пользователя Guacamole
+	SetUserDisabled(ctx context.Context, username string, disabled bool) error
+
+	// SetUserExpired помечает пароль пользователя Guacamole истекшим или снимает эту пометку
+	SetUserExpired(ctx context.Context, username string, expired bool) error
+
+	// SetUserPassword меняет пароль пользователя Guacamole и возвращает прежний
+	SetUserPassword(ctx context.Context, username string, password common.GuacamolePassword) (*common.GuacamolePassword, error)
+
 	AddPermissionToUser(ctx context.Context, id int, permissions []string) error
+
+	// FindSystemPermissions возвращает системные разрешения пользователя Guacamole, включая полученные через группы
+	FindSystemPermissions(ctx context.Context, username string) ([]string, error)
+
+	// FindUserConnectionPermissions возвращает разрешения пользователя Guacamole на подключения, включая полученные через группы
+	FindUserConnectionPermissions(ctx context.Context, username string) ([]*common.UserConnectionPermission, error)
+
+	// SetSystemPermissions заменяет собственные системные разрешения пользователя Guacamole
+	SetSystemPermissions(ctx context.Context, username string, permissions []string) error
+
+	// FindEntity возвращает пользователя или группу Guacamole по имени и типу или nil, если сущность не найдена
+	FindEntity(ctx context.Context, name string, entityType string) (*common.GuacamoleEntity, error)
+
+	// FindEntityByID возвращает пользователя или группу Guacamole по идентификатору или nil, если сущность не найдена
+	FindEntityByID(ctx context.Context, id uint64) (*common.GuacamoleEntity, error)
+
+	// FindConnectionPermissions возвращает разрешения пользователей и групп на подключение
+	FindConnectionPermissions(ctx context.Context, connectionID uint64) ([]*common.ConnectionGrant, error)
+
+	// GrantConnectionPermissions выдает сущности разрешения на подключение
+	GrantConnectionPermissions(ctx context.Context, entityID uint64, connectionID uint64, permissions []string) error
+
+	// RevokeConnectionPermissions отзывает разрешения сущности на подключение (пустой список — все разрешения)
+	RevokeConnectionPermissions(ctx context.Context, entityID uint64, connectionID uint64, permissions []string) error
+
+	// CreateUserGroup создает группу пользователей Guacamole (сущность USER_GROUP)
+	CreateUserGroup(ctx context.Context, name string) error
+
+	// RenameEntity переименовывает пользователя или группу Guacamole
+	RenameEntity(ctx context.Context, name string, newName string, entityType string) error
+
+	// DeleteEntity удаляет пользователя или группу Guacamole вместе с их разрешениями и членством в группах
+	DeleteEntity(ctx context.Context, name string, entityType string) error
+
+	// AddUserGroupMember добавляет пользователя или группу в группу Guacamole
+	AddUserGroupMember(ctx context.Context, groupName string, memberName string, memberType string) error
+
+	// RemoveUserGroupMember удаляет пользователя или группу из группы Guacamole
+	RemoveUserGroupMember(ctx context.Context, groupName string, memberName string, memberType string) error
 }
 
 // NewUserRepository создает новый экземпляр GuacamoleRepository
@@ -28,73 +84,678 @@ func NewGuacamoleRepository(db *sql.DB) GuacamoleRepository {
 	}
 }
 
-func (repo *guacamoleRepo) CreateEntity(ctx context.Context, username string) (uint64, error) {
-	query := "INSERT INTO guacamole_entity (name, type) VALUES ($1, $2) RETURNING entity_id"
-	var id uint64
-	err := repo.db.QueryRowContext(
-		ctx,
-		query,
-		username,
-		"USER",
-	).Scan(&id)
+// CreateUser создает пользователя Guacamole.
+// Сущность, учетная запись и системные разрешения создаются в одной транзакции:
+// при любой ошибке в базе Guacamole не остается частично созданного пользователя.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - form: имя пользователя, хэш и соль пароля, системные разрешения и признак отключения
+//
+// Возвращает:
+//   - uint64: идентификатор созданной сущности
+//   - error: ошибка выполнения запроса (в том числе если пользователь уже существует)
+func (repo *guacamoleRepo) CreateUser(ctx context.Context, form common.GuacamoleUser) (uint64, error) {
+	tx, err := repo.db.BeginTx(ctx, nil)
 	if err != nil {
 		return 0, err
 	}
-	return id, nil
-}
+	defer tx.Rollback()
 
-func (repo *guacamoleRepo) CreateUserAndPermissions(ctx context.Context, form common.GuacamoleUser) error {
-	tx, _ := repo.db.BeginTx(ctx, nil)
-	defer tx.Commit()
-	query := `
+	var id uint64
+	query := "INSERT INTO guacamole_entity (name, type) VALUES ($1, $2) RETURNING entity_id"
+	if err := tx.QueryRowContext(ctx, query, form.Username, common.EntityTypeUser).Scan(&id); err != nil {
+		return 0, err
+	}
+
+	query = `
 		INSERT INTO guacamole_user (
 			entity_id, password_hash, password_salt, password_date, disabled, expired
-		) VALUES ($1, decode($2, 'hex'), decode($3, 'hex'), CURRENT_TIMESTAMP, false, false)
+		) VALUES ($1, decode($2, 'hex'), decode($3, 'hex'), CURRENT_TIMESTAMP, $4, false)
 	`
-
-	result, err := repo.db.ExecContext(
+	result, err := tx.ExecContext(
 		ctx,
 		query,
-		form.ID,
+		id,
 		form.PasswordHex,
 		form.SaultHex,
+		form.Disabled,
 	)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return err
+		return 0, err
 	}
 	if rowsAffected == 0 {
-		return errors.New("guacamole account was not created")
+		return 0, errors.New("guacamole account was not created")
 	}
 
 	query = `
-		INSERT INTO guacamole_system_permission (
-			entity_id, permission
-		) VALUES ($1, $2)
+		INSERT INTO guacamole_system_permission (entity_id, permission)
+		SELECT $1, unnest($2::text[])::guacamole_system_permission_type
+		ON CONFLICT DO NOTHING
 	`
+	if _, err := tx.ExecContext(ctx, query, id, pq.Array(form.Permissions)); err != nil {
+		return 0, err
+	}
 
-	result, err = repo.db.ExecContext(
-		ctx,
-		query,
-		form.ID,
-		form.Permissions[0],
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// guacamoleAccountQuery выбирает пользователей Guacamole вместе с состоянием их учетных записей
+const guacamoleAccountQuery = `
+	SELECT e.entity_id, e.name, u.user_id IS NOT NULL, COALESCE(u.disabled, false), COALESCE(u.expired, false),
+		EXISTS (
+			SELECT 1 FROM guacamole_system_permission p
+			WHERE p.entity_id = e.entity_id AND p.permission = 'ADMINISTER'
+		)
+	FROM guacamole_entity e
+	LEFT JOIN guacamole_user u ON u.entity_id = e.entity_id
+	WHERE e.type = 'USER'`
+
+// FindUserAccounts возвращает всех пользователей Guacamole, включая сущности без учетной записи
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//
+// Возвращает:
+//   - []*common.GuacamoleAccount: пользователи Guacamole, отсортированные по имени
+//   - error: ошибка выполнения запроса
+func (repo *guacamoleRepo) FindUserAccounts(ctx context.Context) ([]*common.GuacamoleAccount, error) {
+	query := guacamoleAccountQuery + " ORDER BY e.name"
+	rows, err := repo.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := make([]*common.GuacamoleAccount, 0)
+	for rows.Next() {
+		var account common.GuacamoleAccount
+		if err := rows.Scan(
+			&account.EntityID,
+			&account.Username,
+			&account.HasUser,
+			&account.Disabled,
+			&account.Expired,
+			&account.Administrator,
+		); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, &account)
+	}
+	return accounts, rows.Err()
+}
+
+// FindUserAccount возвращает пользователя Guacamole по имени
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - username: имя пользователя Guacamole (email)
+//
+// Возвращает:
+//   - *common.GuacamoleAccount: найденный пользователь или nil
+//   - error: ошибка выполнения запроса
+func (repo *guacamoleRepo) FindUserAccount(ctx context.Context, username string) (*common.GuacamoleAccount, error) {
+	query := guacamoleAccountQuery + " AND e.name = $1"
+	var account common.GuacamoleAccount
+	err := repo.db.QueryRowContext(ctx, query, username).Scan(
+		&account.EntityID,
+		&account.Username,
+		&account.HasUser,
+		&account.Disabled,
+		&account.Expired,
+		&account.Administrator,
 	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// SetUserDisabled отключает или включает учетную запись пользователя Guacamole
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - username: имя пользователя Guacamole (email)
+//   - disabled: отключить (true) или включить (false) учетную запись
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса
+func (repo *guacamoleRepo) SetUserDisabled(ctx context.Context, username string, disabled bool) error {
+	query := `
+		UPDATE guacamole_user u SET disabled = $2
+		FROM guacamole_entity e
+		WHERE u.entity_id = e.entity_id AND e.name = $1 AND e.type = 'USER'
+	`
+	_, err := repo.db.ExecContext(ctx, query, username, disabled)
+	return err
+}
+
+// SetUserExpired помечает пароль пользователя Guacamole истекшим или снимает эту пометку.
+// Пользователь с истекшим паролем не получает токен Guacamole, пока не сменит пароль.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - username: имя пользователя Guacamole (email)
+//   - expired: пометить пароль истекшим (true) или снять пометку (false)
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса или sql.ErrNoRows, если учетная запись не найдена
+func (repo *guacamoleRepo) SetUserExpired(ctx context.Context, username string, expired bool) error {
+	query := `
+		UPDATE guacamole_user u SET expired = $2
+		FROM guacamole_entity e
+		WHERE u.entity_id = e.entity_id AND e.name = $1 AND e.type = 'USER'
+	`
+	result, err := repo.db.ExecContext(ctx, query, username, expired)
 	if err != nil {
 		return err
 	}
-	rowsAffected, err = result.RowsAffected()
+	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
 	if rowsAffected == 0 {
-		return errors.New("permissions for guacamole account was not created")
+		return sql.ErrNoRows
 	}
 	return nil
 }
 
+// SetUserPassword меняет пароль пользователя Guacamole
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - username: имя пользователя Guacamole (email)
+//   - password: хэш нового пароля и соль
+//
+// Возвращает:
+//   - *common.GuacamolePassword: прежний хэш пароля и соль (для отката изменения)
+//   - error: ошибка выполнения запроса или sql.ErrNoRows, если учетная запись не найдена
+//
+// Особенности:
+//   - Обновляет дату смены пароля и снимает признак истекшего пароля
+func (repo *guacamoleRepo) SetUserPassword(
+	ctx context.Context,
+	username string,
+	password common.GuacamolePassword,
+) (*common.GuacamolePassword, error) {
+	query := `
+		UPDATE guacamole_user u
+		SET password_hash = decode($2, 'hex'),
+			password_salt = decode(NULLIF($3, ''), 'hex'),
+			password_date = CURRENT_TIMESTAMP,
+			expired = false
+		FROM guacamole_entity e, guacamole_user old
+		WHERE u.entity_id = e.entity_id AND e.name = $1 AND e.type = 'USER'
+			AND old.user_id = u.user_id
+		RETURNING upper(encode(old.password_hash, 'hex')), COALESCE(upper(encode(old.password_salt, 'hex')), '')
+	`
+	var previous common.GuacamolePassword
+	err := repo.db.QueryRowContext(ctx, query, username, password.HashHex, password.SaltHex).
+		Scan(&previous.HashHex, &previous.SaltHex)
+	if err != nil {
+		return nil, err
+	}
+	return &previous, nil
+}
+
+// AddPermissionToUser выдает сущности системные разрешения Guacamole.
+// Уже выданные разрешения пропускаются.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор сущности (guacamole_entity.entity_id)
+//   - permissions: системные разрешения (CREATE_CONNECTION, ADMINISTER и т.д.)
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса
 func (repo *guacamoleRepo) AddPermissionToUser(ctx context.Context, id int, permissions []string) error {
+	query := `
+		INSERT INTO guacamole_system_permission (entity_id, permission)
+		SELECT $1, unnest($2::text[])::guacamole_system_permission_type
+		ON CONFLICT DO NOTHING
+	`
+	_, err := repo.db.ExecContext(ctx, query, id, pq.Array(permissions))
+	return err
+}
+
+// FindSystemPermissions возвращает системные разрешения пользователя Guacamole
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - username: имя пользователя (email)
+//
+// Возвращает:
+//   - []string: разрешения (CREATE_CONNECTION, ADMINISTER и т.д.) без повторов
+//   - error: ошибка выполнения запроса
+//
+// Особенности:
+//   - Учитываются разрешения всех групп пользователя, включая вложенные,
+//     как это делает Guacamole при вычислении действующих разрешений
+//   - Разрешения отключенных групп не учитываются
+func (repo *guacamoleRepo) FindSystemPermissions(ctx context.Context, username string) ([]string, error) {
+	query := `
+		WITH RECURSIVE entities (entity_id) AS (
+			SELECT entity_id FROM guacamole_entity WHERE name = $1 AND type = 'USER'
+			UNION
+			SELECT g.entity_id
+			FROM entities e
+			JOIN guacamole_user_group_member gm ON gm.member_entity_id = e.entity_id
+			JOIN guacamole_user_group g ON g.user_group_id = gm.user_group_id
+			WHERE NOT g.disabled
+		)
+		SELECT DISTINCT sp.permission::text
+		FROM guacamole_system_permission sp
+		JOIN entities e ON e.entity_id = sp.entity_id
+		ORDER BY 1
+	`
+	rows, err := repo.db.QueryContext(ctx, query, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := make([]string, 0)
+	for rows.Next() {
+		var permission string
+		if err := rows.Scan(&permission); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, permission)
+	}
+	return permissions, rows.Err()
+}
+
+// FindUserConnectionPermissions возвращает разрешения пользователя Guacamole на подключения
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - username: имя пользователя (email)
+//
+// Возвращает:
+//   - []*common.UserConnectionPermission: разрешения, сгруппированные по подключению и группе,
+//     через которую они выданы, отсортированные по названию подключения
+//   - error: ошибка выполнения запроса
+//
+// Особенности:
+//   - Как и в FindSystemPermissions, учитываются вложенные группы, а отключенные группы пропускаются
+func (repo *guacamoleRepo) FindUserConnectionPermissions(
+	ctx context.Context,
+	username string,
+) ([]*common.UserConnectionPermission, error) {
+	query := `
+		WITH RECURSIVE entities (entity_id, group_name) AS (
+			SELECT entity_id, ''::text FROM guacamole_entity WHERE name = $1 AND type = 'USER'
+			UNION
+			SELECT g.entity_id, ge.name::text
+			FROM entities e
+			JOIN guacamole_user_group_member gm ON gm.member_entity_id = e.entity_id
+			JOIN guacamole_user_group g ON g.user_group_id = gm.user_group_id
+			JOIN guacamole_entity ge ON ge.entity_id = g.entity_id
+			WHERE NOT g.disabled
+		)
+		SELECT c.connection_id, c.connection_name, e.group_name,
+			array_agg(DISTINCT cp.permission::text ORDER BY cp.permission::text)
+		FROM guacamole_connection_permission cp
+		JOIN entities e ON e.entity_id = cp.entity_id
+		JOIN guacamole_connection c ON c.connection_id = cp.connection_id
+		GROUP BY c.connection_id, c.connection_name, e.group_name
+		ORDER BY c.connection_name, c.connection_id, e.group_name
+	`
+	rows, err := repo.db.QueryContext(ctx, query, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := make([]*common.UserConnectionPermission, 0)
+	for rows.Next() {
+		var permission common.UserConnectionPermission
+		if err := rows.Scan(
+			&permission.ConnectionID,
+			&permission.ConnectionName,
+			&permission.Group,
+			pq.Array(&permission.Permissions),
+		); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, &permission)
+	}
+	return permissions, rows.Err()
+}
+
+// SetSystemPermissions заменяет системные разрешения пользователя Guacamole
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - username: имя пользователя (email)
+//   - permissions: новые разрешения (CREATE_CONNECTION, ADMINISTER и т.д.)
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса или sql.ErrNoRows, если пользователь не найден
+//
+// Особенности:
+//   - Удаление прежних и добавление новых разрешений выполняется в одной транзакции
+//   - Разрешения, полученные через группы, не изменяются
+func (repo *guacamoleRepo) SetSystemPermissions(ctx context.Context, username string, permissions []string) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var entityID uint64
+	query := "SELECT entity_id FROM guacamole_entity WHERE name = $1 AND type = 'USER'"
+	if err := tx.QueryRowContext(ctx, query, username).Scan(&entityID); err != nil {
+		return err
+	}
+	query = "DELETE FROM guacamole_system_permission WHERE entity_id = $1"
+	if _, err := tx.ExecContext(ctx, query, entityID); err != nil {
+		return err
+	}
+	query = `
+		INSERT INTO guacamole_system_permission (entity_id, permission)
+		SELECT $1, unnest($2::text[])::guacamole_system_permission_type
+		ON CONFLICT DO NOTHING
+	`
+	if _, err := tx.ExecContext(ctx, query, entityID, pq.Array(permissions)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// FindEntity ищет пользователя или группу Guacamole по имени.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - name: имя пользователя (email) или название группы
+//   - entityType: тип сущности (USER или USER_GROUP)
+//
+// Возвращает:
+//   - *common.GuacamoleEntity: найденная сущность или nil
+//   - error: ошибка выполнения запроса
+func (repo *guacamoleRepo) FindEntity(ctx context.Context, name string, entityType string) (*common.GuacamoleEntity, error) {
+	query := "SELECT entity_id, name, type FROM guacamole_entity WHERE name = $1 AND type = $2::guacamole_entity_type"
+	var entity common.GuacamoleEntity
+	err := repo.db.QueryRowContext(ctx, query, name, entityType).Scan(&entity.ID, &entity.Name, &entity.Type)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entity, nil
+}
+
+// FindEntityByID ищет пользователя или группу Guacamole по идентификатору.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор сущности
+//
+// Возвращает:
+//   - *common.GuacamoleEntity: найденная сущность или nil
+//   - error: ошибка выполнения запроса
+func (repo *guacamoleRepo) FindEntityByID(ctx context.Context, id uint64) (*common.GuacamoleEntity, error) {
+	query := "SELECT entity_id, name, type FROM guacamole_entity WHERE entity_id = $1"
+	var entity common.GuacamoleEntity
+	err := repo.db.QueryRowContext(ctx, query, id).Scan(&entity.ID, &entity.Name, &entity.Type)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entity, nil
+}
+
+// FindConnectionPermissions возвращает разрешения на подключение, сгруппированные по сущностям.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - connectionID: идентификатор подключения
+//
+// Возвращает:
+//   - []*common.ConnectionGrant: разрешения пользователей и групп, отсортированные по типу и имени
+//   - error: ошибка выполнения запроса
+func (repo *guacamoleRepo) FindConnectionPermissions(
+	ctx context.Context,
+	connectionID uint64,
+) ([]*common.ConnectionGrant, error) {
+	query := `
+		SELECT e.entity_id, e.name, e.type, array_agg(p.permission::text ORDER BY p.permission)
+		FROM guacamole_connection_permission p
+		JOIN guacamole_entity e ON e.entity_id = p.entity_id
+		WHERE p.connection_id = $1
+		GROUP BY e.entity_id, e.name, e.type
+		ORDER BY e.type, e.name
+	`
+	rows, err := repo.db.QueryContext(ctx, query, connectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	grants := make([]*common.ConnectionGrant, 0)
+	for rows.Next() {
+		var grant common.ConnectionGrant
+		if err := rows.Scan(
+			&grant.ID,
+			&grant.Name,
+			&grant.Type,
+			pq.Array(&grant.Permissions),
+		); err != nil {
+			return nil, err
+		}
+		grants = append(grants, &grant)
+	}
+	return grants, rows.Err()
+}
+
+// GrantConnectionPermissions выдает сущности разрешения на подключение.
+// Уже выданные разрешения пропускаются.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - entityID: идентификатор пользователя или группы
+//   - connectionID: идентификатор подключения
+//   - permissions: разрешения (READ, UPDATE, DELETE, ADMINISTER)
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса
+func (repo *guacamoleRepo) GrantConnectionPermissions(
+	ctx context.Context,
+	entityID uint64,
+	connectionID uint64,
+	permissions []string,
+) error {
+	query := `
+		INSERT INTO guacamole_connection_permission (entity_id, connection_id, permission)
+		SELECT $1, $2, unnest($3::text[])::guacamole_object_permission_type
+		ON CONFLICT DO NOTHING
+	`
+	_, err := repo.db.ExecContext(ctx, query, entityID, connectionID, pq.Array(permissions))
+	return err
+}
+
+// RevokeConnectionPermissions отзывает разрешения сущности на подключение.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - entityID: идентификатор пользователя или группы
+//   - connectionID: идентификатор подключения
+//   - permissions: отзываемые разрешения (пустой список — все разрешения)
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса
+func (repo *guacamoleRepo) RevokeConnectionPermissions(
+	ctx context.Context,
+	entityID uint64,
+	connectionID uint64,
+	permissions []string,
+) error {
+	query := `
+		DELETE FROM guacamole_connection_permission
+		WHERE entity_id = $1
+			AND connection_id = $2
+			AND (cardinality($3::text[]) = 0 OR permission::text = ANY($3::text[]))
+	`
+	if permissions == nil {
+		permissions = []string{} // nil передается как NULL, а не пустой массив
+	}
+	_, err := repo.db.ExecContext(ctx, query, entityID, connectionID, pq.Array(permissions))
+	return err
+}
+
+// CreateUserGroup создает группу пользователей Guacamole.
+// Сущность и группа создаются в одной транзакции.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - name: название группы
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса (в том числе если группа с таким названием уже есть)
+func (repo *guacamoleRepo) CreateUserGroup(ctx context.Context, name string) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var entityID uint64
+	query := "INSERT INTO guacamole_entity (name, type) VALUES ($1, $2) RETURNING entity_id"
+	if err := tx.QueryRowContext(ctx, query, name, common.EntityTypeUserGroup).Scan(&entityID); err != nil {
+		return err
+	}
+	query = "INSERT INTO guacamole_user_group (entity_id, disabled) VALUES ($1, false)"
+	if _, err := tx.ExecContext(ctx, query, entityID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RenameEntity переименовывает пользователя или группу Guacamole
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - name: текущее имя сущности
+//   - newName: новое имя сущности
+//   - entityType: тип сущности (USER или USER_GROUP)
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса
+func (repo *guacamoleRepo) RenameEntity(ctx context.Context, name string, newName string, entityType string) error {
+	query := "UPDATE guacamole_entity SET name = $2 WHERE name = $1 AND type = $3::guacamole_entity_type"
+	_, err := repo.db.ExecContext(ctx, query, name, newName, entityType)
+	return err
+}
+
+// DeleteEntity удаляет пользователя или группу Guacamole.
+// Связанные записи (учетная запись, разрешения, членство в группах) удаляются каскадно.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - name: имя сущности
+//   - entityType: тип сущности (USER или USER_GROUP)
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса
+func (repo *guacamoleRepo) DeleteEntity(ctx context.Context, name string, entityType string) error {
+	query := "DELETE FROM guacamole_entity WHERE name = $1 AND type = $2::guacamole_entity_type"
+	_, err := repo.db.ExecContext(ctx, query, name, entityType)
+	return err
+}
+
+// AddUserGroupMember добавляет пользователя или группу в группу Guacamole.
+// Участники вложенной группы наследуют разрешения родительской группы.
+// Повторное добавление ничего не меняет.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - groupName: название группы
+//   - memberName: имя пользователя (email) или название вкладываемой группы
+//   - memberType: тип участника (USER или USER_GROUP)
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса или отсутствие группы либо участника в Guacamole
+func (repo *guacamoleRepo) AddUserGroupMember(
+	ctx context.Context,
+	groupName string,
+	memberName string,
+	memberType string,
+) error {
+	query := `
+		INSERT INTO guacamole_user_group_member (user_group_id, member_entity_id)
+		SELECT g.user_group_id, m.entity_id
+		FROM guacamole_user_group g
+		JOIN guacamole_entity ge ON ge.entity_id = g.entity_id
+		JOIN guacamole_entity m ON m.name = $2 AND m.type = $3::guacamole_entity_type
+		WHERE ge.name = $1 AND ge.type = 'USER_GROUP'
+		ON CONFLICT DO NOTHING
+		RETURNING 1
+	`
+	var inserted int
+	err := repo.db.QueryRowContext(ctx, query, groupName, memberName, memberType).Scan(&inserted)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	// Ничего не вставлено: участник уже в группе либо группа или участник отсутствуют в Guacamole
+	query = `
+		SELECT EXISTS (
+			SELECT 1
+			FROM guacamole_user_group_member gm
+			JOIN guacamole_user_group g ON g.user_group_id = gm.user_group_id
+			JOIN guacamole_entity ge ON ge.entity_id = g.entity_id
+			JOIN guacamole_entity m ON m.entity_id = gm.member_entity_id
+			WHERE ge.name = $1 AND ge.type = 'USER_GROUP' AND m.name = $2 AND m.type = $3::guacamole_entity_type
+		)
+	`
+	var exists bool
+	if err := repo.db.QueryRowContext(ctx, query, groupName, memberName, memberType).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New("guacamole user group or member does not exist")
+	}
 	return nil
 }
+
+// RemoveUserGroupMember удаляет пользователя или группу из группы Guacamole
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - groupName: название группы
+//   - memberName: имя пользователя (email) или название вложенной группы
+//   - memberType: тип участника (USER или USER_GROUP)
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса
+func (repo *guacamoleRepo) RemoveUserGroupMember(
+	ctx context.Context,
+	groupName string,
+	memberName string,
+	memberType string,
+) error {
+	query := `
+		DELETE FROM guacamole_user_group_member gm
+		USING guacamole_user_group g, guacamole_entity ge, guacamole_entity m
+		WHERE gm.user_group_id = g.user_group_id
+			AND g.entity_id = ge.entity_id
+			AND gm.member_entity_id = m.entity_id
+			AND ge.name = $1 AND ge.type = 'USER_GROUP'
+			AND m.name = $2 AND m.type = $3::guacamole_entity_type
+	`
+	_, err := repo.db.ExecContext(ctx, query, groupName, memberName, memberType)
+	return err
+}
@@ -0,0 +1,261 @@
+// Package repository предоставляет реализации репозиториев для работы с данными приложения.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+)
+
+// historyDurationSQL выражение длительности записи истории в секундах.
+// Для активных подключений длительность считается до текущего момента.
+const historyDurationSQL = "EXTRACT(EPOCH FROM (COALESCE(h.end_date, now()) - h.start_date))"
+
+// historyRepo реализует HistoryRepository для работы с PostgreSQL Guacamole
+type historyRepo struct {
+	db *sql.DB
+}
+
+// HistoryRepository определяет контракт для чтения истории подключений Guacamole
+type HistoryRepository interface {
+	// FindRecords возвращает страницу записей истории и общее количество записей
+	FindRecords(ctx context.Context, filter *common.HistoryFilter) ([]*common.ConnectionHistoryRecord, int, error)
+
+	// WeeklyUsage возвращает суммарное время подключений по пользователям и неделям
+	WeeklyUsage(ctx context.Context, filter *common.HistoryFilter) ([]*common.UserWeeklyUsage, error)
+
+	// TopHosts возвращает наиболее используемые хосты
+	TopHosts(ctx context.Context, filter *common.HistoryFilter) ([]*common.HostUsage, error)
+
+	// FindUsernameAt возвращает пользователя, чье подключение началось ближе всего к указанному времени
+	FindUsernameAt(ctx context.Context, connectionID string, at time.Time, window time.Duration) (string, error)
+}
+
+// NewHistoryRepository создает новый экземпляр HistoryRepository
+func NewHistoryRepository(db *sql.DB) HistoryRepository {
+	return &historyRepo{
+		db: db,
+	}
+}
+
+// FindRecords возвращает записи истории подключений, отсортированные от новых к старым
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - filter: параметры выборки и пагинации
+//
+// Возвращает:
+//   - []*common.ConnectionHistoryRecord: записи запрошенной страницы
+//   - int: общее количество записей, удовлетворяющих фильтру
+//   - error: ошибка выполнения запроса
+func (repo *historyRepo) FindRecords(
+	ctx context.Context,
+	filter *common.HistoryFilter,
+) ([]*common.ConnectionHistoryRecord, int, error) {
+	where, args := historyConditions(filter)
+
+	var total int
+	query := "SELECT COUNT(*) FROM guacamole_connection_history h" + where
+	if err := repo.db.QueryRowContext(ctx, query, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query = fmt.Sprintf(`
+		SELECT
+			h.history_id, COALESCE(h.connection_id::text, ''), h.connection_name, h.username,
+			COALESCE(h.remote_host, ''), h.start_date, h.end_date, %s
+		FROM guacamole_connection_history h%s
+		ORDER BY h.start_date DESC, h.history_id DESC
+		LIMIT $%d OFFSET $%d
+	`, historyDurationSQL, where, len(args)+1, len(args)+2)
+	args = append(args, filter.PerPage, (filter.Page-1)*filter.PerPage)
+
+	rows, err := repo.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	records := make([]*common.ConnectionHistoryRecord, 0, filter.PerPage)
+	for rows.Next() {
+		var record common.ConnectionHistoryRecord
+		var endDate sql.NullTime
+		var duration float64
+		if err := rows.Scan(
+			&record.ID,
+			&record.ConnectionID,
+			&record.ConnectionName,
+			&record.Username,
+			&record.RemoteHost,
+			&record.StartDate,
+			&endDate,
+			&duration,
+		); err != nil {
+			return nil, 0, err
+		}
+		if endDate.Valid {
+			record.EndDate = &endDate.Time
+		}
+		record.Active = !endDate.Valid
+		record.DurationSeconds = int64(duration)
+		records = append(records, &record)
+	}
+	return records, total, rows.Err()
+}
+
+// WeeklyUsage возвращает количество подключений и суммарное время в часах
+// для каждого пользователя за каждую неделю периода
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - filter: параметры выборки
+//
+// Возвращает:
+//   - []*common.UserWeeklyUsage: строки отчета, отсортированные по неделе и пользователю
+//   - error: ошибка выполнения запроса
+func (repo *historyRepo) WeeklyUsage(
+	ctx context.Context,
+	filter *common.HistoryFilter,
+) ([]*common.UserWeeklyUsage, error) {
+	where, args := historyConditions(filter)
+	query := fmt.Sprintf(`
+		SELECT h.username, date_trunc('week', h.start_date) AS week, COUNT(*), SUM(%s) / 3600
+		FROM guacamole_connection_history h%s
+		GROUP BY h.username, week
+		ORDER BY week, h.username
+	`, historyDurationSQL, where)
+
+	rows, err := repo.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := make([]*common.UserWeeklyUsage, 0)
+	for rows.Next() {
+		var usage common.UserWeeklyUsage
+		if err := rows.Scan(&usage.Username, &usage.WeekStart, &usage.Sessions, &usage.Hours); err != nil {
+			return nil, err
+		}
+		report = append(report, &usage)
+	}
+	return report, rows.Err()
+}
+
+// TopHosts возвращает хосты с наибольшим количеством подключений.
+// Хост определяется параметром hostname подключения; для удаленных подключений
+// используется название подключения из истории
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - filter: параметры выборки (Limit ограничивает количество строк)
+//
+// Возвращает:
+//   - []*common.HostUsage: строки отчета, отсортированные по количеству подключений
+//   - error: ошибка выполнения запроса
+func (repo *historyRepo) TopHosts(
+	ctx context.Context,
+	filter *common.HistoryFilter,
+) ([]*common.HostUsage, error) {
+	where, args := historyConditions(filter)
+	query := fmt.Sprintf(`
+		SELECT
+			COALESCE(p.parameter_value, h.connection_name) AS host,
+			COUNT(*) AS sessions, COUNT(DISTINCT h.username), SUM(%s) / 3600
+		FROM guacamole_connection_history h
+		LEFT JOIN guacamole_connection_parameter p
+			ON p.connection_id = h.connection_id AND p.parameter_name = 'hostname'%s
+		GROUP BY host
+		ORDER BY sessions DESC, host
+		LIMIT $%d
+	`, historyDurationSQL, where, len(args)+1)
+	args = append(args, filter.Limit)
+
+	rows, err := repo.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := make([]*common.HostUsage, 0, filter.Limit)
+	for rows.Next() {
+		var usage common.HostUsage
+		if err := rows.Scan(&usage.HostName, &usage.Sessions, &usage.Users, &usage.Hours); err != nil {
+			return nil, err
+		}
+		report = append(report, &usage)
+	}
+	return report, rows.Err()
+}
+
+// FindUsernameAt ищет пользователя, подключение которого началось ближе всего к моменту at
+// в пределах window. Используется для сопоставления файлов записей с сессиями.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - connectionID: идентификатор подключения
+//   - at: предполагаемое время начала сессии
+//   - window: допустимое отклонение времени начала
+//
+// Возвращает:
+//   - string: имя пользователя (пустая строка, если сессия не найдена)
+//   - error: ошибка выполнения запроса
+func (repo *historyRepo) FindUsernameAt(
+	ctx context.Context,
+	connectionID string,
+	at time.Time,
+	window time.Duration,
+) (string, error) {
+	query := `
+		SELECT h.username
+		FROM guacamole_connection_history h
+		WHERE h.connection_id = $1::integer AND h.start_date BETWEEN $2 AND $3
+		ORDER BY ABS(EXTRACT(EPOCH FROM (h.start_date - $4::timestamptz)))
+		LIMIT 1
+	`
+	var username string
+	err := repo.db.QueryRowContext(ctx, query, connectionID, at.Add(-window), at.Add(window), at).Scan(&username)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return username, err
+}
+
+// historyConditions формирует условие WHERE и его аргументы по фильтру истории
+//
+// Параметры:
+//   - filter: параметры выборки
+//
+// Возвращает:
+//   - string: условие WHERE с ведущим пробелом (пустая строка без условий)
+//   - []interface{}: аргументы условия
+func historyConditions(filter *common.HistoryFilter) (string, []interface{}) {
+	conditions := make([]string, 0, 4)
+	args := make([]interface{}, 0, 4)
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.ConnectionID != "" {
+		add("h.connection_id = $%d::integer", filter.ConnectionID)
+	}
+	if filter.Username != "" {
+		add("h.username = $%d", filter.Username)
+	}
+	if filter.From != nil {
+		add("h.start_date >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		add("h.start_date < $%d", *filter.To)
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
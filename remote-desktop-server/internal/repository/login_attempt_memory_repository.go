@@ -0,0 +1,158 @@
+// Package repository предоставляет реализации репозиториев для работы с данными приложения.
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+)
+
+// memoryLoginAttemptRepo реализует LoginAttemptRepository в памяти процесса.
+// Используется в тестах и при запуске сервера в одном экземпляре (LOGIN_ATTEMPTS_STORE=memory):
+// счетчики и журнал не переживают перезапуск и не разделяются между экземплярами.
+type memoryLoginAttemptRepo struct {
+	mu        sync.Mutex
+	throttles map[[2]string]common.LoginThrottle
+	audit     []common.LoginAuditEntry
+}
+
+// NewMemoryLoginAttemptRepository создает новый экземпляр LoginAttemptRepository в памяти процесса
+func NewMemoryLoginAttemptRepository() LoginAttemptRepository {
+	return &memoryLoginAttemptRepo{
+		throttles: make(map[[2]string]common.LoginThrottle),
+	}
+}
+
+// Find возвращает копию неудачных попыток входа или nil
+func (repo *memoryLoginAttemptRepo) Find(ctx context.Context, scope string, key string) (*common.LoginThrottle, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	throttle, ok := repo.throttles[[2]string{scope, key}]
+	if !ok {
+		return nil, nil
+	}
+	return &throttle, nil
+}
+
+// Reserve учитывает попытку входа как неудачную по тем же правилам, что и хранилище PostgreSQL
+func (repo *memoryLoginAttemptRepo) Reserve(
+	ctx context.Context,
+	scope string,
+	key string,
+	now time.Time,
+	policy common.LoginFailurePolicy,
+) (*common.LoginReservation, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	var current *common.LoginThrottle
+	if throttle, ok := repo.throttles[[2]string{scope, key}]; ok {
+		current = &throttle
+	}
+	reservation := reserveLoginThrottle(current, scope, key, now, policy)
+	if reservation.RetryAfter == 0 {
+		repo.throttles[[2]string{scope, key}] = *reservation.Throttle
+	}
+	return reservation, nil
+}
+
+// Release снимает учет попытки входа по тем же правилам, что и хранилище PostgreSQL
+func (repo *memoryLoginAttemptRepo) Release(ctx context.Context, reservation *common.LoginReservation) error {
+	if reservation.RetryAfter > 0 {
+		return nil
+	}
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	id := [2]string{reservation.Throttle.Scope, reservation.Throttle.Key}
+	var current *common.LoginThrottle
+	if throttle, ok := repo.throttles[id]; ok {
+		current = &throttle
+	}
+	released, changed := releaseLoginThrottle(current, reservation)
+	switch {
+	case !changed:
+	case released == nil:
+		delete(repo.throttles, id)
+	default:
+		repo.throttles[id] = *released
+	}
+	return nil
+}
+
+// Reset удаляет счетчик неудачных попыток и блокировку
+func (repo *memoryLoginAttemptRepo) Reset(ctx context.Context, scope string, key string) (bool, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	_, ok := repo.throttles[[2]string{scope, key}]
+	delete(repo.throttles, [2]string{scope, key})
+	return ok, nil
+}
+
+// FindLocked возвращает действующие блокировки, заканчивающиеся позже остальных — первыми
+func (repo *memoryLoginAttemptRepo) FindLocked(ctx context.Context, now time.Time) ([]*common.LoginThrottle, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	throttles := make([]*common.LoginThrottle, 0)
+	for _, throttle := range repo.throttles {
+		if throttle.LockedUntil != nil && throttle.LockedUntil.After(now) {
+			throttles = append(throttles, &throttle)
+		}
+	}
+	sort.Slice(throttles, func(i, j int) bool {
+		a, b := throttles[i], throttles[j]
+		if !a.LockedUntil.Equal(*b.LockedUntil) {
+			return a.LockedUntil.After(*b.LockedUntil)
+		}
+		if a.Scope != b.Scope {
+			return a.Scope < b.Scope
+		}
+		return a.Key < b.Key
+	})
+	return throttles, nil
+}
+
+// AddAuditEntry добавляет запись в журнал входа
+func (repo *memoryLoginAttemptRepo) AddAuditEntry(ctx context.Context, entry *common.LoginAuditEntry) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	entry.ID = int64(len(repo.audit) + 1)
+	repo.audit = append(repo.audit, *entry)
+	return nil
+}
+
+// FindAuditPage возвращает страницу журнала входа, новые записи первыми
+func (repo *memoryLoginAttemptRepo) FindAuditPage(
+	ctx context.Context,
+	filter *common.LoginAuditFilter,
+) ([]*common.LoginAuditEntry, int, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	matched := make([]*common.LoginAuditEntry, 0)
+	for i := len(repo.audit) - 1; i >= 0; i-- {
+		entry := repo.audit[i]
+		if filter.Email != "" && !strings.EqualFold(entry.Email, filter.Email) {
+			continue
+		}
+		if filter.RemoteAddr != "" && entry.RemoteAddr != filter.RemoteAddr {
+			continue
+		}
+		if filter.Event != "" && entry.Event != filter.Event {
+			continue
+		}
+		matched = append(matched, &entry)
+	}
+
+	start := min((filter.Page-1)*filter.PerPage, len(matched))
+	end := min(start+filter.PerPage, len(matched))
+	return matched[start:end], len(matched), nil
+}
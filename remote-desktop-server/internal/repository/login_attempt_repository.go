@@ -0,0 +1,451 @@
+// Package repository предоставляет реализации репозиториев для работы с данными приложения.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+)
+
+// loginAttemptRepo реализует LoginAttemptRepository для работы с PostgreSQL
+type loginAttemptRepo struct {
+	db *sql.DB
+}
+
+// LoginAttemptRepository определяет контракт для хранения неудачных попыток входа и журнала входа.
+// Время передается вызывающей стороной, чтобы расчет задержек не зависел от часов базы данных.
+type LoginAttemptRepository interface {
+	// Find возвращает неудачные попытки входа для учетной записи или адреса либо nil
+	Find(ctx context.Context, scope string, key string) (*common.LoginThrottle, error)
+
+	// Reserve атомарно проверяет задержку и блокировку и, если попытка разрешена, учитывает ее
+	// как неудачную до проверки учетных данных (при достижении порога вход блокируется)
+	Reserve(
+		ctx context.Context,
+		scope string,
+		key string,
+		now time.Time,
+		policy common.LoginFailurePolicy,
+	) (*common.LoginReservation, error)
+
+	// Release снимает учет попытки, оказавшейся удачной или не дошедшей до проверки учетных данных
+	Release(ctx context.Context, reservation *common.LoginReservation) error
+
+	// Reset удаляет счетчик неудачных попыток и блокировку, возвращает false, если их не было
+	Reset(ctx context.Context, scope string, key string) (bool, error)
+
+	// FindLocked возвращает действующие на момент now блокировки
+	FindLocked(ctx context.Context, now time.Time) ([]*common.LoginThrottle, error)
+
+	// AddAuditEntry добавляет запись в журнал входа
+	AddAuditEntry(ctx context.Context, entry *common.LoginAuditEntry) error
+
+	// FindAuditPage возвращает страницу журнала входа и общее количество записей
+	FindAuditPage(ctx context.Context, filter *common.LoginAuditFilter) ([]*common.LoginAuditEntry, int, error)
+}
+
+// NewLoginAttemptRepository создает новый экземпляр LoginAttemptRepository
+func NewLoginAttemptRepository(db *sql.DB) LoginAttemptRepository {
+	return &loginAttemptRepo{
+		db: db,
+	}
+}
+
+// Find возвращает неудачные попытки входа
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - scope: account или ip
+//   - key: email или адрес клиента
+//
+// Возвращает:
+//   - *common.LoginThrottle: неудачные попытки или nil, если их нет
+//   - error: ошибка выполнения запроса
+func (repo *loginAttemptRepo) Find(ctx context.Context, scope string, key string) (*common.LoginThrottle, error) {
+	query := `
+		SELECT scope, key, failures, last_failed_at, locked_until
+		FROM login_throttles WHERE scope = $1 AND key = $2
+	`
+	throttle, err := scanLoginThrottle(repo.db.QueryRowContext(ctx, query, scope, key))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return throttle, nil
+}
+
+// Reserve учитывает попытку входа как неудачную, если для учетной записи или адреса
+// не действует задержка или блокировка
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - scope: account или ip
+//   - key: email или адрес клиента
+//   - now: время попытки
+//   - policy: правила задержек, подсчета неудач и блокировки
+//
+// Возвращает:
+//   - *common.LoginReservation: учтенная попытка или время до следующей попытки, если эта отклонена
+//   - error: ошибка выполнения запроса
+//
+// Особенности:
+//   - Строка счетчика блокируется (SELECT ... FOR UPDATE) до конца транзакции, поэтому одновременные
+//     попытки проверяются и учитываются по очереди: пока проверяется пароль первой, остальные видят
+//     увеличенный счетчик и отклоняются, а блокировка устанавливается один раз
+func (repo *loginAttemptRepo) Reserve(
+	ctx context.Context,
+	scope string,
+	key string,
+	now time.Time,
+	policy common.LoginFailurePolicy,
+) (*common.LoginReservation, error) {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO login_throttles (scope, key, failures, last_failed_at) VALUES ($1, $2, 0, $3)
+		ON CONFLICT (scope, key) DO NOTHING
+	`
+	if _, err := tx.ExecContext(ctx, query, scope, key, now); err != nil {
+		return nil, err
+	}
+	query = `
+		SELECT scope, key, failures, last_failed_at, locked_until
+		FROM login_throttles WHERE scope = $1 AND key = $2
+		FOR UPDATE
+	`
+	current, err := scanLoginThrottle(tx.QueryRowContext(ctx, query, scope, key))
+	if err != nil {
+		return nil, err
+	}
+
+	reservation := reserveLoginThrottle(current, scope, key, now, policy)
+	if reservation.RetryAfter == 0 {
+		query = `
+			UPDATE login_throttles SET failures = $3, last_failed_at = $4, locked_until = $5
+			WHERE scope = $1 AND key = $2
+			RETURNING scope, key, failures, last_failed_at, locked_until
+		`
+		throttle := reservation.Throttle
+		reservation.Throttle, err = scanLoginThrottle(tx.QueryRowContext(
+			ctx,
+			query,
+			scope,
+			key,
+			throttle.Failures,
+			throttle.LastFailedAt,
+			throttle.LockedUntil,
+		))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return reservation, nil
+}
+
+// Release снимает учет попытки входа
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - reservation: попытка, учтенная Reserve
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса
+//
+// Особенности:
+//   - Если после этой попытки других не было, восстанавливаются счетчик и блокировка до нее,
+//     иначе из счетчика вычитается одна попытка
+func (repo *loginAttemptRepo) Release(ctx context.Context, reservation *common.LoginReservation) error {
+	if reservation.RetryAfter > 0 {
+		return nil
+	}
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	scope, key := reservation.Throttle.Scope, reservation.Throttle.Key
+	query := `
+		SELECT scope, key, failures, last_failed_at, locked_until
+		FROM login_throttles WHERE scope = $1 AND key = $2
+		FOR UPDATE
+	`
+	current, err := scanLoginThrottle(tx.QueryRowContext(ctx, query, scope, key))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	released, changed := releaseLoginThrottle(current, reservation)
+	switch {
+	case !changed:
+		return nil
+	case released == nil:
+		query = "DELETE FROM login_throttles WHERE scope = $1 AND key = $2"
+		_, err = tx.ExecContext(ctx, query, scope, key)
+	default:
+		query = `
+			UPDATE login_throttles SET failures = $3, last_failed_at = $4, locked_until = $5
+			WHERE scope = $1 AND key = $2
+		`
+		_, err = tx.ExecContext(ctx, query, scope, key, released.Failures, released.LastFailedAt, released.LockedUntil)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Reset удаляет счетчик неудачных попыток и блокировку
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - scope: account или ip
+//   - key: email или адрес клиента
+//
+// Возвращает:
+//   - bool: false, если неудачных попыток не было
+//   - error: ошибка выполнения запроса
+func (repo *loginAttemptRepo) Reset(ctx context.Context, scope string, key string) (bool, error) {
+	query := "DELETE FROM login_throttles WHERE scope = $1 AND key = $2"
+	result, err := repo.db.ExecContext(ctx, query, scope, key)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// FindLocked возвращает действующие блокировки входа
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - now: текущее время
+//
+// Возвращает:
+//   - []*common.LoginThrottle: блокировки, заканчивающиеся позже остальных — первыми
+//   - error: ошибка выполнения запроса
+func (repo *loginAttemptRepo) FindLocked(ctx context.Context, now time.Time) ([]*common.LoginThrottle, error) {
+	query := `
+		SELECT scope, key, failures, last_failed_at, locked_until
+		FROM login_throttles WHERE locked_until > $1
+		ORDER BY locked_until DESC, scope, key
+	`
+	rows, err := repo.db.QueryContext(ctx, query, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	throttles := make([]*common.LoginThrottle, 0)
+	for rows.Next() {
+		throttle, err := scanLoginThrottle(rows)
+		if err != nil {
+			return nil, err
+		}
+		throttles = append(throttles, throttle)
+	}
+	return throttles, rows.Err()
+}
+
+// AddAuditEntry добавляет запись в журнал входа
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - entry: запись журнала (ID заполняется после добавления)
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса
+func (repo *loginAttemptRepo) AddAuditEntry(ctx context.Context, entry *common.LoginAuditEntry) error {
+	query := `
+		INSERT INTO login_audit (event, email, remote_addr, actor, detail, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+	return repo.db.QueryRowContext(
+		ctx,
+		query,
+		entry.Event,
+		entry.Email,
+		entry.RemoteAddr,
+		entry.Actor,
+		entry.Detail,
+		entry.CreatedAt,
+	).Scan(&entry.ID)
+}
+
+// FindAuditPage возвращает страницу журнала входа
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - filter: фильтры и пагинация
+//
+// Возвращает:
+//   - []*common.LoginAuditEntry: записи запрошенной страницы, новые первыми
+//   - int: общее количество записей, удовлетворяющих фильтру
+//   - error: ошибка выполнения запроса
+func (repo *loginAttemptRepo) FindAuditPage(
+	ctx context.Context,
+	filter *common.LoginAuditFilter,
+) ([]*common.LoginAuditEntry, int, error) {
+	conditions := []string{"TRUE"}
+	args := make([]interface{}, 0)
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.Email != "" {
+		add("lower(email) = lower($%d)", filter.Email)
+	}
+	if filter.RemoteAddr != "" {
+		add("remote_addr = $%d", filter.RemoteAddr)
+	}
+	if filter.Event != "" {
+		add("event = $%d", filter.Event)
+	}
+	where := " FROM login_audit WHERE " + strings.Join(conditions, " AND ")
+
+	var total int
+	if err := repo.db.QueryRowContext(ctx, "SELECT COUNT(*)"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, event, email, remote_addr, actor, detail, created_at
+		%s
+		ORDER BY id DESC
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)+1, len(args)+2)
+	args = append(args, filter.PerPage, (filter.Page-1)*filter.PerPage)
+
+	rows, err := repo.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := make([]*common.LoginAuditEntry, 0, filter.PerPage)
+	for rows.Next() {
+		var entry common.LoginAuditEntry
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.Event,
+			&entry.Email,
+			&entry.RemoteAddr,
+			&entry.Actor,
+			&entry.Detail,
+			&entry.CreatedAt,
+		); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, &entry)
+	}
+	return entries, total, rows.Err()
+}
+
+// scanLoginThrottle читает неудачные попытки входа из строки результата
+func scanLoginThrottle(row interface{ Scan(dest ...any) error }) (*common.LoginThrottle, error) {
+	var throttle common.LoginThrottle
+	err := row.Scan(
+		&throttle.Scope,
+		&throttle.Key,
+		&throttle.Failures,
+		&throttle.LastFailedAt,
+		&throttle.LockedUntil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &throttle, nil
+}
+
+// nextLoginThrottle возвращает неудачные попытки входа после еще одной неудачи.
+// Счетчик начинается заново, если последняя неудача была раньше policy.Window
+// или закончилась блокировка; при достижении policy.MaxFailures вход блокируется на policy.Lockout.
+func nextLoginThrottle(
+	current *common.LoginThrottle,
+	scope string,
+	key string,
+	now time.Time,
+	policy common.LoginFailurePolicy,
+) *common.LoginThrottle {
+	throttle := &common.LoginThrottle{Scope: scope, Key: key, Failures: 1, LastFailedAt: now}
+	expired := current == nil ||
+		current.LastFailedAt.Before(now.Add(-policy.Window)) ||
+		(current.LockedUntil != nil && !current.LockedUntil.After(now))
+	if !expired {
+		throttle.Failures = current.Failures + 1
+		throttle.LockedUntil = current.LockedUntil
+	}
+	if throttle.LockedUntil == nil && policy.MaxFailures > 0 && throttle.Failures >= policy.MaxFailures {
+		lockedUntil := now.Add(policy.Lockout)
+		throttle.LockedUntil = &lockedUntil
+	}
+	return throttle
+}
+
+// reserveLoginThrottle проверяет задержку и блокировку после неудач current и, если попытка разрешена,
+// возвращает неудачные попытки с ее учетом. Строка без неудач (failures = 0) равнозначна отсутствию неудач.
+func reserveLoginThrottle(
+	current *common.LoginThrottle,
+	scope string,
+	key string,
+	now time.Time,
+	policy common.LoginFailurePolicy,
+) *common.LoginReservation {
+	if current != nil && current.Failures <= 0 {
+		current = nil
+	}
+	if wait, locked := policy.Wait(current, now); wait > 0 {
+		return &common.LoginReservation{Throttle: current, Previous: current, RetryAfter: wait, Locked: locked}
+	}
+	return &common.LoginReservation{
+		Throttle: nextLoginThrottle(current, scope, key, now, policy),
+		Previous: current,
+	}
+}
+
+// releaseLoginThrottle возвращает неудачные попытки current без попытки reservation
+// (nil — неудач не осталось) и false, если менять ничего не нужно.
+// Если после попытки других не было, восстанавливается состояние до нее; если счетчик с тех пор
+// начался заново, попытка в нем уже не учтена; иначе вычитается одна попытка.
+func releaseLoginThrottle(
+	current *common.LoginThrottle,
+	reservation *common.LoginReservation,
+) (*common.LoginThrottle, bool) {
+	reserved := reservation.Throttle
+	switch {
+	case current == nil || reservation.RetryAfter > 0:
+		return current, false
+	case current.Failures == reserved.Failures && current.LastFailedAt.Equal(reserved.LastFailedAt):
+		if reservation.Previous == nil {
+			return nil, true
+		}
+		previous := *reservation.Previous
+		return &previous, true
+	case current.Failures < reserved.Failures:
+		return current, false
+	}
+	released := *current
+	released.Failures--
+	return &released, true
+}
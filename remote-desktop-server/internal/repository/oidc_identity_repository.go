@@ -0,0 +1,122 @@
+// Package repository предоставляет реализации репозиториев для работы с данными приложения.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+)
+
+// oidcIdentityRepo реализует OIDCIdentityRepository для работы с PostgreSQL
+type oidcIdentityRepo struct {
+	db *sql.DB
+}
+
+// OIDCIdentityRepository определяет контракт для хранения связей учетных записей
+// провайдера OpenID Connect с пользователями
+type OIDCIdentityRepository interface {
+	// Find возвращает связь учетной записи провайдера или nil, если она не найдена
+	Find(ctx context.Context, issuer string, subject string) (*common.OIDCIdentity, error)
+
+	// FindByUser возвращает связь пользователя с учетной записью провайдера или nil
+	FindByUser(ctx context.Context, issuer string, userID uuid.UUID) (*common.OIDCIdentity, error)
+
+	// Create связывает учетную запись провайдера с пользователем и возвращает false,
+	// если учетная запись провайдера или пользователь уже связаны
+	Create(ctx context.Context, issuer string, subject string, userID uuid.UUID) (bool, error)
+}
+
+// NewOIDCIdentityRepository создает новый экземпляр OIDCIdentityRepository
+func NewOIDCIdentityRepository(db *sql.DB) OIDCIdentityRepository {
+	return &oidcIdentityRepo{
+		db: db,
+	}
+}
+
+// Find ищет связь учетной записи провайдера с пользователем
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - issuer: провайдер (утверждение iss)
+//   - subject: идентификатор пользователя у провайдера (утверждение sub)
+//
+// Возвращает:
+//   - *common.OIDCIdentity: связь или nil, если учетная запись провайдера не связана
+//   - error: ошибка выполнения запроса
+func (repo *oidcIdentityRepo) Find(ctx context.Context, issuer string, subject string) (*common.OIDCIdentity, error) {
+	query := `
+		SELECT issuer, subject, user_id, created_at FROM oidc_identities
+		WHERE issuer = $1 AND subject = $2
+	`
+	return scanOIDCIdentity(repo.db.QueryRowContext(ctx, query, issuer, subject))
+}
+
+// FindByUser ищет связь пользователя с учетной записью провайдера
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - issuer: провайдер (утверждение iss)
+//   - userID: идентификатор пользователя
+//
+// Возвращает:
+//   - *common.OIDCIdentity: связь или nil, если пользователь не связан с учетной записью провайдера
+//   - error: ошибка выполнения запроса
+func (repo *oidcIdentityRepo) FindByUser(
+	ctx context.Context,
+	issuer string,
+	userID uuid.UUID,
+) (*common.OIDCIdentity, error) {
+	query := `
+		SELECT issuer, subject, user_id, created_at FROM oidc_identities
+		WHERE issuer = $1 AND user_id = $2
+	`
+	return scanOIDCIdentity(repo.db.QueryRowContext(ctx, query, issuer, userID))
+}
+
+// Create связывает учетную запись провайдера с пользователем
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - issuer: провайдер (утверждение iss)
+//   - subject: идентификатор пользователя у провайдера (утверждение sub)
+//   - userID: идентификатор пользователя
+//
+// Возвращает:
+//   - bool: false, если учетная запись провайдера или пользователь уже связаны (например, параллельным входом)
+//   - error: ошибка выполнения запроса
+func (repo *oidcIdentityRepo) Create(
+	ctx context.Context,
+	issuer string,
+	subject string,
+	userID uuid.UUID,
+) (bool, error) {
+	query := `
+		INSERT INTO oidc_identities (issuer, subject, user_id) VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+	`
+	result, err := repo.db.ExecContext(ctx, query, issuer, subject, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// scanOIDCIdentity читает связь из строки результата или возвращает nil, если строки нет
+func scanOIDCIdentity(row *sql.Row) (*common.OIDCIdentity, error) {
+	var identity common.OIDCIdentity
+	err := row.Scan(&identity.Issuer, &identity.Subject, &identity.UserID, &identity.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
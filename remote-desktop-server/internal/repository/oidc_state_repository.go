@@ -0,0 +1,95 @@
+// Package repository предоставляет реализации репозиториев для работы с данными приложения.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+)
+
+// oidcStateRepo реализует OIDCStateRepository для работы с PostgreSQL
+type oidcStateRepo struct {
+	db *sql.DB
+}
+
+// OIDCStateRepository определяет контракт для хранения незавершенных входов через OpenID Connect
+type OIDCStateRepository interface {
+	// Create сохраняет хэш параметра state вместе с code_verifier и nonce
+	Create(ctx context.Context, stateHash string, codeVerifier string, nonce string, ttl time.Duration) error
+
+	// Consume помечает действующий вход использованным и возвращает его, иначе nil
+	Consume(ctx context.Context, stateHash string) (*common.OIDCLoginState, error)
+}
+
+// NewOIDCStateRepository создает новый экземпляр OIDCStateRepository
+func NewOIDCStateRepository(db *sql.DB) OIDCStateRepository {
+	return &oidcStateRepo{
+		db: db,
+	}
+}
+
+// Create сохраняет незавершенный вход через OpenID Connect
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - stateHash: SHA-256 хэш параметра state (сам state передается провайдеру и не хранится)
+//   - codeVerifier: code_verifier PKCE
+//   - nonce: значение nonce
+//   - ttl: время, за которое нужно завершить вход
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса
+//
+// Особенности:
+//   - Удаляет истекшие и использованные входы
+func (repo *oidcStateRepo) Create(
+	ctx context.Context,
+	stateHash string,
+	codeVerifier string,
+	nonce string,
+	ttl time.Duration,
+) error {
+	query := "DELETE FROM oidc_login_states WHERE expires_at < CURRENT_TIMESTAMP OR used_at IS NOT NULL"
+	if _, err := repo.db.ExecContext(ctx, query); err != nil {
+		return err
+	}
+	query = `
+		INSERT INTO oidc_login_states (state_hash, code_verifier, nonce, expires_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP + make_interval(secs => $4))
+	`
+	_, err := repo.db.ExecContext(ctx, query, stateHash, codeVerifier, nonce, ttl.Seconds())
+	return err
+}
+
+// Consume помечает незавершенный вход использованным
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - stateHash: SHA-256 хэш параметра state
+//
+// Возвращает:
+//   - *common.OIDCLoginState: вход или nil, если он не найден, истек или уже завершен
+//   - error: ошибка выполнения запроса
+//
+// Особенности:
+//   - Проверка и отметка выполняются одним запросом, поэтому state нельзя использовать дважды
+func (repo *oidcStateRepo) Consume(ctx context.Context, stateHash string) (*common.OIDCLoginState, error) {
+	query := `
+		UPDATE oidc_login_states SET used_at = CURRENT_TIMESTAMP
+		WHERE state_hash = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		RETURNING id, code_verifier, nonce, expires_at
+	`
+	var state common.OIDCLoginState
+	err := repo.db.QueryRowContext(ctx, query, stateHash).
+		Scan(&state.ID, &state.CodeVerifier, &state.Nonce, &state.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
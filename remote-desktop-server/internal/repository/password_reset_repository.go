@@ -0,0 +1,104 @@
+// Package repository предоставляет реализации репозиториев для работы с данными приложения.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+)
+
+// passwordResetRepo реализует PasswordResetRepository для работы с PostgreSQL
+type passwordResetRepo struct {
+	db *sql.DB
+}
+
+// PasswordResetRepository определяет контракт для хранения токенов сброса пароля
+type PasswordResetRepository interface {
+	// Create сохраняет хэш нового токена сброса пароля пользователя
+	Create(ctx context.Context, userID uuid.UUID, tokenHash string, ttl time.Duration) error
+
+	// Consume помечает действующий токен использованным и возвращает его, иначе nil
+	Consume(ctx context.Context, tokenHash string) (*common.PasswordResetToken, error)
+
+	// Release снимает отметку об использовании токена (компенсация неудачного сброса)
+	Release(ctx context.Context, id uuid.UUID) error
+}
+
+// NewPasswordResetRepository создает новый экземпляр PasswordResetRepository
+func NewPasswordResetRepository(db *sql.DB) PasswordResetRepository {
+	return &passwordResetRepo{
+		db: db,
+	}
+}
+
+// Create сохраняет токен сброса пароля
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - userID: идентификатор пользователя
+//   - tokenHash: SHA-256 хэш токена (сам токен отправляется пользователю и не хранится)
+//   - ttl: время жизни токена
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса
+func (repo *passwordResetRepo) Create(ctx context.Context, userID uuid.UUID, tokenHash string, ttl time.Duration) error {
+	query := `
+		INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP + make_interval(secs => $3))
+	`
+	_, err := repo.db.ExecContext(ctx, query, userID, tokenHash, ttl.Seconds())
+	return err
+}
+
+// Consume помечает токен сброса пароля использованным
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - tokenHash: SHA-256 хэш токена
+//
+// Возвращает:
+//   - *common.PasswordResetToken: использованный токен или nil, если токен не найден,
+//     истек, уже использован или пользователь удален
+//   - error: ошибка выполнения запроса
+//
+// Особенности:
+//   - Проверка и отметка выполняются одним запросом, поэтому токен нельзя использовать дважды
+func (repo *passwordResetRepo) Consume(ctx context.Context, tokenHash string) (*common.PasswordResetToken, error) {
+	query := `
+		UPDATE password_reset_tokens t SET used_at = CURRENT_TIMESTAMP
+		FROM users u
+		WHERE t.token_hash = $1
+			AND t.used_at IS NULL
+			AND t.expires_at > CURRENT_TIMESTAMP
+			AND u.id = t.user_id
+			AND u.deleted_at IS NULL
+		RETURNING t.id, t.user_id, t.expires_at
+	`
+	var token common.PasswordResetToken
+	err := repo.db.QueryRowContext(ctx, query, tokenHash).Scan(&token.ID, &token.UserID, &token.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Release снимает отметку об использовании токена сброса пароля
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор токена
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса
+func (repo *passwordResetRepo) Release(ctx context.Context, id uuid.UUID) error {
+	query := "UPDATE password_reset_tokens SET used_at = NULL WHERE id = $1"
+	_, err := repo.db.ExecContext(ctx, query, id)
+	return err
+}
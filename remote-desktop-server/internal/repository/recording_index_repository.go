@@ -0,0 +1,234 @@
+// Package repository предоставляет реализации репозиториев для работы с данными приложения.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+)
+
+// likeEscaper экранирует спецсимволы шаблона LIKE в поисковой строке
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// recordingIndexRepo реализует RecordingIndexRepository для работы с PostgreSQL
+type recordingIndexRepo struct {
+	db *sql.DB
+}
+
+// RecordingIndexRepository определяет контракт для хранения индекса команд записей терминала
+type RecordingIndexRepository interface {
+	// FindRecording возвращает проиндексированную запись или nil, если запись не индексировалась
+	FindRecording(ctx context.Context, connectionID string, name string) (*common.IndexedRecording, error)
+
+	// Save заменяет индекс записи и ее команды
+	Save(ctx context.Context, recording common.IndexedRecording, commands []*common.RecordedCommand) error
+
+	// Delete удаляет запись и ее команды из индекса
+	Delete(ctx context.Context, connectionID string, name string) error
+
+	// SearchCommands ищет команды по подстроке и возвращает страницу результатов и их общее количество
+	SearchCommands(ctx context.Context, filter *common.CommandSearchFilter) ([]*common.RecordedCommand, int, error)
+}
+
+// NewRecordingIndexRepository создает новый экземпляр RecordingIndexRepository
+func NewRecordingIndexRepository(db *sql.DB) RecordingIndexRepository {
+	return &recordingIndexRepo{
+		db: db,
+	}
+}
+
+// FindRecording ищет проиндексированную запись
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - connectionID: идентификатор подключения
+//   - name: имя файла typescript
+//
+// Возвращает:
+//   - *common.IndexedRecording: найденная запись или nil
+//   - error: ошибка выполнения запроса
+func (repo *recordingIndexRepo) FindRecording(
+	ctx context.Context,
+	connectionID string,
+	name string,
+) (*common.IndexedRecording, error) {
+	var recording common.IndexedRecording
+	query := `
+		SELECT id, connection_id, recording_name, username, started_at, size
+		FROM indexed_recordings
+		WHERE connection_id = $1 AND recording_name = $2
+	`
+	err := repo.db.QueryRowContext(ctx, query, connectionID, name).Scan(
+		&recording.ID,
+		&recording.ConnectionID,
+		&recording.RecordingName,
+		&recording.Username,
+		&recording.StartedAt,
+		&recording.Size,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &recording, nil
+}
+
+// Save сохраняет запись и ее команды в одной транзакции.
+// Ранее сохраненные команды записи заменяются.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - recording: сведения о записи
+//   - commands: команды записи
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса
+func (repo *recordingIndexRepo) Save(
+	ctx context.Context,
+	recording common.IndexedRecording,
+	commands []*common.RecordedCommand,
+) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id string
+	query := `
+		INSERT INTO indexed_recordings (connection_id, recording_name, username, started_at, size)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (connection_id, recording_name)
+		DO UPDATE SET
+			username = EXCLUDED.username,
+			started_at = EXCLUDED.started_at,
+			size = EXCLUDED.size,
+			indexed_at = CURRENT_TIMESTAMP
+		RETURNING id
+	`
+	if err := tx.QueryRowContext(
+		ctx,
+		query,
+		recording.ConnectionID,
+		recording.RecordingName,
+		recording.Username,
+		recording.StartedAt,
+		recording.Size,
+	).Scan(&id); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM recording_commands WHERE recording_id = $1", id); err != nil {
+		return err
+	}
+	statement, err := tx.PrepareContext(ctx, `
+		INSERT INTO recording_commands (recording_id, command, offset_ms, executed_at)
+		VALUES ($1, $2, $3, $4)
+	`)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+	for _, command := range commands {
+		if _, err := statement.ExecContext(ctx, id, command.Command, command.OffsetMs, command.ExecutedAt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Delete удаляет запись из индекса. Команды удаляются каскадно.
+// Отсутствие записи в индексе не считается ошибкой.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - connectionID: идентификатор подключения
+//   - name: имя файла typescript
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса
+func (repo *recordingIndexRepo) Delete(ctx context.Context, connectionID string, name string) error {
+	query := "DELETE FROM indexed_recordings WHERE connection_id = $1 AND recording_name = $2"
+	_, err := repo.db.ExecContext(ctx, query, connectionID, name)
+	return err
+}
+
+// SearchCommands ищет команды, содержащие подстроку (без учета регистра),
+// и возвращает их от новых к старым
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - filter: поисковая строка, фильтры и пагинация
+//
+// Возвращает:
+//   - []*common.RecordedCommand: команды запрошенной страницы
+//   - int: общее количество найденных команд
+//   - error: ошибка выполнения запроса
+func (repo *recordingIndexRepo) SearchCommands(
+	ctx context.Context,
+	filter *common.CommandSearchFilter,
+) ([]*common.RecordedCommand, int, error) {
+	conditions := []string{`c.command ILIKE $1 ESCAPE '\'`}
+	args := []interface{}{"%" + likeEscaper.Replace(filter.Query) + "%"}
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.ConnectionID != "" {
+		add("r.connection_id = $%d", filter.ConnectionID)
+	}
+	if filter.Username != "" {
+		add("r.username = $%d", filter.Username)
+	}
+	if filter.From != nil {
+		add("c.executed_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		add("c.executed_at < $%d", *filter.To)
+	}
+	from := `
+		FROM recording_commands c
+		JOIN indexed_recordings r ON r.id = c.recording_id
+		WHERE ` + strings.Join(conditions, " AND ")
+
+	var total int
+	if err := repo.db.QueryRowContext(ctx, "SELECT COUNT(*)"+from, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT r.connection_id, r.recording_name, r.username, c.command, c.offset_ms, c.executed_at
+		%s
+		ORDER BY c.executed_at DESC, c.id DESC
+		LIMIT $%d OFFSET $%d
+	`, from, len(args)+1, len(args)+2)
+	args = append(args, filter.PerPage, (filter.Page-1)*filter.PerPage)
+
+	rows, err := repo.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	commands := make([]*common.RecordedCommand, 0, filter.PerPage)
+	for rows.Next() {
+		var command common.RecordedCommand
+		if err := rows.Scan(
+			&command.ConnectionID,
+			&command.RecordingName,
+			&command.Username,
+			&command.Command,
+			&command.OffsetMs,
+			&command.ExecutedAt,
+		); err != nil {
+			return nil, 0, err
+		}
+		commands = append(commands, &command)
+	}
+	return commands, total, rows.Err()
+}
@@ -0,0 +1,123 @@
+// Package repository предоставляет реализации репозиториев для работы с данными приложения.
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+)
+
+// recordingPolicyRepo реализует RecordingPolicyRepository для работы с PostgreSQL
+type recordingPolicyRepo struct {
+	db *sql.DB
+}
+
+// RecordingPolicyRepository определяет контракт для хранения сроков хранения записей подключений
+type RecordingPolicyRepository interface {
+	// Find возвращает политику подключения или nil, если она не задана
+	Find(ctx context.Context, connectionID string) (*common.RecordingPolicy, error)
+
+	// FindAll возвращает политики всех подключений
+	FindAll(ctx context.Context) ([]*common.RecordingPolicy, error)
+
+	// Save создает или обновляет политику подключения
+	Save(ctx context.Context, policy common.RecordingPolicy) error
+
+	// Delete удаляет политику подключения
+	Delete(ctx context.Context, connectionID string) error
+}
+
+// NewRecordingPolicyRepository создает новый экземпляр RecordingPolicyRepository
+func NewRecordingPolicyRepository(db *sql.DB) RecordingPolicyRepository {
+	return &recordingPolicyRepo{
+		db: db,
+	}
+}
+
+// Find ищет политику хранения записей подключения
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - connectionID: идентификатор подключения Guacamole
+//
+// Возвращает:
+//   - *common.RecordingPolicy: найденная политика или nil
+//   - error: ошибка выполнения запроса
+func (repo *recordingPolicyRepo) Find(ctx context.Context, connectionID string) (*common.RecordingPolicy, error) {
+	var policy common.RecordingPolicy
+	query := "SELECT connection_id, retention_days, updated_at FROM recording_policies WHERE connection_id = $1"
+	err := repo.db.QueryRowContext(ctx, query, connectionID).Scan(
+		&policy.ConnectionID,
+		&policy.RetentionDays,
+		&policy.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// FindAll возвращает политики хранения записей всех подключений
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//
+// Возвращает:
+//   - []*common.RecordingPolicy: список политик
+//   - error: ошибка выполнения запроса
+func (repo *recordingPolicyRepo) FindAll(ctx context.Context) ([]*common.RecordingPolicy, error) {
+	query := "SELECT connection_id, retention_days, updated_at FROM recording_policies"
+	rows, err := repo.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := make([]*common.RecordingPolicy, 0)
+	for rows.Next() {
+		var policy common.RecordingPolicy
+		if err := rows.Scan(&policy.ConnectionID, &policy.RetentionDays, &policy.UpdatedAt); err != nil {
+			return nil, err
+		}
+		policies = append(policies, &policy)
+	}
+	return policies, rows.Err()
+}
+
+// Save создает или обновляет политику хранения записей подключения
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - policy: идентификатор подключения и срок хранения
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса
+func (repo *recordingPolicyRepo) Save(ctx context.Context, policy common.RecordingPolicy) error {
+	query := `
+		INSERT INTO recording_policies (connection_id, retention_days)
+		VALUES ($1, $2)
+		ON CONFLICT (connection_id)
+		DO UPDATE SET retention_days = EXCLUDED.retention_days, updated_at = CURRENT_TIMESTAMP
+	`
+	_, err := repo.db.ExecContext(ctx, query, policy.ConnectionID, policy.RetentionDays)
+	return err
+}
+
+// Delete удаляет политику хранения записей подключения.
+// Отсутствие политики не считается ошибкой.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - connectionID: идентификатор подключения Guacamole
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса
+func (repo *recordingPolicyRepo) Delete(ctx context.Context, connectionID string) error {
+	query := "DELETE FROM recording_policies WHERE connection_id = $1"
+	_, err := repo.db.ExecContext(ctx, query, connectionID)
+	return err
+}
@@ -0,0 +1,280 @@
+// Package repository предоставляет реализации репозиториев для работы с данными приложения.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+)
+
+// shareLinkColumns список колонок ссылки совместного доступа в порядке scanShareLink
+const shareLinkColumns = `
+	id, connection_id, sharing_profile_id, created_by, note, expires_at,
+	max_uses, uses, revoked_at, created_at, token_hash, share_credentials
+`
+
+// shareLinkRepo реализует ShareLinkRepository для работы с PostgreSQL
+type shareLinkRepo struct {
+	db *sql.DB
+}
+
+// ShareLinkRepository определяет контракт для хранения ссылок совместного доступа
+type ShareLinkRepository interface {
+	// Create сохраняет новую ссылку и заполняет ее идентификатор и время создания
+	Create(ctx context.Context, link *common.ShareLink) error
+
+	// FindByID возвращает ссылку по идентификатору или nil, если она не найдена
+	FindByID(ctx context.Context, id string) (*common.ShareLink, error)
+
+	// FindByToken возвращает ссылку по хэшу токена или nil, если она не найдена
+	FindByToken(ctx context.Context, tokenHash string) (*common.ShareLink, error)
+
+	// FindByConnection возвращает ссылки подключения (createdBy — фильтр по создателю, пустой — все)
+	FindByConnection(ctx context.Context, connectionID string, createdBy string) ([]*common.ShareLink, error)
+
+	// Revoke отзывает ссылку
+	Revoke(ctx context.Context, id string) error
+
+	// Use учитывает переход по ссылке и возвращает ее, если ссылка действует, иначе nil
+	Use(ctx context.Context, tokenHash string, remoteAddr string, userAgent string) (*common.ShareLink, error)
+
+	// FindUses возвращает переходы по ссылке
+	FindUses(ctx context.Context, linkID string) ([]*common.ShareLinkUse, error)
+}
+
+// NewShareLinkRepository создает новый экземпляр ShareLinkRepository
+func NewShareLinkRepository(db *sql.DB) ShareLinkRepository {
+	return &shareLinkRepo{
+		db: db,
+	}
+}
+
+// Create сохраняет новую ссылку совместного доступа
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - link: данные ссылки (ID и CreatedAt заполняются после сохранения)
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса
+func (repo *shareLinkRepo) Create(ctx context.Context, link *common.ShareLink) error {
+	query := `
+		INSERT INTO share_links (
+			token_hash, connection_id, sharing_profile_id, share_credentials, created_by, note, expires_at, max_uses
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at
+	`
+	return repo.db.QueryRowContext(
+		ctx,
+		query,
+		link.TokenHash,
+		link.ConnectionID,
+		link.SharingProfileID,
+		link.ShareCredentials,
+		link.CreatedBy,
+		link.Note,
+		link.ExpiresAt,
+		link.MaxUses,
+	).Scan(&link.ID, &link.CreatedAt)
+}
+
+// FindByID ищет ссылку совместного доступа по идентификатору
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор ссылки
+//
+// Возвращает:
+//   - *common.ShareLink: найденная ссылка или nil
+//   - error: ошибка выполнения запроса
+func (repo *shareLinkRepo) FindByID(ctx context.Context, id string) (*common.ShareLink, error) {
+	query := "SELECT" + shareLinkColumns + "FROM share_links WHERE id::text = $1"
+	link, err := scanShareLink(repo.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return link, err
+}
+
+// FindByToken ищет ссылку совместного доступа по хэшу токена
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - tokenHash: SHA-256 хэш токена ссылки
+//
+// Возвращает:
+//   - *common.ShareLink: найденная ссылка (в том числе отозванная или истекшая) или nil
+//   - error: ошибка выполнения запроса
+func (repo *shareLinkRepo) FindByToken(ctx context.Context, tokenHash string) (*common.ShareLink, error) {
+	query := "SELECT" + shareLinkColumns + "FROM share_links WHERE token_hash = $1"
+	link, err := scanShareLink(repo.db.QueryRowContext(ctx, query, tokenHash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return link, err
+}
+
+// FindByConnection возвращает ссылки совместного доступа подключения от новых к старым
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - connectionID: идентификатор подключения
+//   - createdBy: email создателя (пустая строка — ссылки всех пользователей)
+//
+// Возвращает:
+//   - []*common.ShareLink: список ссылок
+//   - error: ошибка выполнения запроса
+func (repo *shareLinkRepo) FindByConnection(
+	ctx context.Context,
+	connectionID string,
+	createdBy string,
+) ([]*common.ShareLink, error) {
+	query := "SELECT" + shareLinkColumns + `
+		FROM share_links
+		WHERE connection_id = $1 AND ($2 = '' OR created_by = $2)
+		ORDER BY created_at DESC
+	`
+	rows, err := repo.db.QueryContext(ctx, query, connectionID, createdBy)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := make([]*common.ShareLink, 0)
+	for rows.Next() {
+		link, err := scanShareLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+// Revoke отзывает ссылку совместного доступа. Повторный отзыв не меняет время отзыва.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор ссылки
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса
+func (repo *shareLinkRepo) Revoke(ctx context.Context, id string) error {
+	query := "UPDATE share_links SET revoked_at = CURRENT_TIMESTAMP WHERE id::text = $1 AND revoked_at IS NULL"
+	_, err := repo.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// Use учитывает переход по ссылке совместного доступа.
+// Счетчик увеличивается только у действующей ссылки (не отозвана, не истекла,
+// лимит переходов не исчерпан); проверка и увеличение выполняются одним запросом.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - tokenHash: SHA-256 хэш токена ссылки
+//   - remoteAddr: адрес клиента
+//   - userAgent: User-Agent клиента
+//
+// Возвращает:
+//   - *common.ShareLink: действующая ссылка или nil
+//   - error: ошибка выполнения запроса
+func (repo *shareLinkRepo) Use(
+	ctx context.Context,
+	tokenHash string,
+	remoteAddr string,
+	userAgent string,
+) (*common.ShareLink, error) {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE share_links SET uses = uses + 1
+		WHERE token_hash = $1
+			AND revoked_at IS NULL
+			AND expires_at > CURRENT_TIMESTAMP
+			AND (max_uses IS NULL OR uses < max_uses)
+		RETURNING` + shareLinkColumns
+	link, err := scanShareLink(tx.QueryRowContext(ctx, query, tokenHash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	query = "INSERT INTO share_link_uses (link_id, remote_addr, user_agent) VALUES ($1, $2, $3)"
+	if _, err := tx.ExecContext(ctx, query, link.ID, remoteAddr, userAgent); err != nil {
+		return nil, err
+	}
+	return link, tx.Commit()
+}
+
+// FindUses возвращает переходы по ссылке от новых к старым
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - linkID: идентификатор ссылки
+//
+// Возвращает:
+//   - []*common.ShareLinkUse: список переходов
+//   - error: ошибка выполнения запроса
+func (repo *shareLinkRepo) FindUses(ctx context.Context, linkID string) ([]*common.ShareLinkUse, error) {
+	query := `
+		SELECT id, link_id, remote_addr, user_agent, used_at
+		FROM share_link_uses
+		WHERE link_id::text = $1
+		ORDER BY used_at DESC, id DESC
+	`
+	rows, err := repo.db.QueryContext(ctx, query, linkID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	uses := make([]*common.ShareLinkUse, 0)
+	for rows.Next() {
+		var use common.ShareLinkUse
+		if err := rows.Scan(&use.ID, &use.LinkID, &use.RemoteAddr, &use.UserAgent, &use.UsedAt); err != nil {
+			return nil, err
+		}
+		uses = append(uses, &use)
+	}
+	return uses, rows.Err()
+}
+
+// scanShareLink читает ссылку совместного доступа из строки результата
+func scanShareLink(row interface{ Scan(dest ...any) error }) (*common.ShareLink, error) {
+	var link common.ShareLink
+	var maxUses sql.NullInt64
+	var revokedAt sql.NullTime
+	err := row.Scan(
+		&link.ID,
+		&link.ConnectionID,
+		&link.SharingProfileID,
+		&link.CreatedBy,
+		&link.Note,
+		&link.ExpiresAt,
+		&maxUses,
+		&link.Uses,
+		&revokedAt,
+		&link.CreatedAt,
+		&link.TokenHash,
+		&link.ShareCredentials,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if maxUses.Valid {
+		value := int(maxUses.Int64)
+		link.MaxUses = &value
+	}
+	if revokedAt.Valid {
+		link.RevokedAt = &revokedAt.Time
+	}
+	return &link, nil
+}
@@ -0,0 +1,399 @@
+// Package repository предоставляет реализации репозиториев для работы с данными приложения.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+)
+
+// twoFactorRepo реализует TwoFactorRepository для работы с PostgreSQL
+type twoFactorRepo struct {
+	db *sql.DB
+}
+
+// TwoFactorRepository определяет контракт для хранения секретов TOTP,
+// кодов восстановления и незавершенных входов
+type TwoFactorRepository interface {
+	// FindTOTP возвращает секрет TOTP пользователя или nil
+	FindTOTP(ctx context.Context, userID uuid.UUID) (*common.UserTOTP, error)
+
+	// SavePendingTOTP сохраняет новый неподтвержденный секрет, если TOTP еще не подключен
+	SavePendingTOTP(ctx context.Context, userID uuid.UUID, secret []byte) (bool, error)
+
+	// EnableTOTP подтверждает секрет и заменяет коды восстановления
+	EnableTOTP(ctx context.Context, userID uuid.UUID, step int64, codeHashes []string) (bool, error)
+
+	// UseTOTPStep запоминает шаг принятого кода, если он новее последнего принятого
+	UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+
+	// DeleteTOTP отключает TOTP и удаляет коды восстановления
+	DeleteTOTP(ctx context.Context, userID uuid.UUID) error
+
+	// ReplaceRecoveryCodes заменяет коды восстановления пользователя
+	ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) error
+
+	// UseRecoveryCode помечает неиспользованный код восстановления использованным
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error)
+
+	// CountRecoveryCodes возвращает количество неиспользованных кодов восстановления
+	CountRecoveryCodes(ctx context.Context, userID uuid.UUID) (int, error)
+
+	// CreateChallenge сохраняет незавершенный вход
+	CreateChallenge(ctx context.Context, userID uuid.UUID, tokenHash string, credentials []byte, ttl time.Duration) (time.Time, error)
+
+	// FindChallenge возвращает действующий незавершенный вход или nil
+	FindChallenge(ctx context.Context, tokenHash string, maxAttempts int) (*common.AuthChallengeRecord, error)
+
+	// AddChallengeAttempt увеличивает счетчик неверных кодов
+	AddChallengeAttempt(ctx context.Context, id uuid.UUID) error
+
+	// ConsumeChallenge помечает незавершенный вход использованным
+	ConsumeChallenge(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+// NewTwoFactorRepository создает новый экземпляр TwoFactorRepository
+func NewTwoFactorRepository(db *sql.DB) TwoFactorRepository {
+	return &twoFactorRepo{
+		db: db,
+	}
+}
+
+// FindTOTP ищет секрет TOTP пользователя
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - userID: идентификатор пользователя
+//
+// Возвращает:
+//   - *common.UserTOTP: секрет (подтвержденный или нет) или nil, если TOTP не подключался
+//   - error: ошибка выполнения запроса
+func (repo *twoFactorRepo) FindTOTP(ctx context.Context, userID uuid.UUID) (*common.UserTOTP, error) {
+	query := "SELECT user_id, secret, last_used_step, enabled_at FROM user_totp WHERE user_id = $1"
+	var totp common.UserTOTP
+	err := repo.db.QueryRowContext(ctx, query, userID).Scan(
+		&totp.UserID,
+		&totp.Secret,
+		&totp.LastUsedStep,
+		&totp.EnabledAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &totp, nil
+}
+
+// SavePendingTOTP сохраняет неподтвержденный секрет TOTP
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - userID: идентификатор пользователя
+//   - secret: зашифрованный секрет
+//
+// Возвращает:
+//   - bool: false, если TOTP уже подключен (подтвержденный секрет не заменяется)
+//   - error: ошибка выполнения запроса
+//
+// Особенности:
+//   - Ранее выданный неподтвержденный секрет заменяется новым
+func (repo *twoFactorRepo) SavePendingTOTP(ctx context.Context, userID uuid.UUID, secret []byte) (bool, error) {
+	query := `
+		INSERT INTO user_totp (user_id, secret) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = 0, created_at = CURRENT_TIMESTAMP
+		WHERE user_totp.enabled_at IS NULL
+	`
+	result, err := repo.db.ExecContext(ctx, query, userID, secret)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// EnableTOTP подтверждает секрет TOTP
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - userID: идентификатор пользователя
+//   - step: шаг времени кода, которым подтвержден секрет
+//   - codeHashes: хэши новых кодов восстановления
+//
+// Возвращает:
+//   - bool: false, если неподтвержденного секрета нет
+//   - error: ошибка выполнения запроса
+//
+// Особенности:
+//   - Подтверждение и замена кодов восстановления выполняются в одной транзакции
+func (repo *twoFactorRepo) EnableTOTP(ctx context.Context, userID uuid.UUID, step int64, codeHashes []string) (bool, error) {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE user_totp SET enabled_at = CURRENT_TIMESTAMP, last_used_step = $2
+		WHERE user_id = $1 AND enabled_at IS NULL AND last_used_step < $2
+	`
+	result, err := tx.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// UseTOTPStep запоминает шаг времени принятого кода
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - userID: идентификатор пользователя
+//   - step: шаг времени кода
+//
+// Возвращает:
+//   - bool: false, если код этого или более позднего шага уже принят (повторное использование)
+//   - error: ошибка выполнения запроса
+func (repo *twoFactorRepo) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	query := "UPDATE user_totp SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2"
+	result, err := repo.db.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// DeleteTOTP удаляет секрет TOTP и коды восстановления пользователя
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - userID: идентификатор пользователя
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса
+func (repo *twoFactorRepo) DeleteTOTP(ctx context.Context, userID uuid.UUID) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_totp WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ReplaceRecoveryCodes заменяет коды восстановления пользователя
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - userID: идентификатор пользователя
+//   - codeHashes: хэши новых кодов
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса
+func (repo *twoFactorRepo) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// replaceRecoveryCodes удаляет прежние коды восстановления и сохраняет новые в транзакции tx
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID uuid.UUID, codeHashes []string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	query := "INSERT INTO recovery_codes (user_id, code_hash) SELECT $1, unnest($2::text[])"
+	_, err := tx.ExecContext(ctx, query, userID, pq.Array(codeHashes))
+	return err
+}
+
+// UseRecoveryCode помечает код восстановления использованным
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - userID: идентификатор пользователя
+//   - codeHash: SHA-256 хэш кода
+//
+// Возвращает:
+//   - bool: false, если код не найден или уже использован
+//   - error: ошибка выполнения запроса
+func (repo *twoFactorRepo) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	query := `
+		UPDATE recovery_codes SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+	result, err := repo.db.ExecContext(ctx, query, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// CountRecoveryCodes возвращает количество неиспользованных кодов восстановления
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - userID: идентификатор пользователя
+//
+// Возвращает:
+//   - int: количество кодов
+//   - error: ошибка выполнения запроса
+func (repo *twoFactorRepo) CountRecoveryCodes(ctx context.Context, userID uuid.UUID) (int, error) {
+	query := "SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL"
+	var count int
+	err := repo.db.QueryRowContext(ctx, query, userID).Scan(&count)
+	return count, err
+}
+
+// CreateChallenge сохраняет незавершенный вход
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - userID: идентификатор пользователя
+//   - tokenHash: SHA-256 хэш токена (сам токен передается клиенту и не хранится)
+//   - credentials: зашифрованный пароль для получения токена Guacamole
+//   - ttl: время жизни
+//
+// Возвращает:
+//   - time.Time: время истечения
+//   - error: ошибка выполнения запроса
+func (repo *twoFactorRepo) CreateChallenge(
+	ctx context.Context,
+	userID uuid.UUID,
+	tokenHash string,
+	credentials []byte,
+	ttl time.Duration,
+) (time.Time, error) {
+	query := `
+		INSERT INTO auth_challenges (user_id, token_hash, credentials, expires_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP + make_interval(secs => $4))
+		RETURNING expires_at
+	`
+	var expiresAt time.Time
+	err := repo.db.QueryRowContext(ctx, query, userID, tokenHash, credentials, ttl.Seconds()).Scan(&expiresAt)
+	return expiresAt, err
+}
+
+// FindChallenge ищет незавершенный вход по хэшу токена
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - tokenHash: SHA-256 хэш токена
+//   - maxAttempts: допустимое количество неверных кодов
+//
+// Возвращает:
+//   - *common.AuthChallengeRecord: незавершенный вход или nil, если токен не найден, истек,
+//     использован, исчерпаны попытки или пользователь удален
+//   - error: ошибка выполнения запроса
+func (repo *twoFactorRepo) FindChallenge(
+	ctx context.Context,
+	tokenHash string,
+	maxAttempts int,
+) (*common.AuthChallengeRecord, error) {
+	query := `
+		SELECT c.id, c.user_id, c.credentials, c.attempts, c.expires_at
+		FROM auth_challenges c
+		JOIN users u ON u.id = c.user_id AND u.deleted_at IS NULL
+		WHERE c.token_hash = $1
+			AND c.used_at IS NULL
+			AND c.expires_at > CURRENT_TIMESTAMP
+			AND c.attempts < $2
+	`
+	var challenge common.AuthChallengeRecord
+	err := repo.db.QueryRowContext(ctx, query, tokenHash, maxAttempts).Scan(
+		&challenge.ID,
+		&challenge.UserID,
+		&challenge.Credentials,
+		&challenge.Attempts,
+		&challenge.ExpiresAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// AddChallengeAttempt увеличивает счетчик неверных кодов незавершенного входа
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор незавершенного входа
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса
+func (repo *twoFactorRepo) AddChallengeAttempt(ctx context.Context, id uuid.UUID) error {
+	query := "UPDATE auth_challenges SET attempts = attempts + 1 WHERE id = $1"
+	_, err := repo.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// ConsumeChallenge помечает незавершенный вход использованным
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор незавершенного входа
+//
+// Возвращает:
+//   - bool: false, если вход уже завершен параллельным запросом
+//   - error: ошибка выполнения запроса
+//
+// Особенности:
+//   - Зашифрованный пароль удаляется сразу после завершения входа
+func (repo *twoFactorRepo) ConsumeChallenge(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `
+		UPDATE auth_challenges SET used_at = CURRENT_TIMESTAMP, credentials = NULL
+		WHERE id = $1 AND used_at IS NULL
+	`
+	result, err := repo.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
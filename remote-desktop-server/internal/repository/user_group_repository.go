@@ -0,0 +1,327 @@
+// Package repository предоставляет реализации репозиториев для работы с данными приложения.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+)
+
+// userGroupRepo реализует UserGroupRepository для работы с PostgreSQL
+type userGroupRepo struct {
+	db *sql.DB
+}
+
+// UserGroupRepository определяет контракт для хранения групп пользователей
+type UserGroupRepository interface {
+	// FindAll возвращает все группы, отсортированные по названию
+	FindAll(ctx context.Context) ([]*common.UserGroup, error)
+
+	// FindByID возвращает группу по идентификатору или nil, если она не найдена
+	FindByID(ctx context.Context, id uuid.UUID) (*common.UserGroup, error)
+
+	// Create сохраняет новую группу и заполняет ее идентификатор и даты
+	Create(ctx context.Context, group *common.UserGroup) error
+
+	// Update изменяет название и описание группы
+	Update(ctx context.Context, group *common.UserGroup) error
+
+	// Delete удаляет группу вместе с ее участниками и связями с другими группами
+	Delete(ctx context.Context, id uuid.UUID) error
+
+	// FindMembers возвращает пользователей группы
+	FindMembers(ctx context.Context, id uuid.UUID) ([]*common.UserGroupMember, error)
+
+	// AddMember добавляет пользователя в группу
+	AddMember(ctx context.Context, groupID uuid.UUID, userID uuid.UUID) error
+
+	// RemoveMember удаляет пользователя из группы
+	RemoveMember(ctx context.Context, groupID uuid.UUID, userID uuid.UUID) error
+
+	// FindSubgroups возвращает группы, непосредственно вложенные в группу
+	FindSubgroups(ctx context.Context, id uuid.UUID) ([]*common.UserGroup, error)
+
+	// IsNestedIn проверяет, вложена ли группа (непосредственно или через другие группы) в группу ancestorID
+	IsNestedIn(ctx context.Context, id uuid.UUID, ancestorID uuid.UUID) (bool, error)
+
+	// AddSubgroup вкладывает группу childID в группу parentID
+	AddSubgroup(ctx context.Context, parentID uuid.UUID, childID uuid.UUID) error
+
+	// RemoveSubgroup убирает группу childID из группы parentID
+	RemoveSubgroup(ctx context.Context, parentID uuid.UUID, childID uuid.UUID) error
+}
+
+// NewUserGroupRepository создает новый экземпляр UserGroupRepository
+func NewUserGroupRepository(db *sql.DB) UserGroupRepository {
+	return &userGroupRepo{
+		db: db,
+	}
+}
+
+// userGroupSelect запрос групп с количеством участников в порядке scanUserGroup
+const userGroupSelect = `
+	SELECT g.id, g.name, g.description, g.created_at, g.updated_at,
+		(SELECT COUNT(*) FROM user_group_members m WHERE m.group_id = g.id)
+	FROM user_groups g
+`
+
+// FindAll возвращает все группы пользователей
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//
+// Возвращает:
+//   - []*common.UserGroup: группы, отсортированные по названию
+//   - error: ошибка выполнения запроса
+func (repo *userGroupRepo) FindAll(ctx context.Context) ([]*common.UserGroup, error) {
+	return repo.findGroups(ctx, userGroupSelect+"ORDER BY g.name")
+}
+
+// FindByID ищет группу пользователей по идентификатору
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор группы
+//
+// Возвращает:
+//   - *common.UserGroup: найденная группа или nil
+//   - error: ошибка выполнения запроса
+func (repo *userGroupRepo) FindByID(ctx context.Context, id uuid.UUID) (*common.UserGroup, error) {
+	group, err := scanUserGroup(repo.db.QueryRowContext(ctx, userGroupSelect+"WHERE g.id = $1", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return group, err
+}
+
+// Create сохраняет новую группу пользователей
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - group: данные группы (ID и даты заполняются после сохранения)
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса (в том числе нарушение уникальности названия)
+func (repo *userGroupRepo) Create(ctx context.Context, group *common.UserGroup) error {
+	query := `
+		INSERT INTO user_groups (name, description) VALUES ($1, $2)
+		RETURNING id, created_at, updated_at
+	`
+	return repo.db.QueryRowContext(
+		ctx,
+		query,
+		group.Name,
+		group.Description,
+	).Scan(&group.ID, &group.CreatedAt, &group.UpdatedAt)
+}
+
+// Update изменяет название и описание группы пользователей
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - group: данные группы
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса
+func (repo *userGroupRepo) Update(ctx context.Context, group *common.UserGroup) error {
+	query := `
+		UPDATE user_groups SET name = $2, description = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING updated_at
+	`
+	return repo.db.QueryRowContext(
+		ctx,
+		query,
+		group.ID,
+		group.Name,
+		group.Description,
+	).Scan(&group.UpdatedAt)
+}
+
+// Delete удаляет группу пользователей
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор группы
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса
+func (repo *userGroupRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := repo.db.ExecContext(ctx, "DELETE FROM user_groups WHERE id = $1", id)
+	return err
+}
+
+// FindMembers возвращает активных пользователей группы
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор группы
+//
+// Возвращает:
+//   - []*common.UserGroupMember: пользователи, отсортированные по имени
+//   - error: ошибка выполнения запроса
+func (repo *userGroupRepo) FindMembers(ctx context.Context, id uuid.UUID) ([]*common.UserGroupMember, error) {
+	query := `
+		SELECT u.id, u.name, u.email, m.created_at
+		FROM user_group_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.group_id = $1 AND u.deleted_at IS NULL
+		ORDER BY u.name, u.email
+	`
+	rows, err := repo.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := make([]*common.UserGroupMember, 0)
+	for rows.Next() {
+		var member common.UserGroupMember
+		if err := rows.Scan(&member.ID, &member.Name, &member.Email, &member.AddedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, &member)
+	}
+	return members, rows.Err()
+}
+
+// AddMember добавляет пользователя в группу. Повторное добавление ничего не меняет.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - groupID: идентификатор группы
+//   - userID: идентификатор пользователя
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса
+func (repo *userGroupRepo) AddMember(ctx context.Context, groupID uuid.UUID, userID uuid.UUID) error {
+	query := "INSERT INTO user_group_members (group_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING"
+	_, err := repo.db.ExecContext(ctx, query, groupID, userID)
+	return err
+}
+
+// RemoveMember удаляет пользователя из группы
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - groupID: идентификатор группы
+//   - userID: идентификатор пользователя
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса
+func (repo *userGroupRepo) RemoveMember(ctx context.Context, groupID uuid.UUID, userID uuid.UUID) error {
+	query := "DELETE FROM user_group_members WHERE group_id = $1 AND user_id = $2"
+	_, err := repo.db.ExecContext(ctx, query, groupID, userID)
+	return err
+}
+
+// FindSubgroups возвращает группы, непосредственно вложенные в группу
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор родительской группы
+//
+// Возвращает:
+//   - []*common.UserGroup: вложенные группы, отсортированные по названию
+//   - error: ошибка выполнения запроса
+func (repo *userGroupRepo) FindSubgroups(ctx context.Context, id uuid.UUID) ([]*common.UserGroup, error) {
+	query := userGroupSelect + `
+		JOIN user_group_subgroups s ON s.child_id = g.id
+		WHERE s.parent_id = $1
+		ORDER BY g.name
+	`
+	return repo.findGroups(ctx, query, id)
+}
+
+// IsNestedIn проверяет, вложена ли группа в другую группу на любом уровне
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор проверяемой группы
+//   - ancestorID: идентификатор предполагаемой родительской группы
+//
+// Возвращает:
+//   - bool: вложена ли группа id в группу ancestorID
+//   - error: ошибка выполнения запроса
+func (repo *userGroupRepo) IsNestedIn(ctx context.Context, id uuid.UUID, ancestorID uuid.UUID) (bool, error) {
+	query := `
+		WITH RECURSIVE descendants (id) AS (
+			SELECT child_id FROM user_group_subgroups WHERE parent_id = $2
+			UNION
+			SELECT s.child_id FROM user_group_subgroups s JOIN descendants d ON s.parent_id = d.id
+		)
+		SELECT EXISTS (SELECT 1 FROM descendants WHERE id = $1)
+	`
+	var nested bool
+	err := repo.db.QueryRowContext(ctx, query, id, ancestorID).Scan(&nested)
+	return nested, err
+}
+
+// AddSubgroup вкладывает группу в другую группу. Повторное вложение ничего не меняет.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - parentID: идентификатор родительской группы
+//   - childID: идентификатор вкладываемой группы
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса
+func (repo *userGroupRepo) AddSubgroup(ctx context.Context, parentID uuid.UUID, childID uuid.UUID) error {
+	query := "INSERT INTO user_group_subgroups (parent_id, child_id) VALUES ($1, $2) ON CONFLICT DO NOTHING"
+	_, err := repo.db.ExecContext(ctx, query, parentID, childID)
+	return err
+}
+
+// RemoveSubgroup убирает группу из родительской группы
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - parentID: идентификатор родительской группы
+//   - childID: идентификатор вложенной группы
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса
+func (repo *userGroupRepo) RemoveSubgroup(ctx context.Context, parentID uuid.UUID, childID uuid.UUID) error {
+	query := "DELETE FROM user_group_subgroups WHERE parent_id = $1 AND child_id = $2"
+	_, err := repo.db.ExecContext(ctx, query, parentID, childID)
+	return err
+}
+
+// findGroups выполняет запрос групп и читает результат
+func (repo *userGroupRepo) findGroups(ctx context.Context, query string, args ...any) ([]*common.UserGroup, error) {
+	rows, err := repo.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := make([]*common.UserGroup, 0)
+	for rows.Next() {
+		group, err := scanUserGroup(rows)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	return groups, rows.Err()
+}
+
+// scanUserGroup читает группу пользователей из строки результата
+func scanUserGroup(row interface{ Scan(dest ...any) error }) (*common.UserGroup, error) {
+	var group common.UserGroup
+	err := row.Scan(
+		&group.ID,
+		&group.Name,
+		&group.Description,
+		&group.CreatedAt,
+		&group.UpdatedAt,
+		&group.MembersCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
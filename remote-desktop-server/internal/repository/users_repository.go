@@ -5,7 +5,10 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
 
+	"github.com/google/uuid"
 	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
 )
 
@@ -19,8 +22,48 @@ type UserRepository interface {
 	// FindByEmail находит пользователя по email
 	FindByEmail(ctx context.Context, email string) (*common.User, error)
 
-	// Create создает нового пользователя в системе
-	Create(ctx context.Context, form common.AuthSignUpRequest) error
+	// FindByID находит пользователя по идентификатору
+	FindByID(ctx context.Context, id uuid.UUID) (*common.User, error)
+
+	// Create создает нового пользователя в системе с указанной ролью
+	// (verified — электронная почта пользователя уже подтверждена)
+	Create(ctx context.Context, form common.AuthSignUpRequest, role string, verified bool) error
+
+	// FindEmails возвращает email всех пользователей (withDeleted — включая удаленных и не подтвердивших почту)
+	FindEmails(ctx context.Context, withDeleted bool) ([]string, error)
+
+	// SoftDelete помечает пользователя удаленным
+	SoftDelete(ctx context.Context, email string) error
+
+	// UpdatePassword меняет хэш пароля пользователя и отзывает выданные ему токены
+	UpdatePassword(ctx context.Context, id uuid.UUID, password string) error
+
+	// SetGuacamoleCredentials сохраняет зашифрованный пароль Guacamole, управляемый сервером
+	SetGuacamoleCredentials(ctx context.Context, id uuid.UUID, credentials []byte) error
+
+	// SetRole меняет роль пользователя
+	SetRole(ctx context.Context, id uuid.UUID, role string) error
+
+	// FindPage возвращает страницу списка пользователей, включая отключенных, и их общее количество
+	FindPage(ctx context.Context, filter *common.UserFilter) ([]*common.User, int, error)
+
+	// FindByIDWithDeleted находит пользователя по идентификатору, включая отключенных
+	FindByIDWithDeleted(ctx context.Context, id uuid.UUID) (*common.User, error)
+
+	// SetDisabled отключает (помечает удаленным) или снова включает пользователя
+	SetDisabled(ctx context.Context, id uuid.UUID, disabled bool) error
+
+	// Delete удаляет пользователя вместе с его сессиями, токенами и членством в группах
+	Delete(ctx context.Context, id uuid.UUID) error
+
+	// UpdateProfile сохраняет имя, язык и часовой пояс пользователя
+	UpdateProfile(ctx context.Context, user *common.User) error
+
+	// UpdateEmail меняет электронную почту пользователя и отзывает выданные ему токены
+	UpdateEmail(ctx context.Context, id uuid.UUID, email string) error
+
+	// MarkEmailVerified отмечает электронную почту пользователя подтвержденной
+	MarkEmailVerified(ctx context.Context, id uuid.UUID) error
 }
 
 // NewUserRepository создает новый экземпляр UserRepository
@@ -42,16 +85,66 @@ func NewUserRepository(db *sql.DB) UserRepository {
 //
 // Особенности:
 //   - Возвращает только активных пользователей (deleted_at IS NULL)
-//   - Включает в результат: ID, имя, email, хэш пароля и дату создания
+//   - Включает в результат: ID, имя, email, хэш пароля, роль, язык и часовой пояс, версию токенов,
+//     пароль Guacamole, управляемый сервером, время подтверждения почты и дату создания
 func (repo *userRepo) FindByEmail(ctx context.Context, email string) (*common.User, error) {
 	var user common.User
-	query := "SELECT id, name, email, password, created_at FROM users WHERE email = $1 AND deleted_at IS NULL"
+	query := `
+		SELECT id, name, email, password, role, locale, timezone, token_version, guacamole_credentials, email_verified_at,
+			created_at
+		FROM users WHERE email = $1 AND deleted_at IS NULL
+	`
 	row := repo.db.QueryRowContext(ctx, query, email)
 	err := row.Scan(
 		&user.ID,
 		&user.Name,
 		&user.Email,
 		&user.Password,
+		&user.Role,
+		&user.Locale,
+		&user.Timezone,
+		&user.TokenVersion,
+		&user.GuacamoleCredentials,
+		&user.EmailVerifiedAt,
+		&user.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// FindByID ищет пользователя по идентификатору
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор пользователя
+//
+// Возвращает:
+//   - *common.User: найденный пользователь
+//   - error: ошибка если пользователь не найден или произошла ошибка запроса
+//
+// Особенности:
+//   - Возвращает только активных пользователей (deleted_at IS NULL)
+func (repo *userRepo) FindByID(ctx context.Context, id uuid.UUID) (*common.User, error) {
+	var user common.User
+	query := `
+		SELECT id, name, email, password, role, locale, timezone, token_version, guacamole_credentials, email_verified_at,
+			created_at
+		FROM users WHERE id = $1 AND deleted_at IS NULL
+	`
+	row := repo.db.QueryRowContext(ctx, query, id)
+	err := row.Scan(
+		&user.ID,
+		&user.Name,
+		&user.Email,
+		&user.Password,
+		&user.Role,
+		&user.Locale,
+		&user.Timezone,
+		&user.TokenVersion,
+		&user.GuacamoleCredentials,
+		&user.EmailVerifiedAt,
 		&user.CreatedAt,
 	)
 	if err != nil {
@@ -65,23 +158,31 @@ func (repo *userRepo) FindByEmail(ctx context.Context, email string) (*common.Us
 // Параметры:
 //   - ctx: контекст выполнения запроса
 //   - form: данные для регистрации (AuthSignUpRequest)
+//   - role: роль пользователя
+//   - verified: электронная почта уже подтверждена (пользователи внешних провайдеров);
+//     иначе пользователь не может войти до перехода по ссылке из письма
 //
 // Возвращает:
 //   - error: ошибка если не удалось создать пользователя
 //
 // Особенности:
-//   - Сохраняет имя, email и хэш пароля
+//   - Сохраняет имя, email, хэш пароля, роль и время подтверждения почты
 //   - Проверяет количество затронутых строк (rowsAffected)
 //   - Возвращает ошибку "room was not created" если запись не была создана
 //     (Примечание: возможно стоит изменить текст ошибки на более подходящий)
-func (repo *userRepo) Create(ctx context.Context, form common.AuthSignUpRequest) error {
-	query := "INSERT INTO users (name, email, password) VALUES ($1, $2, $3)"
+func (repo *userRepo) Create(ctx context.Context, form common.AuthSignUpRequest, role string, verified bool) error {
+	query := `
+		INSERT INTO users (name, email, password, role, email_verified_at)
+		VALUES ($1, $2, $3, $4, CASE WHEN $5 THEN CURRENT_TIMESTAMP END)
+	`
 	result, err := repo.db.ExecContext(
 		ctx,
 		query,
 		&form.Name,
 		&form.Email,
 		&form.Password,
+		role,
+		verified,
 	)
 	if err != nil {
 		return err
@@ -95,3 +196,448 @@ func (repo *userRepo) Create(ctx context.Context, form common.AuthSignUpRequest)
 	}
 	return nil
 }
+
+// FindEmails возвращает email адреса пользователей
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - withDeleted: включать ли удаленных пользователей (deleted_at IS NOT NULL)
+//     и пользователей, не подтвердивших электронную почту (их учетные записи Guacamole отключены)
+//
+// Возвращает:
+//   - []string: email адреса, отсортированные по алфавиту
+//   - error: ошибка выполнения запроса
+func (repo *userRepo) FindEmails(ctx context.Context, withDeleted bool) ([]string, error) {
+	query := `
+		SELECT DISTINCT email FROM users
+		WHERE $1 OR (deleted_at IS NULL AND email_verified_at IS NOT NULL)
+		ORDER BY email
+	`
+	rows, err := repo.db.QueryContext(ctx, query, withDeleted)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	emails := make([]string, 0)
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, err
+		}
+		emails = append(emails, email)
+	}
+	return emails, rows.Err()
+}
+
+// SoftDelete помечает пользователя удаленным (soft delete)
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - email: email адрес пользователя
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса
+//
+// Особенности:
+//   - Уже удаленные пользователи не изменяются
+func (repo *userRepo) SoftDelete(ctx context.Context, email string) error {
+	query := `
+		UPDATE users SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE email = $1 AND deleted_at IS NULL
+	`
+	_, err := repo.db.ExecContext(ctx, query, email)
+	return err
+}
+
+// UpdatePassword меняет хэш пароля пользователя
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор пользователя
+//   - password: bcrypt хэш нового пароля
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса или sql.ErrNoRows, если пользователь не найден
+//
+// Особенности:
+//   - В одной транзакции увеличивает версию токенов доступа (ранее выданные JWT перестают
+//     действовать) и отзывает неиспользованные токены сброса пароля
+//   - Пароль Guacamole снова совпадает с паролем пользователя, поэтому управляемый сервером
+//     пароль Guacamole удаляется
+func (repo *userRepo) UpdatePassword(ctx context.Context, id uuid.UUID, password string) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE users
+		SET password = $2, token_version = token_version + 1, guacamole_credentials = NULL,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+	result, err := tx.ExecContext(ctx, query, id, password)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	query = "UPDATE password_reset_tokens SET used_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND used_at IS NULL"
+	if _, err := tx.ExecContext(ctx, query, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetGuacamoleCredentials сохраняет пароль Guacamole, управляемый сервером
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор пользователя
+//   - credentials: пароль Guacamole, зашифрованный ключом GUAC_CREDENTIALS_KEY
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса или sql.ErrNoRows, если пользователь не найден
+func (repo *userRepo) SetGuacamoleCredentials(ctx context.Context, id uuid.UUID, credentials []byte) error {
+	query := `
+		UPDATE users SET guacamole_credentials = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+	result, err := repo.db.ExecContext(ctx, query, id, credentials)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SetRole меняет роль пользователя
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор пользователя
+//   - role: новая роль (admin, operator или viewer)
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса или sql.ErrNoRows, если пользователь не найден
+func (repo *userRepo) SetRole(ctx context.Context, id uuid.UUID, role string) error {
+	query := `
+		UPDATE users SET role = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+	result, err := repo.db.ExecContext(ctx, query, id, role)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// FindPage возвращает страницу списка пользователей, отсортированного по email
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - filter: поисковая строка, фильтры и пагинация
+//
+// Возвращает:
+//   - []*common.User: пользователи запрошенной страницы (без хэша пароля)
+//   - int: общее количество пользователей, удовлетворяющих фильтру
+//   - error: ошибка выполнения запроса
+//
+// Особенности:
+//   - Включает отключенных пользователей (deleted_at IS NOT NULL), если фильтр по состоянию не задан
+func (repo *userRepo) FindPage(ctx context.Context, filter *common.UserFilter) ([]*common.User, int, error) {
+	conditions := []string{"TRUE"}
+	args := make([]interface{}, 0)
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.Search != "" {
+		add(`(name ILIKE $%[1]d ESCAPE '\' OR email ILIKE $%[1]d ESCAPE '\')`, "%"+likeEscaper.Replace(filter.Search)+"%")
+	}
+	if filter.Role != "" {
+		add("role = $%d", filter.Role)
+	}
+	switch filter.Status {
+	case common.UserStatusActive:
+		conditions = append(conditions, "deleted_at IS NULL")
+	case common.UserStatusDisabled:
+		conditions = append(conditions, "deleted_at IS NOT NULL")
+	}
+	where := " FROM users WHERE " + strings.Join(conditions, " AND ")
+
+	var total int
+	if err := repo.db.QueryRowContext(ctx, "SELECT COUNT(*)"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, name, email, role, guacamole_credentials, email_verified_at, created_at, deleted_at
+		%s
+		ORDER BY email, created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)+1, len(args)+2)
+	args = append(args, filter.PerPage, (filter.Page-1)*filter.PerPage)
+
+	rows, err := repo.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := make([]*common.User, 0, filter.PerPage)
+	for rows.Next() {
+		var user common.User
+		if err := rows.Scan(
+			&user.ID,
+			&user.Name,
+			&user.Email,
+			&user.Role,
+			&user.GuacamoleCredentials,
+			&user.EmailVerifiedAt,
+			&user.CreatedAt,
+			&user.DeletedAt,
+		); err != nil {
+			return nil, 0, err
+		}
+		users = append(users, &user)
+	}
+	return users, total, rows.Err()
+}
+
+// FindByIDWithDeleted ищет пользователя по идентификатору, в том числе отключенного
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор пользователя
+//
+// Возвращает:
+//   - *common.User: найденный пользователь (DeletedAt заполнено для отключенного пользователя)
+//   - error: sql.ErrNoRows, если пользователь не найден, или ошибка выполнения запроса
+func (repo *userRepo) FindByIDWithDeleted(ctx context.Context, id uuid.UUID) (*common.User, error) {
+	var user common.User
+	query := `
+		SELECT id, name, email, password, role, locale, timezone, token_version, guacamole_credentials, email_verified_at,
+			created_at, deleted_at
+		FROM users WHERE id = $1
+	`
+	row := repo.db.QueryRowContext(ctx, query, id)
+	err := row.Scan(
+		&user.ID,
+		&user.Name,
+		&user.Email,
+		&user.Password,
+		&user.Role,
+		&user.Locale,
+		&user.Timezone,
+		&user.TokenVersion,
+		&user.GuacamoleCredentials,
+		&user.EmailVerifiedAt,
+		&user.CreatedAt,
+		&user.DeletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// SetDisabled отключает или включает пользователя
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор пользователя
+//   - disabled: отключить (true) или включить (false) пользователя
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса или sql.ErrNoRows, если пользователь не найден
+//
+// Особенности:
+//   - Отключенный пользователь помечается удаленным (deleted_at) так же, как SoftDelete,
+//     поэтому он не находится FindByEmail и FindByID и не может войти
+//   - Время отключения уже отключенного пользователя не меняется
+func (repo *userRepo) SetDisabled(ctx context.Context, id uuid.UUID, disabled bool) error {
+	query := `
+		UPDATE users
+		SET deleted_at = CASE WHEN $2 THEN COALESCE(deleted_at, CURRENT_TIMESTAMP) END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+	result, err := repo.db.ExecContext(ctx, query, id, disabled)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Delete удаляет пользователя без возможности восстановления
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор пользователя
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса или sql.ErrNoRows, если пользователь не найден
+//
+// Особенности:
+//   - Сессии входа, refresh токены, токены сброса пароля, TOTP и членство в группах
+//     удаляются каскадно (ON DELETE CASCADE)
+func (repo *userRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := repo.db.ExecContext(ctx, "DELETE FROM users WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// UpdateProfile сохраняет профиль пользователя
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - user: пользователь с новыми именем, языком и часовым поясом
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса или sql.ErrNoRows, если пользователь не найден
+func (repo *userRepo) UpdateProfile(ctx context.Context, user *common.User) error {
+	query := `
+		UPDATE users SET name = $2, locale = $3, timezone = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+	result, err := repo.db.ExecContext(ctx, query, user.ID, user.Name, user.Locale, user.Timezone)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// UpdateEmail меняет электронную почту пользователя
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор пользователя
+//   - email: подтвержденная электронная почта
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса или sql.ErrNoRows, если пользователь не найден
+//
+// Особенности:
+//   - Новый адрес считается подтвержденным (email_verified_at)
+//   - В одной транзакции увеличивает версию токенов доступа (в JWT записан прежний email)
+//     и отзывает остальные неиспользованные токены подтверждения электронной почты
+func (repo *userRepo) UpdateEmail(ctx context.Context, id uuid.UUID, email string) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE users
+		SET email = $2, email_verified_at = CURRENT_TIMESTAMP, token_version = token_version + 1,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+	result, err := tx.ExecContext(ctx, query, id, email)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	query = "UPDATE email_verification_tokens SET used_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND used_at IS NULL"
+	if _, err := tx.ExecContext(ctx, query, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MarkEmailVerified отмечает электронную почту пользователя подтвержденной
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор пользователя
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса или sql.ErrNoRows, если пользователь не найден
+//
+// Особенности:
+//   - Время подтверждения уже подтвердившего почту пользователя не меняется
+//   - Отзывает остальные неиспользованные токены подтверждения электронной почты (повторно
+//     отправленные письма), в той же транзакции
+func (repo *userRepo) MarkEmailVerified(ctx context.Context, id uuid.UUID) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, CURRENT_TIMESTAMP), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+	result, err := tx.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	query = "UPDATE email_verification_tokens SET used_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND used_at IS NULL"
+	if _, err := tx.ExecContext(ctx, query, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,21 @@
+// Package router предоставляет функциональность для настройки маршрутизации HTTP запросов.
+package router
+
+import "github.com/go-chi/chi/v5"
+
+// adminRouterGroup регистрирует маршруты администрирования
+//
+// Регистрируемые маршруты:
+//
+//	GET  /reconciliation - состояние фоновой сверки пользователей с Guacamole
+//	POST /reconciliation - немедленный запуск сверки
+//	GET  /sign-in/lockouts - действующие блокировки входа учетных записей и адресов
+//	POST /sign-in/unlock   - снятие блокировки входа учетной записи или адреса
+//	GET  /sign-in/audit    - журнал входа по паролю (фильтры, пагинация)
+func adminRouterGroup(admin chi.Router) {
+	admin.Get("/reconciliation", dependencies.ReconciliationHandler.Status)
+	admin.Post("/reconciliation", dependencies.ReconciliationHandler.Run)
+	admin.Get("/sign-in/lockouts", dependencies.LoginProtectionHandler.Locked)
+	admin.Post("/sign-in/unlock", dependencies.LoginProtectionHandler.Unlock)
+	admin.Get("/sign-in/audit", dependencies.LoginProtectionHandler.Audit)
+}
@@ -14,8 +14,28 @@ import (
 // Регистрируемые маршруты:
 //
 //	POST /sign-in - обработка входа пользователя
+//	POST /two-factor - второй шаг входа: код TOTP или код восстановления
+//	POST /two-factor/enroll - подключение обязательного TOTP во время входа
 //	POST /sign-up - обработка регистрации нового пользователя
+//	POST /refresh - обмен refresh токена на новую пару токенов
+//	POST /logout - завершение сессии входа
+//	POST /forgot-password - отправка письма со ссылкой для сброса пароля
+//	POST /reset-password - установка нового пароля по токену из письма
+//	POST /verify-email - подтверждение электронной почты по токену из письма
+//	POST /resend-verification - повторная отправка письма для подтверждения почты после регистрации
+//	GET /oidc/login - перенаправление на страницу входа провайдера OpenID Connect
+//	GET /oidc/callback - завершение входа через OpenID Connect (адрес возврата провайдера)
 func authRouterGroup(auth chi.Router) {
 	auth.Post("/sign-in", dependencies.AuthHandler.SingIn)
+	auth.Post("/two-factor", dependencies.TwoFactorHandler.Verify)
+	auth.Post("/two-factor/enroll", dependencies.TwoFactorHandler.EnrollChallenge)
 	auth.Post("/sign-up", dependencies.AuthHandler.SignUp)
+	auth.Post("/refresh", dependencies.AuthHandler.Refresh)
+	auth.Post("/logout", dependencies.AuthHandler.SignOut)
+	auth.Post("/forgot-password", dependencies.PasswordHandler.Forgot)
+	auth.Post("/reset-password", dependencies.PasswordHandler.Reset)
+	auth.Post("/verify-email", dependencies.EmailVerificationHandler.Verify)
+	auth.Post("/resend-verification", dependencies.EmailVerificationHandler.Resend)
+	auth.Get("/oidc/login", dependencies.OIDCHandler.Login)
+	auth.Get("/oidc/callback", dependencies.OIDCHandler.Callback)
 }
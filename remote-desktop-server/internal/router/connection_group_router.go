@@ -0,0 +1,40 @@
+// Package router предоставляет функциональность для настройки маршрутизации HTTP запросов.
+package router
+
+import (
+	"github.com/go-chi/chi/v5"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/handler/middleware"
+)
+
+// connectionGroupsRouterGroup регистрирует маршруты для работы с группами подключений
+//
+// Регистрируемые маршруты:
+//
+//	GET    /           - плоский список групп
+//	POST   /           - создание группы
+//	POST   /balancing  - создание группы балансировки вместе с участниками
+//	GET    /{id}/tree  - дерево группы с вложенными группами и подключениями
+//	GET    /{id}/edit  - данные группы для редактирования
+//	GET    /{id}/health - доступность участников группы балансировки
+//	PUT    /{id}       - обновление (в том числе перемещение) группы
+//	DELETE /{id}       - удаление группы
+//
+// Просмотр доступен с разрешением connections:use, изменение — с connections:manage.
+func connectionGroupsRouterGroup(groups chi.Router) {
+	groups.Group(func(groups chi.Router) {
+		groups.Use(middleware.RequirePermission(common.PermissionUseConnections))
+		groups.Get("/", dependencies.ConnectionGroupHandler.Get)
+		groups.Get("/{id}/tree", dependencies.ConnectionGroupHandler.Tree)
+		groups.Get("/{id}/health", dependencies.ConnectionGroupHandler.Health)
+	})
+	groups.Group(func(groups chi.Router) {
+		groups.Use(middleware.RequirePermission(common.PermissionManageConnections))
+		groups.Post("/", dependencies.ConnectionGroupHandler.StoreGroup)
+		groups.Post("/balancing", dependencies.ConnectionGroupHandler.StoreBalancingGroup)
+		groups.Get("/{id}/edit", dependencies.ConnectionGroupHandler.Edit)
+		groups.Put("/{id}", dependencies.ConnectionGroupHandler.UpdateGroup)
+		groups.Delete("/{id}", dependencies.ConnectionGroupHandler.RemoveGroup)
+	})
+}
@@ -0,0 +1,21 @@
+// Package router предоставляет функциональность для настройки маршрутизации HTTP запросов.
+package router
+
+import "github.com/go-chi/chi/v5"
+
+// historyRouterGroup регистрирует маршруты истории подключений и отчетов
+//
+// Параметры:
+//   - history: chi.Router - роутер для регистрации маршрутов истории
+//   - dependencies: содержит обработчики запросов (HistoryHandler)
+//
+// Регистрируемые маршруты:
+//
+//	GET /                     - страница истории подключений (фильтры, период, пагинация, CSV)
+//	GET /reports/weekly-usage - суммарное время подключений пользователей по неделям
+//	GET /reports/top-hosts    - наиболее используемые хосты
+func historyRouterGroup(history chi.Router) {
+	history.Get("/", dependencies.HistoryHandler.Get)
+	history.Get("/reports/weekly-usage", dependencies.HistoryHandler.WeeklyUsage)
+	history.Get("/reports/top-hosts", dependencies.HistoryHandler.TopHosts)
+}
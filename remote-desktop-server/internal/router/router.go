@@ -4,6 +4,7 @@ package router
 import (
 	"github.com/go-chi/chi/v5"
 
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
 	"github.com/margar-melkonyan/remote-desktop.git/internal/common/dependency"
 	"github.com/margar-melkonyan/remote-desktop.git/internal/handler/middleware"
 )
@@ -22,6 +23,8 @@ var dependencies *dependency.AppDependencies
 //   - Добавляет middleware для CORS и логирования
 //   - Организует маршруты в иерархическую структуру
 //   - Разделяет публичные и приватные маршруты
+//   - Ограничивает приватные маршруты разрешениями роли пользователя (middleware.RequirePermission);
+//     маршруты /users/current доступны любому вошедшему пользователю
 func NewRouter(deps *dependency.AppDependencies) *chi.Mux {
 	dependencies = deps
 	route := chi.NewMux()
@@ -31,13 +34,21 @@ func NewRouter(deps *dependency.AppDependencies) *chi.Mux {
 	)
 
 	route.Route("/auth", authRouterGroup)
+	route.Route("/share", shareRouterGroup) // Публичные ссылки совместного доступа к сессиям
 	route.Route("/api", func(api chi.Router) {
 		// Приватные маршруты (требуют аутентификации)
 		api.Route("/v1", func(v1 chi.Router) {
 			v1.Use(middleware.AuthMiddleware(deps)) // Middleware аутентификации
 			// Группы маршрутов:
-			v1.Route("/users", usersRouterGroup)       // Работа с пользователями
-			v1.Route("/sessions", sessionsRouterGroup) // Работа c сессиями
+			v1.Route("/users", usersRouterGroup)                        // Текущий пользователь и управление пользователями
+			v1.Route("/sessions", sessionsRouterGroup)                  // Работа c сессиями
+			v1.Route("/connection-groups", connectionGroupsRouterGroup) // Работа с группами подключений
+			v1.With(middleware.RequirePermission(common.PermissionUseConnections)).
+				Route("/history", historyRouterGroup) // История подключений и отчеты
+			v1.With(middleware.RequirePermission(common.PermissionManageUsers)).
+				Route("/groups", userGroupsRouterGroup) // Группы пользователей
+			v1.With(middleware.RequirePermission(common.PermissionAdministerSystem)).
+				Route("/admin", adminRouterGroup) // Администрирование
 		})
 	})
 
@@ -1,11 +1,46 @@
 package router
 
-import "github.com/go-chi/chi/v5"
+import (
+	"github.com/go-chi/chi/v5"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/handler/middleware"
+)
 
 func sessionsRouterGroup(sessions chi.Router) {
-	sessions.Get("/", dependencies.SessionHandler.Get)
-	sessions.Post("/", dependencies.SessionHandler.StoreConnection)
-	sessions.Put("/{id}", dependencies.SessionHandler.UpdateConnection)
-	sessions.Delete("/{id}", dependencies.SessionHandler.RemoveConnection)
-	sessions.Get("/{id}/edit", dependencies.SessionHandler.Edit)
+	sessions.Group(func(sessions chi.Router) {
+		sessions.Use(middleware.RequirePermission(common.PermissionUseConnections))
+		sessions.Get("/", dependencies.SessionHandler.Get)
+		sessions.Get("/{id}/tunnel", dependencies.SessionHandler.Tunnel)
+		sessions.Get("/{id}/history", dependencies.HistoryHandler.Get)
+		// Без прав администратора Guacamole видны и завершаются только свои подключения
+		sessions.Get("/active", dependencies.ActiveConnectionHandler.Get)
+		sessions.Delete("/active/{id}", dependencies.ActiveConnectionHandler.Kill)
+	})
+	sessions.Group(func(sessions chi.Router) {
+		sessions.Use(middleware.RequirePermission(common.PermissionManageConnections))
+		sessions.Get("/commands", dependencies.CommandHandler.Search)
+		sessions.Post("/", dependencies.SessionHandler.StoreConnection)
+		sessions.Put("/{id}", dependencies.SessionHandler.UpdateConnection)
+		sessions.Delete("/{id}", dependencies.SessionHandler.RemoveConnection)
+		sessions.Get("/{id}/edit", dependencies.SessionHandler.Edit)
+		sessions.Put("/{id}/parent", dependencies.SessionHandler.MoveConnection)
+		sessions.Get("/{id}/recordings", dependencies.RecordingHandler.Get)
+		sessions.Get("/{id}/recordings/{name}", dependencies.RecordingHandler.Download)
+		sessions.Get("/{id}/recordings/{name}/index", dependencies.RecordingHandler.Index)
+		sessions.Get("/{id}/recordings/{name}/thumbnail", dependencies.RecordingHandler.Thumbnail)
+		sessions.Get("/{id}/recordings/{name}/asciicast", dependencies.RecordingHandler.Asciicast)
+		sessions.Post("/{id}/recordings/{name}/commands", dependencies.CommandHandler.Index)
+		sessions.Delete("/{id}/recordings/{name}", dependencies.RecordingHandler.Remove)
+		sessions.Get("/{id}/permissions", dependencies.ConnectionPermissionHandler.Get)
+		sessions.Post("/{id}/permissions", dependencies.ConnectionPermissionHandler.Grant)
+		sessions.Delete("/{id}/permissions/{entityId}", dependencies.ConnectionPermissionHandler.Revoke)
+		sessions.Get("/{id}/sharing-profiles", dependencies.SharingHandler.GetProfiles)
+		sessions.Post("/{id}/sharing-profiles", dependencies.SharingHandler.StoreProfile)
+		sessions.Delete("/{id}/sharing-profiles/{profileId}", dependencies.SharingHandler.RemoveProfile)
+		sessions.Get("/{id}/share-links", dependencies.SharingHandler.GetLinks)
+		sessions.Post("/{id}/share-links", dependencies.SharingHandler.StoreLink)
+		sessions.Delete("/{id}/share-links/{linkId}", dependencies.SharingHandler.RevokeLink)
+		sessions.Get("/{id}/share-links/{linkId}/uses", dependencies.SharingHandler.GetLinkUses)
+	})
 }
@@ -0,0 +1,19 @@
+// Package router предоставляет функциональность для настройки маршрутизации HTTP запросов.
+package router
+
+import "github.com/go-chi/chi/v5"
+
+// shareRouterGroup регистрирует публичные маршруты ссылок совместного доступа
+//
+// Параметры:
+//   - share: chi.Router - роутер для регистрации маршрутов совместного доступа
+//   - dependencies: содержит обработчики запросов (SharingHandler)
+//
+// Регистрируемые маршруты:
+//
+//	GET /{token} - перенаправление на страницу просмотра или WebSocket туннель к общей сессии
+//
+// Маршруты не требуют аутентификации: доступ определяется только токеном ссылки.
+func shareRouterGroup(share chi.Router) {
+	share.Get("/{token}", dependencies.SharingHandler.Redeem)
+}
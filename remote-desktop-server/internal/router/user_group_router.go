@@ -0,0 +1,29 @@
+// Package router предоставляет функциональность для настройки маршрутизации HTTP запросов.
+package router
+
+import "github.com/go-chi/chi/v5"
+
+// userGroupsRouterGroup регистрирует маршруты для работы с группами пользователей
+//
+// Регистрируемые маршруты:
+//
+//	GET    /                          - список групп
+//	POST   /                          - создание группы
+//	GET    /{id}                      - группа с участниками и вложенными группами
+//	PUT    /{id}                      - изменение группы
+//	DELETE /{id}                      - удаление группы
+//	POST   /{id}/members              - добавление пользователя в группу
+//	DELETE /{id}/members/{userId}     - удаление пользователя из группы
+//	POST   /{id}/subgroups            - вложение группы
+//	DELETE /{id}/subgroups/{childId}  - удаление вложенной группы
+func userGroupsRouterGroup(groups chi.Router) {
+	groups.Get("/", dependencies.UserGroupHandler.Get)
+	groups.Post("/", dependencies.UserGroupHandler.StoreGroup)
+	groups.Get("/{id}", dependencies.UserGroupHandler.Show)
+	groups.Put("/{id}", dependencies.UserGroupHandler.UpdateGroup)
+	groups.Delete("/{id}", dependencies.UserGroupHandler.RemoveGroup)
+	groups.Post("/{id}/members", dependencies.UserGroupHandler.StoreMember)
+	groups.Delete("/{id}/members/{userId}", dependencies.UserGroupHandler.RemoveMember)
+	groups.Post("/{id}/subgroups", dependencies.UserGroupHandler.StoreSubgroup)
+	groups.Delete("/{id}/subgroups/{childId}", dependencies.UserGroupHandler.RemoveSubgroup)
+}
@@ -3,6 +3,9 @@ package router
 
 import (
 	"github.com/go-chi/chi/v5"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/handler/middleware"
 )
 
 // usersRouterGroup регистрирует маршруты для работы с пользователями
@@ -14,6 +17,45 @@ import (
 // Регистрируемые маршруты:
 //
 //	GET /current - получение информации о текущем пользователе
+//	PATCH /current - изменение имени, электронной почты (с подтверждением по ссылке), языка и часового пояса
+//	PUT /current/password - смена пароля текущего пользователя
+//	GET /current/two-factor - состояние двухфакторной аутентификации
+//	POST /current/two-factor - выдача секрета TOTP
+//	POST /current/two-factor/enable - подтверждение секрета кодом, выдача кодов восстановления
+//	POST /current/two-factor/recovery-codes - замена кодов восстановления
+//	DELETE /current/two-factor - отключение TOTP
+//
+// Управление пользователями (разрешение users:manage):
+//
+//	GET    /                      - список пользователей (поиск, фильтры, пагинация)
+//	GET    /{id}                  - пользователь и его учетная запись Guacamole
+//	GET    /{id}/permissions      - разрешения пользователя в приложении и в Guacamole
+//	PUT    /{id}/role             - назначение роли
+//	POST   /{id}/disable          - отключение пользователя
+//	POST   /{id}/enable           - включение пользователя
+//	POST   /{id}/expire-password  - пометка пароля истекшим
+//	POST   /{id}/unlock           - снятие блокировки входа после неудачных попыток
+//	DELETE /{id}                  - удаление пользователя вместе с учетной записью Guacamole
 func usersRouterGroup(users chi.Router) {
 	users.Get("/current", dependencies.UserHandler.GetCurrentUser)
+	users.Patch("/current", dependencies.ProfileHandler.Update)
+	users.Put("/current/password", dependencies.PasswordHandler.Change)
+	users.Get("/current/two-factor", dependencies.TwoFactorHandler.Status)
+	users.Post("/current/two-factor", dependencies.TwoFactorHandler.Enroll)
+	users.Post("/current/two-factor/enable", dependencies.TwoFactorHandler.Enable)
+	users.Post("/current/two-factor/recovery-codes", dependencies.TwoFactorHandler.RegenerateRecoveryCodes)
+	users.Delete("/current/two-factor", dependencies.TwoFactorHandler.Disable)
+
+	users.Group(func(users chi.Router) {
+		users.Use(middleware.RequirePermission(common.PermissionManageUsers))
+		users.Get("/", dependencies.UserAdminHandler.Get)
+		users.Get("/{id}", dependencies.UserAdminHandler.Show)
+		users.Get("/{id}/permissions", dependencies.UserAdminHandler.Permissions)
+		users.Put("/{id}/role", dependencies.UserAdminHandler.UpdateRole)
+		users.Post("/{id}/disable", dependencies.UserAdminHandler.Disable)
+		users.Post("/{id}/enable", dependencies.UserAdminHandler.Enable)
+		users.Post("/{id}/expire-password", dependencies.UserAdminHandler.ExpirePassword)
+		users.Post("/{id}/unlock", dependencies.LoginProtectionHandler.UnlockUser)
+		users.Delete("/{id}", dependencies.UserAdminHandler.Remove)
+	})
 }
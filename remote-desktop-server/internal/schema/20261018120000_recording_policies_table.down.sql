@@ -0,0 +1 @@
+DROP TABLE recording_policies;
@@ -0,0 +1,6 @@
+CREATE TABLE recording_policies (
+    connection_id TEXT PRIMARY KEY,
+    retention_days INTEGER NOT NULL CHECK (retention_days > 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,2 @@
+DROP TABLE recording_commands;
+DROP TABLE indexed_recordings;
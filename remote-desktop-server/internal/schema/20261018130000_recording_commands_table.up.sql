@@ -0,0 +1,23 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE TABLE indexed_recordings (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    connection_id TEXT NOT NULL,
+    recording_name TEXT NOT NULL,
+    username TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMP NOT NULL,
+    size BIGINT NOT NULL,
+    indexed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (connection_id, recording_name)
+);
+
+CREATE TABLE recording_commands (
+    id BIGSERIAL PRIMARY KEY,
+    recording_id UUID NOT NULL REFERENCES indexed_recordings (id) ON DELETE CASCADE,
+    command TEXT NOT NULL,
+    offset_ms BIGINT NOT NULL,
+    executed_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX recording_commands_recording_id_idx ON recording_commands (recording_id);
+CREATE INDEX recording_commands_command_trgm_idx ON recording_commands USING GIN (command gin_trgm_ops);
@@ -0,0 +1,2 @@
+DROP TABLE share_link_uses;
+DROP TABLE share_links;
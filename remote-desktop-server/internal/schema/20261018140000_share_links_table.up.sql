@@ -0,0 +1,26 @@
+CREATE TABLE share_links (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    token_hash TEXT NOT NULL UNIQUE,
+    connection_id TEXT NOT NULL,
+    sharing_profile_id TEXT NOT NULL,
+    share_key TEXT NOT NULL,
+    created_by TEXT NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMP NOT NULL,
+    max_uses INTEGER,
+    uses INTEGER NOT NULL DEFAULT 0,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX share_links_connection_id_idx ON share_links (connection_id);
+
+CREATE TABLE share_link_uses (
+    id BIGSERIAL PRIMARY KEY,
+    link_id UUID NOT NULL REFERENCES share_links (id) ON DELETE CASCADE,
+    remote_addr TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    used_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX share_link_uses_link_id_idx ON share_link_uses (link_id);
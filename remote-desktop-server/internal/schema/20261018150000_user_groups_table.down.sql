@@ -0,0 +1,3 @@
+DROP TABLE user_group_subgroups;
+DROP TABLE user_group_members;
+DROP TABLE user_groups;
\ No newline at end of file
@@ -0,0 +1,26 @@
+CREATE TABLE user_groups (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE user_group_members (
+    group_id UUID NOT NULL REFERENCES user_groups (id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (group_id, user_id)
+);
+
+CREATE INDEX user_group_members_user_id_idx ON user_group_members (user_id);
+
+CREATE TABLE user_group_subgroups (
+    parent_id UUID NOT NULL REFERENCES user_groups (id) ON DELETE CASCADE,
+    child_id UUID NOT NULL REFERENCES user_groups (id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (parent_id, child_id),
+    CHECK (parent_id <> child_id)
+);
+
+CREATE INDEX user_group_subgroups_child_id_idx ON user_group_subgroups (child_id);
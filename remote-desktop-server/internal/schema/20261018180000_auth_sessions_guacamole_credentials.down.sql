@@ -0,0 +1,3 @@
+DROP INDEX auth_sessions_guacamole_token_idx;
+ALTER TABLE auth_sessions DROP COLUMN previous_guacamole_token;
+ALTER TABLE auth_sessions DROP COLUMN guacamole_credentials;
@@ -0,0 +1,4 @@
+ALTER TABLE auth_sessions ADD COLUMN guacamole_credentials BYTEA;
+ALTER TABLE auth_sessions ADD COLUMN previous_guacamole_token TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX auth_sessions_guacamole_token_idx ON auth_sessions (guacamole_token);
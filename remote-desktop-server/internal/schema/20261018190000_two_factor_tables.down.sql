@@ -0,0 +1,3 @@
+DROP TABLE auth_challenges;
+DROP TABLE recovery_codes;
+DROP TABLE user_totp;
@@ -0,0 +1,2 @@
+DROP TABLE oidc_login_states;
+ALTER TABLE users DROP COLUMN guacamole_credentials;
@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'operator'
+    CHECK (role IN ('admin', 'operator', 'viewer'));
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'viewer';
@@ -0,0 +1,2 @@
+DROP TABLE login_audit;
+DROP TABLE login_throttles;
@@ -0,0 +1,23 @@
+CREATE TABLE login_throttles (
+    scope TEXT NOT NULL CHECK (scope IN ('account', 'ip')),
+    key TEXT NOT NULL,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP,
+    PRIMARY KEY (scope, key)
+);
+
+CREATE INDEX login_throttles_locked_until_idx ON login_throttles (locked_until);
+
+CREATE TABLE login_audit (
+    id BIGSERIAL PRIMARY KEY,
+    event TEXT NOT NULL,
+    email TEXT NOT NULL DEFAULT '',
+    remote_addr TEXT NOT NULL DEFAULT '',
+    actor TEXT NOT NULL DEFAULT '',
+    detail TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX login_audit_email_idx ON login_audit (lower(email));
+CREATE INDEX login_audit_remote_addr_idx ON login_audit (remote_addr);
@@ -0,0 +1,3 @@
+ALTER TABLE share_links DROP COLUMN share_credentials;
+ALTER TABLE share_links ADD COLUMN share_key TEXT NOT NULL DEFAULT '';
+ALTER TABLE share_links ALTER COLUMN share_key DROP DEFAULT;
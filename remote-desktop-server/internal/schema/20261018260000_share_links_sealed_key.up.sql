@@ -0,0 +1,6 @@
+-- Ключ совместного доступа Guacamole хранится зашифрованным (GUAC_CREDENTIALS_KEY).
+-- Прежние ссылки хранили ключ открытым текстом: они отзываются, а ключи удаляются.
+UPDATE share_links SET revoked_at = CURRENT_TIMESTAMP WHERE revoked_at IS NULL;
+ALTER TABLE share_links DROP COLUMN share_key;
+ALTER TABLE share_links ADD COLUMN share_credentials BYTEA NOT NULL DEFAULT '';
+ALTER TABLE share_links ALTER COLUMN share_credentials DROP DEFAULT;
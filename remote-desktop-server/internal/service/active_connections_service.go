@@ -0,0 +1,178 @@
+// Package service реализует бизнес-логику приложения.
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+)
+
+// activeConnectionsURL путь API Guacamole для работы с активными подключениями
+const activeConnectionsURL = "session/data/postgresql/activeConnections"
+
+// ActiveConnectionService предоставляет методы для мониторинга и принудительного
+// завершения активных подключений через API Guacamole.
+type ActiveConnectionService struct {
+	sessions *SessionService
+}
+
+// NewActiveConnectionService создает и возвращает новый экземпляр ActiveConnectionService.
+//
+// Параметры:
+//   - sessions: сервис подключений, через который выполняются запросы к Guacamole
+//
+// Возвращает:
+//   - *ActiveConnectionService: указатель на созданный сервис
+func NewActiveConnectionService(sessions *SessionService) *ActiveConnectionService {
+	return &ActiveConnectionService{
+		sessions: sessions,
+	}
+}
+
+// GetActiveConnections возвращает список активных подключений.
+// Администратор Guacamole видит все подключения, остальные пользователи — только свои.
+//
+// Параметры:
+//   - username: имя пользователя Guacamole (email) вызывающего
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - []*common.ActiveConnectionResponse: активные подключения, отсортированные по времени начала
+//   - error: ошибка, если не удалось получить данные
+func (service *ActiveConnectionService) GetActiveConnections(
+	username string,
+	guacToken string,
+) ([]*common.ActiveConnectionResponse, error) {
+	isAdmin, err := service.sessions.isAdministrator(guacToken)
+	if err != nil {
+		return nil, err
+	}
+	active, err := service.fetchActiveConnections(guacToken)
+	if err != nil {
+		return nil, err
+	}
+	names, err := service.connectionNames(guacToken)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*common.ActiveConnectionResponse, 0, len(active))
+	for _, conn := range active {
+		if !isAdmin && conn.Username != username {
+			continue
+		}
+		result = append(result, &common.ActiveConnectionResponse{
+			ID:             conn.Identifier,
+			ConnectionID:   conn.ConnectionIdentifier,
+			ConnectionName: names[conn.ConnectionIdentifier],
+			Username:       conn.Username,
+			RemoteHost:     conn.RemoteHost,
+			StartedAt:      time.UnixMilli(conn.StartDate),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].StartedAt.Before(result[j].StartedAt)
+	})
+	return result, nil
+}
+
+// KillActiveConnection принудительно завершает активное подключение.
+// Пользователь может завершить только свое подключение, администратор — любое.
+//
+// Параметры:
+//   - id: идентификатор активного подключения
+//   - username: имя пользователя Guacamole (email) вызывающего
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - error: ErrNotFound, если подключение не найдено; ErrForbidden, если подключение чужое
+func (service *ActiveConnectionService) KillActiveConnection(id string, username string, guacToken string) error {
+	active, err := service.fetchActiveConnections(guacToken)
+	if err != nil {
+		return err
+	}
+	conn, ok := active[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if conn.Username != username {
+		isAdmin, err := service.sessions.isAdministrator(guacToken)
+		if err != nil {
+			return err
+		}
+		if !isAdmin {
+			return ErrForbidden
+		}
+	}
+
+	// Guacamole завершает подключения через JSON Patch с операцией remove
+	patch := []map[string]string{
+		{
+			"op":   "remove",
+			"path": fmt.Sprintf("/%s", id),
+		},
+	}
+	if err := service.sessions.makeGuacamoleRequest(
+		http.MethodPatch,
+		activeConnectionsURL,
+		guacToken,
+		patch,
+		nil,
+	); err != nil {
+		return fmt.Errorf("failed to kill active connection: %w", err)
+	}
+	return nil
+}
+
+// fetchActiveConnections получает активные подключения, видимые владельцу токена.
+//
+// Параметры:
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - map[string]*common.GuacamoleActiveConnection: активные подключения по идентификатору
+//   - error: ошибка, если не удалось получить данные
+func (service *ActiveConnectionService) fetchActiveConnections(
+	guacToken string,
+) (map[string]*common.GuacamoleActiveConnection, error) {
+	var active map[string]*common.GuacamoleActiveConnection
+	if err := service.sessions.makeGuacamoleRequest(
+		http.MethodGet,
+		activeConnectionsURL,
+		guacToken,
+		nil,
+		&active,
+	); err != nil {
+		return nil, fmt.Errorf("failed to fetch active connections: %w", err)
+	}
+	return active, nil
+}
+
+// connectionNames возвращает названия подключений, доступных владельцу токена.
+//
+// Параметры:
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - map[string]string: названия подключений по идентификатору
+//   - error: ошибка, если не удалось получить данные
+func (service *ActiveConnectionService) connectionNames(guacToken string) (map[string]string, error) {
+	var connections map[string]*common.GuacamoleRDConnectionResponse
+	if err := service.sessions.makeGuacamoleRequest(
+		http.MethodGet,
+		connectionsURL,
+		guacToken,
+		nil,
+		&connections,
+	); err != nil {
+		return nil, fmt.Errorf("failed to fetch connections: %w", err)
+	}
+	names := make(map[string]string, len(connections))
+	for id, conn := range connections {
+		names[id] = conn.Name
+	}
+	return names, nil
+}
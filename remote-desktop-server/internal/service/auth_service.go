@@ -10,12 +10,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
 	"github.com/margar-melkonyan/remote-desktop.git/internal/config"
 	"github.com/margar-melkonyan/remote-desktop.git/internal/repository"
@@ -26,6 +28,11 @@ import (
 type AuthService struct {
 	repoAuth      repository.UserRepository
 	repoGuacamole repository.GuacamoleRepository
+	tokens        *TokenService
+	twoFactor     *TwoFactorService
+	verification  *EmailVerificationService
+	protection    *LoginProtectionService
+	authenticator Authenticator
 }
 
 // NewAuthService создает новый экземпляр AuthService.
@@ -33,24 +40,47 @@ type AuthService struct {
 // Параметры:
 //   - repoAuth: репозиторий для работы с пользователями
 //   - repoGuacamole: репозиторий для работы с Guacamole
+//   - tokens: сервис токенов сессий входа
+//   - twoFactor: сервис двухфакторной аутентификации
+//   - verification: сервис подтверждения электронной почты
+//   - protection: защита входа от подбора пароля
+//   - authenticator: способ проверки учетных данных при входе (обычно AuthenticatorChain)
 //
 // Возвращает:
 //   - *AuthService: указатель на созданный сервис
 func NewAuthService(
 	repoAuth repository.UserRepository,
 	repoGuacamole repository.GuacamoleRepository,
+	tokens *TokenService,
+	twoFactor *TwoFactorService,
+	verification *EmailVerificationService,
+	protection *LoginProtectionService,
+	authenticator Authenticator,
 ) *AuthService {
 	return &AuthService{
 		repoAuth:      repoAuth,
 		repoGuacamole: repoGuacamole,
+		tokens:        tokens,
+		twoFactor:     twoFactor,
+		verification:  verification,
+		protection:    protection,
+		authenticator: authenticator,
 	}
 }
 
 // Claims представляет структуру JWT токена с информацией о пользователе.
+// Version совпадает с версией токенов пользователя на момент выдачи:
+// после смены пароля версия увеличивается и ранее выданные токены перестают действовать.
+// SessionID — сессия входа, при ее отзыве (выход) токен также перестает действовать.
+// Role — роль пользователя на момент выдачи для клиента; права проверяются по текущей роли
+// пользователя в базе, поэтому смена роли действует сразу, без повторного входа.
 type Claims struct {
 	Sub struct {
 		Email string `json:"email"`
 	} `json:"sub"`
+	Version   int    `json:"ver"`
+	SessionID string `json:"sid"`
+	Role      string `json:"role"`
 	jwt.RegisteredClaims
 }
 
@@ -74,42 +104,101 @@ func CheckTokenIsNotExpired(token string) (*Claims, error) {
 }
 
 // SignIn выполняет аутентификацию пользователя.
+// Учетные данные проверяются способами из AUTH_BACKENDS (база приложения, LDAP)
+// только если для учетной записи и адреса клиента не действует задержка или блокировка
+// после неудачных попыток (LoginProtectionService); неудачи учетной записи сбрасываются,
+// только когда вход завершен.
+// Если у пользователя подключен TOTP (или TOTP для него обязателен),
+// вместо токенов возвращается токен второго шага входа (POST /auth/two-factor).
 //
 // Параметры:
 //   - ctx: контекст
 //   - form: данные для входа (email и пароль)
+//   - remoteAddr: адрес соединения клиента
+//   - forwardedFor: заголовок X-Forwarded-For (учитывается при LOGIN_TRUST_FORWARDED_FOR=true)
 //
 // Возвращает:
-//   - map[string]string: содержит JWT токен
+//   - *common.AuthSignInResponse: JWT токен доступа и refresh токен либо токен второго шага
 //   - error: ошибки:
-//   - "password is not valid" - неверный пароль
-//   - "user not found" - пользователь не найден
+//   - *LoginThrottledError (ErrTooManyAttempts) - попытка отклонена до проверки пароля
+//   - ErrInvalidPassword - неверный пароль или пользователь не найден
+//   - ErrEmailNotVerified - пользователь не подтвердил электронную почту
+//   - недоступность способа проверки учетных данных
 //   - ошибки генерации токена
-func (service *AuthService) SignIn(ctx context.Context, form common.AuthSignInRequest) (map[string]string, error) {
-	currentUser, err := service.repoAuth.FindByEmail(ctx, form.Email)
+func (service *AuthService) SignIn(
+	ctx context.Context,
+	form common.AuthSignInRequest,
+	remoteAddr string,
+	forwardedFor string,
+) (*common.AuthSignInResponse, error) {
+	remoteAddr = service.protection.ClientAddr(remoteAddr, forwardedFor)
+	attempt, err := service.protection.Begin(ctx, form.Email, remoteAddr)
 	if err != nil {
 		return nil, err
 	}
-	if err = bcrypt.CompareHashAndPassword([]byte(currentUser.Password), []byte(strings.TrimSpace(form.Password))); err != nil {
-		return nil, errors.New("password is not valid")
+	currentUser, err := service.authenticator.Authenticate(ctx, form.Email, form.Password)
+	if errors.Is(err, ErrInvalidPassword) {
+		service.protection.Failed(ctx, attempt, "")
+		return nil, err
 	}
-
-	accessToken, err := getToken(*currentUser)
 	if err != nil {
+		service.protection.Cancel(ctx, attempt)
 		return nil, err
 	}
 
-	guacToken, err := getGuacamoleToken(form)
+	challenge, err := service.twoFactor.Begin(ctx, currentUser, form.Password)
 	if err != nil {
+		service.protection.Cancel(ctx, attempt)
 		return nil, err
 	}
-	return map[string]string{
-		"token":      accessToken,
-		"guac_token": guacToken,
-	}, nil
+	if challenge != nil {
+		// Вход завершится вторым шагом: неудачи учетной записи сбросит TwoFactorService.Complete
+		service.protection.Cancel(ctx, attempt)
+		return &common.AuthSignInResponse{TwoFactor: challenge}, nil
+	}
+	tokens, err := service.tokens.Issue(ctx, currentUser, form.Password)
+	if err != nil {
+		service.protection.Cancel(ctx, attempt)
+		return nil, err
+	}
+	service.protection.Succeeded(ctx, attempt)
+	return &common.AuthSignInResponse{AuthTokens: tokens}, nil
+}
+
+// Refresh обменивает refresh токен на новую пару токенов.
+//
+// Параметры:
+//   - ctx: контекст
+//   - form: refresh токен
+//
+// Возвращает:
+//   - *common.AuthTokens: новые токены
+//   - error: ErrInvalidToken или ошибка обновления
+func (service *AuthService) Refresh(ctx context.Context, form common.AuthRefreshRequest) (*common.AuthTokens, error) {
+	return service.tokens.Refresh(ctx, form.RefreshToken)
 }
 
-// SignUp регистрирует нового пользователя.
+// SignOut завершает сессию входа: отзывает все ее refresh токены и токен Guacamole.
+//
+// Параметры:
+//   - ctx: контекст
+//   - form: refresh токен сессии
+//
+// Возвращает:
+//   - error: ErrInvalidToken или ошибка отзыва сессии
+func (service *AuthService) SignOut(ctx context.Context, form common.AuthRefreshRequest) error {
+	return service.tokens.Logout(ctx, form.RefreshToken)
+}
+
+// SignUp регистрирует нового пользователя с ролью AUTH_DEFAULT_ROLE.
+// Регистрация затрагивает две базы данных и выполняется как сага:
+//  1. создание отключенного пользователя Guacamole (одна транзакция в базе Guacamole);
+//  2. создание пользователя в базе приложения.
+//
+// Если второй шаг завершается ошибкой, созданный пользователь Guacamole удаляется.
+// Затем на электронную почту отправляется ссылка для ее подтверждения: до перехода по ссылке
+// вход запрещен, а учетная запись Guacamole отключена. Если письмо отправить не удалось,
+// регистрация не отменяется — письмо можно запросить повторно (POST /auth/resend-verification).
 //
 // Параметры:
 //   - ctx: контекст
@@ -121,26 +210,46 @@ func (service *AuthService) SignIn(ctx context.Context, form common.AuthSignInRe
 //   - ошибки хеширования пароля
 //   - ошибки создания пользователя
 func (service *AuthService) SignUp(ctx context.Context, form common.AuthSignUpRequest) error {
-	if _, err := service.repoAuth.FindByEmail(ctx, form.Email); err == nil {
-		return errors.New("user with this email already exists")
+	role := config.ServerConfig.AuthConfig.DefaultRole
+	if err := registerUser(ctx, service.repoAuth, service.repoGuacamole, form, role, false); err != nil {
+		return err
 	}
-	id, err := service.repoGuacamole.CreateEntity(ctx, form.Email)
+
+	user, err := service.repoAuth.FindByEmail(ctx, form.Email)
 	if err != nil {
 		return err
 	}
-	saultHex := getGuacamoleSault()
-	hashedGuacamolePasswordHex := getHashedGuacamolePassword(form.Password, saultHex)
-	err = service.repoGuacamole.CreateUserAndPermissions(ctx, common.GuacamoleUser{
-		ID:          id,
-		PasswordHex: hashedGuacamolePasswordHex,
-		SaultHex:    saultHex,
-		Permissions: []string{
-			"CREATE_CONNECTION",
-		},
-	})
+	if err := service.verification.Send(ctx, user, user.Email); err != nil {
+		slog.Error(fmt.Sprintf("Error sending email verification to %s: %s", user.Email, err.Error()))
+	}
+	return nil
+}
+
+// registerUser создает пользователя приложения с ролью role и пользователя Guacamole
+// с системными разрешениями этой роли (см. AuthService.SignUp).
+// Пока электронная почта не подтверждена (verified), учетная запись Guacamole отключена.
+// Используется также при первом входе через внешний провайдер, который сам подтверждает почту.
+func registerUser(
+	ctx context.Context,
+	users repository.UserRepository,
+	guacamole repository.GuacamoleRepository,
+	form common.AuthSignUpRequest,
+	role string,
+	verified bool,
+) error {
+	if _, err := users.FindByEmail(ctx, form.Email); err == nil {
+		return errors.New("user with this email already exists")
+	}
+	entity, err := guacamole.FindEntity(ctx, form.Email, common.EntityTypeUser)
 	if err != nil {
 		return err
 	}
+	if entity != nil {
+		return errors.New("user with this email already exists")
+	}
+
+	saultHex := getGuacamoleSault()
+	hashedGuacamolePasswordHex := getHashedGuacamolePassword(form.Password, saultHex)
 	password, err := bcrypt.GenerateFromPassword(
 		[]byte(strings.TrimSpace(form.Password)),
 		config.ServerConfig.BcryptPower,
@@ -150,18 +259,43 @@ func (service *AuthService) SignUp(ctx context.Context, form common.AuthSignUpRe
 	}
 	form.Password = string(password)
 
-	return service.repoAuth.Create(ctx, form)
+	return newSaga("sign-up").
+		step(
+			"create guacamole user",
+			func(ctx context.Context) error {
+				_, err := guacamole.CreateUser(ctx, common.GuacamoleUser{
+					Username:    form.Email,
+					PasswordHex: hashedGuacamolePasswordHex,
+					SaultHex:    saultHex,
+					Permissions: common.RoleGuacamolePermissions(role),
+					Disabled:    !verified,
+				})
+				return err
+			},
+			func(ctx context.Context) error {
+				return guacamole.DeleteEntity(ctx, form.Email, common.EntityTypeUser)
+			},
+		).
+		step(
+			"create user",
+			func(ctx context.Context) error {
+				return users.Create(ctx, form, role, verified)
+			},
+			nil,
+		).
+		run(ctx)
 }
 
 // getToken генерирует JWT токен для пользователя.
 //
 // Параметры:
 //   - user: данные пользователя
+//   - sessionID: сессия входа, к которой относится токен
 //
 // Возвращает:
 //   - string: JWT токен
 //   - error: ошибки генерации токена
-func getToken(user common.User) (string, error) {
+func getToken(user common.User, sessionID uuid.UUID) (string, error) {
 	seconds := config.ServerConfig.JWTConfig.AccessTokenTTL
 	duration, err := time.ParseDuration(seconds)
 
@@ -172,7 +306,10 @@ func getToken(user common.User) (string, error) {
 		"sub": map[string]interface{}{
 			"email": user.Email,
 		},
-		"exp": time.Now().Add(time.Duration(duration)).Unix(),
+		"ver":  user.TokenVersion,
+		"sid":  sessionID.String(),
+		"role": user.Role,
+		"exp":  time.Now().Add(time.Duration(duration)).Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
@@ -232,6 +369,7 @@ func getHashedGuacamolePassword(password string, saltHex string) string {
 	return strings.ToUpper(hex.EncodeToString(hash[:]))
 }
 
+// getGuacamoleToken аутентифицирует пользователя в Guacamole (POST /tokens) и возвращает токен Guacamole
 func getGuacamoleToken(form common.AuthSignInRequest) (string, error) {
 	formData := url.Values{}
 	formData.Set("username", form.Email)
@@ -249,6 +387,9 @@ func getGuacamoleToken(form common.AuthSignInRequest) (string, error) {
 		return "", fmt.Errorf("HTTP request failed: %w", err)
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusForbidden && guacamolePasswordExpired(resp.Body) {
+		return "", ErrPasswordExpired
+	}
 	if resp.StatusCode != http.StatusOK {
 		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
@@ -265,3 +406,70 @@ func getGuacamoleToken(form common.AuthSignInRequest) (string, error) {
 
 	return authResp.AuthToken, nil
 }
+
+// guacamolePasswordExpired проверяет, что Guacamole отказал во входе из-за истекшего пароля:
+// в этом случае среди ожидаемых полей ответа есть поле нового пароля
+func guacamolePasswordExpired(body io.Reader) bool {
+	var failure struct {
+		Expected []struct {
+			Name string `json:"name"`
+		} `json:"expected"`
+	}
+	if err := json.NewDecoder(body).Decode(&failure); err != nil {
+		return false
+	}
+	for _, field := range failure.Expected {
+		if field.Name == "new-password" {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteGuacamoleToken завершает сессию Guacamole (DELETE /tokens/{token})
+func deleteGuacamoleToken(token string) error {
+	req, err := http.NewRequest(
+		http.MethodDelete,
+		fmt.Sprintf(
+			"%s/tokens/%s",
+			config.ServerConfig.GuacamoleAPIURL,
+			url.PathEscape(token),
+		),
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// checkGuacamoleToken проверяет, действует ли токен Guacamole.
+// Guacamole возвращает тот же токен, если он передан в POST /tokens и еще не истек.
+func checkGuacamoleToken(token string) (bool, error) {
+	formData := url.Values{}
+	formData.Set("token", token)
+	resp, err := http.Post(
+		fmt.Sprintf("%s/%s", config.ServerConfig.GuacamoleAPIURL, "tokens"),
+		"application/x-www-form-urlencoded",
+		strings.NewReader(formData.Encode()),
+	)
+	if err != nil {
+		return false, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return false, nil
+	}
+	return false, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+}
@@ -0,0 +1,161 @@
+// Package service реализует бизнес-логику приложения.
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/config"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrUnknownUser возвращается способом проверки, которому пользователь неизвестен:
+// цепочка в этом случае переходит к следующему способу
+var ErrUnknownUser = errors.New("user is unknown to authenticator")
+
+// Authenticator проверяет учетные данные при входе по паролю.
+//
+// Authenticate возвращает пользователя приложения (при необходимости созданного),
+// ErrUnknownUser, если пользователь этому способу неизвестен, ErrInvalidPassword
+// при неверном пароле или другую ошибку, если проверку выполнить не удалось.
+type Authenticator interface {
+	// Name возвращает название способа для журнала
+	Name() string
+
+	// Authenticate проверяет email и пароль пользователя
+	Authenticate(ctx context.Context, email string, password string) (*common.User, error)
+}
+
+// AuthenticatorChain опрашивает способы проверки по порядку до первого успешного.
+// Неверный пароль или ошибка одного способа не прерывают опрос: пользователь
+// с учетной записью в базе приложения может войти, даже если каталог LDAP недоступен.
+type AuthenticatorChain []Authenticator
+
+// NewAuthenticators создает цепочку способов проверки из AUTH_BACKENDS.
+//
+// Параметры:
+//   - users: репозиторий пользователей
+//   - guacamole: репозиторий Guacamole (создание пользователей внешних каталогов)
+//   - tokens: сервис токенов (отзыв сессий неподтвержденной учетной записи при первом входе через каталог)
+//
+// Возвращает:
+//   - AuthenticatorChain: способы проверки в порядке опроса
+func NewAuthenticators(
+	users repository.UserRepository,
+	guacamole repository.GuacamoleRepository,
+	tokens *TokenService,
+) AuthenticatorChain {
+	chain := make(AuthenticatorChain, 0, len(config.ServerConfig.AuthConfig.Backends))
+	for _, backend := range config.ServerConfig.AuthConfig.Backends {
+		switch backend {
+		case "local":
+			chain = append(chain, NewLocalAuthenticator(users))
+		case "ldap":
+			chain = append(chain, NewLDAPAuthenticator(config.ServerConfig.LDAPConfig, users, guacamole, tokens))
+		}
+	}
+	return chain
+}
+
+// Name возвращает названия способов цепочки
+func (chain AuthenticatorChain) Name() string {
+	names := make([]string, 0, len(chain))
+	for _, authenticator := range chain {
+		names = append(names, authenticator.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+// Authenticate проверяет учетные данные способами цепочки.
+//
+// Параметры:
+//   - ctx: контекст
+//   - email: электронная почта
+//   - password: пароль
+//
+// Возвращает:
+//   - *common.User: пользователь, подтвержденный первым успешным способом
+//   - error: ErrEmailNotVerified, если пароль верен, но пользователь не подтвердил электронную почту,
+//     ErrInvalidPassword, если ни один способ не подтвердил учетные данные
+//     (в том числе если пользователь неизвестен), или ошибка о недоступности способа,
+//     проверку которым выполнить не удалось (подробности пишутся в журнал)
+func (chain AuthenticatorChain) Authenticate(ctx context.Context, email string, password string) (*common.User, error) {
+	var failed string
+	var unverified bool
+	for _, authenticator := range chain {
+		user, err := authenticator.Authenticate(ctx, email, password)
+		switch {
+		case err == nil:
+			return user, nil
+		case errors.Is(err, ErrEmailNotVerified):
+			unverified = true
+			continue
+		case errors.Is(err, ErrUnknownUser), errors.Is(err, ErrInvalidPassword):
+			continue
+		}
+		slog.Error(fmt.Sprintf("Error authenticating %s with %s: %s", email, authenticator.Name(), err.Error()))
+		failed = authenticator.Name()
+	}
+	if unverified {
+		return nil, ErrEmailNotVerified
+	}
+	if failed != "" {
+		return nil, fmt.Errorf("%s authentication is unavailable", failed)
+	}
+	return nil, ErrInvalidPassword
+}
+
+// LocalAuthenticator проверяет пароль по хэшу bcrypt в базе приложения
+type LocalAuthenticator struct {
+	users repository.UserRepository
+}
+
+// NewLocalAuthenticator создает способ проверки пароля в базе приложения.
+//
+// Параметры:
+//   - users: репозиторий пользователей
+//
+// Возвращает:
+//   - *LocalAuthenticator: указатель на созданный способ проверки
+func NewLocalAuthenticator(users repository.UserRepository) *LocalAuthenticator {
+	return &LocalAuthenticator{users: users}
+}
+
+// Name возвращает название способа для журнала
+func (authenticator *LocalAuthenticator) Name() string {
+	return "local"
+}
+
+// Authenticate проверяет пароль пользователя приложения.
+//
+// Параметры:
+//   - ctx: контекст
+//   - email: электронная почта
+//   - password: пароль
+//
+// Возвращает:
+//   - *common.User: пользователь
+//   - error: ErrUnknownUser, ErrInvalidPassword, ErrEmailNotVerified (пароль верен,
+//     но электронная почта не подтверждена) или ошибка чтения пользователя
+func (authenticator *LocalAuthenticator) Authenticate(ctx context.Context, email string, password string) (*common.User, error) {
+	user, err := authenticator.users.FindByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUnknownUser
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(strings.TrimSpace(password))); err != nil {
+		return nil, ErrInvalidPassword
+	}
+	if user.EmailVerifiedAt == nil {
+		return nil, ErrEmailNotVerified
+	}
+	return user, nil
+}
@@ -0,0 +1,331 @@
+// Package service реализует бизнес-логику приложения.
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/config"
+)
+
+// Атрибуты подключения, которыми монитор отмечает отключенного участника
+const (
+	disabledWeight          = "0"                         // Вес, при котором Guacamole не выбирает подключение в группе балансировки
+	defaultWeight           = "1"                         // Вес, который Guacamole использует, если атрибут weight не задан
+	originalWeightAttribute = "balancing-original-weight" // Вес участника до отключения монитором
+)
+
+// BalancingService управляет группами балансировки Guacamole и проверяет доступность их участников.
+//
+// Участник, не прошедший подряд FailureThreshold TCP проверок, отключается установкой веса 0,
+// а исходный вес сохраняется в атрибуте подключения balancing-original-weight.
+// После первой успешной проверки монитор возвращает участнику исходный вес и удаляет атрибут.
+// Состояние отключения хранится в Guacamole, поэтому переживает перезапуск сервера,
+// а участник, которому вес 0 задали вручную, монитором не восстанавливается.
+// В памяти хранится только счетчик неудачных проверок подряд.
+type BalancingService struct {
+	sessions *SessionService
+	groups   *ConnectionGroupService
+	dialer   net.Dialer
+
+	mu       sync.Mutex
+	failures map[string]int // Неудачные проверки монитора подряд по идентификатору подключения
+}
+
+// NewBalancingService создает и возвращает новый экземпляр BalancingService.
+//
+// Параметры:
+//   - sessions: сервис подключений
+//   - groups: сервис групп подключений
+//
+// Возвращает:
+//   - *BalancingService: указатель на созданный сервис
+func NewBalancingService(sessions *SessionService, groups *ConnectionGroupService) *BalancingService {
+	return &BalancingService{
+		sessions: sessions,
+		groups:   groups,
+		dialer: net.Dialer{
+			Timeout: config.ServerConfig.BalancingConfig.ProbeTimeout,
+		},
+		failures: make(map[string]int),
+	}
+}
+
+// CreatePool создает группу балансировки и подключения-участники внутри нее.
+// Если создать участника не удалось, группа удаляется вместе с уже созданными участниками.
+//
+// Параметры:
+//   - form: данные группы и ее участников
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - *common.GuacamoleConnectionGroup: созданная группа
+//   - error: ошибка, если не удалось создать группу или участников
+func (service *BalancingService) CreatePool(
+	form *common.BalancingGroupRequest,
+	guacToken string,
+) (*common.GuacamoleConnectionGroup, error) {
+	group, err := service.groups.CreateGroup(&common.GuacamoleConnectionGroupRequest{
+		Name:                  form.Name,
+		ParentIdentifier:      form.ParentIdentifier,
+		Type:                  common.ConnectionGroupBalancing,
+		EnableSessionAffinity: form.EnableSessionAffinity,
+	}, guacToken)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, member := range form.Members {
+		member.ParentIdentifier = group.Id
+		if err := service.sessions.CreateConnection(member, guacToken); err != nil {
+			if destroyErr := service.groups.DestroyGroup(group.Id, guacToken); destroyErr != nil {
+				slog.Error(fmt.Sprintf("Error removing incomplete balancing group %s: %s", group.Id, destroyErr.Error()))
+			}
+			return nil, fmt.Errorf("failed to create balancing group member %q: %w", member.Name, err)
+		}
+	}
+	return group, nil
+}
+
+// Health проверяет доступность всех участников группы балансировки.
+// Проверка не меняет вес участников и счетчики неудачных проверок монитора:
+// в ответе возвращается счетчик последних проверок монитора.
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//   - id: идентификатор группы
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - *common.BalancingGroupHealth: состояние участников группы
+//   - error: ошибка, если группа не найдена или не является группой балансировки
+func (service *BalancingService) Health(
+	ctx context.Context,
+	id string,
+	guacToken string,
+) (*common.BalancingGroupHealth, error) {
+	group, err := service.groups.GetGroupTree(id, guacToken)
+	if err != nil {
+		return nil, err
+	}
+	if group.Type != common.ConnectionGroupBalancing {
+		return nil, fmt.Errorf("connection group %s is not a balancing group", id)
+	}
+	return service.checkGroup(ctx, group, guacToken, false), nil
+}
+
+// Run запускает фоновую проверку всех групп балансировки, доступных служебной учетной записи.
+// Завершается при отмене контекста.
+//
+// Параметры:
+//   - ctx: контекст, определяющий время работы монитора
+func (service *BalancingService) Run(ctx context.Context) {
+	interval := config.ServerConfig.BalancingConfig.ProbeInterval
+	if interval <= 0 {
+		slog.Info("Balancing health monitor is disabled")
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := service.sessions.withServiceToken(func(guacToken string) error {
+				tree, err := service.sessions.fetchConnections(guacToken)
+				if err != nil {
+					return err
+				}
+				for _, group := range balancingGroups(tree) {
+					service.checkGroup(ctx, group, guacToken, true)
+				}
+				return nil
+			})
+			if err != nil {
+				slog.Error(fmt.Sprintf("Error checking balancing groups: %s", err.Error()))
+			}
+		}
+	}
+}
+
+// checkGroup проверяет участников группы и при необходимости отключает или восстанавливает их.
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//   - group: группа балансировки с подключениями
+//   - guacToken: токен аутентификации Guacamole
+//   - enforce: проверка монитора: учитывать ее в счетчиках и изменять вес участников
+//
+// Возвращает:
+//   - *common.BalancingGroupHealth: состояние участников группы
+func (service *BalancingService) checkGroup(
+	ctx context.Context,
+	group *common.GuacamoleConnectionGroup,
+	guacToken string,
+	enforce bool,
+) *common.BalancingGroupHealth {
+	result := &common.BalancingGroupHealth{
+		Id:      group.Id,
+		Name:    group.Name,
+		Members: make([]*common.BalancingMemberHealth, 0, len(group.ChildConnections)),
+	}
+	for _, conn := range group.ChildConnections {
+		health := service.checkMember(ctx, conn, guacToken, enforce)
+		result.Members = append(result.Members, health)
+	}
+	return result
+}
+
+// checkMember проверяет доступность участника по TCP.
+// Счетчик неудачных проверок подряд обновляют только проверки монитора.
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//   - conn: подключение-участник группы
+//   - guacToken: токен аутентификации Guacamole
+//   - enforce: проверка монитора: учитывать ее в счетчике и изменять вес участника
+//
+// Возвращает:
+//   - *common.BalancingMemberHealth: результат проверки
+func (service *BalancingService) checkMember(
+	ctx context.Context,
+	conn *common.GuacamoleRDConnectionResponse,
+	guacToken string,
+	enforce bool,
+) *common.BalancingMemberHealth {
+	health := common.BalancingMemberHealth{
+		ConnectionID: conn.ID,
+		Name:         conn.Name,
+		CheckedAt:    time.Now(),
+	}
+
+	connection, err := service.sessions.getConnection(conn.ID, guacToken)
+	if err != nil {
+		health.Error = err.Error()
+		return &health
+	}
+	health.Address = net.JoinHostPort(connection.Parameters["hostname"], connection.Parameters["port"])
+	health.Disabled = connection.Attributes["weight"] == disabledWeight
+
+	start := time.Now()
+	probe, err := service.dialer.DialContext(ctx, "tcp", health.Address)
+	if err == nil {
+		probe.Close()
+		health.Reachable = true
+		health.LatencyMs = time.Since(start).Milliseconds()
+	} else {
+		health.Error = err.Error()
+	}
+
+	service.mu.Lock()
+	if enforce {
+		if health.Reachable {
+			delete(service.failures, conn.ID)
+		} else {
+			service.failures[conn.ID]++
+		}
+	}
+	health.ConsecutiveFailures = service.failures[conn.ID]
+	service.mu.Unlock()
+
+	if enforce {
+		service.enforce(conn.ID, disabledByMonitor(connection), &health, guacToken)
+	}
+	return &health
+}
+
+// enforce отключает участника после FailureThreshold неудачных проверок подряд
+// и восстанавливает исходный вес после успешной проверки.
+// Состояние участника перечитывается при изменении, поэтому ручное изменение веса
+// между проверкой и сохранением не перезаписывается.
+//
+// Параметры:
+//   - id: идентификатор подключения
+//   - disabled: отключен ли участник монитором на момент проверки
+//   - health: результат последней проверки
+//   - guacToken: токен аутентификации Guacamole
+func (service *BalancingService) enforce(
+	id string,
+	disabled bool,
+	health *common.BalancingMemberHealth,
+	guacToken string,
+) {
+	threshold := config.ServerConfig.BalancingConfig.FailureThreshold
+
+	switch {
+	case !disabled && !health.Reachable && health.ConsecutiveFailures >= threshold:
+		err := service.sessions.modifyConnection(id, guacToken, func(connection *common.GuacamoleRDConnectionRequest) {
+			weight := connection.Attributes["weight"]
+			if weight == disabledWeight {
+				return
+			}
+			if weight == "" {
+				weight = defaultWeight
+			}
+			connection.Attributes[originalWeightAttribute] = weight
+			connection.Attributes["weight"] = disabledWeight
+		})
+		if err != nil {
+			slog.Error(fmt.Sprintf("Error disabling balancing member %s: %s", id, err.Error()))
+			return
+		}
+		health.Disabled = true
+		slog.Warn(
+			"Balancing member disabled",
+			slog.String("connection", id),
+			slog.String("address", health.Address),
+			slog.Int("failures", health.ConsecutiveFailures),
+		)
+	case disabled && health.Reachable:
+		err := service.sessions.modifyConnection(id, guacToken, func(connection *common.GuacamoleRDConnectionRequest) {
+			if !disabledByMonitor(connection) {
+				return
+			}
+			connection.Attributes["weight"] = connection.Attributes[originalWeightAttribute]
+			// Guacamole сохраняет атрибуты, отсутствующие в запросе, поэтому атрибут очищается явно
+			connection.Attributes[originalWeightAttribute] = ""
+		})
+		if err != nil {
+			slog.Error(fmt.Sprintf("Error restoring balancing member %s: %s", id, err.Error()))
+			return
+		}
+		health.Disabled = false
+		slog.Info(
+			"Balancing member restored",
+			slog.String("connection", id),
+			slog.String("address", health.Address),
+		)
+	}
+}
+
+// disabledByMonitor проверяет, отключен ли участник монитором: вес 0 и сохранен исходный вес
+func disabledByMonitor(connection *common.GuacamoleRDConnectionRequest) bool {
+	return connection.Attributes["weight"] == disabledWeight &&
+		connection.Attributes[originalWeightAttribute] != "" &&
+		connection.Attributes[originalWeightAttribute] != disabledWeight
+}
+
+// balancingGroups рекурсивно собирает все группы балансировки дерева подключений.
+//
+// Параметры:
+//   - group: корневая группа дерева
+//
+// Возвращает:
+//   - []*common.GuacamoleConnectionGroup: найденные группы балансировки
+func balancingGroups(group *common.GuacamoleConnectionGroup) []*common.GuacamoleConnectionGroup {
+	result := make([]*common.GuacamoleConnectionGroup, 0)
+	if group.Type == common.ConnectionGroupBalancing {
+		result = append(result, group)
+	}
+	for _, child := range group.ChildConnectionGroups {
+		result = append(result, balancingGroups(child)...)
+	}
+	return result
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/config"
+)
+
+// fakeBalancingGuacamole эмулирует API Guacamole с одним подключением-участником 7
+type fakeBalancingGuacamole struct {
+	mu         sync.Mutex
+	port       string
+	attributes map[string]string
+	updates    int
+}
+
+func newFakeBalancingGuacamole(t *testing.T, port string, attributes map[string]string) *fakeBalancingGuacamole {
+	t.Helper()
+	guac := &fakeBalancingGuacamole{port: port, attributes: attributes}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /session/data/postgresql/connections/7", func(w http.ResponseWriter, r *http.Request) {
+		guac.mu.Lock()
+		defer guac.mu.Unlock()
+		json.NewEncoder(w).Encode(common.GuacamoleRDConnectionRequest{
+			Name:       "member",
+			Protocol:   "rdp",
+			Attributes: guac.attributes,
+		})
+	})
+	mux.HandleFunc("GET /session/data/postgresql/connections/7/parameters", func(w http.ResponseWriter, r *http.Request) {
+		guac.mu.Lock()
+		defer guac.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]string{"hostname": "127.0.0.1", "port": guac.port})
+	})
+	mux.HandleFunc("PUT /session/data/postgresql/connections/7", func(w http.ResponseWriter, r *http.Request) {
+		var connection common.GuacamoleRDConnectionRequest
+		json.NewDecoder(r.Body).Decode(&connection)
+		guac.mu.Lock()
+		guac.attributes = connection.Attributes
+		guac.updates++
+		guac.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	})
+	server := httptest.NewServer(mux)
+
+	previous := config.ServerConfig.GuacamoleAPIURL
+	config.ServerConfig.GuacamoleAPIURL = server.URL
+	t.Cleanup(func() {
+		server.Close()
+		config.ServerConfig.GuacamoleAPIURL = previous
+	})
+	return guac
+}
+
+// state возвращает вес участника, сохраненный исходный вес и количество изменений
+func (guac *fakeBalancingGuacamole) state() (string, string, int) {
+	guac.mu.Lock()
+	defer guac.mu.Unlock()
+	return guac.attributes["weight"], guac.attributes[originalWeightAttribute], guac.updates
+}
+
+// setPort переключает адрес участника
+func (guac *fakeBalancingGuacamole) setPort(port string) {
+	guac.mu.Lock()
+	defer guac.mu.Unlock()
+	guac.port = port
+}
+
+// probePorts возвращает порт, принимающий соединения, и порт, на котором никто не слушает
+func probePorts(t *testing.T) (string, string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	closed.Close()
+	return strconv.Itoa(listener.Addr().(*net.TCPAddr).Port), strconv.Itoa(closed.Addr().(*net.TCPAddr).Port)
+}
+
+// withFailureThreshold задает порог отключения участника на время теста
+func withFailureThreshold(t *testing.T, threshold int) {
+	t.Helper()
+	previous := config.ServerConfig.BalancingConfig.FailureThreshold
+	config.ServerConfig.BalancingConfig.FailureThreshold = threshold
+	t.Cleanup(func() { config.ServerConfig.BalancingConfig.FailureThreshold = previous })
+}
+
+func TestBalancingServiceDisablesAndRestoresMember(t *testing.T) {
+	withFailureThreshold(t, 2)
+	open, closed := probePorts(t)
+	guac := newFakeBalancingGuacamole(t, closed, map[string]string{"weight": "5"})
+	member := &common.GuacamoleRDConnectionResponse{ID: "7", Name: "member"}
+
+	service := NewBalancingService(NewSessionService(nil, nil), nil)
+	if health := service.checkMember(context.Background(), member, "token", true); health.Disabled {
+		t.Fatal("member disabled before the failure threshold")
+	}
+	health := service.checkMember(context.Background(), member, "token", true)
+	if !health.Disabled || health.ConsecutiveFailures != 2 {
+		t.Fatalf("health = %+v, want disabled after 2 failures", health)
+	}
+	if weight, original, _ := guac.state(); weight != disabledWeight || original != "5" {
+		t.Fatalf("weight = %q, original = %q; want 0 and 5", weight, original)
+	}
+
+	// Состояние отключения хранится в Guacamole: восстанавливает участника новый экземпляр сервиса
+	guac.setPort(open)
+	restarted := NewBalancingService(NewSessionService(nil, nil), nil)
+	health = restarted.checkMember(context.Background(), member, "token", true)
+	if health.Disabled || health.ConsecutiveFailures != 0 {
+		t.Fatalf("health = %+v, want restored member", health)
+	}
+	if weight, original, _ := guac.state(); weight != "5" || original != "" {
+		t.Fatalf("weight = %q, original = %q; want 5 and empty", weight, original)
+	}
+}
+
+func TestBalancingServiceKeepsManuallyDisabledMember(t *testing.T) {
+	withFailureThreshold(t, 1)
+	open, _ := probePorts(t)
+	guac := newFakeBalancingGuacamole(t, open, map[string]string{"weight": disabledWeight})
+	member := &common.GuacamoleRDConnectionResponse{ID: "7", Name: "member"}
+
+	service := NewBalancingService(NewSessionService(nil, nil), nil)
+	health := service.checkMember(context.Background(), member, "token", true)
+	if !health.Reachable || !health.Disabled {
+		t.Fatalf("health = %+v, want reachable and still disabled", health)
+	}
+	if weight, _, updates := guac.state(); weight != disabledWeight || updates != 0 {
+		t.Fatalf("weight = %q after %d updates, want manual weight 0 kept", weight, updates)
+	}
+}
+
+func TestBalancingServiceManualCheckKeepsMonitorState(t *testing.T) {
+	withFailureThreshold(t, 3)
+	_, closed := probePorts(t)
+	guac := newFakeBalancingGuacamole(t, closed, map[string]string{"weight": "1"})
+	member := &common.GuacamoleRDConnectionResponse{ID: "7", Name: "member"}
+
+	service := NewBalancingService(NewSessionService(nil, nil), nil)
+	service.checkMember(context.Background(), member, "token", true)
+	for i := 0; i < 5; i++ {
+		health := service.checkMember(context.Background(), member, "token", false)
+		if health.ConsecutiveFailures != 1 {
+			t.Fatalf("manual check reported %d failures, want the monitor count 1", health.ConsecutiveFailures)
+		}
+	}
+	if health := service.checkMember(context.Background(), member, "token", true); health.ConsecutiveFailures != 2 {
+		t.Fatalf("monitor failures = %d, want 2", health.ConsecutiveFailures)
+	}
+	if _, _, updates := guac.state(); updates != 0 {
+		t.Fatalf("member updated %d times before the threshold", updates)
+	}
+}
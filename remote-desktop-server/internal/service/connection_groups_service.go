@@ -0,0 +1,217 @@
+// Package service реализует бизнес-логику приложения.
+package service
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+)
+
+// ConnectionGroupService предоставляет методы для работы с группами подключений Guacamole.
+// Использует HTTP клиент SessionService для обращения к API Guacamole.
+type ConnectionGroupService struct {
+	sessions *SessionService
+}
+
+// NewConnectionGroupService создает и возвращает новый экземпляр ConnectionGroupService.
+//
+// Параметры:
+//   - sessions: сервис подключений, через который выполняются запросы к Guacamole
+//
+// Возвращает:
+//   - *ConnectionGroupService: указатель на созданный сервис
+func NewConnectionGroupService(sessions *SessionService) *ConnectionGroupService {
+	return &ConnectionGroupService{
+		sessions: sessions,
+	}
+}
+
+// GetGroups возвращает плоский список всех групп подключений, доступных пользователю.
+//
+// Параметры:
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - []*common.GuacamoleConnectionGroup: список групп
+//   - error: ошибка, если не удалось получить данные
+func (service *ConnectionGroupService) GetGroups(guacToken string) ([]*common.GuacamoleConnectionGroup, error) {
+	var response map[string]*common.GuacamoleConnectionGroup
+	if err := service.sessions.makeGuacamoleRequest(
+		http.MethodGet,
+		connectionGroupsURL,
+		guacToken,
+		nil,
+		&response,
+	); err != nil {
+		return nil, fmt.Errorf("failed to fetch connection groups: %w", err)
+	}
+
+	groups := make([]*common.GuacamoleConnectionGroup, 0, len(response))
+	for _, group := range response {
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// GetGroupTree возвращает дерево группы со всеми вложенными группами и подключениями.
+//
+// Параметры:
+//   - id: идентификатор группы ("ROOT" для корня)
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - *common.GuacamoleConnectionGroup: группа с вложенными элементами
+//   - error: ошибка, если не удалось получить данные
+func (service *ConnectionGroupService) GetGroupTree(id string, guacToken string) (*common.GuacamoleConnectionGroup, error) {
+	var group common.GuacamoleConnectionGroup
+	if err := service.sessions.makeGuacamoleRequest(
+		http.MethodGet,
+		fmt.Sprintf("%s/%s/tree", connectionGroupsURL, id),
+		guacToken,
+		nil,
+		&group,
+	); err != nil {
+		return nil, fmt.Errorf("failed to fetch connection group tree: %w", err)
+	}
+	return &group, nil
+}
+
+// EditGroup возвращает данные группы подключений в формате формы.
+//
+// Параметры:
+//   - id: идентификатор группы
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - *common.GuacamoleConnectionGroupRequest: данные группы
+//   - error: ошибка, если не удалось получить данные
+func (service *ConnectionGroupService) EditGroup(id string, guacToken string) (*common.GuacamoleConnectionGroupRequest, error) {
+	var group common.GuacamoleConnectionGroup
+	if err := service.sessions.makeGuacamoleRequest(
+		http.MethodGet,
+		fmt.Sprintf("%s/%s", connectionGroupsURL, id),
+		guacToken,
+		nil,
+		&group,
+	); err != nil {
+		return nil, fmt.Errorf("failed to get connection group: %w", err)
+	}
+
+	form := &common.GuacamoleConnectionGroupRequest{
+		Id:               group.Id,
+		Name:             group.Name,
+		ParentIdentifier: group.ParentIdentifier,
+		Type:             group.Type,
+	}
+	if err := decodeGuacamoleParameters(group.Attributes, form); err != nil {
+		return nil, fmt.Errorf("failed to read connection group attributes: %w", err)
+	}
+	return form, nil
+}
+
+// CreateGroup создает новую группу подключений.
+//
+// Параметры:
+//   - form: данные группы
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - *common.GuacamoleConnectionGroup: созданная группа с присвоенным идентификатором
+//   - error: ошибка, если не удалось создать группу
+func (service *ConnectionGroupService) CreateGroup(
+	form *common.GuacamoleConnectionGroupRequest,
+	guacToken string,
+) (*common.GuacamoleConnectionGroup, error) {
+	var created common.GuacamoleConnectionGroup
+	if err := service.sessions.makeGuacamoleRequest(
+		http.MethodPost,
+		connectionGroupsURL,
+		guacToken,
+		connectionGroupFromForm("", form),
+		&created,
+	); err != nil {
+		return nil, fmt.Errorf("failed to create connection group: %w", err)
+	}
+	return &created, nil
+}
+
+// UpdateGroup обновляет группу подключений. Позволяет в том числе переместить группу
+// в другую родительскую группу.
+//
+// Параметры:
+//   - id: идентификатор группы
+//   - form: новые данные группы
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - error: ошибка, если не удалось обновить группу
+func (service *ConnectionGroupService) UpdateGroup(
+	id string,
+	form *common.GuacamoleConnectionGroupRequest,
+	guacToken string,
+) error {
+	if id == common.RootConnectionGroup {
+		return fmt.Errorf("root connection group can not be changed")
+	}
+	if err := service.sessions.makeGuacamoleRequest(
+		http.MethodPut,
+		fmt.Sprintf("%s/%s", connectionGroupsURL, id),
+		guacToken,
+		connectionGroupFromForm(id, form),
+		nil,
+	); err != nil {
+		return fmt.Errorf("failed to update connection group: %w", err)
+	}
+	return nil
+}
+
+// DestroyGroup удаляет группу подключений вместе со всеми вложенными группами и подключениями.
+//
+// Параметры:
+//   - id: идентификатор группы
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - error: ошибка, если не удалось удалить группу
+func (service *ConnectionGroupService) DestroyGroup(id string, guacToken string) error {
+	if id == common.RootConnectionGroup {
+		return fmt.Errorf("root connection group can not be removed")
+	}
+	if err := service.sessions.makeGuacamoleRequest(
+		http.MethodDelete,
+		fmt.Sprintf("%s/%s", connectionGroupsURL, id),
+		guacToken,
+		nil,
+		nil,
+	); err != nil {
+		return fmt.Errorf("failed to destroy connection group: %w", err)
+	}
+	return nil
+}
+
+// connectionGroupFromForm формирует запрос к API Guacamole из данных формы группы.
+//
+// Параметры:
+//   - id: идентификатор группы (пустой при создании)
+//   - form: данные формы группы
+//
+// Возвращает:
+//   - common.GuacamoleConnectionGroup: тело запроса к API Guacamole
+func connectionGroupFromForm(id string, form *common.GuacamoleConnectionGroupRequest) common.GuacamoleConnectionGroup {
+	parentIdentifier := form.ParentIdentifier
+	if parentIdentifier == "" {
+		parentIdentifier = common.RootConnectionGroup
+	}
+
+	attributes := make(map[string]string)
+	encodeGuacamoleParameters(form, attributes)
+
+	return common.GuacamoleConnectionGroup{
+		Id:               id,
+		Name:             form.Name,
+		ParentIdentifier: parentIdentifier,
+		Type:             form.Type,
+		Attributes:       attributes,
+	}
+}
@@ -0,0 +1,201 @@
+// Package service реализует бизнес-логику приложения.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/repository"
+)
+
+// ConnectionPermissionService предоставляет методы для выдачи другим пользователям и группам
+// доступа к подключениям. Разрешения хранятся в guacamole_connection_permission.
+type ConnectionPermissionService struct {
+	guacamole repository.GuacamoleRepository
+	sessions  *SessionService
+}
+
+// NewConnectionPermissionService создает и возвращает новый экземпляр ConnectionPermissionService.
+//
+// Параметры:
+//   - guacamole: репозиторий базы данных Guacamole
+//   - sessions: сервис подключений, через который проверяются права вызывающего
+//
+// Возвращает:
+//   - *ConnectionPermissionService: указатель на созданный сервис
+func NewConnectionPermissionService(
+	guacamole repository.GuacamoleRepository,
+	sessions *SessionService,
+) *ConnectionPermissionService {
+	return &ConnectionPermissionService{
+		guacamole: guacamole,
+		sessions:  sessions,
+	}
+}
+
+// GetPermissions возвращает пользователей и группы, имеющие доступ к подключению.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор подключения
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - []*common.ConnectionGrant: разрешения на подключение
+//   - error: ErrNotFound, если подключение не найдено; ErrForbidden, если нет права ADMINISTER
+func (service *ConnectionPermissionService) GetPermissions(
+	ctx context.Context,
+	id string,
+	guacToken string,
+) ([]*common.ConnectionGrant, error) {
+	connectionID, err := service.authorize(id, guacToken)
+	if err != nil {
+		return nil, err
+	}
+	return service.guacamole.FindConnectionPermissions(ctx, connectionID)
+}
+
+// GrantPermissions выдает пользователю или группе разрешения на подключение.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор подключения
+//   - form: получатель и выдаваемые разрешения
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - []*common.ConnectionGrant: разрешения на подключение после изменения
+//   - error: ErrNotFound, если подключение или получатель не найдены; ErrForbidden, если нет права ADMINISTER
+func (service *ConnectionPermissionService) GrantPermissions(
+	ctx context.Context,
+	id string,
+	form *common.ConnectionPermissionRequest,
+	guacToken string,
+) ([]*common.ConnectionGrant, error) {
+	connectionID, err := service.authorize(id, guacToken)
+	if err != nil {
+		return nil, err
+	}
+	entity, err := service.guacamole.FindEntity(ctx, form.Name, form.Type)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return nil, ErrNotFound
+	}
+	if err := service.guacamole.GrantConnectionPermissions(ctx, entity.ID, connectionID, form.Permissions); err != nil {
+		return nil, fmt.Errorf("failed to grant connection permissions: %w", err)
+	}
+	return service.guacamole.FindConnectionPermissions(ctx, connectionID)
+}
+
+// RevokePermissions отзывает разрешения пользователя или группы на подключение.
+// Собственные разрешения вызывающего отозвать нельзя, чтобы не потерять управление подключением.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор подключения
+//   - entityID: идентификатор пользователя или группы Guacamole
+//   - permissions: отзываемые разрешения (пустой список — все разрешения)
+//   - username: имя пользователя Guacamole (email) вызывающего
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - error: ErrNotFound, если подключение или сущность не найдены; ErrForbidden, если нет права ADMINISTER
+//     или отзываются собственные разрешения
+func (service *ConnectionPermissionService) RevokePermissions(
+	ctx context.Context,
+	id string,
+	entityID string,
+	permissions []string,
+	username string,
+	guacToken string,
+) error {
+	connectionID, err := service.authorize(id, guacToken)
+	if err != nil {
+		return err
+	}
+	for _, permission := range permissions {
+		if !isObjectPermission(permission) {
+			return ErrUnsupported
+		}
+	}
+	parsedID, err := strconv.ParseUint(entityID, 10, 64)
+	if err != nil {
+		return ErrNotFound
+	}
+	entity, err := service.guacamole.FindEntityByID(ctx, parsedID)
+	if err != nil {
+		return err
+	}
+	if entity == nil {
+		return ErrNotFound
+	}
+	if entity.Type == common.EntityTypeUser && entity.Name == username {
+		return ErrForbidden
+	}
+	if err := service.guacamole.RevokeConnectionPermissions(ctx, entity.ID, connectionID, permissions); err != nil {
+		return fmt.Errorf("failed to revoke connection permissions: %w", err)
+	}
+	return nil
+}
+
+// authorize проверяет, что вызывающий может управлять доступом к подключению:
+// у него есть разрешение ADMINISTER на подключение или системное разрешение ADMINISTER.
+//
+// Параметры:
+//   - id: идентификатор подключения
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - uint64: идентификатор подключения
+//   - error: ErrNotFound, если подключение не найдено; ErrForbidden, если прав недостаточно
+func (service *ConnectionPermissionService) authorize(id string, guacToken string) (uint64, error) {
+	connectionID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return 0, ErrNotFound
+	}
+	permissions, err := service.sessions.effectivePermissions(guacToken)
+	if err != nil {
+		return 0, err
+	}
+	if hasPermission(permissions.ConnectionPermissions[id], common.PermissionAdminister) {
+		return connectionID, nil
+	}
+	if !hasPermission(permissions.SystemPermissions, "ADMINISTER") {
+		if _, ok := permissions.ConnectionPermissions[id]; ok {
+			return 0, ErrForbidden
+		}
+		return 0, ErrNotFound
+	}
+
+	// Администратору доступны все подключения, достаточно проверить, что подключение существует
+	var connection common.GuacamoleRDConnectionResponse
+	if err := service.sessions.makeGuacamoleRequest(
+		http.MethodGet,
+		fmt.Sprintf("%s/%s", connectionsURL, id),
+		guacToken,
+		nil,
+		&connection,
+	); err != nil {
+		var apiErr *GuacamoleAPIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode < 500 {
+			return 0, ErrNotFound
+		}
+		return 0, fmt.Errorf("failed to get connection info: %w", err)
+	}
+	return connectionID, nil
+}
+
+// isObjectPermission проверяет, является ли строка разрешением на объект Guacamole
+func isObjectPermission(permission string) bool {
+	switch permission {
+	case common.PermissionRead, common.PermissionUpdate, common.PermissionDelete, common.PermissionAdminister:
+		return true
+	}
+	return false
+}
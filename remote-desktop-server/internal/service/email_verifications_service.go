@@ -0,0 +1,299 @@
+// Package service реализует бизнес-логику приложения.
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/config"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/mailer"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/repository"
+)
+
+// EmailVerificationService подтверждает электронную почту пользователя по ссылке из письма.
+//
+// Ссылка отправляется после регистрации (подтверждение адреса, указанного при регистрации)
+// и при смене почты в профиле (подтверждение нового адреса). В ссылке передается случайный
+// одноразовый токен, в базе хранится только его хэш. Электронная почта является именем
+// пользователя Guacamole, поэтому подтверждение затрагивает обе базы данных.
+type EmailVerificationService struct {
+	users         repository.UserRepository
+	verifications repository.EmailVerificationRepository
+	guacamole     repository.GuacamoleRepository
+	tokens        *TokenService
+	mailer        mailer.Mailer
+}
+
+// NewEmailVerificationService создает новый экземпляр EmailVerificationService.
+//
+// Параметры:
+//   - users: репозиторий пользователей
+//   - verifications: репозиторий токенов подтверждения электронной почты
+//   - guacamole: репозиторий базы Guacamole
+//   - tokens: сервис токенов сессий входа
+//   - mailer: отправка писем
+//
+// Возвращает:
+//   - *EmailVerificationService: указатель на созданный сервис
+func NewEmailVerificationService(
+	users repository.UserRepository,
+	verifications repository.EmailVerificationRepository,
+	guacamole repository.GuacamoleRepository,
+	tokens *TokenService,
+	mailer mailer.Mailer,
+) *EmailVerificationService {
+	return &EmailVerificationService{
+		users:         users,
+		verifications: verifications,
+		guacamole:     guacamole,
+		tokens:        tokens,
+		mailer:        mailer,
+	}
+}
+
+// Send сохраняет токен подтверждения и отправляет ссылку на подтверждаемый адрес.
+//
+// Параметры:
+//   - ctx: контекст
+//   - user: пользователь
+//   - email: подтверждаемый адрес (текущая почта пользователя после регистрации или новая почта)
+//
+// Возвращает:
+//   - error: ошибка сохранения токена или отправки письма
+func (service *EmailVerificationService) Send(ctx context.Context, user *common.User, email string) error {
+	token, err := newSecretToken()
+	if err != nil {
+		return err
+	}
+	ttl := config.ServerConfig.EmailVerificationConfig.TokenTTL
+	if err := service.verifications.Create(ctx, user.ID, email, hashSecretToken(token), ttl); err != nil {
+		return err
+	}
+	link, err := verificationLink(token)
+	if err != nil {
+		return err
+	}
+
+	purpose := "Чтобы использовать этот адрес для входа в RemoteDesktop, перейдите по ссылке:"
+	ignore := "Если вы не меняли адрес электронной почты, просто проигнорируйте это письмо."
+	if email == user.Email {
+		purpose = "Чтобы завершить регистрацию в RemoteDesktop, подтвердите адрес электронной почты по ссылке:"
+		ignore = "Если вы не регистрировались в RemoteDesktop, просто проигнорируйте это письмо."
+	}
+	return service.mailer.Send(ctx, mailer.Message{
+		To:      []string{email},
+		Subject: "Подтверждение электронной почты RemoteDesktop",
+		Body: fmt.Sprintf(
+			"Здравствуйте, %s!\n\n%s\n%s\n\n"+
+				"Ссылка действует %s и может быть использована один раз.\n%s\n",
+			user.Name,
+			purpose,
+			link,
+			ttl.String(),
+			ignore,
+		),
+	})
+}
+
+// Resend повторно отправляет ссылку для подтверждения почты, указанной при регистрации.
+// Ранее отправленные ссылки продолжают действовать до истечения срока.
+//
+// Параметры:
+//   - ctx: контекст
+//   - form: электронная почта пользователя
+//
+// Возвращает:
+//   - error: ошибка сохранения токена или отправки письма
+//
+// Особенности:
+//   - Для неизвестного или уже подтвержденного адреса письмо не отправляется и ошибка
+//     не возвращается, чтобы по ответу нельзя было узнать, зарегистрирован ли адрес
+func (service *EmailVerificationService) Resend(ctx context.Context, form common.EmailVerificationResendRequest) error {
+	user, err := service.users.FindByEmail(ctx, form.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		slog.Info(fmt.Sprintf("Email verification is requested for unknown email %s", form.Email))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		slog.Info(fmt.Sprintf("Email verification is requested for already verified email %s", form.Email))
+		return nil
+	}
+	return service.Send(ctx, user, user.Email)
+}
+
+// Verify подтверждает электронную почту по токену из письма.
+// Изменение затрагивает две базы данных и выполняется как сага:
+//  1. токен помечается использованным;
+//  2. после регистрации учетная запись Guacamole включается, при смене почты
+//     сущность Guacamole переименовывается в новый адрес;
+//  3. почта отмечается подтвержденной, новый адрес сохраняется в базе приложения.
+//
+// При ошибке выполненные шаги откатываются, а токен снова становится действительным.
+// После смены адреса сессии входа пользователя отзываются: войти нужно с новым адресом.
+//
+// Параметры:
+//   - ctx: контекст
+//   - form: токен из письма
+//
+// Возвращает:
+//   - error: ErrInvalidToken, если токен не найден, уже использован или истек, ErrConflict,
+//     если новый адрес занят после отправки письма, или ошибка изменения данных
+func (service *EmailVerificationService) Verify(ctx context.Context, form common.EmailVerificationRequest) error {
+	var token *common.EmailVerificationToken
+	var user *common.User
+	changed := func() bool { return token.Email != user.Email }
+	err := newSaga("verify email").
+		step(
+			"consume verification token",
+			func(ctx context.Context) error {
+				var err error
+				token, err = service.verifications.Consume(ctx, hashSecretToken(form.Token))
+				if err != nil {
+					return err
+				}
+				if token == nil {
+					return ErrInvalidToken
+				}
+				return nil
+			},
+			func(ctx context.Context) error {
+				return service.verifications.Release(ctx, token.ID)
+			},
+		).
+		step(
+			"find user",
+			func(ctx context.Context) error {
+				var err error
+				if user, err = service.users.FindByID(ctx, token.UserID); err != nil {
+					return err
+				}
+				if !changed() {
+					return nil
+				}
+				return checkEmailAvailable(ctx, service.users, service.guacamole, token.Email)
+			},
+			nil,
+		).
+		step(
+			"update guacamole user",
+			func(ctx context.Context) error {
+				if changed() {
+					return service.guacamole.RenameEntity(ctx, user.Email, token.Email, common.EntityTypeUser)
+				}
+				return service.guacamole.SetUserDisabled(ctx, user.Email, false)
+			},
+			func(ctx context.Context) error {
+				if changed() {
+					return service.guacamole.RenameEntity(ctx, token.Email, user.Email, common.EntityTypeUser)
+				}
+				if user.EmailVerifiedAt != nil {
+					return nil
+				}
+				return service.guacamole.SetUserDisabled(ctx, user.Email, true)
+			},
+		).
+		step(
+			"update user",
+			func(ctx context.Context) error {
+				if changed() {
+					return service.users.UpdateEmail(ctx, user.ID, token.Email)
+				}
+				return service.users.MarkEmailVerified(ctx, user.ID)
+			},
+			nil,
+		).
+		run(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !changed() {
+		slog.Info(fmt.Sprintf("Email %s verified", user.Email))
+		return nil
+	}
+	slog.Info(fmt.Sprintf("Email of %s changed to %s", user.Email, token.Email))
+	if err := service.tokens.RevokeUser(ctx, user.ID); err != nil {
+		slog.Error(fmt.Sprintf("Error revoking auth sessions of %s: %s", token.Email, err.Error()))
+	}
+	return nil
+}
+
+// activateUser включает учетную запись Guacamole пользователя и отмечает его почту подтвержденной.
+// Изменение затрагивает две базы данных и выполняется как сага: если почту не удалось отметить
+// подтвержденной, учетная запись Guacamole снова отключается.
+func activateUser(
+	ctx context.Context,
+	users repository.UserRepository,
+	guacamole repository.GuacamoleRepository,
+	user *common.User,
+) error {
+	err := newSaga("activate user").
+		step(
+			"enable guacamole account",
+			func(ctx context.Context) error {
+				return guacamole.SetUserDisabled(ctx, user.Email, false)
+			},
+			func(ctx context.Context) error {
+				return guacamole.SetUserDisabled(ctx, user.Email, true)
+			},
+		).
+		step(
+			"mark email verified",
+			func(ctx context.Context) error {
+				return users.MarkEmailVerified(ctx, user.ID)
+			},
+			nil,
+		).
+		run(ctx)
+	if err != nil {
+		return err
+	}
+	verifiedAt := time.Now()
+	user.EmailVerifiedAt = &verifiedAt
+	return nil
+}
+
+// checkEmailAvailable проверяет, что адрес не занят ни пользователем приложения, ни сущностью Guacamole
+func checkEmailAvailable(
+	ctx context.Context,
+	users repository.UserRepository,
+	guacamole repository.GuacamoleRepository,
+	email string,
+) error {
+	_, err := users.FindByEmail(ctx, email)
+	if err == nil {
+		return fmt.Errorf("%w: user with email %s", ErrConflict, email)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	entity, err := guacamole.FindEntity(ctx, email, common.EntityTypeUser)
+	if err != nil {
+		return err
+	}
+	if entity != nil {
+		return fmt.Errorf("%w: guacamole user %s", ErrConflict, email)
+	}
+	return nil
+}
+
+// verificationLink возвращает ссылку на страницу подтверждения электронной почты с токеном в параметре token
+func verificationLink(token string) (string, error) {
+	link, err := url.Parse(config.ServerConfig.EmailVerificationConfig.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid email verification url: %w", err)
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link.String(), nil
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/config"
+)
+
+// verificationLinkPattern находит ссылку для подтверждения почты в тексте письма
+var verificationLinkPattern = regexp.MustCompile(`https://rd\.example\.com/verify-email\?\S+`)
+
+// signUpFixture регистрация с подтверждением почты через локальный SMTP сервер
+type signUpFixture struct {
+	*mailFixture
+	auth         *AuthService
+	verification *EmailVerificationService
+}
+
+func newSignUpFixture(t *testing.T, users ...*common.User) *signUpFixture {
+	t.Helper()
+	fixture := &signUpFixture{mailFixture: newMailFixture(t, users...)}
+	tokens := NewTokenService(fixture.sessions, fixture.users)
+	fixture.verification = NewEmailVerificationService(
+		fixture.users,
+		&fakeEmailVerifications{},
+		fixture.guacamole,
+		tokens,
+		fixture.mailer(),
+	)
+	fixture.auth = NewAuthService(
+		fixture.users,
+		fixture.guacamole,
+		tokens,
+		nil,
+		fixture.verification,
+		nil,
+		NewLocalAuthenticator(fixture.users),
+	)
+	return fixture
+}
+
+// verificationToken возвращает токен из последнего письма со ссылкой для подтверждения почты
+func (f *signUpFixture) verificationToken(t *testing.T, email string) string {
+	t.Helper()
+	return f.mailToken(t, email, verificationLinkPattern)
+}
+
+// signUp регистрирует пользователя и проверяет, что учетная запись не активна до подтверждения почты
+func (f *signUpFixture) signUp(t *testing.T, email string, password string) {
+	t.Helper()
+	err := f.auth.SignUp(context.Background(), common.AuthSignUpRequest{
+		Name:                 "Иван Петров",
+		Email:                email,
+		Password:             password,
+		PasswordConfirmation: password,
+	})
+	if err != nil {
+		t.Fatalf("SignUp() error = %v", err)
+	}
+	if user := f.users.get(email); user == nil || user.EmailVerifiedAt != nil {
+		t.Fatalf("signed up user = %+v, want unverified user", user)
+	}
+	if !f.guacamole.isDisabled(email) {
+		t.Fatal("guacamole account of unverified user is enabled")
+	}
+}
+
+func TestEmailVerificationSignUpFlow(t *testing.T) {
+	fixture := newSignUpFixture(t)
+	ctx := context.Background()
+	local := NewLocalAuthenticator(fixture.users)
+
+	fixture.signUp(t, "ivan@example.com", "user-password")
+	if _, err := local.Authenticate(ctx, "ivan@example.com", "user-password"); !errors.Is(err, ErrEmailNotVerified) {
+		t.Errorf("Authenticate() before verification error = %v, want ErrEmailNotVerified", err)
+	}
+
+	token := fixture.verificationToken(t, "ivan@example.com")
+	if err := fixture.verification.Verify(ctx, common.EmailVerificationRequest{Token: token}); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if user := fixture.users.get("ivan@example.com"); user.EmailVerifiedAt == nil {
+		t.Error("email is not verified")
+	}
+	if fixture.guacamole.isDisabled("ivan@example.com") {
+		t.Error("guacamole account is still disabled")
+	}
+	if _, err := local.Authenticate(ctx, "ivan@example.com", "user-password"); err != nil {
+		t.Errorf("Authenticate() after verification error = %v", err)
+	}
+
+	err := fixture.verification.Verify(ctx, common.EmailVerificationRequest{Token: token})
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("second Verify() error = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestEmailVerificationExpiredLink(t *testing.T) {
+	fixture := newSignUpFixture(t)
+	ctx := context.Background()
+	previous := config.ServerConfig.EmailVerificationConfig.TokenTTL
+	config.ServerConfig.EmailVerificationConfig.TokenTTL = -time.Minute
+	t.Cleanup(func() { config.ServerConfig.EmailVerificationConfig.TokenTTL = previous })
+
+	fixture.signUp(t, "ivan@example.com", "user-password")
+	err := fixture.verification.Verify(ctx, common.EmailVerificationRequest{Token: fixture.verificationToken(t, "ivan@example.com")})
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify() with expired link error = %v, want ErrInvalidToken", err)
+	}
+	if !fixture.guacamole.isDisabled("ivan@example.com") {
+		t.Error("expired link enabled the guacamole account")
+	}
+
+	// Новая ссылка действует, даже если предыдущая истекла
+	config.ServerConfig.EmailVerificationConfig.TokenTTL = time.Hour
+	if err := fixture.verification.Resend(ctx, common.EmailVerificationResendRequest{Email: "ivan@example.com"}); err != nil {
+		t.Fatalf("Resend() error = %v", err)
+	}
+	err = fixture.verification.Verify(ctx, common.EmailVerificationRequest{Token: fixture.verificationToken(t, "ivan@example.com")})
+	if err != nil {
+		t.Errorf("Verify() with resent link error = %v", err)
+	}
+}
+
+func TestEmailVerificationResend(t *testing.T) {
+	now := time.Now()
+	verified := &common.User{Name: "Петр", Email: "petr@example.com", Password: "hash", EmailVerifiedAt: &now}
+
+	tests := []struct {
+		name      string
+		email     string
+		wantMails int
+	}{
+		{name: "unverified user", email: "ivan@example.com", wantMails: 1},
+		{name: "verified user", email: "petr@example.com"},
+		{name: "unknown email", email: "nobody@example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			copied := *verified
+			fixture := newSignUpFixture(t, &copied)
+			fixture.signUp(t, "ivan@example.com", "user-password")
+			sent := len(fixture.smtp.Mails())
+
+			err := fixture.verification.Resend(context.Background(), common.EmailVerificationResendRequest{Email: tt.email})
+			if err != nil {
+				t.Fatalf("Resend() error = %v", err)
+			}
+			if got := len(fixture.smtp.Mails()) - sent; got != tt.wantMails {
+				t.Errorf("Resend() sent %d mails, want %d", got, tt.wantMails)
+			}
+			if tt.wantMails > 0 {
+				fixture.verificationToken(t, tt.email)
+			}
+		})
+	}
+}
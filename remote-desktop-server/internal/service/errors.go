@@ -0,0 +1,20 @@
+// Package service реализует бизнес-логику приложения.
+package service
+
+import "errors"
+
+// Общие ошибки сервисов, по которым обработчики выбирают HTTP статус ответа
+var (
+	ErrForbidden   = errors.New("access denied")  // Недостаточно прав для выполнения операции
+	ErrNotFound    = errors.New("not found")      // Запрошенный объект не найден
+	ErrUnsupported = errors.New("unsupported")    // Операция не поддерживается для объекта
+	ErrConflict    = errors.New("already exists") // Объект с такими данными уже существует
+
+	ErrInvalidPassword = errors.New("password is not valid")                     // Неверный текущий пароль
+	ErrInvalidToken    = errors.New("token is invalid or has expired")           // Одноразовый токен не найден, использован или истек
+	ErrInvalidCode     = errors.New("code is not valid")                         // Неверный код TOTP или код восстановления
+	ErrPasswordExpired = errors.New("password has expired, reset it to sign in") // Пароль Guacamole помечен истекшим
+
+	ErrEmailNotVerified = errors.New("email is not verified, follow the link sent to it to sign in") // Почта пользователя не подтверждена
+	ErrTooManyAttempts  = errors.New("too many failed sign-in attempts")                             // Вход временно ограничен (LoginThrottledError)
+)
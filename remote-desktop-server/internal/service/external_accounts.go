@@ -0,0 +1,238 @@
+// Package service реализует бизнес-логику приложения.
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/config"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Пользователи, входящие через внешний провайдер (OpenID Connect, LDAP), не сообщают приложению пароль,
+// а Guacamole выдает токен только по паролю. Поэтому пароль Guacamole таких пользователей
+// генерируется и хранится сервером (users.guacamole_credentials) и используется TokenService.Issue.
+// Смена или сброс пароля в приложении снова делают пароль Guacamole равным паролю пользователя.
+
+// externalAccounts создает и обслуживает учетные записи пользователей внешних провайдеров
+type externalAccounts struct {
+	users     repository.UserRepository
+	guacamole repository.GuacamoleRepository
+	tokens    *TokenService
+	roles     *RoleService
+}
+
+// newExternalAccounts создает помощника для учетных записей внешних провайдеров
+func newExternalAccounts(
+	users repository.UserRepository,
+	guacamole repository.GuacamoleRepository,
+	tokens *TokenService,
+) *externalAccounts {
+	return &externalAccounts{
+		users:     users,
+		guacamole: guacamole,
+		tokens:    tokens,
+		roles:     NewRoleService(users, guacamole),
+	}
+}
+
+// provision возвращает пользователя с паролем Guacamole, управляемым сервером.
+// При первом входе пользователь создается так же, как при регистрации (SignUp), со случайным паролем
+// и ролью AUTH_DEFAULT_ROLE.
+// Если пользователь уже зарегистрирован с паролем, его пароль Guacamole заменяется случайным,
+// а вход по паролю продолжает работать (TokenService.Issue использует сохраненный пароль Guacamole).
+// Электронную почту подтверждает провайдер, поэтому неподтвержденная почта такого пользователя
+// отмечается подтвержденной. Пароль неподтвержденной учетной записи мог задать кто угодно,
+// зарегистрировавшись с чужим адресом, поэтому перед подтверждением он заменяется случайным,
+// а сессии входа отзываются (см. reclaim).
+//
+// Параметры:
+//   - ctx: контекст
+//   - email: электронная почта пользователя
+//   - name: имя пользователя (если пустое, используется email)
+//
+// Возвращает:
+//   - *common.User: пользователь с заполненным GuacamoleCredentials
+//   - error: ошибка создания пользователя или смены пароля Guacamole
+func (accounts *externalAccounts) provision(ctx context.Context, email string, name string) (*common.User, error) {
+	user, err := accounts.users.FindByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return accounts.create(ctx, email, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.EmailVerifiedAt == nil {
+		if err := accounts.reclaim(ctx, user); err != nil {
+			return nil, err
+		}
+		if err := activateUser(ctx, accounts.users, accounts.guacamole, user); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+	if err := accounts.manage(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// reclaim отбирает неподтвержденную учетную запись у того, кто ее зарегистрировал.
+// Пароль в приложении и пароль Guacamole заменяются случайными, а сессии входа отзываются:
+// иначе после подтверждения почты провайдером вход по паролю, заданному при регистрации
+// (в приложение или напрямую в Guacamole), открыл бы доступ к учетной записи владельца почты.
+// Пароль Guacamole заменяется до включения учетной записи Guacamole (activateUser).
+func (accounts *externalAccounts) reclaim(ctx context.Context, user *common.User) error {
+	password, err := newSecretToken()
+	if err != nil {
+		return err
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), config.ServerConfig.BcryptPower)
+	if err != nil {
+		return err
+	}
+	if err := accounts.users.UpdatePassword(ctx, user.ID, string(hashed)); err != nil {
+		return err
+	}
+	user.Password = string(hashed)
+	user.TokenVersion++
+	if err := accounts.tokens.RevokeUser(ctx, user.ID); err != nil {
+		return err
+	}
+	return accounts.rotateGuacamolePassword(ctx, user)
+}
+
+// manage заменяет пароль Guacamole пользователя паролем, управляемым сервером,
+// если пароль Guacamole пользователя еще равен его паролю в приложении
+// (пользователь зарегистрировался с паролем или сменил его).
+func (accounts *externalAccounts) manage(ctx context.Context, user *common.User) error {
+	if len(user.GuacamoleCredentials) > 0 {
+		return nil
+	}
+	return accounts.rotateGuacamolePassword(ctx, user)
+}
+
+// create регистрирует пользователя со случайным паролем и сохраняет этот пароль как пароль Guacamole
+func (accounts *externalAccounts) create(ctx context.Context, email string, name string) (*common.User, error) {
+	password, err := newSecretToken()
+	if err != nil {
+		return nil, err
+	}
+	if name = strings.TrimSpace(name); len([]rune(name)) < 4 {
+		name = email
+	}
+	err = registerUser(ctx, accounts.users, accounts.guacamole, common.AuthSignUpRequest{
+		Name:                 name,
+		Email:                email,
+		Password:             password,
+		PasswordConfirmation: password,
+	}, config.ServerConfig.AuthConfig.DefaultRole, true)
+	if err != nil {
+		return nil, err
+	}
+	user, err := accounts.users.FindByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	credentials, err := sealGuacamolePassword(password)
+	if err != nil {
+		return nil, err
+	}
+	if err := accounts.users.SetGuacamoleCredentials(ctx, user.ID, credentials); err != nil {
+		return nil, err
+	}
+	user.GuacamoleCredentials = credentials
+	return user, nil
+}
+
+// rotateGuacamolePassword заменяет пароль Guacamole пользователя случайным и сохраняет его.
+// Изменение затрагивает две базы данных и выполняется как сага: если пароль не удалось
+// сохранить, прежний пароль Guacamole восстанавливается.
+func (accounts *externalAccounts) rotateGuacamolePassword(ctx context.Context, user *common.User) error {
+	password, err := newSecretToken()
+	if err != nil {
+		return err
+	}
+	credentials, err := sealGuacamolePassword(password)
+	if err != nil {
+		return err
+	}
+	saltHex := getGuacamoleSault()
+	guacamolePassword := common.GuacamolePassword{
+		HashHex: getHashedGuacamolePassword(password, saltHex),
+		SaltHex: saltHex,
+	}
+
+	var previous *common.GuacamolePassword
+	err = newSaga("rotate guacamole password").
+		step(
+			"update guacamole password",
+			func(ctx context.Context) error {
+				var err error
+				previous, err = accounts.guacamole.SetUserPassword(ctx, user.Email, guacamolePassword)
+				if errors.Is(err, sql.ErrNoRows) {
+					return errors.New("guacamole account not found")
+				}
+				return err
+			},
+			func(ctx context.Context) error {
+				_, err := accounts.guacamole.SetUserPassword(ctx, user.Email, *previous)
+				return err
+			},
+		).
+		step(
+			"store guacamole password",
+			func(ctx context.Context) error {
+				return accounts.users.SetGuacamoleCredentials(ctx, user.ID, credentials)
+			},
+			nil,
+		).
+		run(ctx)
+	if err != nil {
+		return err
+	}
+	user.GuacamoleCredentials = credentials
+	return nil
+}
+
+// syncRole назначает пользователю роль по его группам у провайдера.
+// Если группам соответствуют несколько ролей, назначается роль с наибольшими полномочиями,
+// если ни одна группа не указана в соответствии — AUTH_DEFAULT_ROLE. Без настроенного
+// соответствия роль не изменяется, чтобы не отменить роль, назначенную администратором.
+//
+// Параметры:
+//   - ctx: контекст
+//   - user: пользователь
+//   - groups: группы пользователя у провайдера
+//   - mapping: роли для групп провайдера
+//
+// Возвращает:
+//   - error: ошибка назначения роли
+func (accounts *externalAccounts) syncRole(
+	ctx context.Context,
+	user *common.User,
+	groups []string,
+	mapping map[string]string,
+) error {
+	if len(mapping) == 0 {
+		return nil
+	}
+	role := config.ServerConfig.AuthConfig.DefaultRole
+	for _, group := range groups {
+		if mapped, ok := mapping[group]; ok && common.RoleRank(mapped) > common.RoleRank(role) {
+			role = mapped
+		}
+	}
+	if role == user.Role {
+		return nil
+	}
+	if err := accounts.roles.Assign(ctx, user, role); err != nil {
+		return fmt.Errorf("failed to sync role: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+)
+
+func TestExternalAccountsProvisionReclaimsUnverifiedAccount(t *testing.T) {
+	fixture := newSignUpFixture(t)
+	ctx := context.Background()
+
+	// Злоумышленник регистрируется с адресом владельца почты, но не может его подтвердить
+	fixture.signUp(t, "ivan@example.com", "attacker-password")
+	registered, _ := fixture.guacamole.password("ivan@example.com")
+
+	// Владелец почты входит через внешний провайдер, который подтвердил адрес
+	accounts := newExternalAccounts(fixture.users, fixture.guacamole, NewTokenService(fixture.sessions, fixture.users))
+	user, err := accounts.provision(ctx, "ivan@example.com", "Иван Петров")
+	if err != nil {
+		t.Fatalf("provision() error = %v", err)
+	}
+
+	stored := fixture.users.get("ivan@example.com")
+	if stored.ID != user.ID || stored.EmailVerifiedAt == nil {
+		t.Fatalf("provisioned user = %+v, want the registered user verified", stored)
+	}
+	_, err = NewLocalAuthenticator(fixture.users).Authenticate(ctx, "ivan@example.com", "attacker-password")
+	if !errors.Is(err, ErrInvalidPassword) {
+		t.Errorf("Authenticate() with the password set at sign-up error = %v, want ErrInvalidPassword", err)
+	}
+	if stored.TokenVersion == 0 {
+		t.Error("token version was not incremented")
+	}
+	if revoked := fixture.sessions.revokedUsers(); len(revoked) != 1 || revoked[0] != user.ID {
+		t.Errorf("revoked sessions of %v, want %s", revoked, user.ID)
+	}
+
+	guacamolePassword, _ := fixture.guacamole.password("ivan@example.com")
+	if guacamolePassword == registered ||
+		guacamolePassword.HashHex == getHashedGuacamolePassword("attacker-password", guacamolePassword.SaltHex) {
+		t.Error("guacamole password set at sign-up still works")
+	}
+	if len(stored.GuacamoleCredentials) == 0 {
+		t.Fatal("guacamole password is not managed by the server")
+	}
+	managed, err := openGuacamolePassword(stored.GuacamoleCredentials)
+	if err != nil || guacamolePassword.HashHex != getHashedGuacamolePassword(managed, guacamolePassword.SaltHex) {
+		t.Errorf("stored guacamole password does not match guacamole: %v", err)
+	}
+	if fixture.guacamole.isDisabled("ivan@example.com") {
+		t.Error("guacamole account is still disabled")
+	}
+}
+
+func TestExternalAccountsProvisionKeepsVerifiedPassword(t *testing.T) {
+	fixture := newSignUpFixture(t)
+	ctx := context.Background()
+	fixture.signUp(t, "ivan@example.com", "user-password")
+	token := fixture.verificationToken(t, "ivan@example.com")
+	if err := fixture.verification.Verify(ctx, common.EmailVerificationRequest{Token: token}); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	accounts := newExternalAccounts(fixture.users, fixture.guacamole, NewTokenService(fixture.sessions, fixture.users))
+	if _, err := accounts.provision(ctx, "ivan@example.com", "Иван Петров"); err != nil {
+		t.Fatalf("provision() error = %v", err)
+	}
+
+	// Владелец подтвердил почту сам, поэтому вход по его паролю продолжает работать
+	if _, err := NewLocalAuthenticator(fixture.users).Authenticate(ctx, "ivan@example.com", "user-password"); err != nil {
+		t.Errorf("Authenticate() with the verified user's password error = %v", err)
+	}
+	if revoked := fixture.sessions.revokedUsers(); len(revoked) != 0 {
+		t.Errorf("revoked sessions of %v, want none", revoked)
+	}
+}
+
+func TestExternalAccountsProvisionReclaimFailure(t *testing.T) {
+	fixture := newSignUpFixture(t)
+	ctx := context.Background()
+	fixture.signUp(t, "ivan@example.com", "attacker-password")
+	// Пароль Guacamole заменить нельзя: учетная запись не должна быть подтверждена
+	delete(fixture.guacamole.passwords, "ivan@example.com")
+
+	accounts := newExternalAccounts(fixture.users, fixture.guacamole, NewTokenService(fixture.sessions, fixture.users))
+	if _, err := accounts.provision(ctx, "ivan@example.com", "Иван Петров"); err == nil {
+		t.Fatal("provision() without guacamole account succeeded")
+	}
+	if user := fixture.users.get("ivan@example.com"); user.EmailVerifiedAt != nil {
+		t.Error("email was verified although the guacamole password was not replaced")
+	}
+	_, err := NewLocalAuthenticator(fixture.users).Authenticate(ctx, "ivan@example.com", "attacker-password")
+	if !errors.Is(err, ErrInvalidPassword) {
+		t.Errorf("Authenticate() with the password set at sign-up error = %v, want ErrInvalidPassword", err)
+	}
+}
@@ -0,0 +1,67 @@
+// Package service реализует бизнес-логику приложения.
+package service
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/config"
+)
+
+// Пароль Guacamole нужен серверу, чтобы получить новый токен Guacamole, когда прежний истек,
+// не запрашивая пароль у пользователя. Пароль хранится в сессии входа зашифрованным
+// AES-256-GCM и удаляется при отзыве сессии. Тем же ключом шифруются секреты TOTP.
+
+// credentialsCipher возвращает AEAD шифр с ключом из конфигурации
+func credentialsCipher() (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte(config.ServerConfig.GuacamoleCredentialsKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealSecret шифрует данные (случайный nonce записывается перед шифротекстом)
+func sealSecret(plaintext []byte) ([]byte, error) {
+	aead, err := credentialsCipher()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// openSecret расшифровывает данные, зашифрованные sealSecret
+func openSecret(sealed []byte) ([]byte, error) {
+	aead, err := credentialsCipher()
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("encrypted secret is missing")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, nil)
+}
+
+// sealGuacamolePassword шифрует пароль Guacamole
+func sealGuacamolePassword(password string) ([]byte, error) {
+	return sealSecret([]byte(password))
+}
+
+// openGuacamolePassword расшифровывает пароль Guacamole, зашифрованный sealGuacamolePassword
+func openGuacamolePassword(sealed []byte) (string, error) {
+	password, err := openSecret(sealed)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt guacamole credentials: %w", err)
+	}
+	return string(password), nil
+}
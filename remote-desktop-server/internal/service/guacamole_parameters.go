@@ -0,0 +1,116 @@
+// Package service реализует бизнес-логику приложения.
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// guacTag имя тега, содержащего название параметра в API Guacamole
+const guacTag = "guac"
+
+// encodeGuacamoleParameters переносит поля структуры с тегом guac в карту параметров Guacamole.
+// Пустые строки, нулевые числа и false не переносятся, чтобы Guacamole использовал значения по умолчанию.
+//
+// Параметры:
+//   - src: указатель на структуру параметров (может быть nil)
+//   - dst: карта параметров Guacamole
+func encodeGuacamoleParameters(src interface{}, dst map[string]string) {
+	value := reflect.ValueOf(src)
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return
+	}
+
+	fields := value.Type()
+	for i := 0; i < fields.NumField(); i++ {
+		name := fields.Field(i).Tag.Get(guacTag)
+		if name == "" {
+			continue
+		}
+		field := value.Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			if field.String() != "" {
+				dst[name] = field.String()
+			}
+		case reflect.Bool:
+			if field.Bool() {
+				dst[name] = "true"
+			}
+		case reflect.Int:
+			if field.Int() != 0 {
+				dst[name] = strconv.FormatInt(field.Int(), 10)
+			}
+		}
+	}
+}
+
+// decodeGuacamoleParameters заполняет поля структуры с тегом guac из карты параметров Guacamole.
+//
+// Параметры:
+//   - src: карта параметров Guacamole
+//   - dst: указатель на структуру параметров
+//
+// Возвращает:
+//   - error: ошибка, если значение параметра не соответствует типу поля
+func decodeGuacamoleParameters(src map[string]string, dst interface{}) error {
+	value := reflect.ValueOf(dst)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("parameters target must be a pointer to struct, got %T", dst)
+	}
+	value = value.Elem()
+
+	fields := value.Type()
+	for i := 0; i < fields.NumField(); i++ {
+		name := fields.Field(i).Tag.Get(guacTag)
+		raw, ok := src[name]
+		if name == "" || !ok || raw == "" {
+			continue
+		}
+		field := value.Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(raw)
+		case reflect.Bool:
+			field.SetBool(raw == "true")
+		case reflect.Int:
+			number, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				return fmt.Errorf("parameter %q is not a number: %w", name, err)
+			}
+			field.SetInt(number)
+		}
+	}
+	return nil
+}
+
+// guacamoleParameterNames возвращает имена параметров Guacamole, описанных тегами guac структуры.
+//
+// Параметры:
+//   - src: указатель на структуру параметров (может быть nil)
+//
+// Возвращает:
+//   - map[string]struct{}: множество имен параметров
+func guacamoleParameterNames(src interface{}) map[string]struct{} {
+	names := make(map[string]struct{})
+	if src == nil {
+		return names
+	}
+	fields := reflect.Indirect(reflect.ValueOf(src)).Type()
+	if fields.Kind() != reflect.Struct {
+		return names
+	}
+	for i := 0; i < fields.NumField(); i++ {
+		if name := fields.Field(i).Tag.Get(guacTag); name != "" {
+			names[name] = struct{}{}
+		}
+	}
+	return names
+}
@@ -0,0 +1,156 @@
+// Package service реализует бизнес-логику приложения.
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/repository"
+)
+
+// HistoryService предоставляет историю подключений Guacamole и отчеты по ней.
+// Администратор Guacamole видит историю всех пользователей, остальные пользователи — только свою.
+type HistoryService struct {
+	history  repository.HistoryRepository
+	sessions *SessionService
+}
+
+// NewHistoryService создает и возвращает новый экземпляр HistoryService.
+//
+// Параметры:
+//   - history: репозиторий истории подключений
+//   - sessions: сервис подключений, через который проверяются права в Guacamole
+//
+// Возвращает:
+//   - *HistoryService: указатель на созданный сервис
+func NewHistoryService(history repository.HistoryRepository, sessions *SessionService) *HistoryService {
+	return &HistoryService{
+		history:  history,
+		sessions: sessions,
+	}
+}
+
+// GetHistory возвращает страницу записей истории подключений.
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//   - filter: параметры выборки и пагинации
+//   - username: имя пользователя Guacamole (email) вызывающего
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - *common.HistoryPage: страница истории
+//   - error: ErrForbidden при запросе чужой истории без прав администратора
+func (service *HistoryService) GetHistory(
+	ctx context.Context,
+	filter *common.HistoryFilter,
+	username string,
+	guacToken string,
+) (*common.HistoryPage, error) {
+	if err := service.restrict(filter, username, guacToken); err != nil {
+		return nil, err
+	}
+	records, total, err := service.history.FindRecords(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch connection history: %w", err)
+	}
+	return &common.HistoryPage{
+		Items:   records,
+		Total:   total,
+		Page:    filter.Page,
+		PerPage: filter.PerPage,
+	}, nil
+}
+
+// WeeklyUsage возвращает отчет о суммарном времени подключений пользователей по неделям.
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//   - filter: параметры выборки
+//   - username: имя пользователя Guacamole (email) вызывающего
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - []*common.UserWeeklyUsage: строки отчета
+//   - error: ErrForbidden при запросе чужой истории без прав администратора
+func (service *HistoryService) WeeklyUsage(
+	ctx context.Context,
+	filter *common.HistoryFilter,
+	username string,
+	guacToken string,
+) ([]*common.UserWeeklyUsage, error) {
+	if err := service.restrict(filter, username, guacToken); err != nil {
+		return nil, err
+	}
+	report, err := service.history.WeeklyUsage(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build weekly usage report: %w", err)
+	}
+	return report, nil
+}
+
+// TopHosts возвращает отчет о наиболее используемых хостах.
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//   - filter: параметры выборки
+//   - username: имя пользователя Guacamole (email) вызывающего
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - []*common.HostUsage: строки отчета
+//   - error: ErrForbidden при запросе чужой истории без прав администратора
+func (service *HistoryService) TopHosts(
+	ctx context.Context,
+	filter *common.HistoryFilter,
+	username string,
+	guacToken string,
+) ([]*common.HostUsage, error) {
+	if err := service.restrict(filter, username, guacToken); err != nil {
+		return nil, err
+	}
+	report, err := service.history.TopHosts(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build top hosts report: %w", err)
+	}
+	return report, nil
+}
+
+// restrict ограничивает выборку историей вызывающего, если он не администратор Guacamole.
+//
+// Параметры:
+//   - filter: параметры выборки (изменяются на месте)
+//   - username: имя пользователя Guacamole (email) вызывающего
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - error: ErrForbidden, если пользователь запросил чужую историю
+func (service *HistoryService) restrict(filter *common.HistoryFilter, username string, guacToken string) error {
+	return restrictToUser(service.sessions, filter, username, guacToken)
+}
+
+// restrictToUser ограничивает выборку данными вызывающего, если он не администратор Guacamole.
+//
+// Параметры:
+//   - sessions: сервис подключений для проверки прав
+//   - filter: параметры выборки (изменяются на месте)
+//   - username: имя пользователя Guacamole (email) вызывающего
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - error: ErrForbidden, если пользователь запросил данные другого пользователя
+func restrictToUser(sessions *SessionService, filter *common.HistoryFilter, username string, guacToken string) error {
+	isAdmin, err := sessions.isAdministrator(guacToken)
+	if err != nil {
+		return err
+	}
+	if isAdmin {
+		return nil
+	}
+	if filter.Username != "" && filter.Username != username {
+		return ErrForbidden
+	}
+	filter.Username = username
+	return nil
+}
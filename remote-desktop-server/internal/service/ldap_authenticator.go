@@ -0,0 +1,201 @@
+// Package service реализует бизнес-логику приложения.
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/repository"
+)
+
+// LDAPAuthenticator проверяет пароль в каталоге LDAP (Active Directory).
+//
+// Пользователь ищется фильтром LDAP_USER_FILTER от имени служебной учетной записи,
+// затем пароль проверяется привязкой (bind) от имени найденной записи. При первом входе
+// пользователь и пользователь Guacamole создаются так же, как при регистрации, а группы
+// каталога при каждом входе определяют роль пользователя (LDAP_GROUP_ROLES).
+type LDAPAuthenticator struct {
+	config   common.LDAPConfig
+	accounts *externalAccounts
+}
+
+// ldapIdentity содержит сведения о пользователе из записи каталога
+type ldapIdentity struct {
+	Email  string
+	Name   string
+	Groups []string
+}
+
+// NewLDAPAuthenticator создает способ проверки пароля в каталоге LDAP.
+//
+// Параметры:
+//   - config: параметры подключения и поиска
+//   - users: репозиторий пользователей
+//   - guacamole: репозиторий Guacamole
+//   - tokens: сервис токенов (отзыв сессий неподтвержденной учетной записи при первом входе)
+//
+// Возвращает:
+//   - *LDAPAuthenticator: указатель на созданный способ проверки
+func NewLDAPAuthenticator(
+	config common.LDAPConfig,
+	users repository.UserRepository,
+	guacamole repository.GuacamoleRepository,
+	tokens *TokenService,
+) *LDAPAuthenticator {
+	return &LDAPAuthenticator{
+		config:   config,
+		accounts: newExternalAccounts(users, guacamole, tokens),
+	}
+}
+
+// Name возвращает название способа для журнала
+func (authenticator *LDAPAuthenticator) Name() string {
+	return "ldap"
+}
+
+// Authenticate проверяет пароль в каталоге и возвращает пользователя приложения.
+//
+// Параметры:
+//   - ctx: контекст
+//   - email: электронная почта (подставляется в фильтр поиска)
+//   - password: пароль
+//
+// Возвращает:
+//   - *common.User: пользователь (созданный при первом входе)
+//   - error: ErrUnknownUser, если запись не найдена, ErrInvalidPassword при неверном пароле,
+//     ошибка подключения к каталогу, создания пользователя или назначения роли
+func (authenticator *LDAPAuthenticator) Authenticate(ctx context.Context, email string, password string) (*common.User, error) {
+	// Привязка с пустым паролем в LDAP считается анонимной и завершается успешно
+	if password == "" {
+		return nil, ErrInvalidPassword
+	}
+	identity, err := authenticator.verify(email, password)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := authenticator.accounts.provision(ctx, identity.Email, identity.Name)
+	if err != nil {
+		return nil, err
+	}
+	err = authenticator.accounts.syncRole(ctx, user, identity.Groups, authenticator.config.GroupRoles)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// verify ищет запись пользователя и проверяет пароль привязкой от ее имени
+func (authenticator *LDAPAuthenticator) verify(email string, password string) (*ldapIdentity, error) {
+	cfg := authenticator.config
+	conn, err := authenticator.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if cfg.BindDN != "" {
+		if err := conn.Bind(cfg.BindDN, cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("ldap service bind failed: %w", err)
+		}
+	}
+	request := ldap.NewSearchRequest(
+		cfg.BaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		2, // Достаточно, чтобы обнаружить неоднозначный фильтр
+		int(cfg.Timeout.Seconds()),
+		false,
+		strings.ReplaceAll(cfg.UserFilter, "%s", ldap.EscapeFilter(email)),
+		[]string{cfg.EmailAttribute, cfg.NameAttribute, cfg.GroupAttribute},
+		nil,
+	)
+	result, err := conn.Search(request)
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("ldap filter matches several entries for %s", email)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ldap search failed: %w", err)
+	}
+	switch len(result.Entries) {
+	case 0:
+		return nil, ErrUnknownUser
+	case 1:
+	default:
+		return nil, fmt.Errorf("ldap filter matches several entries for %s", email)
+	}
+	entry := result.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidPassword
+		}
+		return nil, fmt.Errorf("ldap user bind failed: %w", err)
+	}
+
+	identity := &ldapIdentity{
+		Email: entry.GetAttributeValue(cfg.EmailAttribute),
+		Name:  entry.GetAttributeValue(cfg.NameAttribute),
+	}
+	if identity.Email == "" {
+		identity.Email = email
+	}
+	for _, group := range entry.GetAttributeValues(cfg.GroupAttribute) {
+		identity.Groups = append(identity.Groups, ldapGroupNames(group)...)
+	}
+	return identity, nil
+}
+
+// dial подключается к каталогу и при необходимости включает TLS командой StartTLS
+func (authenticator *LDAPAuthenticator) dial() (*ldap.Conn, error) {
+	cfg := authenticator.config
+	if cfg.URL == "" {
+		return nil, errors.New("ldap is not configured")
+	}
+	address, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ldap url: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		ServerName:         address.Hostname(),
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	conn, err := ldap.DialURL(
+		cfg.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: cfg.Timeout}),
+		ldap.DialWithTLSConfig(tlsConfig),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ldap connection failed: %w", err)
+	}
+	conn.SetTimeout(cfg.Timeout)
+	if cfg.StartTLS && address.Scheme != "ldaps" {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap starttls failed: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+// ldapGroupNames возвращает имена, по которым группа ищется в LDAP_GROUP_ROLES:
+// DN группы и значение первого RDN (CN группы)
+func ldapGroupNames(group string) []string {
+	names := []string{group}
+	dn, err := ldap.ParseDN(group)
+	if err != nil || len(dn.RDNs) == 0 || len(dn.RDNs[0].Attributes) == 0 {
+		return names
+	}
+	if cn := dn.RDNs[0].Attributes[0].Value; cn != group {
+		names = append(names, cn)
+	}
+	return names
+}
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/ldaptest"
+)
+
+const (
+	ldapServiceDN = "cn=remote-desktop,ou=services,dc=example,dc=com"
+	ldapUserDN    = "cn=Ivan Petrov,ou=people,dc=example,dc=com"
+)
+
+// ldapEntries записи каталога: служебная учетная запись и пользователь в группе администраторов
+func ldapEntries() []ldaptest.Entry {
+	return []ldaptest.Entry{
+		{DN: ldapServiceDN, Password: "service-password"},
+		{
+			DN:       ldapUserDN,
+			Password: "directory-password",
+			Attributes: map[string][]string{
+				"objectClass": {"person"},
+				"mail":        {"ivan@example.com"},
+				"displayName": {"Иван Петров"},
+				"memberOf":    {"cn=rd-admins,ou=groups,dc=example,dc=com", "cn=staff,ou=groups,dc=example,dc=com"},
+			},
+		},
+	}
+}
+
+// ldapFixture способ проверки пароля в локальном каталоге LDAP
+type ldapFixture struct {
+	authenticator *LDAPAuthenticator
+	server        *ldaptest.Server
+	users         *fakeUsers
+	guacamole     *fakeGuacamole
+	sessions      *fakeAuthSessions
+}
+
+func newLDAPFixture(t *testing.T, entries []ldaptest.Entry, modify func(cfg *common.LDAPConfig)) *ldapFixture {
+	t.Helper()
+	fixture := &ldapFixture{
+		server:    ldaptest.NewServer(entries...),
+		users:     newFakeUsers(),
+		guacamole: newFakeGuacamole(),
+		sessions:  &fakeAuthSessions{},
+	}
+	t.Cleanup(fixture.server.Close)
+	cfg := common.LDAPConfig{
+		URL:            fixture.server.URL,
+		BindDN:         ldapServiceDN,
+		BindPassword:   "service-password",
+		BaseDN:         "dc=example,dc=com",
+		UserFilter:     "(&(objectClass=person)(mail=%s))",
+		EmailAttribute: "mail",
+		NameAttribute:  "displayName",
+		GroupAttribute: "memberOf",
+		GroupRoles:     map[string]string{"rd-admins": common.RoleAdmin},
+		Timeout:        5 * time.Second,
+	}
+	if modify != nil {
+		modify(&cfg)
+	}
+	tokens := NewTokenService(fixture.sessions, fixture.users)
+	fixture.authenticator = NewLDAPAuthenticator(cfg, fixture.users, fixture.guacamole, tokens)
+	return fixture
+}
+
+func TestLDAPAuthenticatorProvisionsUser(t *testing.T) {
+	fixture := newLDAPFixture(t, ldapEntries(), nil)
+
+	user, err := fixture.authenticator.Authenticate(context.Background(), "Ivan@Example.com", "directory-password")
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if user.Email != "ivan@example.com" || user.Name != "Иван Петров" {
+		t.Errorf("Authenticate() = %s <%s>, want the directory name and email", user.Name, user.Email)
+	}
+	if binds := fixture.server.Binds(); !reflect.DeepEqual(binds, []string{ldapServiceDN, ldapUserDN}) {
+		t.Errorf("directory binds = %q, want service account then user", binds)
+	}
+
+	stored := fixture.users.get("ivan@example.com")
+	if stored == nil || stored.EmailVerifiedAt == nil || len(stored.GuacamoleCredentials) == 0 {
+		t.Fatalf("provisioned user = %+v, want verified user with server-managed guacamole password", stored)
+	}
+	if stored.Role != common.RoleAdmin {
+		t.Errorf("role = %s, want %s from the rd-admins group", stored.Role, common.RoleAdmin)
+	}
+	permissions, _ := fixture.guacamole.FindSystemPermissions(context.Background(), stored.Email)
+	if !reflect.DeepEqual(permissions, common.RoleGuacamolePermissions(common.RoleAdmin)) {
+		t.Errorf("guacamole permissions = %q, want admin permissions", permissions)
+	}
+
+	// Повторный вход использует созданного пользователя
+	again, err := fixture.authenticator.Authenticate(context.Background(), "ivan@example.com", "directory-password")
+	if err != nil || again.ID != stored.ID {
+		t.Errorf("second Authenticate() = %v, %v, want user %s", again, err, stored.ID)
+	}
+}
+
+func TestLDAPAuthenticatorErrors(t *testing.T) {
+	duplicate := ldapEntries()[1]
+	duplicate.DN = "cn=Ivan Petrov 2,ou=people,dc=example,dc=com"
+
+	tests := []struct {
+		name     string
+		entries  []ldaptest.Entry
+		modify   func(cfg *common.LDAPConfig)
+		email    string
+		password string
+		wantErr  error
+		wantText string
+	}{
+		{
+			name:     "wrong password",
+			email:    "ivan@example.com",
+			password: "wrong-password",
+			wantErr:  ErrInvalidPassword,
+		},
+		{
+			name:     "empty password",
+			email:    "ivan@example.com",
+			password: "",
+			wantErr:  ErrInvalidPassword,
+		},
+		{
+			name:     "unknown user",
+			email:    "petr@example.com",
+			password: "directory-password",
+			wantErr:  ErrUnknownUser,
+		},
+		{
+			name:     "filter characters are escaped",
+			email:    "*",
+			password: "directory-password",
+			wantErr:  ErrUnknownUser,
+		},
+		{
+			name:     "user outside the base dn",
+			modify:   func(cfg *common.LDAPConfig) { cfg.BaseDN = "ou=groups,dc=example,dc=com" },
+			email:    "ivan@example.com",
+			password: "directory-password",
+			wantErr:  ErrUnknownUser,
+		},
+		{
+			name:     "several entries match",
+			entries:  append(ldapEntries(), duplicate),
+			email:    "ivan@example.com",
+			password: "directory-password",
+			wantText: "several entries",
+		},
+		{
+			name:     "service account password is wrong",
+			modify:   func(cfg *common.LDAPConfig) { cfg.BindPassword = "wrong-password" },
+			email:    "ivan@example.com",
+			password: "directory-password",
+			wantText: "service bind failed",
+		},
+		{
+			name:     "anonymous search is not allowed",
+			modify:   func(cfg *common.LDAPConfig) { cfg.BindDN, cfg.BindPassword = "", "" },
+			email:    "ivan@example.com",
+			password: "directory-password",
+			wantText: "search failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := tt.entries
+			if entries == nil {
+				entries = ldapEntries()
+			}
+			fixture := newLDAPFixture(t, entries, tt.modify)
+
+			user, err := fixture.authenticator.Authenticate(context.Background(), tt.email, tt.password)
+			switch {
+			case tt.wantErr != nil && !errors.Is(err, tt.wantErr):
+				t.Errorf("Authenticate() = %v, %v, want %v", user, err, tt.wantErr)
+			case tt.wantText != "" && (err == nil || !strings.Contains(err.Error(), tt.wantText)):
+				t.Errorf("Authenticate() = %v, %v, want error containing %q", user, err, tt.wantText)
+			}
+			if stored := fixture.users.get("ivan@example.com"); stored != nil {
+				t.Errorf("failed login created user %+v", stored)
+			}
+		})
+	}
+}
+
+func TestLDAPAuthenticatorAnonymousSearch(t *testing.T) {
+	fixture := newLDAPFixture(t, ldapEntries(), func(cfg *common.LDAPConfig) { cfg.BindDN, cfg.BindPassword = "", "" })
+	fixture.server.AllowAnonymousSearch()
+
+	if _, err := fixture.authenticator.Authenticate(context.Background(), "ivan@example.com", "directory-password"); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if binds := fixture.server.Binds(); !reflect.DeepEqual(binds, []string{ldapUserDN}) {
+		t.Errorf("directory binds = %q, want only the user", binds)
+	}
+}
+
+func TestLDAPGroupNames(t *testing.T) {
+	tests := []struct {
+		group string
+		want  []string
+	}{
+		{group: "cn=rd-admins,ou=groups,dc=example,dc=com", want: []string{"cn=rd-admins,ou=groups,dc=example,dc=com", "rd-admins"}},
+		{group: "CN=Domain Admins,CN=Users,DC=corp,DC=local", want: []string{"CN=Domain Admins,CN=Users,DC=corp,DC=local", "Domain Admins"}},
+		{group: "rd-operators", want: []string{"rd-operators"}},
+		{group: "not a dn", want: []string{"not a dn"}},
+	}
+	for _, tt := range tests {
+		if got := ldapGroupNames(tt.group); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ldapGroupNames(%q) = %q, want %q", tt.group, got, tt.want)
+		}
+	}
+}
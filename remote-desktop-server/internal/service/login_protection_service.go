@@ -0,0 +1,380 @@
+// Package service реализует бизнес-логику приложения.
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/repository"
+)
+
+// LoginThrottledError возвращается, если попытка входа отклонена без проверки пароля.
+// Сравнивается с ErrTooManyAttempts через errors.Is.
+type LoginThrottledError struct {
+	RetryAfter time.Duration // Через сколько можно повторить попытку
+	Locked     bool          // Учетная запись или адрес временно заблокированы (иначе — задержка между попытками)
+}
+
+// Error возвращает описание ошибки со временем до следующей попытки
+func (err *LoginThrottledError) Error() string {
+	if err.Locked {
+		return fmt.Sprintf("sign-in is temporarily locked, try again in %s", err.RetryAfter)
+	}
+	return fmt.Sprintf("too many failed sign-in attempts, try again in %s", err.RetryAfter)
+}
+
+// Unwrap позволяет сравнивать ошибку с ErrTooManyAttempts
+func (err *LoginThrottledError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+// LoginProtectionService защищает вход по паролю от подбора.
+//
+// Неудачные попытки учитываются отдельно для учетной записи и для адреса клиента.
+// После каждой неудачи следующая попытка разрешена не раньше, чем через задержку, удваиваемую
+// с каждой неудачей (LOGIN_DELAY_BASE, не больше LOGIN_DELAY_MAX), а после LOGIN_*_MAX_FAILURES
+// неудач вход блокируется на LOGIN_LOCKOUT_DURATION. Слишком ранняя попытка отклоняется до проверки
+// пароля, поэтому не нагружает способы проверки и Guacamole. Попытка учитывается как неудачная
+// еще до проверки пароля (Begin) и снимается, если пароль оказался верным, поэтому
+// одновременные попытки не обходят задержку. Неверные коды второго шага входа учитываются так же,
+// а неудачи учетной записи сбрасываются только после завершения входа. Попытки, блокировки и их снятие
+// администратором записываются в журнал входа.
+type LoginProtectionService struct {
+	attempts repository.LoginAttemptRepository
+	users    repository.UserRepository
+	config   common.LoginProtectionConfig
+	now      func() time.Time
+}
+
+// NewLoginProtectionService создает новый экземпляр LoginProtectionService.
+//
+// Параметры:
+//   - attempts: хранилище неудачных попыток и журнала входа (PostgreSQL или в памяти процесса)
+//   - users: репозиторий пользователей
+//   - config: правила задержек и блокировок
+//
+// Возвращает:
+//   - *LoginProtectionService: указатель на созданный сервис
+func NewLoginProtectionService(
+	attempts repository.LoginAttemptRepository,
+	users repository.UserRepository,
+	config common.LoginProtectionConfig,
+) *LoginProtectionService {
+	return &LoginProtectionService{
+		attempts: attempts,
+		users:    users,
+		config:   config,
+		now:      func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// ClientAddr возвращает адрес клиента, для которого учитываются попытки входа.
+// Первый адрес из X-Forwarded-For используется только при LOGIN_TRUST_FORWARDED_FOR=true,
+// иначе клиент мог бы обходить ограничения, подставляя произвольный заголовок.
+//
+// Параметры:
+//   - remoteAddr: адрес соединения (host:port)
+//   - forwardedFor: значение заголовка X-Forwarded-For
+//
+// Возвращает:
+//   - string: адрес клиента без порта
+func (service *LoginProtectionService) ClientAddr(remoteAddr string, forwardedFor string) string {
+	if service.config.TrustForwardedFor && forwardedFor != "" {
+		first, _, _ := strings.Cut(forwardedFor, ",")
+		if first = strings.TrimSpace(first); first != "" {
+			return first
+		}
+	}
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return host
+	}
+	return remoteAddr
+}
+
+// LoginAttempt попытка входа, учтенная LoginProtectionService.Begin.
+// Результат проверки учетных данных сообщается ровно одним вызовом Failed, Succeeded или Cancel.
+type LoginAttempt struct {
+	Email        string // Email, указанный при входе
+	RemoteAddr   string // Адрес клиента
+	reservations []*common.LoginReservation
+}
+
+// Begin учитывает попытку входа в учетную запись с адреса клиента до проверки учетных данных.
+// Попытка считается неудачной, пока не подтверждено обратное (Succeeded или Cancel), поэтому
+// одновременные попытки не могут обойти задержку, пока проверяется пароль или код.
+//
+// Параметры:
+//   - ctx: контекст
+//   - email: email, указанный при входе
+//   - remoteAddr: адрес клиента (пустой — адрес не учитывается)
+//
+// Возвращает:
+//   - *LoginAttempt: учтенная попытка
+//   - error: *LoginThrottledError, если действует задержка или блокировка, или ошибка хранилища попыток
+func (service *LoginProtectionService) Begin(ctx context.Context, email string, remoteAddr string) (*LoginAttempt, error) {
+	now := service.now()
+	attempt := &LoginAttempt{Email: email, RemoteAddr: remoteAddr}
+	var throttled *LoginThrottledError
+	for _, subject := range service.subjects(email, remoteAddr) {
+		reservation, err := service.attempts.Reserve(ctx, subject[0], subject[1], now, service.policy(subject[0]))
+		if err != nil {
+			service.Cancel(ctx, attempt)
+			return nil, err
+		}
+		if reservation.RetryAfter == 0 {
+			attempt.reservations = append(attempt.reservations, reservation)
+			continue
+		}
+		if wait := roundUpToSecond(reservation.RetryAfter); throttled == nil || wait > throttled.RetryAfter {
+			throttled = &LoginThrottledError{RetryAfter: wait, Locked: reservation.Locked}
+		}
+	}
+	if throttled == nil {
+		return attempt, nil
+	}
+	service.Cancel(ctx, attempt)
+	service.audit(ctx, &common.LoginAuditEntry{
+		Event:      common.LoginEventThrottled,
+		Email:      email,
+		RemoteAddr: remoteAddr,
+		Detail:     throttled.Error(),
+	})
+	return nil, throttled
+}
+
+// Failed подтверждает неудачу попытки: она уже учтена Begin, поэтому остается записать ее в журнал
+// и сообщить о блокировке, если попытка ее установила.
+//
+// Параметры:
+//   - ctx: контекст
+//   - attempt: попытка, учтенная Begin
+//   - detail: причина неудачи для журнала входа (пустая — неверный пароль или неизвестный пользователь)
+func (service *LoginProtectionService) Failed(ctx context.Context, attempt *LoginAttempt, detail string) {
+	service.audit(ctx, &common.LoginAuditEntry{
+		Event:      common.LoginEventFailed,
+		Email:      attempt.Email,
+		RemoteAddr: attempt.RemoteAddr,
+		Detail:     detail,
+	})
+	for _, reservation := range attempt.reservations {
+		throttle := reservation.Throttle
+		// Блокировка устанавливается, когда счетчик достигает порога; дальнейшие неудачи ее не продлевают
+		if throttle.LockedUntil == nil || throttle.Failures != service.policy(throttle.Scope).MaxFailures {
+			continue
+		}
+		slog.Warn(fmt.Sprintf(
+			"Sign-in of %s %s is locked until %s after %d failed attempts",
+			throttle.Scope, throttle.Key, throttle.LockedUntil.Format(time.RFC3339), throttle.Failures,
+		))
+		service.audit(ctx, &common.LoginAuditEntry{
+			Event:      common.LoginEventLocked,
+			Email:      attempt.Email,
+			RemoteAddr: attempt.RemoteAddr,
+			Detail: fmt.Sprintf(
+				"%s locked until %s after %d failed attempts",
+				throttle.Scope, throttle.LockedUntil.Format(time.RFC3339), throttle.Failures,
+			),
+		})
+	}
+	attempt.reservations = nil
+}
+
+// Succeeded снимает учет попытки и сбрасывает неудачные попытки входа в учетную запись.
+// Вызывается, когда вход завершен, то есть после второго шага, если он требуется.
+// Прежние неудачи с адреса клиента не сбрасываются: иначе подбор паролей к чужим учетным записям
+// можно было бы чередовать со входом в свою.
+//
+// Параметры:
+//   - ctx: контекст
+//   - attempt: попытка, учтенная Begin
+func (service *LoginProtectionService) Succeeded(ctx context.Context, attempt *LoginAttempt) {
+	service.Cancel(ctx, attempt)
+	if _, err := service.attempts.Reset(ctx, common.LoginScopeAccount, loginAccountKey(attempt.Email)); err != nil {
+		slog.Error(fmt.Sprintf("Error resetting failed sign-ins of %s: %s", attempt.Email, err.Error()))
+	}
+	service.audit(ctx, &common.LoginAuditEntry{
+		Event:      common.LoginEventSucceeded,
+		Email:      attempt.Email,
+		RemoteAddr: attempt.RemoteAddr,
+	})
+}
+
+// Cancel снимает учет попытки, которая не оказалась неудачной, но и не завершила вход:
+// пароль верен, но нужен второй шаг или подтверждение почты, либо проверку выполнить не удалось.
+// Ошибки хранилища только журналируются, чтобы не скрывать от клиента результат проверки.
+//
+// Параметры:
+//   - ctx: контекст
+//   - attempt: попытка, учтенная Begin
+func (service *LoginProtectionService) Cancel(ctx context.Context, attempt *LoginAttempt) {
+	for _, reservation := range attempt.reservations {
+		if err := service.attempts.Release(ctx, reservation); err != nil {
+			slog.Error(fmt.Sprintf(
+				"Error releasing sign-in attempt of %s %s: %s",
+				reservation.Throttle.Scope, reservation.Throttle.Key, err.Error(),
+			))
+		}
+	}
+	attempt.reservations = nil
+}
+
+// GetLocked возвращает действующие блокировки входа.
+//
+// Параметры:
+//   - ctx: контекст
+//
+// Возвращает:
+//   - []*common.LoginThrottle: заблокированные учетные записи и адреса
+//   - error: ошибка чтения блокировок
+func (service *LoginProtectionService) GetLocked(ctx context.Context) ([]*common.LoginThrottle, error) {
+	return service.attempts.FindLocked(ctx, service.now())
+}
+
+// GetAudit возвращает страницу журнала входа.
+//
+// Параметры:
+//   - ctx: контекст
+//   - filter: фильтры и пагинация
+//
+// Возвращает:
+//   - *common.LoginAuditPage: страница журнала
+//   - error: ошибка чтения журнала
+func (service *LoginProtectionService) GetAudit(
+	ctx context.Context,
+	filter *common.LoginAuditFilter,
+) (*common.LoginAuditPage, error) {
+	entries, total, err := service.attempts.FindAuditPage(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return &common.LoginAuditPage{
+		Items:   entries,
+		Total:   total,
+		Page:    filter.Page,
+		PerPage: filter.PerPage,
+	}, nil
+}
+
+// Unlock снимает блокировку и сбрасывает неудачные попытки входа для учетной записи или адреса.
+//
+// Параметры:
+//   - ctx: контекст
+//   - actor: администратор, снимающий блокировку
+//   - form: account и email либо ip и адрес клиента
+//
+// Возвращает:
+//   - error: ErrNotFound, если неудачных попыток не было, или ошибка хранилища
+func (service *LoginProtectionService) Unlock(ctx context.Context, actor *common.User, form common.LoginUnlockRequest) error {
+	key := strings.TrimSpace(form.Key)
+	if form.Scope == common.LoginScopeAccount {
+		key = loginAccountKey(key)
+	}
+	existed, err := service.attempts.Reset(ctx, form.Scope, key)
+	if err != nil {
+		return err
+	}
+	if !existed {
+		return fmt.Errorf("%w: no failed sign-ins of %s %s", ErrNotFound, form.Scope, key)
+	}
+	service.unlocked(ctx, actor, form.Scope, key)
+	return nil
+}
+
+// UnlockUser снимает блокировку входа в учетную запись пользователя.
+// Если неудачных попыток не было, ничего не меняется.
+//
+// Параметры:
+//   - ctx: контекст
+//   - actor: администратор, снимающий блокировку
+//   - id: идентификатор пользователя
+//
+// Возвращает:
+//   - error: ErrNotFound, если пользователь не найден, или ошибка хранилища
+func (service *LoginProtectionService) UnlockUser(ctx context.Context, actor *common.User, id string) error {
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return ErrNotFound
+	}
+	user, err := service.users.FindByIDWithDeleted(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	key := loginAccountKey(user.Email)
+	existed, err := service.attempts.Reset(ctx, common.LoginScopeAccount, key)
+	if err != nil {
+		return err
+	}
+	if existed {
+		service.unlocked(ctx, actor, common.LoginScopeAccount, key)
+	}
+	return nil
+}
+
+// unlocked журналирует снятие блокировки администратором
+func (service *LoginProtectionService) unlocked(ctx context.Context, actor *common.User, scope string, key string) {
+	slog.Info(fmt.Sprintf("Sign-in of %s %s unlocked by %s", scope, key, actor.Email))
+	entry := &common.LoginAuditEntry{
+		Event:  common.LoginEventUnlocked,
+		Actor:  actor.Email,
+		Detail: scope + " unlocked",
+	}
+	if scope == common.LoginScopeAccount {
+		entry.Email = key
+	} else {
+		entry.RemoteAddr = key
+	}
+	service.audit(ctx, entry)
+}
+
+// subjects возвращает учетную запись и адрес клиента, для которых учитываются попытки входа
+func (service *LoginProtectionService) subjects(email string, remoteAddr string) [][2]string {
+	subjects := [][2]string{{common.LoginScopeAccount, loginAccountKey(email)}}
+	if remoteAddr != "" {
+		subjects = append(subjects, [2]string{common.LoginScopeIP, remoteAddr})
+	}
+	return subjects
+}
+
+// policy возвращает правила подсчета неудач для учетной записи или адреса
+func (service *LoginProtectionService) policy(scope string) common.LoginFailurePolicy {
+	maxFailures := service.config.AccountMaxFailures
+	if scope == common.LoginScopeIP {
+		maxFailures = service.config.IPMaxFailures
+	}
+	return common.LoginFailurePolicy{
+		Window:      service.config.FailureWindow,
+		MaxFailures: maxFailures,
+		Lockout:     service.config.LockoutDuration,
+		DelayBase:   service.config.DelayBase,
+		DelayMax:    service.config.DelayMax,
+	}
+}
+
+// audit добавляет запись в журнал входа; ошибка записи только журналируется
+func (service *LoginProtectionService) audit(ctx context.Context, entry *common.LoginAuditEntry) {
+	entry.CreatedAt = service.now()
+	if err := service.attempts.AddAuditEntry(ctx, entry); err != nil {
+		slog.Error(fmt.Sprintf("Error writing sign-in audit entry %s for %s: %s", entry.Event, entry.Email, err.Error()))
+	}
+}
+
+// loginAccountKey возвращает ключ учетной записи для учета попыток входа: email без учета регистра
+func loginAccountKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// roundUpToSecond округляет время ожидания вверх до целых секунд (для заголовка Retry-After)
+func roundUpToSecond(duration time.Duration) time.Duration {
+	return (duration + time.Second - 1).Truncate(time.Second)
+}
@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/repository"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/totp"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// loginProtectionConfig правила защиты входа в тестах: задержка 1s, 2s, 4s, 8s, затем блокировка
+var loginProtectionConfig = common.LoginProtectionConfig{
+	AccountMaxFailures: 6,
+	IPMaxFailures:      20,
+	FailureWindow:      15 * time.Minute,
+	LockoutDuration:    15 * time.Minute,
+	DelayBase:          time.Second,
+	DelayMax:           8 * time.Second,
+}
+
+// loginFixture вход по паролю и второй шаг с защитой от подбора в памяти процесса
+type loginFixture struct {
+	auth       *AuthService
+	twoFactor  *TwoFactorService
+	protection *LoginProtectionService
+	attempts   repository.LoginAttemptRepository
+	secrets    *fakeTwoFactor
+	users      *fakeUsers
+	now        time.Time
+}
+
+func newLoginFixture(t *testing.T) *loginFixture {
+	t.Helper()
+	newGuacamoleAPI(t)
+	hashed, err := bcrypt.GenerateFromPassword([]byte("user-password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	verifiedAt := time.Now()
+	fixture := &loginFixture{
+		attempts: repository.NewMemoryLoginAttemptRepository(),
+		secrets:  newFakeTwoFactor(),
+		users: newFakeUsers(&common.User{
+			Name:            "Иван Петров",
+			Email:           "ivan@example.com",
+			Password:        string(hashed),
+			EmailVerifiedAt: &verifiedAt,
+		}),
+		now: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+	}
+	fixture.protection = NewLoginProtectionService(fixture.attempts, fixture.users, loginProtectionConfig)
+	fixture.protection.now = func() time.Time { return fixture.now }
+
+	guacamole := newFakeGuacamole()
+	tokens := NewTokenService(&fakeAuthSessions{}, fixture.users)
+	fixture.twoFactor = NewTwoFactorService(fixture.secrets, fixture.users, guacamole, tokens, fixture.protection)
+	fixture.auth = NewAuthService(
+		fixture.users,
+		guacamole,
+		tokens,
+		fixture.twoFactor,
+		nil,
+		fixture.protection,
+		NewLocalAuthenticator(fixture.users),
+	)
+	return fixture
+}
+
+// signIn входит с адреса 192.0.2.10
+func (f *loginFixture) signIn(password string) (*common.AuthSignInResponse, error) {
+	form := common.AuthSignInRequest{Email: "ivan@example.com", Password: password}
+	return f.auth.SignIn(context.Background(), form, "192.0.2.10:51234", "")
+}
+
+// complete завершает вход вторым шагом с адреса 192.0.2.10
+func (f *loginFixture) complete(form common.TwoFactorChallengeRequest) (*common.AuthSignInResponse, error) {
+	return f.twoFactor.Complete(context.Background(), form, "192.0.2.10:51234", "")
+}
+
+// failures возвращает число учтенных неудач учетной записи или адреса
+func (f *loginFixture) failures(t *testing.T, scope string, key string) int {
+	t.Helper()
+	throttle, err := f.attempts.Find(context.Background(), scope, key)
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if throttle == nil {
+		return 0
+	}
+	return throttle.Failures
+}
+
+// enableTOTP подключает пользователю TOTP и код восстановления
+func (f *loginFixture) enableTOTP(t *testing.T, recoveryCode string) []byte {
+	t.Helper()
+	key, err := totp.NewSecret()
+	if err != nil {
+		t.Fatalf("NewSecret() error = %v", err)
+	}
+	sealed, err := sealSecret(key)
+	if err != nil {
+		t.Fatalf("sealSecret() error = %v", err)
+	}
+	user := f.users.get("ivan@example.com")
+	enabledAt := time.Now()
+	f.secrets.secrets[user.ID] = &common.UserTOTP{UserID: user.ID, Secret: sealed, EnabledAt: &enabledAt}
+	f.secrets.recoveryCodes[hashRecoveryCode(recoveryCode)] = user.ID
+	return key
+}
+
+func TestLoginProtectionDelaySchedule(t *testing.T) {
+	tests := []struct {
+		name           string
+		failures       int
+		elapsed        time.Duration
+		wantRetryAfter time.Duration
+		wantLocked     bool
+	}{
+		{name: "no failures"},
+		{name: "first failure", failures: 1, wantRetryAfter: time.Second},
+		{name: "delay rounded up to seconds", failures: 1, elapsed: 300 * time.Millisecond, wantRetryAfter: time.Second},
+		{name: "delay passed", failures: 1, elapsed: time.Second},
+		{name: "delay doubles", failures: 2, wantRetryAfter: 2 * time.Second},
+		{name: "third failure", failures: 3, wantRetryAfter: 4 * time.Second},
+		{name: "part of the delay passed", failures: 3, elapsed: 3 * time.Second, wantRetryAfter: time.Second},
+		{name: "delay reaches the maximum", failures: 4, wantRetryAfter: 8 * time.Second},
+		{name: "delay does not exceed the maximum", failures: 5, wantRetryAfter: 8 * time.Second},
+		{name: "locked after max failures", failures: 6, wantRetryAfter: 15 * time.Minute, wantLocked: true},
+		{name: "lock continues", failures: 6, elapsed: 10 * time.Minute, wantRetryAfter: 5 * time.Minute, wantLocked: true},
+		{name: "lock ended", failures: 6, elapsed: 15 * time.Minute},
+		{name: "failures outside the window", failures: 5, elapsed: 16 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixture := newLoginFixture(t)
+			ctx := context.Background()
+			for i := 0; i < tt.failures; i++ {
+				attempt, err := fixture.protection.Begin(ctx, "ivan@example.com", "")
+				if err != nil {
+					t.Fatalf("Begin() after %d failures error = %v", i, err)
+				}
+				fixture.protection.Failed(ctx, attempt, "")
+				fixture.now = fixture.now.Add(loginProtectionConfig.DelayMax)
+			}
+			fixture.now = fixture.now.Add(tt.elapsed - loginProtectionConfig.DelayMax)
+
+			_, err := fixture.protection.Begin(ctx, "ivan@example.com", "")
+			var throttled *LoginThrottledError
+			switch {
+			case tt.wantRetryAfter == 0 && err != nil:
+				t.Errorf("Begin() error = %v, want attempt allowed", err)
+			case tt.wantRetryAfter > 0 && !errors.As(err, &throttled):
+				t.Errorf("Begin() error = %v, want *LoginThrottledError", err)
+			case throttled != nil && (throttled.RetryAfter != tt.wantRetryAfter || throttled.Locked != tt.wantLocked):
+				t.Errorf("Begin() retry after %s, locked %t, want %s, %t",
+					throttled.RetryAfter, throttled.Locked, tt.wantRetryAfter, tt.wantLocked)
+			}
+		})
+	}
+}
+
+func TestLoginProtectionConcurrentAttempts(t *testing.T) {
+	fixture := newLoginFixture(t)
+
+	// Все попытки начинаются раньше, чем закончится проверка пароля первой из них
+	const attempts = 20
+	errs := make(chan error, attempts)
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := fixture.signIn("wrong-password")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	checked := 0
+	for err := range errs {
+		switch {
+		case errors.Is(err, ErrInvalidPassword):
+			checked++
+		case !errors.Is(err, ErrTooManyAttempts):
+			t.Errorf("SignIn() error = %v, want ErrInvalidPassword or ErrTooManyAttempts", err)
+		}
+	}
+	if checked != 1 {
+		t.Errorf("password was checked %d times, want once before the delay", checked)
+	}
+	if got := fixture.failures(t, common.LoginScopeAccount, "ivan@example.com"); got != 1 {
+		t.Errorf("account failures = %d, want 1", got)
+	}
+	if got := fixture.failures(t, common.LoginScopeIP, "192.0.2.10"); got != 1 {
+		t.Errorf("ip failures = %d, want 1", got)
+	}
+}
+
+func TestLoginProtectionSignInSucceeded(t *testing.T) {
+	fixture := newLoginFixture(t)
+
+	if _, err := fixture.signIn("wrong-password"); !errors.Is(err, ErrInvalidPassword) {
+		t.Fatalf("SignIn() with wrong password error = %v", err)
+	}
+	fixture.now = fixture.now.Add(time.Second)
+	response, err := fixture.signIn("user-password")
+	if err != nil || response.AuthTokens == nil {
+		t.Fatalf("SignIn() = %+v, %v, want tokens", response, err)
+	}
+
+	if got := fixture.failures(t, common.LoginScopeAccount, "ivan@example.com"); got != 0 {
+		t.Errorf("account failures = %d, want reset after sign-in", got)
+	}
+	// Удачная попытка не учитывается, но и не сбрасывает прежние неудачи с адреса
+	if got := fixture.failures(t, common.LoginScopeIP, "192.0.2.10"); got != 1 {
+		t.Errorf("ip failures = %d, want 1", got)
+	}
+	if _, err := fixture.signIn("user-password"); err != nil {
+		t.Errorf("second SignIn() error = %v, want no delay after a successful attempt", err)
+	}
+}
+
+func TestLoginProtectionSecondFactor(t *testing.T) {
+	fixture := newLoginFixture(t)
+	key := fixture.enableTOTP(t, "recovery-code")
+
+	if _, err := fixture.signIn("wrong-password"); !errors.Is(err, ErrInvalidPassword) {
+		t.Fatalf("SignIn() with wrong password error = %v", err)
+	}
+	fixture.now = fixture.now.Add(time.Second)
+	response, err := fixture.signIn("user-password")
+	if err != nil || response.TwoFactor == nil {
+		t.Fatalf("SignIn() = %+v, %v, want two-factor challenge", response, err)
+	}
+	// Пароль верен, но вход не завершен: прежние неудачи учетной записи не сбрасываются
+	if got := fixture.failures(t, common.LoginScopeAccount, "ivan@example.com"); got != 1 {
+		t.Errorf("account failures after the password step = %d, want 1", got)
+	}
+
+	token := response.TwoFactor.ChallengeToken
+	invalid := []common.TwoFactorChallengeRequest{
+		{ChallengeToken: token, Code: "000000"},
+		{ChallengeToken: token, RecoveryCode: "wrong-recovery-code"},
+	}
+	for i, form := range invalid {
+		fixture.now = fixture.now.Add(loginProtectionConfig.DelayMax)
+		if _, err := fixture.complete(form); !errors.Is(err, ErrInvalidCode) {
+			t.Fatalf("Complete() with invalid code error = %v, want ErrInvalidCode", err)
+		}
+		if got := fixture.failures(t, common.LoginScopeAccount, "ivan@example.com"); got != i+2 {
+			t.Errorf("account failures after invalid code = %d, want %d", got, i+2)
+		}
+	}
+
+	// Слишком ранний код не проверяется, даже если он верен
+	code := totp.Code(key, totp.Step(time.Now()))
+	_, err = fixture.complete(common.TwoFactorChallengeRequest{ChallengeToken: token, Code: code})
+	var throttled *LoginThrottledError
+	if !errors.As(err, &throttled) || throttled.RetryAfter != 4*time.Second {
+		t.Fatalf("Complete() right after a failure error = %v, want 4s delay", err)
+	}
+
+	fixture.now = fixture.now.Add(4 * time.Second)
+	response, err = fixture.complete(common.TwoFactorChallengeRequest{ChallengeToken: token, RecoveryCode: "recovery-code"})
+	if err != nil || response.AuthTokens == nil {
+		t.Fatalf("Complete() = %+v, %v, want tokens", response, err)
+	}
+	if got := fixture.failures(t, common.LoginScopeAccount, "ivan@example.com"); got != 0 {
+		t.Errorf("account failures after the second factor = %d, want reset", got)
+	}
+
+	page, err := fixture.protection.GetAudit(context.Background(), &common.LoginAuditFilter{Page: 1, PerPage: 10})
+	if err != nil {
+		t.Fatalf("GetAudit() error = %v", err)
+	}
+	var events []string
+	for _, entry := range page.Items {
+		events = append(events, entry.Event)
+	}
+	want := []string{
+		common.LoginEventSucceeded,
+		common.LoginEventThrottled,
+		common.LoginEventFailed,
+		common.LoginEventFailed,
+		common.LoginEventFailed,
+	}
+	if len(events) != len(want) {
+		t.Fatalf("audit events = %q, want %q", events, want)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Fatalf("audit events = %q, want %q", events, want)
+		}
+	}
+	if detail := page.Items[2].Detail; detail != "invalid two-factor code" {
+		t.Errorf("audit detail of invalid code = %q", detail)
+	}
+}
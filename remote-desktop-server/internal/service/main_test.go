@@ -0,0 +1,63 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TestMain задает конфигурацию сервера вместо загрузки из окружения (config.Load)
+func TestMain(m *testing.M) {
+	config.ServerConfig = &common.ServerConfig{
+		BcryptPower: bcrypt.MinCost,
+		JWTConfig: common.JWTConfig{
+			AccessTokenSecret: "test-access-token-secret",
+			AccessTokenTTL:    "15m",
+			RefreshTokenTTL:   time.Hour,
+		},
+		GuacamoleCredentialsKey: "test-guacamole-credentials-key",
+		PasswordResetConfig: common.PasswordResetConfig{
+			TokenTTL: time.Hour,
+			URL:      "https://rd.example.com/reset-password",
+		},
+		EmailVerificationConfig: common.EmailVerificationConfig{
+			TokenTTL: time.Hour,
+			URL:      "https://rd.example.com/verify-email",
+		},
+		TwoFactorConfig: common.TwoFactorConfig{
+			Issuer:       "Remote Desktop",
+			ChallengeTTL: 5 * time.Minute,
+			MaxAttempts:  5,
+		},
+		SharingConfig: common.SharingConfig{
+			LinkTTL:   time.Hour,
+			ViewerURL: "https://rd.example.com/shared",
+		},
+		AuthConfig: common.AuthConfig{
+			Backends:    []string{"local"},
+			DefaultRole: common.RoleViewer,
+		},
+	}
+	os.Exit(m.Run())
+}
+
+// newGuacamoleAPI запускает API Guacamole, выдающий токен на любые учетные данные
+func newGuacamoleAPI(t *testing.T) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"authToken":"guacamole-token"}`))
+	}))
+	previous := config.ServerConfig.GuacamoleAPIURL
+	config.ServerConfig.GuacamoleAPIURL = server.URL
+	t.Cleanup(func() {
+		server.Close()
+		config.ServerConfig.GuacamoleAPIURL = previous
+	})
+}
@@ -0,0 +1,266 @@
+// Package service реализует бизнес-логику приложения.
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/config"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/oidc"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/repository"
+)
+
+// OIDCService выполняет вход через провайдера OpenID Connect (authorization code + PKCE).
+//
+// Пользователь провайдера определяется парой issuer и sub. При первом входе учетная запись
+// провайдера связывается с пользователем по электронной почте, подтвержденной провайдером;
+// если такого пользователя нет, он и пользователь Guacamole создаются так же, как при регистрации.
+// Группы пользователя у провайдера определяют его роль (OIDC_GROUP_ROLES).
+// Если у пользователя подключен (или обязателен) TOTP, вход завершается вторым шагом,
+// как и вход по паролю.
+type OIDCService struct {
+	states     repository.OIDCStateRepository
+	identities repository.OIDCIdentityRepository
+	accounts   *externalAccounts
+	tokens     *TokenService
+	twoFactor  *TwoFactorService
+
+	mu     sync.Mutex
+	client *oidc.Client
+}
+
+// NewOIDCService создает новый экземпляр OIDCService.
+//
+// Параметры:
+//   - states: репозиторий незавершенных входов
+//   - identities: репозиторий связей учетных записей провайдера с пользователями
+//   - users: репозиторий пользователей
+//   - guacamole: репозиторий Guacamole
+//   - tokens: сервис токенов сессий входа
+//   - twoFactor: сервис двухфакторной аутентификации
+//
+// Возвращает:
+//   - *OIDCService: указатель на созданный сервис
+func NewOIDCService(
+	states repository.OIDCStateRepository,
+	identities repository.OIDCIdentityRepository,
+	users repository.UserRepository,
+	guacamole repository.GuacamoleRepository,
+	tokens *TokenService,
+	twoFactor *TwoFactorService,
+) *OIDCService {
+	return &OIDCService{
+		states:     states,
+		identities: identities,
+		accounts:   newExternalAccounts(users, guacamole, tokens),
+		tokens:     tokens,
+		twoFactor:  twoFactor,
+	}
+}
+
+// Login начинает вход: сохраняет state, code_verifier и nonce и возвращает адрес провайдера.
+// Значение state нужно сохранить в cookie браузера: Callback принимает только state,
+// совпадающий с cookie, чтобы чужой код авторизации нельзя было подставить в браузер жертвы.
+//
+// Параметры:
+//   - ctx: контекст
+//
+// Возвращает:
+//   - string: адрес страницы авторизации провайдера
+//   - string: значение state
+//   - error: ErrUnsupported, если вход через OpenID Connect не настроен, или ошибка обращения к провайдеру
+func (service *OIDCService) Login(ctx context.Context) (string, string, error) {
+	client, err := service.provider(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	state, err := newSecretToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := newSecretToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := newSecretToken()
+	if err != nil {
+		return "", "", err
+	}
+	ttl := config.ServerConfig.OIDCConfig.StateTTL
+	if err := service.states.Create(ctx, hashSecretToken(state), verifier, nonce, ttl); err != nil {
+		return "", "", err
+	}
+	return client.AuthCodeURL(state, nonce, verifier), state, nil
+}
+
+// Callback завершает вход по коду авторизации, полученному от провайдера.
+//
+// Параметры:
+//   - ctx: контекст
+//   - code: код авторизации
+//   - state: значение state из адреса возврата
+//   - browserState: значение state из cookie браузера, начавшего вход
+//
+// Возвращает:
+//   - *common.AuthSignInResponse: токены доступа или токен второго шага, если нужен код TOTP
+//   - error: ошибки:
+//   - ErrUnsupported - вход через OpenID Connect не настроен
+//   - ErrInvalidToken - state не совпадает с cookie, не найден, истек или уже использован
+//   - ErrForbidden - провайдер не сообщил подтвержденную электронную почту, пользователь отключен
+//     или уже связан с другой учетной записью провайдера
+//   - ошибки обмена кода, проверки ID токена, создания пользователя или выдачи токенов
+func (service *OIDCService) Callback(
+	ctx context.Context,
+	code string,
+	state string,
+	browserState string,
+) (*common.AuthSignInResponse, error) {
+	client, err := service.provider(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(browserState)) != 1 {
+		return nil, ErrInvalidToken
+	}
+	login, err := service.states.Consume(ctx, hashSecretToken(state))
+	if err != nil {
+		return nil, err
+	}
+	if login == nil {
+		return nil, ErrInvalidToken
+	}
+	identity, err := client.Exchange(ctx, code, login.CodeVerifier, login.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := service.account(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+	err = service.accounts.syncRole(ctx, user, identity.Groups, config.ServerConfig.OIDCConfig.GroupRoles)
+	if err != nil {
+		return nil, err
+	}
+
+	// Пароль Guacamole пользователя управляется сервером, поэтому пароль не передается
+	challenge, err := service.twoFactor.Begin(ctx, user, "")
+	if err != nil {
+		return nil, err
+	}
+	if challenge != nil {
+		return &common.AuthSignInResponse{TwoFactor: challenge}, nil
+	}
+	tokens, err := service.tokens.Issue(ctx, user, "")
+	if err != nil {
+		return nil, err
+	}
+	return &common.AuthSignInResponse{AuthTokens: tokens}, nil
+}
+
+// account возвращает пользователя, связанного с учетной записью провайдера.
+// Если учетная запись провайдера еще не связана, она связывается с пользователем
+// по подтвержденной провайдером электронной почте (пользователь создается при необходимости).
+// Пользователь, уже связанный с другой учетной записью того же провайдера, не связывается:
+// иначе учетная запись провайдера, получившая его адрес, получила бы доступ к его данным.
+func (service *OIDCService) account(ctx context.Context, identity *oidc.Identity) (*common.User, error) {
+	linked, err := service.identities.Find(ctx, identity.Issuer, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if linked != nil {
+		user, err := service.accounts.users.FindByID(ctx, linked.UserID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: user of oidc account %s is disabled", ErrForbidden, identity.Subject)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := service.accounts.manage(ctx, user); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+
+	email := strings.TrimSpace(identity.Email)
+	if email == "" || !identity.EmailVerified {
+		return nil, fmt.Errorf("%w: oidc account %s has no verified email", ErrForbidden, identity.Subject)
+	}
+	existing, err := service.accounts.users.FindByEmail(ctx, email)
+	switch {
+	case err == nil:
+		other, err := service.identities.FindByUser(ctx, identity.Issuer, existing.ID)
+		if err != nil {
+			return nil, err
+		}
+		if other != nil {
+			return nil, fmt.Errorf(
+				"%w: user %s is linked to another oidc account than %s",
+				ErrForbidden,
+				existing.Email,
+				identity.Subject,
+			)
+		}
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, err
+	}
+	user, err := service.accounts.provision(ctx, email, identity.Name)
+	if err != nil {
+		return nil, err
+	}
+	created, err := service.identities.Create(ctx, identity.Issuer, identity.Subject, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, fmt.Errorf("%w: oidc account %s is linked concurrently", ErrForbidden, identity.Subject)
+	}
+	return user, nil
+}
+
+// StateTTL возвращает время, за которое нужно завершить вход (время жизни cookie со state)
+func (service *OIDCService) StateTTL() time.Duration {
+	return config.ServerConfig.OIDCConfig.StateTTL
+}
+
+// FrontendURL возвращает адрес страницы фронтенда, завершающей вход, с параметрами во фрагменте адреса
+func (service *OIDCService) FrontendURL(fragment url.Values) string {
+	return config.ServerConfig.OIDCConfig.FrontendURL + "#" + fragment.Encode()
+}
+
+// provider возвращает клиента OpenID Connect.
+// Документ обнаружения запрашивается при первом входе, а не при запуске сервера,
+// чтобы недоступность провайдера не мешала запуску; при ошибке запрос повторяется при следующем входе.
+func (service *OIDCService) provider(ctx context.Context) (*oidc.Client, error) {
+	cfg := config.ServerConfig.OIDCConfig
+	if cfg.IssuerURL == "" || cfg.ClientID == "" {
+		return nil, ErrUnsupported
+	}
+
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	if service.client != nil {
+		return service.client, nil
+	}
+	client, err := oidc.NewClient(ctx, oidc.Config{
+		IssuerURL:    cfg.IssuerURL,
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL:  cfg.RedirectURL,
+		Scopes:       cfg.Scopes,
+		GroupsClaim:  cfg.GroupsClaim,
+	})
+	if err != nil {
+		return nil, err
+	}
+	service.client = client
+	return client, nil
+}
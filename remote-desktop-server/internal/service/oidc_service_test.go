@@ -0,0 +1,356 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/config"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/oidc/oidctest"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/repository"
+)
+
+// fakeOIDCStates хранит незавершенные входы через провайдера
+type fakeOIDCStates struct {
+	repository.OIDCStateRepository
+
+	mu     sync.Mutex
+	states map[string]*common.OIDCLoginState
+}
+
+func (r *fakeOIDCStates) Create(
+	ctx context.Context,
+	stateHash string,
+	codeVerifier string,
+	nonce string,
+	ttl time.Duration,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.states[stateHash] = &common.OIDCLoginState{
+		ID:           uuid.New(),
+		CodeVerifier: codeVerifier,
+		Nonce:        nonce,
+		ExpiresAt:    time.Now().Add(ttl),
+	}
+	return nil
+}
+
+func (r *fakeOIDCStates) Consume(ctx context.Context, stateHash string) (*common.OIDCLoginState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state, ok := r.states[stateHash]
+	if !ok || time.Now().After(state.ExpiresAt) {
+		return nil, nil
+	}
+	delete(r.states, stateHash)
+	return state, nil
+}
+
+// fakeOIDCIdentities хранит связи учетных записей провайдера с пользователями
+type fakeOIDCIdentities struct {
+	repository.OIDCIdentityRepository
+
+	mu         sync.Mutex
+	identities []common.OIDCIdentity
+}
+
+func (r *fakeOIDCIdentities) Find(ctx context.Context, issuer string, subject string) (*common.OIDCIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, identity := range r.identities {
+		if identity.Issuer == issuer && identity.Subject == subject {
+			return &identity, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeOIDCIdentities) FindByUser(
+	ctx context.Context,
+	issuer string,
+	userID uuid.UUID,
+) (*common.OIDCIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, identity := range r.identities {
+		if identity.Issuer == issuer && identity.UserID == userID {
+			return &identity, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeOIDCIdentities) Create(ctx context.Context, issuer string, subject string, userID uuid.UUID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, identity := range r.identities {
+		if identity.Issuer == issuer && (identity.Subject == subject || identity.UserID == userID) {
+			return false, nil
+		}
+	}
+	r.identities = append(r.identities, common.OIDCIdentity{
+		Issuer:    issuer,
+		Subject:   subject,
+		UserID:    userID,
+		CreatedAt: time.Now(),
+	})
+	return true, nil
+}
+
+// oidcFixture сервис входа через локального провайдера OpenID Connect
+type oidcFixture struct {
+	service    *OIDCService
+	provider   *oidctest.Provider
+	users      *fakeUsers
+	guacamole  *fakeGuacamole
+	sessions   *fakeAuthSessions
+	twoFactor  *fakeTwoFactor
+	identities *fakeOIDCIdentities
+}
+
+func newOIDCFixture(t *testing.T, users ...*common.User) *oidcFixture {
+	t.Helper()
+	newGuacamoleAPI(t)
+	fixture := &oidcFixture{
+		provider:   oidctest.NewProvider("remote-desktop"),
+		users:      newFakeUsers(users...),
+		guacamole:  newFakeGuacamole(),
+		sessions:   &fakeAuthSessions{},
+		twoFactor:  newFakeTwoFactor(),
+		identities: &fakeOIDCIdentities{},
+	}
+	t.Cleanup(fixture.provider.Close)
+	for _, user := range users {
+		fixture.guacamole.passwords[user.Email] = common.GuacamolePassword{HashHex: "old", SaltHex: "old"}
+	}
+
+	previous := config.ServerConfig.OIDCConfig
+	config.ServerConfig.OIDCConfig = common.OIDCConfig{
+		IssuerURL:   fixture.provider.Issuer,
+		ClientID:    fixture.provider.ClientID,
+		RedirectURL: "https://rd.example.com/api/v1/auth/oidc/callback",
+		FrontendURL: "https://rd.example.com/oidc",
+		StateTTL:    10 * time.Minute,
+	}
+	t.Cleanup(func() { config.ServerConfig.OIDCConfig = previous })
+
+	tokens := NewTokenService(fixture.sessions, fixture.users)
+	protection := NewLoginProtectionService(
+		repository.NewMemoryLoginAttemptRepository(),
+		fixture.users,
+		common.LoginProtectionConfig{},
+	)
+	fixture.service = NewOIDCService(
+		&fakeOIDCStates{states: make(map[string]*common.OIDCLoginState)},
+		fixture.identities,
+		fixture.users,
+		fixture.guacamole,
+		tokens,
+		NewTwoFactorService(fixture.twoFactor, fixture.users, fixture.guacamole, tokens, protection),
+	)
+	return fixture
+}
+
+// login выполняет вход в браузере, получившем cookie со state, у провайдера с утверждениями claims
+func (f *oidcFixture) login(t *testing.T, claims map[string]interface{}) (*common.AuthSignInResponse, error) {
+	t.Helper()
+	ctx := context.Background()
+	target, browserState, err := f.service.Login(ctx)
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	code, state, err := f.provider.Authorize(target, claims)
+	if err != nil {
+		t.Fatalf("Authorize() error = %v", err)
+	}
+	return f.service.Callback(ctx, code, state, browserState)
+}
+
+// verifiedClaims возвращает утверждения учетной записи провайдера с подтвержденной почтой
+func verifiedClaims(subject string, email string) map[string]interface{} {
+	return map[string]interface{}{
+		"sub":            subject,
+		"email":          email,
+		"email_verified": true,
+		"name":           "Иван Петров",
+	}
+}
+
+func TestOIDCServiceCallbackProvisionsUser(t *testing.T) {
+	fixture := newOIDCFixture(t)
+
+	response, err := fixture.login(t, verifiedClaims("subject-1", "ivan@example.com"))
+	if err != nil {
+		t.Fatalf("Callback() error = %v", err)
+	}
+	if response.AuthTokens == nil || response.TwoFactor != nil {
+		t.Fatalf("Callback() = %+v, want auth tokens", response)
+	}
+
+	user := fixture.users.get("ivan@example.com")
+	if user == nil || user.EmailVerifiedAt == nil || user.Role != common.RoleViewer {
+		t.Fatalf("provisioned user = %+v, want verified viewer", user)
+	}
+	if len(user.GuacamoleCredentials) == 0 {
+		t.Error("provisioned user has no server-managed guacamole password")
+	}
+	if _, ok := fixture.guacamole.password(user.Email); !ok {
+		t.Error("guacamole user was not created")
+	}
+	linked, _ := fixture.identities.Find(context.Background(), fixture.provider.Issuer, "subject-1")
+	if linked == nil || linked.UserID != user.ID {
+		t.Errorf("oidc identity = %+v, want link to %s", linked, user.ID)
+	}
+	if issued := fixture.sessions.issuedTo(); len(issued) != 1 || issued[0] != user.ID {
+		t.Errorf("sessions issued to %v, want %s", issued, user.ID)
+	}
+}
+
+func TestOIDCServiceCallbackLinksBySubject(t *testing.T) {
+	fixture := newOIDCFixture(t)
+	if _, err := fixture.login(t, verifiedClaims("subject-1", "ivan@example.com")); err != nil {
+		t.Fatalf("first Callback() error = %v", err)
+	}
+	user := fixture.users.get("ivan@example.com")
+
+	// Почта у провайдера изменилась и больше не подтверждена, но учетная запись уже связана
+	_, err := fixture.login(t, map[string]interface{}{"sub": "subject-1", "email": "ivan.petrov@example.com"})
+	if err != nil {
+		t.Fatalf("second Callback() error = %v", err)
+	}
+	if issued := fixture.sessions.issuedTo(); len(issued) != 2 || issued[1] != user.ID {
+		t.Errorf("sessions issued to %v, want second session of %s", issued, user.ID)
+	}
+	if other := fixture.users.get("ivan.petrov@example.com"); other != nil {
+		t.Errorf("second login created user %+v", other)
+	}
+}
+
+func TestOIDCServiceCallbackForbidden(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+	}{
+		{
+			name:   "email is not verified",
+			claims: map[string]interface{}{"sub": "subject-2", "email": "petr@example.com"},
+		},
+		{
+			name: "email_verified is a string",
+			claims: map[string]interface{}{
+				"sub":            "subject-2",
+				"email":          "petr@example.com",
+				"email_verified": "true",
+			},
+		},
+		{
+			name:   "email is missing",
+			claims: map[string]interface{}{"sub": "subject-2", "email_verified": true},
+		},
+		{
+			name:   "email of a user linked to another account",
+			claims: verifiedClaims("subject-2", "ivan@example.com"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixture := newOIDCFixture(t)
+			if _, err := fixture.login(t, verifiedClaims("subject-1", "ivan@example.com")); err != nil {
+				t.Fatalf("Callback() of linked account error = %v", err)
+			}
+
+			response, err := fixture.login(t, tt.claims)
+			if !errors.Is(err, ErrForbidden) {
+				t.Errorf("Callback() = %+v, %v, want ErrForbidden", response, err)
+			}
+			if issued := fixture.sessions.issuedTo(); len(issued) != 1 {
+				t.Errorf("issued %d sessions, want only the linked account's one", len(issued))
+			}
+			if user := fixture.users.get("petr@example.com"); user != nil {
+				t.Errorf("forbidden login created user %+v", user)
+			}
+		})
+	}
+}
+
+func TestOIDCServiceCallbackState(t *testing.T) {
+	tests := []struct {
+		name         string
+		browserState func(state string) string
+	}{
+		{name: "cookie is missing", browserState: func(state string) string { return "" }},
+		{name: "cookie of another login", browserState: func(state string) string { return state + "x" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixture := newOIDCFixture(t)
+			ctx := context.Background()
+			target, _, err := fixture.service.Login(ctx)
+			if err != nil {
+				t.Fatalf("Login() error = %v", err)
+			}
+			code, state, err := fixture.provider.Authorize(target, verifiedClaims("subject-1", "ivan@example.com"))
+			if err != nil {
+				t.Fatalf("Authorize() error = %v", err)
+			}
+
+			_, err = fixture.service.Callback(ctx, code, state, tt.browserState(state))
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("Callback() error = %v, want ErrInvalidToken", err)
+			}
+			if user := fixture.users.get("ivan@example.com"); user != nil {
+				t.Errorf("callback without matching cookie created user %+v", user)
+			}
+
+			// Неудачная проверка cookie не расходует state: вход в исходном браузере завершается
+			if _, err := fixture.service.Callback(ctx, code, state, state); err != nil {
+				t.Errorf("Callback() with matching cookie error = %v", err)
+			}
+		})
+	}
+}
+
+func TestOIDCServiceCallbackTwoFactor(t *testing.T) {
+	now := time.Now()
+	user := &common.User{Name: "Иван Петров", Email: "ivan@example.com", Password: "hash", EmailVerifiedAt: &now}
+	fixture := newOIDCFixture(t, user)
+	fixture.twoFactor.secrets[user.ID] = &common.UserTOTP{UserID: user.ID, Secret: []byte("secret"), EnabledAt: &now}
+
+	response, err := fixture.login(t, verifiedClaims("subject-1", user.Email))
+	if err != nil {
+		t.Fatalf("Callback() error = %v", err)
+	}
+	if response.AuthTokens != nil || response.TwoFactor == nil || response.TwoFactor.ChallengeToken == "" {
+		t.Fatalf("Callback() = %+v, want two-factor challenge without tokens", response)
+	}
+	if response.TwoFactor.EnrollmentRequired {
+		t.Error("Callback() requires enrollment for a user with TOTP enabled")
+	}
+	if challenges := fixture.twoFactor.challenges; len(challenges) != 1 || challenges[0] != user.ID {
+		t.Errorf("challenges created for %v, want %s", challenges, user.ID)
+	}
+	if issued := fixture.sessions.issuedTo(); len(issued) != 0 {
+		t.Errorf("issued sessions to %v before the second factor", issued)
+	}
+}
+
+func TestOIDCServiceCallbackTwoFactorRequired(t *testing.T) {
+	fixture := newOIDCFixture(t)
+	config.ServerConfig.TwoFactorConfig.RequiredPermissions = []string{"ADMINISTER"}
+	t.Cleanup(func() { config.ServerConfig.TwoFactorConfig.RequiredPermissions = nil })
+	config.ServerConfig.AuthConfig.DefaultRole = common.RoleAdmin
+	t.Cleanup(func() { config.ServerConfig.AuthConfig.DefaultRole = common.RoleViewer })
+
+	response, err := fixture.login(t, verifiedClaims("subject-1", "ivan@example.com"))
+	if err != nil {
+		t.Fatalf("Callback() error = %v", err)
+	}
+	if response.TwoFactor == nil || !response.TwoFactor.EnrollmentRequired {
+		t.Fatalf("Callback() = %+v, want challenge with required enrollment", response)
+	}
+}
@@ -0,0 +1,269 @@
+// Package service реализует бизнес-логику приложения.
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/config"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/mailer"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordService предоставляет смену и сброс пароля.
+// Пароль хранится в двух базах: bcrypt хэш в базе приложения и соленый SHA-256 в guacamole_user,
+// поэтому изменение выполняется как сага: сначала меняется пароль Guacamole,
+// затем пароль приложения; при ошибке прежний пароль Guacamole восстанавливается.
+// После смены пароля все сессии входа пользователя отзываются.
+type PasswordService struct {
+	users     repository.UserRepository
+	resets    repository.PasswordResetRepository
+	guacamole repository.GuacamoleRepository
+	tokens    *TokenService
+	mailer    mailer.Mailer
+}
+
+// NewPasswordService создает новый экземпляр PasswordService.
+//
+// Параметры:
+//   - users: репозиторий пользователей
+//   - resets: репозиторий токенов сброса пароля
+//   - guacamole: репозиторий базы Guacamole
+//   - tokens: сервис токенов сессий входа
+//   - mailer: отправка писем
+//
+// Возвращает:
+//   - *PasswordService: указатель на созданный сервис
+func NewPasswordService(
+	users repository.UserRepository,
+	resets repository.PasswordResetRepository,
+	guacamole repository.GuacamoleRepository,
+	tokens *TokenService,
+	mailer mailer.Mailer,
+) *PasswordService {
+	return &PasswordService{
+		users:     users,
+		resets:    resets,
+		guacamole: guacamole,
+		tokens:    tokens,
+		mailer:    mailer,
+	}
+}
+
+// ChangePassword меняет пароль текущего пользователя.
+//
+// Параметры:
+//   - ctx: контекст
+//   - email: email текущего пользователя
+//   - form: текущий и новый пароль
+//
+// Возвращает:
+//   - *common.AuthTokens: токены новой сессии входа, так как ранее выданные токены отзываются
+//   - error: ErrInvalidPassword, если текущий пароль неверен, или ошибка изменения пароля
+func (service *PasswordService) ChangePassword(
+	ctx context.Context,
+	email string,
+	form common.PasswordChangeRequest,
+) (*common.AuthTokens, error) {
+	user, err := service.users.FindByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if err := bcrypt.CompareHashAndPassword(
+		[]byte(user.Password),
+		[]byte(strings.TrimSpace(form.CurrentPassword)),
+	); err != nil {
+		return nil, ErrInvalidPassword
+	}
+
+	s, err := service.passwordSteps(newSaga("change password"), func() *common.User { return user }, form.Password)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.run(ctx); err != nil {
+		return nil, err
+	}
+
+	service.revokeSessions(ctx, user)
+
+	user.TokenVersion++
+	user.GuacamoleCredentials = nil
+	return service.tokens.Issue(ctx, user, form.Password)
+}
+
+// ForgotPassword отправляет пользователю письмо со ссылкой для сброса пароля.
+//
+// Параметры:
+//   - ctx: контекст
+//   - form: email пользователя
+//
+// Возвращает:
+//   - error: ошибка сохранения токена или отправки письма
+//
+// Особенности:
+//   - Для неизвестного email письмо не отправляется и ошибка не возвращается,
+//     чтобы по ответу нельзя было узнать, зарегистрирован ли адрес
+func (service *PasswordService) ForgotPassword(ctx context.Context, form common.PasswordForgotRequest) error {
+	user, err := service.users.FindByEmail(ctx, form.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		slog.Info(fmt.Sprintf("Password reset is requested for unknown email %s", form.Email))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := newSecretToken()
+	if err != nil {
+		return err
+	}
+	ttl := config.ServerConfig.PasswordResetConfig.TokenTTL
+	if err := service.resets.Create(ctx, user.ID, hashSecretToken(token), ttl); err != nil {
+		return err
+	}
+	link, err := resetLink(token)
+	if err != nil {
+		return err
+	}
+
+	return service.mailer.Send(ctx, mailer.Message{
+		To:      []string{user.Email},
+		Subject: "Сброс пароля RemoteDesktop",
+		Body: fmt.Sprintf(
+			"Здравствуйте, %s!\n\n"+
+				"Чтобы задать новый пароль, перейдите по ссылке:\n%s\n\n"+
+				"Ссылка действует %s и может быть использована один раз.\n"+
+				"Если вы не запрашивали сброс пароля, просто проигнорируйте это письмо.\n",
+			user.Name,
+			link,
+			ttl.String(),
+		),
+	})
+}
+
+// ResetPassword устанавливает новый пароль по токену из письма.
+//
+// Параметры:
+//   - ctx: контекст
+//   - form: токен и новый пароль
+//
+// Возвращает:
+//   - error: ErrInvalidToken, если токен не найден, уже использован или истек,
+//     или ошибка изменения пароля
+//
+// Особенности:
+//   - Если пароль изменить не удалось, токен снова становится действительным
+func (service *PasswordService) ResetPassword(ctx context.Context, form common.PasswordResetRequest) error {
+	var token *common.PasswordResetToken
+	var user *common.User
+	s := newSaga("reset password").
+		step(
+			"consume reset token",
+			func(ctx context.Context) error {
+				var err error
+				token, err = service.resets.Consume(ctx, hashSecretToken(form.Token))
+				if err != nil {
+					return err
+				}
+				if token == nil {
+					return ErrInvalidToken
+				}
+				return nil
+			},
+			func(ctx context.Context) error {
+				return service.resets.Release(ctx, token.ID)
+			},
+		).
+		step(
+			"find user",
+			func(ctx context.Context) error {
+				var err error
+				user, err = service.users.FindByID(ctx, token.UserID)
+				return err
+			},
+			nil,
+		)
+	s, err := service.passwordSteps(s, func() *common.User { return user }, form.Password)
+	if err != nil {
+		return err
+	}
+	if err := s.run(ctx); err != nil {
+		return err
+	}
+	service.revokeSessions(ctx, user)
+	return nil
+}
+
+// revokeSessions отзывает сессии входа пользователя после смены пароля.
+// Refresh токены этих сессий уже недействительны из-за новой версии токенов пользователя,
+// поэтому ошибка только журналируется.
+func (service *PasswordService) revokeSessions(ctx context.Context, user *common.User) {
+	if err := service.tokens.RevokeUser(ctx, user.ID); err != nil {
+		slog.Error(fmt.Sprintf("Error revoking auth sessions of %s: %s", user.Email, err.Error()))
+	}
+}
+
+// passwordSteps добавляет в сагу шаги изменения пароля в Guacamole и в базе приложения.
+// Пользователь передается функцией, так как при сбросе пароля он определяется предыдущими шагами саги.
+func (service *PasswordService) passwordSteps(
+	s *saga,
+	user func() *common.User,
+	password string,
+) (*saga, error) {
+	hashed, err := bcrypt.GenerateFromPassword(
+		[]byte(strings.TrimSpace(password)),
+		config.ServerConfig.BcryptPower,
+	)
+	if err != nil {
+		return nil, err
+	}
+	saltHex := getGuacamoleSault()
+	guacamolePassword := common.GuacamolePassword{
+		HashHex: getHashedGuacamolePassword(password, saltHex),
+		SaltHex: saltHex,
+	}
+
+	var previous *common.GuacamolePassword
+	return s.
+		step(
+			"update guacamole password",
+			func(ctx context.Context) error {
+				var err error
+				previous, err = service.guacamole.SetUserPassword(ctx, user().Email, guacamolePassword)
+				if errors.Is(err, sql.ErrNoRows) {
+					return errors.New("guacamole account not found")
+				}
+				return err
+			},
+			func(ctx context.Context) error {
+				_, err := service.guacamole.SetUserPassword(ctx, user().Email, *previous)
+				return err
+			},
+		).
+		step(
+			"update password",
+			func(ctx context.Context) error {
+				return service.users.UpdatePassword(ctx, user().ID, string(hashed))
+			},
+			nil,
+		), nil
+}
+
+// resetLink возвращает ссылку на страницу сброса пароля с токеном в параметре token
+func resetLink(token string) (string, error) {
+	link, err := url.Parse(config.ServerConfig.PasswordResetConfig.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid password reset url: %w", err)
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link.String(), nil
+}
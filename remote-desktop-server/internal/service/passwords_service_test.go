@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// resetLinkPattern находит ссылку для сброса пароля в тексте письма
+var resetLinkPattern = regexp.MustCompile(`https://rd\.example\.com/reset-password\?\S+`)
+
+// passwordResetFixture сервис сброса пароля, отправляющий письма на локальный SMTP сервер
+type passwordResetFixture struct {
+	*mailFixture
+	service *PasswordService
+}
+
+func newPasswordResetFixture(t *testing.T, users ...*common.User) *passwordResetFixture {
+	t.Helper()
+	fixture := &passwordResetFixture{mailFixture: newMailFixture(t, users...)}
+	fixture.service = NewPasswordService(
+		fixture.users,
+		&fakePasswordResets{},
+		fixture.guacamole,
+		NewTokenService(fixture.sessions, fixture.users),
+		fixture.mailer(),
+	)
+	return fixture
+}
+
+// resetToken возвращает токен из последнего письма со ссылкой для сброса пароля
+func (f *passwordResetFixture) resetToken(t *testing.T, email string) string {
+	t.Helper()
+	return f.mailToken(t, email, resetLinkPattern)
+}
+
+func TestPasswordServiceResetFlow(t *testing.T) {
+	user := &common.User{Name: "Иван", Email: "ivan@example.com", Password: "old-hash"}
+	fixture := newPasswordResetFixture(t, user)
+	ctx := context.Background()
+
+	if err := fixture.service.ForgotPassword(ctx, common.PasswordForgotRequest{Email: user.Email}); err != nil {
+		t.Fatalf("ForgotPassword() error = %v", err)
+	}
+	token := fixture.resetToken(t, user.Email)
+
+	err := fixture.service.ResetPassword(ctx, common.PasswordResetRequest{Token: token, Password: "new-password"})
+	if err != nil {
+		t.Fatalf("ResetPassword() error = %v", err)
+	}
+
+	updated := fixture.users.get(user.Email)
+	if bcrypt.CompareHashAndPassword([]byte(updated.Password), []byte("new-password")) != nil {
+		t.Error("application password was not changed")
+	}
+	if updated.TokenVersion != 1 {
+		t.Errorf("token version = %d, want 1", updated.TokenVersion)
+	}
+	guacamolePassword, _ := fixture.guacamole.password(user.Email)
+	if guacamolePassword.HashHex != getHashedGuacamolePassword("new-password", guacamolePassword.SaltHex) {
+		t.Error("guacamole password does not match the new password")
+	}
+	if revoked := fixture.sessions.revokedUsers(); len(revoked) != 1 || revoked[0] != user.ID {
+		t.Errorf("revoked sessions of %v, want %v", revoked, user.ID)
+	}
+
+	err = fixture.service.ResetPassword(ctx, common.PasswordResetRequest{Token: token, Password: "another-password"})
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("second ResetPassword() error = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestPasswordServiceForgotPasswordUnknownEmail(t *testing.T) {
+	fixture := newPasswordResetFixture(t)
+
+	err := fixture.service.ForgotPassword(context.Background(), common.PasswordForgotRequest{Email: "nobody@example.com"})
+	if err != nil {
+		t.Fatalf("ForgotPassword() error = %v", err)
+	}
+	if mails := fixture.smtp.Mails(); len(mails) != 0 {
+		t.Errorf("sent %d mails for unknown email, want 0", len(mails))
+	}
+}
+
+func TestPasswordServiceResetPasswordRollback(t *testing.T) {
+	user := &common.User{Name: "Иван", Email: "ivan@example.com", Password: "old-hash"}
+	fixture := newPasswordResetFixture(t, user)
+	ctx := context.Background()
+
+	if err := fixture.service.ForgotPassword(ctx, common.PasswordForgotRequest{Email: user.Email}); err != nil {
+		t.Fatalf("ForgotPassword() error = %v", err)
+	}
+	token := fixture.resetToken(t, user.Email)
+
+	// Без учетной записи Guacamole пароль изменить нельзя: токен должен остаться действительным
+	delete(fixture.guacamole.passwords, user.Email)
+	err := fixture.service.ResetPassword(ctx, common.PasswordResetRequest{Token: token, Password: "new-password"})
+	if err == nil {
+		t.Fatal("ResetPassword() without guacamole account succeeded")
+	}
+	if updated := fixture.users.get(user.Email); updated.Password != "old-hash" {
+		t.Error("application password was changed by a failed reset")
+	}
+	if revoked := fixture.sessions.revokedUsers(); len(revoked) != 0 {
+		t.Errorf("failed reset revoked sessions of %v", revoked)
+	}
+
+	fixture.guacamole.passwords[user.Email] = common.GuacamolePassword{HashHex: "old", SaltHex: "old"}
+	err = fixture.service.ResetPassword(ctx, common.PasswordResetRequest{Token: token, Password: "new-password"})
+	if err != nil {
+		t.Fatalf("ResetPassword() with released token error = %v", err)
+	}
+}
+
+func TestPasswordServiceChangePasswordRestoresGuacamolePassword(t *testing.T) {
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("current-password"), bcrypt.MinCost)
+	user := &common.User{Name: "Иван", Email: "ivan@example.com", Password: string(hashed)}
+	fixture := newPasswordResetFixture(t, user)
+	fixture.users.failUpdatePassword = errors.New("database is unavailable")
+
+	_, err := fixture.service.ChangePassword(context.Background(), user.Email, common.PasswordChangeRequest{
+		CurrentPassword: "current-password",
+		Password:        "new-password",
+	})
+	if err == nil {
+		t.Fatal("ChangePassword() succeeded while the application password was not saved")
+	}
+	if password, _ := fixture.guacamole.password(user.Email); password.HashHex != "old" || password.SaltHex != "old" {
+		t.Errorf("guacamole password = %+v, want the previous password restored", password)
+	}
+}
+
+func TestPasswordServiceChangePasswordWrongCurrent(t *testing.T) {
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("current-password"), bcrypt.MinCost)
+	user := &common.User{Name: "Иван", Email: "ivan@example.com", Password: string(hashed)}
+	fixture := newPasswordResetFixture(t, user)
+
+	_, err := fixture.service.ChangePassword(context.Background(), user.Email, common.PasswordChangeRequest{
+		CurrentPassword: "wrong-password",
+		Password:        "new-password",
+	})
+	if !errors.Is(err, ErrInvalidPassword) {
+		t.Errorf("ChangePassword() error = %v, want ErrInvalidPassword", err)
+	}
+	if password, _ := fixture.guacamole.password(user.Email); password.HashHex != "old" {
+		t.Error("guacamole password was changed with a wrong current password")
+	}
+}
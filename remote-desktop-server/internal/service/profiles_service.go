@@ -0,0 +1,104 @@
+// Package service реализует бизнес-логику приложения.
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ProfileService предоставляет изменение профиля текущего пользователя.
+//
+// Электронная почта является именем пользователя Guacamole, поэтому она меняется только после
+// подтверждения нового адреса по ссылке из письма (EmailVerificationService.Verify).
+type ProfileService struct {
+	users        repository.UserRepository
+	guacamole    repository.GuacamoleRepository
+	verification *EmailVerificationService
+}
+
+// NewProfileService создает новый экземпляр ProfileService.
+//
+// Параметры:
+//   - users: репозиторий пользователей
+//   - guacamole: репозиторий базы Guacamole
+//   - verification: сервис подтверждения электронной почты
+//
+// Возвращает:
+//   - *ProfileService: указатель на созданный сервис
+func NewProfileService(
+	users repository.UserRepository,
+	guacamole repository.GuacamoleRepository,
+	verification *EmailVerificationService,
+) *ProfileService {
+	return &ProfileService{
+		users:        users,
+		guacamole:    guacamole,
+		verification: verification,
+	}
+}
+
+// UpdateProfile изменяет профиль текущего пользователя.
+// Имя, язык и часовой пояс сохраняются сразу, а на новую электронную почту отправляется
+// письмо со ссылкой для ее подтверждения.
+//
+// Параметры:
+//   - ctx: контекст
+//   - user: текущий пользователь
+//   - form: изменяемые поля профиля
+//
+// Возвращает:
+//   - *common.UserResponse: профиль после изменения (PendingEmail — адрес, ожидающий подтверждения)
+//   - error: ErrInvalidPassword, если при смене почты указан неверный пароль, ErrUnsupported,
+//     если почту пользователя задает внешний провайдер, ErrConflict, если адрес уже занят,
+//     или ошибка сохранения профиля или отправки письма
+func (service *ProfileService) UpdateProfile(
+	ctx context.Context,
+	user *common.User,
+	form common.UserProfileRequest,
+) (*common.UserResponse, error) {
+	var email string
+	if form.Email != nil && strings.TrimSpace(*form.Email) != user.Email {
+		email = strings.TrimSpace(*form.Email)
+		if err := service.checkEmailChange(ctx, user, email, form.CurrentPassword); err != nil {
+			return nil, err
+		}
+	}
+
+	if form.Name != nil {
+		user.Name = strings.TrimSpace(*form.Name)
+	}
+	if form.Locale != nil {
+		user.Locale = *form.Locale
+	}
+	if form.Timezone != nil {
+		user.Timezone = *form.Timezone
+	}
+	if err := service.users.UpdateProfile(ctx, user); err != nil {
+		return nil, err
+	}
+
+	response := userResponse(user)
+	if email != "" {
+		if err := service.verification.Send(ctx, user, email); err != nil {
+			return nil, err
+		}
+		response.PendingEmail = email
+	}
+	return response, nil
+}
+
+// checkEmailChange проверяет, что пользователь может сменить электронную почту на указанную
+func (service *ProfileService) checkEmailChange(ctx context.Context, user *common.User, email string, password string) error {
+	if len(user.GuacamoleCredentials) > 0 {
+		return fmt.Errorf("%w: email of %s is managed by an external provider", ErrUnsupported, user.Email)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(strings.TrimSpace(password))); err != nil {
+		return ErrInvalidPassword
+	}
+	return checkEmailAvailable(ctx, service.users, service.guacamole, email)
+}
@@ -0,0 +1,328 @@
+// Package service реализует бизнес-логику приложения.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/config"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/repository"
+)
+
+// ReconciliationService сверяет пользователей приложения с пользователями Guacamole,
+// исправляет расхождения и хранит результат последней фоновой сверки.
+type ReconciliationService struct {
+	users     repository.UserRepository
+	guacamole repository.GuacamoleRepository
+	sessions  *SessionService
+
+	mu        sync.Mutex                   // Защищает поля состояния ниже
+	running   bool                         // Выполняется ли сверка
+	lastRun   *common.ReconciliationReport // Результат последней сверки
+	lastError string                       // Ошибка последней сверки
+	nextRunAt *time.Time                   // Время следующей фоновой сверки
+}
+
+// NewReconciliationService создает и возвращает новый экземпляр ReconciliationService.
+//
+// Параметры:
+//   - users: репозиторий пользователей приложения
+//   - guacamole: репозиторий базы данных Guacamole
+//   - sessions: сервис подключений, через который проверяются права вызывающего
+//
+// Возвращает:
+//   - *ReconciliationService: указатель на созданный сервис
+func NewReconciliationService(
+	users repository.UserRepository,
+	guacamole repository.GuacamoleRepository,
+	sessions *SessionService,
+) *ReconciliationService {
+	return &ReconciliationService{
+		users:     users,
+		guacamole: guacamole,
+		sessions:  sessions,
+	}
+}
+
+// Run периодически выполняет сверку до отмены контекста.
+// Учетные записи Guacamole удаленных пользователей отключаются всегда,
+// остальные расхождения исправляются, если включен RECONCILE_REPAIR_ORPHANS.
+//
+// Параметры:
+//   - ctx: контекст, при отмене которого сверка прекращается
+func (service *ReconciliationService) Run(ctx context.Context) {
+	interval := config.ServerConfig.ReconciliationConfig.Interval
+	if interval <= 0 {
+		slog.Info("User reconciliation is disabled")
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		service.scheduleNext(time.Now().Add(interval))
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := service.run(ctx, backgroundOptions()); err != nil && !errors.Is(err, ErrConflict) {
+				slog.Error(fmt.Sprintf("Error reconciling users: %s", err.Error()))
+			}
+		}
+	}
+}
+
+// Status возвращает состояние фоновой сверки. Доступно только администратору Guacamole.
+//
+// Параметры:
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - *common.ReconciliationStatus: состояние и результат последней сверки
+//   - error: ErrForbidden, если вызывающий не администратор
+func (service *ReconciliationService) Status(guacToken string) (*common.ReconciliationStatus, error) {
+	if err := service.requireAdministrator(guacToken); err != nil {
+		return nil, err
+	}
+	interval := config.ServerConfig.ReconciliationConfig.Interval
+
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	return &common.ReconciliationStatus{
+		Enabled:   interval > 0,
+		Interval:  interval.String(),
+		Running:   service.running,
+		LastRun:   service.lastRun,
+		LastError: service.lastError,
+		NextRunAt: service.nextRunAt,
+	}, nil
+}
+
+// RunNow немедленно выполняет сверку с параметрами фоновой сверки.
+// Доступно только администратору Guacamole.
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - *common.ReconciliationReport: результат сверки
+//   - error: ErrForbidden, если вызывающий не администратор; ErrConflict, если сверка уже выполняется
+func (service *ReconciliationService) RunNow(
+	ctx context.Context,
+	guacToken string,
+) (*common.ReconciliationReport, error) {
+	if err := service.requireAdministrator(guacToken); err != nil {
+		return nil, err
+	}
+	return service.run(ctx, backgroundOptions())
+}
+
+// Reconcile сверяет пользователей и исправляет расхождения согласно options:
+//   - DisableDeleted: учетные записи Guacamole удаленных пользователей отключаются;
+//   - RepairOrphans: сущности Guacamole без учетной записи и учетные записи Guacamole без пользователя
+//     приложения удаляются (кроме служебной учетной записи и администраторов Guacamole, которые ведутся вручную),
+//     а пользователи приложения без учетной записи Guacamole помечаются удаленными,
+//     чтобы с тем же email можно было зарегистрироваться заново.
+//
+// Активные пользователи с отключенной учетной записью Guacamole только попадают в отчет:
+// учетная запись могла быть отключена администратором намеренно.
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//   - options: какие расхождения исправлять (нулевое значение — только отчет)
+//
+// Возвращает:
+//   - *common.ReconciliationReport: найденные и исправленные расхождения
+//   - error: ошибка, если не удалось получить данные для сверки
+func (service *ReconciliationService) Reconcile(
+	ctx context.Context,
+	options common.ReconciliationOptions,
+) (*common.ReconciliationReport, error) {
+	report := &common.ReconciliationReport{
+		ReconciliationOptions: options,
+		StartedAt:             time.Now(),
+		IncompleteEntities:    []string{},
+		OrphanedAccounts:      []string{},
+		MissingAccounts:       []string{},
+		DeletedUsersEnabled:   []string{},
+		DisabledAccounts:      []string{},
+		Repaired:              []string{},
+		Errors:                []string{},
+	}
+
+	emails, err := service.users.FindEmails(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch users: %w", err)
+	}
+	activeEmails, err := service.users.FindEmails(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch users: %w", err)
+	}
+	accounts, err := service.guacamole.FindUserAccounts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch guacamole users: %w", err)
+	}
+
+	known := make(map[string]bool, len(emails))
+	for _, email := range emails {
+		known[email] = true
+	}
+	active := make(map[string]bool, len(activeEmails))
+	for _, email := range activeEmails {
+		active[email] = true
+	}
+	withAccount := make(map[string]bool, len(accounts))
+	for _, account := range accounts {
+		switch {
+		case !account.HasUser:
+			report.IncompleteEntities = append(report.IncompleteEntities, account.Username)
+		case isManagedAccount(account):
+			// Служебная учетная запись и администраторы Guacamole не сверяются
+		case !known[account.Username]:
+			report.OrphanedAccounts = append(report.OrphanedAccounts, account.Username)
+		case !active[account.Username] && !account.Disabled:
+			report.DeletedUsersEnabled = append(report.DeletedUsersEnabled, account.Username)
+		case active[account.Username] && account.Disabled:
+			report.DisabledAccounts = append(report.DisabledAccounts, account.Username)
+		}
+		if account.HasUser {
+			withAccount[account.Username] = true
+		}
+	}
+	for _, email := range activeEmails {
+		if !withAccount[email] {
+			report.MissingAccounts = append(report.MissingAccounts, email)
+		}
+	}
+
+	if options.DisableDeleted {
+		for _, name := range report.DeletedUsersEnabled {
+			service.repair(report, "deleted user", name, func() error {
+				return service.guacamole.SetUserDisabled(ctx, name, true)
+			})
+		}
+	}
+	if options.RepairOrphans {
+		for _, name := range report.IncompleteEntities {
+			service.repair(report, "incomplete entity", name, func() error {
+				return service.guacamole.DeleteEntity(ctx, name, common.EntityTypeUser)
+			})
+		}
+		for _, name := range report.OrphanedAccounts {
+			service.repair(report, "orphaned account", name, func() error {
+				return service.guacamole.DeleteEntity(ctx, name, common.EntityTypeUser)
+			})
+		}
+		for _, email := range report.MissingAccounts {
+			service.repair(report, "missing account", email, func() error {
+				return service.users.SoftDelete(ctx, email)
+			})
+		}
+	}
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// run выполняет сверку и сохраняет ее результат как состояние фоновой сверки.
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//   - options: какие расхождения исправлять
+//
+// Возвращает:
+//   - *common.ReconciliationReport: результат сверки
+//   - error: ErrConflict, если сверка уже выполняется; ошибка сверки
+func (service *ReconciliationService) run(
+	ctx context.Context,
+	options common.ReconciliationOptions,
+) (*common.ReconciliationReport, error) {
+	service.mu.Lock()
+	if service.running {
+		service.mu.Unlock()
+		return nil, ErrConflict
+	}
+	service.running = true
+	service.mu.Unlock()
+
+	report, err := service.Reconcile(ctx, options)
+
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	service.running = false
+	if err != nil {
+		service.lastError = err.Error()
+		return nil, err
+	}
+	service.lastRun = report
+	service.lastError = ""
+	if report.HasDrift() {
+		slog.Warn(fmt.Sprintf(
+			"User reconciliation found drift: %d orphaned, %d missing, %d disabled, %d errors",
+			len(report.IncompleteEntities)+len(report.OrphanedAccounts),
+			len(report.MissingAccounts),
+			len(report.DisabledAccounts),
+			len(report.Errors),
+		))
+	}
+	return report, nil
+}
+
+// scheduleNext запоминает время следующей фоновой сверки
+func (service *ReconciliationService) scheduleNext(at time.Time) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	service.nextRunAt = &at
+}
+
+// repair выполняет исправление расхождения и записывает результат в отчет
+func (service *ReconciliationService) repair(
+	report *common.ReconciliationReport,
+	kind string,
+	name string,
+	action func() error,
+) {
+	if err := action(); err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("%s %s: %s", kind, name, err.Error()))
+		return
+	}
+	report.Repaired = append(report.Repaired, fmt.Sprintf("%s %s", kind, name))
+}
+
+// requireAdministrator проверяет, что владелец токена — администратор Guacamole.
+//
+// Параметры:
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - error: ErrForbidden, если вызывающий не администратор
+func (service *ReconciliationService) requireAdministrator(guacToken string) error {
+	isAdmin, err := service.sessions.isAdministrator(guacToken)
+	if err != nil {
+		return err
+	}
+	if !isAdmin {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// backgroundOptions возвращает параметры исправления для фоновой сверки
+func backgroundOptions() common.ReconciliationOptions {
+	return common.ReconciliationOptions{
+		DisableDeleted: true,
+		RepairOrphans:  config.ServerConfig.ReconciliationConfig.RepairOrphans,
+	}
+}
+
+// isManagedAccount проверяет, ведется ли учетная запись Guacamole вручную:
+// это служебная учетная запись сервера или администратор Guacamole
+func isManagedAccount(account *common.GuacamoleAccount) bool {
+	return account.Administrator || account.Username == config.ServerConfig.GuacamoleServiceAccount.Username
+}
@@ -0,0 +1,281 @@
+// Package service реализует бизнес-логику приложения.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/config"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/recording"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/repository"
+)
+
+// Параметры фоновой индексации записей терминала
+const (
+	// sessionMatchWindow допустимое расхождение времени начала записи и сессии в истории подключений
+	sessionMatchWindow = 2 * time.Minute
+	// settleTime время без изменений, после которого запись считается законченной
+	settleTime = time.Minute
+)
+
+// CommandIndexService строит индекс команд, введенных в терминальных сессиях,
+// и выполняет поиск по нему.
+//
+// Команды извлекаются из записей typescript (SSH, Telnet, Kubernetes). Пользователь сессии
+// определяется по истории подключений Guacamole: ищется сессия того же подключения,
+// начавшаяся ближе всего ко времени начала записи.
+type CommandIndexService struct {
+	recordings *RecordingService
+	sessions   *SessionService
+	index      repository.RecordingIndexRepository
+	history    repository.HistoryRepository
+}
+
+// NewCommandIndexService создает и возвращает новый экземпляр CommandIndexService.
+//
+// Параметры:
+//   - recordings: сервис записей сессий
+//   - sessions: сервис подключений для проверки прав
+//   - index: репозиторий индекса команд
+//   - history: репозиторий истории подключений
+//
+// Возвращает:
+//   - *CommandIndexService: указатель на созданный сервис
+func NewCommandIndexService(
+	recordings *RecordingService,
+	sessions *SessionService,
+	index repository.RecordingIndexRepository,
+	history repository.HistoryRepository,
+) *CommandIndexService {
+	return &CommandIndexService{
+		recordings: recordings,
+		sessions:   sessions,
+		index:      index,
+		history:    history,
+	}
+}
+
+// SearchCommands ищет команды в проиндексированных записях.
+// Администратор Guacamole ищет по всем пользователям, остальные — только по своим сессиям.
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//   - filter: поисковая строка, фильтры и пагинация
+//   - username: имя пользователя Guacamole (email) вызывающего
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - *common.CommandSearchPage: страница результатов
+//   - error: ErrForbidden при поиске по чужим сессиям без прав администратора
+func (service *CommandIndexService) SearchCommands(
+	ctx context.Context,
+	filter *common.CommandSearchFilter,
+	username string,
+	guacToken string,
+) (*common.CommandSearchPage, error) {
+	if err := restrictToUser(service.sessions, &filter.HistoryFilter, username, guacToken); err != nil {
+		return nil, err
+	}
+	commands, total, err := service.index.SearchCommands(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search commands: %w", err)
+	}
+	return &common.CommandSearchPage{
+		Items:   commands,
+		Total:   total,
+		Page:    filter.Page,
+		PerPage: filter.PerPage,
+	}, nil
+}
+
+// IndexRecording немедленно индексирует запись typescript и возвращает найденные команды.
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//   - id: идентификатор подключения
+//   - name: имя файла typescript
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - []*common.RecordedCommand: команды записи
+//   - error: ErrNotFound, если запись не найдена; ErrUnsupported, если файл не является typescript
+func (service *CommandIndexService) IndexRecording(
+	ctx context.Context,
+	id string,
+	name string,
+	guacToken string,
+) ([]*common.RecordedCommand, error) {
+	if recordingType(name) != common.RecordingTypeTypescript {
+		return nil, ErrUnsupported
+	}
+	data, err := service.recordings.OpenRecording(id, name, guacToken)
+	if err != nil {
+		return nil, err
+	}
+	defer data.Close()
+	return service.indexFile(ctx, id, name, data)
+}
+
+// Run запускает периодическую индексацию новых и изменившихся записей typescript.
+// Завершается при отмене контекста.
+//
+// Параметры:
+//   - ctx: контекст, определяющий время работы индексации
+func (service *CommandIndexService) Run(ctx context.Context) {
+	interval := config.ServerConfig.RecordingConfig.IndexInterval
+	if interval <= 0 {
+		slog.Info("Recording command indexer is disabled")
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := service.indexPending(ctx, time.Now()); err != nil {
+				slog.Error(fmt.Sprintf("Error indexing recordings: %s", err.Error()))
+			}
+		}
+	}
+}
+
+// indexPending индексирует законченные записи typescript, которые еще не индексировались
+// или изменились после индексации.
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//   - now: текущее время
+//
+// Возвращает:
+//   - error: ошибка, если не удалось прочитать каталог записей
+func (service *CommandIndexService) indexPending(ctx context.Context, now time.Time) error {
+	dirs, err := os.ReadDir(config.ServerConfig.RecordingConfig.StoragePath)
+	if err != nil {
+		return fmt.Errorf("failed to read recordings storage: %w", err)
+	}
+	indexed := 0
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		entries, err := os.ReadDir(recordingDir(dir.Name()))
+		if err != nil {
+			slog.Error(fmt.Sprintf("Error reading recordings of connection %s: %s", dir.Name(), err.Error()))
+			continue
+		}
+		for _, entry := range entries {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if !strings.HasSuffix(entry.Name(), common.TypescriptSuffix) {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil || !info.Mode().IsRegular() || now.Sub(info.ModTime()) < settleTime {
+				continue
+			}
+			existing, err := service.index.FindRecording(ctx, dir.Name(), entry.Name())
+			if err != nil {
+				return fmt.Errorf("failed to read recording index: %w", err)
+			}
+			if existing != nil && existing.Size == info.Size() {
+				continue
+			}
+
+			if err := service.indexPath(ctx, dir.Name(), entry.Name()); err != nil {
+				slog.Error(fmt.Sprintf("Error indexing recording %s: %s", entry.Name(), err.Error()))
+				continue
+			}
+			indexed++
+		}
+	}
+	if indexed > 0 {
+		slog.Info("Terminal recordings indexed", slog.Int("count", indexed))
+	}
+	return nil
+}
+
+// indexPath открывает запись typescript по пути в хранилище и индексирует ее
+func (service *CommandIndexService) indexPath(ctx context.Context, id string, name string) error {
+	data, err := os.Open(filepath.Join(recordingDir(id), name))
+	if err != nil {
+		return err
+	}
+	defer data.Close()
+	_, err = service.indexFile(ctx, id, name, data)
+	return err
+}
+
+// indexFile извлекает команды из записи typescript и сохраняет их в индекс.
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//   - id: идентификатор подключения
+//   - name: имя файла typescript
+//   - data: открытый файл вывода typescript
+//
+// Возвращает:
+//   - []*common.RecordedCommand: сохраненные команды
+//   - error: ошибка чтения записи или сохранения индекса
+func (service *CommandIndexService) indexFile(
+	ctx context.Context,
+	id string,
+	name string,
+	data *os.File,
+) ([]*common.RecordedCommand, error) {
+	stream, err := openTypescript(data, name)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.timing.Close()
+
+	info, err := data.Stat()
+	if err != nil {
+		return nil, err
+	}
+	extracted, err := recording.ExtractCommands(stream.data, stream.timing)
+	if err != nil && !errors.Is(err, recording.ErrMalformed) {
+		return nil, fmt.Errorf("failed to extract commands: %w", err)
+	}
+	if err != nil {
+		// Оборванная запись: сохраняются команды, найденные до ошибки формата
+		slog.Warn(fmt.Sprintf("Recording %s is truncated: %s", name, err.Error()))
+	}
+
+	username, err := service.history.FindUsernameAt(ctx, id, stream.startedAt, sessionMatchWindow)
+	if err != nil {
+		return nil, fmt.Errorf("failed to match recording with session: %w", err)
+	}
+
+	commands := make([]*common.RecordedCommand, 0, len(extracted))
+	for _, command := range extracted {
+		commands = append(commands, &common.RecordedCommand{
+			ConnectionID:  id,
+			RecordingName: name,
+			Username:      username,
+			Command:       command.Text,
+			OffsetMs:      command.TimestampMs,
+			ExecutedAt:    stream.startedAt.Add(time.Duration(command.TimestampMs) * time.Millisecond),
+		})
+	}
+	if err := service.index.Save(ctx, common.IndexedRecording{
+		ConnectionID:  id,
+		RecordingName: name,
+		Username:      username,
+		StartedAt:     stream.startedAt,
+		Size:          info.Size(),
+	}, commands); err != nil {
+		return nil, fmt.Errorf("failed to save recording index: %w", err)
+	}
+	return commands, nil
+}
@@ -0,0 +1,443 @@
+// Package service реализует бизнес-логику приложения.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/config"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/recording"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/repository"
+)
+
+// RecordingService предоставляет доступ к записям сессий подключений и удаляет
+// записи с истекшим сроком хранения.
+//
+// Записи читаются из каталога RECORDING_STORAGE_PATH, в который смонтирован
+// каталог записей guacd. Доступ к записям подключения есть у тех, кто может
+// изменять подключение в Guacamole.
+type RecordingService struct {
+	sessions *SessionService
+	policies repository.RecordingPolicyRepository
+	index    repository.RecordingIndexRepository
+}
+
+// NewRecordingService создает и возвращает новый экземпляр RecordingService.
+//
+// Параметры:
+//   - sessions: сервис подключений, через который проверяется доступ
+//   - policies: репозиторий сроков хранения записей
+//   - index: репозиторий индекса команд, из которого удаляются удаленные записи
+//
+// Возвращает:
+//   - *RecordingService: указатель на созданный сервис
+func NewRecordingService(
+	sessions *SessionService,
+	policies repository.RecordingPolicyRepository,
+	index repository.RecordingIndexRepository,
+) *RecordingService {
+	return &RecordingService{
+		sessions: sessions,
+		policies: policies,
+		index:    index,
+	}
+}
+
+// GetRecordings возвращает записи подключения, отсортированные от новых к старым.
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//   - id: идентификатор подключения
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - []*common.Recording: записи подключения
+//   - error: ErrNotFound, если подключение недоступно
+func (service *RecordingService) GetRecordings(
+	ctx context.Context,
+	id string,
+	guacToken string,
+) ([]*common.Recording, error) {
+	if err := service.authorize(id, guacToken); err != nil {
+		return nil, err
+	}
+	retention, err := service.retention(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(recordingDir(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return []*common.Recording{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recordings: %w", err)
+	}
+
+	recordings := make([]*common.Recording, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		recording := &common.Recording{
+			Name:       entry.Name(),
+			Type:       recordingType(entry.Name()),
+			Size:       info.Size(),
+			ModifiedAt: info.ModTime(),
+		}
+		if retention > 0 {
+			expiresAt := info.ModTime().Add(retention)
+			recording.ExpiresAt = &expiresAt
+		}
+		recordings = append(recordings, recording)
+	}
+	sort.Slice(recordings, func(i, j int) bool {
+		return recordings[i].ModifiedAt.After(recordings[j].ModifiedAt)
+	})
+	return recordings, nil
+}
+
+// OpenRecording открывает файл записи подключения для чтения.
+// Закрыть файл должен вызывающий.
+//
+// Параметры:
+//   - id: идентификатор подключения
+//   - name: имя файла записи
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - *os.File: открытый файл записи
+//   - error: ErrNotFound, если подключение недоступно или запись не найдена
+func (service *RecordingService) OpenRecording(id string, name string, guacToken string) (*os.File, error) {
+	path, err := service.recordingPath(id, name, guacToken)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording: %w", err)
+	}
+	return file, nil
+}
+
+// GetRecordingIndex возвращает длительность, события клавиатуры и индекс перемотки
+// записи протокола Guacamole.
+//
+// Параметры:
+//   - id: идентификатор подключения
+//   - name: имя файла записи
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - *recording.Summary: сведения о записи
+//   - error: ErrNotFound, если запись не найдена; ErrUnsupported для записей typescript
+func (service *RecordingService) GetRecordingIndex(id string, name string, guacToken string) (*recording.Summary, error) {
+	file, err := service.openGuacamoleRecording(id, name, guacToken)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	summary, err := recording.Analyze(file, recording.DefaultIndexInterval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze recording: %w", err)
+	}
+	return summary, nil
+}
+
+// GetRecordingThumbnail возвращает снимок экрана записи в указанный момент времени.
+//
+// Параметры:
+//   - id: идентификатор подключения
+//   - name: имя файла записи
+//   - position: момент времени от начала записи
+//   - width: максимальная ширина снимка
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - image.Image: снимок экрана
+//   - error: ErrNotFound, если запись не найдена; ErrUnsupported для записей typescript
+func (service *RecordingService) GetRecordingThumbnail(
+	id string,
+	name string,
+	position time.Duration,
+	width int,
+	guacToken string,
+) (image.Image, error) {
+	file, err := service.openGuacamoleRecording(id, name, guacToken)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	thumbnail, err := recording.Thumbnail(file, position, width)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render recording: %w", err)
+	}
+	return thumbnail, nil
+}
+
+// openGuacamoleRecording открывает запись протокола Guacamole.
+// Записи typescript содержат вывод терминала и не разбираются.
+func (service *RecordingService) openGuacamoleRecording(id string, name string, guacToken string) (*os.File, error) {
+	if recordingType(name) != common.RecordingTypeGuacamole {
+		return nil, ErrUnsupported
+	}
+	return service.OpenRecording(id, name, guacToken)
+}
+
+// DestroyRecording удаляет файл записи подключения.
+//
+// Параметры:
+//   - id: идентификатор подключения
+//   - name: имя файла записи
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - error: ErrNotFound, если подключение недоступно или запись не найдена
+func (service *RecordingService) DestroyRecording(id string, name string, guacToken string) error {
+	path, err := service.recordingPath(id, name, guacToken)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove recording: %w", err)
+	}
+	return service.forget(context.Background(), id, name)
+}
+
+// OpenAsciicast открывает запись typescript для преобразования в asciicast v2.
+// Закрыть поток должен вызывающий.
+//
+// Параметры:
+//   - id: идентификатор подключения
+//   - name: имя файла typescript
+//   - width: количество колонок терминала (0 — по умолчанию)
+//   - height: количество строк терминала (0 — по умолчанию)
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - *AsciicastStream: поток преобразования
+//   - error: ErrNotFound, если запись не найдена; ErrUnsupported, если файл не является typescript
+func (service *RecordingService) OpenAsciicast(
+	id string,
+	name string,
+	width int,
+	height int,
+	guacToken string,
+) (*AsciicastStream, error) {
+	if recordingType(name) != common.RecordingTypeTypescript {
+		return nil, ErrUnsupported
+	}
+	data, err := service.OpenRecording(id, name, guacToken)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := openTypescript(data, name)
+	if err != nil {
+		data.Close()
+		return nil, err
+	}
+	stream.header.Width = width
+	stream.header.Height = height
+	return stream, nil
+}
+
+// Run запускает периодическое удаление записей с истекшим сроком хранения.
+// Завершается при отмене контекста.
+//
+// Параметры:
+//   - ctx: контекст, определяющий время работы очистки
+func (service *RecordingService) Run(ctx context.Context) {
+	interval := config.ServerConfig.RecordingConfig.SweepInterval
+	if interval <= 0 {
+		slog.Info("Recording retention sweeper is disabled")
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := service.sweep(ctx, time.Now()); err != nil {
+				slog.Error(fmt.Sprintf("Error sweeping recordings: %s", err.Error()))
+			}
+		}
+	}
+}
+
+// sweep удаляет записи, измененные раньше срока хранения своего подключения.
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//   - now: текущее время
+//
+// Возвращает:
+//   - error: ошибка, если не удалось прочитать политики или каталог записей
+func (service *RecordingService) sweep(ctx context.Context, now time.Time) error {
+	policies, err := service.policies.FindAll(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read recording policies: %w", err)
+	}
+	retentions := make(map[string]time.Duration, len(policies))
+	for _, policy := range policies {
+		retentions[policy.ConnectionID] = time.Duration(policy.RetentionDays) * 24 * time.Hour
+	}
+
+	dirs, err := os.ReadDir(config.ServerConfig.RecordingConfig.StoragePath)
+	if err != nil {
+		return fmt.Errorf("failed to read recordings storage: %w", err)
+	}
+	removed := 0
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		retention, ok := retentions[dir.Name()]
+		if !ok {
+			retention = config.ServerConfig.RecordingConfig.Retention
+		}
+		if retention <= 0 {
+			continue
+		}
+
+		entries, err := os.ReadDir(recordingDir(dir.Name()))
+		if err != nil {
+			slog.Error(fmt.Sprintf("Error reading recordings of connection %s: %s", dir.Name(), err.Error()))
+			continue
+		}
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil || !info.Mode().IsRegular() || now.Sub(info.ModTime()) < retention {
+				continue
+			}
+			if err := os.Remove(filepath.Join(recordingDir(dir.Name()), entry.Name())); err != nil {
+				slog.Error(fmt.Sprintf("Error removing expired recording %s: %s", entry.Name(), err.Error()))
+				continue
+			}
+			if err := service.forget(ctx, dir.Name(), entry.Name()); err != nil {
+				slog.Error(fmt.Sprintf("Error removing recording %s from index: %s", entry.Name(), err.Error()))
+			}
+			removed++
+		}
+	}
+	if removed > 0 {
+		slog.Info("Expired recordings removed", slog.Int("count", removed))
+	}
+	return nil
+}
+
+// forget удаляет запись typescript из индекса команд
+func (service *RecordingService) forget(ctx context.Context, id string, name string) error {
+	if recordingType(name) != common.RecordingTypeTypescript {
+		return nil
+	}
+	if err := service.index.Delete(ctx, id, name); err != nil {
+		return fmt.Errorf("failed to remove recording from index: %w", err)
+	}
+	return nil
+}
+
+// authorize проверяет, что владелец токена может изменять подключение.
+// Записи содержат сессии всех пользователей подключения, поэтому права на чтение недостаточно.
+//
+// Параметры:
+//   - id: идентификатор подключения
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - error: ErrNotFound, если подключение не найдено или недоступно
+func (service *RecordingService) authorize(id string, guacToken string) error {
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+		return ErrNotFound
+	}
+	if _, err := service.sessions.getConnection(id, guacToken); err != nil {
+		var apiErr *GuacamoleAPIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode < 500 {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// recordingPath проверяет доступ и возвращает путь к файлу записи.
+//
+// Параметры:
+//   - id: идентификатор подключения
+//   - name: имя файла записи
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - string: путь к файлу записи
+//   - error: ErrNotFound, если имя некорректно или подключение недоступно
+func (service *RecordingService) recordingPath(id string, name string, guacToken string) (string, error) {
+	if name == "" || strings.HasPrefix(name, ".") || filepath.Base(name) != name {
+		return "", ErrNotFound
+	}
+	if err := service.authorize(id, guacToken); err != nil {
+		return "", err
+	}
+	return filepath.Join(recordingDir(id), name), nil
+}
+
+// retention возвращает срок хранения записей подключения.
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//   - id: идентификатор подключения
+//
+// Возвращает:
+//   - time.Duration: срок хранения (0 — бессрочно)
+//   - error: ошибка, если не удалось прочитать политику
+func (service *RecordingService) retention(ctx context.Context, id string) (time.Duration, error) {
+	policy, err := service.policies.Find(ctx, id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read recording policy: %w", err)
+	}
+	if policy == nil {
+		return config.ServerConfig.RecordingConfig.Retention, nil
+	}
+	return time.Duration(policy.RetentionDays) * 24 * time.Hour, nil
+}
+
+// recordingDir возвращает локальный каталог записей подключения.
+func recordingDir(id string) string {
+	return filepath.Join(config.ServerConfig.RecordingConfig.StoragePath, id)
+}
+
+// recordingType определяет тип записи по имени файла.
+func recordingType(name string) string {
+	switch {
+	case strings.HasSuffix(name, common.TypescriptTimingSuffix):
+		return common.RecordingTypeTiming
+	case strings.HasSuffix(name, common.TypescriptSuffix):
+		return common.RecordingTypeTypescript
+	}
+	return common.RecordingTypeGuacamole
+}
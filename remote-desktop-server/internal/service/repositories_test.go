@@ -0,0 +1,636 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/mailer"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/mailer/mailertest"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/repository"
+)
+
+// Репозитории в памяти для тестов сервисов.
+// Каждый репозиторий встраивает интерфейс и реализует только методы, которые вызывают
+// тестируемые сервисы; вызов остальных методов завершает тест паникой.
+
+// fakeUsers хранит пользователей приложения
+type fakeUsers struct {
+	repository.UserRepository
+
+	mu    sync.Mutex
+	users map[uuid.UUID]*common.User
+
+	// failUpdatePassword ошибка, которую возвращает UpdatePassword (nil — пароль сохраняется)
+	failUpdatePassword error
+}
+
+func newFakeUsers(users ...*common.User) *fakeUsers {
+	repo := &fakeUsers{users: make(map[uuid.UUID]*common.User)}
+	for _, user := range users {
+		if user.ID == uuid.Nil {
+			user.ID = uuid.New()
+		}
+		repo.users[user.ID] = user
+	}
+	return repo
+}
+
+// get возвращает копию пользователя по email (включая удаленных)
+func (r *fakeUsers) get(email string) *common.User {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if strings.EqualFold(user.Email, email) {
+			copied := *user
+			return &copied
+		}
+	}
+	return nil
+}
+
+func (r *fakeUsers) FindByEmail(ctx context.Context, email string) (*common.User, error) {
+	user := r.get(email)
+	if user == nil || user.DeletedAt != nil {
+		return nil, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+func (r *fakeUsers) FindByID(ctx context.Context, id uuid.UUID) (*common.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt != nil {
+		return nil, sql.ErrNoRows
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *fakeUsers) Create(ctx context.Context, form common.AuthSignUpRequest, role string, verified bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user := &common.User{
+		ID:       uuid.New(),
+		Name:     form.Name,
+		Email:    form.Email,
+		Password: form.Password,
+		Role:     role,
+	}
+	if verified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	r.users[user.ID] = user
+	return nil
+}
+
+func (r *fakeUsers) UpdatePassword(ctx context.Context, id uuid.UUID, password string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failUpdatePassword != nil {
+		return r.failUpdatePassword
+	}
+	user, ok := r.users[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	user.Password = password
+	user.TokenVersion++
+	return nil
+}
+
+func (r *fakeUsers) MarkEmailVerified(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	return nil
+}
+
+func (r *fakeUsers) SetGuacamoleCredentials(ctx context.Context, id uuid.UUID, credentials []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	user.GuacamoleCredentials = credentials
+	return nil
+}
+
+func (r *fakeUsers) SetRole(ctx context.Context, id uuid.UUID, role string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	user.Role = role
+	return nil
+}
+
+// fakeGuacamole хранит пароли, системные разрешения и отключенные учетные записи пользователей Guacamole
+type fakeGuacamole struct {
+	repository.GuacamoleRepository
+
+	mu          sync.Mutex
+	passwords   map[string]common.GuacamolePassword
+	permissions map[string][]string
+	disabled    map[string]bool
+}
+
+func newFakeGuacamole(usernames ...string) *fakeGuacamole {
+	repo := &fakeGuacamole{
+		passwords:   make(map[string]common.GuacamolePassword),
+		permissions: make(map[string][]string),
+		disabled:    make(map[string]bool),
+	}
+	for _, username := range usernames {
+		repo.passwords[username] = common.GuacamolePassword{}
+	}
+	return repo
+}
+
+// isDisabled сообщает, отключена ли учетная запись пользователя Guacamole
+func (r *fakeGuacamole) isDisabled(username string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.disabled[username]
+}
+
+// password возвращает пароль пользователя Guacamole и признак его существования
+func (r *fakeGuacamole) password(username string) (common.GuacamolePassword, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	password, ok := r.passwords[username]
+	return password, ok
+}
+
+func (r *fakeGuacamole) SetUserPassword(
+	ctx context.Context,
+	username string,
+	password common.GuacamolePassword,
+) (*common.GuacamolePassword, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	previous, ok := r.passwords[username]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	r.passwords[username] = password
+	return &previous, nil
+}
+
+func (r *fakeGuacamole) FindEntity(ctx context.Context, name string, entityType string) (*common.GuacamoleEntity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.passwords[name]; !ok || entityType != common.EntityTypeUser {
+		return nil, nil
+	}
+	return &common.GuacamoleEntity{Name: name, Type: entityType}, nil
+}
+
+func (r *fakeGuacamole) CreateUser(ctx context.Context, form common.GuacamoleUser) (uint64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.passwords[form.Username] = common.GuacamolePassword{HashHex: form.PasswordHex, SaltHex: form.SaultHex}
+	r.permissions[form.Username] = form.Permissions
+	r.disabled[form.Username] = form.Disabled
+	return uint64(len(r.passwords)), nil
+}
+
+func (r *fakeGuacamole) SetUserDisabled(ctx context.Context, username string, disabled bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.passwords[username]; !ok {
+		return sql.ErrNoRows
+	}
+	r.disabled[username] = disabled
+	return nil
+}
+
+func (r *fakeGuacamole) DeleteEntity(ctx context.Context, name string, entityType string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.passwords, name)
+	delete(r.permissions, name)
+	delete(r.disabled, name)
+	return nil
+}
+
+func (r *fakeGuacamole) FindSystemPermissions(ctx context.Context, username string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.permissions[username], nil
+}
+
+func (r *fakeGuacamole) SetSystemPermissions(ctx context.Context, username string, permissions []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.passwords[username]; !ok {
+		return sql.ErrNoRows
+	}
+	r.permissions[username] = permissions
+	return nil
+}
+
+// fakeAuthSessions хранит сессии входа и запоминает пользователей, сессии которых были отозваны
+type fakeAuthSessions struct {
+	repository.AuthSessionRepository
+
+	mu       sync.Mutex
+	sessions []*common.AuthSession
+	revoked  []uuid.UUID
+}
+
+func (r *fakeAuthSessions) Create(ctx context.Context, session *common.AuthSession) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	session.ID = uuid.New()
+	session.CreatedAt = time.Now()
+	copied := *session
+	r.sessions = append(r.sessions, &copied)
+	return nil
+}
+
+func (r *fakeAuthSessions) CreateRefreshToken(
+	ctx context.Context,
+	sessionID uuid.UUID,
+	tokenHash string,
+	ttl time.Duration,
+) error {
+	return nil
+}
+
+// issuedTo возвращает пользователей, для которых были созданы сессии входа
+func (r *fakeAuthSessions) issuedTo() []uuid.UUID {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	users := make([]uuid.UUID, 0, len(r.sessions))
+	for _, session := range r.sessions {
+		users = append(users, session.UserID)
+	}
+	return users
+}
+
+func (r *fakeAuthSessions) RevokeByUser(ctx context.Context, userID uuid.UUID) ([]*common.AuthSession, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.revoked = append(r.revoked, userID)
+	return nil, nil
+}
+
+// revokedUsers возвращает пользователей, сессии которых были отозваны
+func (r *fakeAuthSessions) revokedUsers() []uuid.UUID {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]uuid.UUID(nil), r.revoked...)
+}
+
+// fakeTwoFactor хранит секреты TOTP, коды восстановления и незавершенные входы
+type fakeTwoFactor struct {
+	repository.TwoFactorRepository
+
+	mu            sync.Mutex
+	secrets       map[uuid.UUID]*common.UserTOTP
+	recoveryCodes map[string]uuid.UUID
+	challenges    []uuid.UUID
+	records       map[string]*common.AuthChallengeRecord
+	consumed      map[uuid.UUID]bool
+}
+
+func newFakeTwoFactor() *fakeTwoFactor {
+	return &fakeTwoFactor{
+		secrets:       make(map[uuid.UUID]*common.UserTOTP),
+		recoveryCodes: make(map[string]uuid.UUID),
+		records:       make(map[string]*common.AuthChallengeRecord),
+		consumed:      make(map[uuid.UUID]bool),
+	}
+}
+
+func (r *fakeTwoFactor) FindTOTP(ctx context.Context, userID uuid.UUID) (*common.UserTOTP, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	secret, ok := r.secrets[userID]
+	if !ok {
+		return nil, nil
+	}
+	copied := *secret
+	return &copied, nil
+}
+
+func (r *fakeTwoFactor) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	secret, ok := r.secrets[userID]
+	if !ok || step <= secret.LastUsedStep {
+		return false, nil
+	}
+	secret.LastUsedStep = step
+	return true, nil
+}
+
+func (r *fakeTwoFactor) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if owner, ok := r.recoveryCodes[codeHash]; !ok || owner != userID {
+		return false, nil
+	}
+	delete(r.recoveryCodes, codeHash)
+	return true, nil
+}
+
+func (r *fakeTwoFactor) CreateChallenge(
+	ctx context.Context,
+	userID uuid.UUID,
+	tokenHash string,
+	credentials []byte,
+	ttl time.Duration,
+) (time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.challenges = append(r.challenges, userID)
+	r.records[tokenHash] = &common.AuthChallengeRecord{
+		ID:          uuid.New(),
+		UserID:      userID,
+		Credentials: credentials,
+		ExpiresAt:   time.Now().Add(ttl),
+	}
+	return time.Now().Add(ttl), nil
+}
+
+func (r *fakeTwoFactor) FindChallenge(
+	ctx context.Context,
+	tokenHash string,
+	maxAttempts int,
+) (*common.AuthChallengeRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record, ok := r.records[tokenHash]
+	if !ok || r.consumed[record.ID] || record.Attempts >= maxAttempts || time.Now().After(record.ExpiresAt) {
+		return nil, nil
+	}
+	copied := *record
+	return &copied, nil
+}
+
+func (r *fakeTwoFactor) AddChallengeAttempt(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, record := range r.records {
+		if record.ID == id {
+			record.Attempts++
+		}
+	}
+	return nil
+}
+
+func (r *fakeTwoFactor) ConsumeChallenge(ctx context.Context, id uuid.UUID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.consumed[id] {
+		return false, nil
+	}
+	r.consumed[id] = true
+	return true, nil
+}
+
+// fakeShareLinks хранит ссылки совместного доступа
+type fakeShareLinks struct {
+	repository.ShareLinkRepository
+
+	mu    sync.Mutex
+	links map[string]*common.ShareLink
+}
+
+func newFakeShareLinks() *fakeShareLinks {
+	return &fakeShareLinks{links: make(map[string]*common.ShareLink)}
+}
+
+// update изменяет сохраненную ссылку
+func (r *fakeShareLinks) update(id string, modify func(link *common.ShareLink)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	modify(r.links[id])
+}
+
+func (r *fakeShareLinks) Create(ctx context.Context, link *common.ShareLink) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	link.ID = uuid.NewString()
+	link.CreatedAt = time.Now()
+	copied := *link
+	r.links[link.ID] = &copied
+	return nil
+}
+
+func (r *fakeShareLinks) FindByID(ctx context.Context, id string) (*common.ShareLink, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	link, ok := r.links[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *link
+	return &copied, nil
+}
+
+func (r *fakeShareLinks) FindByToken(ctx context.Context, tokenHash string) (*common.ShareLink, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, link := range r.links {
+		if link.TokenHash == tokenHash {
+			copied := *link
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeShareLinks) Revoke(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if link, ok := r.links[id]; ok && link.RevokedAt == nil {
+		now := time.Now()
+		link.RevokedAt = &now
+	}
+	return nil
+}
+
+func (r *fakeShareLinks) Use(
+	ctx context.Context,
+	tokenHash string,
+	remoteAddr string,
+	userAgent string,
+) (*common.ShareLink, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, link := range r.links {
+		if link.TokenHash == tokenHash && shareLinkActive(link, time.Now()) {
+			link.Uses++
+			copied := *link
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+// fakeTokenStore хранит одноразовые токены, отправленные по почте (сброс пароля, подтверждение почты).
+// Токен выдается Consume один раз и до истечения срока; Release возвращает его для повторного использования.
+type fakeTokenStore[T any] struct {
+	mu     sync.Mutex
+	tokens map[string]*fakeStoredToken[T]
+}
+
+type fakeStoredToken[T any] struct {
+	id        uuid.UUID
+	expiresAt time.Time
+	value     T
+	used      bool
+}
+
+// add сохраняет токен по хэшу
+func (s *fakeTokenStore[T]) add(tokenHash string, id uuid.UUID, expiresAt time.Time, value T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tokens == nil {
+		s.tokens = make(map[string]*fakeStoredToken[T])
+	}
+	s.tokens[tokenHash] = &fakeStoredToken[T]{id: id, expiresAt: expiresAt, value: value}
+}
+
+// consume отмечает токен использованным; nil, если токен не найден, использован или истек
+func (s *fakeTokenStore[T]) consume(tokenHash string) *T {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, ok := s.tokens[tokenHash]
+	if !ok || token.used || time.Now().After(token.expiresAt) {
+		return nil
+	}
+	token.used = true
+	value := token.value
+	return &value
+}
+
+// release снимает отметку об использовании с токена
+func (s *fakeTokenStore[T]) release(id uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, token := range s.tokens {
+		if token.id == id {
+			token.used = false
+		}
+	}
+}
+
+// fakePasswordResets хранит токены сброса пароля
+type fakePasswordResets struct {
+	repository.PasswordResetRepository
+	store fakeTokenStore[common.PasswordResetToken]
+}
+
+func (r *fakePasswordResets) Create(ctx context.Context, userID uuid.UUID, tokenHash string, ttl time.Duration) error {
+	token := common.PasswordResetToken{ID: uuid.New(), UserID: userID, ExpiresAt: time.Now().Add(ttl)}
+	r.store.add(tokenHash, token.ID, token.ExpiresAt, token)
+	return nil
+}
+
+func (r *fakePasswordResets) Consume(ctx context.Context, tokenHash string) (*common.PasswordResetToken, error) {
+	return r.store.consume(tokenHash), nil
+}
+
+func (r *fakePasswordResets) Release(ctx context.Context, id uuid.UUID) error {
+	r.store.release(id)
+	return nil
+}
+
+// fakeEmailVerifications хранит токены подтверждения электронной почты
+type fakeEmailVerifications struct {
+	repository.EmailVerificationRepository
+	store fakeTokenStore[common.EmailVerificationToken]
+}
+
+func (r *fakeEmailVerifications) Create(
+	ctx context.Context,
+	userID uuid.UUID,
+	email string,
+	tokenHash string,
+	ttl time.Duration,
+) error {
+	token := common.EmailVerificationToken{ID: uuid.New(), UserID: userID, Email: email, ExpiresAt: time.Now().Add(ttl)}
+	r.store.add(tokenHash, token.ID, token.ExpiresAt, token)
+	return nil
+}
+
+func (r *fakeEmailVerifications) Consume(ctx context.Context, tokenHash string) (*common.EmailVerificationToken, error) {
+	return r.store.consume(tokenHash), nil
+}
+
+func (r *fakeEmailVerifications) Release(ctx context.Context, id uuid.UUID) error {
+	r.store.release(id)
+	return nil
+}
+
+// mailFixture пользователи, учетные записи Guacamole и локальный SMTP сервер
+// для тестов сервисов, отправляющих письма со ссылками
+type mailFixture struct {
+	users     *fakeUsers
+	guacamole *fakeGuacamole
+	sessions  *fakeAuthSessions
+	smtp      *mailertest.Server
+}
+
+func newMailFixture(t *testing.T, users ...*common.User) *mailFixture {
+	t.Helper()
+	fixture := &mailFixture{
+		users:     newFakeUsers(users...),
+		guacamole: newFakeGuacamole(),
+		sessions:  &fakeAuthSessions{},
+		smtp:      mailertest.NewServer("", ""),
+	}
+	t.Cleanup(fixture.smtp.Close)
+	for _, user := range users {
+		fixture.guacamole.passwords[user.Email] = common.GuacamolePassword{HashHex: "old", SaltHex: "old"}
+	}
+	return fixture
+}
+
+// mailer возвращает отправителя писем на локальный SMTP сервер
+func (f *mailFixture) mailer() mailer.Mailer {
+	return mailer.NewSMTPMailer(f.smtp.Config("noreply@rd.example.com"))
+}
+
+// mailToken возвращает токен из ссылки в последнем письме получателю
+func (f *mailFixture) mailToken(t *testing.T, email string, linkPattern *regexp.Regexp) string {
+	t.Helper()
+	mails := f.smtp.Mails()
+	if len(mails) == 0 {
+		t.Fatal("no mail was sent")
+	}
+	mail := mails[len(mails)-1]
+	if len(mail.To) != 1 || mail.To[0] != email {
+		t.Fatalf("mail recipients = %q, want %s", mail.To, email)
+	}
+	text, err := mail.Text()
+	if err != nil {
+		t.Fatalf("failed to read mail: %v", err)
+	}
+	link, err := url.Parse(linkPattern.FindString(text))
+	if err != nil || link.Query().Get("token") == "" {
+		t.Fatalf("mail has no link matching %s: %q", linkPattern, text)
+	}
+	return link.Query().Get("token")
+}
@@ -0,0 +1,112 @@
+// Package service реализует бизнес-логику приложения.
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/repository"
+)
+
+// RoleService назначает роли пользователям.
+//
+// Роль хранится в базе приложения и определяет разрешения приложения (middleware.RequirePermission),
+// а в базе Guacamole пользователю выдаются системные разрешения роли (common.RoleGuacamolePermissions).
+type RoleService struct {
+	users     repository.UserRepository
+	guacamole repository.GuacamoleRepository
+}
+
+// NewRoleService создает новый экземпляр RoleService.
+//
+// Параметры:
+//   - users: репозиторий пользователей
+//   - guacamole: репозиторий Guacamole
+//
+// Возвращает:
+//   - *RoleService: указатель на созданный сервис
+func NewRoleService(users repository.UserRepository, guacamole repository.GuacamoleRepository) *RoleService {
+	return &RoleService{
+		users:     users,
+		guacamole: guacamole,
+	}
+}
+
+// Assign назначает пользователю роль.
+// Изменение затрагивает две базы данных и выполняется как сага:
+//  1. замена системных разрешений пользователя Guacamole разрешениями роли;
+//  2. сохранение роли в базе приложения.
+//
+// Если второй шаг завершается ошибкой, пользователю Guacamole возвращаются разрешения прежней роли.
+// Системные разрешения Guacamole заменяются, даже если роль не меняется, поэтому
+// повторное назначение той же роли исправляет разрешения, измененные в обход приложения.
+//
+// Параметры:
+//   - ctx: контекст
+//   - user: пользователь
+//   - role: новая роль
+//
+// Возвращает:
+//   - error: ErrUnsupported для неизвестной роли, ErrNotFound, если пользователь Guacamole
+//     не найден, или ошибка изменения данных
+func (service *RoleService) Assign(ctx context.Context, user *common.User, role string) error {
+	if !common.IsValidRole(role) {
+		return fmt.Errorf("%w: unknown role %q", ErrUnsupported, role)
+	}
+	previous := user.Role
+
+	err := newSaga("assign role").
+		step(
+			"update guacamole permissions",
+			func(ctx context.Context) error {
+				err := service.guacamole.SetSystemPermissions(ctx, user.Email, common.RoleGuacamolePermissions(role))
+				if errors.Is(err, sql.ErrNoRows) {
+					return fmt.Errorf("%w: guacamole account of %s", ErrNotFound, user.Email)
+				}
+				return err
+			},
+			func(ctx context.Context) error {
+				return service.guacamole.SetSystemPermissions(ctx, user.Email, common.RoleGuacamolePermissions(previous))
+			},
+		).
+		step(
+			"update role",
+			func(ctx context.Context) error {
+				return service.users.SetRole(ctx, user.ID, role)
+			},
+			nil,
+		).
+		run(ctx)
+	if err != nil {
+		return err
+	}
+	if previous != role {
+		slog.Info(fmt.Sprintf("Role of %s changed from %s to %s", user.Email, previous, role))
+	}
+	user.Role = role
+	return nil
+}
+
+// AssignByEmail назначает роль пользователю с указанной электронной почтой.
+//
+// Параметры:
+//   - ctx: контекст
+//   - email: электронная почта пользователя
+//   - role: новая роль
+//
+// Возвращает:
+//   - error: ErrNotFound, если пользователь не найден, или ошибки Assign
+func (service *RoleService) AssignByEmail(ctx context.Context, email string, role string) error {
+	user, err := service.users.FindByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: user %s", ErrNotFound, email)
+	}
+	if err != nil {
+		return err
+	}
+	return service.Assign(ctx, user, role)
+}
@@ -0,0 +1,73 @@
+// Package service реализует бизнес-логику приложения.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// sagaStep описывает шаг саги: действие и компенсацию, отменяющую его результат.
+// Компенсация может отсутствовать у последнего шага или у шагов без побочных эффектов.
+type sagaStep struct {
+	name       string
+	action     func(ctx context.Context) error
+	compensate func(ctx context.Context) error
+}
+
+// saga выполняет последовательность шагов, затрагивающих разные хранилища
+// (например, базу приложения и базу Guacamole), которые нельзя объединить в одну транзакцию.
+// Если шаг завершается ошибкой, компенсации уже выполненных шагов вызываются в обратном порядке.
+type saga struct {
+	name  string
+	steps []sagaStep
+}
+
+// newSaga создает пустую сагу с именем для журналирования
+func newSaga(name string) *saga {
+	return &saga{name: name}
+}
+
+// step добавляет шаг в сагу и возвращает ее для цепочки вызовов
+func (s *saga) step(name string, action func(ctx context.Context) error, compensate func(ctx context.Context) error) *saga {
+	s.steps = append(s.steps, sagaStep{
+		name:       name,
+		action:     action,
+		compensate: compensate,
+	})
+	return s
+}
+
+// run выполняет шаги саги.
+// Компенсации выполняются с контекстом без отмены, чтобы прерванный клиентом запрос
+// не оставлял частично созданные данные.
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//
+// Возвращает:
+//   - error: ошибка шага, объединенная с ошибками компенсаций (если они были)
+func (s *saga) run(ctx context.Context) error {
+	for i, step := range s.steps {
+		err := step.action(ctx)
+		if err == nil {
+			continue
+		}
+		err = fmt.Errorf("%s: step %q failed: %w", s.name, step.name, err)
+
+		compensateCtx := context.WithoutCancel(ctx)
+		for j := i - 1; j >= 0; j-- {
+			done := s.steps[j]
+			if done.compensate == nil {
+				continue
+			}
+			if compensateErr := done.compensate(compensateCtx); compensateErr != nil {
+				slog.Error(fmt.Sprintf("%s: compensation of step %q failed: %s", s.name, done.name, compensateErr.Error()))
+				err = errors.Join(err, fmt.Errorf("compensation of step %q failed: %w", done.name, compensateErr))
+			}
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestSagaRun(t *testing.T) {
+	errStep := errors.New("step failed")
+	errCompensation := errors.New("compensation failed")
+
+	tests := []struct {
+		name string
+		// failAt номер шага, действие которого завершается ошибкой (-1 — без ошибок)
+		failAt int
+		// failCompensation номер шага, компенсация которого завершается ошибкой (-1 — без ошибок)
+		failCompensation int
+		// withoutCompensation номер шага без компенсации (-1 — у всех шагов есть компенсация)
+		withoutCompensation int
+		wantCalls           []string
+		wantErrs            []error
+	}{
+		{
+			name:                "all steps succeed",
+			failAt:              -1,
+			failCompensation:    -1,
+			withoutCompensation: -1,
+			wantCalls:           []string{"do 0", "do 1", "do 2"},
+		},
+		{
+			name:                "first step fails",
+			failAt:              0,
+			failCompensation:    -1,
+			withoutCompensation: -1,
+			wantCalls:           []string{"do 0"},
+			wantErrs:            []error{errStep},
+		},
+		{
+			name:                "last step fails",
+			failAt:              2,
+			failCompensation:    -1,
+			withoutCompensation: -1,
+			wantCalls:           []string{"do 0", "do 1", "do 2", "undo 1", "undo 0"},
+			wantErrs:            []error{errStep},
+		},
+		{
+			name:                "step without compensation is skipped",
+			failAt:              2,
+			failCompensation:    -1,
+			withoutCompensation: 1,
+			wantCalls:           []string{"do 0", "do 1", "do 2", "undo 0"},
+			wantErrs:            []error{errStep},
+		},
+		{
+			name:                "failed compensation does not stop the others",
+			failAt:              2,
+			failCompensation:    1,
+			withoutCompensation: -1,
+			wantCalls:           []string{"do 0", "do 1", "do 2", "undo 1", "undo 0"},
+			wantErrs:            []error{errStep, errCompensation},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := make([]string, 0)
+			s := newSaga("test")
+			for i := 0; i < 3; i++ {
+				action := func(ctx context.Context) error {
+					calls = append(calls, "do "+strconv.Itoa(i))
+					if i == tt.failAt {
+						return errStep
+					}
+					return nil
+				}
+				compensate := func(ctx context.Context) error {
+					calls = append(calls, "undo "+strconv.Itoa(i))
+					if i == tt.failCompensation {
+						return errCompensation
+					}
+					return nil
+				}
+				if i == tt.withoutCompensation {
+					compensate = nil
+				}
+				s.step("step "+strconv.Itoa(i), action, compensate)
+			}
+
+			err := s.run(context.Background())
+			if !reflect.DeepEqual(calls, tt.wantCalls) {
+				t.Errorf("calls = %q, want %q", calls, tt.wantCalls)
+			}
+			if len(tt.wantErrs) == 0 && err != nil {
+				t.Errorf("run() error = %v, want nil", err)
+			}
+			for _, want := range tt.wantErrs {
+				if !errors.Is(err, want) {
+					t.Errorf("run() error = %v, want %v", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSagaRunCompensatesCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var compensateErr error
+	err := newSaga("test").
+		step("create", func(ctx context.Context) error { return nil }, func(ctx context.Context) error {
+			compensateErr = ctx.Err()
+			return nil
+		}).
+		step("cancel", func(ctx context.Context) error {
+			cancel()
+			return ctx.Err()
+		}, nil).
+		run(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("run() error = %v, want context.Canceled", err)
+	}
+	if compensateErr != nil {
+		t.Errorf("compensation context error = %v, want nil", compensateErr)
+	}
+}
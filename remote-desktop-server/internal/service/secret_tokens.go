@@ -0,0 +1,30 @@
+// Package service реализует бизнес-логику приложения.
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// secretTokenSize размер случайных одноразовых токенов (ссылки совместного доступа,
+// сброс пароля, refresh токены) в байтах
+const secretTokenSize = 32
+
+// newSecretToken генерирует случайный токен, безопасный для использования в URL.
+// Пользователю передается сам токен, в базе хранится только его хэш (hashSecretToken).
+func newSecretToken() (string, error) {
+	buf := make([]byte, secretTokenSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashSecretToken возвращает SHA-256 хэш токена в шестнадцатеричном виде
+func hashSecretToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -3,60 +3,101 @@ package service
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"path"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
 	"github.com/margar-melkonyan/remote-desktop.git/internal/config"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/repository"
 )
 
-// Протоколы подключений, используемые для фильтрации
+// Протоколы подключений, используемые для фильтрации.
+// Конкретные протоколы описаны в common (common.ProtocolSSH и т.д.)
 const (
 	all = "all" // Все доступные протоколы подключений
-	ssh = "ssh" // SSH протокол подключения
-	rdp = "rdp" // RDP протокол подключения
 )
 
 // Пути API Guacamole
 const (
-	indexURL       = "session/data/postgresql/connectionGroups/ROOT/tree" // Путь для получения дерева подключений
-	connectionsURL = "session/data/postgresql/connections"                // Базовый путь для работы с подключениями
+	indexURL            = "session/data/postgresql/connectionGroups/ROOT/tree" // Путь для получения дерева подключений
+	connectionsURL      = "session/data/postgresql/connections"                // Базовый путь для работы с подключениями
+	connectionGroupsURL = "session/data/postgresql/connectionGroups"           // Базовый путь для работы с группами подключений
+	permissionsURL      = "session/data/postgresql/self/effectivePermissions"  // Путь для получения разрешений текущего пользователя
 )
 
+// GuacamoleTokenRenewer выдает действующий токен Guacamole взамен токена, отклоненного Guacamole
+type GuacamoleTokenRenewer interface {
+	// ValidGuacamoleToken возвращает тот же токен, если он еще действует, или новый токен той же сессии
+	ValidGuacamoleToken(guacToken string) (string, error)
+}
+
 // SessionService предоставляет методы для работы с подключениями к удаленным серверам через Guacamole API.
 type SessionService struct {
-	client http.Client // HTTP клиент для выполнения запросов
+	client   http.Client                          // HTTP клиент для выполнения запросов
+	policies repository.RecordingPolicyRepository // Сроки хранения записей подключений
+	tokens   GuacamoleTokenRenewer                // Продление токенов Guacamole сессий входа
+
+	mu           sync.Mutex // Защищает serviceToken
+	serviceToken string     // Кэшированный токен служебной учетной записи Guacamole
+}
+
+// GuacamoleAPIError описывает неуспешный ответ API Guacamole.
+type GuacamoleAPIError struct {
+	StatusCode int    // HTTP статус ответа
+	Body       string // Тело ответа
+}
+
+// Error реализует интерфейс error
+func (e *GuacamoleAPIError) Error() string {
+	return fmt.Sprintf("API request failed with status %d: %s", e.StatusCode, e.Body)
+}
+
+// isGuacamoleAuthError проверяет, вызвана ли ошибка недействительным или просроченным токеном Guacamole
+func isGuacamoleAuthError(err error) bool {
+	var apiErr *GuacamoleAPIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden
 }
 
 // NewSessionService создает и возвращает новый экземпляр SessionService.
 // Инициализирует HTTP клиент с таймаутом 10 секунд.
 //
+// Параметры:
+//   - policies: репозиторий сроков хранения записей подключений
+//   - tokens: продление токенов Guacamole, когда Guacamole отклоняет токен сессии входа
+//
 // Возвращает:
 //   - *SessionService: указатель на созданный сервис
-func NewSessionService() *SessionService {
+func NewSessionService(policies repository.RecordingPolicyRepository, tokens GuacamoleTokenRenewer) *SessionService {
 	return &SessionService{
 		client: http.Client{
 			Timeout: 10 * time.Second,
 		},
+		policies: policies,
+		tokens:   tokens,
 	}
 }
 
-// fetchConnections получает список всех доступных подключений из Guacamole.
+// fetchConnections получает дерево всех доступных подключений из Guacamole.
 //
 // Параметры:
 //   - guacToken: токен аутентификации Guacamole
 //
 // Возвращает:
-//   - []*common.GuacamoleRDConnectionResponse: список подключений
+//   - *common.GuacamoleConnectionGroup: корневая группа с вложенными группами и подключениями
 //   - error: ошибка, если не удалось получить данные
-func (service *SessionService) fetchConnections(guacToken string) ([]*common.GuacamoleRDConnectionResponse, error) {
-	var response struct {
-		ChildConnections []*common.GuacamoleRDConnectionResponse `json:"childConnections"`
-	}
+func (service *SessionService) fetchConnections(guacToken string) (*common.GuacamoleConnectionGroup, error) {
+	var response common.GuacamoleConnectionGroup
 
 	if err := service.makeGuacamoleRequest(
 		http.MethodGet,
@@ -68,36 +109,53 @@ func (service *SessionService) fetchConnections(guacToken string) ([]*common.Gua
 		return nil, fmt.Errorf("failed to fetch connections: %w", err)
 	}
 
-	return response.ChildConnections, nil
+	return &response, nil
 }
 
-// GetSession возвращает список подключений, отфильтрованных по протоколу.
+// GetSession возвращает полное дерево групп и подключений, отфильтрованных по протоколу.
+// Группы возвращаются всегда, даже если в них не осталось подключений выбранного протокола.
 //
 // Параметры:
-//   - protocol: протокол для фильтрации (all, ssh, rdp)
+//   - protocol: протокол для фильтрации (all, ssh, rdp, vnc, telnet, kubernetes)
 //   - guacToken: токен аутентификации Guacamole
 //
 // Возвращает:
-//   - []*common.GuacamoleRDConnectionResponse: список подключений
+//   - *common.GuacamoleConnectionGroup: корневая группа подключений
 //   - error: ошибка, если не удалось получить данные
-func (service *SessionService) GetSession(protocol string, guacToken string) ([]*common.GuacamoleRDConnectionResponse, error) {
-	connections, err := service.fetchConnections(guacToken)
+func (service *SessionService) GetSession(protocol string, guacToken string) (*common.GuacamoleConnectionGroup, error) {
+	if protocol != all && !common.IsSupportedProtocol(protocol) {
+		return nil, fmt.Errorf("unsupported protocol: %s", protocol)
+	}
+
+	tree, err := service.fetchConnections(guacToken)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get session: %w", err)
 	}
 
-	if protocol == all {
-		return connections, nil
+	if protocol != all {
+		filterConnectionTree(tree, protocol)
 	}
 
-	result := make([]*common.GuacamoleRDConnectionResponse, 0, len(connections))
-	for _, conn := range connections {
-		if conn.Protocol == string(protocol) {
+	return tree, nil
+}
+
+// filterConnectionTree рекурсивно оставляет в дереве только подключения указанного протокола.
+//
+// Параметры:
+//   - group: группа подключений
+//   - protocol: протокол для фильтрации
+func filterConnectionTree(group *common.GuacamoleConnectionGroup, protocol string) {
+	result := make([]*common.GuacamoleRDConnectionResponse, 0, len(group.ChildConnections))
+	for _, conn := range group.ChildConnections {
+		if conn.Protocol == protocol {
 			result = append(result, conn)
 		}
 	}
+	group.ChildConnections = result
 
-	return result, nil
+	for _, child := range group.ChildConnectionGroups {
+		filterConnectionTree(child, protocol)
+	}
 }
 
 // EditConnection получает полную информацию о подключении по его ID.
@@ -110,41 +168,73 @@ func (service *SessionService) GetSession(protocol string, guacToken string) ([]
 //   - *common.GuacamoleConnectionRequest: данные подключения
 //   - error: ошибка, если не удалось получить данные
 func (service *SessionService) EditConnection(id string, guacToken string) (*common.GuacamoleConnectionRequest, error) {
-	var params common.Parameters
+	connection, err := service.getConnection(id, guacToken)
+	if err != nil {
+		return nil, err
+	}
+
+	form, err := connectionFormFromParameters(connection, connection.Parameters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read connection parameters: %w", err)
+	}
+	if form.Recording != nil {
+		policy, err := service.policies.Find(context.Background(), id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read recording policy: %w", err)
+		}
+		if policy != nil {
+			form.Recording.RetentionDays = policy.RetentionDays
+		}
+	}
+	return form, nil
+}
+
+// getConnection получает описание подключения вместе с его параметрами в формате API Guacamole.
+//
+// Параметры:
+//   - id: идентификатор подключения
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - *common.GuacamoleRDConnectionRequest: подключение с параметрами и атрибутами
+//   - error: ошибка, если не удалось получить данные
+func (service *SessionService) getConnection(id string, guacToken string) (*common.GuacamoleRDConnectionRequest, error) {
+	path := fmt.Sprintf("%s/%s", connectionsURL, id)
+
+	var connection common.GuacamoleRDConnectionRequest
 	if err := service.makeGuacamoleRequest(
 		http.MethodGet,
-		fmt.Sprintf("%s/%s/parameters", connectionsURL, id),
+		path,
 		guacToken,
 		nil,
-		&params,
+		&connection,
 	); err != nil {
-		return nil, fmt.Errorf("failed to get connection parameters: %w", err)
+		return nil, fmt.Errorf("failed to get connection info: %w", err)
 	}
 
-	var connectionInfo common.GuacamoleRDConnectionRequest
+	params := make(map[string]string)
 	if err := service.makeGuacamoleRequest(
 		http.MethodGet,
-		fmt.Sprintf("%s/%s", connectionsURL, id),
+		fmt.Sprintf("%s/parameters", path),
 		guacToken,
 		nil,
-		&connectionInfo,
+		&params,
 	); err != nil {
-		return nil, fmt.Errorf("failed to get connection info: %w", err)
+		return nil, fmt.Errorf("failed to get connection parameters: %w", err)
 	}
 
-	connectionInfo.Parameters = params
-	return &common.GuacamoleConnectionRequest{
-		Id:       connectionInfo.Id,
-		Name:     connectionInfo.Name,
-		HostName: connectionInfo.Parameters.HostName,
-		Username: connectionInfo.Parameters.Username,
-		Password: connectionInfo.Parameters.Password,
-		Port:     connectionInfo.Port,
-		Protocol: connectionInfo.Protocol,
-	}, nil
+	connection.Id = id
+	connection.Parameters = params
+	if connection.Attributes == nil {
+		connection.Attributes = map[string]string{}
+	}
+	return &connection, nil
 }
 
 // CreateConnection создает новое подключение в Guacamole.
+// Каталог записи зависит от идентификатора подключения, поэтому параметры записи
+// сохраняются отдельным запросом после создания. Если сохранить их не удалось,
+// созданное подключение удаляется.
 //
 // Параметры:
 //   - form: данные для создания подключения
@@ -153,34 +243,32 @@ func (service *SessionService) EditConnection(id string, guacToken string) (*com
 // Возвращает:
 //   - error: ошибка, если не удалось создать подключение
 func (service *SessionService) CreateConnection(form *common.GuacamoleConnectionRequest, guacToken string) error {
-	ignoreCert := "false"
-	if form.Protocol == rdp {
-		ignoreCert = "true"
-	}
-
-	requestBody := common.GuacamoleRDConnectionRequest{
-		Name:             form.Name,
-		Protocol:         form.Protocol,
-		ParentIdentifier: "ROOT",
-		Parameters: common.Parameters{
-			HostName:   form.HostName,
-			Username:   form.Username,
-			Password:   form.Password,
-			IgnoreCert: ignoreCert,
-			Port:       form.Port,
-		},
-	}
-
+	var created common.GuacamoleRDConnectionResponse
 	if err := service.makeGuacamoleRequest(
 		http.MethodPost,
 		connectionsURL,
 		guacToken,
-		requestBody,
-		nil,
+		connectionRequestFromForm("", form),
+		&created,
 	); err != nil {
 		return fmt.Errorf("failed to create connection: %w", err)
 	}
+	if form.Recording == nil {
+		return nil
+	}
 
+	err := service.modifyConnection(created.ID, guacToken, func(connection *common.GuacamoleRDConnectionRequest) {
+		recordingParameters(created.ID, form.Protocol, form.Recording, connection.Parameters)
+	})
+	if err == nil {
+		err = service.saveRecordingPolicy(created.ID, form.Recording)
+	}
+	if err != nil {
+		if destroyErr := service.DestroyConnection(created.ID, guacToken); destroyErr != nil {
+			err = errors.Join(err, destroyErr)
+		}
+		return fmt.Errorf("failed to configure connection recording: %w", err)
+	}
 	return nil
 }
 
@@ -198,38 +286,77 @@ func (service *SessionService) UpdateConnection(
 	form *common.GuacamoleConnectionRequest,
 	guacToken string,
 ) error {
-	ignoreCert := "false"
-	if form.Protocol == rdp {
-		ignoreCert = "true"
-	}
-
 	path := fmt.Sprintf("%s/%s", connectionsURL, id)
 
 	if err := service.makeGuacamoleRequest(
 		http.MethodPut,
 		path,
 		guacToken,
-		common.GuacamoleRDConnectionRequest{
-			Id:               id,
-			Name:             form.Name,
-			Protocol:         form.Protocol,
-			ParentIdentifier: "ROOT",
-			Parameters: common.Parameters{
-				HostName:   form.HostName,
-				Username:   form.Username,
-				Password:   form.Password,
-				IgnoreCert: ignoreCert,
-				Port:       form.Port,
-			},
-		},
+		connectionRequestFromForm(id, form),
 		nil,
 	); err != nil {
 		return fmt.Errorf("failed to update connection: %w", err)
 	}
+	if form.Recording != nil {
+		if err := service.saveRecordingPolicy(id, form.Recording); err != nil {
+			return fmt.Errorf("failed to save recording policy: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// MoveConnection перемещает подключение в другую группу.
+//
+// Параметры:
+//   - id: идентификатор подключения
+//   - parentIdentifier: идентификатор новой родительской группы
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - error: ошибка, если не удалось переместить подключение
+func (service *SessionService) MoveConnection(id string, parentIdentifier string, guacToken string) error {
+	if err := service.modifyConnection(id, guacToken, func(connection *common.GuacamoleRDConnectionRequest) {
+		connection.ParentIdentifier = parentIdentifier
+	}); err != nil {
+		return fmt.Errorf("failed to move connection: %w", err)
+	}
 
 	return nil
 }
 
+// modifyConnection получает текущее описание подключения вместе с параметрами,
+// применяет к нему изменения и сохраняет его в Guacamole.
+// API Guacamole требует полного описания подключения при обновлении,
+// поэтому текущие параметры запрашиваются и отправляются повторно.
+//
+// Параметры:
+//   - id: идентификатор подключения
+//   - guacToken: токен аутентификации Guacamole
+//   - modify: функция, изменяющая описание подключения
+//
+// Возвращает:
+//   - error: ошибка, если не удалось получить или сохранить подключение
+func (service *SessionService) modifyConnection(
+	id string,
+	guacToken string,
+	modify func(connection *common.GuacamoleRDConnectionRequest),
+) error {
+	connection, err := service.getConnection(id, guacToken)
+	if err != nil {
+		return err
+	}
+	modify(connection)
+
+	return service.makeGuacamoleRequest(
+		http.MethodPut,
+		fmt.Sprintf("%s/%s", connectionsURL, id),
+		guacToken,
+		connection,
+		nil,
+	)
+}
+
 // DestroyConnection удаляет подключение из Guacamole.
 //
 // Параметры:
@@ -250,12 +377,369 @@ func (service *SessionService) DestroyConnection(id string, guacToken string) er
 	); err != nil {
 		return fmt.Errorf("failed to destroy connection: %w", err)
 	}
+	// Оставшиеся записи удаляются очисткой по сроку хранения по умолчанию
+	if err := service.policies.Delete(context.Background(), id); err != nil {
+		return fmt.Errorf("failed to remove recording policy: %w", err)
+	}
 
 	return nil
 }
 
+// saveRecordingPolicy сохраняет срок хранения записей подключения.
+// Нулевой срок удаляет политику, и к записям применяется срок по умолчанию.
+//
+// Параметры:
+//   - id: идентификатор подключения
+//   - settings: настройки записи из формы
+//
+// Возвращает:
+//   - error: ошибка, если не удалось сохранить политику
+func (service *SessionService) saveRecordingPolicy(id string, settings *common.RecordingSettings) error {
+	if settings.RetentionDays == 0 {
+		return service.policies.Delete(context.Background(), id)
+	}
+	return service.policies.Save(context.Background(), common.RecordingPolicy{
+		ConnectionID:  id,
+		RetentionDays: settings.RetentionDays,
+	})
+}
+
+// isAdministrator проверяет, есть ли у владельца токена системное разрешение ADMINISTER в Guacamole.
+//
+// Параметры:
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - bool: является ли пользователь администратором
+//   - error: ошибка, если не удалось получить разрешения
+func (service *SessionService) isAdministrator(guacToken string) (bool, error) {
+	permissions, err := service.effectivePermissions(guacToken)
+	if err != nil {
+		return false, err
+	}
+	return hasPermission(permissions.SystemPermissions, "ADMINISTER"), nil
+}
+
+// effectivePermissions возвращает действующие разрешения владельца токена,
+// включая унаследованные от групп.
+//
+// Параметры:
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - *common.GuacamolePermissions: системные разрешения и разрешения на подключения
+//   - error: ошибка, если не удалось получить данные
+func (service *SessionService) effectivePermissions(guacToken string) (*common.GuacamolePermissions, error) {
+	var permissions common.GuacamolePermissions
+	if err := service.makeGuacamoleRequest(
+		http.MethodGet,
+		permissionsURL,
+		guacToken,
+		nil,
+		&permissions,
+	); err != nil {
+		return nil, fmt.Errorf("failed to get permissions: %w", err)
+	}
+	return &permissions, nil
+}
+
+// hasPermission проверяет наличие разрешения в списке
+func hasPermission(permissions []string, permission string) bool {
+	for _, p := range permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// withServiceToken выполняет действие от имени служебной учетной записи Guacamole.
+// Если Guacamole отклоняет кэшированный токен, токен запрашивается заново и действие повторяется.
+//
+// Параметры:
+//   - action: действие, получающее токен служебной учетной записи
+//
+// Возвращает:
+//   - error: ошибка получения токена или выполнения действия
+func (service *SessionService) withServiceToken(action func(guacToken string) error) error {
+	token, err := service.getServiceToken(false)
+	if err != nil {
+		return err
+	}
+	err = action(token)
+	if !isGuacamoleAuthError(err) {
+		return err
+	}
+	token, err = service.getServiceToken(true)
+	if err != nil {
+		return err
+	}
+	return action(token)
+}
+
+// getServiceToken возвращает токен служебной учетной записи Guacamole.
+//
+// Параметры:
+//   - refresh: запросить новый токен, даже если есть кэшированный
+//
+// Возвращает:
+//   - string: токен Guacamole
+//   - error: ошибка, если учетная запись не настроена или аутентификация не удалась
+func (service *SessionService) getServiceToken(refresh bool) (string, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	if service.serviceToken != "" && !refresh {
+		return service.serviceToken, nil
+	}
+	account := config.ServerConfig.GuacamoleServiceAccount
+	if account.Username == "" {
+		return "", errors.New("guacamole service account is not configured")
+	}
+	token, err := getGuacamoleToken(common.AuthSignInRequest{
+		Email:    account.Username,
+		Password: account.Password,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to authenticate guacamole service account: %w", err)
+	}
+	service.serviceToken = token
+	return token, nil
+}
+
+// connectionRequestFromForm формирует запрос к API Guacamole из данных формы.
+// Дополнительные параметры формы дополняются общими параметрами (хост, порт, учетные данные)
+// и типизированными параметрами протокола, которые имеют приоритет.
+// Если заданы настройки записи, параметры записи формируются только из них.
+//
+// Параметры:
+//   - id: идентификатор подключения (пустой при создании)
+//   - form: данные формы подключения
+//
+// Возвращает:
+//   - common.GuacamoleRDConnectionRequest: тело запроса к API Guacamole
+func connectionRequestFromForm(id string, form *common.GuacamoleConnectionRequest) common.GuacamoleRDConnectionRequest {
+	params := make(map[string]string, len(form.Parameters))
+	for name, value := range form.Parameters {
+		if common.IsAllowedParameter(form.Protocol, name) {
+			params[name] = value
+		}
+	}
+
+	ignoreCert := "false"
+	if form.Protocol == common.ProtocolRDP {
+		ignoreCert = "true"
+	}
+	if value, ok := params["ignore-cert"]; ok {
+		ignoreCert = value
+	}
+	encodeGuacamoleParameters(&common.Parameters{
+		HostName:   form.HostName,
+		Username:   form.Username,
+		Password:   form.Password,
+		IgnoreCert: ignoreCert,
+		Port:       form.Port,
+	}, params)
+	encodeGuacamoleParameters(protocolParameters(form), params)
+	if drivePath, ok := params["drive-path"]; ok {
+		params["drive-path"] = driveParameter(drivePath)
+	}
+	if form.Recording != nil {
+		recordingParameters(id, form.Protocol, form.Recording, params)
+	}
+
+	parentIdentifier := form.ParentIdentifier
+	if parentIdentifier == "" {
+		parentIdentifier = common.RootConnectionGroup
+	}
+
+	attributes := make(map[string]string)
+	encodeGuacamoleParameters(form.Attributes, attributes)
+
+	return common.GuacamoleRDConnectionRequest{
+		Id:               id,
+		Name:             form.Name,
+		Protocol:         form.Protocol,
+		ParentIdentifier: parentIdentifier,
+		Parameters:       params,
+		Attributes:       attributes,
+	}
+}
+
+// connectionFormFromParameters формирует данные формы из подключения и его параметров Guacamole.
+// Параметры, не покрытые типизированными полями, возвращаются в Parameters.
+//
+// Параметры:
+//   - info: данные подключения из API Guacamole
+//   - params: параметры подключения из API Guacamole
+//
+// Возвращает:
+//   - *common.GuacamoleConnectionRequest: данные формы подключения
+//   - error: ошибка, если параметры не удалось разобрать
+func connectionFormFromParameters(
+	info *common.GuacamoleRDConnectionRequest,
+	params map[string]string,
+) (*common.GuacamoleConnectionRequest, error) {
+	var base common.Parameters
+	if err := decodeGuacamoleParameters(params, &base); err != nil {
+		return nil, err
+	}
+	form := &common.GuacamoleConnectionRequest{
+		Id:               info.Id,
+		Name:             info.Name,
+		HostName:         base.HostName,
+		Username:         base.Username,
+		Password:         base.Password,
+		Port:             base.Port,
+		Protocol:         info.Protocol,
+		ParentIdentifier: info.ParentIdentifier,
+	}
+
+	if len(info.Attributes) > 0 {
+		form.Attributes = &common.ConnectionAttributes{}
+		if err := decodeGuacamoleParameters(info.Attributes, form.Attributes); err != nil {
+			return nil, err
+		}
+	}
+
+	typed := protocolParameters(form)
+	if typed != nil {
+		if err := decodeGuacamoleParameters(params, typed); err != nil {
+			return nil, err
+		}
+	}
+
+	recordingPath := params["recording-path"]
+	if recordingPath != "" {
+		form.Recording = &common.RecordingSettings{
+			Enabled:      true,
+			NameTemplate: params["recording-name"],
+			IncludeKeys:  params["recording-include-keys"] == "true",
+			Typescript:   params["typescript-path"] != "",
+		}
+	}
+
+	// ignore-cert задается общими параметрами по умолчанию, но может быть переопределен пользователем
+	known := guacamoleParameterNames(&base)
+	delete(known, "ignore-cert")
+	for name := range guacamoleParameterNames(typed) {
+		known[name] = struct{}{}
+	}
+	for name, value := range params {
+		if _, ok := known[name]; ok || !common.IsAllowedParameter(form.Protocol, name) {
+			continue
+		}
+		if form.Recording != nil && common.IsRecordingParameter(name) {
+			continue
+		}
+		if form.Parameters == nil {
+			form.Parameters = make(map[string]string)
+		}
+		form.Parameters[name] = value
+	}
+	return form, nil
+}
+
+// recordingParameters заменяет параметры записи подключения параметрами из настроек записи.
+// Записи каждого подключения хранятся в каталоге <RECORDING_PATH>/<id>; typescript сохраняется
+// в тот же каталог с суффиксом .typescript. Пока идентификатор неизвестен (при создании),
+// параметры записи только удаляются.
+//
+// Параметры:
+//   - id: идентификатор подключения
+//   - protocol: протокол подключения
+//   - settings: настройки записи
+//   - params: параметры подключения (изменяются на месте)
+func recordingParameters(id string, protocol string, settings *common.RecordingSettings, params map[string]string) {
+	for name := range params {
+		if common.IsRecordingParameter(name) {
+			delete(params, name)
+		}
+	}
+	if !settings.Enabled || id == "" {
+		return
+	}
+
+	name := settings.NameTemplate
+	if name == "" {
+		name = common.DefaultRecordingName
+	}
+	dir := path.Join(config.ServerConfig.RecordingConfig.Path, id)
+
+	params["recording-path"] = dir
+	params["recording-name"] = name
+	params["create-recording-path"] = "true"
+	if settings.IncludeKeys {
+		params["recording-include-keys"] = "true"
+	}
+	if settings.Typescript && common.SupportsTypescript(protocol) {
+		params["typescript-path"] = dir
+		params["typescript-name"] = name + common.TypescriptSuffix
+		params["create-typescript-path"] = "true"
+	}
+}
+
+// driveParameter возвращает каталог виртуального диска на сервере guacd внутри GUAC_DRIVE_ROOT.
+// Путь внутри корня сохраняется, любой другой путь (в том числе с "..") считается
+// относительным корню, поэтому диск нельзя подключить к произвольному каталогу guacd.
+//
+// Параметры:
+//   - drivePath: путь из формы подключения
+//
+// Возвращает:
+//   - string: путь к каталогу диска внутри GUAC_DRIVE_ROOT
+func driveParameter(drivePath string) string {
+	root := path.Clean(config.ServerConfig.GuacamoleDriveRoot)
+	drivePath = path.Clean("/" + drivePath)
+	if drivePath == root || strings.HasPrefix(drivePath, strings.TrimSuffix(root, "/")+"/") {
+		return drivePath
+	}
+	return path.Join(root, drivePath)
+}
+
+// protocolParameters возвращает типизированные параметры протокола формы.
+// Если параметры не заданы, создает пустую структуру нужного типа.
+//
+// Параметры:
+//   - form: данные формы подключения
+//
+// Возвращает:
+//   - interface{}: указатель на структуру параметров или nil для неизвестного протокола
+func protocolParameters(form *common.GuacamoleConnectionRequest) interface{} {
+	switch form.Protocol {
+	case common.ProtocolRDP:
+		if form.RDP == nil {
+			form.RDP = &common.RDPParameters{}
+		}
+		return form.RDP
+	case common.ProtocolSSH:
+		if form.SSH == nil {
+			form.SSH = &common.SSHParameters{}
+		}
+		return form.SSH
+	case common.ProtocolVNC:
+		if form.VNC == nil {
+			form.VNC = &common.VNCParameters{}
+		}
+		return form.VNC
+	case common.ProtocolTelnet:
+		if form.Telnet == nil {
+			form.Telnet = &common.TelnetParameters{}
+		}
+		return form.Telnet
+	case common.ProtocolKubernetes:
+		if form.Kubernetes == nil {
+			form.Kubernetes = &common.KubernetesParameters{}
+		}
+		return form.Kubernetes
+	}
+	return nil
+}
+
 // makeGuacamoleRequest выполняет HTTP запрос к API Guacamole.
 // Внутренний метод, используемый другими методами сервиса.
+// Если Guacamole отклоняет токен (401/403), токен проверяется и при необходимости продлевается,
+// после чего запрос повторяется один раз с новым токеном.
 //
 // Параметры:
 //   - method: HTTP метод (GET, POST, PUT, DELETE)
@@ -273,14 +757,48 @@ func (service *SessionService) makeGuacamoleRequest(
 	requestBody interface{},
 	responseTarget interface{},
 ) error {
-	url := fmt.Sprintf("%s/%s", config.ServerConfig.GuacamoleAPIURL, path)
-
-	var body io.Reader
+	var jsonData []byte
 	if requestBody != nil {
-		jsonData, err := json.Marshal(requestBody)
+		var err error
+		jsonData, err = json.Marshal(requestBody)
 		if err != nil {
 			return fmt.Errorf("failed to marshal request body: %w", err)
 		}
+	}
+
+	err := service.doGuacamoleRequest(method, path, guacToken, jsonData, responseTarget)
+	if !isGuacamoleAuthError(err) || service.tokens == nil {
+		return err
+	}
+	renewed, renewErr := service.tokens.ValidGuacamoleToken(strings.TrimSpace(guacToken))
+	if renewErr != nil || renewed == strings.TrimSpace(guacToken) {
+		return err
+	}
+	return service.doGuacamoleRequest(method, path, renewed, jsonData, responseTarget)
+}
+
+// doGuacamoleRequest выполняет один HTTP запрос к API Guacamole.
+//
+// Параметры:
+//   - method: HTTP метод
+//   - path: путь API
+//   - guacToken: токен аутентификации
+//   - jsonData: JSON тело запроса (nil — без тела)
+//   - responseTarget: указатель на структуру для разбора ответа (может быть nil)
+//
+// Возвращает:
+//   - error: ошибка запроса или *GuacamoleAPIError при неуспешном ответе
+func (service *SessionService) doGuacamoleRequest(
+	method string,
+	path string,
+	guacToken string,
+	jsonData []byte,
+	responseTarget interface{},
+) error {
+	url := fmt.Sprintf("%s/%s", config.ServerConfig.GuacamoleAPIURL, path)
+
+	var body io.Reader
+	if jsonData != nil {
 		body = bytes.NewReader(jsonData)
 	}
 
@@ -302,7 +820,7 @@ func (service *SessionService) makeGuacamoleRequest(
 
 	if resp.StatusCode >= 400 {
 		errorBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(errorBody))
+		return &GuacamoleAPIError{StatusCode: resp.StatusCode, Body: string(errorBody)}
 	}
 
 	if responseTarget != nil {
@@ -0,0 +1,182 @@
+// Package service реализует бизнес-логику приложения.
+package service
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/config"
+)
+
+// tunnelDialTimeout ограничивает установку соединения и рукопожатие WebSocket с Guacamole
+const tunnelDialTimeout = 10 * time.Second
+
+// tunnelHeaders перечисляет заголовки рукопожатия WebSocket, передаваемые в Guacamole
+var tunnelHeaders = []string{
+	"Upgrade",
+	"Connection",
+	"Sec-WebSocket-Key",
+	"Sec-WebSocket-Version",
+	"Sec-WebSocket-Protocol",
+	"Sec-WebSocket-Extensions",
+	"User-Agent",
+}
+
+// tunnelConn — соединение с Guacamole, читающее сначала данные,
+// уже прочитанные в буфер при разборе ответа на рукопожатие
+type tunnelConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// Read реализует io.Reader
+func (c *tunnelConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+// OpenTunnel открывает WebSocket туннель Guacamole к подключению.
+// Токен Guacamole подставляется на сервере, поэтому браузер его не получает;
+// просроченный токен перед подключением заменяется новым.
+//
+// Параметры:
+//   - id: идентификатор подключения
+//   - guacToken: токен Guacamole сессии входа
+//   - query: параметры клиента Guacamole (GUAC_WIDTH, GUAC_HEIGHT, GUAC_AUDIO и т.д.)
+//   - header: заголовки запроса браузера на переход к WebSocket
+//
+// Возвращает:
+//   - net.Conn: соединение с Guacamole после успешного рукопожатия
+//   - http.Header: заголовки ответа Guacamole на рукопожатие
+//   - error: ошибка соединения или *GuacamoleAPIError, если Guacamole отклонил рукопожатие
+func (service *SessionService) OpenTunnel(
+	id string,
+	guacToken string,
+	query url.Values,
+	header http.Header,
+) (net.Conn, http.Header, error) {
+	if service.tokens != nil {
+		var err error
+		guacToken, err = service.tokens.ValidGuacamoleToken(guacToken)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	params := tunnelParameters(query)
+	params.Set("GUAC_DATA_SOURCE", "postgresql")
+	params.Set("GUAC_ID", id)
+	params.Set("GUAC_TYPE", "c")
+	return openGuacamoleTunnel(guacToken, params, header)
+}
+
+// openGuacamoleTunnel выполняет рукопожатие WebSocket с туннелем Guacamole.
+//
+// Параметры:
+//   - guacToken: токен Guacamole, от имени которого открывается туннель
+//   - params: параметры туннеля (источник данных, подключение и параметры клиента)
+//   - header: заголовки запроса браузера на переход к WebSocket
+//
+// Возвращает:
+//   - net.Conn: соединение с Guacamole после успешного рукопожатия
+//   - http.Header: заголовки ответа Guacamole на рукопожатие
+//   - error: ошибка соединения или *GuacamoleAPIError, если Guacamole отклонил рукопожатие
+func openGuacamoleTunnel(guacToken string, params url.Values, header http.Header) (net.Conn, http.Header, error) {
+	tunnelURL, err := url.Parse(strings.TrimSuffix(config.ServerConfig.GuacamoleAPIURL, "/api") + "/websocket-tunnel")
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid guacamole url: %w", err)
+	}
+	params.Set("token", guacToken)
+	tunnelURL.RawQuery = params.Encode()
+
+	conn, err := dialTunnel(tunnelURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn.SetDeadline(time.Now().Add(tunnelDialTimeout))
+
+	req, err := http.NewRequest(http.MethodGet, tunnelURL.String(), nil)
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for _, name := range tunnelHeaders {
+		for _, value := range header.Values(name) {
+			req.Header.Add(name, value)
+		}
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("request failed: %w", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("request failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		conn.Close()
+		return nil, nil, &GuacamoleAPIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	conn.SetDeadline(time.Time{})
+
+	return &tunnelConn{Conn: conn, reader: reader}, resp.Header, nil
+}
+
+// dialTunnel устанавливает TCP (или TLS для https) соединение с сервером Guacamole
+func dialTunnel(tunnelURL *url.URL) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: tunnelDialTimeout}
+	port := tunnelURL.Port()
+	switch {
+	case port != "":
+	case tunnelURL.Scheme == "https":
+		port = "443"
+	default:
+		port = "80"
+	}
+	address := net.JoinHostPort(tunnelURL.Hostname(), port)
+
+	if tunnelURL.Scheme == "https" {
+		conn, err := tls.DialWithDialer(dialer, "tcp", address, &tls.Config{ServerName: tunnelURL.Hostname()})
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to guacamole: %w", err)
+		}
+		return conn, nil
+	}
+	conn, err := dialer.Dial("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to guacamole: %w", err)
+	}
+	return conn, nil
+}
+
+// tunnelParameters возвращает параметры клиента Guacamole (GUAC_*) из строки запроса браузера
+func tunnelParameters(query url.Values) url.Values {
+	params := url.Values{}
+	for key, values := range query {
+		if isTunnelParameter(key) {
+			params[key] = append([]string(nil), values...)
+		}
+	}
+	return params
+}
+
+// isTunnelParameter проверяет, может ли клиент передать параметр в туннель Guacamole.
+// Токен, источник данных и подключение задает сервер.
+func isTunnelParameter(name string) bool {
+	switch name {
+	case "GUAC_DATA_SOURCE", "GUAC_ID", "GUAC_TYPE":
+		return false
+	}
+	return strings.HasPrefix(name, "GUAC_")
+}
@@ -0,0 +1,758 @@
+// Package service реализует бизнес-логику приложения.
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/config"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/repository"
+)
+
+// Пути API Guacamole для совместного доступа
+const (
+	sharingProfilesURL = "session/data/postgresql/sharingProfiles" // Базовый путь для работы с профилями совместного доступа
+	tunnelsURL         = "session/tunnels"                         // Путь для получения туннелей текущей сессии
+)
+
+// readOnlyParameter параметр профиля совместного доступа, запрещающий ввод
+const readOnlyParameter = "read-only"
+
+// shareLinkCheckInterval период проверки, не отозвана ли ссылка, по которой открыт туннель
+// (ссылку мог отозвать другой экземпляр сервера)
+const shareLinkCheckInterval = 30 * time.Second
+
+// SharingService предоставляет методы для управления профилями совместного доступа
+// Guacamole и ссылками на активные сессии с ограниченным сроком действия.
+//
+// Ключ совместного доступа Guacamole не покидает сервер: он хранится зашифрованным,
+// а зритель подключается к сессии через WebSocket туннель сервера по токену ссылки (Join).
+// Guacamole не знает о сроке действия, лимите переходов и отзыве ссылки, поэтому туннели,
+// открытые по ссылке, закрываются сервером, когда ссылка истекает или отзывается.
+type SharingService struct {
+	sessions *SessionService
+	links    repository.ShareLinkRepository
+
+	mu            sync.Mutex                            // Защищает tunnels
+	tunnels       map[string]map[*sharedTunnel]struct{} // Открытые туннели по идентификатору ссылки
+	checkInterval time.Duration                         // Период проверки отзыва ссылок открытых туннелей
+}
+
+// sharedTunnel туннель зрителя, открытый по ссылке совместного доступа.
+// Закрытие туннеля завершает и сессию Guacamole, полученную по ключу совместного доступа.
+type sharedTunnel struct {
+	net.Conn
+	once    sync.Once
+	closed  chan struct{}
+	onClose func()
+}
+
+// Close закрывает туннель; повторные вызовы ничего не делают
+func (t *sharedTunnel) Close() error {
+	err := net.ErrClosed
+	t.once.Do(func() {
+		err = t.Conn.Close()
+		close(t.closed)
+		t.onClose()
+	})
+	return err
+}
+
+// NewSharingService создает и возвращает новый экземпляр SharingService.
+//
+// Параметры:
+//   - sessions: сервис подключений, через который выполняются запросы к Guacamole
+//   - links: репозиторий ссылок совместного доступа
+//
+// Возвращает:
+//   - *SharingService: указатель на созданный сервис
+func NewSharingService(sessions *SessionService, links repository.ShareLinkRepository) *SharingService {
+	return &SharingService{
+		sessions: sessions,
+		links:    links,
+		tunnels:  make(map[string]map[*sharedTunnel]struct{}),
+
+		checkInterval: shareLinkCheckInterval,
+	}
+}
+
+// GetSharingProfiles возвращает профили совместного доступа подключения.
+//
+// Параметры:
+//   - id: идентификатор подключения
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - []*common.SharingProfile: профили, отсортированные по названию
+//   - error: ErrNotFound, если подключение недоступно
+func (service *SharingService) GetSharingProfiles(id string, guacToken string) ([]*common.SharingProfile, error) {
+	if err := service.authorize(id, guacToken); err != nil {
+		return nil, err
+	}
+
+	var profiles map[string]*common.GuacamoleSharingProfile
+	if err := service.sessions.makeGuacamoleRequest(
+		http.MethodGet,
+		fmt.Sprintf("%s/%s/sharingProfiles", connectionsURL, id),
+		guacToken,
+		nil,
+		&profiles,
+	); err != nil {
+		return nil, fmt.Errorf("failed to fetch sharing profiles: %w", err)
+	}
+
+	result := make([]*common.SharingProfile, 0, len(profiles))
+	for pid, profile := range profiles {
+		params, err := service.profileParameters(pid, guacToken)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &common.SharingProfile{
+			ID:           pid,
+			Name:         profile.Name,
+			ConnectionID: id,
+			ReadOnly:     params[readOnlyParameter] == "true",
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// CreateSharingProfile создает профиль совместного доступа к подключению.
+//
+// Параметры:
+//   - id: идентификатор подключения
+//   - form: данные профиля
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - *common.SharingProfile: созданный профиль
+//   - error: ErrNotFound, если подключение недоступно
+func (service *SharingService) CreateSharingProfile(
+	id string,
+	form *common.SharingProfileRequest,
+	guacToken string,
+) (*common.SharingProfile, error) {
+	if err := service.authorize(id, guacToken); err != nil {
+		return nil, err
+	}
+
+	params := map[string]string{}
+	if form.ReadOnly {
+		params[readOnlyParameter] = "true"
+	}
+	request := common.GuacamoleSharingProfile{
+		Name:                        form.Name,
+		PrimaryConnectionIdentifier: id,
+		Parameters:                  params,
+		Attributes:                  map[string]string{},
+	}
+	var created common.GuacamoleSharingProfile
+	if err := service.sessions.makeGuacamoleRequest(
+		http.MethodPost,
+		sharingProfilesURL,
+		guacToken,
+		request,
+		&created,
+	); err != nil {
+		return nil, fmt.Errorf("failed to create sharing profile: %w", err)
+	}
+
+	return &common.SharingProfile{
+		ID:           created.Identifier,
+		Name:         form.Name,
+		ConnectionID: id,
+		ReadOnly:     form.ReadOnly,
+	}, nil
+}
+
+// DestroySharingProfile удаляет профиль совместного доступа подключения.
+// Ссылки, созданные по профилю, перестают работать вместе с ним.
+//
+// Параметры:
+//   - id: идентификатор подключения
+//   - profileID: идентификатор профиля
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - error: ErrNotFound, если профиль не принадлежит подключению или недоступен
+func (service *SharingService) DestroySharingProfile(id string, profileID string, guacToken string) error {
+	if err := service.profileOf(id, profileID, guacToken); err != nil {
+		return err
+	}
+	if err := service.sessions.makeGuacamoleRequest(
+		http.MethodDelete,
+		fmt.Sprintf("%s/%s", sharingProfilesURL, profileID),
+		guacToken,
+		nil,
+		nil,
+	); err != nil {
+		return fmt.Errorf("failed to delete sharing profile: %w", err)
+	}
+	return nil
+}
+
+// GetShareLinks возвращает ссылки совместного доступа подключения.
+// Администратор Guacamole видит все ссылки, остальные пользователи — только свои.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор подключения
+//   - username: имя пользователя Guacamole (email) вызывающего
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - []*common.ShareLink: ссылки от новых к старым
+//   - error: ErrNotFound, если подключение недоступно
+func (service *SharingService) GetShareLinks(
+	ctx context.Context,
+	id string,
+	username string,
+	guacToken string,
+) ([]*common.ShareLink, error) {
+	if err := service.authorize(id, guacToken); err != nil {
+		return nil, err
+	}
+	isAdmin, err := service.sessions.isAdministrator(guacToken)
+	if err != nil {
+		return nil, err
+	}
+	createdBy := username
+	if isAdmin {
+		createdBy = ""
+	}
+	return service.links.FindByConnection(ctx, id, createdBy)
+}
+
+// CreateShareLink создает ссылку совместного доступа к активной сессии вызывающего.
+// Ключ совместного доступа Guacamole действует, пока открыта сессия, для которой он получен,
+// поэтому у вызывающего должно быть активное подключение к указанному подключению.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор подключения
+//   - form: параметры ссылки
+//   - username: имя пользователя Guacamole (email) вызывающего
+//   - guacToken: токен аутентификации Guacamole, которым открыта сессия
+//
+// Возвращает:
+//   - *common.ShareLink: сохраненная ссылка
+//   - string: токен ссылки (возвращается только один раз, в базе хранится его хэш)
+//   - error: ErrNotFound, если подключение или профиль недоступны; ErrUnsupported, если нет активной сессии
+func (service *SharingService) CreateShareLink(
+	ctx context.Context,
+	id string,
+	form *common.ShareLinkRequest,
+	username string,
+	guacToken string,
+) (*common.ShareLink, string, error) {
+	if err := service.profileOf(id, form.SharingProfileID, guacToken); err != nil {
+		return nil, "", err
+	}
+	tunnel, err := service.findTunnel(id, guacToken)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var credentials common.GuacamoleSharingCredentials
+	if err := service.sessions.makeGuacamoleRequest(
+		http.MethodGet,
+		fmt.Sprintf("%s/%s/activeConnection/sharingCredentials/%s", tunnelsURL, tunnel, form.SharingProfileID),
+		guacToken,
+		nil,
+		&credentials,
+	); err != nil {
+		return nil, "", fmt.Errorf("failed to get sharing credentials: %w", err)
+	}
+	shareKey := credentials.Values["key"]
+	if shareKey == "" {
+		return nil, "", errors.New("guacamole returned empty sharing key")
+	}
+	sealed, err := sealSecret([]byte(shareKey))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encrypt sharing key: %w", err)
+	}
+
+	token, err := newSecretToken()
+	if err != nil {
+		return nil, "", err
+	}
+	ttl := config.ServerConfig.SharingConfig.LinkTTL
+	if form.ExpiresInMinutes > 0 {
+		ttl = time.Duration(form.ExpiresInMinutes) * time.Minute
+	}
+	link := &common.ShareLink{
+		ConnectionID:     id,
+		SharingProfileID: form.SharingProfileID,
+		CreatedBy:        username,
+		Note:             form.Note,
+		ExpiresAt:        time.Now().Add(ttl),
+		TokenHash:        hashSecretToken(token),
+		ShareCredentials: sealed,
+	}
+	if form.MaxUses > 0 {
+		maxUses := form.MaxUses
+		link.MaxUses = &maxUses
+	}
+	if err := service.links.Create(ctx, link); err != nil {
+		return nil, "", fmt.Errorf("failed to save share link: %w", err)
+	}
+	return link, token, nil
+}
+
+// RevokeShareLink отзывает ссылку совместного доступа и закрывает туннели, открытые по ней.
+// Отозвать ссылку может ее создатель или администратор Guacamole.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор подключения
+//   - linkID: идентификатор ссылки
+//   - username: имя пользователя Guacamole (email) вызывающего
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - error: ErrNotFound, если ссылка не найдена; ErrForbidden, если ссылка чужая
+func (service *SharingService) RevokeShareLink(
+	ctx context.Context,
+	id string,
+	linkID string,
+	username string,
+	guacToken string,
+) error {
+	link, err := service.findLink(ctx, id, linkID, username, guacToken)
+	if err != nil {
+		return err
+	}
+	if err := service.links.Revoke(ctx, link.ID); err != nil {
+		return err
+	}
+	service.closeTunnels(link.ID)
+	return nil
+}
+
+// GetShareLinkUses возвращает журнал переходов по ссылке совместного доступа.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор подключения
+//   - linkID: идентификатор ссылки
+//   - username: имя пользователя Guacamole (email) вызывающего
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - []*common.ShareLinkUse: переходы от новых к старым
+//   - error: ErrNotFound, если ссылка не найдена; ErrForbidden, если ссылка чужая
+func (service *SharingService) GetShareLinkUses(
+	ctx context.Context,
+	id string,
+	linkID string,
+	username string,
+	guacToken string,
+) ([]*common.ShareLinkUse, error) {
+	link, err := service.findLink(ctx, id, linkID, username, guacToken)
+	if err != nil {
+		return nil, err
+	}
+	return service.links.FindUses(ctx, link.ID)
+}
+
+// Redeem проверяет ссылку совместного доступа и возвращает адрес страницы просмотра
+// с токеном ссылки во фрагменте (фрагмент не передается на сервер и не попадает в журналы).
+// Переход не учитывается: страница открывает туннель Join, который и учитывается.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - token: токен ссылки
+//
+// Возвращает:
+//   - string: адрес для перенаправления
+//   - error: ErrNotFound, если ссылка не существует, отозвана, истекла или исчерпана
+func (service *SharingService) Redeem(ctx context.Context, token string) (string, error) {
+	if token == "" {
+		return "", ErrNotFound
+	}
+	link, err := service.links.FindByToken(ctx, hashSecretToken(token))
+	if err != nil {
+		return "", err
+	}
+	if !shareLinkActive(link, time.Now()) {
+		return "", ErrNotFound
+	}
+	fragment := url.Values{"token": {token}}
+	return config.ServerConfig.SharingConfig.ViewerURL + "#" + fragment.Encode(), nil
+}
+
+// Join учитывает переход по ссылке совместного доступа и открывает туннель зрителя к сессии.
+// Сервер получает токен Guacamole по ключу совместного доступа сам, поэтому браузер не получает
+// ни ключ, ни токен и не может подключиться к сессии в обход ссылки. Туннель закрывается,
+// когда ссылка истекает или отзывается.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - token: токен ссылки
+//   - remoteAddr: адрес клиента
+//   - userAgent: User-Agent клиента
+//   - query: параметры клиента Guacamole (GUAC_WIDTH, GUAC_HEIGHT, GUAC_AUDIO и т.д.)
+//   - header: заголовки запроса браузера на переход к WebSocket
+//
+// Возвращает:
+//   - net.Conn: соединение с Guacamole после успешного рукопожатия
+//   - http.Header: заголовки ответа Guacamole на рукопожатие
+//   - error: ErrNotFound, если ссылка не существует, отозвана, истекла, исчерпана
+//     или сессия владельца уже завершена
+func (service *SharingService) Join(
+	ctx context.Context,
+	token string,
+	remoteAddr string,
+	userAgent string,
+	query url.Values,
+	header http.Header,
+) (net.Conn, http.Header, error) {
+	if token == "" {
+		return nil, nil, ErrNotFound
+	}
+	link, err := service.links.Use(ctx, hashSecretToken(token), remoteAddr, userAgent)
+	if err != nil {
+		return nil, nil, err
+	}
+	if link == nil {
+		return nil, nil, ErrNotFound
+	}
+	shareKey, err := openSecret(link.ShareCredentials)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decrypt sharing key: %w", err)
+	}
+
+	guacToken, dataSource, err := getGuacamoleShareToken(string(shareKey))
+	if err == nil {
+		var connectionID string
+		connectionID, err = service.sharedConnection(guacToken, dataSource)
+		if err == nil {
+			params := tunnelParameters(query)
+			params.Set("GUAC_DATA_SOURCE", dataSource)
+			params.Set("GUAC_ID", connectionID)
+			params.Set("GUAC_TYPE", "c")
+			var conn net.Conn
+			var responseHeader http.Header
+			conn, responseHeader, err = openGuacamoleTunnel(guacToken, params, header)
+			if err == nil {
+				return service.track(link, conn, guacToken), responseHeader, nil
+			}
+		}
+		service.endShareSession(guacToken)
+	}
+	var apiErr *GuacamoleAPIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode < 500 {
+		return nil, nil, ErrNotFound
+	}
+	return nil, nil, err
+}
+
+// track регистрирует туннель, открытый по ссылке, и закрывает его, когда ссылка истекает
+// или отзывается
+func (service *SharingService) track(link *common.ShareLink, conn net.Conn, guacToken string) *sharedTunnel {
+	tunnel := &sharedTunnel{Conn: conn, closed: make(chan struct{})}
+	tunnel.onClose = func() {
+		service.mu.Lock()
+		delete(service.tunnels[link.ID], tunnel)
+		if len(service.tunnels[link.ID]) == 0 {
+			delete(service.tunnels, link.ID)
+		}
+		service.mu.Unlock()
+		service.endShareSession(guacToken)
+	}
+
+	service.mu.Lock()
+	if service.tunnels[link.ID] == nil {
+		service.tunnels[link.ID] = make(map[*sharedTunnel]struct{})
+	}
+	service.tunnels[link.ID][tunnel] = struct{}{}
+	service.mu.Unlock()
+
+	go service.watch(link, tunnel)
+	return tunnel
+}
+
+// watch закрывает туннель, когда истекает срок действия ссылки или ссылка отозвана
+func (service *SharingService) watch(link *common.ShareLink, tunnel *sharedTunnel) {
+	expiry := time.NewTimer(time.Until(link.ExpiresAt))
+	defer expiry.Stop()
+	check := time.NewTicker(service.checkInterval)
+	defer check.Stop()
+	for {
+		select {
+		case <-tunnel.closed:
+			return
+		case <-expiry.C:
+			tunnel.Close()
+			return
+		case <-check.C:
+			current, err := service.links.FindByID(context.Background(), link.ID)
+			if err != nil {
+				slog.Warn(fmt.Sprintf("Error checking share link %s: %s", link.ID, err.Error()))
+				continue
+			}
+			if current == nil || current.RevokedAt != nil {
+				tunnel.Close()
+				return
+			}
+		}
+	}
+}
+
+// closeTunnels закрывает туннели, открытые по ссылке
+func (service *SharingService) closeTunnels(linkID string) {
+	service.mu.Lock()
+	tunnels := make([]*sharedTunnel, 0, len(service.tunnels[linkID]))
+	for tunnel := range service.tunnels[linkID] {
+		tunnels = append(tunnels, tunnel)
+	}
+	service.mu.Unlock()
+
+	for _, tunnel := range tunnels {
+		tunnel.Close()
+	}
+}
+
+// sharedConnection возвращает идентификатор подключения, доступного по ключу совместного доступа.
+//
+// Параметры:
+//   - guacToken: токен Guacamole, полученный по ключу совместного доступа
+//   - dataSource: источник данных Guacamole, в котором доступно общее подключение
+//
+// Возвращает:
+//   - string: идентификатор подключения
+//   - error: ошибка запроса или *GuacamoleAPIError, если общих подключений нет
+func (service *SharingService) sharedConnection(guacToken string, dataSource string) (string, error) {
+	var connections map[string]json.RawMessage
+	if err := service.sessions.doGuacamoleRequest(
+		http.MethodGet,
+		fmt.Sprintf("session/data/%s/connections", url.PathEscape(dataSource)),
+		guacToken,
+		nil,
+		&connections,
+	); err != nil {
+		return "", fmt.Errorf("failed to fetch shared connection: %w", err)
+	}
+	for id := range connections {
+		return id, nil
+	}
+	return "", &GuacamoleAPIError{StatusCode: http.StatusNotFound, Body: "no shared connection"}
+}
+
+// endShareSession завершает сессию Guacamole, полученную по ключу совместного доступа
+func (service *SharingService) endShareSession(guacToken string) {
+	if err := deleteGuacamoleToken(guacToken); err != nil {
+		slog.Warn(fmt.Sprintf("Error ending shared guacamole session: %s", err.Error()))
+	}
+}
+
+// getGuacamoleShareToken аутентифицируется в Guacamole ключом совместного доступа (POST /tokens)
+//
+// Параметры:
+//   - shareKey: ключ совместного доступа
+//
+// Возвращает:
+//   - string: токен Guacamole
+//   - string: источник данных, в котором доступно общее подключение
+//   - error: ошибка запроса или *GuacamoleAPIError, если ключ не принят (сессия владельца завершена)
+func getGuacamoleShareToken(shareKey string) (string, string, error) {
+	formData := url.Values{}
+	formData.Set("key", shareKey)
+	resp, err := http.Post(
+		fmt.Sprintf("%s/%s", config.ServerConfig.GuacamoleAPIURL, "tokens"),
+		"application/x-www-form-urlencoded",
+		strings.NewReader(formData.Encode()),
+	)
+	if err != nil {
+		return "", "", fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", "", &GuacamoleAPIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	var authResp struct {
+		AuthToken  string `json:"authToken"`
+		DataSource string `json:"dataSource"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&authResp); err != nil {
+		return "", "", fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+	return authResp.AuthToken, authResp.DataSource, nil
+}
+
+// shareLinkActive проверяет, действует ли ссылка: не отозвана, не истекла и лимит переходов не исчерпан
+func shareLinkActive(link *common.ShareLink, now time.Time) bool {
+	return link != nil &&
+		link.RevokedAt == nil &&
+		link.ExpiresAt.After(now) &&
+		(link.MaxUses == nil || link.Uses < *link.MaxUses)
+}
+
+// authorize проверяет, что подключение существует и доступно для изменения владельцу токена.
+//
+// Параметры:
+//   - id: идентификатор подключения
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - error: ErrNotFound, если подключение недоступно
+func (service *SharingService) authorize(id string, guacToken string) error {
+	if _, err := service.sessions.getConnection(id, guacToken); err != nil {
+		var apiErr *GuacamoleAPIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode < 500 {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// profileOf проверяет, что профиль совместного доступа относится к подключению.
+//
+// Параметры:
+//   - id: идентификатор подключения
+//   - profileID: идентификатор профиля
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - error: ErrNotFound, если профиль не найден или относится к другому подключению
+func (service *SharingService) profileOf(id string, profileID string, guacToken string) error {
+	if err := service.authorize(id, guacToken); err != nil {
+		return err
+	}
+	var profile common.GuacamoleSharingProfile
+	if err := service.sessions.makeGuacamoleRequest(
+		http.MethodGet,
+		fmt.Sprintf("%s/%s", sharingProfilesURL, profileID),
+		guacToken,
+		nil,
+		&profile,
+	); err != nil {
+		var apiErr *GuacamoleAPIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode < 500 {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to get sharing profile: %w", err)
+	}
+	if profile.PrimaryConnectionIdentifier != id {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// profileParameters возвращает параметры профиля совместного доступа.
+//
+// Параметры:
+//   - profileID: идентификатор профиля
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - map[string]string: параметры профиля
+//   - error: ошибка, если не удалось получить данные
+func (service *SharingService) profileParameters(profileID string, guacToken string) (map[string]string, error) {
+	params := make(map[string]string)
+	if err := service.sessions.makeGuacamoleRequest(
+		http.MethodGet,
+		fmt.Sprintf("%s/%s/parameters", sharingProfilesURL, profileID),
+		guacToken,
+		nil,
+		&params,
+	); err != nil {
+		return nil, fmt.Errorf("failed to get sharing profile parameters: %w", err)
+	}
+	return params, nil
+}
+
+// findTunnel ищет туннель сессии владельца токена, открытый к подключению.
+//
+// Параметры:
+//   - id: идентификатор подключения
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - string: идентификатор туннеля
+//   - error: ErrUnsupported, если активной сессии к подключению нет
+func (service *SharingService) findTunnel(id string, guacToken string) (string, error) {
+	var tunnels []string
+	if err := service.sessions.makeGuacamoleRequest(
+		http.MethodGet,
+		tunnelsURL,
+		guacToken,
+		nil,
+		&tunnels,
+	); err != nil {
+		return "", fmt.Errorf("failed to fetch tunnels: %w", err)
+	}
+	for _, tunnel := range tunnels {
+		var active common.GuacamoleActiveConnection
+		if err := service.sessions.makeGuacamoleRequest(
+			http.MethodGet,
+			fmt.Sprintf("%s/%s/activeConnection", tunnelsURL, tunnel),
+			guacToken,
+			nil,
+			&active,
+		); err != nil {
+			// Туннель мог закрыться между запросами
+			continue
+		}
+		if active.ConnectionIdentifier == id {
+			return tunnel, nil
+		}
+	}
+	return "", ErrUnsupported
+}
+
+// findLink ищет ссылку подключения и проверяет право вызывающего управлять ею.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор подключения
+//   - linkID: идентификатор ссылки
+//   - username: имя пользователя Guacamole (email) вызывающего
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - *common.ShareLink: найденная ссылка
+//   - error: ErrNotFound, если ссылка не найдена; ErrForbidden, если ссылка чужая
+func (service *SharingService) findLink(
+	ctx context.Context,
+	id string,
+	linkID string,
+	username string,
+	guacToken string,
+) (*common.ShareLink, error) {
+	link, err := service.links.FindByID(ctx, linkID)
+	if err != nil {
+		return nil, err
+	}
+	if link == nil || link.ConnectionID != id {
+		return nil, ErrNotFound
+	}
+	if link.CreatedBy == username {
+		return link, nil
+	}
+	isAdmin, err := service.sessions.isAdministrator(guacToken)
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin {
+		return nil, ErrForbidden
+	}
+	return link, nil
+}
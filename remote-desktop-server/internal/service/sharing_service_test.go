@@ -0,0 +1,322 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/config"
+)
+
+// fakeSharingGuacamole эмулирует Guacamole: сессию владельца с подключением 7 и профилем 3,
+// вход по ключу совместного доступа и WebSocket туннель
+type fakeSharingGuacamole struct {
+	mu       sync.Mutex
+	issued   int
+	deleted  []string
+	tunnels  []url.Values
+	upstream []net.Conn
+	// ownerActive false завершает сессию владельца: ключ совместного доступа перестает действовать
+	ownerActive bool
+}
+
+func newFakeSharingGuacamole(t *testing.T) *fakeSharingGuacamole {
+	t.Helper()
+	guac := &fakeSharingGuacamole{ownerActive: true}
+	mux := http.NewServeMux()
+	json := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, body)
+		}
+	}
+	mux.HandleFunc("GET /api/session/data/postgresql/connections/7", json(`{"name":"desktop"}`))
+	mux.HandleFunc("GET /api/session/data/postgresql/connections/7/parameters", json(`{}`))
+	mux.HandleFunc("GET /api/session/data/postgresql/sharingProfiles/3", json(`{"primaryConnectionIdentifier":"7"}`))
+	mux.HandleFunc("GET /api/session/tunnels", json(`["tunnel-1"]`))
+	mux.HandleFunc("GET /api/session/tunnels/tunnel-1/activeConnection", json(`{"connectionIdentifier":"7"}`))
+	mux.HandleFunc(
+		"GET /api/session/tunnels/tunnel-1/activeConnection/sharingCredentials/3",
+		json(`{"values":{"key":"share-key"}}`),
+	)
+	mux.HandleFunc("GET /api/session/data/postgresql-shared/connections", json(`{"share-key":{"name":"desktop"}}`))
+	mux.HandleFunc("POST /api/tokens", func(w http.ResponseWriter, r *http.Request) {
+		guac.mu.Lock()
+		defer guac.mu.Unlock()
+		if r.FormValue("key") != "share-key" || !guac.ownerActive {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		guac.issued++
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"authToken":"share-token-`+strconv.Itoa(guac.issued)+`","dataSource":"postgresql-shared"}`)
+	})
+	mux.HandleFunc("DELETE /api/tokens/{token}", func(w http.ResponseWriter, r *http.Request) {
+		guac.mu.Lock()
+		guac.deleted = append(guac.deleted, r.PathValue("token"))
+		guac.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /websocket-tunnel", func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		guac.mu.Lock()
+		guac.tunnels = append(guac.tunnels, r.URL.Query())
+		guac.upstream = append(guac.upstream, conn)
+		guac.mu.Unlock()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		rw.Flush()
+	})
+	server := httptest.NewServer(mux)
+
+	previous := config.ServerConfig.GuacamoleAPIURL
+	config.ServerConfig.GuacamoleAPIURL = server.URL + "/api"
+	t.Cleanup(func() {
+		guac.mu.Lock()
+		for _, conn := range guac.upstream {
+			conn.Close()
+		}
+		guac.mu.Unlock()
+		server.Close()
+		config.ServerConfig.GuacamoleAPIURL = previous
+	})
+	return guac
+}
+
+// deletedTokens возвращает токены, сессии которых завершены
+func (guac *fakeSharingGuacamole) deletedTokens() []string {
+	guac.mu.Lock()
+	defer guac.mu.Unlock()
+	return append([]string(nil), guac.deleted...)
+}
+
+// sharingFixture сервис совместного доступа, подключенный к эмулятору Guacamole
+type sharingFixture struct {
+	service *SharingService
+	links   *fakeShareLinks
+	guac    *fakeSharingGuacamole
+}
+
+func newSharingFixture(t *testing.T) *sharingFixture {
+	t.Helper()
+	links := newFakeShareLinks()
+	return &sharingFixture{
+		service: NewSharingService(NewSessionService(nil, nil), links),
+		links:   links,
+		guac:    newFakeSharingGuacamole(t),
+	}
+}
+
+// createLink создает ссылку на профиль 3 подключения 7
+func (f *sharingFixture) createLink(t *testing.T, maxUses int) (*common.ShareLink, string) {
+	t.Helper()
+	link, token, err := f.service.CreateShareLink(
+		context.Background(),
+		"7",
+		&common.ShareLinkRequest{SharingProfileID: "3", MaxUses: maxUses},
+		"owner@example.com",
+		"owner-token",
+	)
+	if err != nil {
+		t.Fatalf("CreateShareLink() error = %v", err)
+	}
+	return link, token
+}
+
+// join открывает туннель зрителя по ссылке
+func (f *sharingFixture) join(token string) (net.Conn, error) {
+	query := url.Values{"GUAC_WIDTH": {"1024"}, "GUAC_ID": {"1"}, "token": {"viewer-token"}}
+	conn, _, err := f.service.Join(context.Background(), token, "203.0.113.7:51000", "test", query, http.Header{})
+	return conn, err
+}
+
+// waitDeleted ожидает завершения сессии Guacamole закрытого туннеля
+func (guac *fakeSharingGuacamole) waitDeleted(t *testing.T, count int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(guac.deletedTokens()) < count {
+		if time.Now().After(deadline) {
+			t.Fatalf("deleted guacamole tokens = %v, want %d", guac.deletedTokens(), count)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// waitClosed ожидает закрытия туннеля сервером
+func waitClosed(t *testing.T, conn net.Conn) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err := bufio.NewReader(conn).ReadByte()
+	var netErr net.Error
+	if err == nil || errors.As(err, &netErr) && netErr.Timeout() {
+		t.Fatalf("tunnel is still open: read error = %v", err)
+	}
+}
+
+func TestSharingServiceLinkDoesNotExposeShareKey(t *testing.T) {
+	f := newSharingFixture(t)
+	link, token := f.createLink(t, 0)
+
+	stored, _ := f.links.FindByID(context.Background(), link.ID)
+	if strings.Contains(string(stored.ShareCredentials), "share-key") {
+		t.Fatal("share key is stored in plain text")
+	}
+	if key, err := openSecret(stored.ShareCredentials); err != nil || string(key) != "share-key" {
+		t.Fatalf("openSecret() = %q, %v; want share-key", key, err)
+	}
+
+	redirect, err := f.service.Redeem(context.Background(), token)
+	if err != nil {
+		t.Fatalf("Redeem() error = %v", err)
+	}
+	want := "https://rd.example.com/shared#token=" + url.QueryEscape(token)
+	if redirect != want {
+		t.Fatalf("Redeem() = %q, want %q", redirect, want)
+	}
+	if stored, _ := f.links.FindByID(context.Background(), link.ID); stored.Uses != 0 {
+		t.Fatalf("Redeem() counted a use: uses = %d", stored.Uses)
+	}
+}
+
+func TestSharingServiceJoin(t *testing.T) {
+	f := newSharingFixture(t)
+	link, token := f.createLink(t, 0)
+
+	conn, err := f.join(token)
+	if err != nil {
+		t.Fatalf("Join() error = %v", err)
+	}
+	f.guac.mu.Lock()
+	params := f.guac.tunnels[0]
+	f.guac.mu.Unlock()
+	want := map[string]string{
+		"token":            "share-token-1",
+		"GUAC_DATA_SOURCE": "postgresql-shared",
+		"GUAC_ID":          "share-key",
+		"GUAC_TYPE":        "c",
+		"GUAC_WIDTH":       "1024",
+	}
+	for name, value := range want {
+		if got := params[name]; len(got) != 1 || got[0] != value {
+			t.Errorf("tunnel parameter %s = %v, want %q", name, got, value)
+		}
+	}
+	if stored, _ := f.links.FindByID(context.Background(), link.ID); stored.Uses != 1 {
+		t.Fatalf("uses = %d, want 1", stored.Uses)
+	}
+
+	conn.Close()
+	if deleted := f.guac.deletedTokens(); len(deleted) != 1 || deleted[0] != "share-token-1" {
+		t.Fatalf("deleted guacamole tokens = %v, want [share-token-1]", deleted)
+	}
+}
+
+func TestSharingServiceRevoke(t *testing.T) {
+	f := newSharingFixture(t)
+	link, token := f.createLink(t, 0)
+	conn, err := f.join(token)
+	if err != nil {
+		t.Fatalf("Join() error = %v", err)
+	}
+
+	if err := f.service.RevokeShareLink(
+		context.Background(),
+		"7",
+		link.ID,
+		"owner@example.com",
+		"owner-token",
+	); err != nil {
+		t.Fatalf("RevokeShareLink() error = %v", err)
+	}
+	waitClosed(t, conn)
+	if deleted := f.guac.deletedTokens(); len(deleted) != 1 {
+		t.Fatalf("deleted guacamole tokens = %v, want the viewer session ended", deleted)
+	}
+
+	if _, err := f.service.Redeem(context.Background(), token); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Redeem() after revoke error = %v, want ErrNotFound", err)
+	}
+	if _, err := f.join(token); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Join() after revoke error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSharingServiceRevokedByAnotherInstance(t *testing.T) {
+	f := newSharingFixture(t)
+	f.service.checkInterval = 10 * time.Millisecond
+	link, token := f.createLink(t, 0)
+	conn, err := f.join(token)
+	if err != nil {
+		t.Fatalf("Join() error = %v", err)
+	}
+
+	// Ссылку отзывает другой экземпляр сервера: туннели этого экземпляра он закрыть не может
+	f.links.Revoke(context.Background(), link.ID)
+	waitClosed(t, conn)
+	f.guac.waitDeleted(t, 1)
+}
+
+func TestSharingServiceMaxUses(t *testing.T) {
+	f := newSharingFixture(t)
+	_, token := f.createLink(t, 1)
+
+	conn, err := f.join(token)
+	if err != nil {
+		t.Fatalf("first Join() error = %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := f.join(token); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("second Join() error = %v, want ErrNotFound", err)
+	}
+	if _, err := f.service.Redeem(context.Background(), token); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Redeem() of exhausted link error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSharingServiceExpiry(t *testing.T) {
+	f := newSharingFixture(t)
+	link, token := f.createLink(t, 0)
+	f.links.update(link.ID, func(link *common.ShareLink) {
+		link.ExpiresAt = time.Now().Add(200 * time.Millisecond)
+	})
+
+	conn, err := f.join(token)
+	if err != nil {
+		t.Fatalf("Join() error = %v", err)
+	}
+	waitClosed(t, conn)
+	f.guac.waitDeleted(t, 1)
+
+	if _, err := f.service.Redeem(context.Background(), token); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Redeem() of expired link error = %v, want ErrNotFound", err)
+	}
+	if _, err := f.join(token); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Join() of expired link error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSharingServiceOwnerSessionEnded(t *testing.T) {
+	f := newSharingFixture(t)
+	_, token := f.createLink(t, 0)
+	f.guac.mu.Lock()
+	f.guac.ownerActive = false
+	f.guac.mu.Unlock()
+
+	if _, err := f.join(token); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Join() error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,331 @@
+// Package service реализует бизнес-логику приложения.
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/config"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/repository"
+)
+
+// TokenService выдает, обновляет и отзывает токены сессий входа.
+//
+// Каждый вход создает сессию (семейство refresh токенов). Refresh токен одноразовый:
+// при обновлении он обменивается на новую пару токенов. Повторное предъявление уже
+// использованного токена означает, что токен украден, поэтому отзывается вся сессия.
+type TokenService struct {
+	sessions repository.AuthSessionRepository
+	users    repository.UserRepository
+}
+
+// NewTokenService создает новый экземпляр TokenService.
+//
+// Параметры:
+//   - sessions: репозиторий сессий входа и refresh токенов
+//   - users: репозиторий пользователей
+//
+// Возвращает:
+//   - *TokenService: указатель на созданный сервис
+func NewTokenService(sessions repository.AuthSessionRepository, users repository.UserRepository) *TokenService {
+	return &TokenService{
+		sessions: sessions,
+		users:    users,
+	}
+}
+
+// Issue создает сессию входа и выдает для нее токены.
+// Токен Guacamole сессии запрашивается с паролем пользователя и остается на сервере.
+// Если пароль Guacamole пользователя управляется сервером (вход через внешний провайдер),
+// используется он, а не переданный пароль.
+//
+// Параметры:
+//   - ctx: контекст
+//   - user: пользователь
+//   - password: пароль пользователя (совпадает с паролем Guacamole)
+//
+// Возвращает:
+//   - *common.AuthTokens: токен доступа и refresh токен
+//   - error: ошибка аутентификации в Guacamole, сохранения сессии или подписи токена
+func (service *TokenService) Issue(ctx context.Context, user *common.User, password string) (*common.AuthTokens, error) {
+	if len(user.GuacamoleCredentials) > 0 {
+		var err error
+		if password, err = openGuacamolePassword(user.GuacamoleCredentials); err != nil {
+			return nil, err
+		}
+	}
+	guacToken, err := getGuacamoleToken(common.AuthSignInRequest{
+		Email:    user.Email,
+		Password: password,
+	})
+	if err != nil {
+		return nil, err
+	}
+	credentials, err := sealGuacamolePassword(password)
+	if err != nil {
+		return nil, err
+	}
+	session := &common.AuthSession{
+		UserID:               user.ID,
+		TokenVersion:         user.TokenVersion,
+		GuacamoleToken:       guacToken,
+		GuacamoleCredentials: credentials,
+	}
+	if err := service.sessions.Create(ctx, session); err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := newSecretToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := service.sessions.CreateRefreshToken(
+		ctx,
+		session.ID,
+		hashSecretToken(refreshToken),
+		config.ServerConfig.JWTConfig.RefreshTokenTTL,
+	); err != nil {
+		return nil, err
+	}
+
+	accessToken, err := getToken(*user, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &common.AuthTokens{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// Refresh обменивает refresh токен на новую пару токенов той же сессии.
+//
+// Параметры:
+//   - ctx: контекст
+//   - refreshToken: refresh токен
+//
+// Возвращает:
+//   - *common.AuthTokens: новые токены
+//   - error: ErrInvalidToken, если токен не найден, истек, уже использован,
+//     сессия отозвана или пароль пользователя изменен после входа
+//
+// Особенности:
+//   - Повторное использование токена отзывает всю сессию
+func (service *TokenService) Refresh(ctx context.Context, refreshToken string) (*common.AuthTokens, error) {
+	token, session, err := service.find(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if session.RevokedAt != nil || token.Expired {
+		return nil, ErrInvalidToken
+	}
+	if token.Used {
+		slog.Warn(fmt.Sprintf("Refresh token reuse detected, revoking auth session %s", session.ID))
+		service.revoke(ctx, session)
+		return nil, ErrInvalidToken
+	}
+
+	user, err := service.users.FindByID(ctx, session.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		service.revoke(ctx, session)
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.TokenVersion != session.TokenVersion {
+		service.revoke(ctx, session)
+		return nil, ErrInvalidToken
+	}
+
+	nextToken, err := newSecretToken()
+	if err != nil {
+		return nil, err
+	}
+	rotated, err := service.sessions.RotateRefreshToken(
+		ctx,
+		token,
+		hashSecretToken(nextToken),
+		config.ServerConfig.JWTConfig.RefreshTokenTTL,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		slog.Warn(fmt.Sprintf("Concurrent refresh token reuse detected, revoking auth session %s", session.ID))
+		service.revoke(ctx, session)
+		return nil, ErrInvalidToken
+	}
+
+	accessToken, err := getToken(*user, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &common.AuthTokens{
+		Token:        accessToken,
+		RefreshToken: nextToken,
+	}, nil
+}
+
+// Logout отзывает сессию входа, к которой относится refresh токен, и ее токен Guacamole.
+//
+// Параметры:
+//   - ctx: контекст
+//   - refreshToken: любой refresh токен сессии (в том числе уже использованный)
+//
+// Возвращает:
+//   - error: ErrInvalidToken, если токен не найден, или ошибка отзыва сессии
+func (service *TokenService) Logout(ctx context.Context, refreshToken string) error {
+	_, session, err := service.find(ctx, refreshToken)
+	if err != nil {
+		return err
+	}
+	revoked, err := service.sessions.Revoke(ctx, session.ID)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		service.deleteGuacamoleToken(session)
+	}
+	return nil
+}
+
+// RevokeUser отзывает все сессии входа пользователя и их токены Guacamole.
+//
+// Параметры:
+//   - ctx: контекст
+//   - userID: идентификатор пользователя
+//
+// Возвращает:
+//   - error: ошибка отзыва сессий
+func (service *TokenService) RevokeUser(ctx context.Context, userID uuid.UUID) error {
+	sessions, err := service.sessions.RevokeByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		service.deleteGuacamoleToken(session)
+	}
+	return nil
+}
+
+// ValidGuacamoleToken возвращает действующий токен Guacamole взамен отклоненного Guacamole.
+// Используется SessionService, когда Guacamole отвечает 401/403: если токен еще действует,
+// ошибка вызвана недостатком прав и возвращается тот же токен; иначе сервер повторно
+// аутентифицируется в Guacamole с сохраненным в сессии входа паролем.
+//
+// Параметры:
+//   - guacToken: токен Guacamole, отклоненный Guacamole
+//
+// Возвращает:
+//   - string: действующий токен Guacamole (тот же или новый)
+//   - error: ErrInvalidToken, если токен не относится к действующей сессии входа,
+//     или ошибка повторной аутентификации
+func (service *TokenService) ValidGuacamoleToken(guacToken string) (string, error) {
+	ctx := context.Background()
+	session, err := service.sessions.FindByGuacamoleToken(ctx, guacToken)
+	if err != nil {
+		return "", err
+	}
+	if session == nil {
+		return "", ErrInvalidToken
+	}
+	if session.GuacamoleToken != guacToken {
+		// Токен уже заменен параллельным запросом
+		return session.GuacamoleToken, nil
+	}
+	valid, err := checkGuacamoleToken(guacToken)
+	if err != nil {
+		return "", err
+	}
+	if valid {
+		return guacToken, nil
+	}
+
+	user, err := service.users.FindByID(ctx, session.UserID)
+	if err != nil {
+		return "", err
+	}
+	password, err := openGuacamolePassword(session.GuacamoleCredentials)
+	if err != nil {
+		return "", err
+	}
+	newToken, err := getGuacamoleToken(common.AuthSignInRequest{
+		Email:    user.Email,
+		Password: password,
+	})
+	if err != nil {
+		return "", err
+	}
+	replaced, err := service.sessions.ReplaceGuacamoleToken(ctx, session.ID, guacToken, newToken)
+	if err != nil {
+		return "", err
+	}
+	if replaced {
+		return newToken, nil
+	}
+
+	// Токен заменен параллельным запросом: полученный токен больше не нужен
+	if err := deleteGuacamoleToken(newToken); err != nil {
+		slog.Warn(fmt.Sprintf("Error deleting unused guacamole token of auth session %s: %s", session.ID, err.Error()))
+	}
+	session, err = service.sessions.FindByID(ctx, session.ID)
+	if err != nil {
+		return "", err
+	}
+	if session == nil || session.RevokedAt != nil {
+		return "", ErrInvalidToken
+	}
+	return session.GuacamoleToken, nil
+}
+
+// find возвращает refresh токен и его сессию входа или ErrInvalidToken
+func (service *TokenService) find(
+	ctx context.Context,
+	refreshToken string,
+) (*common.RefreshToken, *common.AuthSession, error) {
+	token, err := service.sessions.FindRefreshToken(ctx, hashSecretToken(refreshToken))
+	if err != nil {
+		return nil, nil, err
+	}
+	if token == nil {
+		return nil, nil, ErrInvalidToken
+	}
+	session, err := service.sessions.FindByID(ctx, token.SessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if session == nil {
+		return nil, nil, ErrInvalidToken
+	}
+	return token, session, nil
+}
+
+// revoke отзывает сессию входа при обнаружении компрометации.
+// Ошибки только журналируются: клиент в любом случае получает ErrInvalidToken.
+func (service *TokenService) revoke(ctx context.Context, session *common.AuthSession) {
+	revoked, err := service.sessions.Revoke(ctx, session.ID)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Error revoking auth session %s: %s", session.ID, err.Error()))
+		return
+	}
+	if revoked {
+		service.deleteGuacamoleToken(session)
+	}
+}
+
+// deleteGuacamoleToken завершает сессию Guacamole, полученную при входе.
+// Токен мог уже истечь на стороне Guacamole, поэтому ошибки только журналируются.
+func (service *TokenService) deleteGuacamoleToken(session *common.AuthSession) {
+	if session.GuacamoleToken == "" {
+		return
+	}
+	if err := deleteGuacamoleToken(session.GuacamoleToken); err != nil {
+		slog.Warn(fmt.Sprintf("Error deleting guacamole token of auth session %s: %s", session.ID, err.Error()))
+	}
+}
@@ -0,0 +1,567 @@
+// Package service реализует бизнес-логику приложения.
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/config"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/repository"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/totp"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// recoveryCodeCount количество кодов восстановления, выдаваемых при подключении TOTP
+const recoveryCodeCount = 10
+
+// recoveryCodeEncoding кодирует коды восстановления строчными буквами и цифрами
+var recoveryCodeEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// TwoFactorService предоставляет двухфакторную аутентификацию по TOTP.
+// Вход с подключенным TOTP выполняется в два шага: после проверки пароля выдается
+// короткоживущий токен второго шага, а токены доступа — только после ввода кода TOTP
+// или одноразового кода восстановления. Для владельцев системных разрешений Guacamole
+// из TWO_FACTOR_REQUIRED_PERMISSIONS TOTP обязателен: без него вход завершается
+// только после подключения приложения-аутентификатора.
+type TwoFactorService struct {
+	repo       repository.TwoFactorRepository
+	users      repository.UserRepository
+	guacamole  repository.GuacamoleRepository
+	tokens     *TokenService
+	protection *LoginProtectionService
+}
+
+// NewTwoFactorService создает новый экземпляр TwoFactorService.
+//
+// Параметры:
+//   - repo: репозиторий секретов TOTP, кодов восстановления и незавершенных входов
+//   - users: репозиторий пользователей
+//   - guacamole: репозиторий базы Guacamole (системные разрешения пользователя)
+//   - tokens: сервис токенов сессий входа
+//   - protection: защита входа от подбора (учитывает неверные коды второго шага)
+//
+// Возвращает:
+//   - *TwoFactorService: указатель на созданный сервис
+func NewTwoFactorService(
+	repo repository.TwoFactorRepository,
+	users repository.UserRepository,
+	guacamole repository.GuacamoleRepository,
+	tokens *TokenService,
+	protection *LoginProtectionService,
+) *TwoFactorService {
+	return &TwoFactorService{
+		repo:       repo,
+		users:      users,
+		guacamole:  guacamole,
+		tokens:     tokens,
+		protection: protection,
+	}
+}
+
+// Status возвращает состояние двухфакторной аутентификации текущего пользователя.
+//
+// Параметры:
+//   - ctx: контекст
+//   - email: email текущего пользователя
+//
+// Возвращает:
+//   - *common.TwoFactorStatus: состояние
+//   - error: ошибка чтения данных
+func (service *TwoFactorService) Status(ctx context.Context, email string) (*common.TwoFactorStatus, error) {
+	user, err := service.users.FindByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := service.repo.FindTOTP(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	required, err := service.required(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	left, err := service.repo.CountRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &common.TwoFactorStatus{
+		Enabled:           secret != nil && secret.EnabledAt != nil,
+		Pending:           secret != nil && secret.EnabledAt == nil,
+		Required:          required,
+		RecoveryCodesLeft: left,
+	}, nil
+}
+
+// Enroll выдает новый секрет TOTP текущему пользователю.
+// Секрет начинает действовать после подтверждения кодом (Enable).
+//
+// Параметры:
+//   - ctx: контекст
+//   - email: email текущего пользователя
+//
+// Возвращает:
+//   - *common.TwoFactorEnrollment: секрет и URI для QR кода
+//   - error: ErrConflict, если TOTP уже подключен, или ошибка сохранения
+func (service *TwoFactorService) Enroll(ctx context.Context, email string) (*common.TwoFactorEnrollment, error) {
+	user, err := service.users.FindByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	return service.enroll(ctx, user)
+}
+
+// Enable подтверждает секрет TOTP текущего пользователя кодом из приложения.
+//
+// Параметры:
+//   - ctx: контекст
+//   - email: email текущего пользователя
+//   - form: код TOTP
+//
+// Возвращает:
+//   - *common.TwoFactorRecoveryCodes: коды восстановления (показываются один раз)
+//   - error: ErrNotFound, если секрет не выдавался; ErrConflict, если TOTP уже подключен;
+//     ErrInvalidCode, если код неверен
+func (service *TwoFactorService) Enable(
+	ctx context.Context,
+	email string,
+	form common.TwoFactorCodeRequest,
+) (*common.TwoFactorRecoveryCodes, error) {
+	user, err := service.users.FindByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := service.repo.FindTOTP(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, ErrNotFound
+	}
+	if secret.EnabledAt != nil {
+		return nil, ErrConflict
+	}
+	codes, err := service.enable(ctx, secret, form.Code)
+	if err != nil {
+		return nil, err
+	}
+	return &common.TwoFactorRecoveryCodes{RecoveryCodes: codes}, nil
+}
+
+// Disable отключает TOTP текущего пользователя.
+//
+// Параметры:
+//   - ctx: контекст
+//   - email: email текущего пользователя
+//   - form: текущий пароль и код TOTP или код восстановления
+//
+// Возвращает:
+//   - error: ErrForbidden, если TOTP обязателен для пользователя; ErrNotFound, если TOTP не подключен;
+//     ErrInvalidPassword или ErrInvalidCode при неверных данных
+func (service *TwoFactorService) Disable(ctx context.Context, email string, form common.TwoFactorDisableRequest) error {
+	user, err := service.users.FindByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	required, err := service.required(ctx, user)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrForbidden
+	}
+	secret, err := service.repo.FindTOTP(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if secret == nil {
+		return ErrNotFound
+	}
+	if err := bcrypt.CompareHashAndPassword(
+		[]byte(user.Password),
+		[]byte(strings.TrimSpace(form.CurrentPassword)),
+	); err != nil {
+		return ErrInvalidPassword
+	}
+	if secret.EnabledAt != nil {
+		ok, err := service.verifyCode(ctx, secret, form.Code)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if ok, err = service.repo.UseRecoveryCode(ctx, user.ID, hashRecoveryCode(form.Code)); err != nil {
+				return err
+			}
+		}
+		if !ok {
+			return ErrInvalidCode
+		}
+	}
+	return service.repo.DeleteTOTP(ctx, user.ID)
+}
+
+// RegenerateRecoveryCodes заменяет коды восстановления текущего пользователя.
+//
+// Параметры:
+//   - ctx: контекст
+//   - email: email текущего пользователя
+//   - form: код TOTP
+//
+// Возвращает:
+//   - *common.TwoFactorRecoveryCodes: новые коды (прежние перестают действовать)
+//   - error: ErrNotFound, если TOTP не подключен; ErrInvalidCode, если код неверен
+func (service *TwoFactorService) RegenerateRecoveryCodes(
+	ctx context.Context,
+	email string,
+	form common.TwoFactorCodeRequest,
+) (*common.TwoFactorRecoveryCodes, error) {
+	user, err := service.users.FindByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := service.repo.FindTOTP(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.EnabledAt == nil {
+		return nil, ErrNotFound
+	}
+	ok, err := service.verifyCode(ctx, secret, form.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := service.repo.ReplaceRecoveryCodes(ctx, user.ID, hashes); err != nil {
+		return nil, err
+	}
+	return &common.TwoFactorRecoveryCodes{RecoveryCodes: codes}, nil
+}
+
+// Begin начинает вход пользователя, прошедшего проверку пароля.
+//
+// Параметры:
+//   - ctx: контекст
+//   - user: пользователь
+//   - password: пароль (нужен для получения токена Guacamole после второго шага)
+//
+// Возвращает:
+//   - *common.AuthChallenge: токен второго шага или nil, если второй фактор не нужен
+//   - error: ошибка сохранения незавершенного входа
+func (service *TwoFactorService) Begin(
+	ctx context.Context,
+	user *common.User,
+	password string,
+) (*common.AuthChallenge, error) {
+	secret, err := service.repo.FindTOTP(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	enabled := secret != nil && secret.EnabledAt != nil
+	if !enabled {
+		required, err := service.required(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		if !required {
+			return nil, nil
+		}
+	}
+
+	token, err := newSecretToken()
+	if err != nil {
+		return nil, err
+	}
+	credentials, err := sealGuacamolePassword(password)
+	if err != nil {
+		return nil, err
+	}
+	expiresAt, err := service.repo.CreateChallenge(
+		ctx,
+		user.ID,
+		hashSecretToken(token),
+		credentials,
+		config.ServerConfig.TwoFactorConfig.ChallengeTTL,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &common.AuthChallenge{
+		ChallengeToken:     token,
+		EnrollmentRequired: !enabled,
+		ExpiresAt:          expiresAt,
+	}, nil
+}
+
+// EnrollChallenge выдает секрет TOTP во время входа, когда TOTP обязателен, но не подключен.
+//
+// Параметры:
+//   - ctx: контекст
+//   - form: токен второго шага
+//
+// Возвращает:
+//   - *common.TwoFactorEnrollment: секрет и URI для QR кода
+//   - error: ErrInvalidToken, если токен недействителен; ErrConflict, если TOTP уже подключен
+func (service *TwoFactorService) EnrollChallenge(
+	ctx context.Context,
+	form common.TwoFactorEnrollRequest,
+) (*common.TwoFactorEnrollment, error) {
+	challenge, user, err := service.findChallenge(ctx, form.ChallengeToken)
+	if err != nil {
+		return nil, err
+	}
+	enrollment, err := service.enroll(ctx, user)
+	if errors.Is(err, ErrConflict) {
+		service.addAttempt(ctx, challenge)
+	}
+	return enrollment, err
+}
+
+// Complete завершает вход кодом TOTP или кодом восстановления.
+// Если секрет TOTP был выдан во время входа (EnrollChallenge), код подтверждает его
+// и в ответе возвращаются коды восстановления. Неверные коды учитываются как неудачные попытки
+// входа в учетную запись и с адреса клиента (LoginProtectionService), а неудачи учетной записи
+// сбрасываются только после верного кода.
+//
+// Параметры:
+//   - ctx: контекст
+//   - form: токен второго шага и код
+//   - remoteAddr: адрес соединения клиента
+//   - forwardedFor: заголовок X-Forwarded-For (учитывается при LOGIN_TRUST_FORWARDED_FOR=true)
+//
+// Возвращает:
+//   - *common.AuthSignInResponse: токены доступа (и коды восстановления при подключении TOTP)
+//   - error: ErrInvalidToken, если токен недействителен или исчерпаны попытки;
+//     ErrInvalidCode, если код неверен;
+//     *LoginThrottledError (ErrTooManyAttempts), если код не проверялся из-за задержки или блокировки
+func (service *TwoFactorService) Complete(
+	ctx context.Context,
+	form common.TwoFactorChallengeRequest,
+	remoteAddr string,
+	forwardedFor string,
+) (*common.AuthSignInResponse, error) {
+	challenge, user, err := service.findChallenge(ctx, form.ChallengeToken)
+	if err != nil {
+		return nil, err
+	}
+	remoteAddr = service.protection.ClientAddr(remoteAddr, forwardedFor)
+	attempt, err := service.protection.Begin(ctx, user.Email, remoteAddr)
+	if err != nil {
+		return nil, err
+	}
+	response, err := service.complete(ctx, challenge, user, form)
+	switch {
+	case errors.Is(err, ErrInvalidCode):
+		service.protection.Failed(ctx, attempt, "invalid two-factor code")
+	case err != nil:
+		service.protection.Cancel(ctx, attempt)
+	default:
+		service.protection.Succeeded(ctx, attempt)
+	}
+	return response, err
+}
+
+// complete проверяет код второго шага и выдает токены доступа
+func (service *TwoFactorService) complete(
+	ctx context.Context,
+	challenge *common.AuthChallengeRecord,
+	user *common.User,
+	form common.TwoFactorChallengeRequest,
+) (*common.AuthSignInResponse, error) {
+	secret, err := service.repo.FindTOTP(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		service.addAttempt(ctx, challenge)
+		return nil, ErrInvalidCode
+	}
+
+	var response common.AuthSignInResponse
+	var ok bool
+	switch {
+	case secret.EnabledAt == nil:
+		codes, err := service.enable(ctx, secret, form.Code)
+		if err != nil && !errors.Is(err, ErrInvalidCode) {
+			return nil, err
+		}
+		ok = err == nil
+		response.RecoveryCodes = codes
+	case form.RecoveryCode != "":
+		ok, err = service.repo.UseRecoveryCode(ctx, user.ID, hashRecoveryCode(form.RecoveryCode))
+	default:
+		ok, err = service.verifyCode(ctx, secret, form.Code)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		service.addAttempt(ctx, challenge)
+		return nil, ErrInvalidCode
+	}
+
+	consumed, err := service.repo.ConsumeChallenge(ctx, challenge.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, ErrInvalidToken
+	}
+	password, err := openGuacamolePassword(challenge.Credentials)
+	if err != nil {
+		return nil, err
+	}
+	response.AuthTokens, err = service.tokens.Issue(ctx, user, password)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// findChallenge возвращает действующий незавершенный вход и его пользователя или ErrInvalidToken
+func (service *TwoFactorService) findChallenge(
+	ctx context.Context,
+	token string,
+) (*common.AuthChallengeRecord, *common.User, error) {
+	challenge, err := service.repo.FindChallenge(
+		ctx,
+		hashSecretToken(token),
+		config.ServerConfig.TwoFactorConfig.MaxAttempts,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	if challenge == nil {
+		return nil, nil, ErrInvalidToken
+	}
+	user, err := service.users.FindByID(ctx, challenge.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return challenge, user, nil
+}
+
+// addAttempt учитывает неверный код незавершенного входа
+func (service *TwoFactorService) addAttempt(ctx context.Context, challenge *common.AuthChallengeRecord) {
+	if err := service.repo.AddChallengeAttempt(ctx, challenge.ID); err != nil {
+		slog.Warn(fmt.Sprintf("Error counting two-factor attempt of challenge %s: %s", challenge.ID, err.Error()))
+	}
+}
+
+// enroll сохраняет новый неподтвержденный секрет TOTP пользователя
+func (service *TwoFactorService) enroll(ctx context.Context, user *common.User) (*common.TwoFactorEnrollment, error) {
+	secret, err := totp.NewSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := sealSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	saved, err := service.repo.SavePendingTOTP(ctx, user.ID, sealed)
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		return nil, ErrConflict
+	}
+	return &common.TwoFactorEnrollment{
+		Secret:          totp.EncodeSecret(secret),
+		ProvisioningURI: totp.ProvisioningURI(config.ServerConfig.TwoFactorConfig.Issuer, user.Email, secret),
+	}, nil
+}
+
+// enable подтверждает неподтвержденный секрет кодом и выдает коды восстановления
+func (service *TwoFactorService) enable(ctx context.Context, secret *common.UserTOTP, code string) ([]string, error) {
+	key, err := openSecret(secret.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt totp secret: %w", err)
+	}
+	step, ok := totp.Validate(key, code, time.Now(), secret.LastUsedStep)
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	enabled, err := service.repo.EnableTOTP(ctx, secret.UserID, step, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		// Секрет подтвержден параллельным запросом или код уже использован
+		return nil, ErrInvalidCode
+	}
+	return codes, nil
+}
+
+// verifyCode проверяет код подключенного TOTP; принятый код нельзя использовать повторно
+func (service *TwoFactorService) verifyCode(ctx context.Context, secret *common.UserTOTP, code string) (bool, error) {
+	key, err := openSecret(secret.Secret)
+	if err != nil {
+		return false, fmt.Errorf("failed to decrypt totp secret: %w", err)
+	}
+	step, ok := totp.Validate(key, code, time.Now(), secret.LastUsedStep)
+	if !ok {
+		return false, nil
+	}
+	return service.repo.UseTOTPStep(ctx, secret.UserID, step)
+}
+
+// required проверяет, обязателен ли TOTP для пользователя
+func (service *TwoFactorService) required(ctx context.Context, user *common.User) (bool, error) {
+	requiredPermissions := config.ServerConfig.TwoFactorConfig.RequiredPermissions
+	if len(requiredPermissions) == 0 {
+		return false, nil
+	}
+	permissions, err := service.guacamole.FindSystemPermissions(ctx, user.Email)
+	if err != nil {
+		return false, err
+	}
+	for _, permission := range requiredPermissions {
+		if hasPermission(permissions, permission) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// newRecoveryCodes генерирует коды восстановления вида xxxxx-xxxxx и их хэши
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	buf := make([]byte, 6)
+	for i := 0; i < recoveryCodeCount; i++ {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		code := recoveryCodeEncoding.EncodeToString(buf)
+		code = code[:5] + "-" + code[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode возвращает хэш кода восстановления без учета регистра, пробелов и дефисов
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	return hashSecretToken(code)
+}
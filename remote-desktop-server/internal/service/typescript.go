@@ -0,0 +1,84 @@
+// Package service реализует бизнес-логику приложения.
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/recording"
+)
+
+// AsciicastStream открытая запись typescript, готовая к преобразованию в asciicast v2
+type AsciicastStream struct {
+	data      *os.File
+	timing    *os.File
+	header    recording.AsciicastHeader
+	startedAt time.Time
+}
+
+// Name возвращает имя файла asciicast для скачивания
+func (stream *AsciicastStream) Name() string {
+	return stream.header.Title + ".cast"
+}
+
+// Convert записывает запись в формате asciicast v2
+func (stream *AsciicastStream) Convert(w io.Writer) error {
+	return recording.ConvertTypescript(stream.data, stream.timing, w, stream.header)
+}
+
+// Close закрывает файлы записи
+func (stream *AsciicastStream) Close() error {
+	return errors.Join(stream.data.Close(), stream.timing.Close())
+}
+
+// openTypescript открывает файл временных меток записи typescript и вычисляет
+// время начала записи. При ошибке файл data не закрывается.
+//
+// Параметры:
+//   - data: открытый файл вывода typescript
+//   - name: имя файла typescript
+//
+// Возвращает:
+//   - *AsciicastStream: открытая запись
+//   - error: ErrNotFound, если файл временных меток отсутствует
+func openTypescript(data *os.File, name string) (*AsciicastStream, error) {
+	timingPath := data.Name() + ".timing"
+	timing, err := os.Open(timingPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording timing: %w", err)
+	}
+
+	duration, err := recording.TypescriptDuration(timing)
+	if err == nil {
+		_, err = timing.Seek(0, io.SeekStart)
+	}
+	info, statErr := data.Stat()
+	if err = errors.Join(err, statErr); err != nil {
+		timing.Close()
+		return nil, fmt.Errorf("failed to read recording timing: %w", err)
+	}
+
+	// Файл вывода дописывается до конца сессии, поэтому начало записи — время
+	// последнего изменения за вычетом длительности
+	startedAt := info.ModTime().Add(-duration)
+	return &AsciicastStream{
+		data:   data,
+		timing: timing,
+		header: recording.AsciicastHeader{
+			Timestamp: startedAt.Unix(),
+			Duration:  recording.Seconds(duration),
+			Title:     strings.TrimSuffix(name, common.TypescriptSuffix),
+			Env:       map[string]string{"TERM": "xterm-256color"},
+		},
+		startedAt: startedAt,
+	}, nil
+}
@@ -0,0 +1,447 @@
+// Package service реализует бизнес-логику приложения.
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/repository"
+)
+
+// UserGroupService предоставляет методы для управления группами пользователей.
+// Группы хранятся в базе приложения и зеркалируются в Guacamole (сущности USER_GROUP
+// и guacamole_user_group_member), поэтому доступ к подключению можно выдать всей группе сразу.
+// Изменять группы может только администратор Guacamole.
+type UserGroupService struct {
+	groups    repository.UserGroupRepository
+	users     repository.UserRepository
+	guacamole repository.GuacamoleRepository
+	sessions  *SessionService
+}
+
+// NewUserGroupService создает и возвращает новый экземпляр UserGroupService.
+//
+// Параметры:
+//   - groups: репозиторий групп пользователей
+//   - users: репозиторий пользователей
+//   - guacamole: репозиторий базы данных Guacamole
+//   - sessions: сервис подключений, через который проверяются права вызывающего
+//
+// Возвращает:
+//   - *UserGroupService: указатель на созданный сервис
+func NewUserGroupService(
+	groups repository.UserGroupRepository,
+	users repository.UserRepository,
+	guacamole repository.GuacamoleRepository,
+	sessions *SessionService,
+) *UserGroupService {
+	return &UserGroupService{
+		groups:    groups,
+		users:     users,
+		guacamole: guacamole,
+		sessions:  sessions,
+	}
+}
+
+// GetGroups возвращает все группы пользователей.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//
+// Возвращает:
+//   - []*common.UserGroup: группы, отсортированные по названию
+//   - error: ошибка, если не удалось получить данные
+func (service *UserGroupService) GetGroups(ctx context.Context) ([]*common.UserGroup, error) {
+	return service.groups.FindAll(ctx)
+}
+
+// GetGroup возвращает группу вместе с участниками и вложенными группами.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор группы
+//
+// Возвращает:
+//   - *common.UserGroupDetails: группа с участниками
+//   - error: ErrNotFound, если группа не найдена
+func (service *UserGroupService) GetGroup(ctx context.Context, id string) (*common.UserGroupDetails, error) {
+	group, err := service.findGroup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	members, err := service.groups.FindMembers(ctx, group.ID)
+	if err != nil {
+		return nil, err
+	}
+	subgroups, err := service.groups.FindSubgroups(ctx, group.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &common.UserGroupDetails{
+		UserGroup: *group,
+		Members:   members,
+		Subgroups: subgroups,
+	}, nil
+}
+
+// CreateGroup создает группу пользователей в базе приложения и в Guacamole.
+// Если сохранить группу в базе приложения не удалось, группа Guacamole удаляется.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - form: данные группы
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - *common.UserGroup: созданная группа
+//   - error: ErrForbidden, если вызывающий не администратор; ErrConflict, если название занято
+func (service *UserGroupService) CreateGroup(
+	ctx context.Context,
+	form *common.UserGroupRequest,
+	guacToken string,
+) (*common.UserGroup, error) {
+	if err := service.requireAdministrator(guacToken); err != nil {
+		return nil, err
+	}
+	if err := service.ensureNameAvailable(ctx, form.Name); err != nil {
+		return nil, err
+	}
+
+	if err := service.guacamole.CreateUserGroup(ctx, form.Name); err != nil {
+		return nil, fmt.Errorf("failed to create guacamole user group: %w", err)
+	}
+	group := &common.UserGroup{
+		Name:        form.Name,
+		Description: form.Description,
+	}
+	if err := service.groups.Create(ctx, group); err != nil {
+		if rollbackErr := service.guacamole.DeleteEntity(ctx, form.Name, common.EntityTypeUserGroup); rollbackErr != nil {
+			slog.Error(fmt.Sprintf("Error deleting guacamole user group %s: %s", form.Name, rollbackErr.Error()))
+		}
+		return nil, fmt.Errorf("failed to save user group: %w", err)
+	}
+	return group, nil
+}
+
+// UpdateGroup изменяет название и описание группы.
+// При смене названия группа Guacamole переименовывается.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор группы
+//   - form: новые данные группы
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - *common.UserGroup: измененная группа
+//   - error: ErrNotFound, ErrForbidden или ErrConflict, если новое название занято
+func (service *UserGroupService) UpdateGroup(
+	ctx context.Context,
+	id string,
+	form *common.UserGroupRequest,
+	guacToken string,
+) (*common.UserGroup, error) {
+	if err := service.requireAdministrator(guacToken); err != nil {
+		return nil, err
+	}
+	group, err := service.findGroup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	oldName := group.Name
+	renamed := form.Name != oldName
+	if renamed {
+		if err := service.ensureNameAvailable(ctx, form.Name); err != nil {
+			return nil, err
+		}
+		if err := service.guacamole.RenameEntity(ctx, oldName, form.Name, common.EntityTypeUserGroup); err != nil {
+			return nil, fmt.Errorf("failed to rename guacamole user group: %w", err)
+		}
+	}
+	group.Name = form.Name
+	group.Description = form.Description
+	if err := service.groups.Update(ctx, group); err != nil {
+		if renamed {
+			if rollbackErr := service.guacamole.RenameEntity(
+				ctx,
+				form.Name,
+				oldName,
+				common.EntityTypeUserGroup,
+			); rollbackErr != nil {
+				slog.Error(fmt.Sprintf("Error renaming guacamole user group %s back: %s", form.Name, rollbackErr.Error()))
+			}
+		}
+		return nil, fmt.Errorf("failed to update user group: %w", err)
+	}
+	return group, nil
+}
+
+// DestroyGroup удаляет группу из базы приложения и из Guacamole.
+// Разрешения, выданные группе в Guacamole, удаляются вместе с ней.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор группы
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - error: ErrNotFound или ErrForbidden
+func (service *UserGroupService) DestroyGroup(ctx context.Context, id string, guacToken string) error {
+	if err := service.requireAdministrator(guacToken); err != nil {
+		return err
+	}
+	group, err := service.findGroup(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := service.groups.Delete(ctx, group.ID); err != nil {
+		return fmt.Errorf("failed to delete user group: %w", err)
+	}
+	if err := service.guacamole.DeleteEntity(ctx, group.Name, common.EntityTypeUserGroup); err != nil {
+		return fmt.Errorf("failed to delete guacamole user group: %w", err)
+	}
+	return nil
+}
+
+// AddMember добавляет зарегистрированного пользователя в группу.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор группы
+//   - form: email пользователя
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - error: ErrNotFound, если группа или пользователь не найдены; ErrForbidden
+func (service *UserGroupService) AddMember(
+	ctx context.Context,
+	id string,
+	form *common.UserGroupMemberRequest,
+	guacToken string,
+) error {
+	if err := service.requireAdministrator(guacToken); err != nil {
+		return err
+	}
+	group, err := service.findGroup(ctx, id)
+	if err != nil {
+		return err
+	}
+	user, err := service.users.FindByEmail(ctx, form.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := service.guacamole.AddUserGroupMember(ctx, group.Name, user.Email, common.EntityTypeUser); err != nil {
+		return fmt.Errorf("failed to add guacamole user group member: %w", err)
+	}
+	if err := service.groups.AddMember(ctx, group.ID, user.ID); err != nil {
+		if rollbackErr := service.guacamole.RemoveUserGroupMember(
+			ctx,
+			group.Name,
+			user.Email,
+			common.EntityTypeUser,
+		); rollbackErr != nil {
+			slog.Error(fmt.Sprintf("Error removing %s from guacamole user group %s: %s", user.Email, group.Name, rollbackErr.Error()))
+		}
+		return fmt.Errorf("failed to add user group member: %w", err)
+	}
+	return nil
+}
+
+// RemoveMember удаляет пользователя из группы.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор группы
+//   - userID: идентификатор пользователя
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - error: ErrNotFound, если группа или пользователь не найдены; ErrForbidden
+func (service *UserGroupService) RemoveMember(ctx context.Context, id string, userID string, guacToken string) error {
+	if err := service.requireAdministrator(guacToken); err != nil {
+		return err
+	}
+	group, err := service.findGroup(ctx, id)
+	if err != nil {
+		return err
+	}
+	parsedID, err := uuid.Parse(userID)
+	if err != nil {
+		return ErrNotFound
+	}
+	user, err := service.users.FindByID(ctx, parsedID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := service.groups.RemoveMember(ctx, group.ID, user.ID); err != nil {
+		return fmt.Errorf("failed to remove user group member: %w", err)
+	}
+	if err := service.guacamole.RemoveUserGroupMember(ctx, group.Name, user.Email, common.EntityTypeUser); err != nil {
+		return fmt.Errorf("failed to remove guacamole user group member: %w", err)
+	}
+	return nil
+}
+
+// AddSubgroup вкладывает группу в другую группу.
+// Участники вложенной группы получают доступ, выданный родительской группе.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор родительской группы
+//   - form: идентификатор вкладываемой группы
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - error: ErrNotFound или ErrForbidden; ErrUnsupported, если вложение образует цикл
+func (service *UserGroupService) AddSubgroup(
+	ctx context.Context,
+	id string,
+	form *common.UserSubgroupRequest,
+	guacToken string,
+) error {
+	if err := service.requireAdministrator(guacToken); err != nil {
+		return err
+	}
+	parent, err := service.findGroup(ctx, id)
+	if err != nil {
+		return err
+	}
+	child, err := service.findGroup(ctx, form.GroupID)
+	if err != nil {
+		return err
+	}
+	if parent.ID == child.ID {
+		return ErrUnsupported
+	}
+	cycle, err := service.groups.IsNestedIn(ctx, parent.ID, child.ID)
+	if err != nil {
+		return err
+	}
+	if cycle {
+		return ErrUnsupported
+	}
+
+	if err := service.guacamole.AddUserGroupMember(ctx, parent.Name, child.Name, common.EntityTypeUserGroup); err != nil {
+		return fmt.Errorf("failed to add guacamole user group member: %w", err)
+	}
+	if err := service.groups.AddSubgroup(ctx, parent.ID, child.ID); err != nil {
+		if rollbackErr := service.guacamole.RemoveUserGroupMember(
+			ctx,
+			parent.Name,
+			child.Name,
+			common.EntityTypeUserGroup,
+		); rollbackErr != nil {
+			slog.Error(fmt.Sprintf("Error removing %s from guacamole user group %s: %s", child.Name, parent.Name, rollbackErr.Error()))
+		}
+		return fmt.Errorf("failed to add subgroup: %w", err)
+	}
+	return nil
+}
+
+// RemoveSubgroup убирает вложенную группу из родительской группы.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор родительской группы
+//   - childID: идентификатор вложенной группы
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - error: ErrNotFound или ErrForbidden
+func (service *UserGroupService) RemoveSubgroup(ctx context.Context, id string, childID string, guacToken string) error {
+	if err := service.requireAdministrator(guacToken); err != nil {
+		return err
+	}
+	parent, err := service.findGroup(ctx, id)
+	if err != nil {
+		return err
+	}
+	child, err := service.findGroup(ctx, childID)
+	if err != nil {
+		return err
+	}
+
+	if err := service.groups.RemoveSubgroup(ctx, parent.ID, child.ID); err != nil {
+		return fmt.Errorf("failed to remove subgroup: %w", err)
+	}
+	if err := service.guacamole.RemoveUserGroupMember(ctx, parent.Name, child.Name, common.EntityTypeUserGroup); err != nil {
+		return fmt.Errorf("failed to remove guacamole user group member: %w", err)
+	}
+	return nil
+}
+
+// findGroup ищет группу по строковому идентификатору.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - id: идентификатор группы
+//
+// Возвращает:
+//   - *common.UserGroup: найденная группа
+//   - error: ErrNotFound, если идентификатор некорректен или группа не найдена
+func (service *UserGroupService) findGroup(ctx context.Context, id string) (*common.UserGroup, error) {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	group, err := service.groups.FindByID(ctx, parsedID)
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return nil, ErrNotFound
+	}
+	return group, nil
+}
+
+// ensureNameAvailable проверяет, что название не занято группой Guacamole.
+// Группы приложения зеркалируются в Guacamole, поэтому проверки Guacamole достаточно.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса
+//   - name: название группы
+//
+// Возвращает:
+//   - error: ErrConflict, если название занято
+func (service *UserGroupService) ensureNameAvailable(ctx context.Context, name string) error {
+	entity, err := service.guacamole.FindEntity(ctx, name, common.EntityTypeUserGroup)
+	if err != nil {
+		return err
+	}
+	if entity != nil {
+		return ErrConflict
+	}
+	return nil
+}
+
+// requireAdministrator проверяет, что владелец токена — администратор Guacamole.
+//
+// Параметры:
+//   - guacToken: токен аутентификации Guacamole
+//
+// Возвращает:
+//   - error: ErrForbidden, если вызывающий не администратор
+func (service *UserGroupService) requireAdministrator(guacToken string) error {
+	isAdmin, err := service.sessions.isAdministrator(guacToken)
+	if err != nil {
+		return err
+	}
+	if !isAdmin {
+		return ErrForbidden
+	}
+	return nil
+}
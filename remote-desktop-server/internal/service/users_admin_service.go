@@ -0,0 +1,384 @@
+// Package service реализует бизнес-логику приложения.
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/common"
+	"github.com/margar-melkonyan/remote-desktop.git/internal/repository"
+)
+
+// UserAdminService предоставляет администратору методы управления пользователями.
+//
+// Учетная запись пользователя существует и в базе приложения, и в базе Guacamole,
+// поэтому отключение, включение и удаление меняют обе базы (как сага там, где изменение можно откатить).
+// Доступ к методам ограничивается разрешением users:manage на уровне маршрутов.
+type UserAdminService struct {
+	users     repository.UserRepository
+	guacamole repository.GuacamoleRepository
+	tokens    *TokenService
+	roles     *RoleService
+}
+
+// NewUserAdminService создает новый экземпляр UserAdminService.
+//
+// Параметры:
+//   - users: репозиторий пользователей
+//   - guacamole: репозиторий Guacamole
+//   - tokens: сервис токенов (отзыв сессий входа)
+//   - roles: сервис назначения ролей
+//
+// Возвращает:
+//   - *UserAdminService: указатель на созданный сервис
+func NewUserAdminService(
+	users repository.UserRepository,
+	guacamole repository.GuacamoleRepository,
+	tokens *TokenService,
+	roles *RoleService,
+) *UserAdminService {
+	return &UserAdminService{
+		users:     users,
+		guacamole: guacamole,
+		tokens:    tokens,
+		roles:     roles,
+	}
+}
+
+// GetUsers возвращает страницу списка пользователей.
+//
+// Параметры:
+//   - ctx: контекст
+//   - filter: поисковая строка, фильтры и пагинация
+//
+// Возвращает:
+//   - *common.UserPage: страница списка
+//   - error: ошибка чтения пользователей
+func (service *UserAdminService) GetUsers(ctx context.Context, filter *common.UserFilter) (*common.UserPage, error) {
+	users, total, err := service.users.FindPage(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch users: %w", err)
+	}
+	items := make([]*common.UserAccount, 0, len(users))
+	for _, user := range users {
+		items = append(items, userAccount(user))
+	}
+	return &common.UserPage{
+		Items:   items,
+		Total:   total,
+		Page:    filter.Page,
+		PerPage: filter.PerPage,
+	}, nil
+}
+
+// GetUser возвращает пользователя вместе с состоянием его учетной записи Guacamole.
+//
+// Параметры:
+//   - ctx: контекст
+//   - id: идентификатор пользователя
+//
+// Возвращает:
+//   - *common.UserDetails: пользователь
+//   - error: ErrNotFound, если пользователь не найден, или ошибка чтения данных
+func (service *UserAdminService) GetUser(ctx context.Context, id string) (*common.UserDetails, error) {
+	user, err := service.findUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	account, err := service.guacamole.FindUserAccount(ctx, user.Email)
+	if err != nil {
+		return nil, err
+	}
+	return &common.UserDetails{
+		UserAccount: *userAccount(user),
+		Guacamole:   account,
+	}, nil
+}
+
+// GetPermissions возвращает разрешения пользователя: разрешения приложения по роли,
+// системные разрешения Guacamole и разрешения на подключения, включая полученные через группы.
+//
+// Параметры:
+//   - ctx: контекст
+//   - id: идентификатор пользователя
+//
+// Возвращает:
+//   - *common.UserPermissions: разрешения пользователя
+//   - error: ErrNotFound, если пользователь не найден, или ошибка чтения разрешений
+func (service *UserAdminService) GetPermissions(ctx context.Context, id string) (*common.UserPermissions, error) {
+	user, err := service.findUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	systemPermissions, err := service.guacamole.FindSystemPermissions(ctx, user.Email)
+	if err != nil {
+		return nil, err
+	}
+	connectionPermissions, err := service.guacamole.FindUserConnectionPermissions(ctx, user.Email)
+	if err != nil {
+		return nil, err
+	}
+	return &common.UserPermissions{
+		Role:                  user.Role,
+		Permissions:           common.RolePermissions(user.Role),
+		SystemPermissions:     systemPermissions,
+		ConnectionPermissions: connectionPermissions,
+	}, nil
+}
+
+// Disable отключает пользователя: помечает его удаленным в базе приложения, отключает учетную запись
+// Guacamole и отзывает его сессии входа.
+// Изменение затрагивает две базы данных и выполняется как сага: если пользователя не удалось
+// отключить в базе приложения, учетная запись Guacamole снова включается.
+//
+// Параметры:
+//   - ctx: контекст
+//   - actor: администратор, выполняющий операцию
+//   - id: идентификатор пользователя
+//
+// Возвращает:
+//   - error: ErrNotFound, если пользователь не найден, ErrUnsupported при попытке отключить себя,
+//     или ошибка изменения данных
+func (service *UserAdminService) Disable(ctx context.Context, actor *common.User, id string) error {
+	user, err := service.findUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	if user.ID == actor.ID {
+		return fmt.Errorf("%w: administrators can not disable their own account", ErrUnsupported)
+	}
+	if user.DeletedAt != nil {
+		return nil
+	}
+
+	err = newSaga("disable user").
+		step(
+			"disable guacamole account",
+			func(ctx context.Context) error {
+				return service.guacamole.SetUserDisabled(ctx, user.Email, true)
+			},
+			func(ctx context.Context) error {
+				return service.guacamole.SetUserDisabled(ctx, user.Email, false)
+			},
+		).
+		step(
+			"disable user",
+			func(ctx context.Context) error {
+				return service.users.SetDisabled(ctx, user.ID, true)
+			},
+			nil,
+		).
+		run(ctx)
+	if err != nil {
+		return err
+	}
+	slog.Info(fmt.Sprintf("User %s disabled by %s", user.Email, actor.Email))
+	return service.tokens.RevokeUser(ctx, user.ID)
+}
+
+// Enable снова включает отключенного пользователя и его учетную запись Guacamole.
+// Как и Disable, выполняется как сага.
+//
+// Параметры:
+//   - ctx: контекст
+//   - actor: администратор, выполняющий операцию
+//   - id: идентификатор пользователя
+//
+// Возвращает:
+//   - error: ErrNotFound, если пользователь не найден, ErrConflict, если после отключения
+//     зарегистрирован другой пользователь с той же почтой, или ошибка изменения данных
+func (service *UserAdminService) Enable(ctx context.Context, actor *common.User, id string) error {
+	user, err := service.findUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	if user.DeletedAt == nil {
+		return nil
+	}
+	active, err := service.users.FindByEmail(ctx, user.Email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if active != nil {
+		return fmt.Errorf("%w: another user with email %s is active", ErrConflict, user.Email)
+	}
+
+	err = newSaga("enable user").
+		step(
+			"enable guacamole account",
+			func(ctx context.Context) error {
+				// Учетная запись Guacamole остается отключенной до подтверждения почты
+				if user.EmailVerifiedAt == nil {
+					return nil
+				}
+				return service.guacamole.SetUserDisabled(ctx, user.Email, false)
+			},
+			func(ctx context.Context) error {
+				if user.EmailVerifiedAt == nil {
+					return nil
+				}
+				return service.guacamole.SetUserDisabled(ctx, user.Email, true)
+			},
+		).
+		step(
+			"enable user",
+			func(ctx context.Context) error {
+				return service.users.SetDisabled(ctx, user.ID, false)
+			},
+			nil,
+		).
+		run(ctx)
+	if err != nil {
+		return err
+	}
+	slog.Info(fmt.Sprintf("User %s enabled by %s", user.Email, actor.Email))
+	return nil
+}
+
+// ExpirePassword помечает пароль пользователя истекшим и отзывает его сессии входа.
+// Войти снова пользователь сможет только после сброса пароля (POST /auth/forgot-password),
+// который снимает пометку.
+//
+// Параметры:
+//   - ctx: контекст
+//   - actor: администратор, выполняющий операцию
+//   - id: идентификатор пользователя
+//
+// Возвращает:
+//   - error: ErrNotFound, если пользователь или его учетная запись Guacamole не найдены
+//     или пользователь отключен, ErrUnsupported, если пароль Guacamole пользователя
+//     управляется сервером (вход через OpenID Connect или LDAP), или ошибка изменения данных
+func (service *UserAdminService) ExpirePassword(ctx context.Context, actor *common.User, id string) error {
+	user, err := service.findUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	if user.DeletedAt != nil {
+		return fmt.Errorf("%w: user %s is disabled", ErrNotFound, user.Email)
+	}
+	if len(user.GuacamoleCredentials) > 0 {
+		return fmt.Errorf("%w: password of %s is managed by an external provider", ErrUnsupported, user.Email)
+	}
+	err = service.guacamole.SetUserExpired(ctx, user.Email, true)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: guacamole account of %s", ErrNotFound, user.Email)
+	}
+	if err != nil {
+		return err
+	}
+	slog.Info(fmt.Sprintf("Password of %s expired by %s", user.Email, actor.Email))
+	return service.tokens.RevokeUser(ctx, user.ID)
+}
+
+// SetRole назначает пользователю роль (см. RoleService.Assign).
+//
+// Параметры:
+//   - ctx: контекст
+//   - actor: администратор, выполняющий операцию
+//   - id: идентификатор пользователя
+//   - role: новая роль
+//
+// Возвращает:
+//   - error: ErrNotFound, если пользователь не найден или отключен, ErrUnsupported при попытке
+//     понизить собственную роль или для неизвестной роли, или ошибка изменения данных
+func (service *UserAdminService) SetRole(ctx context.Context, actor *common.User, id string, role string) error {
+	user, err := service.findUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	if user.DeletedAt != nil {
+		return fmt.Errorf("%w: user %s is disabled", ErrNotFound, user.Email)
+	}
+	if user.ID == actor.ID && role != user.Role {
+		return fmt.Errorf("%w: administrators can not change their own role", ErrUnsupported)
+	}
+	return service.roles.Assign(ctx, user, role)
+}
+
+// Delete удаляет пользователя без возможности восстановления: его учетную запись Guacamole
+// (вместе с разрешениями и членством в группах Guacamole), пользователя приложения и его сессии входа.
+//
+// Удаленную учетную запись Guacamole восстановить нельзя, поэтому она удаляется первой:
+// если затем не удастся удалить пользователя приложения, он останется без учетной записи Guacamole
+// и будет помечен удаленным при сверке, а не наоборот — с учетной записью Guacamole без пользователя.
+// Если удаляется отключенный пользователь, а с той же почтой зарегистрирован действующий,
+// учетная запись Guacamole принадлежит действующему пользователю и не удаляется.
+//
+// Параметры:
+//   - ctx: контекст
+//   - actor: администратор, выполняющий операцию
+//   - id: идентификатор пользователя
+//
+// Возвращает:
+//   - error: ErrNotFound, если пользователь не найден, ErrUnsupported при попытке удалить себя,
+//     или ошибка удаления
+func (service *UserAdminService) Delete(ctx context.Context, actor *common.User, id string) error {
+	user, err := service.findUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	if user.ID == actor.ID {
+		return fmt.Errorf("%w: administrators can not delete their own account", ErrUnsupported)
+	}
+	if err := service.tokens.RevokeUser(ctx, user.ID); err != nil {
+		return err
+	}
+
+	ownsGuacamoleAccount := true
+	if user.DeletedAt != nil {
+		active, err := service.users.FindByEmail(ctx, user.Email)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		ownsGuacamoleAccount = active == nil
+	}
+	if ownsGuacamoleAccount {
+		if err := service.guacamole.DeleteEntity(ctx, user.Email, common.EntityTypeUser); err != nil {
+			return err
+		}
+	}
+	if err := service.users.Delete(ctx, user.ID); err != nil {
+		return err
+	}
+	slog.Info(fmt.Sprintf("User %s deleted by %s", user.Email, actor.Email))
+	return nil
+}
+
+// findUser возвращает пользователя, в том числе отключенного, по идентификатору из адреса запроса.
+//
+// Возвращает:
+//   - *common.User: пользователь
+//   - error: ErrNotFound, если идентификатор некорректен или пользователь не найден
+func (service *UserAdminService) findUser(ctx context.Context, id string) (*common.User, error) {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	user, err := service.users.FindByIDWithDeleted(ctx, parsedID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// userAccount преобразует пользователя в элемент списка пользователей администратора
+func userAccount(user *common.User) *common.UserAccount {
+	return &common.UserAccount{
+		ID:         user.ID,
+		Name:       user.Name,
+		Email:      user.Email,
+		Role:       user.Role,
+		External:   len(user.GuacamoleCredentials) > 0,
+		Disabled:   user.DeletedAt != nil,
+		Verified:   user.EmailVerifiedAt != nil,
+		DisabledAt: user.DeletedAt,
+		CreatedAt:  user.CreatedAt,
+	}
+}
@@ -31,11 +31,19 @@ func (service *UserService) GetCurrentUser(ctx context.Context) (*common.UserRes
 	if err != nil {
 		return nil, err
 	}
-	userResponse := &common.UserResponse{
-		ID:        user.ID,
-		Name:      user.Name,
-		Email:     user.Email,
-		CreatedAt: &user.CreatedAt,
+	return userResponse(user), nil
+}
+
+// userResponse преобразует пользователя в ответ с данными текущего пользователя
+func userResponse(user *common.User) *common.UserResponse {
+	return &common.UserResponse{
+		ID:          user.ID,
+		Name:        user.Name,
+		Email:       user.Email,
+		Role:        user.Role,
+		Permissions: common.RolePermissions(user.Role),
+		Locale:      user.Locale,
+		Timezone:    user.Timezone,
+		CreatedAt:   &user.CreatedAt,
 	}
-	return userResponse, nil
 }
@@ -0,0 +1,130 @@
+// Package totp реализует одноразовые пароли на основе времени (TOTP, RFC 6238),
+// совместимые с приложениями-аутентификаторами (Google Authenticator, FreeOTP и т.д.).
+//
+// Используются параметры, которые поддерживают все распространенные приложения:
+// HMAC-SHA1, 6 цифр, шаг 30 секунд.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	SecretSize = 20               // Размер секрета в байтах (рекомендация RFC 4226)
+	Digits     = 6                // Количество цифр в коде
+	Period     = 30 * time.Second // Шаг времени
+	Skew       = 1                // Допустимое отклонение часов в шагах в каждую сторону
+)
+
+// encoding кодирует секрет в base32 без дополнения, как ожидают приложения-аутентификаторы
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewSecret генерирует случайный секрет.
+//
+// Возвращает:
+//   - []byte: секрет
+//   - error: ошибка генератора случайных чисел
+func NewSecret() ([]byte, error) {
+	secret := make([]byte, SecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return secret, nil
+}
+
+// EncodeSecret возвращает секрет в base32 для ручного ввода в приложении-аутентификаторе
+func EncodeSecret(secret []byte) string {
+	return encoding.EncodeToString(secret)
+}
+
+// ProvisioningURI возвращает URI otpauth:// для QR кода приложения-аутентификатора.
+//
+// Параметры:
+//   - issuer: название сервиса, отображаемое в приложении
+//   - account: имя учетной записи (email пользователя)
+//   - secret: секрет
+//
+// Возвращает:
+//   - string: URI вида otpauth://totp/issuer:account?secret=...&issuer=...
+func ProvisioningURI(issuer string, account string, secret []byte) string {
+	query := url.Values{}
+	query.Set("secret", EncodeSecret(secret))
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(Digits))
+	query.Set("period", fmt.Sprint(int(Period.Seconds())))
+	uri := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: strings.ReplaceAll(query.Encode(), "+", "%20"), // Приложения не декодируют "+" как пробел
+	}
+	return uri.String()
+}
+
+// Step возвращает номер шага времени для момента t
+func Step(t time.Time) int64 {
+	return t.Unix() / int64(Period.Seconds())
+}
+
+// Code вычисляет код для шага времени (RFC 4226, раздел 5.3).
+//
+// Параметры:
+//   - secret: секрет
+//   - step: номер шага времени
+//
+// Возвращает:
+//   - string: код из Digits цифр с ведущими нулями
+func Code(secret []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	modulo := uint32(1)
+	for i := 0; i < Digits; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", Digits, value%modulo)
+}
+
+// Validate проверяет код с учетом отклонения часов.
+//
+// Параметры:
+//   - secret: секрет
+//   - code: введенный пользователем код (пробелы игнорируются)
+//   - t: текущее время
+//   - lastStep: шаг последнего принятого кода; коды этого и более ранних шагов
+//     отклоняются, чтобы перехваченный код нельзя было использовать повторно
+//
+// Возвращает:
+//   - int64: шаг, которому соответствует код
+//   - bool: код верен
+func Validate(secret []byte, code string, t time.Time, lastStep int64) (int64, bool) {
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != Digits {
+		return 0, false
+	}
+	current := Step(t)
+	for step := current - Skew; step <= current+Skew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(Code(secret, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
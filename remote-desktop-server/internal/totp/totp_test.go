@@ -0,0 +1,129 @@
+package totp
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret секрет тестовых векторов RFC 6238 (приложение B) для HMAC-SHA1
+var rfcSecret = []byte("12345678901234567890")
+
+func TestCode(t *testing.T) {
+	// Коды RFC 6238 состоят из 8 цифр; 6-значный код — их последние 6 цифр
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+		{unix: 20000000000, want: "353130"},
+	}
+	for _, tt := range tests {
+		if got := Code(rfcSecret, Step(time.Unix(tt.unix, 0))); got != tt.want {
+			t.Errorf("Code() at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestStep(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want int64
+	}{
+		{unix: 0, want: 0},
+		{unix: 29, want: 0},
+		{unix: 30, want: 1},
+		{unix: 59, want: 1},
+		{unix: 1111111109, want: 37037036},
+	}
+	for _, tt := range tests {
+		if got := Step(time.Unix(tt.unix, 0)); got != tt.want {
+			t.Errorf("Step(%d) = %d, want %d", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+	code := func(step int64) string { return Code(rfcSecret, step) }
+
+	tests := []struct {
+		name     string
+		code     string
+		lastStep int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "current step", code: code(current), wantStep: current, wantOK: true},
+		{name: "previous step within skew", code: code(current - 1), wantStep: current - 1, wantOK: true},
+		{name: "next step within skew", code: code(current + 1), wantStep: current + 1, wantOK: true},
+		{name: "step outside skew", code: code(current - 2)},
+		{name: "future step outside skew", code: code(current + 2)},
+		{name: "spaces are ignored", code: code(current)[:3] + " " + code(current)[3:], wantStep: current, wantOK: true},
+		{name: "code already used", code: code(current), lastStep: current},
+		{name: "earlier code after newer one", code: code(current - 1), lastStep: current},
+		{name: "newer code after earlier one", code: code(current), lastStep: current - 1, wantStep: current, wantOK: true},
+		{name: "wrong code", code: "000000"},
+		{name: "too short", code: code(current)[:5]},
+		{name: "too long", code: code(current) + "0"},
+		{name: "empty", code: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(rfcSecret, tt.code, now, tt.lastStep)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("Validate(%q) = %d, %t, want %d, %t", tt.code, step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri, err := url.Parse(ProvisioningURI("Remote Desktop", "ivan@example.com", rfcSecret))
+	if err != nil {
+		t.Fatalf("ProvisioningURI() is not a valid URL: %v", err)
+	}
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/Remote Desktop:ivan@example.com" {
+		t.Errorf("ProvisioningURI() = %s, want otpauth://totp/Remote Desktop:ivan@example.com", uri)
+	}
+	want := map[string]string{
+		"secret":    "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		"issuer":    "Remote Desktop",
+		"algorithm": "SHA1",
+		"digits":    "6",
+		"period":    "30",
+	}
+	query := uri.Query()
+	for key, value := range want {
+		if got := query.Get(key); got != value {
+			t.Errorf("ProvisioningURI() %s = %q, want %q", key, got, value)
+		}
+	}
+	if raw := uri.RawQuery; strings.Contains(raw, "+") {
+		t.Errorf("ProvisioningURI() query %q encodes spaces as +", raw)
+	}
+}
+
+func TestNewSecret(t *testing.T) {
+	first, err := NewSecret()
+	if err != nil {
+		t.Fatalf("NewSecret() error = %v", err)
+	}
+	second, _ := NewSecret()
+	if len(first) != SecretSize || len(second) != SecretSize {
+		t.Errorf("NewSecret() length = %d, want %d", len(first), SecretSize)
+	}
+	if string(first) == string(second) {
+		t.Error("NewSecret() returned the same secret twice")
+	}
+	decoded, err := encoding.DecodeString(EncodeSecret(first))
+	if err != nil || string(decoded) != string(first) {
+		t.Errorf("EncodeSecret() does not round-trip: %v", err)
+	}
+}
@@ -8,6 +8,7 @@ class Session extends BaseAPI {
     edit: (id: number): string => `${this.baseURL}/${this.URI}/${id}/edit`,
     update: (id: number): string => `${this.baseURL}/${this.URI}/${id}`,
     delete: (id: number): string => `${this.baseURL}/${this.URI}/${id}`,
+    tunnel: (id: number): string => `${this.baseURL.replace(/^http/, "ws")}/${this.URI}/${id}/tunnel`,
   };
 }
 export default Session;
@@ -9,6 +9,11 @@ interface User {
   name: string;
   email: string;
   current_won_score?: number;
+  role?: string;
+  permissions?: string[];
+  locale?: string;
+  timezone?: string;
+  pending_email?: string;
   created_at?: string;
 }
 
@@ -21,7 +26,7 @@ export const useAuthStore = defineStore('auth', () => {
   async function signIn(form: Form) {
     const { data, status } = await form.post(apiAuth.urls.signIn())
     localStorage.setItem("token", data.data.token)
-    localStorage.setItem("guac_token", data.data.guac_token)
+    localStorage.setItem("refresh_token", data.data.refresh_token)
     await currentUser()
     return status
   }
@@ -41,7 +46,7 @@ export const useAuthStore = defineStore('auth', () => {
   function signOut() {
     user.value = null
     localStorage.removeItem('token')
-    localStorage.removeItem("guac_token")
+    localStorage.removeItem("refresh_token")
   }
 
   return { user, currentUser, signIn, signUp, signOut }